@@ -8,11 +8,20 @@ package startcmd
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/backfill"
+	"github.com/trustbloc/orb/pkg/activitypub/service/inbox/ratelimiter"
+	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/delivery"
+	"github.com/trustbloc/orb/pkg/activitypub/service/poller"
+	"github.com/trustbloc/orb/pkg/activitypub/service/retention"
+	activitypubspi "github.com/trustbloc/orb/pkg/activitypub/store/spi"
 )
 
 const (
@@ -32,8 +41,20 @@ const (
 	startupDelayFlagUsage     = "Orb server start-up delay (in seconds). " + commonEnvVarUsageText + startupDelayEnvKey
 
 	vctURLFlagName  = "vct-url"
-	vctURLFlagUsage = "Verifiable credential transparency URL."
-	vctURLEnvKey    = "ORB_VCT_URL"
+	vctURLFlagUsage = "Verifiable credential transparency URL. Required unless the built-in transparency log is " +
+		"enabled (see " + vctLogEnabledFlagName + "). " + commonEnvVarUsageText + vctURLEnvKey
+	vctURLEnvKey = "ORB_VCT_URL"
+
+	vctLogEnabledFlagName  = "enable-vct-log"
+	vctLogEnabledEnvKey    = "ORB_VCT_LOG_ENABLED"
+	vctLogEnabledFlagUsage = `Set to "true" to use the built-in transparency log as the witness instead of the ` +
+		"VCT server at " + vctURLFlagName + ". The log's REST API is exposed at the external endpoint. The built-in " +
+		"log supports a single writer, so it must be enabled on only one server in a cluster. The server holds a " +
+		"lease on the log which is identified by its host name and host URL, so a restarted server re-claims its " +
+		"own lease immediately. If the lease is held by another server then start-up waits for the lease to " +
+		"expire (30s), e.g. if that server crashed, and fails if the other server is still running. " +
+		"Defaults to false. " +
+		commonEnvVarUsageText + vctLogEnabledEnvKey
 
 	kmsStoreEndpointFlagName  = "kms-store-endpoint"
 	kmsStoreEndpointEnvKey    = "ORB_KMS_STORE_ENDPOINT"
@@ -132,6 +153,7 @@ const (
 	tokenFlagName  = "api-token"
 	tokenEnvKey    = "ORB_API_TOKEN" //nolint: gosec
 	tokenFlagUsage = "Check for bearer token in the authorization header (optional). " +
+		"The activity stream endpoint is only enabled if a token is specified. " +
 		commonEnvVarUsageText + tokenEnvKey
 
 	databaseTypeMemOption     = "mem"
@@ -172,6 +194,105 @@ const (
 	maxWitnessDelayFlagShorthand = "w"
 	maxWitnessDelayFlagUsage     = "Maximum witness response time (in seconds). " + commonEnvVarUsageText + maxWitnessDelayEnvKey
 
+	actorCacheTTLFlagName  = "actor-cache-ttl"
+	actorCacheTTLEnvKey    = "ACTOR_CACHE_TTL"
+	actorCacheTTLFlagUsage = "The amount of time (in seconds) that a remote ActivityPub actor is cached before it is " +
+		"refreshed. " + commonEnvVarUsageText + actorCacheTTLEnvKey
+
+	keyRotationIntervalFlagName  = "key-rotation-interval"
+	keyRotationIntervalEnvKey    = "KEY_ROTATION_INTERVAL"
+	keyRotationIntervalFlagUsage = "The interval (in seconds) at which the service's signing key is rotated. " +
+		"If not set then the key is never rotated. " + commonEnvVarUsageText + keyRotationIntervalEnvKey
+
+	keyRotationOverlapFlagName  = "key-rotation-overlap"
+	keyRotationOverlapEnvKey    = "KEY_ROTATION_OVERLAP"
+	keyRotationOverlapFlagUsage = "The amount of time (in seconds) that both the old and the new key are published " +
+		"before the service starts signing with the new key. Defaults to 24 hours. " +
+		commonEnvVarUsageText + keyRotationOverlapEnvKey
+
+	inboxMaxClockSkewFlagName  = "inbox-max-clock-skew"
+	inboxMaxClockSkewEnvKey    = "INBOX_MAX_CLOCK_SKEW"
+	inboxMaxClockSkewFlagUsage = "The maximum difference (in seconds) between the Date header of a request posted to " +
+		"the inbox and the local time. Requests outside of this window are rejected. Defaults to 5 minutes. " +
+		commonEnvVarUsageText + inboxMaxClockSkewEnvKey
+
+	processedActivityRetentionFlagName  = "processed-activity-retention"
+	processedActivityRetentionEnvKey    = "PROCESSED_ACTIVITY_RETENTION"
+	processedActivityRetentionFlagUsage = "The amount of time (in seconds) that the ID of an activity processed by the " +
+		"inbox is remembered in order to detect duplicates. Defaults to 24 hours. " +
+		commonEnvVarUsageText + processedActivityRetentionEnvKey
+
+	inboxRateLimitFlagName  = "inbox-rate-limit"
+	inboxRateLimitEnvKey    = "INBOX_RATE_LIMIT"
+	inboxRateLimitFlagUsage = "The per-actor rate limits on requests posted to the inbox, by relationship of the actor " +
+		"to this service. The format is a comma-separated list of relationship=rate:burst where relationship is " +
+		"'none', 'follower' or 'witnessing' and rate is the number of requests per second. " +
+		"For example, none=1:10,follower=10:50,witnessing=20:100. If not set then requests are not rate limited. " +
+		commonEnvVarUsageText + inboxRateLimitEnvKey
+
+	inboxOfferRateLimitFlagName  = "inbox-offer-rate-limit"
+	inboxOfferRateLimitEnvKey    = "INBOX_OFFER_RATE_LIMIT"
+	inboxOfferRateLimitFlagUsage = "The per-actor rate limits on 'Offer' activities (witness requests) posted to the " +
+		"inbox, in the same format as " + inboxRateLimitFlagName + ". " + commonEnvVarUsageText + inboxOfferRateLimitEnvKey
+
+	inboxDomainRateLimitFlagName  = "inbox-domain-rate-limit"
+	inboxDomainRateLimitEnvKey    = "INBOX_DOMAIN_RATE_LIMIT"
+	inboxDomainRateLimitFlagUsage = "The rate limit on requests posted to the inbox from all actors in a domain. " +
+		"The format is rate:burst, where rate is the number of requests per second. " +
+		commonEnvVarUsageText + inboxDomainRateLimitEnvKey
+
+	activityRetentionFlagName  = "activity-retention"
+	activityRetentionEnvKey    = "ACTIVITY_RETENTION"
+	activityRetentionFlagUsage = "The retention policies for activities in the ActivityPub store. The format is a " +
+		"comma-separated list of collection=maxAge:maxCount where collection is 'inbox', 'outbox', 'like', " +
+		"'liked' or 'share', maxAge is in seconds and either maxAge or maxCount may be empty (no limit). " +
+		"For example, inbox=2592000:10000,outbox=2592000:,like=:1000. If not set then activities are never pruned. " +
+		"Activities that carry an anchor credential ('Create' and 'Announce') are retained for as long as the " +
+		"anchor credential is stored, i.e. the policies don't apply to them. " +
+		commonEnvVarUsageText + activityRetentionEnvKey
+
+	activityRetentionIntervalFlagName  = "activity-retention-interval"
+	activityRetentionIntervalEnvKey    = "ACTIVITY_RETENTION_INTERVAL"
+	activityRetentionIntervalFlagUsage = "The interval (in seconds) at which activities are pruned according to the " +
+		"retention policies. Defaults to 1 hour. " + commonEnvVarUsageText + activityRetentionIntervalEnvKey
+
+	followBackfillEnabledFlagName  = "enable-follow-backfill"
+	followBackfillEnabledEnvKey    = "FOLLOW_BACKFILL_ENABLED"
+	followBackfillEnabledFlagUsage = `Set to "true" to backfill the anchor credentials that were published by a ` +
+		"service before it accepted our 'Follow'. Defaults to true. " + commonEnvVarUsageText + followBackfillEnabledEnvKey
+
+	followBackfillRetryIntervalFlagName  = "follow-backfill-retry-interval"
+	followBackfillRetryIntervalEnvKey    = "FOLLOW_BACKFILL_RETRY_INTERVAL"
+	followBackfillRetryIntervalFlagUsage = "The interval (in seconds) at which incomplete backfill jobs are resumed. " +
+		"Defaults to 5 minutes. " + commonEnvVarUsageText + followBackfillRetryIntervalEnvKey
+
+	announceBatchSizeFlagName  = "announce-batch-size"
+	announceBatchSizeEnvKey    = "ANNOUNCE_BATCH_SIZE"
+	announceBatchSizeFlagUsage = "The maximum number of anchor credentials that are announced to our followers in a " +
+		"single 'Announce' activity. If neither the batch size nor the batch window is set then each anchor " +
+		"credential is announced immediately. " + commonEnvVarUsageText + announceBatchSizeEnvKey
+
+	announceBatchWindowFlagName  = "announce-batch-window"
+	announceBatchWindowEnvKey    = "ANNOUNCE_BATCH_WINDOW"
+	announceBatchWindowFlagUsage = "The maximum amount of time (in seconds) that an anchor credential is buffered " +
+		"before it is announced to our followers. " + commonEnvVarUsageText + announceBatchWindowEnvKey
+
+	outboxPollIntervalFlagName  = "outbox-poll-interval"
+	outboxPollIntervalEnvKey    = "OUTBOX_POLL_INTERVAL"
+	outboxPollIntervalFlagUsage = "The interval (in seconds) at which the outboxes of followed services are polled " +
+		"for activities that were not delivered to our inbox. If not set then outboxes are not polled. " +
+		commonEnvVarUsageText + outboxPollIntervalEnvKey
+
+	outboxMaxDeliveryWorkersFlagName  = "outbox-max-delivery-workers"
+	outboxMaxDeliveryWorkersEnvKey    = "OUTBOX_MAX_DELIVERY_WORKERS"
+	outboxMaxDeliveryWorkersFlagUsage = "The maximum number of activities that are delivered from the outbox " +
+		"concurrently (across all hosts). Defaults to 20. " + commonEnvVarUsageText + outboxMaxDeliveryWorkersEnvKey
+
+	outboxMaxDeliveriesPerHostFlagName  = "outbox-max-deliveries-per-host"
+	outboxMaxDeliveriesPerHostEnvKey    = "OUTBOX_MAX_DELIVERIES_PER_HOST"
+	outboxMaxDeliveriesPerHostFlagUsage = "The maximum number of activities that are delivered from the outbox " +
+		"concurrently to a single host. Defaults to 2. " + commonEnvVarUsageText + outboxMaxDeliveriesPerHostEnvKey
+
 	signWithLocalWitnessFlagName      = "sign-with-local-witness"
 	signWithLocalWitnessEnvKey        = "SIGN_WITH_LOCAL_WITNESS"
 	signWithLocalWitnessFlagShorthand = "f"
@@ -192,37 +313,98 @@ const (
 	httpSignaturesEnabledUsage     = `Set to "true" to enable HTTP signatures in ActivityPub. ` +
 		commonEnvVarUsageText + httpSignaturesEnabledEnvKey
 
+	httpSignaturesLegacyAlgorithmFlagName  = "http-signatures-legacy-algorithm"
+	httpSignaturesLegacyAlgorithmEnvKey    = "HTTP_SIGNATURES_LEGACY_ALGORITHM"
+	httpSignaturesLegacyAlgorithmFlagUsage = `Set to "true" to sign outbound ActivityPub requests with the ` +
+		"algorithm name used by previous versions of Orb instead of hs2019. This is only required if peers " +
+		"run a version of Orb that doesn't accept hs2019. Defaults to false. " +
+		commonEnvVarUsageText + httpSignaturesLegacyAlgorithmEnvKey
+
+	brokenPromiseWebhookURLFlagName  = "broken-promise-webhook-url"
+	brokenPromiseWebhookURLEnvKey    = "BROKEN_PROMISE_WEBHOOK_URL"
+	brokenPromiseWebhookURLFlagUsage = "The URL to which a broken witness promise (i.e. an anchor credential that " +
+		"was not added to the witness's log before the promise expired) is posted. " +
+		commonEnvVarUsageText + brokenPromiseWebhookURLEnvKey
+
+	notifyBrokenPromiseFlagName  = "notify-broken-promise"
+	notifyBrokenPromiseEnvKey    = "NOTIFY_BROKEN_PROMISE"
+	notifyBrokenPromiseFlagUsage = `Set to "true" to post a 'Reject' activity to a witness that broke its promise ` +
+		"to add an anchor credential to its log. Defaults to false. " + commonEnvVarUsageText + notifyBrokenPromiseEnvKey
+
+	monitoringMinPollIntervalFlagName  = "monitoring-min-poll-interval"
+	monitoringMinPollIntervalEnvKey    = "MONITORING_MIN_POLL_INTERVAL"
+	monitoringMinPollIntervalFlagUsage = "The minimum interval (in seconds) at which a witness log is polled to " +
+		"confirm that an anchor credential was added to the log. Defaults to 1 second. " +
+		commonEnvVarUsageText + monitoringMinPollIntervalEnvKey
+
+	monitoringMaxPollIntervalFlagName  = "monitoring-max-poll-interval"
+	monitoringMaxPollIntervalEnvKey    = "MONITORING_MAX_POLL_INTERVAL"
+	monitoringMaxPollIntervalFlagUsage = "The maximum interval (in seconds) at which a witness log is polled to " +
+		"confirm that an anchor credential was added to the log. The interval backs off (up to this maximum) " +
+		"the further away the witness's deadline is. Defaults to 60 seconds. " +
+		commonEnvVarUsageText + monitoringMaxPollIntervalEnvKey
+
+	witnessSelectionCountFlagName  = "witness-selection-count"
+	witnessSelectionCountEnvKey    = "WITNESS_SELECTION_COUNT"
+	witnessSelectionCountFlagUsage = "The number of system witnesses (the healthiest according to their recorded " +
+		"metrics) to which an anchor credential is offered. Every fifth offer, the least healthy of the selected " +
+		"witnesses is replaced by one of the other witnesses so that their metrics are updated. " +
+		"If not set then the anchor credential is offered to all system witnesses. " + commonEnvVarUsageText + witnessSelectionCountEnvKey
+
 	// TODO: Add verification method
 
 )
 
 type orbParameters struct {
-	hostURL                   string
-	vctURL                    string
-	keyID                     string
-	secretLockKeyPath         string
-	kmsEndpoint               string
-	kmsStoreEndpoint          string
-	externalEndpoint          string
-	didNamespace              string
-	didAliases                []string
-	batchWriterTimeout        time.Duration
-	casURL                    string
-	dbParameters              *dbParameters
-	token                     string
-	logLevel                  string
-	methodContext             []string
-	baseEnabled               bool
-	allowedOrigins            []string
-	tlsCertificate            string
-	tlsKey                    string
-	anchorCredentialParams    *anchorCredentialParams
-	discoveryDomains          []string
-	discoveryMinimumResolvers int
-	maxWitnessDelay           time.Duration
-	startupDelay              time.Duration
-	signWithLocalWitness      bool
-	httpSignaturesEnabled     bool
+	hostURL                    string
+	vctURL                     string
+	vctLogEnabled              bool
+	keyID                      string
+	secretLockKeyPath          string
+	kmsEndpoint                string
+	kmsStoreEndpoint           string
+	externalEndpoint           string
+	didNamespace               string
+	didAliases                 []string
+	batchWriterTimeout         time.Duration
+	casURL                     string
+	dbParameters               *dbParameters
+	token                      string
+	logLevel                   string
+	methodContext              []string
+	baseEnabled                bool
+	allowedOrigins             []string
+	tlsCertificate             string
+	tlsKey                     string
+	anchorCredentialParams     *anchorCredentialParams
+	discoveryDomains           []string
+	discoveryMinimumResolvers  int
+	maxWitnessDelay            time.Duration
+	actorCacheTTL              time.Duration
+	keyRotationInterval        time.Duration
+	keyRotationOverlap         time.Duration
+	inboxMaxClockSkew          time.Duration
+	processedActivityRetention time.Duration
+	inboxRateLimits            *ratelimiter.Config
+	activityRetention          *retention.Config
+	followBackfill             *backfill.Config
+	outboxPolling              *poller.Config
+	outboxDelivery             *delivery.Config
+	announceBatchSize          int
+	witnessSelectionCount      int
+	announceBatchWindow        time.Duration
+	startupDelay               time.Duration
+	signWithLocalWitness       bool
+	httpSignaturesEnabled      bool
+	httpSignaturesLegacyAlgo   bool
+	monitoring                 *monitoringParameters
+}
+
+type monitoringParameters struct {
+	brokenPromiseWebhookURL string
+	notifyBrokenPromise     bool
+	minPollInterval         time.Duration
+	maxPollInterval         time.Duration
 }
 
 type anchorCredentialParams struct {
@@ -249,11 +431,21 @@ func getOrbParameters(cmd *cobra.Command) (*orbParameters, error) {
 		return nil, err
 	}
 
-	vctURL, err := cmdutils.GetUserSetVarFromString(cmd, vctURLFlagName, vctURLEnvKey, false)
+	vctLogEnabled, err := getVCTLogEnabled(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// The VCT URL is required unless the built-in transparency log is explicitly enabled.
+	vctURL, err := cmdutils.GetUserSetVarFromString(cmd, vctURLFlagName, vctURLEnvKey, vctLogEnabled)
 	if err != nil {
 		return nil, err
 	}
 
+	if vctLogEnabled && vctURL != "" {
+		return nil, fmt.Errorf("%s and %s are mutually exclusive", vctURLFlagName, vctLogEnabledFlagName)
+	}
+
 	// no need to check errors for optional flags
 	kmsStoreEndpoint, _ := cmdutils.GetUserSetVarFromString(cmd, kmsStoreEndpointFlagName, kmsStoreEndpointEnvKey, true)    // nolint: errcheck,lll
 	kmsEndpoint, _ := cmdutils.GetUserSetVarFromString(cmd, kmsEndpointFlagName, kmsEndpointEnvKey, true)                   // nolint: errcheck,lll
@@ -314,6 +506,82 @@ func getOrbParameters(cmd *cobra.Command) (*orbParameters, error) {
 		maxWitnessDelay = time.Duration(delay) * time.Second
 	}
 
+	actorCacheTTLStr, err := cmdutils.GetUserSetVarFromString(cmd, actorCacheTTLFlagName, actorCacheTTLEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var actorCacheTTL time.Duration
+	if actorCacheTTLStr != "" {
+		ttl, parseErr := strconv.ParseUint(actorCacheTTLStr, 10, 32)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid actor cache TTL format: %s", parseErr.Error())
+		}
+
+		actorCacheTTL = time.Duration(ttl) * time.Second
+	}
+
+	keyRotationInterval, err := getDuration(cmd, keyRotationIntervalFlagName, keyRotationIntervalEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key rotation interval format: %w", err)
+	}
+
+	keyRotationOverlap, err := getDuration(cmd, keyRotationOverlapFlagName, keyRotationOverlapEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key rotation overlap format: %w", err)
+	}
+
+	inboxMaxClockSkew, err := getDuration(cmd, inboxMaxClockSkewFlagName, inboxMaxClockSkewEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inbox max clock skew format: %w", err)
+	}
+
+	processedActivityRetention, err := getDuration(cmd, processedActivityRetentionFlagName,
+		processedActivityRetentionEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid processed activity retention format: %w", err)
+	}
+
+	inboxRateLimits, err := getInboxRateLimits(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	activityRetention, err := getActivityRetention(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	followBackfill, err := getFollowBackfill(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	outboxPolling, err := getOutboxPolling(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	outboxDelivery, err := getOutboxDelivery(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	announceBatchSize, announceBatchWindow, err := getAnnounceBatching(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	witnessSelectionCount, err := getPositiveInt(cmd, witnessSelectionCountFlagName, witnessSelectionCountEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	monitoringParams, err := getMonitoringParameters(cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	signWithLocalWitnessStr, err := cmdutils.GetUserSetVarFromString(cmd, signWithLocalWitnessFlagName, signWithLocalWitnessEnvKey, true)
 	if err != nil {
 		return nil, err
@@ -358,6 +626,11 @@ func getOrbParameters(cmd *cobra.Command) (*orbParameters, error) {
 		httpSignaturesEnabled = enable
 	}
 
+	httpSignaturesLegacyAlgo, err := getHTTPSignaturesLegacyAlgorithm(cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	didNamespace, err := cmdutils.GetUserSetVarFromString(cmd, didNamespaceFlagName, didNamespaceEnvKey, false)
 	if err != nil {
 		return nil, err
@@ -404,33 +677,458 @@ func getOrbParameters(cmd *cobra.Command) (*orbParameters, error) {
 	}
 
 	return &orbParameters{
-		hostURL:                   hostURL,
-		vctURL:                    vctURL,
-		kmsEndpoint:               kmsEndpoint,
-		keyID:                     keyID,
-		secretLockKeyPath:         secretLockKeyPath,
-		kmsStoreEndpoint:          kmsStoreEndpoint,
-		externalEndpoint:          externalEndpoint,
-		tlsKey:                    tlsKey,
-		tlsCertificate:            tlsCertificate,
-		didNamespace:              didNamespace,
-		didAliases:                didAliases,
-		allowedOrigins:            allowedOrigins,
-		casURL:                    casURL,
-		batchWriterTimeout:        batchWriterTimeout,
-		anchorCredentialParams:    anchorCredentialParams,
-		dbParameters:              dbParams,
-		token:                     token,
-		logLevel:                  loggingLevel,
-		discoveryDomains:          discoveryDomains,
-		discoveryMinimumResolvers: discoveryMinimumResolvers,
-		maxWitnessDelay:           maxWitnessDelay,
-		startupDelay:              startupDelay,
-		signWithLocalWitness:      signWithLocalWitness,
-		httpSignaturesEnabled:     httpSignaturesEnabled,
+		hostURL:                    hostURL,
+		vctURL:                     vctURL,
+		vctLogEnabled:              vctLogEnabled,
+		kmsEndpoint:                kmsEndpoint,
+		keyID:                      keyID,
+		secretLockKeyPath:          secretLockKeyPath,
+		kmsStoreEndpoint:           kmsStoreEndpoint,
+		externalEndpoint:           externalEndpoint,
+		tlsKey:                     tlsKey,
+		tlsCertificate:             tlsCertificate,
+		didNamespace:               didNamespace,
+		didAliases:                 didAliases,
+		allowedOrigins:             allowedOrigins,
+		casURL:                     casURL,
+		batchWriterTimeout:         batchWriterTimeout,
+		anchorCredentialParams:     anchorCredentialParams,
+		dbParameters:               dbParams,
+		token:                      token,
+		logLevel:                   loggingLevel,
+		discoveryDomains:           discoveryDomains,
+		discoveryMinimumResolvers:  discoveryMinimumResolvers,
+		maxWitnessDelay:            maxWitnessDelay,
+		actorCacheTTL:              actorCacheTTL,
+		keyRotationInterval:        keyRotationInterval,
+		keyRotationOverlap:         keyRotationOverlap,
+		inboxMaxClockSkew:          inboxMaxClockSkew,
+		processedActivityRetention: processedActivityRetention,
+		inboxRateLimits:            inboxRateLimits,
+		activityRetention:          activityRetention,
+		followBackfill:             followBackfill,
+		outboxPolling:              outboxPolling,
+		outboxDelivery:             outboxDelivery,
+		announceBatchSize:          announceBatchSize,
+		witnessSelectionCount:      witnessSelectionCount,
+		announceBatchWindow:        announceBatchWindow,
+		startupDelay:               startupDelay,
+		signWithLocalWitness:       signWithLocalWitness,
+		httpSignaturesEnabled:      httpSignaturesEnabled,
+		httpSignaturesLegacyAlgo:   httpSignaturesLegacyAlgo,
+		monitoring:                 monitoringParams,
+	}, nil
+}
+
+// getDuration returns the duration (specified in seconds) for the given flag or environment variable.
+// Zero is returned if the value is not set.
+func getDuration(cmd *cobra.Command, flagName, envKey string) (time.Duration, error) {
+	value, err := cmdutils.GetUserSetVarFromString(cmd, flagName, envKey, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if value == "" {
+		return 0, nil
+	}
+
+	seconds, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// getInboxRateLimits returns the inbox rate limits or nil if no limits were specified.
+func getInboxRateLimits(cmd *cobra.Command) (*ratelimiter.Config, error) {
+	actorLimitsStr, err := cmdutils.GetUserSetVarFromString(cmd, inboxRateLimitFlagName, inboxRateLimitEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	offerLimitsStr, err := cmdutils.GetUserSetVarFromString(cmd, inboxOfferRateLimitFlagName,
+		inboxOfferRateLimitEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	domainLimitStr, err := cmdutils.GetUserSetVarFromString(cmd, inboxDomainRateLimitFlagName,
+		inboxDomainRateLimitEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if actorLimitsStr == "" && offerLimitsStr == "" && domainLimitStr == "" {
+		return nil, nil
+	}
+
+	actorLimits, err := parseRateLimits(actorLimitsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inbox rate limit: %w", err)
+	}
+
+	offerLimits, err := parseRateLimits(offerLimitsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inbox offer rate limit: %w", err)
+	}
+
+	var domainLimit ratelimiter.Limit
+
+	if domainLimitStr != "" {
+		domainLimit, err = parseRateLimit(domainLimitStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inbox domain rate limit: %w", err)
+		}
+	}
+
+	return &ratelimiter.Config{
+		ActorLimits: actorLimits,
+		OfferLimits: offerLimits,
+		DomainLimit: domainLimit,
+	}, nil
+}
+
+func parseRateLimits(value string) (map[ratelimiter.Relationship]ratelimiter.Limit, error) {
+	limits := make(map[ratelimiter.Relationship]ratelimiter.Limit)
+
+	if value == "" {
+		return limits, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expecting relationship=rate:burst but got [%s]", entry)
+		}
+
+		relationship := ratelimiter.Relationship(parts[0])
+
+		switch relationship {
+		case ratelimiter.RelationshipNone, ratelimiter.RelationshipFollower, ratelimiter.RelationshipWitnessing:
+		default:
+			return nil, fmt.Errorf("unsupported relationship [%s]", parts[0])
+		}
+
+		limit, err := parseRateLimit(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		limits[relationship] = limit
+	}
+
+	return limits, nil
+}
+
+func parseRateLimit(value string) (ratelimiter.Limit, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return ratelimiter.Limit{}, fmt.Errorf("expecting rate:burst but got [%s]", value)
+	}
+
+	rate, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return ratelimiter.Limit{}, fmt.Errorf("invalid rate [%s]: %w", parts[0], err)
+	}
+
+	burst, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return ratelimiter.Limit{}, fmt.Errorf("invalid burst [%s]: %w", parts[1], err)
+	}
+
+	return ratelimiter.Limit{Rate: rate, Burst: burst}, nil
+}
+
+// getActivityRetention returns the activity retention configuration or nil if no policies were specified.
+func getActivityRetention(cmd *cobra.Command) (*retention.Config, error) {
+	policiesStr, err := cmdutils.GetUserSetVarFromString(cmd, activityRetentionFlagName, activityRetentionEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if policiesStr == "" {
+		return nil, nil
+	}
+
+	policies, err := parseRetentionPolicies(policiesStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid activity retention: %w", err)
+	}
+
+	interval, err := getDuration(cmd, activityRetentionIntervalFlagName, activityRetentionIntervalEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid activity retention interval format: %w", err)
+	}
+
+	return &retention.Config{
+		Policies: policies,
+		Interval: interval,
+	}, nil
+}
+
+func getVCTLogEnabled(cmd *cobra.Command) (bool, error) {
+	enabledStr, err := cmdutils.GetUserSetVarFromString(cmd, vctLogEnabledFlagName, vctLogEnabledEnvKey, true)
+	if err != nil {
+		return false, err
+	}
+
+	if enabledStr == "" {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(enabledStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", vctLogEnabledFlagName, err)
+	}
+
+	return enabled, nil
+}
+
+func getHTTPSignaturesLegacyAlgorithm(cmd *cobra.Command) (bool, error) {
+	legacyStr, err := cmdutils.GetUserSetVarFromString(cmd, httpSignaturesLegacyAlgorithmFlagName,
+		httpSignaturesLegacyAlgorithmEnvKey, true)
+	if err != nil {
+		return false, err
+	}
+
+	if legacyStr == "" {
+		return false, nil
+	}
+
+	legacy, err := strconv.ParseBool(legacyStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", httpSignaturesLegacyAlgorithmFlagName, err)
+	}
+
+	return legacy, nil
+}
+
+// getFollowBackfill returns the backfill configuration or nil if backfill is disabled.
+func getFollowBackfill(cmd *cobra.Command) (*backfill.Config, error) {
+	enabledStr, err := cmdutils.GetUserSetVarFromString(cmd, followBackfillEnabledFlagName,
+		followBackfillEnabledEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := defaultFollowBackfillEnabled
+	if enabledStr != "" {
+		enabled, err = strconv.ParseBool(enabledStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", followBackfillEnabledFlagName, err)
+		}
+	}
+
+	if !enabled {
+		return nil, nil
+	}
+
+	retryInterval, err := getDuration(cmd, followBackfillRetryIntervalFlagName, followBackfillRetryIntervalEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid follow backfill retry interval format: %w", err)
+	}
+
+	return &backfill.Config{RetryInterval: retryInterval}, nil
+}
+
+// getOutboxPolling returns the outbox poller configuration or nil if outbox polling is disabled.
+func getOutboxPolling(cmd *cobra.Command) (*poller.Config, error) {
+	interval, err := getDuration(cmd, outboxPollIntervalFlagName, outboxPollIntervalEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outbox poll interval format: %w", err)
+	}
+
+	if interval == 0 {
+		return nil, nil
+	}
+
+	return &poller.Config{Interval: interval}, nil
+}
+
+func getMonitoringParameters(cmd *cobra.Command) (*monitoringParameters, error) {
+	webhookURL, err := cmdutils.GetUserSetVarFromString(cmd, brokenPromiseWebhookURLFlagName,
+		brokenPromiseWebhookURLEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhookURL != "" {
+		if _, err = url.ParseRequestURI(webhookURL); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", brokenPromiseWebhookURLFlagName, err)
+		}
+	}
+
+	notifyStr, err := cmdutils.GetUserSetVarFromString(cmd, notifyBrokenPromiseFlagName,
+		notifyBrokenPromiseEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var notify bool
+
+	if notifyStr != "" {
+		notify, err = strconv.ParseBool(notifyStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", notifyBrokenPromiseFlagName, err)
+		}
+	}
+
+	minPollInterval, err := getDuration(cmd, monitoringMinPollIntervalFlagName, monitoringMinPollIntervalEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid monitoring min poll interval format: %w", err)
+	}
+
+	if minPollInterval == 0 {
+		minPollInterval = defaultMonitoringMinPollInterval
+	}
+
+	maxPollInterval, err := getDuration(cmd, monitoringMaxPollIntervalFlagName, monitoringMaxPollIntervalEnvKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid monitoring max poll interval format: %w", err)
+	}
+
+	if maxPollInterval == 0 {
+		maxPollInterval = defaultMonitoringMaxPollInterval
+	}
+
+	if maxPollInterval < minPollInterval {
+		return nil, fmt.Errorf("%s must not be less than %s",
+			monitoringMaxPollIntervalFlagName, monitoringMinPollIntervalFlagName)
+	}
+
+	return &monitoringParameters{
+		brokenPromiseWebhookURL: webhookURL,
+		notifyBrokenPromise:     notify,
+		minPollInterval:         minPollInterval,
+		maxPollInterval:         maxPollInterval,
 	}, nil
 }
 
+func getOutboxDelivery(cmd *cobra.Command) (*delivery.Config, error) {
+	maxWorkers, err := getPositiveInt(cmd, outboxMaxDeliveryWorkersFlagName, outboxMaxDeliveryWorkersEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPerHost, err := getPositiveInt(cmd, outboxMaxDeliveriesPerHostFlagName, outboxMaxDeliveriesPerHostEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery.Config{
+		MaxWorkers: maxWorkers,
+		MaxPerHost: maxPerHost,
+	}, nil
+}
+
+// getPositiveInt returns the value of the given flag (or environment variable) which, if set,
+// must be a positive integer. Zero is returned if the value isn't set.
+func getPositiveInt(cmd *cobra.Command, flagName, envKey string) (int, error) {
+	str, err := cmdutils.GetUserSetVarFromString(cmd, flagName, envKey, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if str == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(str)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid value for %s [%s]: must be a positive integer", flagName, str)
+	}
+
+	return value, nil
+}
+
+// getAnnounceBatching returns the maximum batch size and the batch window for announcing anchor credentials.
+func getAnnounceBatching(cmd *cobra.Command) (int, time.Duration, error) {
+	batchSizeStr, err := cmdutils.GetUserSetVarFromString(cmd, announceBatchSizeFlagName, announceBatchSizeEnvKey, true)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	batchSize := 0
+
+	if batchSizeStr != "" {
+		batchSize, err = strconv.Atoi(batchSizeStr)
+		if err != nil || batchSize <= 0 {
+			return 0, 0, fmt.Errorf("invalid value for %s [%s]: must be a positive integer",
+				announceBatchSizeFlagName, batchSizeStr)
+		}
+	}
+
+	batchWindow, err := getDuration(cmd, announceBatchWindowFlagName, announceBatchWindowEnvKey)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid announce batch window format: %w", err)
+	}
+
+	return batchSize, batchWindow, nil
+}
+
+var retentionCollections = map[string]activitypubspi.ReferenceType{
+	"inbox":  activitypubspi.Inbox,
+	"outbox": activitypubspi.Outbox,
+	"like":   activitypubspi.Like,
+	"liked":  activitypubspi.Liked,
+	"share":  activitypubspi.Share,
+}
+
+func parseRetentionPolicies(value string) (map[activitypubspi.ReferenceType]retention.Policy, error) {
+	policies := make(map[activitypubspi.ReferenceType]retention.Policy)
+
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expecting collection=maxAge:maxCount but got [%s]", entry)
+		}
+
+		refType, ok := retentionCollections[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("unsupported collection [%s]", parts[0])
+		}
+
+		policy, err := parseRetentionPolicy(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		policies[refType] = policy
+	}
+
+	return policies, nil
+}
+
+func parseRetentionPolicy(value string) (retention.Policy, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return retention.Policy{}, fmt.Errorf("expecting maxAge:maxCount but got [%s]", value)
+	}
+
+	var policy retention.Policy
+
+	if parts[0] != "" {
+		seconds, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return retention.Policy{}, fmt.Errorf("invalid max age [%s]: %w", parts[0], err)
+		}
+
+		policy.MaxAge = time.Duration(seconds) * time.Second
+	}
+
+	if parts[1] != "" {
+		count, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return retention.Policy{}, fmt.Errorf("invalid max count [%s]: %w", parts[1], err)
+		}
+
+		policy.MaxCount = count
+	}
+
+	return policy, nil
+}
+
 func getAnchorCredentialParameters(cmd *cobra.Command) (*anchorCredentialParams, error) {
 	domain, err := cmdutils.GetUserSetVarFromString(cmd, anchorCredentialDomainFlagName, anchorCredentialDomainEnvKey, false)
 	if err != nil {
@@ -513,6 +1211,7 @@ func createFlags(startCmd *cobra.Command) {
 	startCmd.Flags().StringP(hostURLFlagName, hostURLFlagShorthand, "", hostURLFlagUsage)
 	startCmd.Flags().StringP(startupDelayFlagName, startupDelayFlagShorthand, "", startupDelayFlagUsage)
 	startCmd.Flags().String(vctURLFlagName, "", vctURLFlagUsage)
+	startCmd.Flags().String(vctLogEnabledFlagName, "", vctLogEnabledFlagUsage)
 	startCmd.Flags().String(kmsStoreEndpointFlagName, "", kmsStoreEndpointFlagUsage)
 	startCmd.Flags().String(kmsEndpointFlagName, "", kmsEndpointFlagUsage)
 	startCmd.Flags().String(keyIDFlagName, "", keyIDFlagUsage)
@@ -522,8 +1221,31 @@ func createFlags(startCmd *cobra.Command) {
 	startCmd.Flags().StringP(tlsKeyFlagName, tlsKeyFlagShorthand, "", tlsKeyFlagUsage)
 	startCmd.Flags().StringP(batchWriterTimeoutFlagName, batchWriterTimeoutFlagShorthand, "", batchWriterTimeoutFlagUsage)
 	startCmd.Flags().StringP(maxWitnessDelayFlagName, maxWitnessDelayFlagShorthand, "", maxWitnessDelayFlagUsage)
+	startCmd.Flags().String(actorCacheTTLFlagName, "", actorCacheTTLFlagUsage)
+	startCmd.Flags().String(keyRotationIntervalFlagName, "", keyRotationIntervalFlagUsage)
+	startCmd.Flags().String(keyRotationOverlapFlagName, "", keyRotationOverlapFlagUsage)
+	startCmd.Flags().String(inboxMaxClockSkewFlagName, "", inboxMaxClockSkewFlagUsage)
+	startCmd.Flags().String(processedActivityRetentionFlagName, "", processedActivityRetentionFlagUsage)
+	startCmd.Flags().String(inboxRateLimitFlagName, "", inboxRateLimitFlagUsage)
+	startCmd.Flags().String(inboxOfferRateLimitFlagName, "", inboxOfferRateLimitFlagUsage)
+	startCmd.Flags().String(inboxDomainRateLimitFlagName, "", inboxDomainRateLimitFlagUsage)
+	startCmd.Flags().String(activityRetentionFlagName, "", activityRetentionFlagUsage)
+	startCmd.Flags().String(activityRetentionIntervalFlagName, "", activityRetentionIntervalFlagUsage)
+	startCmd.Flags().String(followBackfillEnabledFlagName, "", followBackfillEnabledFlagUsage)
+	startCmd.Flags().String(followBackfillRetryIntervalFlagName, "", followBackfillRetryIntervalFlagUsage)
+	startCmd.Flags().String(outboxPollIntervalFlagName, "", outboxPollIntervalFlagUsage)
+	startCmd.Flags().String(outboxMaxDeliveryWorkersFlagName, "", outboxMaxDeliveryWorkersFlagUsage)
+	startCmd.Flags().String(outboxMaxDeliveriesPerHostFlagName, "", outboxMaxDeliveriesPerHostFlagUsage)
+	startCmd.Flags().String(announceBatchSizeFlagName, "", announceBatchSizeFlagUsage)
+	startCmd.Flags().String(witnessSelectionCountFlagName, "", witnessSelectionCountFlagUsage)
+	startCmd.Flags().String(announceBatchWindowFlagName, "", announceBatchWindowFlagUsage)
+	startCmd.Flags().String(brokenPromiseWebhookURLFlagName, "", brokenPromiseWebhookURLFlagUsage)
+	startCmd.Flags().String(notifyBrokenPromiseFlagName, "", notifyBrokenPromiseFlagUsage)
+	startCmd.Flags().String(monitoringMinPollIntervalFlagName, "", monitoringMinPollIntervalFlagUsage)
+	startCmd.Flags().String(monitoringMaxPollIntervalFlagName, "", monitoringMaxPollIntervalFlagUsage)
 	startCmd.Flags().StringP(signWithLocalWitnessFlagName, signWithLocalWitnessFlagShorthand, "", signWithLocalWitnessFlagUsage)
 	startCmd.Flags().StringP(httpSignaturesEnabledFlagName, httpSignaturesEnabledShorthand, "", httpSignaturesEnabledUsage)
+	startCmd.Flags().String(httpSignaturesLegacyAlgorithmFlagName, "", httpSignaturesLegacyAlgorithmFlagUsage)
 	startCmd.Flags().StringP(casURLFlagName, casURLFlagShorthand, "", casURLFlagUsage)
 	startCmd.Flags().StringP(didNamespaceFlagName, didNamespaceFlagShorthand, "", didNamespaceFlagUsage)
 	startCmd.Flags().StringArrayP(didAliasesFlagName, didAliasesFlagShorthand, []string{}, didAliasesFlagUsage)
@@ -16,6 +16,10 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
 	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/inbox/ratelimiter"
+	"github.com/trustbloc/orb/pkg/activitypub/service/retention"
+	activitypubspi "github.com/trustbloc/orb/pkg/activitypub/store/spi"
 )
 
 func TestStartCmdContents(t *testing.T) {
@@ -32,183 +36,797 @@ func TestStartCmdWithBlankArg(t *testing.T) {
 	t.Run("test blank host url arg", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
-		args := []string{"--" + hostURLFlagName, ""}
+		args := []string{"--" + hostURLFlagName, ""}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "host-url value is empty", err.Error())
+	})
+
+	t.Run("test blank cas url arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{"--" + hostURLFlagName, "test"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+
+		const errMsg = "vct-url (command line flag) nor ORB_VCT_URL (environment variable) have been set."
+		require.Contains(t, err.Error(), errMsg)
+	})
+
+	t.Run("test built-in transparency log", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{"--" + hostURLFlagName, "test", "--" + vctLogEnabledFlagName, "true"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+
+		// The VCT URL isn't required when the built-in log is enabled.
+		const errMsg = "cas-url (command line flag) nor CAS_URL (environment variable) have been set."
+		require.Contains(t, err.Error(), errMsg)
+	})
+
+	t.Run("test invalid enable transparency log arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{"--" + hostURLFlagName, "test", "--" + vctLogEnabledFlagName, "invalid"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for enable-vct-log")
+	})
+
+	t.Run("test VCT URL and built-in transparency log", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "test", "--" + vctURLFlagName, "test", "--" + vctLogEnabledFlagName, "true",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.EqualError(t, err, "vct-url and enable-vct-log are mutually exclusive")
+	})
+
+	t.Run("test blank cas url arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{"--" + hostURLFlagName, "test", "--" + casURLFlagName, "", "--" + vctURLFlagName, "test"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "cas-url value is empty", err.Error())
+	})
+
+	t.Run("test blank did namespace arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "test", "--" + casURLFlagName,
+			"test", "--" + vctURLFlagName, "test", "--" + didNamespaceFlagName, "",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "did-namespace value is empty", err.Error())
+	})
+
+	t.Run("test blank database type arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "test", "--" + casURLFlagName, "test", "--" + vctURLFlagName, "test",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, "",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "database-type value is empty", err.Error())
+	})
+}
+
+func TestStartCmdWithMissingArg(t *testing.T) {
+	t.Run("test missing host url arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Equal(t,
+			"Neither host-url (command line flag) nor ORB_HOST_URL (environment variable) have been set.",
+			err.Error())
+	})
+	t.Run("test missing cas url arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + vctURLFlagName, "test"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Equal(t,
+			"Neither cas-url (command line flag) nor CAS_URL (environment variable) have been set.",
+			err.Error())
+	})
+	t.Run("test missing anchor credential issuer arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + didNamespaceFlagName, "namespace",
+			"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Equal(t,
+			"Neither anchor-credential-issuer (command line flag) nor ANCHOR_CREDENTIAL_ISSUER (environment variable) have been set.",
+			err.Error())
+	})
+	t.Run("test missing anchor credential domain arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + didNamespaceFlagName, "namespace",
+			"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Equal(t,
+			"Neither anchor-credential-domain (command line flag) nor ANCHOR_CREDENTIAL_DOMAIN (environment variable) have been set.",
+			err.Error())
+	})
+
+	t.Run("test missing anchor credential url", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + didNamespaceFlagName, "namespace",
+			"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Equal(t,
+			"Neither anchor-credential-url (command line flag) nor ANCHOR_CREDENTIAL_URL (environment variable) have been set.",
+			err.Error())
+	})
+	t.Run("test missing anchor credential signature suite arg", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + didNamespaceFlagName, "namespace",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+		}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Equal(t,
+			"Neither anchor-credential-signature-suite (command line flag) nor ANCHOR_CREDENTIAL_SIGNATURE_SUITE (environment variable) have been set.",
+			err.Error())
+	})
+
+	t.Run("test invalid batch writer timeout", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + batchWriterTimeoutFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid batch writer timeout format")
+	})
+
+	t.Run("test invalid max witness delay", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + maxWitnessDelayFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid max witness delay format")
+	})
+
+	t.Run("test invalid actor cache TTL", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + actorCacheTTLFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid actor cache TTL format")
+	})
+
+	t.Run("test invalid key rotation interval", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + keyRotationIntervalFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid key rotation interval format")
+	})
+
+	t.Run("test invalid key rotation overlap", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + keyRotationOverlapFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid key rotation overlap format")
+	})
+
+	t.Run("test invalid inbox max clock skew", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + inboxMaxClockSkewFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid inbox max clock skew format")
+	})
+
+	t.Run("test invalid processed activity retention", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + processedActivityRetentionFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid processed activity retention format")
+	})
+
+	t.Run("test invalid activity retention", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + activityRetentionFlagName, "inbox=abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid activity retention")
+	})
+
+	t.Run("test invalid activity retention interval", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + activityRetentionFlagName, "inbox=60:",
+			"--" + activityRetentionIntervalFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid activity retention interval format")
+	})
+
+	t.Run("test invalid follow backfill enabled", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + followBackfillEnabledFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+followBackfillEnabledFlagName)
+	})
+
+	t.Run("test invalid follow backfill retry interval", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + followBackfillRetryIntervalFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
+
 		require.Error(t, err)
-		require.Equal(t, "host-url value is empty", err.Error())
+		require.Contains(t, err.Error(), "invalid follow backfill retry interval format")
 	})
 
-	t.Run("test blank cas url arg", func(t *testing.T) {
+	t.Run("test invalid broken promise webhook URL", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
-		args := []string{"--" + hostURLFlagName, "test"}
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + brokenPromiseWebhookURLFlagName, "invalid url",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
-		require.Error(t, err)
 
-		const errMsg = "vct-url (command line flag) nor ORB_VCT_URL (environment variable) have been set."
-		require.Contains(t, err.Error(), errMsg)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+brokenPromiseWebhookURLFlagName)
 	})
 
-	t.Run("test blank cas url arg", func(t *testing.T) {
+	t.Run("test invalid notify broken promise", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
-		args := []string{"--" + hostURLFlagName, "test", "--" + casURLFlagName, "", "--" + vctURLFlagName, "test"}
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + notifyBrokenPromiseFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
+
 		require.Error(t, err)
-		require.Equal(t, "cas-url value is empty", err.Error())
+		require.Contains(t, err.Error(), "invalid value for "+notifyBrokenPromiseFlagName)
 	})
 
-	t.Run("test blank did namespace arg", func(t *testing.T) {
+	t.Run("test invalid monitoring min poll interval", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
 		args := []string{
-			"--" + hostURLFlagName, "test", "--" + casURLFlagName,
-			"test", "--" + vctURLFlagName, "test", "--" + didNamespaceFlagName, "",
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + monitoringMinPollIntervalFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
 		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
+
 		require.Error(t, err)
-		require.Equal(t, "did-namespace value is empty", err.Error())
+		require.Contains(t, err.Error(), "invalid monitoring min poll interval format")
 	})
 
-	t.Run("test blank database type arg", func(t *testing.T) {
+	t.Run("test invalid monitoring max poll interval", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
 		args := []string{
-			"--" + hostURLFlagName, "test", "--" + casURLFlagName, "test", "--" + vctURLFlagName, "test",
-			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, "",
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + monitoringMaxPollIntervalFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
 		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
+
 		require.Error(t, err)
-		require.Equal(t, "database-type value is empty", err.Error())
+		require.Contains(t, err.Error(), "invalid monitoring max poll interval format")
 	})
-}
 
-func TestStartCmdWithMissingArg(t *testing.T) {
-	t.Run("test missing host url arg", func(t *testing.T) {
+	t.Run("test monitoring max poll interval less than min", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + monitoringMinPollIntervalFlagName, "30",
+			"--" + monitoringMaxPollIntervalFlagName, "10",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
+		startCmd.SetArgs(args)
+
 		err := startCmd.Execute()
 
 		require.Error(t, err)
-		require.Equal(t,
-			"Neither host-url (command line flag) nor ORB_HOST_URL (environment variable) have been set.",
-			err.Error())
+		require.Contains(t, err.Error(), monitoringMaxPollIntervalFlagName+" must not be less than "+monitoringMinPollIntervalFlagName)
 	})
-	t.Run("test missing cas url arg", func(t *testing.T) {
+
+	t.Run("test invalid outbox poll interval", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
-		args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + vctURLFlagName, "test"}
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + outboxPollIntervalFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
 
 		require.Error(t, err)
-		require.Equal(t,
-			"Neither cas-url (command line flag) nor CAS_URL (environment variable) have been set.",
-			err.Error())
+		require.Contains(t, err.Error(), "invalid outbox poll interval format")
 	})
-	t.Run("test missing anchor credential issuer arg", func(t *testing.T) {
+
+	t.Run("test invalid outbox delivery workers", func(t *testing.T) {
+		for _, flag := range []string{outboxMaxDeliveryWorkersFlagName, outboxMaxDeliveriesPerHostFlagName} {
+			startCmd := GetStartCmd()
+
+			args := []string{
+				"--" + hostURLFlagName, "localhost:8247",
+				"--" + vctURLFlagName, "localhost:8081",
+				"--" + externalEndpointFlagName, "orb.example.com",
+				"--" + casURLFlagName, "localhost:8081",
+				"--" + flag, "0",
+				"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+				"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+				"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+				"--" + anchorCredentialDomainFlagName, "domain.com",
+				"--" + anchorCredentialIssuerFlagName, "issuer.com",
+				"--" + anchorCredentialURLFlagName, "peer.com",
+				"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+			}
+
+			startCmd.SetArgs(args)
+
+			err := startCmd.Execute()
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid value for "+flag)
+		}
+	})
+
+	t.Run("test invalid announce batch size", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
 		args := []string{
-			"--" + hostURLFlagName, "localhost:8080",
-			"--" + casURLFlagName, "localhost:8081",
+			"--" + hostURLFlagName, "localhost:8247",
 			"--" + vctURLFlagName, "localhost:8081",
-			"--" + didNamespaceFlagName, "namespace",
-			"--" + databaseTypeFlagName, databaseTypeMemOption,
-			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + announceBatchSizeFlagName, "0",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
 			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
 			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
 		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
 
 		require.Error(t, err)
-		require.Equal(t,
-			"Neither anchor-credential-issuer (command line flag) nor ANCHOR_CREDENTIAL_ISSUER (environment variable) have been set.",
-			err.Error())
+		require.Contains(t, err.Error(), "invalid value for "+announceBatchSizeFlagName)
 	})
-	t.Run("test missing anchor credential domain arg", func(t *testing.T) {
+
+	t.Run("test invalid witness selection count", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
 		args := []string{
-			"--" + hostURLFlagName, "localhost:8080",
-			"--" + casURLFlagName, "localhost:8081",
+			"--" + hostURLFlagName, "localhost:8247",
 			"--" + vctURLFlagName, "localhost:8081",
-			"--" + didNamespaceFlagName, "namespace",
-			"--" + databaseTypeFlagName, databaseTypeMemOption,
-			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + witnessSelectionCountFlagName, "-1",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
 			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
 			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
 		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
 
 		require.Error(t, err)
-		require.Equal(t,
-			"Neither anchor-credential-domain (command line flag) nor ANCHOR_CREDENTIAL_DOMAIN (environment variable) have been set.",
-			err.Error())
+		require.Contains(t, err.Error(), "invalid value for "+witnessSelectionCountFlagName)
 	})
 
-	t.Run("test missing anchor credential url", func(t *testing.T) {
+	t.Run("test invalid announce batch window", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
 		args := []string{
-			"--" + hostURLFlagName, "localhost:8080",
-			"--" + casURLFlagName, "localhost:8081",
+			"--" + hostURLFlagName, "localhost:8247",
 			"--" + vctURLFlagName, "localhost:8081",
-			"--" + didNamespaceFlagName, "namespace",
-			"--" + databaseTypeFlagName, databaseTypeMemOption,
-			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + announceBatchWindowFlagName, "abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
 			"--" + anchorCredentialDomainFlagName, "domain.com",
 			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
 		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
 
 		require.Error(t, err)
-		require.Equal(t,
-			"Neither anchor-credential-url (command line flag) nor ANCHOR_CREDENTIAL_URL (environment variable) have been set.",
-			err.Error())
+		require.Contains(t, err.Error(), "invalid announce batch window format")
 	})
-	t.Run("test missing anchor credential signature suite arg", func(t *testing.T) {
+
+	t.Run("test invalid inbox rate limit", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
 		args := []string{
-			"--" + hostURLFlagName, "localhost:8080",
-			"--" + casURLFlagName, "localhost:8081",
-			"--" + didNamespaceFlagName, "namespace",
+			"--" + hostURLFlagName, "localhost:8247",
 			"--" + vctURLFlagName, "localhost:8081",
-			"--" + databaseTypeFlagName, databaseTypeMemOption,
-			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + inboxRateLimitFlagName, "follower=abc",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
 			"--" + anchorCredentialDomainFlagName, "domain.com",
 			"--" + anchorCredentialIssuerFlagName, "issuer.com",
 			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
 		}
+
 		startCmd.SetArgs(args)
 
 		err := startCmd.Execute()
 
 		require.Error(t, err)
-		require.Equal(t,
-			"Neither anchor-credential-signature-suite (command line flag) nor ANCHOR_CREDENTIAL_SIGNATURE_SUITE (environment variable) have been set.",
-			err.Error())
+		require.Contains(t, err.Error(), "invalid inbox rate limit")
 	})
 
-	t.Run("test invalid batch writer timeout", func(t *testing.T) {
+	t.Run("test invalid inbox offer rate limit", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
 		args := []string{
@@ -216,7 +834,7 @@ func TestStartCmdWithMissingArg(t *testing.T) {
 			"--" + vctURLFlagName, "localhost:8081",
 			"--" + externalEndpointFlagName, "orb.example.com",
 			"--" + casURLFlagName, "localhost:8081",
-			"--" + batchWriterTimeoutFlagName, "abc",
+			"--" + inboxOfferRateLimitFlagName, "unknown=1:1",
 			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
 			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
 			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
@@ -231,10 +849,10 @@ func TestStartCmdWithMissingArg(t *testing.T) {
 		err := startCmd.Execute()
 
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "invalid batch writer timeout format")
+		require.Contains(t, err.Error(), "invalid inbox offer rate limit")
 	})
 
-	t.Run("test invalid max witness delay", func(t *testing.T) {
+	t.Run("test invalid inbox domain rate limit", func(t *testing.T) {
 		startCmd := GetStartCmd()
 
 		args := []string{
@@ -242,7 +860,7 @@ func TestStartCmdWithMissingArg(t *testing.T) {
 			"--" + vctURLFlagName, "localhost:8081",
 			"--" + externalEndpointFlagName, "orb.example.com",
 			"--" + casURLFlagName, "localhost:8081",
-			"--" + maxWitnessDelayFlagName, "abc",
+			"--" + inboxDomainRateLimitFlagName, "1",
 			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
 			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
 			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
@@ -257,7 +875,7 @@ func TestStartCmdWithMissingArg(t *testing.T) {
 		err := startCmd.Execute()
 
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "invalid max witness delay format")
+		require.Contains(t, err.Error(), "invalid inbox domain rate limit")
 	})
 
 	t.Run("test invalid sign with local witness flag", func(t *testing.T) {
@@ -337,6 +955,31 @@ func TestStartCmdWithMissingArg(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "invalid value for enable-http-signatures")
 	})
+	t.Run("test invalid http-signatures-legacy-algorithm", func(t *testing.T) {
+		startCmd := GetStartCmd()
+
+		args := []string{
+			"--" + hostURLFlagName, "localhost:8247",
+			"--" + vctURLFlagName, "localhost:8081",
+			"--" + externalEndpointFlagName, "orb.example.com",
+			"--" + casURLFlagName, "localhost:8081",
+			"--" + didNamespaceFlagName, "namespace", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+			"--" + anchorCredentialSignatureSuiteFlagName, "suite",
+			"--" + anchorCredentialDomainFlagName, "domain.com",
+			"--" + anchorCredentialIssuerFlagName, "issuer.com",
+			"--" + anchorCredentialURLFlagName, "peer.com",
+			"--" + LogLevelFlagName, log.ParseString(log.ERROR),
+			"--" + httpSignaturesLegacyAlgorithmFlagName, "invalid bool",
+		}
+
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for http-signatures-legacy-algorithm")
+	})
 }
 
 func TestStartCmdWithBlankEnvVar(t *testing.T) {
@@ -558,3 +1201,71 @@ func checkFlagPropertiesCorrect(t *testing.T, cmd *cobra.Command, flagName, flag
 	flagAnnotations := flag.Annotations
 	require.Nil(t, flagAnnotations)
 }
+
+func TestParseRateLimits(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		limits, err := parseRateLimits("none=1:10, follower=10.5:50,witnessing=20:100")
+		require.NoError(t, err)
+		require.Len(t, limits, 3)
+		require.Equal(t, ratelimiter.Limit{Rate: 1, Burst: 10}, limits[ratelimiter.RelationshipNone])
+		require.Equal(t, ratelimiter.Limit{Rate: 10.5, Burst: 50}, limits[ratelimiter.RelationshipFollower])
+		require.Equal(t, ratelimiter.Limit{Rate: 20, Burst: 100}, limits[ratelimiter.RelationshipWitnessing])
+
+		limits, err = parseRateLimits("")
+		require.NoError(t, err)
+		require.Empty(t, limits)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := parseRateLimits("follower")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "expecting relationship=rate:burst")
+
+		_, err = parseRateLimits("following=1:1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported relationship")
+
+		_, err = parseRateLimits("follower=x:1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid rate")
+
+		_, err = parseRateLimits("follower=1:x")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid burst")
+	})
+}
+
+func TestParseRetentionPolicies(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		policies, err := parseRetentionPolicies("inbox=3600:100, outbox=60:,like=:10,liked=:,share=:5")
+		require.NoError(t, err)
+		require.Len(t, policies, 5)
+		require.Equal(t, retention.Policy{MaxAge: time.Hour, MaxCount: 100}, policies[activitypubspi.Inbox])
+		require.Equal(t, retention.Policy{MaxAge: time.Minute}, policies[activitypubspi.Outbox])
+		require.Equal(t, retention.Policy{MaxCount: 10}, policies[activitypubspi.Like])
+		require.Equal(t, retention.Policy{}, policies[activitypubspi.Liked])
+		require.Equal(t, retention.Policy{MaxCount: 5}, policies[activitypubspi.Share])
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := parseRetentionPolicies("inbox")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "expecting collection=maxAge:maxCount")
+
+		_, err = parseRetentionPolicies("followers=1:1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported collection")
+
+		_, err = parseRetentionPolicies("inbox=1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "expecting maxAge:maxCount")
+
+		_, err = parseRetentionPolicies("inbox=x:1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid max age")
+
+		_, err = parseRetentionPolicies("inbox=1:x")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid max count")
+	})
+}
@@ -8,11 +8,8 @@ package startcmd
 
 import (
 	"context"
-	"crypto/ed25519"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"net/http"
@@ -45,7 +42,6 @@ import (
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/spf13/cobra"
 	"github.com/trustbloc/edge-core/pkg/log"
-	"github.com/trustbloc/orb/pkg/activitypub/client"
 	"github.com/trustbloc/orb/pkg/activitypub/httpsig"
 	casapi "github.com/trustbloc/sidetree-core-go/pkg/api/cas"
 	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
@@ -58,18 +54,20 @@ import (
 	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
 	aphandler "github.com/trustbloc/orb/pkg/activitypub/resthandler"
 	apservice "github.com/trustbloc/orb/pkg/activitypub/service"
+	"github.com/trustbloc/orb/pkg/activitypub/service/actorresolver"
 	"github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
+	"github.com/trustbloc/orb/pkg/activitypub/service/retention"
 	apspi "github.com/trustbloc/orb/pkg/activitypub/service/spi"
 	"github.com/trustbloc/orb/pkg/activitypub/service/vct"
 	apariesstore "github.com/trustbloc/orb/pkg/activitypub/store/ariesstore"
 	apmemstore "github.com/trustbloc/orb/pkg/activitypub/store/memstore"
 	activitypubspi "github.com/trustbloc/orb/pkg/activitypub/store/spi"
-	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 	"github.com/trustbloc/orb/pkg/anchor/builder"
 	"github.com/trustbloc/orb/pkg/anchor/graph"
 	"github.com/trustbloc/orb/pkg/anchor/handler/credential"
 	"github.com/trustbloc/orb/pkg/anchor/handler/proof"
 	anchorinfo "github.com/trustbloc/orb/pkg/anchor/info"
+	"github.com/trustbloc/orb/pkg/anchor/witness/policy"
 	"github.com/trustbloc/orb/pkg/anchor/writer"
 	"github.com/trustbloc/orb/pkg/config"
 	sidetreecontext "github.com/trustbloc/orb/pkg/context"
@@ -79,8 +77,10 @@ import (
 	orbpc "github.com/trustbloc/orb/pkg/context/protocol/client"
 	orbpcp "github.com/trustbloc/orb/pkg/context/protocol/provider"
 	localdiscovery "github.com/trustbloc/orb/pkg/discovery/did/local"
+	discoveryclient "github.com/trustbloc/orb/pkg/discovery/endpoint/client"
 	discoveryrest "github.com/trustbloc/orb/pkg/discovery/endpoint/restapi"
 	"github.com/trustbloc/orb/pkg/httpserver"
+	"github.com/trustbloc/orb/pkg/keyrotation"
 	"github.com/trustbloc/orb/pkg/observer"
 	"github.com/trustbloc/orb/pkg/protocolversion/factoryregistry"
 	"github.com/trustbloc/orb/pkg/resolver/document"
@@ -89,6 +89,7 @@ import (
 	vcstore "github.com/trustbloc/orb/pkg/store/verifiable"
 	proofstore "github.com/trustbloc/orb/pkg/store/witness"
 	"github.com/trustbloc/orb/pkg/vcsigner"
+	"github.com/trustbloc/orb/pkg/vctlog"
 	"github.com/trustbloc/orb/pkg/webcas"
 )
 
@@ -102,6 +103,14 @@ const (
 	noStartupDelay = 0 * time.Second // no delay
 
 	defaulthttpSignaturesEnabled = true
+
+	defaultFollowBackfillEnabled = true
+
+	defaultMonitoringMinPollInterval = time.Second
+	defaultMonitoringMaxPollInterval = time.Minute
+
+	// mysqlMaxKeyLength is the maximum length of a key in the MySQL storage provider (varchar(255)).
+	mysqlMaxKeyLength = 255
 )
 
 var logger = log.New("orb-server")
@@ -122,6 +131,8 @@ const (
 
 	webKeyStoreKey = "web-key-store"
 	kidKey         = "kid"
+	vctLogKIDKey   = "vct-log-kid"
+	apKeyIDKey     = "ap-key-id"
 )
 
 type server interface {
@@ -241,6 +252,42 @@ func createKID(km kms.KeyManager, parameters *orbParameters, cfg storage.Store)
 	})
 }
 
+func createVCTLog(parameters *orbParameters, km kms.KeyManager, cr acrypto.Crypto, provider storage.Provider,
+	cfg storage.Store, signer *vcsigner.Signer, documentLoader *jld.DocumentLoader) (*vctlog.Log, error) {
+	var keyID string
+
+	err := getOrInit(cfg, vctLogKIDKey, &keyID, func() (interface{}, error) {
+		keyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+
+		return keyID, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get or init transparency log key: %w", err)
+	}
+
+	return vctlog.New(&vctlog.Config{
+		Endpoint:       parameters.externalEndpoint,
+		KeyID:          keyID,
+		KeyType:        kms.ECDSAP256TypeIEEEP1363,
+		DocumentLoader: documentLoader,
+		WriterID:       vctLogWriterID(parameters.hostURL),
+	}, provider, km, cr, signer)
+}
+
+// vctLogWriterID returns the identity of this server as the writer of the transparency log. The ID is derived
+// from the host name and the host URL so that a restarted server may re-claim its own lease on the log.
+// An empty ID (i.e. a random ID) is returned if the host name can't be resolved.
+func vctLogWriterID(hostURL string) string {
+	hostName, err := os.Hostname()
+	if err != nil {
+		logger.Warnf("Unable to resolve the host name for the transparency log writer ID: %s", err)
+
+		return ""
+	}
+
+	return hostName + "/" + hostURL
+}
+
 // nolint: gocyclo,funlen,gocognit
 func startOrbServices(parameters *orbParameters) error {
 	if parameters.logLevel != "" {
@@ -318,10 +365,20 @@ func startOrbServices(parameters *orbParameters) error {
 		return fmt.Errorf("failed to get protocol client for namespace [%s]: %s", parameters.didNamespace, err.Error())
 	}
 
+	apPublicKeyID := aphandler.MainKeyID
+
 	if parameters.keyID == "" {
 		if err = createKID(km, parameters, configStore); err != nil {
 			return fmt.Errorf("create kid: %w", err)
 		}
+
+		// The ID of the ActivityPub public key changes when the key is rotated.
+		err = getOrInit(configStore, apKeyIDKey, &apPublicKeyID, func() (interface{}, error) {
+			return aphandler.MainKeyID, nil
+		})
+		if err != nil {
+			return fmt.Errorf("get ActivityPub key ID: %w", err)
+		}
 	}
 
 	u, err := url.Parse(parameters.externalEndpoint)
@@ -329,6 +386,30 @@ func startOrbServices(parameters *orbParameters) error {
 		return fmt.Errorf("parse external endpoint: %w", err)
 	}
 
+	apServiceIRI := mustParseURL(parameters.externalEndpoint, activityPubServicesPath)
+
+	var activityPubService *apservice.Service
+
+	var vcSigner *vcsigner.Signer
+
+	keyManager, err := keyrotation.New(
+		&keyrotation.Config{
+			ServiceIRI:       apServiceIRI,
+			KeyType:          kmsKeyType,
+			RotationInterval: parameters.keyRotationInterval,
+			OverlapPeriod:    parameters.keyRotationOverlap,
+		},
+		km, configStore, parameters.keyID, apPublicKeyID,
+		func() keyrotation.Outbox { return activityPubService.Outbox() },
+		newKeyActivatedHandler(configStore, u.Host, func() *vcsigner.Signer { return vcSigner }),
+	)
+	if err != nil {
+		return fmt.Errorf("create key rotation manager: %w", err)
+	}
+
+	// The persisted rotation state takes precedence over the configured key ID.
+	parameters.keyID = keyManager.CurrentKey().KMSKeyID
+
 	signingParams := vcsigner.SigningParams{
 		VerificationMethod: "did:web:" + u.Host + "#" + parameters.keyID,
 		Domain:             parameters.anchorCredentialParams.domain,
@@ -341,7 +422,7 @@ func startOrbServices(parameters *orbParameters) error {
 		DocLoader:  orbDocumentLoader,
 	}
 
-	vcSigner, err := vcsigner.New(signingProviders, signingParams)
+	vcSigner, err = vcsigner.New(signingProviders, signingParams)
 	if err != nil {
 		return fmt.Errorf("failed to create vc signer: %s", err.Error())
 	}
@@ -375,27 +456,50 @@ func startOrbServices(parameters *orbParameters) error {
 		return fmt.Errorf("failed to create proof store: %s", err.Error())
 	}
 
+	witnessMetricsStore, err := proofstore.NewMetricsStore(storeProviders.provider)
+	if err != nil {
+		return fmt.Errorf("failed to create witness metrics store: %s", err.Error())
+	}
+
 	opProcessor := processor.New(parameters.didNamespace, opStore, pc)
 
 	casIRI := mustParseURL(parameters.externalEndpoint, casPath)
 
-	apServiceIRI := mustParseURL(parameters.externalEndpoint, activityPubServicesPath)
-
-	apServicePublicKeyIRI := mustParseURL(parameters.externalEndpoint,
-		fmt.Sprintf("%s/keys/%s", activityPubServicesPath, aphandler.MainKeyID))
-
 	apTransactionsIRI := mustParseURL(parameters.externalEndpoint, activityPubTransactionsPath)
 
 	apConfig := &apservice.Config{
 		ServiceEndpoint:        activityPubServicesPath,
 		ServiceIRI:             apServiceIRI,
 		MaxWitnessDelay:        parameters.maxWitnessDelay,
+		ActorCacheTTL:          parameters.actorCacheTTL,
 		VerifyActorInSignature: parameters.httpSignaturesEnabled,
+		RequireDigestAndDate:   parameters.httpSignaturesEnabled,
+		InboxMaxClockSkew:      parameters.inboxMaxClockSkew,
+
+		ProcessedActivityRetention: parameters.processedActivityRetention,
+		InboxRateLimits:            parameters.inboxRateLimits,
+		Backfill:                   parameters.followBackfill,
+		OutboxPolling:              parameters.outboxPolling,
+		OutboxDelivery:             parameters.outboxDelivery,
+		AnnounceBatchSize:          parameters.announceBatchSize,
+		AnnounceBatchWindow:        parameters.announceBatchWindow,
+	}
+
+	if parameters.activityRetention != nil {
+		// Anchor-related activities are retained while the anchor credential is still being witnessed, and
+		// the activities that carry an anchor credential are retained while the credential is stored.
+		parameters.activityRetention.ReferenceCheckers = []retention.ReferenceChecker{
+			retention.NewWitnessChecker(witnessProofStore),
+		}
+		parameters.activityRetention.AnchorChecker = retention.NewAnchorChecker(vcStore)
+
+		apConfig.Retention = parameters.activityRetention
 	}
 
 	var apStore activitypubspi.Store
 
-	if parameters.dbParameters.databaseType == databaseTypeCouchDBOption {
+	switch {
+	case strings.EqualFold(parameters.dbParameters.databaseType, databaseTypeCouchDBOption):
 		couchDBProvider, err := ariescouchdbstorage.NewProvider(parameters.dbParameters.databaseURL,
 			ariescouchdbstorage.WithDBPrefix(parameters.dbParameters.databasePrefix+"_"+apConfig.ServiceEndpoint),
 			ariescouchdbstorage.WithLogger(logger))
@@ -407,27 +511,82 @@ func startOrbServices(parameters *orbParameters) error {
 		if err != nil {
 			return fmt.Errorf("failed to create in-memory storage provider for ActivityPub: %w", err)
 		}
-	} else {
+
+		apConfig.StorageProvider = couchDBProvider
+	case strings.EqualFold(parameters.dbParameters.databaseType, databaseTypeMYSQLDBOption):
+		mysqlProvider, err := ariesmysqlstorage.NewProvider(parameters.dbParameters.databaseURL,
+			ariesmysqlstorage.WithDBPrefix(getMySQLDBPrefix(parameters.dbParameters.databasePrefix,
+				apConfig.ServiceEndpoint)))
+		if err != nil {
+			return fmt.Errorf("failed to create MySQL storage provider for ActivityPub: %w", err)
+		}
+
+		// The MySQL provider doesn't support sorting and paging of query results and keys are limited in length.
+		apStore, err = apariesstore.New(mysqlProvider, apConfig.ServiceEndpoint,
+			apariesstore.WithLimitedQuerySupport(), apariesstore.WithMaxKeyLength(mysqlMaxKeyLength))
+		if err != nil {
+			return fmt.Errorf("failed to create MySQL storage provider for ActivityPub: %w", err)
+		}
+
+		apConfig.StorageProvider = mysqlProvider
+	default:
 		apStore = apmemstore.New(apConfig.ServiceEndpoint)
 	}
 
-	pubKey, err := km.ExportPubKeyBytes(parameters.keyID)
-	if err != nil {
-		return fmt.Errorf("failed to export pub key: %w", err)
+	// The VCT log of this server is either the configured external log or the built-in log.
+	vctURL := parameters.vctURL
+	if vctURL == "" {
+		vctURL = parameters.externalEndpoint
 	}
 
-	publicKey, err := getActivityPubPublicKey(pubKey, apServiceIRI, apServicePublicKeyIRI)
+	discoveryClient := discoveryclient.New(discoveryclient.WithHTTPClient(httpClient))
+
+	logKeyProvider := &discoveryLogKeyProvider{logURL: vctURL, resolver: discoveryClient}
+
+	// create discovery rest api
+	endpointDiscoveryOp, err := discoveryrest.New(&discoveryrest.Config{
+		KeyProvider:               &discoveryKeyProvider{keyManager: keyManager},
+		VerificationMethodType:    verificationMethodType,
+		ResolutionPath:            baseResolvePath,
+		OperationPath:             baseUpdatePath,
+		BaseURL:                   parameters.externalEndpoint,
+		DiscoveryDomains:          parameters.discoveryDomains,
+		DiscoveryMinimumResolvers: parameters.discoveryMinimumResolvers,
+		ServiceIRI:                apServiceIRI.String(),
+		VctURL:                    vctURL,
+		LogKeyProvider:            logKeyProvider,
+	})
 	if err != nil {
-		return fmt.Errorf("get public key: %w", err)
+		return fmt.Errorf("discovery rest: %w", err)
 	}
 
-	apGetSigner, apPostSigner := getActivityPubSigners(parameters, km, cr)
+	apGetSigner, apPostSigner := getActivityPubSigners(parameters, km, cr, keyManager)
 
-	t := transport.New(httpClient, apServicePublicKeyIRI, apGetSigner, apPostSigner)
+	t := transport.NewWithPublicKeyIDProvider(httpClient, keyManager, apGetSigner, apPostSigner,
+		transport.WithCircuitBreaker(transport.NewCircuitBreaker(&transport.CircuitBreakerConfig{})),
+	)
 
-	apSigVerifier := getActivityPubVerifier(parameters, km, cr, t)
+	apSigVerifier := getActivityPubVerifier(parameters, km, cr, apStore, t)
 
-	monitoringSvc, err := monitoring.New(storeProviders.provider, vcStore, monitoring.WithHTTPClient(httpClient))
+	monitoringOpts := []monitoring.Opt{
+		monitoring.WithHTTPClient(httpClient),
+		monitoring.WithLogResolver(discoveryClient),
+		monitoring.WithPollInterval(parameters.monitoring.minPollInterval, parameters.monitoring.maxPollInterval),
+	}
+
+	if parameters.monitoring.brokenPromiseWebhookURL != "" {
+		monitoringOpts = append(monitoringOpts, monitoring.WithBrokenPromiseHandler(
+			monitoring.NewWebhookHandler(parameters.monitoring.brokenPromiseWebhookURL, httpClient),
+		))
+	}
+
+	if parameters.monitoring.notifyBrokenPromise {
+		monitoringOpts = append(monitoringOpts, monitoring.WithBrokenPromiseHandler(
+			monitoring.NewWitnessNotifier(apServiceIRI, func() monitoring.Outbox { return activityPubService.Outbox() }),
+		))
+	}
+
+	monitoringSvc, err := monitoring.New(storeProviders.provider, vcStore, monitoringOpts...)
 	if err != nil {
 		return fmt.Errorf("monitoring: %w", err)
 	}
@@ -436,18 +595,44 @@ func startOrbServices(parameters *orbParameters) error {
 
 	proofHandler := proof.New(
 		&proof.Providers{
-			Store:         vcStore,
-			MonitoringSvc: monitoringSvc,
-			DocLoader:     orbDocumentLoader,
-			WitnessStore:  witnessProofStore,
+			Store:          vcStore,
+			MonitoringSvc:  monitoringSvc,
+			DocLoader:      orbDocumentLoader,
+			WitnessStore:   witnessProofStore,
+			Pkf:            graphProviders.Pkf,
+			WitnessMetrics: witnessMetricsStore,
+			LogResolver:    discoveryClient,
 		},
 		vcCh)
 
-	witness := vct.New(parameters.vctURL, vcSigner,
-		vct.WithHTTPClient(httpClient),
-		vct.WithDocumentLoader(orbDocumentLoader))
+	var (
+		witness apspi.WitnessHandler
+		vctLog  *vctlog.Log
+	)
+
+	if parameters.vctLogEnabled {
+		vctLog, err = createVCTLog(parameters, km, cr, storeProviders.provider, configStore, vcSigner,
+			orbDocumentLoader)
+		if err != nil {
+			return fmt.Errorf("create transparency log: %w", err)
+		}
+
+		vctLog.Start()
+		defer vctLog.Stop()
+
+		logger.Infof("Using the built-in transparency log at %s", parameters.externalEndpoint)
+
+		logKeyProvider.log = vctLog
+
+		witness = vctLog
+	} else {
+		witness = vct.New(parameters.vctURL, vcSigner,
+			vct.WithHTTPClient(httpClient),
+			vct.WithDocumentLoader(orbDocumentLoader),
+			vct.WithPublicKeyResolver(discoveryClient))
+	}
 
-	activityPubService, err := apservice.New(apConfig,
+	activityPubService, err = apservice.New(apConfig,
 		apStore, t, apSigVerifier,
 		apspi.WithProofHandler(proofHandler),
 		apspi.WithWitness(witness),
@@ -473,6 +658,15 @@ func startOrbServices(parameters *orbParameters) error {
 		MonitoringSvc:   monitoringSvc,
 		ActivityStore:   apStore,
 		WitnessStore:    witnessProofStore,
+		WitnessMetrics:  witnessMetricsStore,
+	}
+
+	if parameters.witnessSelectionCount > 0 {
+		logger.Infof("Anchor credentials are offered to the %d healthiest system witnesses",
+			parameters.witnessSelectionCount)
+
+		anchorWriterProviders.WitnessPolicy = policy.NewHealthiest(parameters.witnessSelectionCount,
+			witnessMetricsStore, monitoringSvc)
 	}
 
 	anchorWriter := writer.New(parameters.didNamespace,
@@ -504,6 +698,9 @@ func startOrbServices(parameters *orbParameters) error {
 	observer.New(providers).Start()
 	logger.Infof("started observer")
 
+	keyManager.Start()
+	defer keyManager.Stop()
+
 	didDocHandler := dochandler.New(
 		parameters.didNamespace,
 		parameters.didAliases,
@@ -532,28 +729,15 @@ func startOrbServices(parameters *orbParameters) error {
 		localdiscovery.New(didCh),
 	)
 
-	// create discovery rest api
-	endpointDiscoveryOp, err := discoveryrest.New(&discoveryrest.Config{
-		PubKey:                    pubKey,
-		VerificationMethodType:    verificationMethodType,
-		KID:                       parameters.keyID,
-		ResolutionPath:            baseResolvePath,
-		OperationPath:             baseUpdatePath,
-		BaseURL:                   parameters.externalEndpoint,
-		DiscoveryDomains:          parameters.discoveryDomains,
-		DiscoveryMinimumResolvers: parameters.discoveryMinimumResolvers,
-	})
-	if err != nil {
-		return fmt.Errorf("discovery rest: %w", err)
-	}
-
 	handlers := make([]restcommon.HTTPHandler, 0)
 
 	handlers = append(handlers, diddochandler.NewUpdateHandler(baseUpdatePath, didDocHandler, pc),
 		diddochandler.NewResolveHandler(baseResolvePath, orbResolver),
 		activityPubService.InboxHTTPHandler(),
-		aphandler.NewServices(apEndpointCfg, apStore, publicKey),
-		aphandler.NewPublicKeys(apEndpointCfg, apStore, publicKey),
+		activityPubService.SharedInboxHTTPHandler(),
+		activityPubService.StatsHTTPHandler(),
+		aphandler.NewServicesWithKeyProvider(apEndpointCfg, apStore, keyManager),
+		aphandler.NewPublicKeysWithKeyProvider(apEndpointCfg, apStore, keyManager),
 		aphandler.NewFollowers(apEndpointCfg, apStore, apSigVerifier),
 		aphandler.NewFollowing(apEndpointCfg, apStore, apSigVerifier),
 		aphandler.NewOutbox(apEndpointCfg, apStore, apSigVerifier),
@@ -565,12 +749,34 @@ func startOrbServices(parameters *orbParameters) error {
 		aphandler.NewShares(apTxnCfg, apStore, apSigVerifier),
 		aphandler.NewPostOutbox(apEndpointCfg, activityPubService.Outbox(), apSigVerifier),
 		aphandler.NewActivity(apEndpointCfg, apStore, apSigVerifier),
+		aphandler.NewActivityQuery(apEndpointCfg, apStore, apSigVerifier),
 		webcas.New(casClient),
 	)
 
 	handlers = append(handlers,
 		endpointDiscoveryOp.GetRESTHandlers()...)
 
+	handlers = append(handlers, monitoringSvc.HTTPHandlers()...)
+
+	if vctLog != nil {
+		handlers = append(handlers, vctLog.HTTPHandlers()...)
+	}
+
+	// Backfill may be triggered on demand by an administrator so the endpoint is only enabled when an API token is set.
+	if parameters.followBackfill != nil && parameters.token != "" {
+		handlers = append(handlers, activityPubService.BackfillHTTPHandlers()...)
+	}
+
+	// The activity stream exposes all inbox and outbox activities so it's only enabled when an API token is set.
+	if parameters.token != "" {
+		handlers = append(handlers,
+			aphandler.NewActivityStream(apEndpointCfg, apStore, parameters.token,
+				activityPubService.Subscribe(), activityPubService.SubscribeOutbox()),
+		)
+	} else {
+		logger.Infof("The activity stream is disabled since no API token was specified.")
+	}
+
 	httpServer := httpserver.New(
 		parameters.hostURL,
 		parameters.tlsCertificate,
@@ -654,6 +860,12 @@ type storageProviders struct {
 	kmsSecretsProvider storage.Provider
 }
 
+// getMySQLDBPrefix returns a database prefix for the given service endpoint. MySQL database names may not
+// contain '/' or '.' characters.
+func getMySQLDBPrefix(prefix, serviceEndpoint string) string {
+	return prefix + "_" + strings.NewReplacer("/", "_", ".", "_").Replace(serviceEndpoint)
+}
+
 //nolint: gocyclo
 func createStoreProviders(parameters *orbParameters) (*storageProviders, error) {
 	var edgeServiceProvs storageProviders
@@ -780,22 +992,79 @@ func mustParseURL(basePath, relativePath string) *url.URL {
 	return u
 }
 
-func getActivityPubPublicKey(pubKey []byte, apServiceIRI, apServicePublicKeyIRI *url.URL) (*vocab.PublicKeyType, error) {
-	pubDerKey, err := x509.MarshalPKIXPublicKey(ed25519.PublicKey(pubKey))
+// newKeyActivatedHandler returns a handler that is invoked after the signing key is rotated. The handler persists
+// the new key IDs and updates the verification method of the VC signer. (The did:web document is updated by the
+// discovery key provider.)
+func newKeyActivatedHandler(cfg storage.Store, host string,
+	getVCSigner func() *vcsigner.Signer) keyrotation.KeyActivatedHandler {
+	return func(key *keyrotation.Key) error {
+		if err := putConfig(cfg, kidKey, key.KMSKeyID); err != nil {
+			return err
+		}
+
+		// The KMS key ID is also used as the ID of the ActivityPub public key for rotated keys.
+		if err := putConfig(cfg, apKeyIDKey, key.KMSKeyID); err != nil {
+			return err
+		}
+
+		if err := getVCSigner().SetVerificationMethod("did:web:" + host + "#" + key.KMSKeyID); err != nil {
+			return fmt.Errorf("set verification method: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// discoveryLogKeyProvider provides the public key of the VCT log of this server, which is published in the
+// WebFinger response of the ActivityPub service. The key of an external log is retrieved from the configured
+// log (and cached).
+type discoveryLogKeyProvider struct {
+	logURL   string
+	resolver *discoveryclient.Client
+	log      *vctlog.Log
+}
+
+func (p *discoveryLogKeyProvider) LogPublicKey() ([]byte, error) {
+	if p.log != nil {
+		return p.log.GetPublicKey(), nil
+	}
+
+	return p.resolver.GetLogPublicKey(p.logURL)
+}
+
+// discoveryKeyProvider publishes the active keys of the key rotation manager in the did:web document. Retired
+// keys are published as assertion methods only so that previously issued credentials may still be verified.
+type discoveryKeyProvider struct {
+	keyManager *keyrotation.Manager
+}
+
+func (p *discoveryKeyProvider) PublicKeys() []discoveryrest.PublicKey {
+	active, retired := p.keyManager.VerificationKeys()
+
+	keys := make([]discoveryrest.PublicKey, 0, len(active)+len(retired))
+
+	for _, key := range active {
+		keys = append(keys, discoveryrest.PublicKey{ID: key.KMSKeyID, Value: key.PublicKeyBytes})
+	}
+
+	for _, key := range retired {
+		keys = append(keys, discoveryrest.PublicKey{ID: key.KMSKeyID, Value: key.PublicKeyBytes, AssertionOnly: true})
+	}
+
+	return keys
+}
+
+func putConfig(cfg storage.Store, key string, v interface{}) error {
+	src, err := json.Marshal(v)
 	if err != nil {
-		return nil, fmt.Errorf("marshal pub key: %w", err)
+		return fmt.Errorf("marshal config value for %q: %w", key, err)
 	}
 
-	pemBytes := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: pubDerKey,
-	})
+	if err = cfg.Put(key, src); err != nil {
+		return fmt.Errorf("store config value for %q: %w", key, err)
+	}
 
-	return vocab.NewPublicKey(
-		vocab.WithID(apServicePublicKeyIRI),
-		vocab.WithOwner(apServiceIRI),
-		vocab.WithPublicKeyPem(string(pemBytes)),
-	), nil
+	return nil
 }
 
 type signer interface {
@@ -807,10 +1076,20 @@ type signatureVerifier interface {
 }
 
 func getActivityPubSigners(parameters *orbParameters, km kms.KeyManager,
-	cr acrypto.Crypto) (getSigner signer, postSigner signer) {
+	cr acrypto.Crypto, signingKeys httpsig.SigningKeyResolver) (getSigner signer, postSigner signer) {
 	if parameters.httpSignaturesEnabled {
-		getSigner = httpsig.NewSigner(httpsig.DefaultGetSignerConfig(), cr, km, parameters.keyID)
-		postSigner = httpsig.NewSigner(httpsig.DefaultPostSignerConfig(), cr, km, parameters.keyID)
+		getSignerConfig := httpsig.DefaultGetSignerConfig()
+		postSignerConfig := httpsig.DefaultPostSignerConfig()
+
+		if parameters.httpSignaturesLegacyAlgo {
+			// Previous versions of Orb only accept the legacy algorithm name. The verifier treats it the same as
+			// hs2019, i.e. the algorithm is derived from the type of public key.
+			getSignerConfig.Algorithm = httpsig.AlgorithmLegacyOrb
+			postSignerConfig.Algorithm = httpsig.AlgorithmLegacyOrb
+		}
+
+		getSigner = httpsig.NewSignerWithKeyResolver(getSignerConfig, cr, km, signingKeys)
+		postSigner = httpsig.NewSignerWithKeyResolver(postSignerConfig, cr, km, signingKeys)
 	} else {
 		getSigner = &transport.NoOpSigner{}
 		postSigner = &transport.NoOpSigner{}
@@ -824,9 +1103,17 @@ type httpTransport interface {
 }
 
 func getActivityPubVerifier(parameters *orbParameters, km kms.KeyManager,
-	cr acrypto.Crypto, t httpTransport) signatureVerifier {
+	cr acrypto.Crypto, apStore activitypubspi.Store, t httpTransport) signatureVerifier {
 	if parameters.httpSignaturesEnabled {
-		return httpsig.NewVerifier(client.New(t), cr, km)
+		resolver := actorresolver.New(
+			&actorresolver.Config{
+				ServiceName: activityPubServicesPath,
+				TTL:         parameters.actorCacheTTL,
+			},
+			apStore, t,
+		)
+
+		return httpsig.NewVerifier(resolver, cr, km)
 	}
 
 	logger.Warnf("HTTP signature verification for ActivityPub is disabled.")
@@ -11,6 +11,9 @@ import (
 
 	ariesmockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
+	"github.com/trustbloc/orb/pkg/activitypub/httpsig"
 )
 
 func TestCreateProviders(t *testing.T) {
@@ -143,3 +146,30 @@ func TestPrepareMasterKeyReader(t *testing.T) {
 		require.Contains(t, err.Error(), "open key.file: no such file or directory")
 	})
 }
+
+func TestGetMySQLDBPrefix(t *testing.T) {
+	require.Equal(t, "orb__services_orb", getMySQLDBPrefix("orb", "/services/orb"))
+	require.Equal(t, "orb__services_orb_v1_0", getMySQLDBPrefix("orb", "/services/orb/v1.0"))
+}
+
+func TestGetActivityPubSigners(t *testing.T) {
+	t.Run("hs2019", func(t *testing.T) {
+		getSigner, postSigner := getActivityPubSigners(&orbParameters{httpSignaturesEnabled: true}, nil, nil, nil)
+		require.Equal(t, httpsig.AlgorithmHS2019, getSigner.(*httpsig.Signer).Algorithm)
+		require.Equal(t, httpsig.AlgorithmHS2019, postSigner.(*httpsig.Signer).Algorithm)
+	})
+
+	t.Run("legacy algorithm", func(t *testing.T) {
+		getSigner, postSigner := getActivityPubSigners(
+			&orbParameters{httpSignaturesEnabled: true, httpSignaturesLegacyAlgo: true}, nil, nil, nil,
+		)
+		require.Equal(t, httpsig.AlgorithmLegacyOrb, getSigner.(*httpsig.Signer).Algorithm)
+		require.Equal(t, httpsig.AlgorithmLegacyOrb, postSigner.(*httpsig.Signer).Algorithm)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		getSigner, postSigner := getActivityPubSigners(&orbParameters{}, nil, nil, nil)
+		require.IsType(t, &transport.NoOpSigner{}, getSigner)
+		require.IsType(t, &transport.NoOpSigner{}, postSigner)
+	})
+}
@@ -11,6 +11,7 @@ require (
 	github.com/cenkalti/backoff/v4 v4.1.0
 	github.com/go-kivik/couchdb/v3 v3.2.7 // indirect
 	github.com/go-kivik/kivik/v3 v3.2.3
+	github.com/google/trillian v1.3.13
 	github.com/google/uuid v1.2.0
 	github.com/gorilla/mux v1.8.0
 	github.com/hyperledger/aries-framework-go v0.1.7-0.20210429013345-a595aa0b19c4
@@ -9,6 +9,7 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -25,12 +26,42 @@ var logger = log.New("activitypub_client")
 // ErrNotFound is returned when the object is not found or the iterator has reached the end.
 var ErrNotFound = fmt.Errorf("not found")
 
+// ErrNotModified is returned from a conditional request when the object has not changed
+// since it was last retrieved.
+var ErrNotModified = errors.New("not modified")
+
+const (
+	etagHeader            = "ETag"
+	lastModifiedHeader    = "Last-Modified"
+	ifNoneMatchHeader     = "If-None-Match"
+	ifModifiedSinceHeader = "If-Modified-Since"
+)
+
 // ReferenceIterator iterates over all of the references in a result set.
 type ReferenceIterator interface {
 	Next() (*url.URL, error)
 	TotalItems() int
 }
 
+// ActivityIterator iterates over all of the activities in a result set.
+type ActivityIterator interface {
+	Next() (*vocab.ActivityType, error)
+	TotalItems() int
+}
+
+// Order specifies the order in which the items of a collection are traversed.
+type Order string
+
+const (
+	// Forward traverses the collection from the first page to the last page.
+	Forward Order = "forward"
+
+	// Reverse traverses the collection from the last page to the first page and returns the items of
+	// each page in reverse order. For a collection of activities that's sorted by most recent first
+	// (such as an outbox) the oldest activity is returned first.
+	Reverse Order = "reverse"
+)
+
 type httpTransport interface {
 	Get(ctx context.Context, req *transport.Request) (*http.Response, error)
 }
@@ -68,6 +99,43 @@ func (c *Client) GetActor(actorIRI *url.URL) (*vocab.ActorType, error) {
 	return actor, nil
 }
 
+// CacheValidators holds the validators (ETag and Last-Modified) returned by the remote server along
+// with an object. The validators may be used in a subsequent conditional request for the same object.
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// GetActorIfModified retrieves the actor at the given IRI using a conditional request. If the given validators
+// are provided and the remote server indicates that the actor has not changed then ErrNotModified is returned.
+// The validators returned by the server are returned along with the actor.
+//nolint:interfacer
+func (c *Client) GetActorIfModified(actorIRI *url.URL,
+	validators *CacheValidators) (*vocab.ActorType, *CacheValidators, error) {
+	respBytes, header, err := c.getWithHeader(actorIRI, newConditionalHeader(validators))
+	if err != nil {
+		if errors.Is(err, ErrNotModified) {
+			return nil, validators, err
+		}
+
+		return nil, nil, fmt.Errorf("error reading response from %s: %w", actorIRI, err)
+	}
+
+	logger.Debugf("Got response from %s: %s", actorIRI, respBytes)
+
+	actor := &vocab.ActorType{}
+
+	err = json.Unmarshal(respBytes, actor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid actor in response from %s: %w", actorIRI, err)
+	}
+
+	return actor, &CacheValidators{
+		ETag:         header.Get(etagHeader),
+		LastModified: header.Get(lastModifiedHeader),
+	}, nil
+}
+
 // GetPublicKey retrieves the public key at the given IRI.
 //nolint:interfacer
 func (c *Client) GetPublicKey(keyIRI *url.URL) (*vocab.PublicKeyType, error) {
@@ -106,21 +174,45 @@ func (c *Client) GetReferences(iri *url.URL) (ReferenceIterator, error) {
 	return newIterator(items, firstPage, totalItems, c.get), nil
 }
 
-func (c *Client) get(iri *url.URL) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodGet, iri.String(), nil)
+// GetActivities returns an iterator that reads all activities in the collection (or ordered collection)
+// at the given IRI in the given order.
+func (c *Client) GetActivities(iri *url.URL, order Order) (ActivityIterator, error) {
+	respBytes, err := c.get(iri)
 	if err != nil {
-		return nil, fmt.Errorf("create HTTP request: %w", err)
+		return nil, fmt.Errorf("error reading response from %s: %w", iri, err)
 	}
 
-	req.Header.Set("Accept", "application/json")
+	logger.Debugf("Got response from %s: %s", iri, respBytes)
 
-	resp, err := c.Get(context.Background(), transport.NewRequest(iri))
+	firstPage, lastPage, totalItems, err := unmarshalCollection(respBytes)
 	if err != nil {
-		return nil, fmt.Errorf("request to %s failed: %w", iri, err)
+		return nil, fmt.Errorf("error unmarsalling response from %s: %w", iri, err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request to %s returned status code %d", iri, resp.StatusCode)
+	startPage := firstPage
+	if order == Reverse {
+		startPage = lastPage
+	}
+
+	return newActivityIterator(startPage, order, totalItems, c.get), nil
+}
+
+func (c *Client) get(iri *url.URL) ([]byte, error) {
+	respBytes, _, err := c.getWithHeader(iri, nil)
+
+	return respBytes, err
+}
+
+func (c *Client) getWithHeader(iri *url.URL, header http.Header) ([]byte, http.Header, error) {
+	req := transport.NewRequest(iri)
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.Get(context.Background(), req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("request to %s failed: %w", iri, err)
 	}
 
 	defer func() {
@@ -129,12 +221,40 @@ func (c *Client) get(iri *url.URL) ([]byte, error) {
 		}
 	}()
 
+	if resp.StatusCode == http.StatusNotModified {
+		logger.Debugf("Object at %s was not modified", iri)
+
+		return nil, resp.Header, ErrNotModified
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("request to %s returned status code %d", iri, resp.StatusCode)
+	}
+
 	respBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response from %s: %w", iri, err)
+		return nil, nil, fmt.Errorf("error reading response from %s: %w", iri, err)
 	}
 
-	return respBytes, nil
+	return respBytes, resp.Header, nil
+}
+
+func newConditionalHeader(validators *CacheValidators) http.Header {
+	header := make(http.Header)
+
+	if validators == nil {
+		return header
+	}
+
+	if validators.ETag != "" {
+		header.Set(ifNoneMatchHeader, validators.ETag)
+	}
+
+	if validators.LastModified != "" {
+		header.Set(ifModifiedSinceHeader, validators.LastModified)
+	}
+
+	return header
 }
 
 type getFunc func(iri *url.URL) ([]byte, error)
@@ -214,10 +334,10 @@ func unmarshalReference(respBytes []byte) (items []*url.URL, nextPage *url.URL,
 	}
 
 	switch {
-	case obj.Type().Is(vocab.TypeService):
+	case obj.Type().IsAny(vocab.ActorTypes()...):
 		actor := &vocab.ActorType{}
 		if err := json.Unmarshal(respBytes, actor); err != nil {
-			return nil, nil, 0, fmt.Errorf("invalid service in response: %w", err)
+			return nil, nil, 0, fmt.Errorf("invalid actor in response: %w", err)
 		}
 
 		return []*url.URL{actor.ID().URL()}, nil, 1, nil
@@ -295,3 +415,192 @@ func unmarshalCollectionPage(respBytes []byte) ([]*url.URL, *url.URL, error) {
 
 	return refs, next, nil
 }
+
+type activityIterator struct {
+	totalItems   int
+	order        Order
+	currentItems []json.RawMessage
+	currentIndex int
+	nextPage     *url.URL
+	get          getFunc
+}
+
+func newActivityIterator(startPage *url.URL, order Order, totalItems int, retrieve getFunc) *activityIterator {
+	return &activityIterator{
+		totalItems: totalItems,
+		order:      order,
+		nextPage:   startPage,
+		get:        retrieve,
+	}
+}
+
+func (it *activityIterator) Next() (*vocab.ActivityType, error) {
+	if it.currentIndex >= len(it.currentItems) {
+		err := it.getNextPage()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	item := it.currentItems[it.currentIndex]
+
+	it.currentIndex++
+
+	var iri string
+
+	// The item is either the IRI of an activity or an embedded activity.
+	if err := json.Unmarshal(item, &iri); err == nil {
+		activityIRI, err := url.Parse(iri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid activity IRI [%s]: %w", iri, err)
+		}
+
+		return it.getActivity(activityIRI)
+	}
+
+	activity := &vocab.ActivityType{}
+
+	if err := json.Unmarshal(item, activity); err != nil {
+		return nil, fmt.Errorf("invalid activity in collection page: %w", err)
+	}
+
+	return activity, nil
+}
+
+func (it *activityIterator) TotalItems() int {
+	return it.totalItems
+}
+
+func (it *activityIterator) getNextPage() error {
+	// Skip over empty pages.
+	for {
+		if it.nextPage == nil {
+			logger.Debugf("No more pages")
+
+			return ErrNotFound
+		}
+
+		logger.Debugf("Retrieving next page %s", it.nextPage)
+
+		respBytes, err := it.get(it.nextPage)
+		if err != nil {
+			return fmt.Errorf("request to %s failed: %w", it.nextPage, err)
+		}
+
+		logger.Debugf("Got response from %s: %s", it.nextPage, respBytes)
+
+		items, next, prev, err := unmarshalActivityPage(respBytes)
+		if err != nil {
+			return err
+		}
+
+		logger.Debugf("Got page %s with %d items. Next page: %s, Previous page: %s",
+			it.nextPage, len(items), next, prev)
+
+		if it.order == Reverse {
+			reverse(items)
+
+			next = prev
+		}
+
+		it.currentItems = items
+		it.currentIndex = 0
+		it.nextPage = next
+
+		if len(items) > 0 {
+			return nil
+		}
+	}
+}
+
+func (it *activityIterator) getActivity(iri *url.URL) (*vocab.ActivityType, error) {
+	respBytes, err := it.get(iri)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", iri, err)
+	}
+
+	activity := &vocab.ActivityType{}
+
+	if err := json.Unmarshal(respBytes, activity); err != nil {
+		return nil, fmt.Errorf("invalid activity in response from %s: %w", iri, err)
+	}
+
+	return activity, nil
+}
+
+func unmarshalCollection(respBytes []byte) (first, last *url.URL, totalCount int, err error) {
+	obj := &vocab.ObjectType{}
+
+	if err := json.Unmarshal(respBytes, &obj); err != nil {
+		return nil, nil, 0, err
+	}
+
+	switch {
+	case obj.Type().Is(vocab.TypeCollection):
+		coll := &vocab.CollectionType{}
+		if err := json.Unmarshal(respBytes, coll); err != nil {
+			return nil, nil, 0, fmt.Errorf("invalid collection in response: %w", err)
+		}
+
+		return coll.First(), coll.Last(), coll.TotalItems(), nil
+
+	case obj.Type().Is(vocab.TypeOrderedCollection):
+		coll := &vocab.OrderedCollectionType{}
+		if err := json.Unmarshal(respBytes, coll); err != nil {
+			return nil, nil, 0, fmt.Errorf("invalid ordered collection in response: %w", err)
+		}
+
+		return coll.First(), coll.Last(), coll.TotalItems(), nil
+
+	default:
+		return nil, nil, 0, fmt.Errorf("expecting Collection or OrderedCollection in response payload")
+	}
+}
+
+// unmarshalActivityPage returns the raw items of a collection page (or ordered collection page) since
+// the items may be IRIs or embedded activities.
+func unmarshalActivityPage(respBytes []byte) (items []json.RawMessage, next, prev *url.URL, err error) {
+	obj := &vocab.ObjectType{}
+
+	if err := json.Unmarshal(respBytes, &obj); err != nil {
+		return nil, nil, nil, err
+	}
+
+	rawPage := &struct {
+		Items        []json.RawMessage `json:"items"`
+		OrderedItems []json.RawMessage `json:"orderedItems"`
+	}{}
+
+	if err := json.Unmarshal(respBytes, rawPage); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid collection page in response: %w", err)
+	}
+
+	switch {
+	case obj.Type().Is(vocab.TypeCollectionPage):
+		coll := &vocab.CollectionPageType{}
+
+		if err := json.Unmarshal(respBytes, coll); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid collection page in response: %w", err)
+		}
+
+		return rawPage.Items, coll.Next(), coll.Prev(), nil
+
+	case obj.Type().Is(vocab.TypeOrderedCollectionPage):
+		coll := &vocab.OrderedCollectionPageType{}
+
+		if err := json.Unmarshal(respBytes, coll); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid ordered collection page in response: %w", err)
+		}
+
+		return rawPage.OrderedItems, coll.Next(), coll.Prev(), nil
+
+	default:
+		return nil, nil, nil, fmt.Errorf("expecting CollectionPage or OrderedCollectionPage in response payload")
+	}
+}
+
+func reverse(items []json.RawMessage) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
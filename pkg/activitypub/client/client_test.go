@@ -7,7 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -17,6 +19,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/trustbloc/edge-core/pkg/log"
 
+	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
 	"github.com/trustbloc/orb/pkg/activitypub/mocks"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 	"github.com/trustbloc/orb/pkg/internal/aptestutil"
@@ -116,6 +119,122 @@ func TestClient_GetActor(t *testing.T) {
 	})
 }
 
+func TestClient_GetActorIfModified(t *testing.T) {
+	actorIRI := testutil.MustParseURL("https://example.com/services/service1")
+
+	actorBytes, err := json.Marshal(aptestutil.NewMockService(actorIRI))
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		httpClient := &mocks.HTTPTransport{}
+
+		rw := httptest.NewRecorder()
+		rw.Header().Set("ETag", "etag1")
+		rw.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+
+		_, err = rw.Write(actorBytes)
+		require.NoError(t, err)
+
+		result := rw.Result()
+
+		httpClient.GetReturns(result, nil)
+
+		c := New(httpClient)
+		require.NotNil(t, t, c)
+
+		actor, validators, e := c.GetActorIfModified(actorIRI, nil)
+		require.NoError(t, e)
+		require.NotNil(t, actor)
+		require.Equal(t, actorIRI.String(), actor.ID().String())
+		require.NotNil(t, validators)
+		require.Equal(t, "etag1", validators.ETag)
+		require.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", validators.LastModified)
+
+		_, req := httpClient.GetArgsForCall(0)
+		require.Empty(t, req.Header.Get("If-None-Match"))
+		require.Empty(t, req.Header.Get("If-Modified-Since"))
+
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Not modified", func(t *testing.T) {
+		httpClient := &mocks.HTTPTransport{}
+
+		rw := httptest.NewRecorder()
+
+		rw.Code = http.StatusNotModified
+
+		result := rw.Result()
+
+		httpClient.GetReturns(result, nil)
+
+		c := New(httpClient)
+		require.NotNil(t, t, c)
+
+		validators := &CacheValidators{
+			ETag:         "etag1",
+			LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+		}
+
+		actor, newValidators, e := c.GetActorIfModified(actorIRI, validators)
+		require.True(t, errors.Is(e, ErrNotModified))
+		require.Nil(t, actor)
+		require.Equal(t, validators, newValidators)
+
+		_, req := httpClient.GetArgsForCall(0)
+		require.Equal(t, "etag1", req.Header.Get("If-None-Match"))
+		require.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", req.Header.Get("If-Modified-Since"))
+
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Error status code", func(t *testing.T) {
+		httpClient := &mocks.HTTPTransport{}
+
+		rw := httptest.NewRecorder()
+
+		rw.Code = http.StatusInternalServerError
+
+		result := rw.Result()
+
+		httpClient.GetReturns(result, nil)
+
+		c := New(httpClient)
+		require.NotNil(t, t, c)
+
+		actor, validators, e := c.GetActorIfModified(actorIRI, &CacheValidators{ETag: "etag1"})
+		require.Error(t, e)
+		require.Contains(t, e.Error(), "status code 500")
+		require.Nil(t, actor)
+		require.Nil(t, validators)
+
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Unmarshal client error", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+
+		_, err = rw.Write([]byte("{"))
+		require.NoError(t, err)
+
+		httpClient := &mocks.HTTPTransport{}
+
+		result := rw.Result()
+
+		httpClient.GetReturns(result, nil)
+
+		c := New(httpClient)
+		require.NotNil(t, t, c)
+
+		actor, _, err := c.GetActorIfModified(actorIRI, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unexpected end of JSON input")
+		require.Nil(t, actor)
+
+		require.NoError(t, result.Body.Close())
+	})
+}
+
 func TestClient_GetReferences(t *testing.T) {
 	log.SetLevel("activitypub_client", log.DEBUG)
 
@@ -523,3 +642,126 @@ func TestClient_GetPublicKey(t *testing.T) {
 		require.NoError(t, result.Body.Close())
 	})
 }
+
+func TestClient_GetActivities(t *testing.T) {
+	serviceIRI := testutil.MustParseURL("https://example.com/services/service1")
+	outboxIRI := testutil.NewMockID(serviceIRI, "/outbox")
+
+	page0 := testutil.NewMockID(outboxIRI, "?page=true&page-num=0")
+	page1 := testutil.NewMockID(outboxIRI, "?page=true&page-num=1")
+
+	activities := make([]*vocab.ActivityType, 3)
+
+	for i := range activities {
+		activities[i] = vocab.NewCreateActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(testutil.NewMockID(serviceIRI, fmt.Sprintf("/objects/%d", i)))),
+			vocab.WithID(testutil.NewMockID(serviceIRI, fmt.Sprintf("/activities/%d", i))),
+		)
+	}
+
+	// The outbox is sorted by most recent first, i.e. page 1 is the first page and page 0 is the last page.
+	responses := map[string]interface{}{
+		outboxIRI.String(): vocab.NewOrderedCollection(nil,
+			vocab.WithID(outboxIRI),
+			vocab.WithFirst(page1),
+			vocab.WithLast(page0),
+			vocab.WithTotalItems(len(activities)),
+		),
+		page1.String(): vocab.NewOrderedCollectionPage(
+			[]*vocab.ObjectProperty{
+				vocab.NewObjectProperty(vocab.WithActivity(activities[2])),
+			},
+			vocab.WithID(page1),
+			vocab.WithNext(page0),
+		),
+		page0.String(): vocab.NewOrderedCollectionPage(
+			[]*vocab.ObjectProperty{
+				vocab.NewObjectProperty(vocab.WithIRI(activities[1].ID().URL())),
+				vocab.NewObjectProperty(vocab.WithActivity(activities[0])),
+			},
+			vocab.WithID(page0),
+			vocab.WithPrev(page1),
+		),
+		activities[1].ID().String(): activities[1],
+	}
+
+	httpClient := newMockTransport(t, responses)
+
+	t.Run("Forward -> Success", func(t *testing.T) {
+		it, err := New(httpClient).GetActivities(outboxIRI, Forward)
+		require.NoError(t, err)
+		require.Equal(t, len(activities), it.TotalItems())
+
+		result, err := ReadActivities(it, -1)
+		require.NoError(t, err)
+		require.Len(t, result, 3)
+		require.Equal(t, activities[2].ID().String(), result[0].ID().String())
+		require.Equal(t, activities[1].ID().String(), result[1].ID().String())
+		require.Equal(t, activities[0].ID().String(), result[2].ID().String())
+	})
+
+	t.Run("Reverse -> Success", func(t *testing.T) {
+		it, err := New(httpClient).GetActivities(outboxIRI, Reverse)
+		require.NoError(t, err)
+
+		result, err := ReadActivities(it, 2)
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		require.Equal(t, activities[0].ID().String(), result[0].ID().String())
+		require.Equal(t, activities[1].ID().String(), result[1].ID().String())
+	})
+
+	t.Run("Invalid collection error", func(t *testing.T) {
+		_, err := New(httpClient).GetActivities(activities[1].ID().URL(), Forward)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "expecting Collection or OrderedCollection in response payload")
+	})
+
+	t.Run("HTTP client error", func(t *testing.T) {
+		_, err := New(httpClient).GetActivities(testutil.NewMockID(serviceIRI, "/unknown"), Forward)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "returned status code 404")
+	})
+
+	t.Run("Page error", func(t *testing.T) {
+		collIRI := testutil.NewMockID(serviceIRI, "/liked")
+
+		it, err := New(newMockTransport(t, map[string]interface{}{
+			collIRI.String(): vocab.NewCollection(nil, vocab.WithID(collIRI), vocab.WithFirst(collIRI)),
+		})).GetActivities(collIRI, Forward)
+		require.NoError(t, err)
+
+		_, err = it.Next()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "expecting CollectionPage or OrderedCollectionPage in response payload")
+	})
+}
+
+// newMockTransport returns a mock HTTP transport that responds with the marshalled object that's
+// registered for the requested URL, or with a 404 if no object is registered.
+func newMockTransport(t *testing.T, responses map[string]interface{}) *mocks.HTTPTransport {
+	t.Helper()
+
+	httpClient := &mocks.HTTPTransport{}
+
+	httpClient.GetStub = func(_ context.Context, req *transport.Request) (*http.Response, error) {
+		rw := httptest.NewRecorder()
+
+		obj, ok := responses[req.URL.String()]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+
+			return rw.Result(), nil
+		}
+
+		objBytes, err := json.Marshal(obj)
+		require.NoError(t, err)
+
+		_, err = rw.Write(objBytes)
+		require.NoError(t, err)
+
+		return rw.Result(), nil
+	}
+
+	return httpClient
+}
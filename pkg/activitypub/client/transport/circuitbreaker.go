@@ -0,0 +1,280 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package transport
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request isn't attempted because the circuit for the destination
+// host is open, i.e. the host has recently failed too many times.
+var ErrCircuitOpen = errors.New("circuit open")
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+	defaultMaxRetries       = 2
+	defaultInitialBackoff   = 500 * time.Millisecond
+	defaultMaxBackoff       = 5 * time.Second
+)
+
+// CircuitState is the state of the circuit for a host.
+type CircuitState string
+
+const (
+	// CircuitClosed indicates that the host is healthy and requests are sent.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen indicates that the host is unhealthy and requests are rejected until the open timeout expires.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen indicates that the open timeout has expired and a single (probe) request is allowed
+	// in order to determine whether or not the host has recovered.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreakerConfig holds the configuration for the circuit breaker.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures after which the circuit for a host is opened.
+	FailureThreshold int
+	// OpenTimeout is the time that the circuit stays open before a probe request is allowed.
+	OpenTimeout time.Duration
+	// MaxRetries is the maximum number of times that an idempotent (GET) request is retried.
+	MaxRetries int
+	// InitialBackoff is the time to wait before the first retry. The backoff doubles on each subsequent retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum time to wait between retries.
+	MaxBackoff time.Duration
+}
+
+// PeerHealth contains the health of a remote host.
+type PeerHealth struct {
+	Host                string       `json:"host"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	LastFailure         time.Time    `json:"lastFailure"`
+	OpenedAt            time.Time    `json:"openedAt"`
+}
+
+// CircuitBreaker tracks the health of remote hosts. After a number of consecutive failures the circuit for
+// the host is opened and requests to the host are rejected (without being attempted) until the open timeout
+// expires, after which a single probe request is allowed. If the probe succeeds then the circuit is closed.
+type CircuitBreaker struct {
+	*CircuitBreakerConfig
+
+	mutex sync.Mutex
+	peers map[string]*peer
+	now   func() time.Time
+}
+
+type peer struct {
+	PeerHealth
+
+	probing bool
+}
+
+// NewCircuitBreaker returns a new circuit breaker.
+func NewCircuitBreaker(cfg *CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultOpenTimeout
+	}
+
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultInitialBackoff
+	}
+
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+
+	return &CircuitBreaker{
+		CircuitBreakerConfig: cfg,
+		peers:                make(map[string]*peer),
+		now:                  time.Now,
+	}
+}
+
+// Allow returns true if a request may be sent to the host of the given URL. If the circuit is open and
+// the open timeout has expired then the circuit is moved to the half-open state and a single request is allowed.
+func (b *CircuitBreaker) Allow(u *url.URL) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p, ok := b.peers[u.Host]
+	if !ok {
+		return true
+	}
+
+	switch p.State {
+	case CircuitOpen:
+		if b.now().Sub(p.OpenedAt) < b.OpenTimeout {
+			return false
+		}
+
+		logger.Debugf("Circuit for host [%s] is half-open. Allowing probe request.", u.Host)
+
+		p.State = CircuitHalfOpen
+		p.probing = true
+
+		return true
+	case CircuitHalfOpen:
+		if p.probing {
+			return false
+		}
+
+		p.probing = true
+
+		return true
+	default:
+		return true
+	}
+}
+
+// IsHealthy returns true if the circuit for the host of the given URL is not open (or the open timeout
+// has expired, in which case a request may be attempted).
+func (b *CircuitBreaker) IsHealthy(u *url.URL) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p, ok := b.peers[u.Host]
+	if !ok {
+		return true
+	}
+
+	return p.State != CircuitOpen || b.now().Sub(p.OpenedAt) >= b.OpenTimeout
+}
+
+// HealthyAt returns the time at which the open timeout of the circuit for the host of the given URL expires,
+// i.e. the time at which a request to the host may be attempted. The zero time is returned if the circuit isn't open.
+func (b *CircuitBreaker) HealthyAt(u *url.URL) time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p, ok := b.peers[u.Host]
+	if !ok || p.State != CircuitOpen {
+		return time.Time{}
+	}
+
+	return p.OpenedAt.Add(b.OpenTimeout)
+}
+
+// Peers returns the health of all hosts which have recently failed, sorted by host.
+func (b *CircuitBreaker) Peers() []*PeerHealth {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	peers := make([]*PeerHealth, 0, len(b.peers))
+
+	for _, p := range b.peers {
+		health := p.PeerHealth
+
+		peers = append(peers, &health)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Host < peers[j].Host
+	})
+
+	return peers
+}
+
+// Health returns the health of the host of the given URL.
+func (b *CircuitBreaker) Health(u *url.URL) *PeerHealth {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p, ok := b.peers[u.Host]
+	if !ok {
+		return &PeerHealth{Host: u.Host, State: CircuitClosed}
+	}
+
+	health := p.PeerHealth
+
+	return &health
+}
+
+// Success records a successful request to the host of the given URL, which closes the circuit.
+func (b *CircuitBreaker) Success(u *url.URL) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p, ok := b.peers[u.Host]
+	if !ok {
+		return
+	}
+
+	if p.State != CircuitClosed {
+		logger.Infof("Host [%s] has recovered. Closing circuit.", u.Host)
+	}
+
+	delete(b.peers, u.Host)
+}
+
+// Failure records a failed request to the host of the given URL. The circuit is opened if the number
+// of consecutive failures reaches the threshold or if the probe request of a half-open circuit failed.
+func (b *CircuitBreaker) Failure(u *url.URL) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p, ok := b.peers[u.Host]
+	if !ok {
+		p = &peer{PeerHealth: PeerHealth{Host: u.Host, State: CircuitClosed}}
+		b.peers[u.Host] = p
+	}
+
+	now := b.now()
+
+	p.ConsecutiveFailures++
+	p.LastFailure = now
+
+	if p.State == CircuitHalfOpen || (p.State == CircuitClosed && p.ConsecutiveFailures >= b.FailureThreshold) {
+		logger.Warnf("Opening circuit for host [%s] after %d consecutive failures. Requests to the host"+
+			" will be rejected for %s.", u.Host, p.ConsecutiveFailures, b.OpenTimeout)
+
+		p.State = CircuitOpen
+		p.OpenedAt = now
+		p.probing = false
+	}
+}
+
+// release releases the probe of a half-open circuit without recording a result, for example when the
+// request was cancelled by the caller.
+func (b *CircuitBreaker) release(u *url.URL) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if p, ok := b.peers[u.Host]; ok {
+		p.probing = false
+	}
+}
+
+// backoff returns the time to wait before the given retry attempt (starting at 1).
+func (b *CircuitBreaker) backoff(attempt int) time.Duration {
+	backoff := b.InitialBackoff
+
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+
+		if backoff >= b.MaxBackoff {
+			return b.MaxBackoff
+		}
+	}
+
+	return backoff
+}
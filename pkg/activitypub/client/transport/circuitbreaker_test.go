@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package transport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+func TestNewCircuitBreaker(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		b := NewCircuitBreaker(&CircuitBreakerConfig{})
+		require.Equal(t, defaultFailureThreshold, b.FailureThreshold)
+		require.Equal(t, defaultOpenTimeout, b.OpenTimeout)
+		require.Equal(t, defaultMaxRetries, b.MaxRetries)
+		require.Equal(t, defaultInitialBackoff, b.InitialBackoff)
+		require.Equal(t, defaultMaxBackoff, b.MaxBackoff)
+	})
+
+	t.Run("No retries", func(t *testing.T) {
+		b := NewCircuitBreaker(&CircuitBreakerConfig{MaxRetries: -1})
+		require.Equal(t, 0, b.MaxRetries)
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	u1 := testutil.MustParseURL("https://domain1.com/services/orb/inbox")
+	u2 := testutil.MustParseURL("https://domain2.com/services/orb/inbox")
+
+	now := time.Now()
+
+	b := NewCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+	})
+
+	b.now = func() time.Time { return now }
+
+	require.True(t, b.Allow(u1))
+	require.True(t, b.IsHealthy(u1))
+	require.Equal(t, CircuitClosed, b.Health(u1).State)
+
+	b.Failure(u1)
+	b.Failure(u1)
+
+	require.True(t, b.Allow(u1))
+	require.True(t, b.IsHealthy(u1))
+
+	health := b.Health(u1)
+	require.Equal(t, CircuitClosed, health.State)
+	require.Equal(t, 2, health.ConsecutiveFailures)
+	require.Equal(t, now, health.LastFailure)
+
+	// A success resets the failure count.
+	b.Success(u1)
+	require.Equal(t, 0, b.Health(u1).ConsecutiveFailures)
+
+	b.Failure(u1)
+	b.Failure(u1)
+	b.Failure(u1)
+
+	require.False(t, b.Allow(u1))
+	require.False(t, b.IsHealthy(u1))
+	require.Equal(t, CircuitOpen, b.Health(u1).State)
+	require.Equal(t, now, b.Health(u1).OpenedAt)
+	require.Equal(t, now.Add(time.Minute), b.HealthyAt(u1))
+
+	// Other hosts aren't affected.
+	require.True(t, b.Allow(u2))
+	require.True(t, b.IsHealthy(u2))
+	require.True(t, b.HealthyAt(u2).IsZero())
+
+	b.Failure(u2)
+
+	peers := b.Peers()
+	require.Len(t, peers, 2)
+	require.Equal(t, u1.Host, peers[0].Host)
+	require.Equal(t, CircuitOpen, peers[0].State)
+	require.Equal(t, u2.Host, peers[1].Host)
+	require.Equal(t, CircuitClosed, peers[1].State)
+
+	b.Success(u2)
+	require.Len(t, b.Peers(), 1)
+
+	// Open timeout expires.
+	now = now.Add(time.Minute)
+
+	require.True(t, b.IsHealthy(u1))
+	require.True(t, b.Allow(u1))
+	require.Equal(t, CircuitHalfOpen, b.Health(u1).State)
+	require.True(t, b.HealthyAt(u1).IsZero())
+
+	// Only one probe is allowed.
+	require.False(t, b.Allow(u1))
+
+	// The probe fails, so the circuit is opened again.
+	b.Failure(u1)
+	require.False(t, b.Allow(u1))
+	require.Equal(t, CircuitOpen, b.Health(u1).State)
+	require.Equal(t, 4, b.Health(u1).ConsecutiveFailures)
+
+	now = now.Add(time.Minute)
+
+	require.True(t, b.Allow(u1))
+
+	// The probe is released without a result so that another probe is allowed.
+	b.release(u1)
+	require.True(t, b.Allow(u1))
+
+	// The probe succeeds, so the circuit is closed.
+	b.Success(u1)
+	require.True(t, b.Allow(u1))
+	require.Equal(t, CircuitClosed, b.Health(u1).State)
+	require.Equal(t, 0, b.Health(u1).ConsecutiveFailures)
+}
+
+func TestCircuitBreaker_Backoff(t *testing.T) {
+	b := NewCircuitBreaker(&CircuitBreakerConfig{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	})
+
+	require.Equal(t, 100*time.Millisecond, b.backoff(1))
+	require.Equal(t, 200*time.Millisecond, b.backoff(2))
+	require.Equal(t, 400*time.Millisecond, b.backoff(3))
+	require.Equal(t, 800*time.Millisecond, b.backoff(4))
+	require.Equal(t, time.Second, b.backoff(5))
+	require.Equal(t, time.Second, b.backoff(10))
+}
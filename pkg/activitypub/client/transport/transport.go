@@ -13,6 +13,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/trustbloc/edge-core/pkg/log"
 )
@@ -22,6 +24,7 @@ var logger = log.New("activitypub_client")
 const (
 	contentTypeHeader          = "Content-Type"
 	acceptHeader               = "Accept"
+	retryAfterHeader           = "Retry-After"
 	activityStreamsContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
 )
 
@@ -30,6 +33,19 @@ type Signer interface {
 	SignRequest(pubKeyID string, req *http.Request) error
 }
 
+// PublicKeyIDProvider provides the ID of the public key that is used to sign requests.
+type PublicKeyIDProvider interface {
+	PublicKeyID() *url.URL
+}
+
+type staticPublicKeyID struct {
+	keyID *url.URL
+}
+
+func (p *staticPublicKeyID) PublicKeyID() *url.URL {
+	return p.keyID
+}
+
 type httpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
@@ -39,17 +55,45 @@ type Transport struct {
 	client      httpClient
 	getSigner   Signer
 	postSigner  Signer
-	publicKeyID *url.URL
+	publicKeyID PublicKeyIDProvider
+	breaker     *CircuitBreaker
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+// Opt sets a transport option.
+type Opt func(t *Transport)
+
+// WithCircuitBreaker sets a circuit breaker which tracks the health of each remote host. Requests to a host
+// whose circuit is open are rejected with ErrCircuitOpen, and GET requests that fail with a network error
+// or a server error are retried with backoff.
+func WithCircuitBreaker(breaker *CircuitBreaker) Opt {
+	return func(t *Transport) {
+		t.breaker = breaker
+	}
 }
 
 // New returns a new transport.
-func New(client httpClient, publicKeyID *url.URL, getSigner, postSigner Signer) *Transport {
-	return &Transport{
+func New(client httpClient, publicKeyID *url.URL, getSigner, postSigner Signer, opts ...Opt) *Transport {
+	return NewWithPublicKeyIDProvider(client, &staticPublicKeyID{keyID: publicKeyID}, getSigner, postSigner, opts...)
+}
+
+// NewWithPublicKeyIDProvider returns a new transport which retrieves the public key ID
+// from the given provider for each request. This allows the signing key to be rotated.
+func NewWithPublicKeyIDProvider(client httpClient, publicKeyID PublicKeyIDProvider,
+	getSigner, postSigner Signer, opts ...Opt) *Transport {
+	t := &Transport{
 		client:      client,
 		publicKeyID: publicKeyID,
 		getSigner:   getSigner,
 		postSigner:  postSigner,
+		sleep:       sleep,
+	}
+
+	for _, opt := range opts {
+		opt(t)
 	}
+
+	return t
 }
 
 // Request contains the destination URL and headers.
@@ -71,14 +115,78 @@ func NewRequest(toURL *url.URL) *Request {
 func Default() *Transport {
 	return &Transport{
 		client:      http.DefaultClient,
-		publicKeyID: &url.URL{},
+		publicKeyID: &staticPublicKeyID{keyID: &url.URL{}},
 		getSigner:   &NoOpSigner{},
 		postSigner:  &NoOpSigner{},
+		sleep:       sleep,
+	}
+}
+
+// IsHealthy returns false if the circuit for the host of the given URL is open, i.e. a request to the host
+// would be rejected. True is always returned if no circuit breaker is configured.
+func (t *Transport) IsHealthy(u *url.URL) bool {
+	if t.breaker == nil {
+		return true
+	}
+
+	return t.breaker.IsHealthy(u)
+}
+
+// HealthyAt returns the time at which a request to the host of the given URL may be attempted if the circuit
+// for the host is open. The zero time is returned if the circuit isn't open or if no circuit breaker is configured.
+func (t *Transport) HealthyAt(u *url.URL) time.Time {
+	if t.breaker == nil {
+		return time.Time{}
+	}
+
+	return t.breaker.HealthyAt(u)
+}
+
+// PeerHealth returns the health of all remote hosts which have recently failed or nil if no circuit breaker
+// is configured.
+func (t *Transport) PeerHealth() []*PeerHealth {
+	if t.breaker == nil {
+		return nil
+	}
+
+	return t.breaker.Peers()
+}
+
+// Health returns the health of the host of the given URL or nil if no circuit breaker is configured.
+func (t *Transport) Health(u *url.URL) *PeerHealth {
+	if t.breaker == nil {
+		return nil
 	}
+
+	return t.breaker.Health(u)
 }
 
 // Post posts an HTTP request. The HTTP request is first signed and the signature is added to the request header.
+// Since a POST is not idempotent, the request is not retried.
 func (t *Transport) Post(ctx context.Context, r *Request, payload []byte) (*http.Response, error) {
+	if t.breaker != nil && !t.breaker.Allow(r.URL) {
+		return nil, fmt.Errorf("post to %s: %w", r.URL, ErrCircuitOpen)
+	}
+
+	req, err := t.newPostRequest(ctx, r, payload)
+	if err != nil {
+		if t.breaker != nil {
+			t.breaker.release(r.URL)
+		}
+
+		return nil, err
+	}
+
+	resp, err := t.client.Do(req)
+
+	if t.breaker != nil {
+		t.recordResult(ctx, r.URL, resp, err)
+	}
+
+	return resp, err
+}
+
+func (t *Transport) newPostRequest(ctx context.Context, r *Request, payload []byte) (*http.Request, error) {
 	var body io.Reader
 	if len(payload) > 0 {
 		body = bytes.NewBuffer(payload)
@@ -95,18 +203,121 @@ func (t *Transport) Post(ctx context.Context, r *Request, payload []byte) (*http
 		req.Header[k] = v
 	}
 
-	err = t.postSigner.SignRequest(t.publicKeyID.String(), req)
+	err = t.postSigner.SignRequest(t.publicKeyID.PublicKeyID().String(), req)
 	if err != nil {
 		return nil, fmt.Errorf("sign request: %w", err)
 	}
 
 	logger.Debugf("Signed HTTP POST to %s. Headers: %s", r.URL, req.Header)
 
-	return t.client.Do(req)
+	return req, nil
 }
 
 // Get sends an HTTP GET. The HTTP request is first signed and the signature is added to the request header.
+// If a circuit breaker is configured then the request is retried (with backoff) if it fails with a network
+// error or a server error. If the server responds with a Retry-After header then the request is retried after
+// the time requested by the server (up to the maximum backoff).
 func (t *Transport) Get(ctx context.Context, r *Request) (*http.Response, error) {
+	if t.breaker == nil {
+		req, err := t.newGetRequest(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		return t.client.Do(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if !t.breaker.Allow(r.URL) {
+			return nil, fmt.Errorf("get from %s: %w", r.URL, ErrCircuitOpen)
+		}
+
+		// A new request is created for each attempt since the signature includes the date.
+		req, err := t.newGetRequest(ctx, r)
+		if err != nil {
+			t.breaker.release(r.URL)
+
+			return nil, err
+		}
+
+		resp, err := t.client.Do(req)
+
+		failed := t.recordResult(ctx, r.URL, resp, err)
+		if !failed || attempt >= t.breaker.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		logger.Debugf("GET from %s failed (attempt %d): %s", r.URL, attempt+1, getFailure(resp, err))
+
+		if resp != nil && resp.Body != nil {
+			if e := resp.Body.Close(); e != nil {
+				logger.Warnf("Error closing response body from %s: %s", r.URL, e)
+			}
+		}
+
+		if e := t.sleep(ctx, t.retryBackoff(resp, attempt+1)); e != nil {
+			return nil, fmt.Errorf("get from %s: %w", r.URL, e)
+		}
+	}
+}
+
+// retryBackoff returns the time to wait before the given retry attempt (starting at 1). The time specified by
+// the Retry-After header of the response is used if present, otherwise the backoff of the circuit breaker is used.
+func (t *Transport) retryBackoff(resp *http.Response, attempt int) time.Duration {
+	if d, ok := retryAfter(resp, t.breaker.MaxBackoff); ok {
+		return d
+	}
+
+	return t.breaker.backoff(attempt)
+}
+
+// retryAfter returns the time to wait, capped at maxWait, as specified by the Retry-After header of the response.
+// The header contains either a number of seconds or an HTTP date. False is returned if the header isn't present
+// or is invalid.
+func retryAfter(resp *http.Response, maxWait time.Duration) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	value := resp.Header.Get(retryAfterHeader)
+	if value == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		if seconds > int64(maxWait/time.Second) {
+			return maxWait, true
+		}
+
+		wait = time.Duration(seconds) * time.Second
+	} else {
+		date, err := http.ParseTime(value)
+		if err != nil {
+			logger.Debugf("Ignoring invalid %s header [%s]: %s", retryAfterHeader, value, err)
+
+			return 0, false
+		}
+
+		wait = time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+	}
+
+	if wait > maxWait {
+		return maxWait, true
+	}
+
+	return wait, true
+}
+
+func (t *Transport) newGetRequest(ctx context.Context, r *Request) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("get from %s: %w", r.URL, err)
@@ -120,12 +331,57 @@ func (t *Transport) Get(ctx context.Context, r *Request) (*http.Response, error)
 
 	logger.Debugf("Signed HTTP GET to %s. Headers: %s", r.URL, req.Header)
 
-	err = t.getSigner.SignRequest(t.publicKeyID.String(), req)
+	err = t.getSigner.SignRequest(t.publicKeyID.PublicKeyID().String(), req)
 	if err != nil {
 		return nil, fmt.Errorf("sign request: %w", err)
 	}
 
-	return t.client.Do(req)
+	return req, nil
+}
+
+// recordResult records the result of the request with the circuit breaker and returns true if the request failed.
+// A request fails if a network error occurred or if the server responded with a server error (or is throttling
+// requests). A request that was cancelled by the caller is not recorded.
+func (t *Transport) recordResult(ctx context.Context, u *url.URL, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		t.breaker.release(u)
+
+		return true
+	}
+
+	if getFailure(resp, err) != nil {
+		t.breaker.Failure(u)
+
+		return true
+	}
+
+	t.breaker.Success(u)
+
+	return false
+}
+
+func getFailure(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // NoOpSigner is a signer that does nothing. This signer should only be used by tests.
@@ -8,9 +8,11 @@ package transport
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -28,6 +30,38 @@ func TestNew(t *testing.T) {
 	require.NotNil(t, tp)
 }
 
+func TestNewWithPublicKeyIDProvider(t *testing.T) {
+	httpClient := &mocks.HTTPClient{}
+	httpClient.DoReturns(&http.Response{}, nil)
+
+	signer := &mocks.HTTPSigner{}
+
+	keyIDProvider := &staticPublicKeyID{keyID: testutil.MustParseURL(publicKeyID)}
+
+	tp := NewWithPublicKeyIDProvider(httpClient, keyIDProvider, signer, signer)
+	require.NotNil(t, tp)
+
+	//nolint:bodyclose
+	_, err := tp.Get(context.Background(), NewRequest(testutil.MustParseURL("https://domain1.com")))
+	require.NoError(t, err)
+
+	const newPublicKeyID = "https://alice.example.com/services/orb/keys/key2"
+
+	keyIDProvider.keyID = testutil.MustParseURL(newPublicKeyID)
+
+	//nolint:bodyclose
+	_, err = tp.Post(context.Background(), NewRequest(testutil.MustParseURL("https://domain1.com")), nil)
+	require.NoError(t, err)
+
+	require.Equal(t, 2, signer.SignRequestCallCount())
+
+	keyID, _ := signer.SignRequestArgsForCall(0)
+	require.Equal(t, publicKeyID, keyID)
+
+	keyID, _ = signer.SignRequestArgsForCall(1)
+	require.Equal(t, newPublicKeyID, keyID)
+}
+
 func TestNewRequest(t *testing.T) {
 	require.NotNil(t, NewRequest(testutil.MustParseURL("https://someurl")))
 }
@@ -109,3 +143,180 @@ func TestTransport_Get(t *testing.T) {
 		require.Nil(t, resp)
 	})
 }
+
+func TestTransport_CircuitBreaker(t *testing.T) {
+	u := testutil.MustParseURL("https://domain1.com/services/orb/outbox")
+
+	noSleep := func(context.Context, time.Duration) error { return nil }
+
+	t.Run("GET retry -> success", func(t *testing.T) {
+		httpClient := &mocks.HTTPClient{}
+		httpClient.DoReturnsOnCall(0, nil, errors.New("injected network error"))
+		httpClient.DoReturnsOnCall(1, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil)
+		httpClient.DoReturnsOnCall(2, &http.Response{StatusCode: http.StatusOK}, nil)
+
+		breaker := NewCircuitBreaker(&CircuitBreakerConfig{})
+
+		tp := New(httpClient, testutil.MustParseURL(publicKeyID), DefaultSigner(), DefaultSigner(),
+			WithCircuitBreaker(breaker))
+		tp.sleep = noSleep
+
+		//nolint:bodyclose
+		resp, err := tp.Get(context.Background(), NewRequest(u))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 3, httpClient.DoCallCount())
+		require.True(t, tp.IsHealthy(u))
+		require.Equal(t, 0, tp.Health(u).ConsecutiveFailures)
+	})
+
+	t.Run("GET retry after", func(t *testing.T) {
+		tooManyRequests := func(retryAfter string) *http.Response {
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"Retry-After": []string{retryAfter}},
+			}
+		}
+
+		httpClient := &mocks.HTTPClient{}
+		httpClient.DoReturnsOnCall(0, tooManyRequests("2"), nil)
+		httpClient.DoReturnsOnCall(1, tooManyRequests("3600"), nil)
+		httpClient.DoReturnsOnCall(2, tooManyRequests(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)), nil)
+		httpClient.DoReturnsOnCall(3, tooManyRequests("invalid"), nil)
+		httpClient.DoReturnsOnCall(4, &http.Response{StatusCode: http.StatusOK}, nil)
+
+		tp := New(httpClient, testutil.MustParseURL(publicKeyID), DefaultSigner(), DefaultSigner(),
+			WithCircuitBreaker(NewCircuitBreaker(&CircuitBreakerConfig{
+				FailureThreshold: 10,
+				MaxRetries:       4,
+				InitialBackoff:   100 * time.Millisecond,
+				MaxBackoff:       10 * time.Second,
+			})))
+
+		var waits []time.Duration
+
+		tp.sleep = func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+
+			return nil
+		}
+
+		//nolint:bodyclose
+		resp, err := tp.Get(context.Background(), NewRequest(u))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 5, httpClient.DoCallCount())
+
+		// The Retry-After header is honoured up to the maximum backoff. An invalid header is ignored.
+		require.Equal(t, []time.Duration{2 * time.Second, 10 * time.Second, 10 * time.Second, 800 * time.Millisecond},
+			waits)
+	})
+
+	t.Run("GET client error -> no retry", func(t *testing.T) {
+		httpClient := &mocks.HTTPClient{}
+		httpClient.DoReturns(&http.Response{StatusCode: http.StatusNotFound}, nil)
+
+		tp := New(httpClient, testutil.MustParseURL(publicKeyID), DefaultSigner(), DefaultSigner(),
+			WithCircuitBreaker(NewCircuitBreaker(&CircuitBreakerConfig{})))
+		tp.sleep = noSleep
+
+		//nolint:bodyclose
+		resp, err := tp.Get(context.Background(), NewRequest(u))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		require.Equal(t, 1, httpClient.DoCallCount())
+	})
+
+	t.Run("GET retries exhausted -> circuit open", func(t *testing.T) {
+		errExpected := errors.New("injected network error")
+
+		httpClient := &mocks.HTTPClient{}
+		httpClient.DoReturns(nil, errExpected)
+
+		tp := New(httpClient, testutil.MustParseURL(publicKeyID), DefaultSigner(), DefaultSigner(),
+			WithCircuitBreaker(NewCircuitBreaker(&CircuitBreakerConfig{
+				FailureThreshold: 4,
+				MaxRetries:       2,
+			})))
+		tp.sleep = noSleep
+
+		//nolint:bodyclose
+		_, err := tp.Get(context.Background(), NewRequest(u))
+		require.ErrorIs(t, err, errExpected)
+		require.Equal(t, 3, httpClient.DoCallCount())
+		require.True(t, tp.IsHealthy(u))
+
+		// The circuit opens after the fourth failure so the remaining retries aren't attempted.
+		//nolint:bodyclose
+		_, err = tp.Get(context.Background(), NewRequest(u))
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.Equal(t, 4, httpClient.DoCallCount())
+		require.False(t, tp.IsHealthy(u))
+		require.Equal(t, CircuitOpen, tp.Health(u).State)
+
+		//nolint:bodyclose
+		_, err = tp.Post(context.Background(), NewRequest(u), nil)
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.Equal(t, 4, httpClient.DoCallCount())
+	})
+
+	t.Run("GET context cancelled during backoff", func(t *testing.T) {
+		httpClient := &mocks.HTTPClient{}
+		httpClient.DoReturns(nil, errors.New("injected network error"))
+
+		tp := New(httpClient, testutil.MustParseURL(publicKeyID), DefaultSigner(), DefaultSigner(),
+			WithCircuitBreaker(NewCircuitBreaker(&CircuitBreakerConfig{InitialBackoff: time.Minute})))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		//nolint:bodyclose
+		_, err := tp.Get(ctx, NewRequest(u))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, httpClient.DoCallCount())
+	})
+
+	t.Run("GET sign error", func(t *testing.T) {
+		signer := &mocks.HTTPSigner{}
+		signer.SignRequestReturns(errors.New("injected signer error"))
+
+		httpClient := &mocks.HTTPClient{}
+
+		tp := New(httpClient, testutil.MustParseURL(publicKeyID), signer, signer,
+			WithCircuitBreaker(NewCircuitBreaker(&CircuitBreakerConfig{})))
+
+		//nolint:bodyclose
+		_, err := tp.Get(context.Background(), NewRequest(u))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected signer error")
+		require.Equal(t, 0, httpClient.DoCallCount())
+
+		//nolint:bodyclose
+		_, err = tp.Post(context.Background(), NewRequest(u), nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected signer error")
+		require.Equal(t, 0, httpClient.DoCallCount())
+		require.Equal(t, 0, tp.Health(u).ConsecutiveFailures)
+	})
+
+	t.Run("POST -> no retry", func(t *testing.T) {
+		httpClient := &mocks.HTTPClient{}
+		httpClient.DoReturns(&http.Response{StatusCode: http.StatusInternalServerError}, nil)
+
+		tp := New(httpClient, testutil.MustParseURL(publicKeyID), DefaultSigner(), DefaultSigner(),
+			WithCircuitBreaker(NewCircuitBreaker(&CircuitBreakerConfig{})))
+
+		//nolint:bodyclose
+		resp, err := tp.Post(context.Background(), NewRequest(u), []byte("payload"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.Equal(t, 1, httpClient.DoCallCount())
+		require.Equal(t, 1, tp.Health(u).ConsecutiveFailures)
+	})
+
+	t.Run("No circuit breaker", func(t *testing.T) {
+		tp := Default()
+		require.True(t, tp.IsHealthy(u))
+		require.Nil(t, tp.Health(u))
+	})
+}
@@ -7,43 +7,99 @@ SPDX-License-Identifier: Apache-2.0
 package httpsig
 
 import (
+	gocrypto "crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"math/big"
 	"net/url"
+	"strings"
 
 	"github.com/hyperledger/aries-framework-go/pkg/crypto"
 	ariesverifier "github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	httpsig "github.com/igor-pavlenko/httpsignatures-go"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+// Supported HTTP signature algorithms.
+const (
+	// AlgorithmHS2019 indicates that the signature algorithm is derived from the type of key.
+	AlgorithmHS2019 = "hs2019"
+	// AlgorithmRSASHA256 is RSASSA-PKCS1-v1_5 using SHA-256.
+	AlgorithmRSASHA256 = "rsa-sha256"
+	// AlgorithmECDSASHA256 is ECDSA using curve P-256 and SHA-256.
+	AlgorithmECDSASHA256 = "ecdsa-sha256"
+	// AlgorithmED25519 is EdDSA using curve 25519.
+	AlgorithmED25519 = "ed25519"
+
+	// AlgorithmLegacyOrb is the algorithm name used by previous versions of Orb. It is treated the same as hs2019.
+	AlgorithmLegacyOrb = "https://github.com/trustbloc/orb/httpsig"
 )
 
-const orbHTTPSigAlgorithm = "https://github.com/trustbloc/orb/httpsig"
+// verifierAlgorithms contains the algorithms that are accepted when verifying signatures. An empty
+// algorithm is allowed since the 'algorithm' parameter in the signature header is optional.
+var verifierAlgorithms = []string{
+	"", AlgorithmHS2019, AlgorithmRSASHA256, AlgorithmECDSASHA256, AlgorithmED25519, AlgorithmLegacyOrb,
+}
 
 // ErrInvalidSignature indicates that the signature is not valid for the given data.
 var ErrInvalidSignature = errors.New("invalid HTTP signature")
 
+// ErrUnsupportedKeyType indicates that the type of public key is not supported.
+var ErrUnsupportedKeyType = errors.New("unsupported public key type")
+
 type keyResolver interface {
 	// Resolve returns the public key bytes and the type of public key for the given key ID.
 	Resolve(keyID string) (*ariesverifier.PublicKey, error)
 }
 
+type keyRefresher interface {
+	// Refresh re-resolves the public key for the given key ID, bypassing any cached value.
+	Refresh(keyID string) (*ariesverifier.PublicKey, error)
+}
+
+// SigningKeyResolver resolves the ID of the KMS key that is used to sign requests for the given public key ID.
+type SigningKeyResolver interface {
+	ResolveSigningKey(pubKeyID string) (string, error)
+}
+
+type staticSigningKey string
+
+func (k staticSigningKey) ResolveSigningKey(string) (string, error) {
+	return string(k), nil
+}
+
 // SignatureHashAlgorithm is a custom httpsignatures.SignatureHashAlgorithm that uses KMS to sign HTTP requests.
 type SignatureHashAlgorithm struct {
 	Crypto      crypto.Crypto
 	KMS         kms.KeyManager
 	keyResolver keyResolver
-	keyID       string
+	signingKey  SigningKeyResolver
+	name        string
 }
 
 // NewSignerAlgorithm returns a new SignatureHashAlgorithm which uses KMS to sign HTTP requests.
 func NewSignerAlgorithm(c crypto.Crypto, km kms.KeyManager, keyID string) *SignatureHashAlgorithm {
+	return NewSignerAlgorithmWithKeyResolver(c, km, staticSigningKey(keyID))
+}
+
+// NewSignerAlgorithmWithKeyResolver returns a new SignatureHashAlgorithm which uses KMS to sign HTTP requests.
+// The KMS key is resolved from the public key ID for each request, which allows the signing key to be rotated.
+func NewSignerAlgorithmWithKeyResolver(c crypto.Crypto, km kms.KeyManager,
+	signingKey SigningKeyResolver) *SignatureHashAlgorithm {
 	return &SignatureHashAlgorithm{
-		Crypto: c,
-		KMS:    km,
-		keyID:  keyID,
+		Crypto:     c,
+		KMS:        km,
+		signingKey: signingKey,
+		name:       AlgorithmHS2019,
 	}
 }
 
@@ -54,17 +110,32 @@ func NewVerifierAlgorithm(c crypto.Crypto, km kms.KeyManager, keyResolver keyRes
 		Crypto:      c,
 		KMS:         km,
 		keyResolver: keyResolver,
+		name:        AlgorithmHS2019,
 	}
 }
 
 // Algorithm returns this algorithm's name.
 func (a *SignatureHashAlgorithm) Algorithm() string {
-	return orbHTTPSigAlgorithm
+	return a.name
+}
+
+// withName returns a copy of the algorithm with the given name. The HTTP signature library looks up algorithms
+// by name so a copy is registered for each of the supported algorithm names.
+func (a *SignatureHashAlgorithm) withName(name string) *SignatureHashAlgorithm {
+	algo := *a
+	algo.name = name
+
+	return &algo
 }
 
 // Create signs data with the secret.
 func (a *SignatureHashAlgorithm) Create(secret httpsig.Secret, data []byte) ([]byte, error) {
-	kh, err := a.KMS.Get(a.keyID)
+	keyID, err := a.signingKey.ResolveSigningKey(secret.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve signing key for public key [%s]: %w", secret.KeyID, err)
+	}
+
+	kh, err := a.KMS.Get(keyID)
 	if err != nil {
 		return nil, fmt.Errorf("get key handle: %w", err)
 	}
@@ -76,12 +147,14 @@ func (a *SignatureHashAlgorithm) Create(secret httpsig.Secret, data []byte) ([]b
 		return nil, fmt.Errorf("sign data: %w", err)
 	}
 
-	logger.Debugf("... successfully signed data with keyID from KMS [%s]", a.keyID)
+	logger.Debugf("... successfully signed data with keyID from KMS [%s]", keyID)
 
 	return sig, nil
 }
 
-// Verify verifies the signature over data with the secret.
+// Verify verifies the signature over data with the secret. If the signature can't be verified and the key
+// resolver supports refreshing keys then the key is refreshed (since the resolved key may be stale) and the
+// signature is verified again.
 func (a *SignatureHashAlgorithm) Verify(secret httpsig.Secret, data, signature []byte) error {
 	pubKey, err := a.keyResolver.Resolve(secret.KeyID)
 	if err != nil {
@@ -90,15 +163,145 @@ func (a *SignatureHashAlgorithm) Verify(secret httpsig.Secret, data, signature [
 
 	logger.Debugf("Got key %+v from keyID [%s]", pubKey, secret.KeyID)
 
-	if !ed25519.Verify(pubKey.Value, data, signature) {
+	err = a.verify(pubKey, data, signature)
+	if err == nil {
+		logger.Debugf("Successfully verified signature using keyID [%s]", secret.KeyID)
+
+		return nil
+	}
+
+	logger.Debugf("Error verifying signature using keyID [%s]: %s", secret.KeyID, err)
+
+	refresher, ok := a.keyResolver.(keyRefresher)
+	if !ok {
 		return ErrInvalidSignature
 	}
 
-	logger.Debugf("Successfully verified signature using keyID [%s]", secret.KeyID)
+	logger.Debugf("Signature verification failed using keyID [%s]. Refreshing key and trying again.", secret.KeyID)
+
+	pubKey, err = refresher.Refresh(secret.KeyID)
+	if err != nil {
+		logger.Debugf("Unable to refresh key [%s]: %s", secret.KeyID, err)
+
+		return ErrInvalidSignature
+	}
+
+	if err := a.verify(pubKey, data, signature); err != nil {
+		return err
+	}
+
+	logger.Debugf("Successfully verified signature using refreshed keyID [%s]", secret.KeyID)
+
+	return nil
+}
+
+func (a *SignatureHashAlgorithm) verify(pubKey *ariesverifier.PublicKey, data, signature []byte) error {
+	keyType := pubKey.Type
+	if keyType == "" {
+		keyType = kms.ED25519
+	}
+
+	if !isAlgorithmSupportedForKey(a.name, keyType) {
+		return fmt.Errorf("%w: algorithm [%s] is not supported for key type [%s]",
+			ErrInvalidSignature, a.name, keyType)
+	}
+
+	var valid bool
+
+	switch keyType {
+	case kms.ED25519:
+		valid = len(pubKey.Value) == ed25519.PublicKeySize && ed25519.Verify(pubKey.Value, data, signature)
+	case kms.RSARS256:
+		valid = verifyRSA(pubKey.Value, data, signature)
+	case kms.ECDSAP256DER:
+		valid = verifyECDSA(pubKey.Value, data, signature)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedKeyType, keyType)
+	}
+
+	if !valid {
+		return ErrInvalidSignature
+	}
 
 	return nil
 }
 
+func verifyRSA(pubKeyBytes, data, signature []byte) bool {
+	pubKey, err := x509.ParsePKCS1PublicKey(pubKeyBytes)
+	if err != nil {
+		logger.Debugf("Invalid RSA public key: %s", err)
+
+		return false
+	}
+
+	hash := sha256.Sum256(data)
+
+	return rsa.VerifyPKCS1v15(pubKey, gocrypto.SHA256, hash[:], signature) == nil
+}
+
+func verifyECDSA(pubKeyBytes, data, signature []byte) bool {
+	x, y := elliptic.Unmarshal(elliptic.P256(), pubKeyBytes)
+	if x == nil {
+		logger.Debugf("Invalid ECDSA public key")
+
+		return false
+	}
+
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+
+	hash := sha256.Sum256(data)
+
+	if ecdsa.VerifyASN1(pubKey, hash[:], signature) {
+		return true
+	}
+
+	// Also accept signatures in IEEE P1363 format (r||s).
+	const p1363SignatureSize = 64
+
+	if len(signature) != p1363SignatureSize {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(signature[:p1363SignatureSize/2])
+	s := new(big.Int).SetBytes(signature[p1363SignatureSize/2:])
+
+	return ecdsa.Verify(pubKey, hash[:], r, s)
+}
+
+// isAlgorithmSupportedForKey returns true if the given HTTP signature algorithm may be used with the given key type.
+// For hs2019 (and the legacy Orb algorithm) the algorithm is derived from the key.
+func isAlgorithmSupportedForKey(algorithm, keyType string) bool {
+	switch strings.ToLower(algorithm) {
+	case "", AlgorithmHS2019, AlgorithmLegacyOrb:
+		return true
+	case AlgorithmRSASHA256:
+		return keyType == kms.RSARS256
+	case AlgorithmECDSASHA256:
+		return keyType == kms.ECDSAP256DER
+	case AlgorithmED25519:
+		return keyType == kms.ED25519
+	default:
+		return false
+	}
+}
+
+// AlgorithmForPublicKeyPem returns the HTTP signature algorithm for the type of key in the given PEM.
+func AlgorithmForPublicKeyPem(publicKeyPem string) (string, error) {
+	pubKey, err := parsePublicKeyPem(publicKeyPem)
+	if err != nil {
+		return "", err
+	}
+
+	switch pubKey.Type {
+	case kms.RSARS256:
+		return AlgorithmRSASHA256, nil
+	case kms.ECDSAP256DER:
+		return AlgorithmECDSASHA256, nil
+	default:
+		return AlgorithmED25519, nil
+	}
+}
+
 // KeyResolver resolves the public key for an ActivityPub actor.
 type KeyResolver struct {
 	pubKeyRetriever actorRetriever
@@ -111,6 +314,22 @@ func NewKeyResolver(actorRetriever actorRetriever) *KeyResolver {
 
 // Resolve returns the public key for the given key ID.
 func (r *KeyResolver) Resolve(keyID string) (*ariesverifier.PublicKey, error) {
+	return r.resolve(keyID, r.pubKeyRetriever.GetPublicKey)
+}
+
+// Refresh returns the public key for the given key ID after refreshing the key's owner. An error is returned
+// if the underlying actor retriever doesn't support refreshing keys.
+func (r *KeyResolver) Refresh(keyID string) (*ariesverifier.PublicKey, error) {
+	refresher, ok := r.pubKeyRetriever.(publicKeyRefresher)
+	if !ok {
+		return nil, errors.New("key refresh not supported")
+	}
+
+	return r.resolve(keyID, refresher.RefreshPublicKey)
+}
+
+func (r *KeyResolver) resolve(keyID string,
+	getPublicKey func(keyIRI *url.URL) (*vocab.PublicKeyType, error)) (*ariesverifier.PublicKey, error) {
 	keyIRI, err := url.Parse(keyID)
 	if err != nil {
 		logger.Errorf("Error parsing public key IRI [%s]: %s", keyID, err)
@@ -120,38 +339,75 @@ func (r *KeyResolver) Resolve(keyID string) (*ariesverifier.PublicKey, error) {
 
 	logger.Debugf("Retrieving public key for key IRI [%s]", keyIRI)
 
-	pubKey, err := r.pubKeyRetriever.GetPublicKey(keyIRI)
+	pubKey, err := getPublicKey(keyIRI)
 	if err != nil {
 		logger.Errorf("Error retrieving public key for IRI [%s]: %s", keyIRI, err)
 
 		return nil, fmt.Errorf("retrieve public key for ID [%s]: %w", keyID, err)
 	}
 
-	block, rest := pem.Decode([]byte(pubKey.PublicKeyPem))
+	pk, err := parsePublicKeyPem(pubKey.PublicKeyPem)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key for ID [%s]: %w", keyID, err)
+	}
+
+	return pk, nil
+}
+
+// parsePublicKeyPem parses the PEM-encoded public key. The type of the returned key is
+// determined from the key in the PEM.
+func parsePublicKeyPem(publicKeyPem string) (*ariesverifier.PublicKey, error) {
+	block, rest := pem.Decode([]byte(publicKeyPem))
 	if block == nil {
 		logger.Warnf("invalid public key: nil block. Rest: %s", rest)
 
-		return nil, fmt.Errorf("invalid public key for ID [%s]: nil block", keyID)
+		return nil, errors.New("nil block")
 	}
 
 	pk, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
-		return nil, fmt.Errorf("parse public key for ID [%s]: %w", keyID, err)
+		return nil, fmt.Errorf("parse public key: %w", err)
 	}
 
-	return &ariesverifier.PublicKey{
-		Type:  kms.ED25519, // TODO: Support other algorithms?
-		Value: pk.(ed25519.PublicKey),
-	}, nil
+	switch key := pk.(type) {
+	case ed25519.PublicKey:
+		return &ariesverifier.PublicKey{
+			Type:  kms.ED25519,
+			Value: key,
+		}, nil
+	case *rsa.PublicKey:
+		return &ariesverifier.PublicKey{
+			Type:  kms.RSARS256,
+			Value: x509.MarshalPKCS1PublicKey(key),
+		}, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: ECDSA curve %s", ErrUnsupportedKeyType, key.Curve.Params().Name)
+		}
+
+		return &ariesverifier.PublicKey{
+			Type:  kms.ECDSAP256DER,
+			Value: elliptic.Marshal(key.Curve, key.X, key.Y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKeyType, pk)
+	}
 }
 
 // SecretRetriever implements a custom key retriever to be used with the HTTP signature library.
-type SecretRetriever struct{}
+type SecretRetriever struct {
+	algorithm string
+}
+
+// NewSecretRetriever returns a secret retriever for the given HTTP signature algorithm.
+func NewSecretRetriever(algorithm string) *SecretRetriever {
+	return &SecretRetriever{algorithm: algorithm}
+}
 
 // Get returns a 'secret' that directs the HTTP signature library to use the custom SignatureHashAlgorithm above.
 func (r *SecretRetriever) Get(keyID string) (httpsig.Secret, error) {
 	return httpsig.Secret{
 		KeyID:     keyID,
-		Algorithm: orbHTTPSigAlgorithm,
+		Algorithm: r.algorithm,
 	}, nil
 }
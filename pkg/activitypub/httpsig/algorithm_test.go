@@ -7,10 +7,14 @@ SPDX-License-Identifier: Apache-2.0
 package httpsig
 
 import (
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"errors"
 	"fmt"
+	"net/url"
 	"testing"
 
 	verifier2 "github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
@@ -38,7 +42,7 @@ func TestSignatureHashAlgorithm_Create(t *testing.T) {
 
 	algo := NewSignerAlgorithm(cr, km, kmsKeyID)
 	require.NotNil(t, algo)
-	require.Equal(t, orbHTTPSigAlgorithm, algo.Algorithm())
+	require.Equal(t, AlgorithmHS2019, algo.Algorithm())
 
 	secret := httpsignatures.Secret{
 		KeyID: pubKeyID,
@@ -75,6 +79,38 @@ func TestSignatureHashAlgorithm_Create(t *testing.T) {
 		require.Contains(t, err.Error(), km.GetKeyErr.Error())
 		require.Nil(t, signature)
 	})
+
+	t.Run("Signing key resolver", func(t *testing.T) {
+		km.GetKeyErr = nil
+		cr.SignValue = []byte("signature")
+		cr.SignErr = nil
+
+		signingKeys := &mockSigningKeyResolver{keys: map[string]string{pubKeyID: kmsKeyID}}
+
+		algo := NewSignerAlgorithmWithKeyResolver(cr, km, signingKeys)
+
+		signature, err := algo.Create(secret, data)
+		require.NoError(t, err)
+		require.Equal(t, cr.SignValue, signature)
+
+		signature, err = algo.Create(httpsignatures.Secret{KeyID: "https://example.com/keys/unknown"}, data)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "resolve signing key")
+		require.Nil(t, signature)
+	})
+}
+
+type mockSigningKeyResolver struct {
+	keys map[string]string
+}
+
+func (m *mockSigningKeyResolver) ResolveSigningKey(pubKeyID string) (string, error) {
+	keyID, ok := m.keys[pubKeyID]
+	if !ok {
+		return "", fmt.Errorf("signing key not found for [%s]", pubKeyID)
+	}
+
+	return keyID, nil
 }
 
 func TestSignatureHashAlgorithm_Verify(t *testing.T) {
@@ -86,7 +122,7 @@ func TestSignatureHashAlgorithm_Verify(t *testing.T) {
 
 	algo := NewVerifierAlgorithm(cr, km, resolver)
 	require.NotNil(t, algo)
-	require.Equal(t, orbHTTPSigAlgorithm, algo.Algorithm())
+	require.Equal(t, AlgorithmHS2019, algo.Algorithm())
 
 	secret := httpsignatures.Secret{
 		KeyID: pubKeyID,
@@ -126,6 +162,42 @@ func TestSignatureHashAlgorithm_Verify(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), errExpected.Error())
 	})
+
+	t.Run("Stale key -> refresh", func(t *testing.T) {
+		stalePubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		refreshingResolver := &mockRefreshingKeyResolver{
+			KeyResolver: &mocks.KeyResolver{},
+		}
+
+		refreshingResolver.ResolveReturns(&verifier2.PublicKey{Value: stalePubKey}, nil)
+
+		algo := NewVerifierAlgorithm(cr, km, refreshingResolver)
+
+		t.Run("Success", func(t *testing.T) {
+			refreshingResolver.refreshedKey = &verifier2.PublicKey{Value: pubKey}
+			refreshingResolver.refreshErr = nil
+
+			require.NoError(t, algo.Verify(secret, data, signature))
+		})
+
+		t.Run("Refreshed key is invalid", func(t *testing.T) {
+			refreshingResolver.refreshedKey = &verifier2.PublicKey{Value: stalePubKey}
+			refreshingResolver.refreshErr = nil
+
+			err := algo.Verify(secret, data, signature)
+			require.True(t, errors.Is(err, ErrInvalidSignature))
+		})
+
+		t.Run("Refresh error", func(t *testing.T) {
+			refreshingResolver.refreshedKey = nil
+			refreshingResolver.refreshErr = errors.New("injected refresh error")
+
+			err := algo.Verify(secret, data, signature)
+			require.True(t, errors.Is(err, ErrInvalidSignature))
+		})
+	})
 }
 
 func TestKeyResolver_Resolve(t *testing.T) {
@@ -187,4 +259,110 @@ func TestKeyResolver_Resolve(t *testing.T) {
 		require.Contains(t, err.Error(), "invalid public key")
 		require.Nil(t, pk)
 	})
+
+	t.Run("Unsupported ECDSA curve", func(t *testing.T) {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		require.NoError(t, err)
+
+		ecPubKeyPem, err := getPublicKeyPem(&ecKey.PublicKey)
+		require.NoError(t, err)
+
+		resolver := NewKeyResolver(servicemocks.NewActorRetriever().
+			WithPublicKey(vocab.NewPublicKey(
+				vocab.WithID(pubKeyIRI),
+				vocab.WithPublicKeyPem(string(ecPubKeyPem)),
+			)))
+
+		pk, err := resolver.Resolve(pubKeyIRI.String())
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrUnsupportedKeyType))
+		require.Nil(t, pk)
+	})
+}
+
+func TestAlgorithmForPublicKeyPem(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	edPubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		pubKey    interface{}
+		algorithm string
+	}{
+		{pubKey: &rsaKey.PublicKey, algorithm: AlgorithmRSASHA256},
+		{pubKey: &ecKey.PublicKey, algorithm: AlgorithmECDSASHA256},
+		{pubKey: edPubKey, algorithm: AlgorithmED25519},
+	}
+
+	for _, tc := range tests {
+		pubKeyPem, err := getPublicKeyPem(tc.pubKey)
+		require.NoError(t, err)
+
+		algorithm, err := AlgorithmForPublicKeyPem(string(pubKeyPem))
+		require.NoError(t, err)
+		require.Equal(t, tc.algorithm, algorithm)
+	}
+
+	_, err = AlgorithmForPublicKeyPem("invalid")
+	require.Error(t, err)
+}
+
+func TestKeyResolver_Refresh(t *testing.T) {
+	actorIRI := testutil.MustParseURL("https://example.com/services/orb")
+	pubKeyIRI := testutil.NewMockID(actorIRI, "/keys/main-key")
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	pubKeyPem, err := getPublicKeyPem(pubKey)
+	require.NoError(t, err)
+
+	pubKeyRetriever := servicemocks.NewActorRetriever().
+		WithPublicKey(vocab.NewPublicKey(
+			vocab.WithID(pubKeyIRI),
+			vocab.WithPublicKeyPem(string(pubKeyPem)),
+		))
+
+	t.Run("Success", func(t *testing.T) {
+		resolver := NewKeyResolver(&mockRefreshingActorRetriever{ActorRetriever: pubKeyRetriever})
+		require.NotNil(t, resolver)
+
+		pk, err := resolver.Refresh(pubKeyIRI.String())
+		require.NoError(t, err)
+		require.NotNil(t, pk)
+	})
+
+	t.Run("Refresh not supported", func(t *testing.T) {
+		resolver := NewKeyResolver(pubKeyRetriever)
+		require.NotNil(t, resolver)
+
+		pk, err := resolver.Refresh(pubKeyIRI.String())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "key refresh not supported")
+		require.Nil(t, pk)
+	})
+}
+
+type mockRefreshingKeyResolver struct {
+	*mocks.KeyResolver
+
+	refreshedKey *verifier2.PublicKey
+	refreshErr   error
+}
+
+func (m *mockRefreshingKeyResolver) Refresh(string) (*verifier2.PublicKey, error) {
+	return m.refreshedKey, m.refreshErr
+}
+
+type mockRefreshingActorRetriever struct {
+	*servicemocks.ActorRetriever
+}
+
+func (m *mockRefreshingActorRetriever) RefreshPublicKey(keyIRI *url.URL) (*vocab.PublicKeyType, error) {
+	return m.GetPublicKey(keyIRI)
 }
@@ -40,6 +40,11 @@ func DefaultPostSignerConfig() SignerConfig {
 // SignerConfig contains the configuration for signing HTTP requests.
 type SignerConfig struct {
 	Headers []string
+
+	// Algorithm is the HTTP signature algorithm that is set in the signature header. The algorithm should
+	// correspond to the type of signing key (see AlgorithmForPublicKeyPem), or be hs2019 or the legacy Orb
+	// algorithm, in which case the verifier derives the algorithm from the key. Defaults to hs2019.
+	Algorithm string
 }
 
 type signer interface {
@@ -54,8 +59,23 @@ type Signer struct {
 
 // NewSigner returns a new signer.
 func NewSigner(cfg SignerConfig, cr crypto.Crypto, km kms.KeyManager, keyID string) *Signer {
-	algo := NewSignerAlgorithm(cr, km, keyID)
-	secretRetriever := &SecretRetriever{}
+	return newSigner(cfg, NewSignerAlgorithm(cr, km, keyID))
+}
+
+// NewSignerWithKeyResolver returns a new signer which resolves the KMS signing key from
+// the public key ID of each request.
+func NewSignerWithKeyResolver(cfg SignerConfig, cr crypto.Crypto, km kms.KeyManager,
+	signingKey SigningKeyResolver) *Signer {
+	return newSigner(cfg, NewSignerAlgorithmWithKeyResolver(cr, km, signingKey))
+}
+
+func newSigner(cfg SignerConfig, algo *SignatureHashAlgorithm) *Signer {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmHS2019
+	}
+
+	algo = algo.withName(cfg.Algorithm)
+	secretRetriever := NewSecretRetriever(cfg.Algorithm)
 
 	return &Signer{
 		SignerConfig: cfg,
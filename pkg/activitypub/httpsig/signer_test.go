@@ -61,4 +61,46 @@ func TestSigner(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), err.Error())
 	})
+
+	t.Run("Algorithm", func(t *testing.T) {
+		s := NewSigner(DefaultGetSignerConfig(), &mockcrypto.Crypto{}, &mockkms.KeyManager{}, keyID)
+
+		req, err := http.NewRequest(http.MethodGet, "https://domain1.com", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, s.SignRequest("pubKeyID", req))
+		require.Contains(t, req.Header.Get("Signature"), `algorithm="hs2019"`)
+
+		cfg := DefaultGetSignerConfig()
+		cfg.Algorithm = AlgorithmED25519
+
+		s = NewSigner(cfg, &mockcrypto.Crypto{}, &mockkms.KeyManager{}, keyID)
+
+		req, err = http.NewRequest(http.MethodGet, "https://domain1.com", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, s.SignRequest("pubKeyID", req))
+		require.Contains(t, req.Header.Get("Signature"), `algorithm="ed25519"`)
+
+		cfg.Algorithm = AlgorithmLegacyOrb
+
+		s = NewSigner(cfg, &mockcrypto.Crypto{}, &mockkms.KeyManager{}, keyID)
+
+		req, err = http.NewRequest(http.MethodGet, "https://domain1.com", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, s.SignRequest("pubKeyID", req))
+		require.Contains(t, req.Header.Get("Signature"), `algorithm="`+AlgorithmLegacyOrb+`"`)
+	})
+
+	t.Run("With signing key resolver", func(t *testing.T) {
+		s := NewSignerWithKeyResolver(DefaultGetSignerConfig(), &mockcrypto.Crypto{}, &mockkms.KeyManager{},
+			&mockSigningKeyResolver{keys: map[string]string{"pubKeyID": keyID}})
+
+		req, err := http.NewRequest(http.MethodGet, "https://domain1.com", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, s.SignRequest("pubKeyID", req))
+		require.NotEmpty(t, req.Header["Signature"])
+	})
 }
@@ -29,6 +29,12 @@ type actorRetriever interface {
 	GetActor(actorIRI *url.URL) (*vocab.ActorType, error)
 }
 
+// publicKeyRefresher is implemented by actor retrievers that cache keys. RefreshPublicKey
+// bypasses the cache and retrieves the latest version of the key.
+type publicKeyRefresher interface {
+	RefreshPublicKey(keyIRI *url.URL) (*vocab.PublicKeyType, error)
+}
+
 type verifier interface {
 	Verify(r *http.Request) error
 }
@@ -36,21 +42,24 @@ type verifier interface {
 // Verifier verifies signatures of HTTP requests.
 type Verifier struct {
 	actorRetriever actorRetriever
-	verifier       func() verifier
+	verifier       func(algorithm string) verifier
 }
 
-// NewVerifier returns a new HTTP signature verifier.
+// NewVerifier returns a new HTTP signature verifier. The hs2019, rsa-sha256, ecdsa-sha256 and ed25519
+// algorithms are supported. The signature is verified according to the type of the actor's public key.
 func NewVerifier(actorRetriever actorRetriever, cr crypto.Crypto, km kms.KeyManager) *Verifier {
 	algo := NewVerifierAlgorithm(cr, km, NewKeyResolver(actorRetriever))
-	secretRetriever := &SecretRetriever{}
 
 	return &Verifier{
 		actorRetriever: actorRetriever,
-		verifier: func() verifier {
+		verifier: func(algorithm string) verifier {
 			// Return a new instance for each verification since the HTTP signature
 			// implementation is not thread safe.
-			hs := httpsig.NewHTTPSignatures(secretRetriever)
-			hs.SetSignatureHashAlgorithm(algo)
+			hs := httpsig.NewHTTPSignatures(NewSecretRetriever(algorithm))
+
+			for _, name := range verifierAlgorithms {
+				hs.SetSignatureHashAlgorithm(algo.withName(name))
+			}
 
 			return hs
 		},
@@ -68,14 +77,14 @@ func NewVerifier(actorRetriever actorRetriever, cr crypto.Crypto, km kms.KeyMana
 func (v *Verifier) VerifyRequest(req *http.Request) (bool, *url.URL, error) {
 	logger.Debugf("Verifying request. Headers: %s", req.Header)
 
-	err := v.verifier().Verify(req)
+	err := v.verifier(getSignatureParam(req, "algorithm")).Verify(req)
 	if err != nil {
 		logger.Infof("Signature verification failed for request %s: %s", req.URL, err)
 
 		return false, nil, nil
 	}
 
-	keyID := getKeyIDFromSignatureHeader(req)
+	keyID := getSignatureParam(req, "keyId")
 	if keyID == "" {
 		logger.Debugf("'keyId' not found in Signature header in request %s", req.URL)
 
@@ -123,7 +132,7 @@ func (v *Verifier) VerifyRequest(req *http.Request) (bool, *url.URL, error) {
 	return true, actor.ID().URL(), nil
 }
 
-func getKeyIDFromSignatureHeader(req *http.Request) string {
+func getSignatureParam(req *http.Request, name string) string {
 	signatureHeader, ok := req.Header["Signature"]
 	if !ok || len(signatureHeader) == 0 {
 		logger.Debugf("'Signature' not found in request header for request %s", req.URL)
@@ -131,7 +140,7 @@ func getKeyIDFromSignatureHeader(req *http.Request) string {
 		return ""
 	}
 
-	var keyID string
+	var value string
 
 	const kvLength = 2
 
@@ -142,11 +151,11 @@ func getKeyIDFromSignatureHeader(req *http.Request) string {
 				continue
 			}
 
-			if parts[0] == "keyId" {
-				keyID = strings.ReplaceAll(parts[1], `"`, "")
+			if strings.TrimSpace(parts[0]) == name {
+				value = strings.ReplaceAll(parts[1], `"`, "")
 			}
 		}
 	}
 
-	return keyID
+	return value
 }
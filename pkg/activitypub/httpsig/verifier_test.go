@@ -8,8 +8,11 @@ package httpsig
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -18,6 +21,7 @@ import (
 
 	mockcrypto "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
 	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	"github.com/igor-pavlenko/httpsignatures-go"
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/orb/pkg/activitypub/mocks"
@@ -83,7 +87,7 @@ func TestVerifier_VerifyRequest(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		v := &Verifier{
 			actorRetriever: retriever,
-			verifier:       func() verifier { return &mocks.HTTPSignatureVerifier{} },
+			verifier:       func(string) verifier { return &mocks.HTTPSignatureVerifier{} },
 		}
 
 		req, err := http.NewRequest(http.MethodPost, "https://domain1.com", bytes.NewBuffer(payload))
@@ -118,7 +122,7 @@ func TestVerifier_VerifyRequest(t *testing.T) {
 	t.Run("Key ID not found in signature header", func(t *testing.T) {
 		v := &Verifier{
 			actorRetriever: retriever,
-			verifier:       func() verifier { return &mocks.HTTPSignatureVerifier{} },
+			verifier:       func(string) verifier { return &mocks.HTTPSignatureVerifier{} },
 		}
 
 		req, err := http.NewRequest(http.MethodPost, "https://domain1.com", bytes.NewBuffer(payload))
@@ -135,7 +139,7 @@ func TestVerifier_VerifyRequest(t *testing.T) {
 	t.Run("Invalid key ID", func(t *testing.T) {
 		v := &Verifier{
 			actorRetriever: retriever,
-			verifier:       func() verifier { return &mocks.HTTPSignatureVerifier{} },
+			verifier:       func(string) verifier { return &mocks.HTTPSignatureVerifier{} },
 		}
 
 		req, err := http.NewRequest(http.MethodPost, "https://domain1.com", bytes.NewBuffer(payload))
@@ -152,7 +156,7 @@ func TestVerifier_VerifyRequest(t *testing.T) {
 	t.Run("Public key not found -> error", func(t *testing.T) {
 		v := &Verifier{
 			actorRetriever: retriever,
-			verifier:       func() verifier { return &mocks.HTTPSignatureVerifier{} },
+			verifier:       func(string) verifier { return &mocks.HTTPSignatureVerifier{} },
 		}
 
 		req, err := http.NewRequest(http.MethodPost, "https://domain1.com", bytes.NewBuffer(payload))
@@ -170,7 +174,7 @@ func TestVerifier_VerifyRequest(t *testing.T) {
 	t.Run("Actor not found -> error", func(t *testing.T) {
 		v := &Verifier{
 			actorRetriever: servicemocks.NewActorRetriever().WithPublicKey(publicKey),
-			verifier:       func() verifier { return &mocks.HTTPSignatureVerifier{} },
+			verifier:       func(string) verifier { return &mocks.HTTPSignatureVerifier{} },
 		}
 
 		req, err := http.NewRequest(http.MethodPost, "https://domain1.com", bytes.NewBuffer(payload))
@@ -190,7 +194,7 @@ func TestVerifier_VerifyRequest(t *testing.T) {
 			actorRetriever: servicemocks.NewActorRetriever().
 				WithPublicKey(publicKey).
 				WithActor(aptestutil.NewMockService(actorIRI, aptestutil.WithPublicKey(nil))),
-			verifier: func() verifier { return &mocks.HTTPSignatureVerifier{} },
+			verifier: func(string) verifier { return &mocks.HTTPSignatureVerifier{} },
 		}
 
 		req, err := http.NewRequest(http.MethodPost, "https://domain1.com", bytes.NewBuffer(payload))
@@ -215,7 +219,7 @@ func TestVerifier_VerifyRequest(t *testing.T) {
 			actorRetriever: servicemocks.NewActorRetriever().
 				WithPublicKey(publicKey).
 				WithActor(aptestutil.NewMockService(actorIRI, aptestutil.WithPublicKey(actorPublicKey))),
-			verifier: func() verifier { return &mocks.HTTPSignatureVerifier{} },
+			verifier: func(string) verifier { return &mocks.HTTPSignatureVerifier{} },
 		}
 
 		req, err := http.NewRequest(http.MethodPost, "https://domain1.com", bytes.NewBuffer(payload))
@@ -230,6 +234,122 @@ func TestVerifier_VerifyRequest(t *testing.T) {
 	})
 }
 
+func TestVerifier_Algorithms(t *testing.T) {
+	actorIRI := testutil.MustParseURL("https://example.com/services/orb")
+	pubKeyIRI := testutil.NewMockID(actorIRI, "/keys/main-key")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	edPubKey, edPrivKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		algorithm  string
+		publicKey  interface{}
+		privateKey interface{}
+		valid      bool
+	}{
+		{name: "rsa-sha256", algorithm: AlgorithmRSASHA256, publicKey: &rsaKey.PublicKey, privateKey: rsaKey, valid: true},
+		{name: "hs2019 with RSA key", algorithm: AlgorithmHS2019, publicKey: &rsaKey.PublicKey, privateKey: rsaKey,
+			valid: true},
+		{name: "ecdsa-sha256", algorithm: AlgorithmECDSASHA256, publicKey: &ecKey.PublicKey, privateKey: ecKey,
+			valid: true},
+		{name: "hs2019 with ECDSA key", algorithm: AlgorithmHS2019, publicKey: &ecKey.PublicKey, privateKey: ecKey,
+			valid: true},
+		{name: "ed25519", algorithm: AlgorithmED25519, publicKey: edPubKey, privateKey: edPrivKey, valid: true},
+		{name: "rsa-sha256 with ECDSA key", algorithm: AlgorithmRSASHA256, publicKey: &ecKey.PublicKey,
+			privateKey: rsaKey, valid: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			pubKeyPem, err := getPublicKeyPem(tc.publicKey)
+			require.NoError(t, err)
+
+			publicKey := vocab.NewPublicKey(
+				vocab.WithID(pubKeyIRI),
+				vocab.WithOwner(actorIRI),
+				vocab.WithPublicKeyPem(string(pubKeyPem)),
+			)
+
+			retriever := servicemocks.NewActorRetriever().
+				WithPublicKey(publicKey).
+				WithActor(aptestutil.NewMockService(actorIRI, aptestutil.WithPublicKey(publicKey)))
+
+			v := NewVerifier(retriever, &mockcrypto.Crypto{}, &mockkms.KeyManager{})
+
+			req, err := http.NewRequest(http.MethodPost, "https://domain1.com/services/orb/inbox",
+				bytes.NewBuffer([]byte("payload")))
+			require.NoError(t, err)
+
+			signRequest(t, req, pubKeyIRI.String(), tc.algorithm, tc.privateKey)
+
+			ok, actorID, err := v.VerifyRequest(req)
+			require.NoError(t, err)
+			require.Equal(t, tc.valid, ok)
+
+			if tc.valid {
+				require.Equal(t, actorIRI.String(), actorID.String())
+			}
+		})
+	}
+}
+
+// signRequest signs the request using the standard algorithm implementations of the HTTP signature library.
+func signRequest(t *testing.T, req *http.Request, keyID, algorithm string, privateKey interface{}) {
+	t.Helper()
+
+	privKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	privKeyPem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privKeyBytes})
+
+	// The library has no 'hs2019' algorithm so the RSA or ECDSA implementation is registered under that name.
+	libAlgorithm := map[string]httpsignatures.SignatureHashAlgorithm{
+		AlgorithmRSASHA256:   httpsignatures.RsaSha256{},
+		AlgorithmECDSASHA256: httpsignatures.EcdsaSha256{},
+		AlgorithmED25519:     httpsignatures.ED25519{},
+	}
+
+	algo, ok := libAlgorithm[algorithm]
+	if !ok {
+		switch privateKey.(type) {
+		case *rsa.PrivateKey:
+			algo = &namedAlgorithm{name: algorithm, SignatureHashAlgorithm: httpsignatures.RsaSha256{}}
+		default:
+			algo = &namedAlgorithm{name: algorithm, SignatureHashAlgorithm: httpsignatures.EcdsaSha256{}}
+		}
+	}
+
+	hs := httpsignatures.NewHTTPSignatures(httpsignatures.NewSimpleSecretsStorage(
+		map[string]httpsignatures.Secret{
+			keyID: {KeyID: keyID, PrivateKey: string(privKeyPem), Algorithm: algorithm},
+		},
+	))
+	hs.SetDefaultSignatureHeaders(DefaultPostSignerConfig().Headers)
+	hs.SetSignatureHashAlgorithm(algo)
+
+	req.Header.Add(dateHeader, date())
+
+	require.NoError(t, hs.Sign(keyID, req))
+}
+
+type namedAlgorithm struct {
+	httpsignatures.SignatureHashAlgorithm
+	name string
+}
+
+func (a *namedAlgorithm) Algorithm() string {
+	return a.name
+}
+
 func getPublicKeyPem(pubKey interface{}) ([]byte, error) {
 	keyBytes, err := x509.MarshalPKIXPublicKey(pubKey)
 	if err != nil {
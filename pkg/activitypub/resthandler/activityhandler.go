@@ -13,7 +13,6 @@ import (
 	"net/url"
 
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
-	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 )
 
@@ -151,25 +150,18 @@ func (h *Activities) handleActivities(rw http.ResponseWriter, _ *http.Request, o
 }
 
 func (h *Activities) handleActivitiesPage(rw http.ResponseWriter, req *http.Request, objectIRI, id *url.URL) {
-	var page *vocab.OrderedCollectionPageType
+	_, useBookmark := h.getBookmark(req)
 
-	var err error
+	page, err := h.getPage(objectIRI, id, useBookmark, h.getPageOpts(req, spi.SortDescending)...)
+	if err != nil {
+		if errors.Is(err, spi.ErrInvalidBookmark) {
+			logger.Debugf("[%s] Invalid bookmark for object IRI [%s]: %s", h.endpoint, objectIRI, err)
 
-	pageNum, ok := h.getPageNum(req)
-	if ok {
-		page, err = h.getPage(objectIRI, id,
-			spi.WithPageSize(h.PageSize),
-			spi.WithPageNum(pageNum),
-			spi.WithSortOrder(spi.SortDescending),
-		)
-	} else {
-		page, err = h.getPage(objectIRI, id,
-			spi.WithPageSize(h.PageSize),
-			spi.WithSortOrder(spi.SortDescending),
-		)
-	}
+			h.writeResponse(rw, http.StatusBadRequest, nil)
+
+			return
+		}
 
-	if err != nil {
 		logger.Errorf("[%s] Error retrieving page for object IRI [%s]: %s",
 			h.endpoint, objectIRI, err)
 
@@ -228,49 +220,14 @@ func (h *Activities) getActivities(objectIRI, id *url.URL) (*vocab.OrderedCollec
 	), nil
 }
 
-func (h *Activities) getPage(objectIRI, id *url.URL, opts ...spi.QueryOpt) (*vocab.OrderedCollectionPageType, error) {
-	it, err := h.activityStore.QueryActivities(
+func (h *Activities) getPage(objectIRI, id *url.URL, useBookmark bool,
+	opts ...spi.QueryOpt) (*vocab.OrderedCollectionPageType, error) {
+	return h.getActivitiesPage(
 		spi.NewCriteria(
 			spi.WithReferenceType(h.refType),
 			spi.WithObjectIRI(objectIRI),
-		), opts...,
+		), id, useBookmark, opts...,
 	)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		err = it.Close()
-		if err != nil {
-			logger.Errorf("failed to close iterator: %s", err.Error())
-		}
-	}()
-
-	options := storeutil.GetQueryOptions(opts...)
-
-	activities, err := storeutil.ReadActivities(it, options.PageSize)
-	if err != nil {
-		return nil, err
-	}
-
-	items := make([]*vocab.ObjectProperty, len(activities))
-
-	for i, activity := range activities {
-		items[i] = vocab.NewObjectProperty(vocab.WithActivity(activity))
-	}
-
-	id, prev, next, err := h.getIDPrevNextURL(id, it.TotalItems(), options)
-	if err != nil {
-		return nil, err
-	}
-
-	return vocab.NewOrderedCollectionPage(items,
-		vocab.WithContext(vocab.ContextActivityStreams),
-		vocab.WithID(id),
-		vocab.WithPrev(prev),
-		vocab.WithNext(next),
-		vocab.WithTotalItems(it.TotalItems()),
-	), nil
 }
 
 // Activity implements a REST handler that retrieves a single activity by ID.
@@ -401,6 +401,64 @@ func TestActivities_PageHandler(t *testing.T) {
 	})
 }
 
+func TestActivities_BookmarkPageHandler(t *testing.T) {
+	activityStore := memstore.New("")
+
+	verifier := &mocks.SignatureVerifier{}
+	verifier.VerifyRequestReturns(true, serviceIRI, nil)
+
+	for _, activity := range newMockCreateActivities(9) {
+		require.NoError(t, activityStore.AddActivity(activity))
+		require.NoError(t, activityStore.AddReference(spi.Outbox, serviceIRI, activity.ID().URL()))
+	}
+
+	h := NewOutbox(&Config{ObjectIRI: serviceIRI, PageSize: 4}, activityStore, verifier)
+	require.NotNil(t, h)
+
+	page := &vocab.OrderedCollectionPageType{}
+
+	status := handleBookmarkRequest(t, h.handle, outboxURL+"?page=true&bookmark", page)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, page.Items(), 4)
+	require.Nil(t, page.Prev())
+	require.NotNil(t, page.Next())
+
+	// The most recent activity is returned first.
+	require.Equal(t, "https://activity_8", page.Items()[0].Object().ID().String())
+
+	// Add more activities before retrieving the next page.
+	for _, activity := range newMockCreateActivities(11)[9:] {
+		require.NoError(t, activityStore.AddActivity(activity))
+		require.NoError(t, activityStore.AddReference(spi.Outbox, serviceIRI, activity.ID().URL()))
+	}
+
+	nextPage := &vocab.OrderedCollectionPageType{}
+
+	status = handleBookmarkRequest(t, h.handle, page.Next().String(), nextPage)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, nextPage.Items(), 4)
+
+	// The next page continues where the previous page left off.
+	require.Equal(t, "https://activity_4", nextPage.Items()[0].Object().ID().String())
+
+	// Delete an activity on the first page before going back to the previous page.
+	require.NoError(t, activityStore.DeleteReference(spi.Outbox, serviceIRI, testutil.MustParseURL("https://activity_7")))
+
+	prevPage := &vocab.OrderedCollectionPageType{}
+
+	status = handleBookmarkRequest(t, h.handle, nextPage.Prev().String(), prevPage)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, prevPage.Items(), 4)
+
+	// The previous page ends immediately before the first item of the next page.
+	require.Equal(t, "https://activity_9", prevPage.Items()[0].Object().ID().String())
+	require.Equal(t, "https://activity_5", prevPage.Items()[3].Object().ID().String())
+	require.NotNil(t, prevPage.Next())
+
+	status = handleBookmarkRequest(t, h.handle, outboxURL+"?page=true&bookmark=invalid", nil)
+	require.Equal(t, http.StatusBadRequest, status)
+}
+
 func TestShares_Handler(t *testing.T) {
 	objectIRI := testutil.NewMockID(transactionsIRI, "/"+objectID)
 
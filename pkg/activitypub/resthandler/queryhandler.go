@@ -0,0 +1,265 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+// ActivityQueryPath specifies the service's activity query endpoint.
+const ActivityQueryPath = "/activities"
+
+const (
+	objectParam = "object"
+	afterParam  = "after"
+	beforeParam = "before"
+)
+
+// ActivityQuery implements a REST handler that queries the activities in the activity store. The activities
+// may be filtered using the following (optional) parameters:
+// - type: The activity type. This parameter may be repeated in order to match any of the given types.
+// - actor: The IRI of the actor that published the activity.
+// - object: The IRI of the activity's object (or target), e.g. the ID of an anchor credential.
+// - after: Only activities that were added at or after the given time (RFC 3339) are returned.
+// - before: Only activities that were added before the given time (RFC 3339) are returned.
+//
+// The results are returned as an ordered collection (most recent first) which is paged in the same way
+// as the other activity collections.
+type ActivityQuery struct {
+	*handler
+}
+
+// NewActivityQuery returns a new 'activities' REST handler that queries the activity store.
+func NewActivityQuery(cfg *Config, activityStore spi.Store, verifier signatureVerifier) *ActivityQuery {
+	h := &ActivityQuery{}
+	h.handler = newHandler(ActivityQueryPath, cfg, activityStore, h.handle, verifier)
+
+	return h
+}
+
+func (h *ActivityQuery) handle(w http.ResponseWriter, req *http.Request) {
+	ok, _, err := h.verifier.VerifyRequest(req)
+	if err != nil {
+		logger.Errorf("[%s] Error verifying HTTP signature: %s", h.endpoint, err)
+
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	if !ok {
+		logger.Infof("[%s] Invalid HTTP signature", h.endpoint)
+
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	criteria, filter, err := h.getCriteria(req)
+	if err != nil {
+		logger.Debugf("[%s] Invalid query: %s", h.endpoint, err)
+
+		h.writeResponse(w, http.StatusBadRequest, nil)
+
+		return
+	}
+
+	id, err := h.getQueryID(filter)
+	if err != nil {
+		logger.Errorf("[%s] Error generating ID: %s", h.endpoint, err)
+
+		h.writeResponse(w, http.StatusInternalServerError, nil)
+
+		return
+	}
+
+	var result interface{}
+
+	if h.isPaging(req) {
+		_, useBookmark := h.getBookmark(req)
+
+		result, err = h.getActivitiesPage(criteria, id, useBookmark, h.getPageOpts(req, spi.SortDescending)...)
+	} else {
+		result, err = h.getActivities(criteria, id)
+	}
+
+	if err != nil {
+		if errors.Is(err, spi.ErrInvalidBookmark) {
+			logger.Debugf("[%s] Invalid bookmark: %s", h.endpoint, err)
+
+			h.writeResponse(w, http.StatusBadRequest, nil)
+
+			return
+		}
+
+		logger.Errorf("[%s] Error querying activities [%s]: %s", h.endpoint, id, err)
+
+		h.writeResponse(w, http.StatusInternalServerError, nil)
+
+		return
+	}
+
+	resultBytes, err := h.marshal(result)
+	if err != nil {
+		logger.Errorf("[%s] Unable to marshal query results [%s]: %s", h.endpoint, id, err)
+
+		h.writeResponse(w, http.StatusInternalServerError, nil)
+
+		return
+	}
+
+	h.writeResponse(w, http.StatusOK, resultBytes)
+}
+
+//nolint:dupl
+func (h *ActivityQuery) getActivities(criteria *spi.Criteria, id *url.URL) (*vocab.OrderedCollectionType, error) {
+	it, err := h.activityStore.QueryActivities(criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = it.Close()
+		if err != nil {
+			logger.Errorf("failed to close iterator: %s", err.Error())
+		}
+	}()
+
+	firstURL, err := h.getPageURL(id, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	lastURL, err := h.getPageURL(id, getLastPageNum(it.TotalItems(), h.PageSize, spi.SortDescending))
+	if err != nil {
+		return nil, err
+	}
+
+	return vocab.NewOrderedCollection(nil,
+		vocab.WithContext(vocab.ContextActivityStreams),
+		vocab.WithID(id),
+		vocab.WithFirst(firstURL),
+		vocab.WithLast(lastURL),
+		vocab.WithTotalItems(it.TotalItems()),
+	), nil
+}
+
+// getCriteria returns the query criteria from the request parameters along with the (validated) filter
+// parameters, which are included in the ID of the results collection.
+func (h *ActivityQuery) getCriteria(req *http.Request) (*spi.Criteria, url.Values, error) {
+	params := h.getParams(req)
+
+	filter := url.Values{}
+
+	var opts []spi.CriteriaOpt
+
+	for _, t := range params[typeParam] {
+		if t == "" {
+			continue
+		}
+
+		opts = append(opts, spi.WithType(vocab.Type(t)))
+		filter.Add(typeParam, t)
+	}
+
+	actorIRI, err := getIRIParam(params, actorParam)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if actorIRI != nil {
+		opts = append(opts, spi.WithActorIRI(actorIRI))
+		filter.Set(actorParam, actorIRI.String())
+	}
+
+	targetIRI, err := getIRIParam(params, objectParam)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if targetIRI != nil {
+		opts = append(opts, spi.WithTargetIRI(targetIRI))
+		filter.Set(objectParam, targetIRI.String())
+	}
+
+	after, err := getTimeParam(params, afterParam)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before, err := getTimeParam(params, beforeParam)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if after != nil || before != nil {
+		if after != nil && before != nil && !after.Before(*before) {
+			return nil, nil, fmt.Errorf("parameter [%s] must be before parameter [%s]", afterParam, beforeParam)
+		}
+
+		opts = append(opts, spi.WithTimeRange(after, before))
+
+		if after != nil {
+			filter.Set(afterParam, after.Format(time.RFC3339Nano))
+		}
+
+		if before != nil {
+			filter.Set(beforeParam, before.Format(time.RFC3339Nano))
+		}
+	}
+
+	return spi.NewCriteria(opts...), filter, nil
+}
+
+func (h *ActivityQuery) getQueryID(filter url.Values) (*url.URL, error) {
+	id := fmt.Sprintf("%s%s", h.ObjectIRI, ActivityQueryPath)
+
+	if len(filter) > 0 {
+		id = fmt.Sprintf("%s?%s", id, filter.Encode())
+	}
+
+	return url.Parse(id)
+}
+
+func getIRIParam(params map[string][]string, param string) (*url.URL, error) {
+	values := params[param]
+	if len(values) == 0 || values[0] == "" {
+		return nil, nil
+	}
+
+	iri, err := url.Parse(values[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for parameter [%s]: %w", param, err)
+	}
+
+	if !iri.IsAbs() {
+		return nil, fmt.Errorf("invalid value for parameter [%s]: IRI must be absolute", param)
+	}
+
+	return iri, nil
+}
+
+func getTimeParam(params map[string][]string, param string) (*time.Time, error) {
+	values := params[param]
+	if len(values) == 0 || values[0] == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, values[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for parameter [%s]: %w", param, err)
+	}
+
+	return &t, nil
+}
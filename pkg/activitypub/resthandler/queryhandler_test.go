@@ -0,0 +1,217 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+const queryURL = "https://example1.com/services/orb/activities"
+
+func TestNewActivityQuery(t *testing.T) {
+	cfg := &Config{
+		BasePath:  basePath,
+		ObjectIRI: serviceIRI,
+		PageSize:  4,
+	}
+
+	h := NewActivityQuery(cfg, memstore.New(""), &mocks.SignatureVerifier{})
+	require.NotNil(t, h)
+	require.Equal(t, "/services/orb/activities", h.Path())
+	require.Equal(t, http.MethodGet, h.Method())
+	require.NotNil(t, h.Handler())
+}
+
+func TestActivityQuery_Handler(t *testing.T) {
+	actor1 := testutil.MustParseURL("https://example1.com/services/orb")
+	actor2 := testutil.MustParseURL("https://example2.com/services/orb")
+	anchorCredID := testutil.MustParseURL("https://example1.com/cas/bafkrei1234")
+
+	activityStore := memstore.New("")
+
+	// Older activities
+	for i := 0; i < 3; i++ {
+		require.NoError(t, activityStore.AddActivity(vocab.NewOfferActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(anchorCredID)),
+			vocab.WithID(testutil.MustParseURL(fmt.Sprintf("https://example2.com/activities/offer_%d", i))),
+			vocab.WithActor(actor2),
+		)))
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	after := time.Now()
+
+	for i := 3; i < 9; i++ {
+		require.NoError(t, activityStore.AddActivity(vocab.NewOfferActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(testutil.MustParseURL(fmt.Sprintf("https://example1.com/cas/%d", i)))),
+			vocab.WithID(testutil.MustParseURL(fmt.Sprintf("https://example2.com/activities/offer_%d", i))),
+			vocab.WithActor(actor2),
+		)))
+	}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, activityStore.AddActivity(vocab.NewLikeActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(anchorCredID)),
+			vocab.WithID(testutil.MustParseURL(fmt.Sprintf("https://example1.com/activities/like_%d", i))),
+			vocab.WithActor(actor1),
+		)))
+	}
+
+	verifier := &mocks.SignatureVerifier{}
+	verifier.VerifyRequestReturns(true, serviceIRI, nil)
+
+	cfg := &Config{
+		BasePath:  basePath,
+		ObjectIRI: serviceIRI,
+		PageSize:  4,
+	}
+
+	h := NewActivityQuery(cfg, activityStore, verifier)
+
+	t.Run("All activities", func(t *testing.T) {
+		coll := &vocab.OrderedCollectionType{}
+
+		status := handleBookmarkRequest(t, h.handle, queryURL, coll)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 11, coll.TotalItems())
+		require.Equal(t, queryURL, coll.ID().String())
+		require.Equal(t, queryURL+"?page=true", coll.First().String())
+		require.Equal(t, queryURL+"?page=true&page-num=0", coll.Last().String())
+	})
+
+	t.Run("Offers from actor after time", func(t *testing.T) {
+		query := url.Values{}
+		query.Set(typeParam, string(vocab.TypeOffer))
+		query.Set(actorParam, actor2.String())
+		query.Set(afterParam, after.Format(time.RFC3339Nano))
+
+		coll := &vocab.OrderedCollectionType{}
+
+		status := handleBookmarkRequest(t, h.handle, queryURL+"?"+query.Encode(), coll)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 6, coll.TotalItems())
+		require.Equal(t, queryURL+"?"+query.Encode(), coll.ID().String())
+
+		page := &vocab.OrderedCollectionPageType{}
+
+		status = handleBookmarkRequest(t, h.handle, coll.First().String(), page)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 6, page.TotalItems())
+		require.Len(t, page.Items(), 4)
+		require.Equal(t, "https://example2.com/activities/offer_8", page.Items()[0].Activity().ID().String())
+		require.NotNil(t, page.Next())
+		require.Nil(t, page.Prev())
+
+		nextPage := &vocab.OrderedCollectionPageType{}
+
+		status = handleBookmarkRequest(t, h.handle, page.Next().String(), nextPage)
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, nextPage.Items(), 2)
+		require.Equal(t, "https://example2.com/activities/offer_4", nextPage.Items()[0].Activity().ID().String())
+		require.Equal(t, "https://example2.com/activities/offer_3", nextPage.Items()[1].Activity().ID().String())
+		require.Nil(t, nextPage.Next())
+	})
+
+	t.Run("Likes for anchor credential", func(t *testing.T) {
+		query := url.Values{}
+		query.Set(typeParam, string(vocab.TypeLike))
+		query.Set(objectParam, anchorCredID.String())
+
+		page := &vocab.OrderedCollectionPageType{}
+
+		status := handleBookmarkRequest(t, h.handle, queryURL+"?page=true&"+query.Encode(), page)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 2, page.TotalItems())
+		require.Len(t, page.Items(), 2)
+		require.Equal(t, "https://example1.com/activities/like_1", page.Items()[0].Activity().ID().String())
+	})
+
+	t.Run("Bookmark paging", func(t *testing.T) {
+		page := &vocab.OrderedCollectionPageType{}
+
+		status := handleBookmarkRequest(t, h.handle, queryURL+"?page=true&bookmark&before="+
+			url.QueryEscape(after.Format(time.RFC3339Nano)), page)
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, page.Items(), 3)
+		require.Nil(t, page.Prev())
+		require.Nil(t, page.Next())
+		require.Equal(t, "https://example2.com/activities/offer_2", page.Items()[0].Activity().ID().String())
+
+		status = handleBookmarkRequest(t, h.handle, queryURL+"?page=true&bookmark=invalid", nil)
+		require.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		for _, query := range []string{
+			"actor=services/orb",
+			"object=cas/bafkrei1234",
+			"after=yesterday",
+			"before=2021-01-01",
+			"after=2021-01-02T00:00:00Z&before=2021-01-01T00:00:00Z",
+		} {
+			status := handleBookmarkRequest(t, h.handle, queryURL+"?"+query, nil)
+			require.Equalf(t, http.StatusBadRequest, status, "query: %s", query)
+		}
+	})
+
+	t.Run("Invalid HTTP signature", func(t *testing.T) {
+		verifier := &mocks.SignatureVerifier{}
+		verifier.VerifyRequestReturns(false, nil, nil)
+
+		h := NewActivityQuery(cfg, activityStore, verifier)
+
+		status := handleBookmarkRequest(t, h.handle, queryURL, nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("HTTP signature verification error", func(t *testing.T) {
+		verifier := &mocks.SignatureVerifier{}
+		verifier.VerifyRequestReturns(false, nil, errors.New("injected verification error"))
+
+		h := NewActivityQuery(cfg, activityStore, verifier)
+
+		status := handleBookmarkRequest(t, h.handle, queryURL, nil)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		s := &mocks.ActivityStore{}
+		s.QueryActivitiesReturns(nil, errors.New("injected store error"))
+
+		h := NewActivityQuery(cfg, s, verifier)
+
+		status := handleBookmarkRequest(t, h.handle, queryURL, nil)
+		require.Equal(t, http.StatusInternalServerError, status)
+
+		status = handleBookmarkRequest(t, h.handle, queryURL+"?page=true", nil)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+
+	t.Run("Marshal error", func(t *testing.T) {
+		h := NewActivityQuery(cfg, activityStore, verifier)
+
+		h.marshal = func(v interface{}) ([]byte, error) {
+			return nil, errors.New("injected marshal error")
+		}
+
+		status := handleBookmarkRequest(t, h.handle, queryURL, nil)
+		require.Equal(t, http.StatusInternalServerError, status)
+	})
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package resthandler
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
 
@@ -144,20 +145,18 @@ func (h *Reference) handleReference(rw http.ResponseWriter, objectIRI, id *url.U
 }
 
 func (h *Reference) handleReferencePage(rw http.ResponseWriter, req *http.Request, objectIRI, id *url.URL) {
-	var page interface{}
+	_, useBookmark := h.getBookmark(req)
 
-	var err error
+	page, err := h.getPage(objectIRI, id, useBookmark, h.getPageOpts(req, h.sortOrder)...)
+	if err != nil {
+		if errors.Is(err, spi.ErrInvalidBookmark) {
+			logger.Debugf("[%s] Invalid bookmark for object IRI [%s]: %s", h.endpoint, objectIRI, err)
 
-	pageNum, ok := h.getPageNum(req)
-	if ok {
-		page, err = h.getPage(objectIRI, id,
-			spi.WithPageSize(h.PageSize), spi.WithPageNum(pageNum), spi.WithSortOrder(h.sortOrder))
-	} else {
-		page, err = h.getPage(objectIRI, id,
-			spi.WithPageSize(h.PageSize), spi.WithSortOrder(h.sortOrder))
-	}
+			h.writeResponse(rw, http.StatusBadRequest, nil)
+
+			return
+		}
 
-	if err != nil {
 		logger.Errorf("[%s] Error retrieving page for object IRI [%s]: %s",
 			h.endpoint, objectIRI, err)
 
@@ -216,7 +215,7 @@ func (h *Reference) getReference(objectIRI, id *url.URL) (interface{}, error) {
 	), nil
 }
 
-func (h *Reference) getPage(objectIRI, id *url.URL, opts ...spi.QueryOpt) (interface{}, error) {
+func (h *Reference) getPage(objectIRI, id *url.URL, useBookmark bool, opts ...spi.QueryOpt) (interface{}, error) {
 	it, err := h.activityStore.QueryReferences(
 		h.refType,
 		spi.NewCriteria(spi.WithObjectIRI(objectIRI)),
@@ -241,23 +240,19 @@ func (h *Reference) getPage(objectIRI, id *url.URL, opts ...spi.QueryOpt) (inter
 	}
 
 	items := make([]*vocab.ObjectProperty, len(refs))
+	keys := make([]string, len(refs))
 
 	for i, ref := range refs {
 		items[i] = vocab.NewObjectProperty(vocab.WithIRI(ref))
+		keys[i] = ref.String()
 	}
 
-	id, prev, next, err := h.getIDPrevNextURL(id, it.TotalItems(), options)
+	id, prev, next, err := h.getPageIDPrevNextURL(id, it.TotalItems, keys, useBookmark, options)
 	if err != nil {
 		return nil, err
 	}
 
-	return h.createCollectionPage(items,
-		vocab.WithContext(vocab.ContextActivityStreams),
-		vocab.WithID(id),
-		vocab.WithPrev(prev),
-		vocab.WithNext(next),
-		vocab.WithTotalItems(it.TotalItems()),
-	), nil
+	return h.createCollectionPage(items, newPageOpts(id, prev, next, it.TotalItems, useBookmark)...), nil
 }
 
 func createCollection(ordered bool) createCollectionFunc {
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package resthandler
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -20,6 +21,7 @@ import (
 	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
 	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 	"github.com/trustbloc/orb/pkg/internal/testutil"
 )
 
@@ -380,6 +382,73 @@ func TestFollowers_PageHandler(t *testing.T) {
 	})
 }
 
+func TestFollowers_BookmarkPageHandler(t *testing.T) {
+	followers := testutil.NewMockURLs(19, func(i int) string {
+		return fmt.Sprintf("https://example%d.com/services/orb", i+1)
+	})
+
+	activityStore := memstore.New("")
+
+	for _, ref := range followers {
+		require.NoError(t, activityStore.AddReference(spi.Follower, serviceIRI, ref))
+	}
+
+	cfg := &Config{
+		ObjectIRI: serviceIRI,
+		PageSize:  4,
+	}
+
+	verifier := &mocks.SignatureVerifier{}
+	verifier.VerifyRequestReturns(true, serviceIRI, nil)
+
+	h := NewFollowers(cfg, activityStore, verifier)
+	require.NotNil(t, h)
+
+	t.Run("Success", func(t *testing.T) {
+		var received []string
+
+		pageURL := followersURL + "?page=true&bookmark="
+
+		for i := 0; pageURL != ""; i++ {
+			require.Less(t, i, 5, "too many pages")
+
+			page := &vocab.CollectionPageType{}
+
+			status := handleBookmarkRequest(t, h.handle, pageURL, page)
+			require.Equal(t, http.StatusOK, status)
+
+			if i == 0 {
+				require.Nil(t, page.Prev())
+			} else {
+				require.NotNil(t, page.Prev())
+			}
+
+			for _, item := range page.Items() {
+				received = append(received, item.IRI().String())
+			}
+
+			pageURL = ""
+
+			if page.Next() != nil {
+				require.Contains(t, page.Next().String(), "bookmark=")
+
+				pageURL = page.Next().String()
+			}
+		}
+
+		require.Len(t, received, 19)
+
+		for i, follower := range followers {
+			require.Equal(t, follower.String(), received[i])
+		}
+	})
+
+	t.Run("Invalid bookmark", func(t *testing.T) {
+		status := handleBookmarkRequest(t, h.handle, followersURL+"?page=true&bookmark=invalid", nil)
+		require.Equal(t, http.StatusBadRequest, status)
+	})
+}
+
 func TestWitnesses_Handler(t *testing.T) {
 	witnesses := testutil.NewMockURLs(19, func(i int) string {
 		return fmt.Sprintf("https://example%d.com/services/orb", i+1)
@@ -452,6 +521,27 @@ func TestWitnessing_Handler(t *testing.T) {
 	})
 }
 
+func handleBookmarkRequest(t *testing.T, handle http.HandlerFunc, pageURL string, page interface{}) int {
+	t.Helper()
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, pageURL, nil)
+
+	handle(rw, req)
+
+	result := rw.Result()
+
+	respBytes, err := ioutil.ReadAll(result.Body)
+	require.NoError(t, err)
+	require.NoError(t, result.Body.Close())
+
+	if result.StatusCode == http.StatusOK && page != nil {
+		require.NoError(t, json.Unmarshal(respBytes, page))
+	}
+
+	return result.StatusCode
+}
+
 func handleRequest(t *testing.T, h *handler, handle http.HandlerFunc, page, pageNum, expected string) {
 	t.Helper()
 
@@ -11,12 +11,14 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
 
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 )
 
@@ -33,6 +35,8 @@ const (
 	OutboxPath = "/outbox"
 	// InboxPath specifies the service's 'inbox' endpoint.
 	InboxPath = "/inbox"
+	// SharedInboxPath specifies the service's 'sharedInbox' endpoint.
+	SharedInboxPath = "/sharedinbox"
 	// WitnessesPath specifies the service's 'witnesses' endpoint.
 	WitnessesPath = "/witnesses"
 	// WitnessingPath specifies the service's 'witnessing' endpoint.
@@ -48,9 +52,10 @@ const (
 )
 
 const (
-	pageParam    = "page"
-	pageNumParam = "page-num"
-	idParam      = "id"
+	pageParam     = "page"
+	pageNumParam  = "page-num"
+	bookmarkParam = "bookmark"
+	idParam       = "id"
 )
 
 // Config contains configuration parameters for the handler.
@@ -126,10 +131,22 @@ func (h *handler) Handler() common.HTTPRequestHandler {
 
 func (h *handler) getPageID(objectIRI fmt.Stringer, pageNum int) string {
 	if pageNum >= 0 {
-		return fmt.Sprintf("%s?%s=true&%s=%d", objectIRI, pageParam, pageNumParam, pageNum)
+		return fmt.Sprintf("%s%s%s=true&%s=%d", objectIRI, querySeparator(objectIRI), pageParam, pageNumParam, pageNum)
 	}
 
-	return fmt.Sprintf("%s?%s=true", objectIRI, pageParam)
+	return fmt.Sprintf("%s%s%s=true", objectIRI, querySeparator(objectIRI), pageParam)
+}
+
+func (h *handler) getBookmarkPageURL(objectIRI fmt.Stringer, bookmark string) (*url.URL, error) {
+	pageID := fmt.Sprintf("%s%s%s=true&%s=%s", objectIRI, querySeparator(objectIRI), pageParam, bookmarkParam,
+		url.QueryEscape(bookmark))
+
+	pageURL, err := url.Parse(pageID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'page' URL [%s]: %w", pageID, err)
+	}
+
+	return pageURL, nil
 }
 
 func (h *handler) getPageURL(objectIRI fmt.Stringer, pageNum int) (*url.URL, error) {
@@ -197,6 +214,132 @@ func (h *handler) getIDPrevNextURL(objectIRI fmt.Stringer, totalItems int,
 	return pageURI, prevURL, nextURL, nil
 }
 
+// getPageIDPrevNextURL returns the page, previous page and next page URLs of a page, where keys are the IDs of
+// the items on the page. The total number of items is only required for pages that are linked by page number.
+func (h *handler) getPageIDPrevNextURL(objectIRI fmt.Stringer, totalItems func() int, keys []string,
+	useBookmark bool, options *spi.QueryOptions) (*url.URL, *url.URL, *url.URL, error) {
+	if useBookmark {
+		return h.getBookmarkIDPrevNextURL(objectIRI, keys, options)
+	}
+
+	return h.getIDPrevNextURL(objectIRI, totalItems(), options)
+}
+
+// getBookmarkIDPrevNextURL returns the page, previous page and next page URLs of a page that was
+// retrieved using a bookmark. The URLs contain bookmarks whose cursors are the first and last items
+// on the page rather than page numbers.
+func (h *handler) getBookmarkIDPrevNextURL(objectIRI fmt.Stringer, keys []string,
+	options *spi.QueryOptions) (*url.URL, *url.URL, *url.URL, error) {
+	pageURL, err := h.getBookmarkPageURL(objectIRI, options.Bookmark)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(keys) == 0 {
+		return pageURL, nil, nil, nil
+	}
+
+	var b *storeutil.Bookmark
+
+	if options.Bookmark != "" {
+		b, err = storeutil.ParseBookmark(options.Bookmark)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// The offset of the first item on the page. This is only a hint for locating the cursor.
+	offset := 0
+
+	if b != nil {
+		if b.Before {
+			offset = b.Offset - len(keys)
+
+			if offset < 0 {
+				offset = 0
+			}
+		} else {
+			offset = b.Offset + 1
+		}
+	}
+
+	var prevURL, nextURL *url.URL
+
+	if b != nil && (!b.Before || len(keys) == options.PageSize) {
+		prevURL, err = h.getBookmarkPageURL(objectIRI, storeutil.NewBookmark(keys[0], offset, true))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	if (b != nil && b.Before) || len(keys) == options.PageSize {
+		nextURL, err = h.getBookmarkPageURL(objectIRI,
+			storeutil.NewBookmark(keys[len(keys)-1], offset+len(keys)-1, false))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return pageURL, prevURL, nextURL, nil
+}
+
+// getActivitiesPage returns a page of the activities that match the given criteria.
+func (h *handler) getActivitiesPage(criteria *spi.Criteria, id *url.URL, useBookmark bool,
+	opts ...spi.QueryOpt) (*vocab.OrderedCollectionPageType, error) {
+	it, err := h.activityStore.QueryActivities(criteria, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = it.Close()
+		if err != nil {
+			logger.Errorf("failed to close iterator: %s", err.Error())
+		}
+	}()
+
+	options := storeutil.GetQueryOptions(opts...)
+
+	activities, err := storeutil.ReadActivities(it, options.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*vocab.ObjectProperty, len(activities))
+	keys := make([]string, len(activities))
+
+	for i, activity := range activities {
+		items[i] = vocab.NewObjectProperty(vocab.WithActivity(activity))
+		keys[i] = activity.ID().String()
+	}
+
+	id, prev, next, err := h.getPageIDPrevNextURL(id, it.TotalItems, keys, useBookmark, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return vocab.NewOrderedCollectionPage(items,
+		newPageOpts(id, prev, next, it.TotalItems, useBookmark)...,
+	), nil
+}
+
+// newPageOpts returns the options of a collection page. The total number of items is omitted from
+// pages that are linked by bookmarks, since it would require all of the items to be counted.
+func newPageOpts(id, prev, next *url.URL, totalItems func() int, useBookmark bool) []vocab.Opt {
+	opts := []vocab.Opt{
+		vocab.WithContext(vocab.ContextActivityStreams),
+		vocab.WithID(id),
+		vocab.WithPrev(prev),
+		vocab.WithNext(next),
+	}
+
+	if !useBookmark {
+		opts = append(opts, vocab.WithTotalItems(totalItems()))
+	}
+
+	return opts
+}
+
 func (h *handler) isPaging(req *http.Request) bool {
 	return h.paramAsBool(req, pageParam)
 }
@@ -205,6 +348,39 @@ func (h *handler) getPageNum(req *http.Request) (int, bool) {
 	return h.paramAsInt(req, pageNumParam)
 }
 
+// getBookmark returns the bookmark parameter. If the parameter is present (even if empty) then true is
+// returned, indicating that the pages should be linked using bookmarks rather than page numbers.
+func (h *handler) getBookmark(req *http.Request) (string, bool) {
+	values, ok := h.getParams(req)[bookmarkParam]
+	if !ok {
+		return "", false
+	}
+
+	if len(values) == 0 {
+		return "", true
+	}
+
+	return values[0], true
+}
+
+// getPageOpts returns the query options for the requested page.
+func (h *handler) getPageOpts(req *http.Request, sortOrder spi.SortOrder) []spi.QueryOpt {
+	opts := []spi.QueryOpt{
+		spi.WithPageSize(h.PageSize),
+		spi.WithSortOrder(sortOrder),
+	}
+
+	if bookmark, ok := h.getBookmark(req); ok {
+		return append(opts, spi.WithBookmark(bookmark))
+	}
+
+	if pageNum, ok := h.getPageNum(req); ok {
+		return append(opts, spi.WithPageNum(pageNum))
+	}
+
+	return opts
+}
+
 func (h *handler) paramAsInt(req *http.Request, param string) (int, bool) {
 	params := h.getParams(req)
 
@@ -303,6 +479,16 @@ func getLastPageNum(totalItems, pageSize int, sortOrder spi.SortOrder) int {
 	return totalItems/pageSize - 1
 }
 
+// querySeparator returns the separator that precedes the paging parameters, which depends on whether or not
+// the given IRI already contains query parameters.
+func querySeparator(iri fmt.Stringer) string {
+	if strings.Contains(iri.String(), "?") {
+		return "&"
+	}
+
+	return "?"
+}
+
 type paramsBuilder []string
 
 func (p paramsBuilder) build() map[string]string {
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"path"
 
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
@@ -18,17 +19,45 @@ import (
 // MainKeyID is the ID of the service's public key.
 const MainKeyID = "main-key"
 
+// PublicKeyProvider provides the public keys of the service.
+type PublicKeyProvider interface {
+	// PublicKey returns the public key that is currently used by the service.
+	PublicKey() *vocab.PublicKeyType
+
+	// PublicKeys returns all of the published keys of the service. While a key is being rotated
+	// both the old and the new key are returned.
+	PublicKeys() []*vocab.PublicKeyType
+}
+
+type staticPublicKey struct {
+	publicKey *vocab.PublicKeyType
+}
+
+func (p *staticPublicKey) PublicKey() *vocab.PublicKeyType {
+	return p.publicKey
+}
+
+func (p *staticPublicKey) PublicKeys() []*vocab.PublicKeyType {
+	return []*vocab.PublicKeyType{p.publicKey}
+}
+
 // Services implements the 'services' REST handler to retrieve a given ActivityPub service (actor).
 type Services struct {
 	*handler
 
-	publicKey *vocab.PublicKeyType
+	publicKeys PublicKeyProvider
 }
 
 // NewServices returns a new 'services' REST handler.
 func NewServices(cfg *Config, activityStore spi.Store, publicKey *vocab.PublicKeyType) *Services {
+	return NewServicesWithKeyProvider(cfg, activityStore, &staticPublicKey{publicKey: publicKey})
+}
+
+// NewServicesWithKeyProvider returns a new 'services' REST handler which retrieves the
+// service's public key from the given provider.
+func NewServicesWithKeyProvider(cfg *Config, activityStore spi.Store, publicKeys PublicKeyProvider) *Services {
 	h := &Services{
-		publicKey: publicKey,
+		publicKeys: publicKeys,
 	}
 
 	h.handler = newHandler("", cfg, activityStore, h.handle, nil)
@@ -38,8 +67,14 @@ func NewServices(cfg *Config, activityStore spi.Store, publicKey *vocab.PublicKe
 
 // NewPublicKeys returns a new public keys REST handler.
 func NewPublicKeys(cfg *Config, activityStore spi.Store, publicKey *vocab.PublicKeyType) *Services {
+	return NewPublicKeysWithKeyProvider(cfg, activityStore, &staticPublicKey{publicKey: publicKey})
+}
+
+// NewPublicKeysWithKeyProvider returns a new public keys REST handler which serves all of the
+// keys returned by the given provider.
+func NewPublicKeysWithKeyProvider(cfg *Config, activityStore spi.Store, publicKeys PublicKeyProvider) *Services {
 	h := &Services{
-		publicKey: publicKey,
+		publicKeys: publicKeys,
 	}
 
 	h.handler = newHandler(PublicKeysPath, cfg, activityStore, h.handlePublicKey, nil)
@@ -80,9 +115,8 @@ func (h *Services) handlePublicKey(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Currently only one key is supported. In the future we may wish to have
-	// multiple keys per service.
-	if keyID != MainKeyID {
+	publicKey := h.getPublicKey(keyID)
+	if publicKey == nil {
 		logger.Infof("[%s] Public key [%s] not found for [%s]", h.endpoint, h.ObjectIRI, keyID)
 
 		h.writeResponse(w, http.StatusNotFound, nil)
@@ -90,7 +124,7 @@ func (h *Services) handlePublicKey(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	publicKeyBytes, err := h.marshal(h.publicKey)
+	publicKeyBytes, err := h.marshal(publicKey)
 	if err != nil {
 		logger.Errorf("[%s] Unable to marshal public key [%s]: %s", h.endpoint, h.ObjectIRI, err)
 
@@ -104,44 +138,66 @@ func (h *Services) handlePublicKey(w http.ResponseWriter, req *http.Request) {
 	h.writeResponse(w, http.StatusOK, publicKeyBytes)
 }
 
+// getPublicKey returns the published public key whose ID ends with the given key ID
+// or nil if no such key exists.
+func (h *Services) getPublicKey(keyID string) *vocab.PublicKeyType {
+	for _, publicKey := range h.publicKeys.PublicKeys() {
+		if publicKey.ID != nil && path.Base(publicKey.ID.URL().Path) == keyID {
+			return publicKey
+		}
+	}
+
+	return nil
+}
+
 func (h *Services) newService() (*vocab.ActorType, error) {
-	inbox, err := newID(h.ObjectIRI, InboxPath)
+	return NewService(h.ObjectIRI, h.publicKeys.PublicKey())
+}
+
+// NewService returns the ActivityPub service (actor) for the given service IRI and public key.
+func NewService(serviceIRI *url.URL, publicKey *vocab.PublicKeyType) (*vocab.ActorType, error) {
+	inbox, err := newID(serviceIRI, InboxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedInbox, err := newID(serviceIRI, SharedInboxPath)
 	if err != nil {
 		return nil, err
 	}
 
-	outbox, err := newID(h.ObjectIRI, OutboxPath)
+	outbox, err := newID(serviceIRI, OutboxPath)
 	if err != nil {
 		return nil, err
 	}
 
-	followers, err := newID(h.ObjectIRI, FollowersPath)
+	followers, err := newID(serviceIRI, FollowersPath)
 	if err != nil {
 		return nil, err
 	}
 
-	following, err := newID(h.ObjectIRI, FollowingPath)
+	following, err := newID(serviceIRI, FollowingPath)
 	if err != nil {
 		return nil, err
 	}
 
-	witnesses, err := newID(h.ObjectIRI, WitnessesPath)
+	witnesses, err := newID(serviceIRI, WitnessesPath)
 	if err != nil {
 		return nil, err
 	}
 
-	witnessing, err := newID(h.ObjectIRI, WitnessingPath)
+	witnessing, err := newID(serviceIRI, WitnessingPath)
 	if err != nil {
 		return nil, err
 	}
 
-	liked, err := newID(h.ObjectIRI, LikedPath)
+	liked, err := newID(serviceIRI, LikedPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return vocab.NewService(h.ObjectIRI,
-		vocab.WithPublicKey(h.publicKey),
+	return vocab.NewService(serviceIRI,
+		vocab.WithPublicKey(publicKey),
 		vocab.WithInbox(inbox),
 		vocab.WithOutbox(outbox),
 		vocab.WithFollowers(followers),
@@ -149,6 +205,7 @@ func (h *Services) newService() (*vocab.ActorType, error) {
 		vocab.WithWitnesses(witnesses),
 		vocab.WithWitnessing(witnessing),
 		vocab.WithLiked(liked),
+		vocab.WithSharedInbox(sharedInbox),
 	), nil
 }
 
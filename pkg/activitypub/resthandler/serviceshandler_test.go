@@ -203,6 +203,86 @@ func TestPublicKeys_Handler(t *testing.T) {
 	})
 }
 
+func TestPublicKeys_WithKeyProvider(t *testing.T) {
+	cfg := &Config{
+		BasePath:  basePath,
+		ObjectIRI: serviceIRI,
+	}
+
+	activityStore := memstore.New("")
+
+	publicKey2 := vocab.NewPublicKey(
+		vocab.WithID(testutil.NewMockID(serviceIRI, "/keys/key2")),
+		vocab.WithOwner(serviceIRI),
+		vocab.WithPublicKeyPem(keyPem),
+	)
+
+	keyProvider := &mockPublicKeyProvider{
+		current: publicKey2,
+		keys:    []*vocab.PublicKeyType{publicKey, publicKey2},
+	}
+
+	t.Run("Service contains current key", func(t *testing.T) {
+		h := NewServicesWithKeyProvider(cfg, activityStore, keyProvider)
+		require.NotNil(t, h)
+
+		s, err := h.newService()
+		require.NoError(t, err)
+		require.NotNil(t, s.PublicKey())
+		require.Equal(t, publicKey2.ID.String(), s.PublicKey().ID.String())
+	})
+
+	h := NewPublicKeysWithKeyProvider(cfg, activityStore, keyProvider)
+	require.NotNil(t, h)
+
+	for _, keyID := range []string{MainKeyID, "key2"} {
+		keyID := keyID
+
+		t.Run("Published key "+keyID, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, serviceIRI.String(), nil)
+
+			restoreID := setIDParam(keyID)
+			defer restoreID()
+
+			h.handlePublicKey(rw, req)
+
+			result := rw.Result()
+			require.Equal(t, http.StatusOK, result.StatusCode)
+			require.NoError(t, result.Body.Close())
+		})
+	}
+
+	t.Run("Retired key -> NotFound", func(t *testing.T) {
+		keyProvider.keys = []*vocab.PublicKeyType{publicKey2}
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, serviceIRI.String(), nil)
+
+		restoreID := setIDParam(MainKeyID)
+		defer restoreID()
+
+		h.handlePublicKey(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusNotFound, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+}
+
+type mockPublicKeyProvider struct {
+	current *vocab.PublicKeyType
+	keys    []*vocab.PublicKeyType
+}
+
+func (m *mockPublicKeyProvider) PublicKey() *vocab.PublicKeyType {
+	return m.current
+}
+
+func (m *mockPublicKeyProvider) PublicKeys() []*vocab.PublicKeyType {
+	return m.keys
+}
+
 const (
 	serviceJSON = `{
   "@context": [
@@ -223,7 +303,10 @@ const (
   "following": "https://example1.com/services/orb/following",
   "liked": "https://example1.com/services/orb/liked",
   "witnesses": "https://example1.com/services/orb/witnesses",
-  "witnessing": "https://example1.com/services/orb/witnessing"
+  "witnessing": "https://example1.com/services/orb/witnessing",
+  "endpoints": {
+    "sharedInbox": "https://example1.com/services/orb/sharedinbox"
+  }
 }`
 
 	publicKeyJSON = `{
@@ -0,0 +1,510 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+
+	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+// ActivityStreamPath specifies the service's activity stream (Server-Sent Events) endpoint.
+const ActivityStreamPath = "/stream"
+
+const (
+	typeParam         = "type"
+	actorParam        = "actor"
+	lastEventIDHeader = "Last-Event-ID"
+
+	inboxSource  = "inbox"
+	outboxSource = "outbox"
+
+	defaultStreamBufferSize  = 100
+	defaultHeartbeatInterval = 30 * time.Second
+	defaultMaxReplay         = 1000
+)
+
+// ActivityStream implements a REST handler that streams the activities that are handled by the inbox and
+// outbox to authenticated clients using Server-Sent Events. Each event contains the activity (data), the
+// source of the activity (event) and a cursor (id) which contains the positions of the client in the inbox
+// and outbox. A client that reconnects with a Last-Event-ID header is sent the activities that it missed,
+// which are read from the activity store.
+type ActivityStream struct {
+	*Config
+
+	endpoint          string
+	authToken         string
+	activityStore     spi.Store
+	mutex             sync.RWMutex
+	clients           map[*streamClient]struct{}
+	bufferSize        int
+	heartbeatInterval time.Duration
+	maxReplay         int
+	marshal           func(v interface{}) ([]byte, error)
+}
+
+// NewActivityStream returns a new activity stream REST handler which streams the activities received on
+// the given inbox and outbox channels. Clients must provide the given token as a bearer token in the
+// Authorization header.
+func NewActivityStream(cfg *Config, activityStore spi.Store, authToken string,
+	inbox, outbox <-chan *vocab.ActivityType) *ActivityStream {
+	h := &ActivityStream{
+		Config:            cfg,
+		endpoint:          fmt.Sprintf("%s%s", cfg.BasePath, ActivityStreamPath),
+		authToken:         authToken,
+		activityStore:     activityStore,
+		clients:           make(map[*streamClient]struct{}),
+		bufferSize:        defaultStreamBufferSize,
+		heartbeatInterval: defaultHeartbeatInterval,
+		maxReplay:         defaultMaxReplay,
+		marshal:           vocab.Marshal,
+	}
+
+	go h.listen(inboxSource, inbox)
+	go h.listen(outboxSource, outbox)
+
+	return h
+}
+
+// Path returns the base path of the target URL for this handler.
+func (h *ActivityStream) Path() string {
+	return h.endpoint
+}
+
+// Method returns the HTTP method, which is always GET.
+func (h *ActivityStream) Method() string {
+	return http.MethodGet
+}
+
+// Handler returns the handler that should be invoked when an HTTP GET is requested to the target endpoint.
+// This handler must be registered with an HTTP server.
+func (h *ActivityStream) Handler() common.HTTPRequestHandler {
+	return h.handle
+}
+
+type streamEvent struct {
+	source   string
+	activity *vocab.ActivityType
+}
+
+type streamClient struct {
+	events    chan *streamEvent
+	dropped   chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *streamClient) drop() {
+	c.closeOnce.Do(func() { close(c.dropped) })
+}
+
+// streamFilter filters activities by type and actor. An empty filter matches all activities.
+type streamFilter struct {
+	types  []vocab.Type
+	actors []string
+}
+
+func newStreamFilter(req *http.Request) *streamFilter {
+	f := &streamFilter{actors: req.URL.Query()[actorParam]}
+
+	for _, t := range req.URL.Query()[typeParam] {
+		f.types = append(f.types, vocab.Type(t))
+	}
+
+	return f
+}
+
+func (f *streamFilter) matches(activity *vocab.ActivityType) bool {
+	if len(f.types) > 0 && !activity.Type().IsAny(f.types...) {
+		return false
+	}
+
+	if len(f.actors) > 0 {
+		if activity.Actor() == nil || !containsString(f.actors, activity.Actor().String()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// streamCursor contains the IDs of the most recent inbox and outbox activities that were processed
+// for a client.
+type streamCursor struct {
+	inbox  string
+	outbox string
+}
+
+func parseCursor(value string) (*streamCursor, error) {
+	values, err := url.ParseQuery(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamCursor{
+		inbox:  values.Get(inboxSource),
+		outbox: values.Get(outboxSource),
+	}, nil
+}
+
+func (c *streamCursor) String() string {
+	values := url.Values{}
+
+	if c.inbox != "" {
+		values.Set(inboxSource, c.inbox)
+	}
+
+	if c.outbox != "" {
+		values.Set(outboxSource, c.outbox)
+	}
+
+	return values.Encode()
+}
+
+func (c *streamCursor) advance(e *streamEvent) {
+	if e.source == inboxSource {
+		c.inbox = e.activity.ID().String()
+	} else {
+		c.outbox = e.activity.ID().String()
+	}
+}
+
+func (h *ActivityStream) handle(w http.ResponseWriter, req *http.Request) {
+	if !h.authorized(req) {
+		logger.Infof("[%s] Unauthorized request to the activity stream", h.endpoint)
+
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.Errorf("[%s] Streaming is not supported by the response writer", h.endpoint)
+
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	var lastCursor *streamCursor
+
+	if lastEventID := req.Header.Get(lastEventIDHeader); lastEventID != "" {
+		c, err := parseCursor(lastEventID)
+		if err != nil {
+			logger.Infof("[%s] Invalid %s [%s]: %s", h.endpoint, lastEventIDHeader, lastEventID, err)
+
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		lastCursor = c
+	}
+
+	// The client is registered before the missed activities are read so that no activity is lost in between.
+	client := h.register()
+	defer h.unregister(client)
+
+	cursor, replay, err := h.resume(lastCursor)
+	if err != nil {
+		logger.Errorf("[%s] Error resuming activity stream: %s", h.endpoint, err)
+
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	filter := newStreamFilter(req)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// The IDs of the replayed activities are remembered since they may also be received from the live stream.
+	replayed := make(map[string]struct{})
+
+	for _, e := range replay {
+		replayed[e.activity.ID().String()] = struct{}{}
+
+		if err := h.send(w, flusher, cursor, filter, e); err != nil {
+			logger.Debugf("[%s] Error writing to activity stream: %s", h.endpoint, err)
+
+			return
+		}
+	}
+
+	h.stream(w, flusher, req, client, cursor, filter, replayed)
+}
+
+func (h *ActivityStream) stream(w http.ResponseWriter, flusher http.Flusher, req *http.Request,
+	client *streamClient, cursor *streamCursor, filter *streamFilter, replayed map[string]struct{}) {
+	ticker := time.NewTicker(h.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-client.events:
+			if _, ok := replayed[e.activity.ID().String()]; ok {
+				continue
+			}
+
+			if err := h.send(w, flusher, cursor, filter, e); err != nil {
+				logger.Debugf("[%s] Error writing to activity stream: %s", h.endpoint, err)
+
+				return
+			}
+
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				logger.Debugf("[%s] Error writing heartbeat to activity stream: %s", h.endpoint, err)
+
+				return
+			}
+
+			flusher.Flush()
+
+		case <-client.dropped:
+			logger.Warnf("[%s] Closing activity stream since the client is not keeping up", h.endpoint)
+
+			return
+
+		case <-req.Context().Done():
+			logger.Debugf("[%s] Client disconnected from activity stream", h.endpoint)
+
+			return
+		}
+	}
+}
+
+// send advances the cursor and writes the event to the stream if it matches the filter.
+func (h *ActivityStream) send(w http.ResponseWriter, flusher http.Flusher, cursor *streamCursor,
+	filter *streamFilter, e *streamEvent) error {
+	cursor.advance(e)
+
+	if !filter.matches(e.activity) {
+		return nil
+	}
+
+	activityBytes, err := h.marshal(e.activity)
+	if err != nil {
+		logger.Errorf("[%s] Error marshalling activity [%s]: %s", h.endpoint, e.activity.ID(), err)
+
+		return nil
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", cursor, e.source, activityBytes)
+	if err != nil {
+		return err
+	}
+
+	flusher.Flush()
+
+	return nil
+}
+
+func (h *ActivityStream) authorized(req *http.Request) bool {
+	if h.authToken == "" {
+		return false
+	}
+
+	actual := req.Header.Get("Authorization")
+	expected := "Bearer " + h.authToken
+
+	return subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) == 1
+}
+
+// resume returns the cursor for the client and the events that the client missed. If no cursor
+// was provided (no Last-Event-ID) then the cursor is positioned at the most recent inbox and outbox activities.
+func (h *ActivityStream) resume(cursor *streamCursor) (*streamCursor, []*streamEvent, error) {
+	if cursor == nil {
+		cursor, err := h.head()
+
+		return cursor, nil, err
+	}
+
+	inboxEvents, err := h.replay(inboxSource, spi.Inbox, cursor.inbox)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outboxEvents, err := h.replay(outboxSource, spi.Outbox, cursor.outbox)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cursor, append(inboxEvents, outboxEvents...), nil
+}
+
+func (h *ActivityStream) head() (*streamCursor, error) {
+	inbox, err := h.latest(spi.Inbox)
+	if err != nil {
+		return nil, err
+	}
+
+	outbox, err := h.latest(spi.Outbox)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamCursor{inbox: inbox, outbox: outbox}, nil
+}
+
+// latest returns the ID of the most recent activity in the given collection or an empty string if the
+// collection is empty.
+func (h *ActivityStream) latest(refType spi.ReferenceType) (string, error) {
+	it, err := h.activityStore.QueryReferences(refType, spi.NewCriteria(spi.WithObjectIRI(h.ObjectIRI)),
+		spi.WithSortOrder(spi.SortDescending), spi.WithPageSize(1))
+	if err != nil {
+		return "", fmt.Errorf("query %s: %w", refType, err)
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warnf("[%s] Error closing iterator: %s", h.endpoint, e)
+		}
+	}()
+
+	activityIRI, err := it.Next()
+	if err != nil {
+		if errors.Is(err, spi.ErrNotFound) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("get next %s reference: %w", refType, err)
+	}
+
+	return activityIRI.String(), nil
+}
+
+// replay returns the activities in the given collection that were added after the activity with the given ID,
+// oldest first. At most maxReplay activities are returned.
+func (h *ActivityStream) replay(source string, refType spi.ReferenceType, lastID string) ([]*streamEvent, error) {
+	if lastID == "" {
+		return nil, nil
+	}
+
+	it, err := h.activityStore.QueryReferences(refType, spi.NewCriteria(spi.WithObjectIRI(h.ObjectIRI)),
+		spi.WithSortOrder(spi.SortDescending))
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", refType, err)
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warnf("[%s] Error closing iterator: %s", h.endpoint, e)
+		}
+	}()
+
+	var missed []*url.URL
+
+	for {
+		activityIRI, err := it.Next()
+		if err != nil {
+			if errors.Is(err, spi.ErrNotFound) {
+				logger.Warnf("[%s] Activity [%s] was not found in %s. Some activities may not be replayed.",
+					h.endpoint, lastID, refType)
+
+				break
+			}
+
+			return nil, fmt.Errorf("get next %s reference: %w", refType, err)
+		}
+
+		if activityIRI.String() == lastID {
+			break
+		}
+
+		if len(missed) == h.maxReplay {
+			logger.Warnf("[%s] Reached the maximum number of activities [%d] to replay from %s.",
+				h.endpoint, h.maxReplay, refType)
+
+			break
+		}
+
+		missed = append(missed, activityIRI)
+	}
+
+	var events []*streamEvent
+
+	for i := len(missed) - 1; i >= 0; i-- {
+		activity, err := h.activityStore.GetActivity(missed[i])
+		if err != nil {
+			if errors.Is(err, spi.ErrNotFound) {
+				continue
+			}
+
+			return nil, fmt.Errorf("get activity [%s]: %w", missed[i], err)
+		}
+
+		events = append(events, &streamEvent{source: source, activity: activity})
+	}
+
+	return events, nil
+}
+
+func (h *ActivityStream) listen(source string, activities <-chan *vocab.ActivityType) {
+	for activity := range activities {
+		h.publish(&streamEvent{source: source, activity: activity})
+	}
+
+	logger.Debugf("[%s] The %s activity channel was closed", h.endpoint, source)
+}
+
+// publish sends the event to all clients. A client that is not keeping up is dropped (the client may
+// reconnect and resume from its last event).
+func (h *ActivityStream) publish(e *streamEvent) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for client := range h.clients {
+		select {
+		case client.events <- e:
+		default:
+			client.drop()
+		}
+	}
+}
+
+func (h *ActivityStream) register() *streamClient {
+	client := &streamClient{
+		events:  make(chan *streamEvent, h.bufferSize),
+		dropped: make(chan struct{}),
+	}
+
+	h.mutex.Lock()
+	h.clients[client] = struct{}{}
+	h.mutex.Unlock()
+
+	return client
+}
+
+func (h *ActivityStream) unregister(client *streamClient) {
+	h.mutex.Lock()
+	delete(h.clients, client)
+	h.mutex.Unlock()
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
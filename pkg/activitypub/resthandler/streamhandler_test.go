@@ -0,0 +1,359 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resthandler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+const streamToken = "stream-token"
+
+func TestNewActivityStream(t *testing.T) {
+	cfg := &Config{
+		BasePath:  "/services/orb",
+		ObjectIRI: serviceIRI,
+	}
+
+	inbox := make(chan *vocab.ActivityType)
+	outbox := make(chan *vocab.ActivityType)
+
+	defer close(inbox)
+	defer close(outbox)
+
+	h := NewActivityStream(cfg, memstore.New(""), streamToken, inbox, outbox)
+
+	require.NotNil(t, h.Handler())
+	require.Equal(t, http.MethodGet, h.Method())
+	require.Equal(t, "/services/orb/stream", h.Path())
+}
+
+func TestActivityStream_Handler(t *testing.T) {
+	service2IRI := testutil.MustParseURL("https://example2.com/services/orb")
+
+	cfg := &Config{
+		BasePath:  "/services/orb",
+		ObjectIRI: serviceIRI,
+	}
+
+	activityStore := memstore.New("")
+
+	// Activities that were handled before the client connected.
+	addStreamActivity(t, activityStore, spi.Inbox, newStreamActivity(vocab.TypeCreate, service2IRI, 0))
+	addStreamActivity(t, activityStore, spi.Outbox, newStreamActivity(vocab.TypeAnnounce, serviceIRI, 1))
+
+	inbox := make(chan *vocab.ActivityType)
+	outbox := make(chan *vocab.ActivityType)
+
+	defer close(inbox)
+	defer close(outbox)
+
+	h := NewActivityStream(cfg, activityStore, streamToken, inbox, outbox)
+
+	server := httptest.NewServer(http.HandlerFunc(h.handle))
+	defer server.Close()
+
+	var lastEventID string
+
+	t.Run("Live events", func(t *testing.T) {
+		resp, events := connect(t, server.URL, "", "")
+		defer resp.Body.Close() //nolint:errcheck
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		create := newStreamActivity(vocab.TypeCreate, service2IRI, 2)
+		addStreamActivity(t, activityStore, spi.Inbox, create)
+		inbox <- create
+
+		e := readEvent(t, events)
+		require.Equal(t, "inbox", e.event)
+		require.Contains(t, e.data, create.ID().String())
+
+		announce := newStreamActivity(vocab.TypeAnnounce, serviceIRI, 3)
+		addStreamActivity(t, activityStore, spi.Outbox, announce)
+		outbox <- announce
+
+		e = readEvent(t, events)
+		require.Equal(t, "outbox", e.event)
+		require.Contains(t, e.data, announce.ID().String())
+
+		cursor, err := parseCursor(e.id)
+		require.NoError(t, err)
+		require.Equal(t, create.ID().String(), cursor.inbox)
+		require.Equal(t, announce.ID().String(), cursor.outbox)
+
+		lastEventID = e.id
+	})
+
+	t.Run("Resume from Last-Event-ID", func(t *testing.T) {
+		like := newStreamActivity(vocab.TypeLike, service2IRI, 4)
+		addStreamActivity(t, activityStore, spi.Inbox, like)
+
+		follow := newStreamActivity(vocab.TypeFollow, serviceIRI, 5)
+		addStreamActivity(t, activityStore, spi.Outbox, follow)
+
+		resp, events := connect(t, server.URL, "", lastEventID)
+		defer resp.Body.Close() //nolint:errcheck
+
+		e := readEvent(t, events)
+		require.Equal(t, "inbox", e.event)
+		require.Contains(t, e.data, like.ID().String())
+
+		e = readEvent(t, events)
+		require.Equal(t, "outbox", e.event)
+		require.Contains(t, e.data, follow.ID().String())
+
+		// An activity that was replayed isn't sent again.
+		inbox <- like
+
+		announce := newStreamActivity(vocab.TypeAnnounce, serviceIRI, 6)
+		addStreamActivity(t, activityStore, spi.Outbox, announce)
+		outbox <- announce
+
+		e = readEvent(t, events)
+		require.Contains(t, e.data, announce.ID().String())
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		resp, events := connect(t, server.URL, "?type=Create&actor="+service2IRI.String(), "")
+		defer resp.Body.Close() //nolint:errcheck
+
+		announce := newStreamActivity(vocab.TypeAnnounce, service2IRI, 7)
+		inbox <- announce
+
+		like := newStreamActivity(vocab.TypeCreate, serviceIRI, 8)
+		inbox <- like
+
+		create := newStreamActivity(vocab.TypeCreate, service2IRI, 9)
+		inbox <- create
+
+		e := readEvent(t, events)
+		require.Contains(t, e.data, create.ID().String())
+
+		cursor, err := parseCursor(e.id)
+		require.NoError(t, err)
+		require.Equal(t, create.ID().String(), cursor.inbox)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer invalid")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("No token configured", func(t *testing.T) {
+		inbox := make(chan *vocab.ActivityType)
+		outbox := make(chan *vocab.ActivityType)
+
+		defer close(inbox)
+		defer close(outbox)
+
+		h := NewActivityStream(cfg, activityStore, "", inbox, outbox)
+
+		rw := httptest.NewRecorder()
+		h.handle(rw, httptest.NewRequest(http.MethodGet, server.URL, nil))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusUnauthorized, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Authorization", "Bearer "+streamToken)
+		req.Header.Set(lastEventIDHeader, "inbox=%zz")
+
+		h.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+}
+
+func TestActivityStream_SlowClient(t *testing.T) {
+	cfg := &Config{
+		BasePath:  "/services/orb",
+		ObjectIRI: serviceIRI,
+	}
+
+	inbox := make(chan *vocab.ActivityType)
+	outbox := make(chan *vocab.ActivityType)
+
+	defer close(inbox)
+	defer close(outbox)
+
+	h := NewActivityStream(cfg, memstore.New(""), streamToken, inbox, outbox)
+
+	client := h.register()
+	defer h.unregister(client)
+
+	for i := 0; i <= h.bufferSize; i++ {
+		h.publish(&streamEvent{source: inboxSource, activity: newStreamActivity(vocab.TypeCreate, serviceIRI, i)})
+	}
+
+	select {
+	case <-client.dropped:
+	default:
+		t.Fatal("expecting client to be dropped")
+	}
+}
+
+func TestActivityStream_StoreError(t *testing.T) {
+	cfg := &Config{
+		BasePath:  "/services/orb",
+		ObjectIRI: serviceIRI,
+	}
+
+	activityStore := &mocks.ActivityStore{}
+	activityStore.QueryReferencesReturns(nil, errors.New("injected query error"))
+
+	inbox := make(chan *vocab.ActivityType)
+	outbox := make(chan *vocab.ActivityType)
+
+	defer close(inbox)
+	defer close(outbox)
+
+	h := NewActivityStream(cfg, activityStore, streamToken, inbox, outbox)
+
+	for _, lastEventID := range []string{"", "inbox=https%3A%2F%2Fexample.com%2Factivities%2F1"} {
+		rw := httptest.NewRecorder()
+
+		req := httptest.NewRequest(http.MethodGet, "https://example1.com/services/orb/stream", nil)
+		req.Header.Set("Authorization", "Bearer "+streamToken)
+		req.Header.Set(lastEventIDHeader, lastEventID)
+
+		h.handle(rw, req)
+
+		result := rw.Result()
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	}
+}
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+func connect(t *testing.T, serverURL, query, lastEventID string) (*http.Response, <-chan *sseEvent) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, serverURL+query, nil)
+	require.NoError(t, err)
+
+	req.Header.Set("Authorization", "Bearer "+streamToken)
+
+	if lastEventID != "" {
+		req.Header.Set(lastEventIDHeader, lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req) //nolint:bodyclose
+	require.NoError(t, err)
+
+	events := make(chan *sseEvent, 10)
+
+	go func() {
+		defer close(events)
+
+		scanner := bufio.NewScanner(resp.Body)
+
+		e := &sseEvent{}
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			switch {
+			case line == "":
+				if e.data != "" {
+					events <- e
+				}
+
+				e = &sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	return resp, events
+}
+
+func readEvent(t *testing.T, events <-chan *sseEvent) *sseEvent {
+	t.Helper()
+
+	select {
+	case e, ok := <-events:
+		require.True(t, ok, "stream closed")
+
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+
+		return nil
+	}
+}
+
+func newStreamActivity(t vocab.Type, actorIRI *url.URL, i int) *vocab.ActivityType {
+	obj := vocab.NewObjectProperty(vocab.WithIRI(testutil.MustParseURL("https://example.com/objects/1")))
+
+	opts := []vocab.Opt{
+		vocab.WithID(testutil.MustParseURL(fmt.Sprintf("https://example.com/activities/%s-%d", t, i))),
+		vocab.WithActor(actorIRI),
+	}
+
+	switch t {
+	case vocab.TypeCreate:
+		return vocab.NewCreateActivity(obj, opts...)
+	case vocab.TypeAnnounce:
+		return vocab.NewAnnounceActivity(obj, opts...)
+	case vocab.TypeFollow:
+		return vocab.NewFollowActivity(obj, opts...)
+	case vocab.TypeLike:
+		return vocab.NewLikeActivity(obj, opts...)
+	default:
+		panic("unsupported activity type: " + t)
+	}
+}
+
+func addStreamActivity(t *testing.T, activityStore spi.Store, refType spi.ReferenceType,
+	activity *vocab.ActivityType) {
+	t.Helper()
+
+	require.NoError(t, activityStore.AddActivity(activity))
+	require.NoError(t, activityStore.AddReference(refType, serviceIRI, activity.ID().URL()))
+}
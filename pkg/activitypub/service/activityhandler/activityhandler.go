@@ -8,7 +8,6 @@ package activityhandler
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -17,8 +16,8 @@ import (
 
 	"github.com/trustbloc/edge-core/pkg/log"
 
-	"github.com/trustbloc/orb/pkg/activitypub/client"
 	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
+	"github.com/trustbloc/orb/pkg/activitypub/service/actorresolver"
 	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
 	service "github.com/trustbloc/orb/pkg/activitypub/service/spi"
 	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
@@ -47,10 +46,25 @@ type Config struct {
 	// MaxWitnessDelay is the maximum delay from when the witness receives the transaction (via an Offer) for
 	// the witness to include the transaction into the ledger.
 	MaxWitnessDelay time.Duration
+
+	// ActorCacheTTL is the amount of time that a cached actor is considered to be fresh before
+	// it's refreshed from the remote server.
+	ActorCacheTTL time.Duration
+
+	// AnnounceBatchSize is the maximum number of anchor credentials that are announced to our followers
+	// in a single 'Announce' activity. Announce batching is enabled if either AnnounceBatchSize or
+	// AnnounceBatchWindow is set, otherwise each anchor credential is announced in its own 'Announce'.
+	AnnounceBatchSize int
+
+	// AnnounceBatchWindow is the maximum amount of time that an anchor credential is buffered before
+	// the batch that contains it is announced to our followers.
+	AnnounceBatchWindow time.Duration
 }
 
-type activityPubClient interface {
+type actorResolver interface {
 	GetActor(iri *url.URL) (*vocab.ActorType, error)
+	UpdateActor(actor *vocab.ActorType) error
+	DeleteActor(actorIRI *url.URL) error
 }
 
 type undoFunc func(activity *vocab.ActivityType) error
@@ -62,7 +76,7 @@ type handler struct {
 	store             store.Store
 	mutex             sync.RWMutex
 	subscribers       []chan *vocab.ActivityType
-	client            activityPubClient
+	actorResolver     actorResolver
 	undoFollow        undoFunc
 	undoInviteWitness undoFunc
 }
@@ -83,7 +97,7 @@ func newHandler(cfg *Config, s store.Store, t httpTransport, undoFollow, undoInv
 	h := &handler{
 		Config:            cfg,
 		store:             s,
-		client:            client.New(t),
+		actorResolver:     newActorResolver(cfg, s, t),
 		undoFollow:        undoFollow,
 		undoInviteWitness: undoInviteWitness,
 	}
@@ -93,6 +107,16 @@ func newHandler(cfg *Config, s store.Store, t httpTransport, undoFollow, undoInv
 	return h
 }
 
+func newActorResolver(cfg *Config, s store.Store, t httpTransport) *actorresolver.Resolver {
+	return actorresolver.New(
+		&actorresolver.Config{
+			ServiceName: cfg.ServiceName,
+			TTL:         cfg.ActorCacheTTL,
+		},
+		s, t,
+	)
+}
+
 func (h *handler) stop() {
 	logger.Infof("[%s] Stopping activity handler", h.ServiceName)
 
@@ -144,8 +168,6 @@ func (h *handler) handleUndoActivity(undo *vocab.ActivityType) error {
 		return fmt.Errorf("undo activity [%s]: %w", undo.ID(), err)
 	}
 
-	h.notify(undo)
-
 	return nil
 }
 
@@ -182,17 +204,7 @@ func defaultOptions() *service.Handlers {
 }
 
 func (h *handler) resolveActor(iri *url.URL) (*vocab.ActorType, error) {
-	actor, err := h.store.GetActor(iri)
-	if err == nil {
-		return actor, nil
-	}
-
-	if !errors.Is(err, store.ErrNotFound) {
-		return nil, err
-	}
-
-	// The actor isn't in our local store. Retrieve the actor from the remote server.
-	return h.client.GetActor(iri)
+	return h.actorResolver.GetActor(iri)
 }
 
 func containsIRI(iris []*url.URL, iri fmt.Stringer) bool {
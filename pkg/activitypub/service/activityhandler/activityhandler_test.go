@@ -279,6 +279,9 @@ func TestHandler_OutboxHandleCreateActivity(t *testing.T) {
 			vocab.NewObjectProperty(vocab.WithObject(obj)))
 
 		t.Run("Success", func(t *testing.T) {
+			subscriber := newMockActivitySubscriber(h.Subscribe())
+			go subscriber.Listen()
+
 			require.NoError(t, h.HandleActivity(create))
 
 			it, err := activityStore.QueryReferences(store.AnchorCredential,
@@ -288,6 +291,10 @@ func TestHandler_OutboxHandleCreateActivity(t *testing.T) {
 			refs, err := storeutil.ReadReferences(it, -1)
 			require.NoError(t, err)
 			require.NotEmpty(t, refs)
+
+			require.Eventually(t, func() bool {
+				return subscriber.Activity(create.ID().URL()) != nil
+			}, time.Second, 5*time.Millisecond)
 		})
 	})
 
@@ -1144,6 +1151,26 @@ func TestHandler_HandleRejectActivity(t *testing.T) {
 		require.True(t, !containsIRI(following, service1IRI))
 	})
 
+	t.Run("Reject Like (broken promise) -> Success", func(t *testing.T) {
+		like := vocab.NewLikeActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(testutil.MustParseURL("http://localhost:8301/vc/1234"))),
+			vocab.WithActor(service2IRI),
+		)
+
+		reject := vocab.NewRejectActivity(
+			vocab.NewObjectProperty(vocab.WithActivity(like)),
+			vocab.WithID(newActivityID(service1IRI)),
+			vocab.WithActor(service1IRI),
+			vocab.WithTo(service2IRI),
+		)
+
+		require.NoError(t, h.HandleActivity(reject))
+
+		time.Sleep(50 * time.Millisecond)
+
+		require.NotNil(t, subscriber.Activity(reject.ID()))
+	})
+
 	t.Run("No actor in Reject activity", func(t *testing.T) {
 		follow := vocab.NewFollowActivity(
 			vocab.NewObjectProperty(vocab.WithIRI(service1IRI)),
@@ -2048,16 +2075,30 @@ func TestHandler_HandleUndoFollowActivity(t *testing.T) {
 
 			undo := vocab.NewUndoActivity(
 				vocab.NewObjectProperty(vocab.WithIRI(follow.ID().URL())),
+				vocab.WithID(newActivityID(service2IRI)),
 				vocab.WithActor(service2IRI),
 				vocab.WithTo(service1IRI),
 			)
 
+			undoSubscriber := mocks.NewSubscriber(obHandler.Subscribe())
+
 			require.NoError(t, obHandler.HandleActivity(undo))
 
 			time.Sleep(50 * time.Millisecond)
 
 			require.NotNil(t, obSubscriber.Activity(undo.ID()))
 
+			// Subscribers are notified of the 'Undo' exactly once.
+			var notifications int
+
+			for _, a := range undoSubscriber.Activities() {
+				if a.ID().String() == undo.ID().String() {
+					notifications++
+				}
+			}
+
+			require.Equal(t, 1, notifications)
+
 			it, err = obHandler.store.QueryReferences(store.Following,
 				store.NewCriteria(store.WithObjectIRI(obHandler.ServiceIRI)))
 			require.NoError(t, err)
@@ -2336,6 +2377,434 @@ func TestHandler_HandleUndoInviteWitnessActivity(t *testing.T) {
 	})
 }
 
+func TestHandler_HandleUpdateActivity(t *testing.T) {
+	service1IRI := testutil.MustParseURL("http://localhost:8301/services/service1")
+	service2IRI := testutil.MustParseURL("http://localhost:8302/services/service2")
+	service3IRI := testutil.MustParseURL("http://localhost:8303/services/service3")
+
+	ibHandler, obHandler, ibSubscriber, _, stop := startInboxOutboxWithMocks(t, service1IRI, service2IRI)
+	defer stop()
+
+	require.NoError(t, ibHandler.store.PutActor(vocab.NewService(service2IRI,
+		vocab.WithInbox(testutil.NewMockID(service2IRI, "/inbox")),
+	)))
+
+	newInbox := testutil.NewMockID(service2IRI, "/new-inbox")
+	newKeyID := testutil.NewMockID(service2IRI, "/keys/new-key")
+
+	t.Run("Inbox", func(t *testing.T) {
+		t.Run("Success", func(t *testing.T) {
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithActorObject(
+					vocab.NewService(service2IRI,
+						vocab.WithInbox(newInbox),
+						vocab.WithPublicKey(vocab.NewPublicKey(
+							vocab.WithID(newKeyID),
+							vocab.WithOwner(service2IRI),
+							vocab.WithPublicKeyPem("-----BEGIN PUBLIC KEY-----"),
+						)),
+					),
+				)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, ibHandler.HandleActivity(update))
+
+			time.Sleep(50 * time.Millisecond)
+
+			require.NotNil(t, ibSubscriber.Activity(update.ID()))
+
+			actor, err := ibHandler.store.GetActor(service2IRI)
+			require.NoError(t, err)
+			require.Equal(t, newInbox.String(), actor.Inbox().String())
+			require.Equal(t, newKeyID.String(), actor.PublicKey().ID.String())
+
+			actor, err = ibHandler.resolveActor(service2IRI)
+			require.NoError(t, err)
+			require.Equal(t, newInbox.String(), actor.Inbox().String())
+		})
+
+		t.Run("Actor updating another actor -> error", func(t *testing.T) {
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithActorObject(vocab.NewService(service3IRI))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := ibHandler.HandleActivity(update)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "may not update actor")
+		})
+
+		t.Run("Public key not owned by actor -> error", func(t *testing.T) {
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithActorObject(
+					vocab.NewService(service2IRI,
+						vocab.WithPublicKey(vocab.NewPublicKey(
+							vocab.WithID(newKeyID),
+							vocab.WithOwner(service3IRI),
+						)),
+					),
+				)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := ibHandler.HandleActivity(update)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "is not owned by actor")
+		})
+
+		t.Run("No actor -> error", func(t *testing.T) {
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithActorObject(vocab.NewService(service2IRI))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := ibHandler.HandleActivity(update)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "no actor specified")
+		})
+
+		t.Run("Unsupported object type -> error", func(t *testing.T) {
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(service2IRI)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := ibHandler.HandleActivity(update)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "unsupported object type in 'Update' activity")
+		})
+
+		t.Run("Store error", func(t *testing.T) {
+			errExpected := errors.New("injected store error")
+
+			activityStore := &mocks.ActivityStore{}
+			activityStore.PutActorReturns(errExpected)
+
+			cfg := &Config{
+				ServiceName: "inbox2",
+				ServiceIRI:  service1IRI,
+			}
+
+			h := NewInbox(cfg, activityStore, mocks.NewOutbox(), &apmocks.HTTPTransport{})
+			require.NotNil(t, h)
+
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithActorObject(vocab.NewService(service2IRI))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := h.HandleActivity(update)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, errExpected))
+		})
+	})
+
+	t.Run("Outbox", func(t *testing.T) {
+		t.Run("Success", func(t *testing.T) {
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithActorObject(vocab.NewService(service2IRI))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, obHandler.HandleActivity(update))
+		})
+
+		t.Run("Not local service -> error", func(t *testing.T) {
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithActorObject(vocab.NewService(service3IRI))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := obHandler.HandleActivity(update)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "may only update its own actor")
+		})
+
+		t.Run("Unsupported object type -> error", func(t *testing.T) {
+			update := vocab.NewUpdateActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(service2IRI)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := obHandler.HandleActivity(update)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "unsupported object type in 'Update' activity")
+		})
+	})
+}
+
+func TestHandler_HandleDeleteActivity(t *testing.T) {
+	service1IRI := testutil.MustParseURL("http://localhost:8301/services/service1")
+	service2IRI := testutil.MustParseURL("http://localhost:8302/services/service2")
+	service3IRI := testutil.MustParseURL("http://localhost:8303/services/service3")
+
+	t.Run("Inbox", func(t *testing.T) {
+		ibHandler, _, ibSubscriber, _, stop := startInboxOutboxWithMocks(t, service1IRI, service2IRI)
+		defer stop()
+
+		t.Run("Delete actor", func(t *testing.T) {
+			require.NoError(t, ibHandler.store.AddReference(store.Follower, service1IRI, service2IRI))
+			require.NoError(t, ibHandler.store.AddReference(store.Witnessing, service1IRI, service2IRI))
+			require.NoError(t, ibHandler.store.PutActor(vocab.NewService(service2IRI)))
+
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(service2IRI)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, ibHandler.HandleActivity(del))
+
+			time.Sleep(50 * time.Millisecond)
+
+			require.NotNil(t, ibSubscriber.Activity(del.ID()))
+
+			for _, refType := range []store.ReferenceType{store.Follower, store.Witnessing} {
+				it, err := ibHandler.store.QueryReferences(refType,
+					store.NewCriteria(store.WithObjectIRI(ibHandler.ServiceIRI)))
+				require.NoError(t, err)
+
+				refs, err := storeutil.ReadReferences(it, -1)
+				require.NoError(t, err)
+				require.False(t, containsIRI(refs, service2IRI))
+			}
+
+			_, err := ibHandler.store.GetActor(service2IRI)
+			require.True(t, errors.Is(err, store.ErrNotFound))
+		})
+
+		t.Run("Delete activity (tombstone)", func(t *testing.T) {
+			like := vocab.NewLikeActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(anchCredID)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, ibHandler.store.AddActivity(like))
+			require.NoError(t, ibHandler.store.AddReference(store.Inbox, service1IRI, like.ID().URL()))
+			require.NoError(t, ibHandler.store.AddReference(store.Like, service1IRI, like.ID().URL()))
+
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithTombstone(vocab.NewTombstone(
+					vocab.WithID(like.ID().URL()),
+					vocab.WithFormerType(vocab.TypeLike),
+				))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, ibHandler.HandleActivity(del))
+
+			for _, refType := range []store.ReferenceType{store.Inbox, store.Like} {
+				it, err := ibHandler.store.QueryReferences(refType,
+					store.NewCriteria(store.WithObjectIRI(ibHandler.ServiceIRI)))
+				require.NoError(t, err)
+
+				refs, err := storeutil.ReadReferences(it, -1)
+				require.NoError(t, err)
+				require.False(t, containsIRI(refs, like.ID().URL()))
+			}
+		})
+
+		t.Run("Activity not found", func(t *testing.T) {
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(newActivityID(service2IRI))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, ibHandler.HandleActivity(del))
+		})
+
+		t.Run("Actor is not the actor of the activity -> error", func(t *testing.T) {
+			like := vocab.NewLikeActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(anchCredID)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, ibHandler.store.AddActivity(like))
+
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(like.ID().URL())),
+				vocab.WithID(newActivityID(service3IRI)),
+				vocab.WithActor(service3IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := ibHandler.HandleActivity(del)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "is not the actor of activity")
+		})
+
+		t.Run("No actor -> error", func(t *testing.T) {
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(service2IRI)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := ibHandler.HandleActivity(del)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "no actor specified in 'Delete' activity")
+		})
+
+		t.Run("No object IRI -> error", func(t *testing.T) {
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := ibHandler.HandleActivity(del)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "no object IRI specified in 'Delete' activity")
+		})
+	})
+
+	t.Run("Inbox store error", func(t *testing.T) {
+		errExpected := errors.New("injected store error")
+
+		activityStore := &mocks.ActivityStore{}
+		activityStore.GetActivityReturns(nil, errExpected)
+		activityStore.DeleteReferenceReturns(errExpected)
+
+		cfg := &Config{
+			ServiceName: "inbox2",
+			ServiceIRI:  service1IRI,
+		}
+
+		h := NewInbox(cfg, activityStore, mocks.NewOutbox(), &apmocks.HTTPTransport{})
+		require.NotNil(t, h)
+
+		t.Run("Delete actor", func(t *testing.T) {
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(service2IRI)),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := h.HandleActivity(del)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, errExpected))
+		})
+
+		t.Run("Delete activity", func(t *testing.T) {
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(newActivityID(service2IRI))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := h.HandleActivity(del)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, errExpected))
+		})
+	})
+
+	t.Run("Outbox", func(t *testing.T) {
+		_, obHandler, _, _, stop := startInboxOutboxWithMocks(t, service1IRI, service2IRI)
+		defer stop()
+
+		create := newMockCreateActivity(service2IRI, service1IRI, newTransactionID(service2IRI),
+			vocab.NewObjectProperty(vocab.WithIRI(anchCredID)))
+
+		require.NoError(t, obHandler.store.AddActivity(create))
+		require.NoError(t, obHandler.store.AddReference(store.Outbox, service2IRI, create.ID().URL()))
+
+		t.Run("Success", func(t *testing.T) {
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(create.ID().URL())),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, obHandler.HandleActivity(del))
+
+			it, err := obHandler.store.QueryReferences(store.Outbox,
+				store.NewCriteria(store.WithObjectIRI(obHandler.ServiceIRI)))
+			require.NoError(t, err)
+
+			refs, err := storeutil.ReadReferences(it, -1)
+			require.NoError(t, err)
+			require.False(t, containsIRI(refs, create.ID().URL()))
+		})
+
+		t.Run("Activity not found", func(t *testing.T) {
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(newActivityID(service2IRI))),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			require.NoError(t, obHandler.HandleActivity(del))
+		})
+
+		t.Run("Not the actor of the activity -> error", func(t *testing.T) {
+			like := vocab.NewLikeActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(anchCredID)),
+				vocab.WithID(newActivityID(service3IRI)),
+				vocab.WithActor(service3IRI),
+				vocab.WithTo(service2IRI),
+			)
+
+			require.NoError(t, obHandler.store.AddActivity(like))
+
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(like.ID().URL())),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := obHandler.HandleActivity(del)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "this service is not the actor of activity")
+		})
+
+		t.Run("No object IRI -> error", func(t *testing.T) {
+			del := vocab.NewDeleteActivity(
+				vocab.NewObjectProperty(),
+				vocab.WithID(newActivityID(service2IRI)),
+				vocab.WithActor(service2IRI),
+				vocab.WithTo(service1IRI),
+			)
+
+			err := obHandler.HandleActivity(del)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "no object IRI specified in 'Delete' activity")
+		})
+	})
+}
+
 func TestHandler_AnnounceAnchorCredential(t *testing.T) {
 	log.SetLevel("activitypub_service", log.DEBUG)
 
@@ -2466,6 +2935,180 @@ func TestHandler_AnnounceAnchorCredential(t *testing.T) {
 	})
 }
 
+func TestHandler_AnnounceAnchorCredentialBatch(t *testing.T) {
+	service1IRI := testutil.MustParseURL("http://localhost:8301/services/service1")
+	service2IRI := testutil.MustParseURL("http://localhost:8302/services/service2")
+
+	newCreate := func(i int) (*vocab.ActivityType, *url.URL) {
+		targetID := testutil.NewMockID(service1IRI, fmt.Sprintf("/cas/target%d", i))
+
+		return newMockCreateActivity(service1IRI, service2IRI, targetID,
+			vocab.NewObjectProperty(
+				vocab.WithAnchorCredentialReference(
+					vocab.NewAnchorCredentialReference(
+						testutil.NewMockID(service1IRI, fmt.Sprintf("/transactions/ref%d", i)), targetID, cid,
+					),
+				),
+			),
+		), targetID
+	}
+
+	requireShared := func(t *testing.T, activityStore store.Store, objectIRI *url.URL) {
+		t.Helper()
+
+		it, err := activityStore.QueryReferences(store.Share, store.NewCriteria(store.WithObjectIRI(objectIRI)))
+		require.NoError(t, err)
+
+		refs, err := storeutil.ReadReferences(it, -1)
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+	}
+
+	t.Run("Batch size reached", func(t *testing.T) {
+		cfg := &Config{
+			ServiceName:         "service2",
+			ServiceIRI:          service2IRI,
+			AnnounceBatchSize:   2,
+			AnnounceBatchWindow: time.Minute,
+		}
+
+		activityStore := memstore.New(cfg.ServiceName)
+		ob := mocks.NewOutbox().WithActivityID(testutil.NewMockID(service2IRI, "/activities/123456789"))
+
+		h := NewInbox(cfg, activityStore, ob, &apmocks.HTTPTransport{})
+		require.NotNil(t, h)
+
+		h.Start()
+		defer h.Stop()
+
+		create1, target1 := newCreate(1)
+		create2, target2 := newCreate(2)
+
+		require.NoError(t, h.announceAnchorCredentialRef(create1))
+		require.Empty(t, ob.Activities().QueryByType(vocab.TypeAnnounce))
+
+		require.NoError(t, h.announceAnchorCredentialRef(create2))
+
+		announces := ob.Activities().QueryByType(vocab.TypeAnnounce)
+		require.Len(t, announces, 1)
+
+		coll := announces[0].Object().OrderedCollection()
+		require.NotNil(t, coll)
+		require.Len(t, coll.Items(), 2)
+		require.Equal(t, target1.String(), coll.Items()[0].AnchorCredentialReference().Target().Object().ID().String())
+		require.Equal(t, target2.String(), coll.Items()[1].AnchorCredentialReference().Target().Object().ID().String())
+
+		requireShared(t, activityStore, target1)
+		requireShared(t, activityStore, target2)
+	})
+
+	t.Run("Batch window expired", func(t *testing.T) {
+		cfg := &Config{
+			ServiceName:         "service2",
+			ServiceIRI:          service2IRI,
+			AnnounceBatchWindow: 20 * time.Millisecond,
+		}
+
+		ob := mocks.NewOutbox().WithActivityID(testutil.NewMockID(service2IRI, "/activities/123456789"))
+
+		h := NewInbox(cfg, memstore.New(cfg.ServiceName), ob, &apmocks.HTTPTransport{})
+		require.NotNil(t, h)
+
+		h.Start()
+		defer h.Stop()
+
+		for i := 0; i < 3; i++ {
+			create, _ := newCreate(i)
+			require.NoError(t, h.announceAnchorCredentialRef(create))
+		}
+
+		require.Eventually(t, func() bool {
+			return len(ob.Activities().QueryByType(vocab.TypeAnnounce)) == 1
+		}, time.Second, 5*time.Millisecond)
+
+		require.Len(t, ob.Activities().QueryByType(vocab.TypeAnnounce)[0].Object().OrderedCollection().Items(), 3)
+	})
+
+	t.Run("Flush on stop", func(t *testing.T) {
+		cfg := &Config{
+			ServiceName:       "service2",
+			ServiceIRI:        service2IRI,
+			AnnounceBatchSize: 10,
+		}
+
+		ob := mocks.NewOutbox().WithActivityID(testutil.NewMockID(service2IRI, "/activities/123456789"))
+
+		h := NewInbox(cfg, memstore.New(cfg.ServiceName), ob, &apmocks.HTTPTransport{})
+		require.NotNil(t, h)
+
+		h.Start()
+
+		create, _ := newCreate(1)
+		require.NoError(t, h.announceAnchorCredentialRef(create))
+
+		h.Stop()
+
+		require.Len(t, ob.Activities().QueryByType(vocab.TypeAnnounce), 1)
+	})
+
+	t.Run("Post error -> ignored", func(t *testing.T) {
+		cfg := &Config{
+			ServiceName:       "service2",
+			ServiceIRI:        service2IRI,
+			AnnounceBatchSize: 1,
+		}
+
+		activityStore := memstore.New(cfg.ServiceName)
+		ob := mocks.NewOutbox().WithError(errors.New("injected post error"))
+
+		h := NewInbox(cfg, activityStore, ob, &apmocks.HTTPTransport{})
+		require.NotNil(t, h)
+
+		h.Start()
+		defer h.Stop()
+
+		create, target := newCreate(1)
+		require.NoError(t, h.announceAnchorCredentialRef(create))
+
+		it, err := activityStore.QueryReferences(store.Share, store.NewCriteria(store.WithObjectIRI(target)))
+		require.NoError(t, err)
+
+		refs, err := storeutil.ReadReferences(it, -1)
+		require.NoError(t, err)
+		require.Empty(t, refs)
+	})
+
+	t.Run("Post error -> retried", func(t *testing.T) {
+		cfg := &Config{
+			ServiceName:         "service2",
+			ServiceIRI:          service2IRI,
+			AnnounceBatchSize:   1,
+			AnnounceBatchWindow: 50 * time.Millisecond,
+		}
+
+		activityStore := memstore.New(cfg.ServiceName)
+		ob := mocks.NewOutbox().
+			WithActivityID(testutil.NewMockID(service2IRI, "/activities/123456789")).
+			WithError(errors.New("injected post error"))
+
+		h := NewInbox(cfg, activityStore, ob, &apmocks.HTTPTransport{})
+		require.NotNil(t, h)
+
+		h.Start()
+		defer h.Stop()
+
+		create, target := newCreate(1)
+		require.NoError(t, h.announceAnchorCredentialRef(create))
+
+		ob.WithError(nil)
+
+		time.Sleep(200 * time.Millisecond)
+
+		require.Len(t, ob.Activities().QueryByType(vocab.TypeAnnounce), 1)
+		requireShared(t, activityStore, target)
+	})
+}
+
 type mockActivitySubscriber struct {
 	mutex        sync.RWMutex
 	activities   map[string]*vocab.ActivityType
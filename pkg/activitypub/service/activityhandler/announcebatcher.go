@@ -0,0 +1,164 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package activityhandler
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+const (
+	defaultAnnounceBatchSize   = 100
+	defaultAnnounceBatchWindow = time.Second
+
+	// maxAnnounceAttempts is the maximum number of times that an anchor credential is included in a batch
+	// that fails to be posted before it's dropped.
+	maxAnnounceAttempts = 5
+)
+
+// announcement is an anchor credential reference that is to be announced to our followers. After the 'Announce'
+// is posted, it is added to the shares of the given object.
+type announcement struct {
+	ref      *vocab.AnchorCredentialReferenceType
+	shareIRI *url.URL
+	attempts int
+}
+
+type postAnnounceFunc func(announcements []*announcement) error
+
+// announceBatcher buffers announcements until either the maximum batch size is reached or the batch window
+// expires (whichever comes first) and then posts all of the buffered announcements in a single 'Announce'.
+// If the batch can't be posted then its announcements are returned to the buffer and retried with the next
+// batch, up to a maximum number of attempts.
+type announceBatcher struct {
+	maxSize int
+	window  time.Duration
+	post    postAnnounceFunc
+
+	mutex         sync.Mutex
+	announcements []*announcement
+	timer         *time.Timer
+	stopped       bool
+}
+
+func newAnnounceBatcher(maxSize int, window time.Duration, post postAnnounceFunc) *announceBatcher {
+	if maxSize <= 0 {
+		maxSize = defaultAnnounceBatchSize
+	}
+
+	if window <= 0 {
+		window = defaultAnnounceBatchWindow
+	}
+
+	return &announceBatcher{
+		maxSize: maxSize,
+		window:  window,
+		post:    post,
+	}
+}
+
+// add adds the given announcement to the current batch. The batch is posted immediately if it's full,
+// otherwise it's posted when the batch window expires.
+func (b *announceBatcher) add(a *announcement) {
+	b.mutex.Lock()
+
+	b.announcements = append(b.announcements, a)
+
+	if len(b.announcements) < b.maxSize {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.window, b.flush)
+		}
+
+		b.mutex.Unlock()
+
+		return
+	}
+
+	announcements := b.take()
+
+	b.mutex.Unlock()
+
+	b.postOrRetry(announcements)
+}
+
+// flush posts all buffered announcements.
+func (b *announceBatcher) flush() {
+	b.mutex.Lock()
+	announcements := b.take()
+	b.mutex.Unlock()
+
+	if len(announcements) > 0 {
+		b.postOrRetry(announcements)
+	}
+}
+
+// stop posts all buffered announcements. Announcements that fail to be posted after the batcher is stopped
+// aren't retried.
+func (b *announceBatcher) stop() {
+	b.mutex.Lock()
+	b.stopped = true
+	b.mutex.Unlock()
+
+	b.flush()
+}
+
+func (b *announceBatcher) postOrRetry(announcements []*announcement) {
+	if err := b.post(announcements); err != nil {
+		b.retry(announcements, err)
+	}
+}
+
+// retry returns the given announcements (whose batch failed to be posted) to the front of the buffer so that
+// they're posted with the next batch. Announcements that have reached the maximum number of attempts are dropped.
+func (b *announceBatcher) retry(announcements []*announcement, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var retained []*announcement
+
+	for _, a := range announcements {
+		a.attempts++
+
+		if b.stopped || a.attempts >= maxAnnounceAttempts {
+			logger.Errorf("Anchor credential [%s] was not announced to our followers after %d attempt(s): %s",
+				a.shareIRI, a.attempts, err)
+
+			continue
+		}
+
+		retained = append(retained, a)
+	}
+
+	if len(retained) == 0 {
+		return
+	}
+
+	logger.Warnf("Failed to announce %d anchor credential(s) to our followers. Retrying in %s: %s",
+		len(retained), b.window, err)
+
+	b.announcements = append(retained, b.announcements...)
+
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+}
+
+// take removes and returns the buffered announcements. The caller must hold the lock.
+func (b *announceBatcher) take() []*announcement {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	announcements := b.announcements
+	b.announcements = nil
+
+	return announcements
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/trustbloc/orb/pkg/activitypub/resthandler"
+	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
 	service "github.com/trustbloc/orb/pkg/activitypub/service/spi"
 	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
 	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
@@ -27,8 +28,9 @@ type Inbox struct {
 	*handler
 	*service.Handlers
 
-	outbox       service.Outbox
-	followersIRI *url.URL
+	outbox          service.Outbox
+	followersIRI    *url.URL
+	announceBatcher *announceBatcher
 }
 
 // NewInbox returns a new ActivityPub inbox activity handler.
@@ -61,9 +63,22 @@ func NewInbox(cfg *Config, s store.Store, outbox service.Outbox, t httpTransport
 		},
 	)
 
+	if cfg.AnnounceBatchSize > 0 || cfg.AnnounceBatchWindow > 0 {
+		h.announceBatcher = newAnnounceBatcher(cfg.AnnounceBatchSize, cfg.AnnounceBatchWindow, h.postAnnounce)
+
+		h.Lifecycle = lifecycle.New(cfg.ServiceName, lifecycle.WithStop(h.stop))
+	}
+
 	return h
 }
 
+func (h *Inbox) stop() {
+	// Announce any anchor credentials that are still buffered.
+	h.announceBatcher.stop()
+
+	h.handler.stop()
+}
+
 // HandleActivity handles the ActivityPub activity in the inbox.
 func (h *Inbox) HandleActivity(activity *vocab.ActivityType) error { //nolint: cyclop
 	typeProp := activity.Type()
@@ -87,11 +102,25 @@ func (h *Inbox) HandleActivity(activity *vocab.ActivityType) error { //nolint: c
 		return h.handleLikeActivity(activity)
 	case typeProp.Is(vocab.TypeUndo):
 		return h.handleUndoActivity(activity)
+	case typeProp.Is(vocab.TypeUpdate):
+		return h.handleUpdateActivity(activity)
+	case typeProp.Is(vocab.TypeDelete):
+		return h.handleDeleteActivity(activity)
 	default:
 		return fmt.Errorf("unsupported activity type: %s", typeProp.Types())
 	}
 }
 
+func (h *Inbox) handleUndoActivity(undo *vocab.ActivityType) error {
+	if err := h.handler.handleUndoActivity(undo); err != nil {
+		return err
+	}
+
+	h.notify(undo)
+
+	return nil
+}
+
 func (h *Inbox) handleCreateActivity(create *vocab.ActivityType) error {
 	logger.Debugf("[%s] Handling 'Create' activity: %s", h.ServiceName, create.ID())
 
@@ -217,7 +246,7 @@ func (h *Inbox) acceptActor(activity *vocab.ActivityType, actor *vocab.ActorType
 func (h *Inbox) handleAcceptActivity(accept *vocab.ActivityType) error {
 	logger.Debugf("[%s] Handling 'Accept' activity: %s", h.ServiceName, accept.ID())
 
-	if err := h.validateAcceptRejectActivity(accept); err != nil {
+	if err := h.validateAcceptRejectActivity(accept, vocab.TypeFollow, vocab.TypeInviteWitness); err != nil {
 		return err
 	}
 
@@ -271,16 +300,24 @@ func (h *Inbox) handleAccept(accept *vocab.ActivityType, refType store.Reference
 func (h *Inbox) handleRejectActivity(reject *vocab.ActivityType) error {
 	logger.Debugf("[%s] Handling 'Reject' activity: %s", h.ServiceName, reject.ID())
 
-	if err := h.validateAcceptRejectActivity(reject); err != nil {
+	// A 'Reject' of a 'Like' indicates that this service (as a witness) promised to add an anchor credential
+	// to its log but the credential was not found in the log before the promise expired.
+	if err := h.validateAcceptRejectActivity(reject,
+		vocab.TypeFollow, vocab.TypeInviteWitness, vocab.TypeLike); err != nil {
 		return err
 	}
 
+	if like := reject.Object().Activity(); like.Type().Is(vocab.TypeLike) {
+		logger.Warnf("[%s] %s reported that the promise to add anchor credential [%s] to the log was broken",
+			h.ServiceName, reject.Actor(), like.Object().IRI())
+	}
+
 	h.notify(reject)
 
 	return nil
 }
 
-func (h *Inbox) validateAcceptRejectActivity(a *vocab.ActivityType) error {
+func (h *Inbox) validateAcceptRejectActivity(a *vocab.ActivityType, supportedTypes ...vocab.Type) error {
 	logger.Debugf("[%s] Handling '%s' activity: %s", h.ServiceName, a.Type(), a.ID())
 
 	if a.Actor() == nil {
@@ -292,7 +329,7 @@ func (h *Inbox) validateAcceptRejectActivity(a *vocab.ActivityType) error {
 		return fmt.Errorf("no activity specified in the 'object' field of the '%s' activity", a.Type())
 	}
 
-	if !activity.Type().IsAny(vocab.TypeFollow, vocab.TypeInviteWitness) {
+	if !activity.Type().IsAny(supportedTypes...) {
 		return fmt.Errorf("unsupported activity type [%s] in the 'object' field of the 'Accept' activity",
 			activity.Type())
 	}
@@ -483,6 +520,111 @@ func (h *Inbox) handleLikeActivity(like *vocab.ActivityType) error {
 	return nil
 }
 
+func (h *Inbox) handleUpdateActivity(update *vocab.ActivityType) error {
+	logger.Infof("[%s] Handling 'Update' activity: %s", h.ServiceName, update.ID())
+
+	actor := update.Object().Actor()
+	if actor == nil {
+		return fmt.Errorf("unsupported object type in 'Update' activity [%s]: %s", update.ID(), update.Object().Type())
+	}
+
+	err := validateUpdatedActor(update, actor)
+	if err != nil {
+		return fmt.Errorf("invalid 'Update' activity [%s]: %w", update.ID(), err)
+	}
+
+	err = h.actorResolver.UpdateActor(actor)
+	if err != nil {
+		return fmt.Errorf("unable to store actor in 'Update' activity [%s]: %w", update.ID(), err)
+	}
+
+	logger.Debugf("[%s] Actor [%s] was updated", h.ServiceName, actor.ID())
+
+	h.notify(update)
+
+	return nil
+}
+
+func (h *Inbox) handleDeleteActivity(del *vocab.ActivityType) error {
+	logger.Infof("[%s] Handling 'Delete' activity: %s", h.ServiceName, del.ID())
+
+	if del.Actor() == nil {
+		return fmt.Errorf("no actor specified in 'Delete' activity [%s]", del.ID())
+	}
+
+	objectIRI := getDeletedObjectIRI(del.Object())
+	if objectIRI == nil {
+		return fmt.Errorf("no object IRI specified in 'Delete' activity [%s]", del.ID())
+	}
+
+	var err error
+
+	if objectIRI.String() == del.Actor().String() {
+		err = h.deleteActor(objectIRI)
+	} else {
+		err = h.deleteActivity(del.Actor(), objectIRI)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error handling 'Delete' activity [%s]: %w", del.ID(), err)
+	}
+
+	h.notify(del)
+
+	return nil
+}
+
+// deleteActor removes all relationships between the local service and the given (deleted) actor
+// and removes the actor from the local store.
+func (h *Inbox) deleteActor(actorIRI *url.URL) error {
+	for _, refType := range []store.ReferenceType{store.Follower, store.Following, store.Witness, store.Witnessing} {
+		if err := h.store.DeleteReference(refType, h.ServiceIRI, actorIRI); err != nil {
+			return fmt.Errorf("unable to delete %s from %s's collection of %s: %w", actorIRI, h.ServiceIRI, refType, err)
+		}
+	}
+
+	if err := h.actorResolver.DeleteActor(actorIRI); err != nil {
+		return fmt.Errorf("unable to delete actor %s: %w", actorIRI, err)
+	}
+
+	logger.Debugf("[%s] Actor %s (if found) was deleted from the store and from all collections",
+		h.ServiceIRI, actorIRI)
+
+	return nil
+}
+
+// deleteActivity removes the given activity from the inbox. The activity may only be deleted by its actor.
+func (h *Inbox) deleteActivity(actorIRI, activityIRI *url.URL) error {
+	activity, err := h.store.GetActivity(activityIRI)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			logger.Debugf("[%s] Activity %s not found. Nothing to delete.", h.ServiceIRI, activityIRI)
+
+			return nil
+		}
+
+		return fmt.Errorf("unable to retrieve activity %s from storage: %w", activityIRI, err)
+	}
+
+	if activity.Actor() == nil || activity.Actor().String() != actorIRI.String() {
+		return fmt.Errorf("actor [%s] is not the actor of activity [%s]", actorIRI, activityIRI)
+	}
+
+	if err := h.store.DeleteReference(store.Inbox, h.ServiceIRI, activityIRI); err != nil {
+		return fmt.Errorf("unable to delete %s from inbox: %w", activityIRI, err)
+	}
+
+	if activity.Type().Is(vocab.TypeLike) {
+		if err := h.store.DeleteReference(store.Like, h.ServiceIRI, activityIRI); err != nil {
+			return fmt.Errorf("unable to delete %s from likes: %w", activityIRI, err)
+		}
+	}
+
+	logger.Debugf("[%s] Activity %s was deleted", h.ServiceIRI, activityIRI)
+
+	return nil
+}
+
 func (h *Inbox) handleAnchorCredential(target *vocab.ObjectProperty, obj *vocab.ObjectType) error {
 	if !target.Type().Is(vocab.TypeContentAddressedStorage) {
 		return fmt.Errorf("unsupported target type %s", target.Type().Types())
@@ -548,6 +690,24 @@ func (h *Inbox) announceAnchorCredential(create *vocab.ActivityType) error {
 		return err
 	}
 
+	return h.announce(&announcement{ref: ref, shareIRI: ref.ID().URL()})
+}
+
+func (h *Inbox) announceAnchorCredentialRef(create *vocab.ActivityType) error {
+	ref := create.Object().AnchorCredentialReference()
+
+	return h.announce(&announcement{ref: ref, shareIRI: ref.Target().Object().ID().URL()})
+}
+
+// announce announces the given anchor credential to our followers. If batching is enabled then the anchor
+// credential is added to the current batch, otherwise it's announced immediately.
+func (h *Inbox) announce(a *announcement) error {
+	if h.announceBatcher != nil {
+		h.announceBatcher.add(a)
+
+		return nil
+	}
+
 	published := time.Now()
 
 	announce := vocab.NewAnnounceActivity(
@@ -556,7 +716,7 @@ func (h *Inbox) announceAnchorCredential(create *vocab.ActivityType) error {
 				vocab.NewCollection(
 					[]*vocab.ObjectProperty{
 						vocab.NewObjectProperty(
-							vocab.WithAnchorCredentialReference(ref),
+							vocab.WithAnchorCredentialReference(a.ref),
 						),
 					},
 				),
@@ -571,32 +731,25 @@ func (h *Inbox) announceAnchorCredential(create *vocab.ActivityType) error {
 		return err
 	}
 
-	logger.Debugf("[%s] Adding 'Announce' %s to shares of %s", h.ServiceIRI, announce.ID(), ref.ID())
-
-	err = h.store.AddReference(store.Share, ref.ID().URL(), activityID)
-	if err != nil {
-		logger.Warnf("[%s] Error adding 'Announce' activity %s to 'shares' of %s: %s",
-			h.ServiceIRI, announce.ID(), ref.ID(), err)
-	}
+	h.addShare(a.shareIRI, activityID)
 
 	return nil
 }
 
-func (h *Inbox) announceAnchorCredentialRef(create *vocab.ActivityType) error {
-	ref := create.Object().AnchorCredentialReference()
+// postAnnounce posts a single 'Announce' activity for a batch of anchor credentials.
+func (h *Inbox) postAnnounce(announcements []*announcement) error {
+	items := make([]*vocab.ObjectProperty, len(announcements))
+
+	for i, a := range announcements {
+		items[i] = vocab.NewObjectProperty(vocab.WithAnchorCredentialReference(a.ref))
+	}
 
 	published := time.Now()
 
 	announce := vocab.NewAnnounceActivity(
 		vocab.NewObjectProperty(
-			vocab.WithCollection(
-				vocab.NewCollection(
-					[]*vocab.ObjectProperty{
-						vocab.NewObjectProperty(
-							vocab.WithAnchorCredentialReference(ref),
-						),
-					},
-				),
+			vocab.WithOrderedCollection(
+				vocab.NewOrderedCollection(items),
 			),
 		),
 		vocab.WithTo(h.followersIRI),
@@ -605,22 +758,29 @@ func (h *Inbox) announceAnchorCredentialRef(create *vocab.ActivityType) error {
 
 	activityID, err := h.outbox.Post(announce)
 	if err != nil {
-		return err
+		return fmt.Errorf("post 'Announce' for %d anchor credentials: %w", len(announcements), err)
 	}
 
-	anchorCredID := ref.Target().Object().ID()
+	logger.Debugf("[%s] Announced %d anchor credentials to our followers in %s",
+		h.ServiceIRI, len(announcements), activityID)
 
-	logger.Debugf("[%s] Adding 'Announce' %s to shares of %s", h.ServiceIRI, announce.ID(), anchorCredID)
-
-	err = h.store.AddReference(store.Share, anchorCredID.URL(), activityID)
-	if err != nil {
-		logger.Warnf("[%s] Error adding 'Announce' activity %s to 'shares' of %s",
-			h.ServiceIRI, announce.ID(), anchorCredID)
+	for _, a := range announcements {
+		h.addShare(a.shareIRI, activityID)
 	}
 
 	return nil
 }
 
+func (h *Inbox) addShare(shareIRI, activityID *url.URL) {
+	logger.Debugf("[%s] Adding 'Announce' %s to shares of %s", h.ServiceIRI, activityID, shareIRI)
+
+	err := h.store.AddReference(store.Share, shareIRI, activityID)
+	if err != nil {
+		logger.Warnf("[%s] Error adding 'Announce' activity %s to 'shares' of %s: %s",
+			h.ServiceIRI, activityID, shareIRI, err)
+	}
+}
+
 func (h *Inbox) validateOfferActivity(offer *vocab.ActivityType) error {
 	if offer.StartTime() == nil {
 		return fmt.Errorf("startTime is required")
@@ -748,6 +908,44 @@ func (h *Inbox) getActivityFromOutbox(activityIRI *url.URL) (*vocab.ActivityType
 	return activities[0], nil
 }
 
+func validateUpdatedActor(update *vocab.ActivityType, actor *vocab.ActorType) error {
+	if update.Actor() == nil {
+		return fmt.Errorf("no actor specified")
+	}
+
+	if actor.ID() == nil {
+		return fmt.Errorf("no ID specified in actor")
+	}
+
+	// An actor may only update itself.
+	if actor.ID().String() != update.Actor().String() {
+		return fmt.Errorf("actor [%s] may not update actor [%s]", update.Actor(), actor.ID())
+	}
+
+	pubKey := actor.PublicKey()
+	if pubKey != nil && pubKey.Owner != nil && pubKey.Owner.String() != actor.ID().String() {
+		return fmt.Errorf("public key [%s] is not owned by actor [%s]", pubKey.ID, actor.ID())
+	}
+
+	return nil
+}
+
+func getDeletedObjectIRI(obj *vocab.ObjectProperty) *url.URL {
+	if iri := obj.IRI(); iri != nil {
+		return iri
+	}
+
+	if tombstone := obj.Tombstone(); tombstone != nil && tombstone.ID() != nil {
+		return tombstone.ID().URL()
+	}
+
+	if o := obj.Object(); o != nil && o.ID() != nil {
+		return o.ID().URL()
+	}
+
+	return nil
+}
+
 func newAnchorCredentialReferenceFromCreate(create *vocab.ActivityType) (*vocab.AnchorCredentialReferenceType, error) {
 	anchorCredential := create.Object().Object()
 
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package activityhandler
 
 import (
+	"errors"
 	"fmt"
 
 	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
@@ -34,8 +35,19 @@ func NewOutbox(cfg *Config, s store.Store, t httpTransport) *Outbox {
 	return h
 }
 
-// HandleActivity handles the ActivityPub activity in the outbox.
+// HandleActivity handles the ActivityPub activity in the outbox. Subscribers are notified of the activity
+// after it's successfully handled.
 func (h *Outbox) HandleActivity(activity *vocab.ActivityType) error {
+	if err := h.handleActivity(activity); err != nil {
+		return err
+	}
+
+	h.notify(activity)
+
+	return nil
+}
+
+func (h *Outbox) handleActivity(activity *vocab.ActivityType) error {
 	typeProp := activity.Type()
 
 	switch {
@@ -43,6 +55,10 @@ func (h *Outbox) HandleActivity(activity *vocab.ActivityType) error {
 		return h.handleCreateActivity(activity)
 	case typeProp.Is(vocab.TypeUndo):
 		return h.handleUndoActivity(activity)
+	case typeProp.Is(vocab.TypeUpdate):
+		return h.handleUpdateActivity(activity)
+	case typeProp.Is(vocab.TypeDelete):
+		return h.handleDeleteActivity(activity)
 	default:
 		// Nothing to do for activity.
 		return nil
@@ -77,6 +93,53 @@ func (h *handler) handleCreateActivity(create *vocab.ActivityType) error {
 	return nil
 }
 
+func (h *Outbox) handleUpdateActivity(update *vocab.ActivityType) error {
+	logger.Debugf("[%s] Handling 'Update' activity: %s", h.ServiceName, update.ID())
+
+	actor := update.Object().Actor()
+	if actor == nil {
+		return fmt.Errorf("unsupported object type in 'Update' activity [%s]: %s", update.ID(), update.Object().Type())
+	}
+
+	if actor.ID() == nil || actor.ID().String() != h.ServiceIRI.String() {
+		return fmt.Errorf("this service may only update its own actor in 'Update' activity [%s]", update.ID())
+	}
+
+	return nil
+}
+
+func (h *Outbox) handleDeleteActivity(del *vocab.ActivityType) error {
+	logger.Debugf("[%s] Handling 'Delete' activity: %s", h.ServiceName, del.ID())
+
+	objectIRI := getDeletedObjectIRI(del.Object())
+	if objectIRI == nil {
+		return fmt.Errorf("no object IRI specified in 'Delete' activity [%s]", del.ID())
+	}
+
+	activity, err := h.store.GetActivity(objectIRI)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			logger.Debugf("[%s] Activity %s not found. Nothing to delete.", h.ServiceName, objectIRI)
+
+			return nil
+		}
+
+		return fmt.Errorf("unable to retrieve activity %s from storage: %w", objectIRI, err)
+	}
+
+	if activity.Actor() == nil || activity.Actor().String() != h.ServiceIRI.String() {
+		return fmt.Errorf("this service is not the actor of activity [%s]", objectIRI)
+	}
+
+	if err := h.store.DeleteReference(store.Outbox, h.ServiceIRI, objectIRI); err != nil {
+		return fmt.Errorf("unable to delete %s from outbox: %w", objectIRI, err)
+	}
+
+	logger.Debugf("[%s] Activity %s was deleted from the outbox", h.ServiceName, objectIRI)
+
+	return nil
+}
+
 func (h *Outbox) undoAddReference(activity *vocab.ActivityType, refType store.ReferenceType) error {
 	if activity.Actor().String() != h.ServiceIRI.String() {
 		return fmt.Errorf("this service is not the actor for the 'Undo'")
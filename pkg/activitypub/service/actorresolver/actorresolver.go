@@ -0,0 +1,318 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package actorresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client"
+	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+var logger = log.New("activitypub_service")
+
+const defaultTTL = time.Hour
+
+// ErrRefreshLimited is returned by RefreshPublicKey if the key was already refreshed within the TTL.
+var ErrRefreshLimited = errors.New("public key was refreshed recently")
+
+// Config holds the configuration parameters for the actor resolver.
+type Config struct {
+	// ServiceName is the name of the service (used for logging).
+	ServiceName string
+
+	// TTL is the amount of time that an actor in the local store is considered to be fresh. After the TTL
+	// expires, the actor is re-fetched from the remote server (using a conditional request if possible).
+	TTL time.Duration
+}
+
+type activityPubClient interface {
+	GetActorIfModified(actorIRI *url.URL,
+		validators *client.CacheValidators) (*vocab.ActorType, *client.CacheValidators, error)
+	GetPublicKey(keyIRI *url.URL) (*vocab.PublicKeyType, error)
+}
+
+type httpTransport interface {
+	Get(ctx context.Context, req *transport.Request) (*http.Response, error)
+}
+
+type cacheEntry struct {
+	validators *client.CacheValidators
+	expiry     time.Time
+}
+
+// Resolver resolves actors and public keys. Actors are cached in the local ActivityPub store and are
+// refreshed from the remote server after the configured TTL expires.
+type Resolver struct {
+	*Config
+
+	store   store.Store
+	client  activityPubClient
+	mutex   sync.RWMutex
+	entries map[string]*cacheEntry
+	owners  map[string]*url.URL
+
+	refreshMutex sync.Mutex
+	refreshed    map[string]time.Time
+}
+
+// New returns a new actor resolver.
+func New(cfg *Config, s store.Store, t httpTransport) *Resolver {
+	if cfg.TTL == 0 {
+		cfg.TTL = defaultTTL
+	}
+
+	return &Resolver{
+		Config:    cfg,
+		store:     s,
+		client:    client.New(t),
+		entries:   make(map[string]*cacheEntry),
+		owners:    make(map[string]*url.URL),
+		refreshed: make(map[string]time.Time),
+	}
+}
+
+// GetActor returns the actor for the given IRI. If the actor is in the local store and its TTL hasn't expired
+// then the stored actor is returned, otherwise the actor is retrieved from the remote server and the local store
+// is updated. If the remote server can't be reached when refreshing an expired actor then the stored actor
+// is returned.
+//
+// Note that the time at which an actor was retrieved isn't persisted, so the TTL of an actor that was stored by
+// a previous instance of the service starts when the actor is first accessed.
+func (r *Resolver) GetActor(actorIRI *url.URL) (*vocab.ActorType, error) {
+	actor, err := r.store.GetActor(actorIRI)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			return nil, fmt.Errorf("load actor %s from storage: %w", actorIRI, err)
+		}
+
+		logger.Debugf("[%s] Actor not found in local store. Retrieving actor from %s", r.ServiceName, actorIRI)
+
+		return r.fetch(actorIRI, nil, nil)
+	}
+
+	entry := r.getOrAddEntry(actor)
+
+	if time.Now().Before(entry.expiry) {
+		logger.Debugf("[%s] Found actor %s in local store", r.ServiceName, actorIRI)
+
+		return actor, nil
+	}
+
+	logger.Debugf("[%s] Actor %s has expired. Refreshing actor from remote server", r.ServiceName, actorIRI)
+
+	refreshed, err := r.fetch(actorIRI, actor, entry.validators)
+	if err != nil {
+		logger.Warnf("[%s] Unable to refresh actor %s. Using actor from local store: %s", r.ServiceName, actorIRI, err)
+
+		return actor, nil
+	}
+
+	return refreshed, nil
+}
+
+// RefreshActor unconditionally retrieves the actor from the remote server and updates the local store. This
+// function should be called when the stored actor is known to be stale, for example when an HTTP signature
+// can't be verified with the actor's stored public key.
+func (r *Resolver) RefreshActor(actorIRI *url.URL) (*vocab.ActorType, error) {
+	logger.Debugf("[%s] Refreshing actor %s from remote server", r.ServiceName, actorIRI)
+
+	return r.fetch(actorIRI, nil, nil)
+}
+
+// UpdateActor replaces the actor in the local store with the given actor, for example when the actor
+// announces a change with an 'Update' activity. The TTL of the actor is reset.
+func (r *Resolver) UpdateActor(actor *vocab.ActorType) error {
+	if err := r.store.PutActor(actor); err != nil {
+		return fmt.Errorf("store actor %s: %w", actor.ID(), err)
+	}
+
+	r.setEntry(actor, nil)
+
+	return nil
+}
+
+// DeleteActor removes the actor from the local store and from the cache, for example when the actor
+// announces that it was deleted with a 'Delete' activity.
+func (r *Resolver) DeleteActor(actorIRI *url.URL) error {
+	if err := r.store.DeleteActor(actorIRI); err != nil {
+		return fmt.Errorf("delete actor %s: %w", actorIRI, err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.entries, actorIRI.String())
+
+	for keyID, ownerIRI := range r.owners {
+		if ownerIRI.String() == actorIRI.String() {
+			delete(r.owners, keyID)
+		}
+	}
+
+	return nil
+}
+
+// GetPublicKey returns the public key for the given key IRI. The key is resolved from the owner of the key
+// (which is cached according to the TTL). If the owner doesn't publish the key then the key is retrieved
+// directly from the remote server.
+func (r *Resolver) GetPublicKey(keyIRI *url.URL) (*vocab.PublicKeyType, error) {
+	return r.resolvePublicKey(keyIRI, r.GetActor)
+}
+
+// RefreshPublicKey refreshes the owner of the given key from the remote server and returns the public key.
+// A key is refreshed at most once per TTL so that requests with invalid signatures (which may reference
+// any key ID) can't be used to trigger requests to remote servers. ErrRefreshLimited is returned if the key
+// was refreshed within the TTL.
+func (r *Resolver) RefreshPublicKey(keyIRI *url.URL) (*vocab.PublicKeyType, error) {
+	if !r.allowRefresh(keyIRI.String()) {
+		logger.Debugf("[%s] Public key %s was refreshed within the last %s", r.ServiceName, keyIRI, r.TTL)
+
+		return nil, ErrRefreshLimited
+	}
+
+	return r.resolvePublicKey(keyIRI, r.RefreshActor)
+}
+
+// allowRefresh returns true if the given key wasn't refreshed within the TTL and, if so, records the refresh.
+// Expired refresh times are purged so that the map doesn't grow with (possibly forged) key IDs.
+func (r *Resolver) allowRefresh(keyID string) bool {
+	r.refreshMutex.Lock()
+	defer r.refreshMutex.Unlock()
+
+	now := time.Now()
+
+	if last, ok := r.refreshed[keyID]; ok && now.Before(last.Add(r.TTL)) {
+		return false
+	}
+
+	for k, last := range r.refreshed {
+		if !now.Before(last.Add(r.TTL)) {
+			delete(r.refreshed, k)
+		}
+	}
+
+	r.refreshed[keyID] = now
+
+	return true
+}
+
+func (r *Resolver) resolvePublicKey(keyIRI *url.URL,
+	getActor func(iri *url.URL) (*vocab.ActorType, error)) (*vocab.PublicKeyType, error) {
+	var pubKey *vocab.PublicKeyType
+
+	ownerIRI, ok := r.getOwner(keyIRI)
+	if !ok {
+		logger.Debugf("[%s] Owner of key %s is unknown. Retrieving key from remote server", r.ServiceName, keyIRI)
+
+		var err error
+
+		pubKey, err = r.client.GetPublicKey(keyIRI)
+		if err != nil {
+			return nil, err
+		}
+
+		if pubKey.Owner == nil || pubKey.Owner.URL() == nil {
+			return pubKey, nil
+		}
+
+		ownerIRI = pubKey.Owner.URL()
+	}
+
+	actor, err := getActor(ownerIRI)
+	if err != nil {
+		return nil, fmt.Errorf("resolve owner %s of public key %s: %w", ownerIRI, keyIRI, err)
+	}
+
+	if actorKey := actor.PublicKey(); actorKey != nil && actorKey.ID != nil &&
+		actorKey.ID.String() == keyIRI.String() {
+		return actorKey, nil
+	}
+
+	if pubKey != nil {
+		return pubKey, nil
+	}
+
+	logger.Debugf("[%s] Public key %s not found in actor %s. Retrieving key from remote server",
+		r.ServiceName, keyIRI, ownerIRI)
+
+	return r.client.GetPublicKey(keyIRI)
+}
+
+func (r *Resolver) fetch(actorIRI *url.URL, cached *vocab.ActorType,
+	validators *client.CacheValidators) (*vocab.ActorType, error) {
+	actor, newValidators, err := r.client.GetActorIfModified(actorIRI, validators)
+	if err != nil {
+		if cached != nil && errors.Is(err, client.ErrNotModified) {
+			logger.Debugf("[%s] Actor %s was not modified", r.ServiceName, actorIRI)
+
+			r.setEntry(cached, validators)
+
+			return cached, nil
+		}
+
+		return nil, err
+	}
+
+	// Add the actor to the local store so that we don't have to retrieve it next time.
+	if err := r.store.PutActor(actor); err != nil {
+		logger.Warnf("[%s] Unable to store actor %s: %s", r.ServiceName, actorIRI, err)
+	}
+
+	r.setEntry(actor, newValidators)
+
+	return actor, nil
+}
+
+func (r *Resolver) getOrAddEntry(actor *vocab.ActorType) *cacheEntry {
+	r.mutex.RLock()
+	entry, ok := r.entries[actor.ID().String()]
+	r.mutex.RUnlock()
+
+	if ok {
+		return entry
+	}
+
+	return r.setEntry(actor, nil)
+}
+
+func (r *Resolver) setEntry(actor *vocab.ActorType, validators *client.CacheValidators) *cacheEntry {
+	entry := &cacheEntry{
+		validators: validators,
+		expiry:     time.Now().Add(r.TTL),
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries[actor.ID().String()] = entry
+
+	if pubKey := actor.PublicKey(); pubKey != nil && pubKey.ID != nil {
+		r.owners[pubKey.ID.String()] = actor.ID().URL()
+	}
+
+	return entry
+}
+
+func (r *Resolver) getOwner(keyIRI fmt.Stringer) (*url.URL, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ownerIRI, ok := r.owners[keyIRI.String()]
+
+	return ownerIRI, ok
+}
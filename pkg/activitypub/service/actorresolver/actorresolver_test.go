@@ -0,0 +1,384 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package actorresolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
+	"github.com/trustbloc/orb/pkg/activitypub/mocks"
+	servicemocks "github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/aptestutil"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+const etag = "etag1"
+
+var (
+	service1IRI = testutil.MustParseURL("https://example1.com/services/orb")
+	service1Key = testutil.NewMockID(service1IRI, "/keys/main-key")
+)
+
+func TestNew(t *testing.T) {
+	r := New(&Config{ServiceName: "service1"}, memstore.New("service1"), &mocks.HTTPTransport{})
+	require.NotNil(t, r)
+	require.Equal(t, defaultTTL, r.TTL)
+}
+
+func TestResolver_GetActor(t *testing.T) {
+	actor := aptestutil.NewMockService(service1IRI)
+
+	actorBytes, err := json.Marshal(actor)
+	require.NoError(t, err)
+
+	t.Run("Not in store -> retrieve from remote server", func(t *testing.T) {
+		s := memstore.New("service1")
+
+		httpTransport := &mocks.HTTPTransport{}
+		httpTransport.GetStub = newResponseStub(http.StatusOK, actorBytes)
+
+		r := New(&Config{ServiceName: "service1"}, s, httpTransport)
+
+		a, err := r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+		require.Equal(t, service1IRI.String(), a.ID().String())
+		require.Equal(t, 1, httpTransport.GetCallCount())
+
+		a, err = s.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+
+		// The second call should be served from the local store.
+		a, err = r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+		require.Equal(t, 1, httpTransport.GetCallCount())
+	})
+
+	t.Run("Not in store -> remote server error", func(t *testing.T) {
+		httpTransport := &mocks.HTTPTransport{}
+		httpTransport.GetStub = newResponseStub(http.StatusInternalServerError, nil)
+
+		r := New(&Config{ServiceName: "service1"}, memstore.New("service1"), httpTransport)
+
+		a, err := r.GetActor(service1IRI)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status code 500")
+		require.Nil(t, a)
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		errExpected := errors.New("injected store error")
+
+		s := &servicemocks.ActivityStore{}
+		s.GetActorReturns(nil, errExpected)
+
+		r := New(&Config{ServiceName: "service1"}, s, &mocks.HTTPTransport{})
+
+		a, err := r.GetActor(service1IRI)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, errExpected))
+		require.Nil(t, a)
+	})
+
+	t.Run("Expired -> not modified", func(t *testing.T) {
+		s := memstore.New("service1")
+
+		httpTransport := &mocks.HTTPTransport{}
+		httpTransport.GetStub = newResponseStub(http.StatusOK, actorBytes)
+
+		r := New(&Config{ServiceName: "service1", TTL: time.Millisecond}, s, httpTransport)
+
+		a, err := r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+
+		time.Sleep(5 * time.Millisecond)
+
+		httpTransport.GetStub = newResponseStub(http.StatusNotModified, nil)
+
+		a, err = r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+		require.Equal(t, 2, httpTransport.GetCallCount())
+
+		_, req := httpTransport.GetArgsForCall(1)
+		require.Equal(t, etag, req.Header.Get("If-None-Match"))
+	})
+
+	t.Run("Expired -> modified", func(t *testing.T) {
+		s := memstore.New("service1")
+
+		httpTransport := &mocks.HTTPTransport{}
+		httpTransport.GetStub = newResponseStub(http.StatusOK, actorBytes)
+
+		r := New(&Config{ServiceName: "service1", TTL: time.Millisecond}, s, httpTransport)
+
+		a, err := r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+
+		time.Sleep(5 * time.Millisecond)
+
+		newInbox := testutil.MustParseURL("https://example1.com/services/orb/new-inbox")
+
+		updatedActorBytes, err := json.Marshal(vocab.NewService(service1IRI,
+			vocab.WithPublicKey(actor.PublicKey()),
+			vocab.WithInbox(newInbox),
+		))
+		require.NoError(t, err)
+
+		httpTransport.GetStub = newResponseStub(http.StatusOK, updatedActorBytes)
+
+		a, err = r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+		require.Equal(t, newInbox.String(), a.Inbox().String())
+
+		a, err = s.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.Equal(t, newInbox.String(), a.Inbox().String())
+	})
+
+	t.Run("Expired -> remote server error", func(t *testing.T) {
+		s := memstore.New("service1")
+
+		httpTransport := &mocks.HTTPTransport{}
+		httpTransport.GetStub = newResponseStub(http.StatusOK, actorBytes)
+
+		r := New(&Config{ServiceName: "service1", TTL: time.Millisecond}, s, httpTransport)
+
+		a, err := r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+
+		time.Sleep(5 * time.Millisecond)
+
+		httpTransport.GetStub = newResponseStub(http.StatusInternalServerError, nil)
+
+		// The actor from the local store should be returned.
+		a, err = r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.NotNil(t, a)
+		require.Equal(t, service1IRI.String(), a.ID().String())
+	})
+}
+
+func TestResolver_RefreshActor(t *testing.T) {
+	actorBytes, err := json.Marshal(aptestutil.NewMockService(service1IRI))
+	require.NoError(t, err)
+
+	s := memstore.New("service1")
+
+	httpTransport := &mocks.HTTPTransport{}
+	httpTransport.GetStub = newResponseStub(http.StatusOK, actorBytes)
+
+	r := New(&Config{ServiceName: "service1"}, s, httpTransport)
+
+	a, err := r.GetActor(service1IRI)
+	require.NoError(t, err)
+	require.NotNil(t, a)
+	require.Equal(t, 1, httpTransport.GetCallCount())
+
+	a, err = r.RefreshActor(service1IRI)
+	require.NoError(t, err)
+	require.NotNil(t, a)
+	require.Equal(t, 2, httpTransport.GetCallCount())
+
+	_, req := httpTransport.GetArgsForCall(1)
+	require.Empty(t, req.Header.Get("If-None-Match"))
+}
+
+func TestResolver_UpdateActor(t *testing.T) {
+	newInbox := testutil.MustParseURL("https://example1.com/services/orb/new-inbox")
+
+	t.Run("Success", func(t *testing.T) {
+		s := memstore.New("service1")
+
+		httpTransport := &mocks.HTTPTransport{}
+
+		r := New(&Config{ServiceName: "service1"}, s, httpTransport)
+
+		require.NoError(t, r.UpdateActor(vocab.NewService(service1IRI, vocab.WithInbox(newInbox))))
+
+		a, err := r.GetActor(service1IRI)
+		require.NoError(t, err)
+		require.Equal(t, newInbox.String(), a.Inbox().String())
+		require.Zero(t, httpTransport.GetCallCount())
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		errExpected := errors.New("injected store error")
+
+		s := &servicemocks.ActivityStore{}
+		s.PutActorReturns(errExpected)
+
+		r := New(&Config{ServiceName: "service1"}, s, &mocks.HTTPTransport{})
+
+		err := r.UpdateActor(vocab.NewService(service1IRI))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, errExpected))
+	})
+}
+
+func TestResolver_GetPublicKey(t *testing.T) {
+	actor := aptestutil.NewMockService(service1IRI)
+
+	actorBytes, err := json.Marshal(actor)
+	require.NoError(t, err)
+
+	pubKeyBytes, err := json.Marshal(actor.PublicKey())
+	require.NoError(t, err)
+
+	t.Run("Owner unknown -> retrieve key from remote server", func(t *testing.T) {
+		httpTransport := &mocks.HTTPTransport{}
+		httpTransport.GetStub = newURLResponseStub(map[string][]byte{
+			service1IRI.String(): actorBytes,
+			service1Key.String(): pubKeyBytes,
+		})
+
+		r := New(&Config{ServiceName: "service1"}, memstore.New("service1"), httpTransport)
+
+		pubKey, err := r.GetPublicKey(service1Key)
+		require.NoError(t, err)
+		require.NotNil(t, pubKey)
+		require.Equal(t, service1Key.String(), pubKey.ID.String())
+		require.Equal(t, 2, httpTransport.GetCallCount())
+
+		// The owner is now known so the key should be resolved from the cached actor.
+		pubKey, err = r.GetPublicKey(service1Key)
+		require.NoError(t, err)
+		require.NotNil(t, pubKey)
+		require.Equal(t, 2, httpTransport.GetCallCount())
+	})
+
+	t.Run("Key not in actor -> retrieve key from remote server", func(t *testing.T) {
+		otherKeyIRI := testutil.NewMockID(service1IRI, "/keys/other-key")
+
+		otherKeyBytes, err := json.Marshal(vocab.NewPublicKey(
+			vocab.WithID(otherKeyIRI),
+			vocab.WithOwner(service1IRI),
+			vocab.WithPublicKeyPem("-----BEGIN PUBLIC KEY-----"),
+		))
+		require.NoError(t, err)
+
+		httpTransport := &mocks.HTTPTransport{}
+		httpTransport.GetStub = newURLResponseStub(map[string][]byte{
+			service1IRI.String(): actorBytes,
+			otherKeyIRI.String(): otherKeyBytes,
+		})
+
+		r := New(&Config{ServiceName: "service1"}, memstore.New("service1"), httpTransport)
+
+		pubKey, err := r.GetPublicKey(otherKeyIRI)
+		require.NoError(t, err)
+		require.NotNil(t, pubKey)
+		require.Equal(t, otherKeyIRI.String(), pubKey.ID.String())
+	})
+
+	t.Run("Remote server error", func(t *testing.T) {
+		httpTransport := &mocks.HTTPTransport{}
+		httpTransport.GetStub = newResponseStub(http.StatusInternalServerError, nil)
+
+		r := New(&Config{ServiceName: "service1"}, memstore.New("service1"), httpTransport)
+
+		pubKey, err := r.GetPublicKey(service1Key)
+		require.Error(t, err)
+		require.Nil(t, pubKey)
+	})
+}
+
+func TestResolver_RefreshPublicKey(t *testing.T) {
+	actor := aptestutil.NewMockService(service1IRI)
+
+	actorBytes, err := json.Marshal(actor)
+	require.NoError(t, err)
+
+	httpTransport := &mocks.HTTPTransport{}
+	httpTransport.GetStub = newResponseStub(http.StatusOK, actorBytes)
+
+	r := New(&Config{ServiceName: "service1"}, memstore.New("service1"), httpTransport)
+
+	// Load the actor so that the owner of the key is known.
+	_, err = r.GetActor(service1IRI)
+	require.NoError(t, err)
+	require.Equal(t, 1, httpTransport.GetCallCount())
+
+	pubKey, err := r.RefreshPublicKey(service1Key)
+	require.NoError(t, err)
+	require.NotNil(t, pubKey)
+	require.Equal(t, service1Key.String(), pubKey.ID.String())
+	require.Equal(t, 2, httpTransport.GetCallCount())
+
+	t.Run("Refreshed within TTL", func(t *testing.T) {
+		pubKey, err := r.RefreshPublicKey(service1Key)
+		require.True(t, errors.Is(err, ErrRefreshLimited))
+		require.Nil(t, pubKey)
+		require.Equal(t, 2, httpTransport.GetCallCount())
+	})
+
+	t.Run("TTL expired", func(t *testing.T) {
+		r := New(&Config{ServiceName: "service1", TTL: time.Millisecond}, memstore.New("service1"), httpTransport)
+
+		_, err := r.RefreshPublicKey(service1Key)
+		require.NoError(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = r.RefreshPublicKey(service1Key)
+		require.NoError(t, err)
+		require.Len(t, r.refreshed, 1)
+	})
+}
+
+type getFunc func(ctx context.Context, req *transport.Request) (*http.Response, error)
+
+func newResponseStub(statusCode int, body []byte) getFunc {
+	return func(ctx context.Context, req *transport.Request) (*http.Response, error) {
+		return newResponse(statusCode, body), nil
+	}
+}
+
+func newURLResponseStub(responses map[string][]byte) getFunc {
+	return func(ctx context.Context, req *transport.Request) (*http.Response, error) {
+		body, ok := responses[req.URL.String()]
+		if !ok {
+			return newResponse(http.StatusNotFound, nil), nil
+		}
+
+		return newResponse(http.StatusOK, body), nil
+	}
+}
+
+func newResponse(statusCode int, body []byte) *http.Response {
+	rw := httptest.NewRecorder()
+
+	if statusCode == http.StatusOK {
+		rw.Header().Set("ETag", etag)
+	}
+
+	rw.WriteHeader(statusCode)
+
+	if body != nil {
+		if _, err := rw.Write(body); err != nil {
+			panic(err)
+		}
+	}
+
+	return rw.Result()
+}
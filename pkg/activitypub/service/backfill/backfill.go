@@ -0,0 +1,677 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client"
+	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
+	service "github.com/trustbloc/orb/pkg/activitypub/service/spi"
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+var logger = log.New("activitypub_service")
+
+const (
+	storeName   = "backfill"
+	backfillTag = "Backfill"
+
+	defaultRetryInterval      = 5 * time.Minute
+	defaultCheckpointInterval = 10
+)
+
+// ErrNotFollowing is returned when a backfill is requested for an actor that the local service doesn't follow.
+var ErrNotFollowing = errors.New("not following actor")
+
+// Config holds the configuration for the backfiller.
+type Config struct {
+	// RetryInterval is the interval at which incomplete backfill jobs (for example, jobs that failed due to
+	// a network error or that were interrupted by a restart) are resumed.
+	RetryInterval time.Duration
+
+	// CheckpointInterval is the number of activities that are processed between saving the progress of a job.
+	CheckpointInterval int
+}
+
+// Progress contains the progress of a backfill job for a followed service.
+type Progress struct {
+	ActorIRI  string `json:"actorIRI"`
+	OutboxIRI string `json:"outboxIRI,omitempty"`
+
+	// Processed is the number of activities in the outbox (starting from the oldest) that were processed.
+	Processed int `json:"processed"`
+
+	// LastActivityID is the ID of the last activity that was processed. It is used to detect that the
+	// outbox has changed (for example, older activities were pruned) since the job was interrupted.
+	LastActivityID string `json:"lastActivityID,omitempty"`
+
+	// Handled is the number of anchor credentials that were passed to the anchor credential handler.
+	Handled int `json:"handled"`
+
+	Completed bool      `json:"completed"`
+	Updated   time.Time `json:"updated"`
+}
+
+type activityClient interface {
+	GetActor(actorIRI *url.URL) (*vocab.ActorType, error)
+	GetActivities(iri *url.URL, order client.Order) (client.ActivityIterator, error)
+}
+
+// Backfiller reads the outbox of a followed service, starting with the oldest activity, and passes
+// the anchor credentials in the 'Create' and 'Announce' activities to the anchor credential handler. This
+// allows the local service to catch up on anchors that were published before the 'Follow' was accepted.
+// The progress of each job is persisted so that an interrupted job resumes where it left off.
+type Backfiller struct {
+	*Config
+	*lifecycle.Lifecycle
+
+	serviceIRI        *url.URL
+	activityStore     store.Store
+	client            activityClient
+	anchorCredHandler service.AnchorCredentialHandler
+	progressStore     ariesstorage.Store
+	jobMutex          sync.Mutex
+	progressMutex     sync.Mutex
+	wake              chan struct{}
+	done              chan struct{}
+	now               func() time.Time
+}
+
+// New returns a new backfiller.
+func New(cfg *Config, serviceIRI *url.URL, activityStore store.Store, c activityClient,
+	anchorCredHandler service.AnchorCredentialHandler, provider ariesstorage.Provider) (*Backfiller, error) {
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+
+	if cfg.CheckpointInterval == 0 {
+		cfg.CheckpointInterval = defaultCheckpointInterval
+	}
+
+	progressStore, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("open store [%s]: %w", storeName, err)
+	}
+
+	err = provider.SetStoreConfig(storeName, ariesstorage.StoreConfiguration{TagNames: []string{backfillTag}})
+	if err != nil {
+		return nil, fmt.Errorf("set store configuration for [%s]: %w", storeName, err)
+	}
+
+	b := &Backfiller{
+		Config:            cfg,
+		serviceIRI:        serviceIRI,
+		activityStore:     activityStore,
+		client:            c,
+		anchorCredHandler: anchorCredHandler,
+		progressStore:     progressStore,
+		wake:              make(chan struct{}, 1),
+		done:              make(chan struct{}),
+		now:               time.Now,
+	}
+
+	b.Lifecycle = lifecycle.New("backfiller",
+		lifecycle.WithStart(b.start),
+		lifecycle.WithStop(b.stop),
+	)
+
+	return b, nil
+}
+
+// Listen schedules a backfill job whenever an 'Accept' of a 'Follow' is received on the given channel.
+// The listener exits when the channel is closed.
+func (b *Backfiller) Listen(activities <-chan *vocab.ActivityType) {
+	go func() {
+		for activity := range activities {
+			if !isAcceptFollow(activity) {
+				continue
+			}
+
+			logger.Infof("Follow was accepted by [%s]. Scheduling backfill job.", activity.Actor())
+
+			if err := b.Schedule(activity.Actor()); err != nil {
+				logger.Errorf("Error scheduling backfill job for [%s]: %s", activity.Actor(), err)
+			}
+		}
+
+		logger.Debugf("Backfill listener stopped")
+	}()
+}
+
+// Schedule schedules a backfill job for the given actor. The job runs in the background. If a previous
+// job for the actor completed then the new job resumes from where the previous job left off. ErrNotFollowing
+// is returned if the local service doesn't follow the actor.
+func (b *Backfiller) Schedule(actorIRI *url.URL) error {
+	if err := b.ensureFollowing(actorIRI); err != nil {
+		return err
+	}
+
+	if err := b.markIncomplete(actorIRI); err != nil {
+		return err
+	}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+		// The worker has already been signaled.
+	}
+
+	return nil
+}
+
+// Backfill runs a backfill job for the given actor and returns its progress. If a previous job for the
+// actor completed then the job resumes from where the previous job left off. ErrNotFollowing is returned
+// if the local service doesn't follow the actor.
+func (b *Backfiller) Backfill(actorIRI *url.URL) (*Progress, error) {
+	b.jobMutex.Lock()
+	defer b.jobMutex.Unlock()
+
+	if err := b.ensureFollowing(actorIRI); err != nil {
+		return nil, err
+	}
+
+	p, err := b.getProgress(actorIRI)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Completed = false
+
+	if err := b.backfill(p); err != nil {
+		return p, fmt.Errorf("backfill from [%s]: %w", actorIRI, err)
+	}
+
+	return p, nil
+}
+
+// Progress returns the progress of the backfill job for the given actor. ErrDataNotFound is returned
+// if no job was ever scheduled for the actor.
+func (b *Backfiller) Progress(actorIRI *url.URL) (*Progress, error) {
+	progressBytes, err := b.progressStore.Get(actorIRI.String())
+	if err != nil {
+		return nil, fmt.Errorf("get progress for [%s]: %w", actorIRI, err)
+	}
+
+	p := &Progress{}
+
+	if err := json.Unmarshal(progressBytes, p); err != nil {
+		return nil, fmt.Errorf("unmarshal progress for [%s]: %w", actorIRI, err)
+	}
+
+	return p, nil
+}
+
+func (b *Backfiller) start() {
+	go b.run()
+}
+
+func (b *Backfiller) stop() {
+	close(b.done)
+}
+
+func (b *Backfiller) run() {
+	ticker := time.NewTicker(b.RetryInterval)
+	defer ticker.Stop()
+
+	// Resume any jobs that were interrupted by a restart.
+	b.resumeIncomplete()
+
+	for {
+		select {
+		case <-b.wake:
+			b.resumeIncomplete()
+		case <-ticker.C:
+			b.resumeIncomplete()
+		case <-b.done:
+			logger.Debugf("Stopped backfiller")
+
+			return
+		}
+	}
+}
+
+func (b *Backfiller) resumeIncomplete() {
+	actorIRIs, err := b.getIncomplete()
+	if err != nil {
+		logger.Errorf("Error querying incomplete backfill jobs: %s", err)
+
+		return
+	}
+
+	for _, actorIRI := range actorIRIs {
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+
+		p, err := b.Backfill(actorIRI)
+		if err != nil {
+			if errors.Is(err, ErrNotFollowing) {
+				b.cancel(actorIRI)
+
+				continue
+			}
+
+			logger.Warnf("Backfill job for [%s] is incomplete and will be retried: %s", actorIRI, err)
+
+			continue
+		}
+
+		logger.Infof("Backfill job for [%s] completed. Activities processed: %d, anchor credentials handled: %d",
+			actorIRI, p.Processed, p.Handled)
+	}
+}
+
+func (b *Backfiller) backfill(p *Progress) error {
+	outboxIRI, err := b.resolveOutbox(p)
+	if err != nil {
+		return err
+	}
+
+	// The outbox is sorted by most recent first so it's read in reverse order in order to process
+	// the oldest activities first.
+	it, err := b.client.GetActivities(outboxIRI, client.Reverse)
+	if err != nil {
+		return fmt.Errorf("get activities from outbox [%s]: %w", outboxIRI, err)
+	}
+
+	resumeFrom := p.Processed
+
+	n := 0
+
+	for ; ; n++ {
+		activity, err := it.Next()
+		if err != nil {
+			if errors.Is(err, client.ErrNotFound) {
+				break
+			}
+
+			return b.checkpoint(p, fmt.Errorf("get next activity from outbox [%s]: %w", outboxIRI, err))
+		}
+
+		if n < resumeFrom {
+			if n == resumeFrom-1 && activity.ID().String() != p.LastActivityID {
+				return b.restart(p, outboxIRI)
+			}
+
+			continue
+		}
+
+		handled, err := b.handleActivity(activity)
+		if err != nil {
+			return b.checkpoint(p, fmt.Errorf("handle activity [%s]: %w", activity.ID(), err))
+		}
+
+		p.Processed = n + 1
+		p.LastActivityID = activity.ID().String()
+		p.Handled += handled
+
+		if p.Processed%b.CheckpointInterval == 0 {
+			if err := b.saveProgress(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if n < resumeFrom {
+		return b.restart(p, outboxIRI)
+	}
+
+	p.Completed = true
+
+	return b.saveProgress(p)
+}
+
+// restart restarts the job from the oldest activity in the outbox. This is done if the outbox has changed
+// such that the activities that were already processed can no longer be skipped. Anchor credentials that
+// were already handled are ignored.
+func (b *Backfiller) restart(p *Progress, outboxIRI *url.URL) error {
+	logger.Infof("Outbox [%s] has changed since the backfill job was interrupted. Restarting job.", outboxIRI)
+
+	p.Processed = 0
+	p.LastActivityID = ""
+
+	return b.backfill(p)
+}
+
+// checkpoint saves the given progress and returns the given error.
+func (b *Backfiller) checkpoint(p *Progress, err error) error {
+	if e := b.saveProgress(p); e != nil {
+		logger.Errorf("Error saving backfill progress for [%s]: %s", p.ActorIRI, e)
+	}
+
+	return err
+}
+
+func (b *Backfiller) resolveOutbox(p *Progress) (*url.URL, error) {
+	if p.OutboxIRI != "" {
+		return url.Parse(p.OutboxIRI)
+	}
+
+	actorIRI, err := url.Parse(p.ActorIRI)
+	if err != nil {
+		return nil, fmt.Errorf("parse actor IRI [%s]: %w", p.ActorIRI, err)
+	}
+
+	actor, err := b.client.GetActor(actorIRI)
+	if err != nil {
+		return nil, fmt.Errorf("get actor [%s]: %w", actorIRI, err)
+	}
+
+	if actor.Outbox() == nil {
+		return nil, fmt.Errorf("actor [%s] has no outbox", actorIRI)
+	}
+
+	p.OutboxIRI = actor.Outbox().String()
+
+	return actor.Outbox(), nil
+}
+
+// handleActivity passes the anchor credentials in the given 'Create' or 'Announce' activity to the anchor
+// credential handler and returns the number of anchor credentials that were handled. Other activity types
+// are ignored.
+func (b *Backfiller) handleActivity(activity *vocab.ActivityType) (int, error) {
+	obj := activity.Object()
+	if obj == nil {
+		return 0, nil
+	}
+
+	switch {
+	case activity.Type().Is(vocab.TypeCreate):
+		t := obj.Type()
+
+		switch {
+		case t.Is(vocab.TypeAnchorCredential, vocab.TypeVerifiableCredential):
+			return b.handleAnchorCredential(activity.Target(), obj.Object())
+
+		case t.Is(vocab.TypeAnchorCredentialRef):
+			return b.handleAnchorCredentialRef(obj.AnchorCredentialReference())
+
+		default:
+			return 0, nil
+		}
+
+	case activity.Type().Is(vocab.TypeAnnounce):
+		if obj.AnchorCredentialReference() != nil {
+			return b.handleAnchorCredentialRef(obj.AnchorCredentialReference())
+		}
+
+		var items []*vocab.ObjectProperty
+
+		switch {
+		case obj.Collection() != nil:
+			items = obj.Collection().Items()
+		case obj.OrderedCollection() != nil:
+			items = obj.OrderedCollection().Items()
+		}
+
+		total := 0
+
+		for _, item := range items {
+			handled, err := b.handleAnchorCredentialRef(item.AnchorCredentialReference())
+			if err != nil {
+				return total, err
+			}
+
+			total += handled
+		}
+
+		return total, nil
+
+	default:
+		return 0, nil
+	}
+}
+
+func (b *Backfiller) handleAnchorCredentialRef(ref *vocab.AnchorCredentialReferenceType) (int, error) {
+	if ref == nil || ref.Object() == nil {
+		return 0, nil
+	}
+
+	return b.handleAnchorCredential(ref.Target(), ref.Object().Object())
+}
+
+func (b *Backfiller) handleAnchorCredential(target *vocab.ObjectProperty, obj *vocab.ObjectType) (int, error) {
+	if target == nil || obj == nil || target.Object() == nil || target.Object().ID() == nil {
+		return 0, nil
+	}
+
+	if !target.Type().Is(vocab.TypeContentAddressedStorage) {
+		logger.Warnf("Ignoring anchor credential with unsupported target type %s", target.Type())
+
+		return 0, nil
+	}
+
+	targetIRI := target.Object().ID().URL()
+
+	exists, err := b.hasAnchorCredential(targetIRI)
+	if err != nil {
+		return 0, err
+	}
+
+	if exists {
+		logger.Debugf("Ignoring duplicate anchor credential [%s]", targetIRI)
+
+		return 0, nil
+	}
+
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return 0, fmt.Errorf("marshal anchor credential [%s]: %w", targetIRI, err)
+	}
+
+	err = b.anchorCredHandler.HandleAnchorCredential(targetIRI, target.Object().CID(), bytes)
+	if err != nil {
+		return 0, fmt.Errorf("handle anchor credential [%s]: %w", targetIRI, err)
+	}
+
+	logger.Debugf("Storing anchor credential reference [%s]", targetIRI)
+
+	err = b.activityStore.AddReference(store.AnchorCredential, targetIRI, b.serviceIRI)
+	if err != nil {
+		return 0, fmt.Errorf("store anchor credential reference: %w", err)
+	}
+
+	return 1, nil
+}
+
+// ensureFollowing returns ErrNotFollowing if the given actor isn't in the 'following' collection of the
+// local service. Only the anchor credentials of followed services are trusted.
+func (b *Backfiller) ensureFollowing(actorIRI *url.URL) error {
+	it, err := b.activityStore.QueryReferences(store.Following,
+		store.NewCriteria(store.WithObjectIRI(b.serviceIRI), store.WithReferenceIRI(actorIRI)),
+	)
+	if err != nil {
+		return fmt.Errorf("query following [%s]: %w", actorIRI, err)
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warnf("Error closing iterator: %s", e)
+		}
+	}()
+
+	_, err = it.Next()
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrNotFollowing, actorIRI)
+		}
+
+		return fmt.Errorf("get next following reference: %w", err)
+	}
+
+	return nil
+}
+
+// cancel removes the job for the given actor, for example if the actor was unfollowed after the job was scheduled.
+func (b *Backfiller) cancel(actorIRI *url.URL) {
+	b.progressMutex.Lock()
+	defer b.progressMutex.Unlock()
+
+	logger.Infof("Cancelling backfill job for [%s] since the actor is no longer followed", actorIRI)
+
+	if err := b.progressStore.Delete(actorIRI.String()); err != nil {
+		logger.Warnf("Error deleting backfill job for [%s]: %s", actorIRI, err)
+	}
+}
+
+func (b *Backfiller) hasAnchorCredential(targetIRI *url.URL) (bool, error) {
+	it, err := b.activityStore.QueryReferences(store.AnchorCredential,
+		store.NewCriteria(store.WithObjectIRI(targetIRI), store.WithReferenceIRI(b.serviceIRI)),
+	)
+	if err != nil {
+		return false, fmt.Errorf("query anchor credential [%s]: %w", targetIRI, err)
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warnf("Error closing iterator: %s", e)
+		}
+	}()
+
+	_, err = it.Next()
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("get next anchor credential reference: %w", err)
+	}
+
+	return true, nil
+}
+
+func (b *Backfiller) getProgress(actorIRI *url.URL) (*Progress, error) {
+	p, err := b.Progress(actorIRI)
+	if err != nil {
+		if errors.Is(err, ariesstorage.ErrDataNotFound) {
+			return &Progress{ActorIRI: actorIRI.String()}, nil
+		}
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// markIncomplete marks the job for the given actor as incomplete (or creates a new job) so that
+// it's picked up by the worker.
+func (b *Backfiller) markIncomplete(actorIRI *url.URL) error {
+	b.progressMutex.Lock()
+	defer b.progressMutex.Unlock()
+
+	p, err := b.getProgress(actorIRI)
+	if err != nil {
+		return err
+	}
+
+	if p.Updated.IsZero() || p.Completed {
+		p.Completed = false
+
+		return b.putProgress(p)
+	}
+
+	return nil
+}
+
+func (b *Backfiller) saveProgress(p *Progress) error {
+	b.progressMutex.Lock()
+	defer b.progressMutex.Unlock()
+
+	return b.putProgress(p)
+}
+
+func (b *Backfiller) putProgress(p *Progress) error {
+	p.Updated = b.now()
+
+	progressBytes, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal progress: %w", err)
+	}
+
+	tags := []ariesstorage.Tag{{Name: backfillTag}}
+
+	if err := b.progressStore.Put(p.ActorIRI, progressBytes, tags...); err != nil {
+		return fmt.Errorf("store progress for [%s]: %w", p.ActorIRI, err)
+	}
+
+	return nil
+}
+
+func (b *Backfiller) getIncomplete() ([]*url.URL, error) {
+	it, err := b.progressStore.Query(backfillTag)
+	if err != nil {
+		return nil, fmt.Errorf("query backfill jobs: %w", err)
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warnf("Error closing iterator: %s", e)
+		}
+	}()
+
+	var actorIRIs []*url.URL
+
+	for {
+		ok, err := it.Next()
+		if err != nil {
+			return nil, fmt.Errorf("next backfill job: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		value, err := it.Value()
+		if err != nil {
+			return nil, fmt.Errorf("get value: %w", err)
+		}
+
+		p := &Progress{}
+		if err := json.Unmarshal(value, p); err != nil {
+			logger.Warnf("Invalid backfill progress: %s", err)
+
+			continue
+		}
+
+		if p.Completed {
+			continue
+		}
+
+		actorIRI, err := url.Parse(p.ActorIRI)
+		if err != nil {
+			logger.Warnf("Invalid actor IRI in backfill progress [%s]: %s", p.ActorIRI, err)
+
+			continue
+		}
+
+		actorIRIs = append(actorIRIs, actorIRI)
+	}
+
+	return actorIRIs, nil
+}
+
+func isAcceptFollow(activity *vocab.ActivityType) bool {
+	if !activity.Type().Is(vocab.TypeAccept) || activity.Actor() == nil {
+		return false
+	}
+
+	obj := activity.Object()
+
+	return obj != nil && obj.Activity() != nil && obj.Activity().Type().Is(vocab.TypeFollow)
+}
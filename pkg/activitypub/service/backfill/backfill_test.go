@@ -0,0 +1,550 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backfill
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/aptestutil"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+const cid = "bafkreihwsnuregceqh263vgdathcprnbvatyat6h6mu7ipjhhodcdbyhoy"
+
+var (
+	serviceIRI = testutil.MustParseURL("https://example.com/services/orb")
+	service2   = testutil.MustParseURL("https://domain2.com/services/orb")
+	outboxIRI  = testutil.NewMockID(service2, "/outbox")
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		b, err := New(&Config{}, serviceIRI, memstore.New(""), newMockClient(), newMockHandler(), mem.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, b)
+		require.Equal(t, defaultRetryInterval, b.RetryInterval)
+		require.Equal(t, defaultCheckpointInterval, b.CheckpointInterval)
+	})
+
+	t.Run("Open store error", func(t *testing.T) {
+		p := mockstore.NewMockStoreProvider()
+		p.ErrOpenStoreHandle = errors.New("injected open error")
+
+		_, err := New(&Config{}, serviceIRI, memstore.New(""), newMockClient(), newMockHandler(), p)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected open error")
+	})
+
+	t.Run("Set store config error", func(t *testing.T) {
+		p := mockstore.NewMockStoreProvider()
+		p.FailNamespace = storeName
+
+		_, err := New(&Config{}, serviceIRI, memstore.New(""), newMockClient(), newMockHandler(), p)
+		require.Error(t, err)
+	})
+}
+
+func TestBackfiller_Backfill(t *testing.T) {
+	activityStore := newActivityStore(t)
+	handler := newMockHandler()
+
+	targets := newTargetIDs(3)
+
+	activities := []*vocab.ActivityType{
+		newCreate(t, targets[0]),
+		vocab.NewFollowActivity(vocab.NewObjectProperty(vocab.WithIRI(serviceIRI)),
+			vocab.WithID(testutil.NewMockID(service2, "/activities/follow")),
+		),
+		newAnnounce(t, targets[0], targets[1]),
+		newCreateRef(t, targets[2]),
+	}
+
+	c := newMockClient().withOutbox(activities...)
+
+	b, err := New(&Config{}, serviceIRI, activityStore, c, handler, mem.NewProvider())
+	require.NoError(t, err)
+
+	p, err := b.Backfill(service2)
+	require.NoError(t, err)
+	require.True(t, p.Completed)
+	require.Equal(t, len(activities), p.Processed)
+	require.Equal(t, 3, p.Handled)
+	require.Equal(t, outboxIRI.String(), p.OutboxIRI)
+	require.Equal(t, activities[3].ID().String(), p.LastActivityID)
+
+	for _, target := range targets {
+		require.Equal(t, 1, handler.count(target))
+		require.True(t, hasAnchorCredentialRef(t, activityStore, target))
+	}
+
+	t.Run("Progress", func(t *testing.T) {
+		progress, err := b.Progress(service2)
+		require.NoError(t, err)
+		require.Equal(t, p.Processed, progress.Processed)
+		require.True(t, progress.Completed)
+
+		_, err = b.Progress(testutil.MustParseURL("https://domain3.com/services/orb"))
+		require.True(t, errors.Is(err, ariesstorage.ErrDataNotFound))
+	})
+
+	t.Run("Resume after new activities are published", func(t *testing.T) {
+		target := testutil.NewMockID(service2, "/cas/new")
+
+		c.withOutbox(append(activities, newCreate(t, target))...)
+
+		p, err := b.Backfill(service2)
+		require.NoError(t, err)
+		require.True(t, p.Completed)
+		require.Equal(t, len(activities)+1, p.Processed)
+		require.Equal(t, 4, p.Handled)
+		require.Equal(t, 1, handler.count(target))
+
+		// The previous activities were not handled again.
+		require.Equal(t, 1, handler.count(targets[0]))
+		require.Equal(t, 1, c.getActorCount())
+	})
+}
+
+func TestBackfiller_Resume(t *testing.T) {
+	activityStore := newActivityStore(t)
+	handler := newMockHandler()
+	targets := newTargetIDs(5)
+
+	var activities []*vocab.ActivityType
+
+	for _, target := range targets {
+		activities = append(activities, newCreate(t, target))
+	}
+
+	c := newMockClient().withOutbox(activities...)
+	c.failAt = 3
+
+	b, err := New(&Config{CheckpointInterval: 2}, serviceIRI, activityStore, c, handler, mem.NewProvider())
+	require.NoError(t, err)
+
+	_, err = b.Backfill(service2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "injected iterator error")
+
+	p, err := b.Progress(service2)
+	require.NoError(t, err)
+	require.False(t, p.Completed)
+	require.Equal(t, 3, p.Processed)
+
+	c.failAt = -1
+
+	p, err = b.Backfill(service2)
+	require.NoError(t, err)
+	require.True(t, p.Completed)
+	require.Equal(t, 5, p.Processed)
+
+	for _, target := range targets {
+		require.Equal(t, 1, handler.count(target))
+	}
+
+	t.Run("Outbox changed -> restart", func(t *testing.T) {
+		// The oldest activity was pruned from the outbox.
+		c.withOutbox(activities[1:]...)
+
+		p.Completed = false
+		require.NoError(t, b.saveProgress(p))
+
+		p, err := b.Backfill(service2)
+		require.NoError(t, err)
+		require.True(t, p.Completed)
+		require.Equal(t, 4, p.Processed)
+
+		// Duplicate anchor credentials are ignored.
+		for _, target := range targets {
+			require.Equal(t, 1, handler.count(target))
+		}
+	})
+}
+
+func TestBackfiller_Error(t *testing.T) {
+	t.Run("Get actor error", func(t *testing.T) {
+		c := newMockClient()
+		c.actorErr = errors.New("injected actor error")
+
+		b, err := New(&Config{}, serviceIRI, newActivityStore(t), c, newMockHandler(), mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = b.Backfill(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected actor error")
+	})
+
+	t.Run("Get activities error", func(t *testing.T) {
+		c := newMockClient()
+		c.activitiesErr = errors.New("injected activities error")
+
+		b, err := New(&Config{}, serviceIRI, newActivityStore(t), c, newMockHandler(), mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = b.Backfill(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected activities error")
+	})
+
+	t.Run("Anchor credential handler error", func(t *testing.T) {
+		target := testutil.NewMockID(service2, "/cas/1")
+
+		c := newMockClient().withOutbox(newCreate(t, target))
+
+		handler := newMockHandler()
+		handler.err = errors.New("injected handler error")
+
+		b, err := New(&Config{}, serviceIRI, newActivityStore(t), c, handler, mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = b.Backfill(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected handler error")
+
+		p, err := b.Progress(service2)
+		require.NoError(t, err)
+		require.False(t, p.Completed)
+		require.Zero(t, p.Processed)
+	})
+}
+
+func TestBackfiller_Schedule(t *testing.T) {
+	activityStore := newActivityStore(t)
+	handler := newMockHandler()
+	target := testutil.NewMockID(service2, "/cas/1")
+
+	c := newMockClient().withOutbox(newCreate(t, target))
+
+	b, err := New(&Config{}, serviceIRI, activityStore, c, handler, mem.NewProvider())
+	require.NoError(t, err)
+
+	activities := make(chan *vocab.ActivityType, 10)
+	defer close(activities)
+
+	b.Listen(activities)
+
+	b.Start()
+	defer b.Stop()
+
+	follow := vocab.NewFollowActivity(vocab.NewObjectProperty(vocab.WithIRI(service2)),
+		vocab.WithID(testutil.NewMockID(serviceIRI, "/activities/follow")),
+		vocab.WithActor(serviceIRI),
+	)
+
+	// Not an 'Accept' of a 'Follow' -> ignored.
+	activities <- follow
+
+	activities <- vocab.NewAcceptActivity(vocab.NewObjectProperty(vocab.WithActivity(follow)),
+		vocab.WithID(testutil.NewMockID(service2, "/activities/accept")),
+		vocab.WithActor(service2),
+	)
+
+	require.Eventually(t, func() bool {
+		p, err := b.Progress(service2)
+
+		return err == nil && p.Completed
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, 1, handler.count(target))
+
+	t.Run("On demand", func(t *testing.T) {
+		target2 := testutil.NewMockID(service2, "/cas/2")
+
+		c.withOutbox(newCreate(t, target), newCreate(t, target2))
+
+		require.NoError(t, b.Schedule(service2))
+
+		require.Eventually(t, func() bool {
+			return handler.count(target2) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestBackfiller_NotFollowing(t *testing.T) {
+	service3 := testutil.MustParseURL("https://domain3.com/services/orb")
+
+	activityStore := newActivityStore(t)
+	handler := newMockHandler()
+	target := testutil.NewMockID(service3, "/cas/1")
+
+	c := newMockClient().withOutbox(newCreate(t, target))
+
+	b, err := New(&Config{}, serviceIRI, activityStore, c, handler, mem.NewProvider())
+	require.NoError(t, err)
+
+	t.Run("Schedule", func(t *testing.T) {
+		err := b.Schedule(service3)
+		require.True(t, errors.Is(err, ErrNotFollowing))
+
+		_, err = b.Progress(service3)
+		require.True(t, errors.Is(err, ariesstorage.ErrDataNotFound))
+	})
+
+	t.Run("Backfill", func(t *testing.T) {
+		_, err := b.Backfill(service3)
+		require.True(t, errors.Is(err, ErrNotFollowing))
+		require.Zero(t, c.getActorCount())
+		require.Zero(t, handler.count(target))
+	})
+
+	t.Run("Unfollowed after the job was scheduled -> job cancelled", func(t *testing.T) {
+		require.NoError(t, activityStore.AddReference(store.Following, serviceIRI, service3))
+		require.NoError(t, b.Schedule(service3))
+		require.NoError(t, activityStore.DeleteReference(store.Following, serviceIRI, service3))
+
+		b.resumeIncomplete()
+
+		_, err := b.Progress(service3)
+		require.True(t, errors.Is(err, ariesstorage.ErrDataNotFound))
+		require.Zero(t, c.getActorCount())
+		require.Zero(t, handler.count(target))
+	})
+}
+
+// newActivityStore returns an activity store in which the local service follows service2.
+func newActivityStore(t *testing.T) store.Store {
+	t.Helper()
+
+	s := memstore.New("")
+
+	require.NoError(t, s.AddReference(store.Following, serviceIRI, service2))
+
+	return s
+}
+
+func newTargetIDs(n int) []*url.URL {
+	targets := make([]*url.URL, n)
+
+	for i := range targets {
+		targets[i] = testutil.NewMockID(service2, fmt.Sprintf("/cas/%d", i))
+	}
+
+	return targets
+}
+
+func newCreate(t *testing.T, targetIRI *url.URL) *vocab.ActivityType {
+	t.Helper()
+
+	return vocab.NewCreateActivity(
+		vocab.NewObjectProperty(vocab.WithObject(newAnchorCredential(t))),
+		vocab.WithID(testutil.NewMockID(service2, "/activities/create"+targetIRI.Path)),
+		vocab.WithActor(service2),
+		vocab.WithTarget(vocab.NewObjectProperty(vocab.WithObject(
+			vocab.NewObject(
+				vocab.WithID(targetIRI),
+				vocab.WithCID(cid),
+				vocab.WithType(vocab.TypeContentAddressedStorage),
+			),
+		))),
+	)
+}
+
+func newCreateRef(t *testing.T, targetIRI *url.URL) *vocab.ActivityType {
+	t.Helper()
+
+	return vocab.NewCreateActivity(
+		vocab.NewObjectProperty(vocab.WithAnchorCredentialReference(newAnchorCredentialRef(t, targetIRI))),
+		vocab.WithID(testutil.NewMockID(service2, "/activities/create-ref"+targetIRI.Path)),
+		vocab.WithActor(service2),
+	)
+}
+
+func newAnnounce(t *testing.T, targetIRIs ...*url.URL) *vocab.ActivityType {
+	t.Helper()
+
+	var items []*vocab.ObjectProperty
+
+	for _, targetIRI := range targetIRIs {
+		items = append(items, vocab.NewObjectProperty(
+			vocab.WithAnchorCredentialReference(newAnchorCredentialRef(t, targetIRI)),
+		))
+	}
+
+	return vocab.NewAnnounceActivity(
+		vocab.NewObjectProperty(vocab.WithCollection(vocab.NewCollection(items))),
+		vocab.WithID(testutil.NewMockID(service2, "/activities/announce")),
+		vocab.WithActor(service2),
+	)
+}
+
+func newAnchorCredentialRef(t *testing.T, targetIRI *url.URL) *vocab.AnchorCredentialReferenceType {
+	t.Helper()
+
+	ref, err := vocab.NewAnchorCredentialReferenceWithDocument(
+		testutil.NewMockID(service2, "/transactions"+targetIRI.Path), targetIRI, cid,
+		vocab.MustUnmarshalToDoc([]byte(anchorCredential)),
+	)
+	require.NoError(t, err)
+
+	return ref
+}
+
+func newAnchorCredential(t *testing.T) *vocab.ObjectType {
+	t.Helper()
+
+	obj, err := vocab.NewObjectWithDocument(vocab.MustUnmarshalToDoc([]byte(anchorCredential)))
+	require.NoError(t, err)
+
+	return obj
+}
+
+func hasAnchorCredentialRef(t *testing.T, s store.Store, targetIRI *url.URL) bool {
+	t.Helper()
+
+	it, err := s.QueryReferences(store.AnchorCredential,
+		store.NewCriteria(store.WithObjectIRI(targetIRI), store.WithReferenceIRI(serviceIRI)))
+	require.NoError(t, err)
+
+	refs, err := storeutil.ReadReferences(it, -1)
+	require.NoError(t, err)
+
+	return len(refs) > 0
+}
+
+type mockHandler struct {
+	mutex  sync.Mutex
+	counts map[string]int
+	err    error
+}
+
+func newMockHandler() *mockHandler {
+	return &mockHandler{counts: make(map[string]int)}
+}
+
+func (m *mockHandler) HandleAnchorCredential(id *url.URL, _ string, _ []byte) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.counts[id.String()]++
+
+	return nil
+}
+
+func (m *mockHandler) count(id *url.URL) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.counts[id.String()]
+}
+
+type mockClient struct {
+	mutex         sync.Mutex
+	activities    []*vocab.ActivityType
+	failAt        int
+	actorErr      error
+	activitiesErr error
+	actorCount    int
+}
+
+func newMockClient() *mockClient {
+	return &mockClient{failAt: -1}
+}
+
+// withOutbox sets the activities in the outbox, ordered from oldest to newest.
+func (m *mockClient) withOutbox(activities ...*vocab.ActivityType) *mockClient {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.activities = activities
+
+	return m
+}
+
+func (m *mockClient) GetActor(actorIRI *url.URL) (*vocab.ActorType, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.actorErr != nil {
+		return nil, m.actorErr
+	}
+
+	m.actorCount++
+
+	return aptestutil.NewMockService(actorIRI), nil
+}
+
+func (m *mockClient) GetActivities(iri *url.URL, order client.Order) (client.ActivityIterator, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.activitiesErr != nil {
+		return nil, m.activitiesErr
+	}
+
+	if iri.String() != outboxIRI.String() || order != client.Reverse {
+		return nil, fmt.Errorf("unexpected request for %s in %s order", iri, order)
+	}
+
+	return &mockIterator{activities: m.activities, failAt: m.failAt}, nil
+}
+
+func (m *mockClient) getActorCount() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.actorCount
+}
+
+type mockIterator struct {
+	activities []*vocab.ActivityType
+	current    int
+	failAt     int
+}
+
+func (it *mockIterator) Next() (*vocab.ActivityType, error) {
+	if it.current == it.failAt {
+		return nil, errors.New("injected iterator error")
+	}
+
+	if it.current >= len(it.activities) {
+		return nil, client.ErrNotFound
+	}
+
+	activity := it.activities[it.current]
+
+	it.current++
+
+	return activity, nil
+}
+
+func (it *mockIterator) TotalItems() int {
+	return len(it.activities)
+}
+
+const anchorCredential = `{
+  "@context": [
+    "https://www.w3.org/2018/credentials/v1",
+    "https://trustbloc.github.io/did-method-orb/contexts/anchor/v1"
+  ],
+  "id": "https://domain2.com/transactions/bafkreihwsn",
+  "type": [
+    "VerifiableCredential",
+    "AnchorCredential"
+  ],
+  "issuer": "https://domain2.com/services/orb",
+  "issuanceDate": "2021-01-27T09:30:10Z",
+  "credentialSubject": {}
+}`
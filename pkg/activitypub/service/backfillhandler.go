@@ -0,0 +1,165 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/backfill"
+)
+
+// BackfillPath is the path (relative to the service endpoint) of the endpoint that triggers a backfill
+// for an actor (POST) and returns the progress of the backfill (GET). The actor is given by the
+// 'actor' query parameter and must be followed by this service.
+const BackfillPath = "/backfill"
+
+const actorParam = "actor"
+
+// errBackfillNotEnabled is returned when a backfill is requested but the backfiller isn't configured.
+var errBackfillNotEnabled = errors.New("backfill is not enabled")
+
+// Backfill schedules a job that handles the anchor credentials that were published by the given
+// (followed) service. The job runs in the background.
+func (s *Service) Backfill(actorIRI *url.URL) error {
+	if s.backfiller == nil {
+		return errBackfillNotEnabled
+	}
+
+	return s.backfiller.Schedule(actorIRI)
+}
+
+// BackfillProgress returns the progress of the backfill job for the given actor. An error that wraps
+// ariesstorage.ErrDataNotFound is returned if a backfill was never run for the actor.
+func (s *Service) BackfillProgress(actorIRI *url.URL) (*backfill.Progress, error) {
+	if s.backfiller == nil {
+		return nil, errBackfillNotEnabled
+	}
+
+	return s.backfiller.Progress(actorIRI)
+}
+
+// BackfillHTTPHandlers returns the HTTP handlers that trigger a backfill and return its progress.
+// These handlers must be registered with an HTTP server that authorizes the requests since they are
+// administrative operations.
+func (s *Service) BackfillHTTPHandlers() []common.HTTPHandler {
+	path := s.serviceEndpoint + BackfillPath
+
+	return []common.HTTPHandler{
+		&backfillHandler{path: path, method: http.MethodPost, handle: s.handleBackfill},
+		&backfillHandler{path: path, method: http.MethodGet, handle: s.handleBackfillProgress},
+	}
+}
+
+type backfillHandler struct {
+	path   string
+	method string
+	handle func(actorIRI *url.URL) (*backfill.Progress, int, error)
+}
+
+// Path returns the path of the backfill endpoint.
+func (h *backfillHandler) Path() string {
+	return h.path
+}
+
+// Method returns the HTTP method.
+func (h *backfillHandler) Method() string {
+	return h.method
+}
+
+// Handler returns the handler that writes the progress of the backfill for the requested actor.
+func (h *backfillHandler) Handler() common.HTTPRequestHandler {
+	return func(w http.ResponseWriter, req *http.Request) {
+		actor := req.URL.Query().Get(actorParam)
+		if actor == "" {
+			h.writeError(w, http.StatusBadRequest, "parameter 'actor' is required")
+
+			return
+		}
+
+		actorIRI, err := url.Parse(actor)
+		if err != nil || !actorIRI.IsAbs() {
+			h.writeError(w, http.StatusBadRequest, "invalid actor IRI")
+
+			return
+		}
+
+		progress, status, err := h.handle(actorIRI)
+		if err != nil {
+			logger.Warnf("[%s] Error handling backfill request for actor [%s]: %s", h.path, actorIRI, err)
+
+			h.writeError(w, status, http.StatusText(status))
+
+			return
+		}
+
+		progressBytes, err := json.Marshal(progress)
+		if err != nil {
+			logger.Errorf("[%s] Error marshalling backfill progress: %s", h.path, err)
+
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+
+		if _, err := w.Write(progressBytes); err != nil {
+			logger.Warnf("[%s] Error writing response: %s", h.path, err)
+		}
+	}
+}
+
+func (h *backfillHandler) writeError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+
+	if _, err := w.Write([]byte(msg)); err != nil {
+		logger.Warnf("[%s] Error writing response: %s", h.path, err)
+	}
+}
+
+// handleBackfill schedules a backfill for the given actor and returns the progress of the (pending) job.
+func (s *Service) handleBackfill(actorIRI *url.URL) (*backfill.Progress, int, error) {
+	if err := s.Backfill(actorIRI); err != nil {
+		return nil, backfillErrorStatus(err), err
+	}
+
+	progress, err := s.BackfillProgress(actorIRI)
+	if err != nil {
+		return nil, backfillErrorStatus(err), err
+	}
+
+	return progress, http.StatusAccepted, nil
+}
+
+func (s *Service) handleBackfillProgress(actorIRI *url.URL) (*backfill.Progress, int, error) {
+	progress, err := s.BackfillProgress(actorIRI)
+	if err != nil {
+		return nil, backfillErrorStatus(err), err
+	}
+
+	return progress, http.StatusOK, nil
+}
+
+func backfillErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBackfillNotEnabled):
+		return http.StatusNotImplemented
+	case errors.Is(err, backfill.ErrNotFollowing):
+		return http.StatusForbidden
+	case errors.Is(err, ariesstorage.ErrDataNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
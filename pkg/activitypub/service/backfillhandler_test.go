@@ -0,0 +1,125 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
+	"github.com/trustbloc/orb/pkg/activitypub/service/backfill"
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	service "github.com/trustbloc/orb/pkg/activitypub/service/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+func TestService_BackfillHTTPHandlers(t *testing.T) {
+	const actor = "http://localhost:8302/services/service2"
+
+	cfg := &Config{
+		ServiceEndpoint: "/services/service1",
+		ServiceIRI:      testutil.MustParseURL("http://localhost:8301/services/service1"),
+		Backfill:        &backfill.Config{},
+	}
+
+	activityStore := memstore.New(cfg.ServiceEndpoint)
+	require.NoError(t, activityStore.AddReference(spi.Following, cfg.ServiceIRI, testutil.MustParseURL(actor)))
+
+	s, err := New(cfg, activityStore, transport.Default(), &mocks.SignatureVerifier{},
+		service.WithAnchorCredentialHandler(mocks.NewAnchorCredentialHandler()))
+	require.NoError(t, err)
+
+	handlers := s.BackfillHTTPHandlers()
+	require.Len(t, handlers, 2)
+
+	postHandler := handlers[0]
+	require.Equal(t, "/services/service1/backfill", postHandler.Path())
+	require.Equal(t, http.MethodPost, postHandler.Method())
+
+	getHandler := handlers[1]
+	require.Equal(t, "/services/service1/backfill", getHandler.Path())
+	require.Equal(t, http.MethodGet, getHandler.Method())
+
+	t.Run("Progress not found", func(t *testing.T) {
+		result := serveBackfill(t, getHandler, actor)
+		require.Equal(t, http.StatusNotFound, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		result := serveBackfill(t, postHandler, actor)
+		require.Equal(t, http.StatusAccepted, result.StatusCode)
+
+		progress := &backfill.Progress{}
+		require.NoError(t, json.NewDecoder(result.Body).Decode(progress))
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, actor, progress.ActorIRI)
+		require.False(t, progress.Completed)
+
+		result = serveBackfill(t, getHandler, actor)
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		progress = &backfill.Progress{}
+		require.NoError(t, json.NewDecoder(result.Body).Decode(progress))
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, actor, progress.ActorIRI)
+	})
+
+	t.Run("Not following", func(t *testing.T) {
+		result := serveBackfill(t, postHandler, "http://localhost:8303/services/service3")
+		require.Equal(t, http.StatusForbidden, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Missing actor", func(t *testing.T) {
+		result := serveBackfill(t, postHandler, "")
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Invalid actor", func(t *testing.T) {
+		result := serveBackfill(t, postHandler, "services/service2")
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Backfill not enabled", func(t *testing.T) {
+		cfg := &Config{
+			ServiceEndpoint: "/services/service1",
+			ServiceIRI:      testutil.MustParseURL("http://localhost:8301/services/service1"),
+		}
+
+		s, err := New(cfg, memstore.New(cfg.ServiceEndpoint), transport.Default(), &mocks.SignatureVerifier{})
+		require.NoError(t, err)
+
+		for _, h := range s.BackfillHTTPHandlers() {
+			result := serveBackfill(t, h, actor)
+			require.Equal(t, http.StatusNotImplemented, result.StatusCode)
+			require.NoError(t, result.Body.Close())
+		}
+	})
+}
+
+func serveBackfill(t *testing.T, h common.HTTPHandler, actor string) *http.Response {
+	t.Helper()
+
+	rw := httptest.NewRecorder()
+
+	h.Handler()(rw, httptest.NewRequest(h.Method(), h.Path()+"?actor="+url.QueryEscape(actor), nil))
+
+	return rw.Result()
+}
@@ -9,8 +9,10 @@ package httpsubscriber
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	wmhttp "github.com/ThreeDotsLabs/watermill-http/pkg/http"
@@ -27,20 +29,43 @@ const (
 	// ActorIRIKey is the metadata key for the actor IRI.
 	ActorIRIKey = "actor-iri"
 
-	defaultBufferSize = 100
-	stopTimeout       = 250 * time.Millisecond
+	retryAfterHeader = "Retry-After"
+
+	defaultBufferSize   = 100
+	defaultMaxClockSkew = 5 * time.Minute
+	stopTimeout         = 250 * time.Millisecond
 )
 
 // Config holds the HTTP subscriber configuration parameters.
 type Config struct {
 	ServiceEndpoint string
 	BufferSize      int
+
+	// RequireDigest indicates that a request must contain a Digest header which is covered by the HTTP signature.
+	// If false then the Digest header is only verified if it's present.
+	RequireDigest bool
+
+	// RequireDate indicates that a request must contain a Date header. If false then the Date header
+	// is only checked if it's present.
+	RequireDate bool
+
+	// MaxClockSkew is the maximum difference between the Date header of a request and the local time.
+	MaxClockSkew time.Duration
+
+	// ReplayCacheExpiry is the amount of time that a signature (and activity ID) is remembered in order to
+	// detect replayed requests. Defaults to twice the MaxClockSkew, which covers the window in which a
+	// request is accepted.
+	ReplayCacheExpiry time.Duration
 }
 
 type signatureVerifier interface {
 	VerifyRequest(req *http.Request) (bool, *url.URL, error)
 }
 
+type rateLimiter interface {
+	Allow(actorIRI *url.URL, remoteAddr, activityType string) (time.Duration, bool)
+}
+
 // Subscriber implements a subscriber for Watermill that handles HTTP requests.
 type Subscriber struct {
 	*lifecycle.Lifecycle
@@ -51,23 +76,48 @@ type Subscriber struct {
 	done             chan struct{}
 	unmarshalMessage wmhttp.UnmarshalMessageFunc
 	verifier         signatureVerifier
+	requestVerifier  *requestVerifier
+	rateLimiter      rateLimiter
+}
+
+// Opt sets a Subscriber option.
+type Opt func(s *Subscriber)
+
+// WithRateLimiter sets the rate limiter which is applied to requests after the HTTP signature is verified.
+func WithRateLimiter(rl rateLimiter) Opt {
+	return func(s *Subscriber) {
+		s.rateLimiter = rl
+	}
 }
 
 // New returns a new HTTP subscriber.
-func New(cfg *Config, sigVerifier signatureVerifier) *Subscriber {
+func New(cfg *Config, sigVerifier signatureVerifier, opts ...Opt) *Subscriber {
 	if cfg.BufferSize == 0 {
 		cfg.BufferSize = defaultBufferSize
 	}
 
+	if cfg.MaxClockSkew == 0 {
+		cfg.MaxClockSkew = defaultMaxClockSkew
+	}
+
+	if cfg.ReplayCacheExpiry == 0 {
+		cfg.ReplayCacheExpiry = 2 * cfg.MaxClockSkew
+	}
+
 	s := &Subscriber{
 		Config:           cfg,
 		unmarshalMessage: wmhttp.DefaultUnmarshalMessageFunc,
 		verifier:         sigVerifier,
+		requestVerifier:  newRequestVerifier(cfg),
 		msgChan:          make(chan *message.Message, cfg.BufferSize),
 		stopped:          make(chan struct{}),
 		done:             make(chan struct{}),
 	}
 
+	for _, opt := range opts {
+		opt(s)
+	}
+
 	s.Lifecycle = lifecycle.New("httpsubscriber-"+cfg.ServiceEndpoint, lifecycle.WithStop(s.stop))
 
 	// Start the service immediately.
@@ -122,6 +172,36 @@ func (s *Subscriber) handleMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	body, err := readBody(r)
+	if err != nil {
+		logger.Warnf("[%s] Error reading request: %s", s.ServiceEndpoint, err)
+
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	status, err := s.verifyRequest(r, body)
+	if err != nil {
+		logger.Infof("[%s] Rejected request from actor [%s]: %s", s.ServiceEndpoint, actorIRI, err)
+
+		w.WriteHeader(status)
+
+		return
+	}
+
+	if s.rateLimiter != nil {
+		retryAfter, ok := s.rateLimiter.Allow(actorIRI, r.RemoteAddr, getActivityInfo(body).Type)
+		if !ok {
+			logger.Infof("[%s] Rate limit exceeded for actor [%s]. Retry after %s", s.ServiceEndpoint, actorIRI, retryAfter)
+
+			w.Header().Set(retryAfterHeader, retryAfterSeconds(retryAfter))
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+	}
+
 	msg, err := s.unmarshalMessage("", r)
 	if err != nil {
 		logger.Warnf("[%s] Error reading message: %s", s.ServiceEndpoint, err)
@@ -135,6 +215,15 @@ func (s *Subscriber) handleMessage(w http.ResponseWriter, r *http.Request) {
 		msg.Metadata[ActorIRIKey] = actorIRI.String()
 	}
 
+	// The request is recorded only after it has been accepted so that a rejected request may be retried.
+	if err := s.requestVerifier.recordRequest(r, body); err != nil {
+		logger.Infof("[%s] Rejected request from actor [%s]: %s", s.ServiceEndpoint, actorIRI, err)
+
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
 	logger.Debugf("[%s] Handling message [%s] from actor [%s]", s.ServiceEndpoint, msg.UUID, actorIRI)
 
 	err = s.publish(msg)
@@ -149,6 +238,35 @@ func (s *Subscriber) handleMessage(w http.ResponseWriter, r *http.Request) {
 	s.respond(msg, w, r)
 }
 
+// verifyRequest verifies the body digest, the date, and ensures that the request isn't a replay of
+// a previous request. The HTTP status code is returned along with the error.
+func (s *Subscriber) verifyRequest(r *http.Request, body []byte) (int, error) {
+	if err := s.requestVerifier.verifyDigest(r, body); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if err := s.requestVerifier.verifyDate(r); err != nil {
+		return http.StatusUnauthorized, err
+	}
+
+	if err := s.requestVerifier.checkReplay(r, body); err != nil {
+		return http.StatusUnauthorized, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// retryAfterSeconds returns the value of the Retry-After header, which is the number of seconds
+// (rounded up) that the client should wait before retrying.
+func retryAfterSeconds(d time.Duration) string {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return strconv.FormatInt(seconds, 10)
+}
+
 func (s *Subscriber) publish(msg *message.Message) error {
 	select {
 	case s.msgChan <- msg:
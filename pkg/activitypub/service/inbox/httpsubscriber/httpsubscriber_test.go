@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"sync"
 	"testing"
 	"time"
@@ -275,3 +276,179 @@ func TestSubscriber_HTTPSignatureError(t *testing.T) {
 	require.Equal(t, http.StatusInternalServerError, result.StatusCode)
 	require.NoError(t, result.Body.Close())
 }
+
+func TestSubscriber_VerifyRequest(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	s := New(&Config{
+		ServiceEndpoint: endpoint,
+		RequireDigest:   true,
+		RequireDate:     true,
+	}, sigVerifier)
+	require.NotNil(t, s)
+
+	defer s.Stop()
+
+	msgChan, err := s.Subscribe(context.Background(), "")
+	require.NoError(t, err)
+
+	go func() {
+		for msg := range msgChan {
+			msg.Ack()
+		}
+	}()
+
+	body := []byte(`{"id":"https://example.com/activities/1","type":"Create"}`)
+
+	newRequest := func(signature string, body []byte) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		req.Header.Set(dateHeader, time.Now().UTC().Format(http.TimeFormat))
+		req.Header.Set(digestHeader, newDigest(body))
+		req.Header.Set(signatureHeader,
+			`keyId="https://example.com/keys/main-key",headers="(request-target) date digest",signature="`+signature+`"`)
+
+		return req
+	}
+
+	handle := func(req *http.Request) int {
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, req)
+
+		result := rw.Result()
+		require.NoError(t, result.Body.Close())
+
+		return result.StatusCode
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, handle(newRequest("sig1", body)))
+	})
+
+	t.Run("Replayed signature -> Unauthorized", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, handle(newRequest("sig1", body)))
+	})
+
+	t.Run("Re-delivered activity with new signature", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, handle(newRequest("sig2", body)))
+	})
+
+	t.Run("Activity ID with different content -> Unauthorized", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized,
+			handle(newRequest("sig3", []byte(`{"id":"https://example.com/activities/1","type":"Delete"}`))))
+	})
+
+	t.Run("Body swapped -> BadRequest", func(t *testing.T) {
+		req := newRequest("sig4", []byte(`{"id":"https://example.com/activities/2","type":"Delete"}`))
+		req.Header.Set(digestHeader, newDigest(body))
+
+		require.Equal(t, http.StatusBadRequest, handle(req))
+	})
+
+	t.Run("Missing digest -> BadRequest", func(t *testing.T) {
+		req := newRequest("sig5", body)
+		req.Header.Del(digestHeader)
+
+		require.Equal(t, http.StatusBadRequest, handle(req))
+	})
+
+	t.Run("Stale date -> Unauthorized", func(t *testing.T) {
+		req := newRequest("sig6", body)
+		req.Header.Set(dateHeader, time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+
+		require.Equal(t, http.StatusUnauthorized, handle(req))
+	})
+}
+
+func TestSubscriber_RateLimit(t *testing.T) {
+	sigVerifier := &mocks.SignatureVerifier{}
+	sigVerifier.VerifyRequestReturns(true, testutil.MustParseURL(serviceURL), nil)
+
+	rl := &mockRateLimiter{}
+
+	s := New(&Config{ServiceEndpoint: endpoint}, sigVerifier, WithRateLimiter(rl))
+	require.NotNil(t, s)
+
+	defer s.Stop()
+
+	msgChan, err := s.Subscribe(context.Background(), "")
+	require.NoError(t, err)
+
+	go func() {
+		for msg := range msgChan {
+			msg.Ack()
+		}
+	}()
+
+	body := []byte(`{"id":"https://example.com/activities/1","type":"Offer"}`)
+
+	t.Run("Allowed", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body)))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, serviceURL, rl.actorIRI.String())
+		require.Equal(t, "Offer", rl.activityType)
+	})
+
+	t.Run("Rate limit exceeded", func(t *testing.T) {
+		rl.retryAfter = 1500 * time.Millisecond
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body)))
+
+		result := rw.Result()
+		require.Equal(t, http.StatusTooManyRequests, result.StatusCode)
+		require.Equal(t, "2", result.Header.Get(retryAfterHeader))
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Rate limited request is not recorded", func(t *testing.T) {
+		newRequest := func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+			req.Header.Set(signatureHeader, `keyId="https://example.com/keys/main-key",signature="sig1"`)
+
+			return req
+		}
+
+		rl.retryAfter = time.Second
+
+		rw := httptest.NewRecorder()
+
+		s.handleMessage(rw, newRequest())
+
+		result := rw.Result()
+		require.Equal(t, http.StatusTooManyRequests, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		// The same signed request is accepted once the rate limit allows it.
+		rl.retryAfter = 0
+
+		rw = httptest.NewRecorder()
+
+		s.handleMessage(rw, newRequest())
+
+		result = rw.Result()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+}
+
+type mockRateLimiter struct {
+	retryAfter   time.Duration
+	actorIRI     *url.URL
+	activityType string
+}
+
+func (m *mockRateLimiter) Allow(actorIRI *url.URL, _, activityType string) (time.Duration, bool) {
+	m.actorIRI = actorIRI
+	m.activityType = activityType
+
+	return m.retryAfter, m.retryAfter == 0
+}
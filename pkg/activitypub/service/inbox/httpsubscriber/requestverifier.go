@@ -0,0 +1,360 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsubscriber
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	digestHeader        = "Digest"
+	dateHeader          = "Date"
+	signatureHeader     = "Signature"
+	authorizationHeader = "Authorization"
+
+	digestSHA256 = "SHA-256"
+	digestSHA512 = "SHA-512"
+)
+
+var (
+	errMissingDigest     = errors.New("missing digest header")
+	errDigestNotSigned   = errors.New("digest header is not covered by the HTTP signature")
+	errDigestMismatch    = errors.New("digest does not match the request body")
+	errUnsupportedDigest = errors.New("no supported algorithm in digest header")
+	errMissingDate       = errors.New("missing date header")
+	errDateNotSigned     = errors.New("date header is not covered by the HTTP signature")
+	errReplay            = errors.New("request has already been processed")
+)
+
+var digestAlgorithms = map[string]func() hash.Hash{
+	digestSHA256: sha256.New,
+	digestSHA512: sha512.New,
+}
+
+// requestVerifier verifies the integrity and freshness of an inbound (signed) HTTP request. The body
+// is verified against the Digest header, the Date header must be within the allowed clock skew and
+// the signature and activity ID must not have been seen before (within the replay window).
+type requestVerifier struct {
+	requireDigest bool
+	requireDate   bool
+	maxClockSkew  time.Duration
+	cache         *replayCache
+	now           func() time.Time
+}
+
+func newRequestVerifier(cfg *Config) *requestVerifier {
+	return &requestVerifier{
+		requireDigest: cfg.RequireDigest,
+		requireDate:   cfg.RequireDate,
+		maxClockSkew:  cfg.MaxClockSkew,
+		cache:         newReplayCache(cfg.ReplayCacheExpiry),
+		now:           time.Now,
+	}
+}
+
+// readBody reads the request body and replaces it so that it may be read again.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+func (v *requestVerifier) verifyDigest(req *http.Request, body []byte) error {
+	digest := req.Header.Get(digestHeader)
+	if digest == "" {
+		if v.requireDigest {
+			return errMissingDigest
+		}
+
+		return nil
+	}
+
+	if v.requireDigest && !isHeaderSigned(req, digestHeader) {
+		return errDigestNotSigned
+	}
+
+	verified := false
+
+	for _, d := range strings.Split(digest, ",") {
+		parts := strings.SplitN(strings.TrimSpace(d), "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid digest header [%s]", digest)
+		}
+
+		newHash, ok := digestAlgorithms[strings.ToUpper(parts[0])]
+		if !ok {
+			continue
+		}
+
+		expected, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return fmt.Errorf("decode %s digest: %w", parts[0], err)
+		}
+
+		h := newHash()
+		h.Write(body) //nolint:errcheck,gosec
+
+		if subtle.ConstantTimeCompare(expected, h.Sum(nil)) != 1 {
+			return errDigestMismatch
+		}
+
+		verified = true
+	}
+
+	if !verified {
+		return errUnsupportedDigest
+	}
+
+	return nil
+}
+
+func (v *requestVerifier) verifyDate(req *http.Request) error {
+	dateStr := req.Header.Get(dateHeader)
+	if dateStr == "" {
+		if v.requireDate {
+			return errMissingDate
+		}
+
+		return nil
+	}
+
+	// An unsigned date may be changed by anyone who replays the request.
+	if v.requireDate && !isHeaderSigned(req, dateHeader) {
+		return errDateNotSigned
+	}
+
+	date, err := http.ParseTime(dateStr)
+	if err != nil {
+		return fmt.Errorf("invalid date header [%s]: %w", dateStr, err)
+	}
+
+	skew := v.now().Sub(date)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	if skew > v.maxClockSkew {
+		return fmt.Errorf("date [%s] is outside of the allowed clock skew of %s", dateStr, v.maxClockSkew)
+	}
+
+	return nil
+}
+
+// checkReplay ensures that the signature of the request has not been seen before and that, if the
+// activity ID was seen before, the body is the same as the previously received body. (Re-delivery of
+// the same activity with a new signature is allowed since a sender retries if it doesn't receive a response.)
+// The request is not recorded; recordRequest must be called once the request is accepted.
+func (v *requestVerifier) checkReplay(req *http.Request, body []byte) error {
+	if sig := getSignature(req); sig != "" {
+		if _, ok := v.cache.get("sig:" + sig); ok {
+			return errReplay
+		}
+	}
+
+	activityID := getActivityInfo(body).ID
+	if activityID == "" {
+		return nil
+	}
+
+	existing, ok := v.cache.get("activity:" + activityID)
+	if ok && existing != bodyHash(body) {
+		return fmt.Errorf("activity [%s] was previously received with different content: %w", activityID, errReplay)
+	}
+
+	return nil
+}
+
+// recordRequest records the signature and the activity of an accepted request so that replays of the
+// request are rejected. An error is returned if a concurrent request with the same signature (or the same
+// activity with different content) was recorded after checkReplay was called.
+func (v *requestVerifier) recordRequest(req *http.Request, body []byte) error {
+	activityID := getActivityInfo(body).ID
+
+	if activityID != "" {
+		existing, ok := v.cache.getOrAdd("activity:"+activityID, bodyHash(body))
+		if ok && existing != bodyHash(body) {
+			return fmt.Errorf("activity [%s] was previously received with different content: %w",
+				activityID, errReplay)
+		}
+	}
+
+	if sig := getSignature(req); sig != "" {
+		if !v.cache.add("sig:"+sig, "") {
+			return errReplay
+		}
+	}
+
+	return nil
+}
+
+func bodyHash(body []byte) string {
+	hash := sha256.Sum256(body)
+
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+type activityInfo struct {
+	ID   string
+	Type string
+}
+
+// getActivityInfo returns the ID and (first) type of the activity in the given body. Empty values are
+// returned if the body isn't a valid activity.
+func getActivityInfo(body []byte) *activityInfo {
+	if len(body) == 0 {
+		return &activityInfo{}
+	}
+
+	raw := &struct {
+		ID   string          `json:"id"`
+		Type json.RawMessage `json:"type"`
+	}{}
+
+	if err := json.Unmarshal(body, raw); err != nil {
+		return &activityInfo{}
+	}
+
+	info := &activityInfo{ID: raw.ID}
+
+	var types []string
+
+	if err := json.Unmarshal(raw.Type, &info.Type); err != nil {
+		if err := json.Unmarshal(raw.Type, &types); err == nil && len(types) > 0 {
+			info.Type = types[0]
+		}
+	}
+
+	return info
+}
+
+func getSignature(req *http.Request) string {
+	return getSignatureParam(req, "signature")
+}
+
+func isHeaderSigned(req *http.Request, header string) bool {
+	for _, h := range strings.Fields(getSignatureParam(req, "headers")) {
+		if strings.EqualFold(h, header) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getSignatureParam(req *http.Request, name string) string {
+	sig := req.Header.Get(signatureHeader)
+	if sig == "" {
+		sig = strings.TrimPrefix(req.Header.Get(authorizationHeader), "Signature ")
+	}
+
+	for _, param := range strings.Split(sig, ",") {
+		parts := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], name) {
+			return strings.Trim(parts[1], `"`)
+		}
+	}
+
+	return ""
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// replayCache holds the signatures and activity IDs of recently received requests. Entries expire
+// after the configured expiry, which should be at least the window in which the Date header is accepted.
+type replayCache struct {
+	expiry    time.Duration
+	mutex     sync.Mutex
+	entries   map[string]*cacheEntry
+	lastPurge time.Time
+	now       func() time.Time
+}
+
+func newReplayCache(expiry time.Duration) *replayCache {
+	return &replayCache{
+		expiry:  expiry,
+		entries: make(map[string]*cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// add adds the given key to the cache and returns true if the key wasn't already in the cache.
+func (c *replayCache) add(key, value string) bool {
+	_, exists := c.getOrAdd(key, value)
+
+	return !exists
+}
+
+// get returns the value for the given key if it exists.
+func (c *replayCache) get(key string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || c.now().After(e.expires) {
+		return "", false
+	}
+
+	return e.value, true
+}
+
+// getOrAdd returns the value for the given key if it exists, otherwise the given value is added.
+func (c *replayCache) getOrAdd(key, value string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+
+	c.purge(now)
+
+	if e, ok := c.entries[key]; ok && !now.After(e.expires) {
+		return e.value, true
+	}
+
+	c.entries[key] = &cacheEntry{value: value, expires: now.Add(c.expiry)}
+
+	return "", false
+}
+
+func (c *replayCache) purge(now time.Time) {
+	// Avoid scanning the entire cache on every request.
+	if now.Sub(c.lastPurge) < c.expiry/2 {
+		return
+	}
+
+	c.lastPurge = now
+
+	for key, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
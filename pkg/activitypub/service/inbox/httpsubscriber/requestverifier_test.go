@@ -0,0 +1,205 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsubscriber
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestVerifier_VerifyDigest(t *testing.T) {
+	body := []byte(`{"id":"https://example.com/activities/1"}`)
+
+	v := newRequestVerifier(&Config{RequireDigest: true})
+
+	t.Run("SHA-256", func(t *testing.T) {
+		req := newSignedRequest(body, "(request-target) date digest")
+		req.Header.Set(digestHeader, newDigest(body))
+
+		require.NoError(t, v.verifyDigest(req, body))
+	})
+
+	t.Run("SHA-512", func(t *testing.T) {
+		sum := sha512.Sum512(body)
+
+		req := newSignedRequest(body, "(request-target) date digest")
+		req.Header.Set(digestHeader, "SHA-512="+base64.StdEncoding.EncodeToString(sum[:]))
+
+		require.NoError(t, v.verifyDigest(req, body))
+	})
+
+	t.Run("Multiple digests", func(t *testing.T) {
+		req := newSignedRequest(body, "(request-target) date digest")
+		req.Header.Set(digestHeader, "MD5=xxx, "+newDigest(body))
+
+		require.NoError(t, v.verifyDigest(req, body))
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		req := newSignedRequest(body, "(request-target) date digest")
+		req.Header.Set(digestHeader, newDigest([]byte("other")))
+
+		require.True(t, errors.Is(v.verifyDigest(req, body), errDigestMismatch))
+	})
+
+	t.Run("Unsupported algorithm", func(t *testing.T) {
+		req := newSignedRequest(body, "(request-target) date digest")
+		req.Header.Set(digestHeader, "MD5=xxx")
+
+		require.True(t, errors.Is(v.verifyDigest(req, body), errUnsupportedDigest))
+	})
+
+	t.Run("Invalid digest", func(t *testing.T) {
+		req := newSignedRequest(body, "(request-target) date digest")
+
+		req.Header.Set(digestHeader, "SHA-256")
+		require.Error(t, v.verifyDigest(req, body))
+
+		req.Header.Set(digestHeader, "SHA-256=%%%")
+		require.Error(t, v.verifyDigest(req, body))
+	})
+
+	t.Run("Digest not signed", func(t *testing.T) {
+		req := newSignedRequest(body, "(request-target) date")
+		req.Header.Set(digestHeader, newDigest(body))
+
+		require.True(t, errors.Is(v.verifyDigest(req, body), errDigestNotSigned))
+	})
+
+	t.Run("Missing digest", func(t *testing.T) {
+		require.True(t, errors.Is(v.verifyDigest(newSignedRequest(body, "(request-target) date"), body),
+			errMissingDigest))
+
+		require.NoError(t, newRequestVerifier(&Config{}).verifyDigest(newSignedRequest(body, ""), body))
+	})
+}
+
+func TestRequestVerifier_VerifyDate(t *testing.T) {
+	now := time.Now()
+
+	v := newRequestVerifier(&Config{RequireDate: true, MaxClockSkew: time.Minute})
+	v.now = func() time.Time { return now }
+
+	req := newSignedRequest(nil, "(request-target) date")
+
+	t.Run("Within skew", func(t *testing.T) {
+		req.Header.Set(dateHeader, now.Add(-30*time.Second).UTC().Format(http.TimeFormat))
+		require.NoError(t, v.verifyDate(req))
+
+		req.Header.Set(dateHeader, now.Add(30*time.Second).UTC().Format(http.TimeFormat))
+		require.NoError(t, v.verifyDate(req))
+	})
+
+	t.Run("Outside of skew", func(t *testing.T) {
+		req.Header.Set(dateHeader, now.Add(-2*time.Minute).UTC().Format(http.TimeFormat))
+		require.Error(t, v.verifyDate(req))
+
+		req.Header.Set(dateHeader, now.Add(2*time.Minute).UTC().Format(http.TimeFormat))
+		require.Error(t, v.verifyDate(req))
+	})
+
+	t.Run("Invalid date", func(t *testing.T) {
+		req.Header.Set(dateHeader, "yesterday")
+
+		err := v.verifyDate(req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid date header")
+	})
+
+	t.Run("Date not signed", func(t *testing.T) {
+		req := newSignedRequest(nil, "(request-target) digest")
+		req.Header.Set(dateHeader, now.UTC().Format(http.TimeFormat))
+
+		require.True(t, errors.Is(v.verifyDate(req), errDateNotSigned))
+
+		require.NoError(t, newRequestVerifier(&Config{MaxClockSkew: time.Minute}).verifyDate(req))
+	})
+
+	t.Run("Missing date", func(t *testing.T) {
+		req.Header.Del(dateHeader)
+		require.True(t, errors.Is(v.verifyDate(req), errMissingDate))
+
+		require.NoError(t, newRequestVerifier(&Config{}).verifyDate(req))
+	})
+}
+
+func TestRequestVerifier_CheckReplay(t *testing.T) {
+	body := []byte(`{"id":"https://example.com/activities/1"}`)
+
+	v := newRequestVerifier(&Config{ReplayCacheExpiry: time.Minute})
+
+	req := newSignedRequest(body, "(request-target) date")
+
+	// The request isn't recorded by checkReplay.
+	require.NoError(t, v.checkReplay(req, body))
+	require.NoError(t, v.checkReplay(req, body))
+
+	require.NoError(t, v.recordRequest(req, body))
+	require.True(t, errors.Is(v.checkReplay(req, body), errReplay))
+	require.True(t, errors.Is(v.recordRequest(req, body), errReplay))
+
+	otherBody := []byte(`{"id":"https://example.com/activities/1","type":"Delete"}`)
+	otherReq := newSignedRequest(otherBody, "(request-target) date")
+	otherReq.Header.Set(signatureHeader, `keyId="https://example.com/keys/main-key",signature="sig2"`)
+
+	require.True(t, errors.Is(v.checkReplay(otherReq, otherBody), errReplay))
+	require.True(t, errors.Is(v.recordRequest(otherReq, otherBody), errReplay))
+}
+
+func TestReplayCache(t *testing.T) {
+	now := time.Now()
+
+	c := newReplayCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	require.True(t, c.add("key1", "value1"))
+	require.False(t, c.add("key1", "value1"))
+
+	value, ok := c.getOrAdd("key1", "value2")
+	require.True(t, ok)
+	require.Equal(t, "value1", value)
+
+	now = now.Add(2 * time.Minute)
+
+	require.True(t, c.add("key1", "value1"))
+	require.Len(t, c.entries, 1)
+}
+
+func newSignedRequest(body []byte, headers string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	req.Header.Set(signatureHeader, `keyId="https://example.com/keys/main-key",headers="`+headers+`",signature="sig"`)
+
+	return req
+}
+
+func newDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestGetActivityInfo(t *testing.T) {
+	info := getActivityInfo([]byte(`{"id":"https://example.com/activities/1","type":"Offer"}`))
+	require.Equal(t, "https://example.com/activities/1", info.ID)
+	require.Equal(t, "Offer", info.Type)
+
+	info = getActivityInfo([]byte(`{"id":"https://example.com/activities/1","type":["Offer","Other"]}`))
+	require.Equal(t, "https://example.com/activities/1", info.ID)
+	require.Equal(t, "Offer", info.Type)
+
+	require.Empty(t, getActivityInfo(nil).ID)
+	require.Empty(t, getActivityInfo([]byte("{")).ID)
+}
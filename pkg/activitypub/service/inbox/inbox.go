@@ -13,10 +13,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
 	"github.com/ThreeDotsLabs/watermill/message/router/plugin"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
 
@@ -24,6 +26,7 @@ import (
 	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
 	service "github.com/trustbloc/orb/pkg/activitypub/service/spi"
 	"github.com/trustbloc/orb/pkg/activitypub/service/wmlogger"
+	"github.com/trustbloc/orb/pkg/activitypub/store/processedindex"
 	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 )
@@ -40,12 +43,36 @@ type signatureVerifier interface {
 	VerifyRequest(req *http.Request) (bool, *url.URL, error)
 }
 
+type rateLimiter interface {
+	Allow(actorIRI *url.URL, remoteAddr, activityType string) (time.Duration, bool)
+}
+
+type processedIndex interface {
+	IsProcessed(activityID *url.URL) (bool, error)
+	IsPending(activityID *url.URL) (bool, error)
+	MarkPending(activityID *url.URL) error
+	MarkProcessed(activityID *url.URL) error
+	Start()
+	Stop()
+}
+
 // Config holds configuration parameters for the Inbox.
 type Config struct {
 	ServiceEndpoint        string
 	ServiceIRI             *url.URL
 	Topic                  string
 	VerifyActorInSignature bool
+
+	// RequireDigestAndDate indicates that requests must contain Digest and Date headers
+	// which are covered by the HTTP signature.
+	RequireDigestAndDate bool
+
+	// MaxClockSkew is the maximum difference between the Date header of a request and the local time.
+	MaxClockSkew time.Duration
+
+	// SharedInboxEndpoint is the endpoint of the shared inbox. Activities posted to the shared inbox
+	// are handled in the same way as activities posted to the inbox. If empty then there is no shared inbox.
+	SharedInboxEndpoint string
 }
 
 // Inbox implements the ActivityPub inbox.
@@ -58,19 +85,55 @@ type Inbox struct {
 	msgChannel      <-chan *message.Message
 	activityHandler service.ActivityHandler
 	activityStore   store.Store
+	processedIndex  processedIndex
+	rateLimiter     rateLimiter
+	activityLocks   *keyLocks
 	jsonUnmarshal   func(data []byte, v interface{}) error
 }
 
+// Opt sets an Inbox option.
+type Opt func(h *Inbox)
+
+// WithProcessedIndex sets the index which records the IDs of the activities that have been processed.
+// A duplicate of an activity in the index is acknowledged but not processed again. If not set then
+// an in-memory index is used.
+func WithProcessedIndex(index processedIndex) Opt {
+	return func(h *Inbox) {
+		h.processedIndex = index
+	}
+}
+
+// WithRateLimiter sets the rate limiter which is applied to requests posted to the inbox.
+func WithRateLimiter(rl rateLimiter) Opt {
+	return func(h *Inbox) {
+		h.rateLimiter = rl
+	}
+}
+
 // New returns a new ActivityPub inbox.
 func New(cfg *Config, s store.Store, pubSub pubSub, activityHandler service.ActivityHandler,
-	sigVerifier signatureVerifier) (*Inbox, error) {
+	sigVerifier signatureVerifier, opts ...Opt) (*Inbox, error) {
 	h := &Inbox{
 		Config:          cfg,
 		activityHandler: activityHandler,
 		activityStore:   s,
+		activityLocks:   newKeyLocks(),
 		jsonUnmarshal:   json.Unmarshal,
 	}
 
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.processedIndex == nil {
+		index, err := processedindex.New(&processedindex.Config{}, mem.NewProvider())
+		if err != nil {
+			return nil, fmt.Errorf("create processed-activity index: %w", err)
+		}
+
+		h.processedIndex = index
+	}
+
 	h.Lifecycle = lifecycle.New(cfg.ServiceEndpoint,
 		lifecycle.WithStart(h.start),
 		lifecycle.WithStop(h.stop),
@@ -81,11 +144,20 @@ func New(cfg *Config, s store.Store, pubSub pubSub, activityHandler service.Acti
 		return nil, fmt.Errorf("subscribe to topic [%s]: %w", cfg.Topic, err)
 	}
 
+	var subscriberOpts []httpsubscriber.Opt
+
+	if h.rateLimiter != nil {
+		subscriberOpts = append(subscriberOpts, httpsubscriber.WithRateLimiter(h.rateLimiter))
+	}
+
 	httpSubscriber := httpsubscriber.New(
 		&httpsubscriber.Config{
 			ServiceEndpoint: cfg.ServiceEndpoint,
+			RequireDigest:   cfg.RequireDigestAndDate,
+			RequireDate:     cfg.RequireDigestAndDate,
+			MaxClockSkew:    cfg.MaxClockSkew,
 		},
-		sigVerifier,
+		sigVerifier, subscriberOpts...,
 	)
 
 	router, err := message.NewRouter(message.RouterConfig{}, wmlogger.New())
@@ -119,7 +191,22 @@ func (h *Inbox) HTTPHandler() common.HTTPHandler {
 	return h.httpSubscriber
 }
 
+// SharedInboxHTTPHandler returns the HTTP handler for the shared inbox or nil if no shared inbox endpoint
+// was configured. This handler must be registered with an HTTP server.
+func (h *Inbox) SharedInboxHTTPHandler() common.HTTPHandler {
+	if h.SharedInboxEndpoint == "" {
+		return nil
+	}
+
+	return &sharedInboxHandler{
+		path:    h.SharedInboxEndpoint,
+		handler: h.httpSubscriber.Handler(),
+	}
+}
+
 func (h *Inbox) start() {
+	h.processedIndex.Start()
+
 	// Start the router
 	go h.route()
 
@@ -136,6 +223,8 @@ func (h *Inbox) stop() {
 	} else {
 		logger.Debugf("[%s] Closed router", h.ServiceEndpoint)
 	}
+
+	h.processedIndex.Stop()
 }
 
 func (h *Inbox) route() {
@@ -173,49 +262,152 @@ func (h *Inbox) handle(msg *message.Message) {
 		return
 	}
 
-	activityID, err := h.activityStore.GetActivity(activity.ID().URL())
+	processed, err := h.processIfNew(activity)
 	if err != nil {
-		if !errors.Is(err, store.ErrNotFound) {
-			logger.Errorf("[%s] Error retrieving activity [%s] in message [%s]: %s",
-				h.ServiceEndpoint, activity.ID(), msg.UUID, err)
-
-			msg.Nack()
-
-			return
-		}
-	} else {
-		logger.Infof("[%s] Ignoring duplicate activity [%s] in message [%s]", h.ServiceEndpoint, activityID, msg.UUID)
+		logger.Warnf("[%s] Error processing activity [%s] in message [%s]: %s",
+			h.ServiceEndpoint, activity.ID(), msg.UUID, err)
 
 		msg.Nack()
 
 		return
 	}
 
-	if err := h.activityStore.AddActivity(activity); err != nil {
-		logger.Errorf("[%s] Error storing activity [%s]: %s", h.ServiceEndpoint, activity.ID(), err)
+	if !processed {
+		logger.Infof("[%s] Ignoring duplicate activity [%s] in message [%s]", h.ServiceEndpoint, activity.ID(), msg.UUID)
 
-		msg.Nack()
+		msg.Ack()
 
 		return
 	}
 
-	if err := h.activityStore.AddReference(store.Inbox, h.ServiceIRI, activity.ID().URL()); err != nil {
-		logger.Errorf("[%s] Error adding reference to activity [%s]: %s", h.ServiceEndpoint, activity.ID(), err)
+	logger.Debugf("[%s] Successfully handled message [%s]", h.ServiceEndpoint, msg.UUID)
 
-		msg.Nack()
+	msg.Ack()
+}
 
-		return
+// HandleActivity processes an activity that was retrieved from a remote outbox rather than delivered to the
+// inbox (for example, by the outbox poller). The activity is ignored if it was already processed.
+func (h *Inbox) HandleActivity(activity *vocab.ActivityType) error {
+	if activity.Actor() == nil {
+		return fmt.Errorf("no actor specified in activity [%s]", activity.ID())
+	}
+
+	processed, err := h.processIfNew(activity)
+	if err != nil {
+		return err
+	}
+
+	if !processed {
+		logger.Debugf("[%s] Ignoring duplicate activity [%s]", h.ServiceEndpoint, activity.ID())
+	}
+
+	return nil
+}
+
+// processIfNew processes the activity unless it was already processed. False is returned if the activity
+// was already processed. Activities may be delivered to the inbox and retrieved by the outbox poller at the
+// same time, so the check and the processing are done while holding a lock on the activity ID. Otherwise
+// both could see the activity as not yet processed and handle it twice.
+func (h *Inbox) processIfNew(activity *vocab.ActivityType) (bool, error) {
+	unlock := h.activityLocks.lock(activity.ID().String())
+	defer unlock()
+
+	processed, err := h.isProcessed(activity.ID().URL())
+	if err != nil {
+		return false, fmt.Errorf("check whether activity [%s] was processed: %w", activity.ID(), err)
+	}
+
+	if processed {
+		return false, nil
+	}
+
+	if err := h.process(activity); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// isProcessed returns true if the activity with the given ID was processed. The entries in the processed index
+// expire, so an activity that is in the activity store and isn't pending (i.e. its processing didn't fail) was
+// also processed.
+func (h *Inbox) isProcessed(activityID *url.URL) (bool, error) {
+	processed, err := h.processedIndex.IsProcessed(activityID)
+	if err != nil {
+		return false, err
+	}
+
+	if processed {
+		return true, nil
+	}
+
+	pending, err := h.processedIndex.IsPending(activityID)
+	if err != nil {
+		return false, err
+	}
+
+	if pending {
+		return false, nil
+	}
+
+	_, err = h.activityStore.GetActivity(activityID)
+	if err == nil {
+		return true, nil
+	}
+
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("retrieve activity: %w", err)
+}
+
+// process stores the activity, invokes the activity handler and adds the activity to the processed index.
+// The activity is marked as pending before it's stored so that it's processed again if processing fails.
+func (h *Inbox) process(activity *vocab.ActivityType) error {
+	if err := h.processedIndex.MarkPending(activity.ID().URL()); err != nil {
+		return fmt.Errorf("mark activity as pending: %w", err)
+	}
+
+	if err := h.store(activity); err != nil {
+		return fmt.Errorf("store activity: %w", err)
 	}
 
 	if err := h.activityHandler.HandleActivity(activity); err != nil {
-		logger.Warnf("[%s] Error handling message [%s]: %s", h.ServiceEndpoint, msg.UUID, err)
+		return fmt.Errorf("handle activity: %w", err)
+	}
 
-		msg.Nack()
-	} else {
-		logger.Debugf("[%s] Successfully handled message [%s]", h.ServiceEndpoint, msg.UUID)
+	// The activity has already been handled so a failure to update the index is not reported.
+	if err := h.processedIndex.MarkProcessed(activity.ID().URL()); err != nil {
+		logger.Warnf("[%s] Error marking activity [%s] as processed: %s", h.ServiceEndpoint, activity.ID(), err)
+	}
 
-		msg.Ack()
+	return nil
+}
+
+// store adds the activity to the activity store and to the inbox. If the activity is already in the store
+// (i.e. a previous attempt to process the activity failed) then it's not added again.
+func (h *Inbox) store(activity *vocab.ActivityType) error {
+	_, err := h.activityStore.GetActivity(activity.ID().URL())
+	if err == nil {
+		logger.Debugf("[%s] Activity [%s] is already in the store", h.ServiceEndpoint, activity.ID())
+
+		return nil
+	}
+
+	if !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("retrieve activity: %w", err)
+	}
+
+	if err := h.activityStore.AddActivity(activity); err != nil {
+		return fmt.Errorf("add activity: %w", err)
+	}
+
+	if err := h.activityStore.AddReference(store.Inbox, h.ServiceIRI, activity.ID().URL()); err != nil {
+		return fmt.Errorf("add reference to activity: %w", err)
 	}
+
+	return nil
 }
 
 func (h *Inbox) unmarshalAndValidateActivity(msg *message.Message) (*vocab.ActivityType, error) {
@@ -230,7 +422,9 @@ func (h *Inbox) unmarshalAndValidateActivity(msg *message.Message) (*vocab.Activ
 		return nil, fmt.Errorf("no actor specified in activity [%s]", activity.ID())
 	}
 
-	if h.VerifyActorInSignature {
+	// 'Update' and 'Delete' activities replace or remove the actor's own data, so the actor must be the signer
+	// of the request even if actors aren't otherwise verified against the HTTP signature.
+	if h.VerifyActorInSignature || activity.Type().IsAny(vocab.TypeUpdate, vocab.TypeDelete) {
 		actorIRI := msg.Metadata[httpsubscriber.ActorIRIKey]
 		if actorIRI == "" {
 			return nil, fmt.Errorf("no actorIRI specified in message context")
@@ -244,3 +438,24 @@ func (h *Inbox) unmarshalAndValidateActivity(msg *message.Message) (*vocab.Activ
 
 	return activity, nil
 }
+
+// sharedInboxHandler accepts deliveries at the shared inbox endpoint and passes them to the inbox subscriber.
+type sharedInboxHandler struct {
+	path    string
+	handler common.HTTPRequestHandler
+}
+
+// Path returns the path of the shared inbox endpoint.
+func (h *sharedInboxHandler) Path() string {
+	return h.path
+}
+
+// Method returns the HTTP method, which is always POST.
+func (h *sharedInboxHandler) Method() string {
+	return http.MethodPost
+}
+
+// Handler returns the handler that should be invoked when an HTTP request is posted to the shared inbox.
+func (h *sharedInboxHandler) Handler() common.HTTPRequestHandler {
+	return h.handler
+}
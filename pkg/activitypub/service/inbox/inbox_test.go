@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
@@ -152,6 +153,77 @@ func TestInbox_Handle(t *testing.T) {
 	require.Equal(t, spi.StateStopped, ib.State())
 }
 
+func TestInbox_SharedInbox(t *testing.T) {
+	const service1URL = "http://localhost:8209/services/service1"
+
+	t.Run("No shared inbox", func(t *testing.T) {
+		ib, err := New(
+			&Config{
+				ServiceEndpoint: "/services/service1/inbox",
+				ServiceIRI:      testutil.MustParseURL(service1URL),
+				Topic:           "activities",
+			},
+			memstore.New(""), mocks.NewPubSub(), &mocks.ActivityHandler{}, &mocks.SignatureVerifier{},
+		)
+		require.NoError(t, err)
+		require.Nil(t, ib.SharedInboxHTTPHandler())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		cfg := &Config{
+			ServiceEndpoint:     "/services/service1/inbox",
+			SharedInboxEndpoint: "/services/service1/sharedinbox",
+			ServiceIRI:          testutil.MustParseURL(service1URL),
+			Topic:               "activities",
+		}
+
+		activityHandler := &mocks.ActivityHandler{}
+		activityStore := memstore.New(cfg.ServiceEndpoint)
+
+		sigVerifier := &mocks.SignatureVerifier{}
+		sigVerifier.VerifyRequestReturns(true, cfg.ServiceIRI, nil)
+
+		ib, err := New(cfg, activityStore, mocks.NewPubSub(), activityHandler, sigVerifier)
+		require.NoError(t, err)
+
+		sharedInboxHandler := ib.SharedInboxHTTPHandler()
+		require.NotNil(t, sharedInboxHandler)
+		require.Equal(t, cfg.SharedInboxEndpoint, sharedInboxHandler.Path())
+		require.Equal(t, http.MethodPost, sharedInboxHandler.Method())
+
+		ib.Start()
+		defer ib.Stop()
+
+		stop := startHTTPServer(t, ":8209", ib.HTTPHandler(), sharedInboxHandler)
+		defer stop()
+
+		time.Sleep(500 * time.Millisecond)
+
+		activity := vocab.NewCreateActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(testutil.MustParseURL("http://example.com/object1"))),
+			vocab.WithID(newActivityID(cfg.ServiceEndpoint)),
+			vocab.WithActor(cfg.ServiceIRI),
+		)
+
+		req, err := newHTTPRequest(service1URL+resthandler.SharedInboxPath, activity)
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+
+		// Wait for the activity to be handled
+		time.Sleep(50 * time.Millisecond)
+
+		require.Equal(t, 1, activityHandler.HandleActivityCallCount())
+
+		a, err := activityStore.GetActivity(activity.ID().URL())
+		require.NoError(t, err)
+		require.Equal(t, activity.ID(), a.ID())
+	})
+}
+
 func TestInbox_Error(t *testing.T) {
 	log.SetLevel("activitypub_service", log.DEBUG)
 
@@ -496,6 +568,203 @@ func TestInbox_Error(t *testing.T) {
 	})
 }
 
+func TestInbox_DuplicateActivity(t *testing.T) {
+	cfg := &Config{
+		ServiceEndpoint: "/services/service1/inbox",
+		ServiceIRI:      testutil.MustParseURL("https://example1.com/services/service1"),
+		Topic:           "activities",
+	}
+
+	activity := vocab.NewCreateActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(testutil.MustParseURL("https://example1.com/object1"))),
+		vocab.WithID(newActivityID(cfg.ServiceEndpoint)),
+		vocab.WithActor(cfg.ServiceIRI),
+	)
+
+	activityBytes, err := json.Marshal(activity)
+	require.NoError(t, err)
+
+	activityStore := memstore.New(cfg.ServiceEndpoint)
+	activityHandler := &mocks.ActivityHandler{}
+
+	ib, err := New(cfg, activityStore, mocks.NewPubSub(), activityHandler, &mocks.SignatureVerifier{})
+	require.NoError(t, err)
+
+	t.Run("Handler error -> redelivered activity is processed", func(t *testing.T) {
+		activityHandler.HandleActivityReturns(fmt.Errorf("injected handler error"))
+
+		msg := message.NewMessage(watermill.NewUUID(), activityBytes)
+		ib.handle(msg)
+
+		requireNacked(t, msg)
+
+		activityHandler.HandleActivityReturns(nil)
+
+		msg = message.NewMessage(watermill.NewUUID(), activityBytes)
+		ib.handle(msg)
+
+		requireAcked(t, msg)
+		require.Equal(t, 2, activityHandler.HandleActivityCallCount())
+	})
+
+	t.Run("Processed activity -> acknowledged but not processed", func(t *testing.T) {
+		msg := message.NewMessage(watermill.NewUUID(), activityBytes)
+		ib.handle(msg)
+
+		requireAcked(t, msg)
+		require.Equal(t, 2, activityHandler.HandleActivityCallCount())
+	})
+
+	t.Run("Processed index entry expired -> stored activity not processed", func(t *testing.T) {
+		ib, err := New(cfg, activityStore, mocks.NewPubSub(), activityHandler, &mocks.SignatureVerifier{},
+			WithProcessedIndex(&mockProcessedIndex{}))
+		require.NoError(t, err)
+
+		msg := message.NewMessage(watermill.NewUUID(), activityBytes)
+		ib.handle(msg)
+
+		requireAcked(t, msg)
+		require.Equal(t, 2, activityHandler.HandleActivityCallCount())
+	})
+
+	t.Run("Pending stored activity -> processed", func(t *testing.T) {
+		ib, err := New(cfg, activityStore, mocks.NewPubSub(), activityHandler, &mocks.SignatureVerifier{},
+			WithProcessedIndex(&mockProcessedIndex{pending: true}))
+		require.NoError(t, err)
+
+		msg := message.NewMessage(watermill.NewUUID(), activityBytes)
+		ib.handle(msg)
+
+		requireAcked(t, msg)
+		require.Equal(t, 3, activityHandler.HandleActivityCallCount())
+	})
+
+	t.Run("Processed index error", func(t *testing.T) {
+		ib, err := New(cfg, activityStore, mocks.NewPubSub(), activityHandler, &mocks.SignatureVerifier{},
+			WithProcessedIndex(&mockProcessedIndex{err: fmt.Errorf("injected index error")}))
+		require.NoError(t, err)
+
+		msg := message.NewMessage(watermill.NewUUID(), activityBytes)
+		ib.handle(msg)
+
+		requireNacked(t, msg)
+	})
+}
+
+func TestInbox_HandleActivity(t *testing.T) {
+	cfg := &Config{
+		ServiceEndpoint: "/services/service1/inbox",
+		ServiceIRI:      testutil.MustParseURL("https://example1.com/services/service1"),
+		Topic:           "activities",
+	}
+
+	activity := vocab.NewCreateActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(testutil.MustParseURL("https://example1.com/object1"))),
+		vocab.WithID(newActivityID(cfg.ServiceEndpoint)),
+		vocab.WithActor(testutil.MustParseURL("https://example2.com/services/service2")),
+	)
+
+	activityStore := memstore.New(cfg.ServiceEndpoint)
+	activityHandler := &mocks.ActivityHandler{}
+
+	ib, err := New(cfg, activityStore, mocks.NewPubSub(), activityHandler, &mocks.SignatureVerifier{})
+	require.NoError(t, err)
+
+	t.Run("Handler error", func(t *testing.T) {
+		activityHandler.HandleActivityReturns(fmt.Errorf("injected handler error"))
+
+		err := ib.HandleActivity(activity)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected handler error")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		activityHandler.HandleActivityReturns(nil)
+
+		require.NoError(t, ib.HandleActivity(activity))
+		require.Equal(t, 2, activityHandler.HandleActivityCallCount())
+
+		_, err := activityStore.GetActivity(activity.ID().URL())
+		require.NoError(t, err)
+	})
+
+	t.Run("Processed activity -> ignored", func(t *testing.T) {
+		require.NoError(t, ib.HandleActivity(activity))
+		require.Equal(t, 2, activityHandler.HandleActivityCallCount())
+	})
+
+	t.Run("No actor", func(t *testing.T) {
+		err := ib.HandleActivity(vocab.NewCreateActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(testutil.MustParseURL("https://example1.com/object1"))),
+			vocab.WithID(newActivityID(cfg.ServiceEndpoint)),
+		))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no actor specified")
+	})
+
+	t.Run("Processed index error", func(t *testing.T) {
+		ib, err := New(cfg, activityStore, mocks.NewPubSub(), activityHandler, &mocks.SignatureVerifier{},
+			WithProcessedIndex(&mockProcessedIndex{err: fmt.Errorf("injected index error")}))
+		require.NoError(t, err)
+
+		err = ib.HandleActivity(activity)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected index error")
+	})
+}
+
+func TestInbox_ConcurrentDeliveryAndPoll(t *testing.T) {
+	cfg := &Config{
+		ServiceEndpoint: "/services/service1/inbox",
+		ServiceIRI:      testutil.MustParseURL("https://example1.com/services/service1"),
+		Topic:           "activities",
+	}
+
+	activity := vocab.NewOfferActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(testutil.MustParseURL("https://example1.com/object1"))),
+		vocab.WithID(newActivityID(cfg.ServiceEndpoint)),
+		vocab.WithActor(testutil.MustParseURL("https://example2.com/services/service2")),
+	)
+
+	activityBytes, err := json.Marshal(activity)
+	require.NoError(t, err)
+
+	activityHandler := &mocks.ActivityHandler{}
+	activityHandler.HandleActivityStub = func(*vocab.ActivityType) error {
+		// Widen the window in which a concurrent delivery could see the activity as not yet processed.
+		time.Sleep(50 * time.Millisecond)
+
+		return nil
+	}
+
+	ib, err := New(cfg, memstore.New(cfg.ServiceEndpoint), mocks.NewPubSub(), activityHandler,
+		&mocks.SignatureVerifier{})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	msg := message.NewMessage(watermill.NewUUID(), activityBytes)
+
+	go func() {
+		defer wg.Done()
+
+		ib.handle(msg)
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		require.NoError(t, ib.HandleActivity(activity))
+	}()
+
+	wg.Wait()
+
+	requireAcked(t, msg)
+	require.Equal(t, 1, activityHandler.HandleActivityCallCount())
+}
+
 func TestUnmarshalAndValidateActivity(t *testing.T) {
 	activityID := testutil.MustParseURL("https://example1.com/activities/activity1")
 	actorIRI := testutil.MustParseURL("https://example1.com/services/service1")
@@ -570,6 +839,38 @@ func TestUnmarshalAndValidateActivity(t *testing.T) {
 		require.Contains(t, err.Error(), "does not match the actor in the HTTP signature")
 		require.Nil(t, a)
 	})
+
+	t.Run("Update/Delete signed by another actor error", func(t *testing.T) {
+		ib, err := New(&Config{}, memstore.New(""), mocks.NewPubSub(), nil, nil)
+		require.NoError(t, err)
+
+		for _, activity := range []*vocab.ActivityType{
+			vocab.NewUpdateActivity(vocab.NewObjectProperty(vocab.WithActorObject(vocab.NewService(actorIRI))),
+				vocab.WithID(activityID), vocab.WithActor(actorIRI)),
+			vocab.NewDeleteActivity(vocab.NewObjectProperty(vocab.WithIRI(actorIRI)),
+				vocab.WithID(activityID), vocab.WithActor(actorIRI)),
+		} {
+			activityBytes, err := json.Marshal(activity)
+			require.NoError(t, err)
+
+			msg := message.NewMessage("msg1", activityBytes)
+
+			_, err = ib.unmarshalAndValidateActivity(msg)
+			require.EqualError(t, err, "no actorIRI specified in message context")
+
+			msg.Metadata[httpsubscriber.ActorIRIKey] = "https://example1.com/services/service2"
+
+			_, err = ib.unmarshalAndValidateActivity(msg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "does not match the actor in the HTTP signature")
+
+			msg.Metadata[httpsubscriber.ActorIRIKey] = actorIRI.String()
+
+			a, err := ib.unmarshalAndValidateActivity(msg)
+			require.NoError(t, err)
+			require.NotNil(t, a)
+		}
+	})
 }
 
 func newHTTPRequest(u string, activity *vocab.ActivityType) (*http.Request, error) {
@@ -612,3 +913,48 @@ func startHTTPServer(t *testing.T, listenAddress string, handlers ...common.HTTP
 		require.NoError(t, httpServer.Stop(context.Background()))
 	}
 }
+
+func requireAcked(t *testing.T, msg *message.Message) {
+	t.Helper()
+
+	select {
+	case <-msg.Acked():
+	default:
+		require.Fail(t, "expecting message to be acked")
+	}
+}
+
+func requireNacked(t *testing.T, msg *message.Message) {
+	t.Helper()
+
+	select {
+	case <-msg.Nacked():
+	default:
+		require.Fail(t, "expecting message to be nacked")
+	}
+}
+
+type mockProcessedIndex struct {
+	err     error
+	pending bool
+}
+
+func (m *mockProcessedIndex) IsProcessed(*url.URL) (bool, error) {
+	return false, m.err
+}
+
+func (m *mockProcessedIndex) IsPending(*url.URL) (bool, error) {
+	return m.pending, m.err
+}
+
+func (m *mockProcessedIndex) MarkPending(*url.URL) error {
+	return m.err
+}
+
+func (m *mockProcessedIndex) MarkProcessed(*url.URL) error {
+	return m.err
+}
+
+func (m *mockProcessedIndex) Start() {}
+
+func (m *mockProcessedIndex) Stop() {}
@@ -0,0 +1,56 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package inbox
+
+import (
+	"sync"
+)
+
+// keyLocks provides a mutex per key. A key's mutex is removed when it's no longer held or waited on.
+type keyLocks struct {
+	mutex sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refCount int
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{locks: make(map[string]*keyLock)}
+}
+
+// lock locks the given key and returns a function that unlocks it.
+func (l *keyLocks) lock(key string) func() {
+	l.mutex.Lock()
+
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+
+	kl.refCount++
+
+	l.mutex.Unlock()
+
+	kl.Lock()
+
+	return func() {
+		kl.Unlock()
+
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		kl.refCount--
+
+		if kl.refCount == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
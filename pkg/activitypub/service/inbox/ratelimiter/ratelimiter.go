@@ -0,0 +1,441 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+var logger = log.New("activitypub_service")
+
+// Relationship is the relationship of a remote actor to the local service.
+type Relationship string
+
+const (
+	// RelationshipNone indicates that the actor has no relationship with the local service.
+	RelationshipNone Relationship = "none"
+	// RelationshipFollower indicates that the actor is following the local service.
+	RelationshipFollower Relationship = "follower"
+	// RelationshipWitnessing indicates that the local service witnesses anchor credentials for the actor.
+	RelationshipWitnessing Relationship = "witnessing"
+)
+
+const (
+	defaultRelationshipCacheTTL = time.Minute
+	defaultIdleTimeout          = 10 * time.Minute
+)
+
+// Limit defines a token-bucket limit. Tokens are added at the given rate (per second) up to a maximum of Burst.
+// A zero rate means that there is no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Config contains the rate limits.
+type Config struct {
+	// ActorLimits are the per-actor limits on inbox requests, keyed by the relationship of the actor to the
+	// local service. The limit for RelationshipNone is used if there is no limit for a given relationship.
+	ActorLimits map[Relationship]Limit
+
+	// OfferLimits are the per-actor limits on 'Offer' activities (witness requests), keyed by the relationship
+	// of the actor to the local service. These limits are applied in addition to the ActorLimits.
+	OfferLimits map[Relationship]Limit
+
+	// DomainLimit is the limit on inbox requests from all actors in a domain.
+	DomainLimit Limit
+
+	// RelationshipCacheTTL is the amount of time that the relationship of an actor is cached.
+	RelationshipCacheTTL time.Duration
+
+	// IdleTimeout is the amount of time after which the buckets of an idle actor or domain are removed.
+	IdleTimeout time.Duration
+}
+
+// Rejection contains the number of requests that were rejected for an actor or domain.
+type Rejection struct {
+	Key          string    `json:"key"`
+	Rejected     uint64    `json:"rejected"`
+	LastRejected time.Time `json:"lastRejected"`
+}
+
+// Limiter applies per-actor and per-domain token-bucket limits on requests posted to the inbox.
+type Limiter struct {
+	*Config
+
+	serviceIRI *url.URL
+	store      store.Store
+	mutex      sync.Mutex
+	actors     map[string]*actorEntry
+	domains    map[string]*bucket
+	rejections map[string]*Rejection
+	lastPurge  time.Time
+	now        func() time.Time
+}
+
+type actorEntry struct {
+	relationship Relationship
+	resolved     time.Time
+	requests     *bucket
+	offers       *bucket
+}
+
+// New returns a new rate limiter. The relationship of an actor to the local service (given by serviceIRI)
+// is resolved from the references in the given store.
+func New(cfg *Config, serviceIRI *url.URL, s store.Store) *Limiter {
+	if cfg.RelationshipCacheTTL == 0 {
+		cfg.RelationshipCacheTTL = defaultRelationshipCacheTTL
+	}
+
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = defaultIdleTimeout
+	}
+
+	return &Limiter{
+		Config:     cfg,
+		serviceIRI: serviceIRI,
+		store:      s,
+		actors:     make(map[string]*actorEntry),
+		domains:    make(map[string]*bucket),
+		rejections: make(map[string]*Rejection),
+		now:        time.Now,
+	}
+}
+
+// Allow returns true if a request of the given activity type from the given actor is allowed. If the request
+// is not allowed then the amount of time after which the request may be retried is returned. If the actor
+// is unknown (i.e. the request wasn't signed) then the domain limit is applied to the remote address.
+func (l *Limiter) Allow(actorIRI *url.URL, remoteAddr, activityType string) (time.Duration, bool) {
+	var actorEntry *actorEntry
+
+	if actorIRI != nil {
+		// Resolve the relationship outside of the lock since it may involve a database query.
+		entry, err := l.getActorEntry(actorIRI)
+		if err != nil {
+			// Don't reject the request because of an internal error.
+			logger.Warnf("Error resolving relationship of actor [%s]: %s", actorIRI, err)
+		}
+
+		actorEntry = entry
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+
+	l.purge(now)
+
+	domain := getDomain(actorIRI, remoteAddr)
+
+	domainBucket, ok := l.domains[domain]
+	if !ok {
+		domainBucket = newBucket(l.DomainLimit, now)
+		l.domains[domain] = domainBucket
+	}
+
+	// Tokens are taken only if all of the applicable buckets allow the request so that a rejected request
+	// doesn't consume the tokens of the other buckets.
+	buckets := []*bucket{domainBucket}
+	keys := []string{"domain:" + domain}
+
+	if actorEntry != nil {
+		buckets = append(buckets, actorEntry.requests)
+		keys = append(keys, actorIRI.String())
+
+		if activityType == string(vocab.TypeOffer) {
+			buckets = append(buckets, actorEntry.offers)
+			keys = append(keys, actorIRI.String())
+		}
+	}
+
+	for i, b := range buckets {
+		if retryAfter, ok := b.check(now); !ok {
+			l.reject(keys[i], now)
+
+			return retryAfter, false
+		}
+	}
+
+	for _, b := range buckets {
+		b.take(now)
+	}
+
+	return 0, true
+}
+
+// Rejections returns the number of rejected requests for each actor (keyed by actor IRI)
+// and domain (keyed by "domain:" + domain). Actors and domains without rejections within the
+// idle timeout are not included.
+func (l *Limiter) Rejections() []*Rejection {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	rejections := make([]*Rejection, 0, len(l.rejections))
+
+	for _, r := range l.rejections {
+		rc := *r
+		rejections = append(rejections, &rc)
+	}
+
+	return rejections
+}
+
+func (l *Limiter) reject(key string, now time.Time) {
+	r, ok := l.rejections[key]
+	if !ok {
+		r = &Rejection{Key: key}
+		l.rejections[key] = r
+	}
+
+	r.Rejected++
+	r.LastRejected = now
+
+	logger.Warnf("Rate limit exceeded for [%s]. Total rejected requests: %d", key, r.Rejected)
+}
+
+func (l *Limiter) getActorEntry(actorIRI *url.URL) (*actorEntry, error) {
+	key := actorIRI.String()
+
+	l.mutex.Lock()
+	entry, ok := l.actors[key]
+	l.mutex.Unlock()
+
+	if ok && l.now().Sub(entry.resolved) < l.RelationshipCacheTTL {
+		return entry, nil
+	}
+
+	relationship, err := l.resolveRelationship(actorIRI)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+
+	if err != nil {
+		// Use the default limits (or the previously resolved limits) until the relationship can be resolved.
+		relationship = RelationshipNone
+	}
+
+	entry, ok = l.actors[key]
+	if !ok {
+		entry = &actorEntry{
+			relationship: relationship,
+			requests:     newBucket(l.limitFor(l.ActorLimits, relationship), now),
+			offers:       newBucket(l.limitFor(l.OfferLimits, relationship), now),
+		}
+
+		l.actors[key] = entry
+	}
+
+	if err != nil {
+		return entry, err
+	}
+
+	entry.resolved = now
+
+	if entry.relationship != relationship {
+		logger.Debugf("Relationship of actor [%s] changed from [%s] to [%s]", key, entry.relationship, relationship)
+
+		entry.relationship = relationship
+		entry.requests.setLimit(l.limitFor(l.ActorLimits, relationship))
+		entry.offers.setLimit(l.limitFor(l.OfferLimits, relationship))
+	}
+
+	return entry, nil
+}
+
+func (l *Limiter) resolveRelationship(actorIRI *url.URL) (Relationship, error) {
+	isWitnessing, err := l.hasReference(actorIRI, store.Witnessing)
+	if err != nil {
+		return "", err
+	}
+
+	if isWitnessing {
+		return RelationshipWitnessing, nil
+	}
+
+	isFollower, err := l.hasReference(actorIRI, store.Follower)
+	if err != nil {
+		return "", err
+	}
+
+	if isFollower {
+		return RelationshipFollower, nil
+	}
+
+	return RelationshipNone, nil
+}
+
+func (l *Limiter) hasReference(actorIRI *url.URL, refType store.ReferenceType) (bool, error) {
+	it, err := l.store.QueryReferences(refType,
+		store.NewCriteria(
+			store.WithObjectIRI(l.serviceIRI),
+			store.WithReferenceIRI(actorIRI),
+		),
+	)
+	if err != nil {
+		return false, fmt.Errorf("query references: %w", err)
+	}
+
+	defer func() {
+		if err := it.Close(); err != nil {
+			logger.Errorf("failed to close iterator: %s", err.Error())
+		}
+	}()
+
+	_, err = it.Next()
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("get next reference: %w", err)
+	}
+
+	return true, nil
+}
+
+func (l *Limiter) limitFor(limits map[Relationship]Limit, relationship Relationship) Limit {
+	if limit, ok := limits[relationship]; ok {
+		return limit
+	}
+
+	return limits[RelationshipNone]
+}
+
+// purge removes the buckets of actors and domains that have been idle for longer than the idle timeout.
+func (l *Limiter) purge(now time.Time) {
+	if now.Sub(l.lastPurge) < l.IdleTimeout {
+		return
+	}
+
+	l.lastPurge = now
+
+	for key, entry := range l.actors {
+		if entry.requests.isIdle(now, l.IdleTimeout) && entry.offers.isIdle(now, l.IdleTimeout) {
+			delete(l.actors, key)
+		}
+	}
+
+	for key, b := range l.domains {
+		if b.isIdle(now, l.IdleTimeout) {
+			delete(l.domains, key)
+		}
+	}
+
+	for key, r := range l.rejections {
+		if now.Sub(r.LastRejected) >= l.IdleTimeout {
+			delete(l.rejections, key)
+		}
+	}
+}
+
+func getDomain(actorIRI *url.URL, remoteAddr string) string {
+	if actorIRI != nil {
+		return actorIRI.Hostname()
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+
+	return host
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit Limit, now time.Time) *bucket {
+	limit = normalize(limit)
+
+	return &bucket{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   now,
+	}
+}
+
+func (b *bucket) setLimit(limit Limit) {
+	b.limit = normalize(limit)
+
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+}
+
+// normalize ensures that a rate limit allows at least one request.
+func normalize(limit Limit) Limit {
+	if limit.Rate > 0 && limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return limit
+}
+
+// check returns true if a token is available in the bucket. If no tokens are available then false is
+// returned along with the amount of time until the next token is available.
+func (b *bucket) check(now time.Time) (time.Duration, bool) {
+	if b.limit.Rate <= 0 {
+		return 0, true
+	}
+
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		return 0, true
+	}
+
+	return time.Duration(math.Ceil((1 - b.tokens) / b.limit.Rate * float64(time.Second))), false
+}
+
+// take takes a token from the bucket. The caller must ensure that a token is available (see check).
+func (b *bucket) take(now time.Time) {
+	if b.limit.Rate <= 0 {
+		return
+	}
+
+	b.refill(now)
+
+	b.tokens--
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	b.last = now
+}
+
+// isIdle returns true if the bucket hasn't been used for the given timeout and is full.
+func (b *bucket) isIdle(now time.Time, timeout time.Duration) bool {
+	if now.Sub(b.last) < timeout {
+		return false
+	}
+
+	b.refill(now)
+
+	return b.limit.Rate <= 0 || b.tokens >= float64(b.limit.Burst)
+}
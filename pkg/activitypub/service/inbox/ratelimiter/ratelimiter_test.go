@@ -0,0 +1,265 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ratelimiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+var (
+	serviceIRI = testutil.MustParseURL("https://example.com/services/orb")
+	actor1     = testutil.MustParseURL("https://domain1.com/services/orb")
+	actor2     = testutil.MustParseURL("https://domain2.com/services/orb")
+	actor3     = testutil.MustParseURL("https://domain3.com/services/orb")
+	actor4     = testutil.MustParseURL("https://domain3.com/services/other")
+)
+
+const (
+	typeCreate = string(vocab.TypeCreate)
+	typeOffer  = string(vocab.TypeOffer)
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	s := memstore.New("")
+
+	require.NoError(t, s.AddReference(store.Follower, serviceIRI, actor2))
+	require.NoError(t, s.AddReference(store.Witnessing, serviceIRI, actor3))
+
+	l := New(&Config{
+		ActorLimits: map[Relationship]Limit{
+			RelationshipNone:       {Rate: 1, Burst: 1},
+			RelationshipFollower:   {Rate: 1, Burst: 3},
+			RelationshipWitnessing: {Rate: 1, Burst: 5},
+		},
+		OfferLimits: map[Relationship]Limit{
+			RelationshipNone:       {Rate: 0.5},
+			RelationshipWitnessing: {Rate: 1, Burst: 2},
+		},
+	}, serviceIRI, s)
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	t.Run("No relationship", func(t *testing.T) {
+		_, ok := l.Allow(actor1, "", typeCreate)
+		require.True(t, ok)
+
+		retryAfter, ok := l.Allow(actor1, "", typeCreate)
+		require.False(t, ok)
+		require.Equal(t, time.Second, retryAfter)
+	})
+
+	t.Run("Follower", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, ok := l.Allow(actor2, "", typeCreate)
+			require.True(t, ok)
+		}
+
+		_, ok := l.Allow(actor2, "", typeCreate)
+		require.False(t, ok)
+	})
+
+	t.Run("Witnessing offers", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, ok := l.Allow(actor3, "", typeOffer)
+			require.True(t, ok)
+		}
+
+		_, ok := l.Allow(actor3, "", typeOffer)
+		require.False(t, ok)
+
+		// Other activities are still allowed.
+		_, ok = l.Allow(actor3, "", typeCreate)
+		require.True(t, ok)
+	})
+
+	t.Run("Refill", func(t *testing.T) {
+		now = now.Add(2 * time.Second)
+
+		_, ok := l.Allow(actor1, "", typeCreate)
+		require.True(t, ok)
+
+		_, ok = l.Allow(actor3, "", typeOffer)
+		require.True(t, ok)
+	})
+
+	t.Run("Rejections", func(t *testing.T) {
+		rejections := l.Rejections()
+		require.Len(t, rejections, 3)
+
+		for _, r := range rejections {
+			require.Equal(t, uint64(1), r.Rejected)
+		}
+	})
+}
+
+func TestLimiter_DomainLimit(t *testing.T) {
+	l := New(&Config{DomainLimit: Limit{Rate: 1, Burst: 2}}, serviceIRI, memstore.New(""))
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	_, ok := l.Allow(actor3, "", typeCreate)
+	require.True(t, ok)
+
+	_, ok = l.Allow(actor4, "", typeCreate)
+	require.True(t, ok)
+
+	_, ok = l.Allow(actor4, "", typeCreate)
+	require.False(t, ok)
+
+	_, ok = l.Allow(actor1, "", typeCreate)
+	require.True(t, ok)
+
+	t.Run("Unsigned request -> limit by remote address", func(t *testing.T) {
+		_, ok := l.Allow(nil, "10.0.0.1:1234", typeCreate)
+		require.True(t, ok)
+
+		_, ok = l.Allow(nil, "10.0.0.1:5678", typeCreate)
+		require.True(t, ok)
+
+		retryAfter, ok := l.Allow(nil, "10.0.0.1:1234", typeCreate)
+		require.False(t, ok)
+		require.Equal(t, time.Second, retryAfter)
+
+		_, ok = l.Allow(nil, "10.0.0.2", typeCreate)
+		require.True(t, ok)
+	})
+
+	rejections := l.Rejections()
+	require.Len(t, rejections, 2)
+}
+
+func TestLimiter_RelationshipChanged(t *testing.T) {
+	s := memstore.New("")
+
+	l := New(&Config{
+		ActorLimits: map[Relationship]Limit{
+			RelationshipNone:     {Rate: 1, Burst: 1},
+			RelationshipFollower: {Rate: 1, Burst: 10},
+		},
+		RelationshipCacheTTL: time.Minute,
+	}, serviceIRI, s)
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	_, ok := l.Allow(actor1, "", typeCreate)
+	require.True(t, ok)
+
+	require.NoError(t, s.AddReference(store.Follower, serviceIRI, actor1))
+
+	// The relationship is cached.
+	_, ok = l.Allow(actor1, "", typeCreate)
+	require.False(t, ok)
+
+	now = now.Add(time.Minute)
+
+	for i := 0; i < 5; i++ {
+		_, ok = l.Allow(actor1, "", typeCreate)
+		require.True(t, ok)
+	}
+}
+
+func TestLimiter_StoreError(t *testing.T) {
+	s := &mocks.ActivityStore{}
+	s.QueryReferencesReturns(nil, errors.New("injected query error"))
+
+	l := New(&Config{
+		ActorLimits: map[Relationship]Limit{
+			RelationshipNone: {Rate: 1, Burst: 1},
+		},
+	}, serviceIRI, s)
+
+	// The default limit is applied.
+	_, ok := l.Allow(actor1, "", typeCreate)
+	require.True(t, ok)
+
+	_, ok = l.Allow(actor1, "", typeCreate)
+	require.False(t, ok)
+}
+
+func TestLimiter_Purge(t *testing.T) {
+	l := New(&Config{
+		ActorLimits: map[Relationship]Limit{
+			RelationshipNone: {Rate: 1, Burst: 1},
+		},
+		DomainLimit: Limit{Rate: 1, Burst: 1},
+		IdleTimeout: time.Minute,
+	}, serviceIRI, memstore.New(""))
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	_, ok := l.Allow(actor1, "", typeCreate)
+	require.True(t, ok)
+
+	require.Len(t, l.actors, 1)
+	require.Len(t, l.domains, 1)
+
+	now = now.Add(2 * time.Minute)
+
+	_, ok = l.Allow(actor2, "", typeCreate)
+	require.True(t, ok)
+
+	require.Len(t, l.actors, 1)
+	require.Len(t, l.domains, 1)
+
+	_, ok = l.Allow(actor2, "", typeCreate)
+	require.False(t, ok)
+	require.Len(t, l.Rejections(), 1)
+
+	now = now.Add(2 * time.Minute)
+
+	_, ok = l.Allow(actor1, "", typeCreate)
+	require.True(t, ok)
+	require.Empty(t, l.Rejections())
+}
+
+func TestLimiter_RejectedRequestConsumesNoTokens(t *testing.T) {
+	l := New(&Config{
+		ActorLimits: map[Relationship]Limit{
+			RelationshipNone: {Rate: 1, Burst: 2},
+		},
+		OfferLimits: map[Relationship]Limit{
+			RelationshipNone: {Rate: 1, Burst: 1},
+		},
+		DomainLimit: Limit{Rate: 1, Burst: 2},
+	}, serviceIRI, memstore.New(""))
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	_, ok := l.Allow(actor1, "", typeOffer)
+	require.True(t, ok)
+
+	// The offer limit is exceeded, so the actor and domain tokens are not consumed.
+	_, ok = l.Allow(actor1, "", typeOffer)
+	require.False(t, ok)
+
+	_, ok = l.Allow(actor1, "", typeCreate)
+	require.True(t, ok)
+
+	// The domain limit is exceeded, so the actor tokens are not consumed.
+	_, ok = l.Allow(actor1, "", typeCreate)
+	require.False(t, ok)
+
+	now = now.Add(time.Second)
+
+	_, ok = l.Allow(actor1, "", typeCreate)
+	require.True(t, ok)
+}
@@ -58,6 +58,28 @@ type ActivityStore struct {
 		result1 *vocab.ActivityType
 		result2 error
 	}
+	DeleteActivityStub        func(activityID *url.URL) error
+	deleteActivityMutex       sync.RWMutex
+	deleteActivityArgsForCall []struct {
+		activityID *url.URL
+	}
+	deleteActivityReturns struct {
+		result1 error
+	}
+	deleteActivityReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteActorStub        func(actorIRI *url.URL) error
+	deleteActorMutex       sync.RWMutex
+	deleteActorArgsForCall []struct {
+		actorIRI *url.URL
+	}
+	deleteActorReturns struct {
+		result1 error
+	}
+	deleteActorReturnsOnCall map[int]struct {
+		result1 error
+	}
 	QueryActivitiesStub        func(query *spi.Criteria, opts ...spi.QueryOpt) (spi.ActivityIterator, error)
 	queryActivitiesMutex       sync.RWMutex
 	queryActivitiesArgsForCall []struct {
@@ -315,6 +337,105 @@ func (fake *ActivityStore) GetActivityReturnsOnCall(i int, result1 *vocab.Activi
 	}{result1, result2}
 }
 
+func (fake *ActivityStore) DeleteActivity(activityID *url.URL) error {
+	fake.deleteActivityMutex.Lock()
+	ret, specificReturn := fake.deleteActivityReturnsOnCall[len(fake.deleteActivityArgsForCall)]
+	fake.deleteActivityArgsForCall = append(fake.deleteActivityArgsForCall, struct {
+		activityID *url.URL
+	}{activityID})
+	fake.recordInvocation("DeleteActivity", []interface{}{activityID})
+	fake.deleteActivityMutex.Unlock()
+	if fake.DeleteActivityStub != nil {
+		return fake.DeleteActivityStub(activityID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.deleteActivityReturns.result1
+}
+
+func (fake *ActivityStore) DeleteActivityCallCount() int {
+	fake.deleteActivityMutex.RLock()
+	defer fake.deleteActivityMutex.RUnlock()
+	fake.deleteActorMutex.RLock()
+	defer fake.deleteActorMutex.RUnlock()
+	return len(fake.deleteActivityArgsForCall)
+}
+
+func (fake *ActivityStore) DeleteActivityArgsForCall(i int) *url.URL {
+	fake.deleteActivityMutex.RLock()
+	defer fake.deleteActivityMutex.RUnlock()
+	fake.deleteActorMutex.RLock()
+	defer fake.deleteActorMutex.RUnlock()
+	return fake.deleteActivityArgsForCall[i].activityID
+}
+
+func (fake *ActivityStore) DeleteActivityReturns(result1 error) {
+	fake.DeleteActivityStub = nil
+	fake.deleteActivityReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ActivityStore) DeleteActivityReturnsOnCall(i int, result1 error) {
+	fake.DeleteActivityStub = nil
+	if fake.deleteActivityReturnsOnCall == nil {
+		fake.deleteActivityReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteActivityReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ActivityStore) DeleteActor(actorIRI *url.URL) error {
+	fake.deleteActorMutex.Lock()
+	ret, specificReturn := fake.deleteActorReturnsOnCall[len(fake.deleteActorArgsForCall)]
+	fake.deleteActorArgsForCall = append(fake.deleteActorArgsForCall, struct {
+		actorIRI *url.URL
+	}{actorIRI})
+	fake.recordInvocation("DeleteActor", []interface{}{actorIRI})
+	fake.deleteActorMutex.Unlock()
+	if fake.DeleteActorStub != nil {
+		return fake.DeleteActorStub(actorIRI)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.deleteActorReturns.result1
+}
+
+func (fake *ActivityStore) DeleteActorCallCount() int {
+	fake.deleteActorMutex.RLock()
+	defer fake.deleteActorMutex.RUnlock()
+	return len(fake.deleteActorArgsForCall)
+}
+
+func (fake *ActivityStore) DeleteActorArgsForCall(i int) *url.URL {
+	fake.deleteActorMutex.RLock()
+	defer fake.deleteActorMutex.RUnlock()
+	return fake.deleteActorArgsForCall[i].actorIRI
+}
+
+func (fake *ActivityStore) DeleteActorReturns(result1 error) {
+	fake.DeleteActorStub = nil
+	fake.deleteActorReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ActivityStore) DeleteActorReturnsOnCall(i int, result1 error) {
+	fake.DeleteActorStub = nil
+	if fake.deleteActorReturnsOnCall == nil {
+		fake.deleteActorReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteActorReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
 func (fake *ActivityStore) QueryActivities(query *spi.Criteria, opts ...spi.QueryOpt) (spi.ActivityIterator, error) {
 	fake.queryActivitiesMutex.Lock()
 	ret, specificReturn := fake.queryActivitiesReturnsOnCall[len(fake.queryActivitiesArgsForCall)]
@@ -531,6 +652,10 @@ func (fake *ActivityStore) Invocations() map[string][][]interface{} {
 	defer fake.addActivityMutex.RUnlock()
 	fake.getActivityMutex.RLock()
 	defer fake.getActivityMutex.RUnlock()
+	fake.deleteActivityMutex.RLock()
+	defer fake.deleteActivityMutex.RUnlock()
+	fake.deleteActorMutex.RLock()
+	defer fake.deleteActorMutex.RUnlock()
 	fake.queryActivitiesMutex.RLock()
 	defer fake.queryActivitiesMutex.RUnlock()
 	fake.addReferenceMutex.RLock()
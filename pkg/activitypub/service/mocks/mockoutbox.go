@@ -29,6 +29,9 @@ func NewOutbox() *Outbox {
 
 // WithError injects an error into the mock outbox.
 func (m *Outbox) WithError(err error) *Outbox {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	m.err = err
 
 	return m
@@ -52,13 +55,13 @@ func (m *Outbox) Activities() Activities {
 // Post post an activity to the outbox. The activity is simply stored
 // so that it may be retrieved by the Activies function.
 func (m *Outbox) Post(activity *vocab.ActivityType) (*url.URL, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	if m.err != nil {
 		return nil, m.err
 	}
 
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
 	m.activities = append(m.activities, activity)
 
 	return m.activityID, nil
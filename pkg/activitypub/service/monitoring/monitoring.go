@@ -8,16 +8,27 @@ package monitoring
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/sirupsen/logrus"
 	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+
+	discoveryclient "github.com/trustbloc/orb/pkg/discovery/endpoint/client"
 )
 
 var logger = logrus.New()
@@ -29,9 +40,17 @@ type vcStore interface {
 const (
 	storeName       = "monitoring"
 	keyPrefix       = "queue"
+	sthKeyPrefix    = "sth-"
+	proofKeyPrefix  = "proof-"
 	tagNotConfirmed = "not_confirmed"
+	tagCredential   = "credential"
 )
 
+type logResolver interface {
+	GetLogEndpoint(witness *url.URL) (*discoveryclient.LogEndpoint, error)
+	GetLogPublicKey(logURL string) ([]byte, error)
+}
+
 // HTTPClient represents HTTP client.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -39,10 +58,24 @@ type HTTPClient interface {
 
 // Client for the monitoring.
 type Client struct {
-	store   storage.Store
-	http    HTTPClient
-	vcStore vcStore
-	ticker  *time.Ticker
+	store                 storage.Store
+	http                  HTTPClient
+	vcStore               vcStore
+	ticker                *time.Ticker
+	minPollInterval       time.Duration
+	maxPollInterval       time.Duration
+	done                  chan struct{}
+	wg                    sync.WaitGroup
+	closeOnce             sync.Once
+	publicKeys            map[string][]byte
+	keyMutex              sync.RWMutex
+	sthMutex              sync.Mutex
+	logMutexes            map[string]*sync.Mutex
+	statsMutex            sync.Mutex
+	nextRunMutex          sync.Mutex
+	nextRun               time.Time
+	brokenPromiseHandlers []BrokenPromiseHandler
+	logResolver           logResolver
 }
 
 // Opt represents client option func.
@@ -55,6 +88,14 @@ func WithHTTPClient(client HTTPClient) Opt {
 	}
 }
 
+// WithLogResolver sets the resolver that discovers the log (and public key) of a witness. If set, the domain
+// of a proof must be the log of the witness that provided the proof.
+func WithLogResolver(resolver logResolver) Opt {
+	return func(o *Client) {
+		o.logResolver = resolver
+	}
+}
+
 // New returns monitoring client.
 func New(provider storage.Provider, vcStore vcStore, opts ...Opt) (*Client, error) {
 	store, err := provider.OpenStore(storeName)
@@ -62,22 +103,31 @@ func New(provider storage.Provider, vcStore vcStore, opts ...Opt) (*Client, erro
 		return nil, fmt.Errorf("open store: %w", err)
 	}
 
-	err = provider.SetStoreConfig(storeName, storage.StoreConfiguration{TagNames: []string{tagNotConfirmed}})
+	err = provider.SetStoreConfig(storeName,
+		storage.StoreConfiguration{TagNames: []string{tagNotConfirmed, tagCredential, tagLog}})
 	if err != nil {
 		return nil, fmt.Errorf("failed to set store configuration: %w", err)
 	}
 
 	client := &Client{
-		store:   store,
-		vcStore: vcStore,
-		ticker:  time.NewTicker(time.Second),
-		http:    &http.Client{Timeout: time.Minute},
+		store:           store,
+		vcStore:         vcStore,
+		http:            &http.Client{Timeout: time.Minute},
+		minPollInterval: defaultMinPollInterval,
+		maxPollInterval: defaultMaxPollInterval,
+		done:            make(chan struct{}),
+		publicKeys:      make(map[string][]byte),
+		logMutexes:      make(map[string]*sync.Mutex),
 	}
 
 	for _, opt := range opts {
 		opt(client)
 	}
 
+	client.ticker = time.NewTicker(client.minPollInterval)
+
+	client.wg.Add(1)
+
 	go client.worker()
 
 	return client, nil
@@ -91,11 +141,25 @@ type Proof struct {
 	} `json:"proof"`
 }
 
+// InclusionProof is a verified proof that an anchor credential is included in the log of a witness.
+type InclusionProof struct {
+	CredentialID string                  `json:"credential_id"`
+	Domain       string                  `json:"domain"`
+	LeafIndex    int64                   `json:"leaf_index"`
+	AuditPath    [][]byte                `json:"audit_path"`
+	STH          *command.GetSTHResponse `json:"sth"`
+}
+
 type entity struct {
 	CredentialID   string    `json:"credential_id"`
 	ExpirationDate time.Time `json:"expiration_date"`
 	Domain         string    `json:"domain"`
 	Created        time.Time `json:"created"`
+	Witness        string    `json:"witness,omitempty"`
+	NextCheck      time.Time `json:"next_check"`
+
+	// key is the key under which the entity was found in the queue (which may be a legacy key).
+	key string
 }
 
 var errExpired = errors.New("expired")
@@ -106,132 +170,150 @@ func (c *Client) exist(e *entity) error {
 		return errExpired
 	}
 
+	hash, err := c.leafHash(e)
+	if err != nil {
+		return err
+	}
+
 	// creates new client based on domain
 	vctClient := vct.New(e.Domain, vct.WithHTTPClient(c.http))
 
+	// gets the latest signed tree head (verified against the previous one) to get the latest tree size.
+	sth, err := c.getVerifiedSTH(vctClient, e.Domain)
+	if err != nil {
+		return err
+	}
+
+	return c.verifyInclusion(vctClient, e, hash, sth)
+}
+
+// leafHash returns the hash of the leaf which contains the credential of the given entity.
+func (c *Client) leafHash(e *entity) (string, error) {
 	// gets initial credential from the store
 	// *initial - a credential that was sent to VCT.
 	vc, err := c.vcStore.Get(e.CredentialID)
 	if err != nil {
-		return fmt.Errorf("get credential %q: %w", e.CredentialID, err)
+		return "", fmt.Errorf("get credential %q: %w", e.CredentialID, err)
 	}
 
 	// calculates leaf hash for given timestamp and initial credential to be able query proof by hash.
 	hash, err := vct.CalculateLeafHash(uint64(e.Created.UnixNano()/int64(time.Millisecond)), vc)
 	if err != nil {
-		return fmt.Errorf("calculate leaf hash: %w", err)
+		return "", fmt.Errorf("calculate leaf hash: %w", err)
 	}
 
-	// gets latest signed tree head to get the latest tree size.
-	sth, err := vctClient.GetSTH(context.Background())
-	if err != nil {
-		return fmt.Errorf("get STH: %w", err)
-	}
+	return hash, nil
+}
 
+// verifyInclusion retrieves the inclusion proof of the leaf with the given hash from the log and verifies
+// it against the given (verified) signed tree head.
+func (c *Client) verifyInclusion(client vctClient, e *entity, hash string, sth *command.GetSTHResponse) error {
 	// gets proof by hash
-	resp, err := vctClient.GetProofByHash(context.Background(), hash, sth.TreeSize)
+	resp, err := client.GetProofByHash(context.Background(), hash, sth.TreeSize)
 	if err != nil {
 		return fmt.Errorf("get proof by hash: %w", err)
 	}
 
-	// checks that audit path it not zero
-	if len(resp.AuditPath) < 1 {
-		return errors.New("audit path cannot be zero")
-	}
-
-	return nil
-}
-
-func (c *Client) worker() {
-	for range c.ticker.C {
-		if err := c.handleEntities(); err != nil {
-			logger.Errorf("handle entities: %v", err)
-		}
+	leafHash, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return fmt.Errorf("decode leaf hash: %w", err)
 	}
-}
-
-// Close stops ticker.
-func (c *Client) Close() {
-	c.ticker.Stop()
-}
 
-// Next is helper function that simplifies the usage of the iterator.
-func Next(records interface{ Next() (bool, error) }) bool {
-	ok, err := records.Next()
+	// recomputes the root hash from the audit path and checks it against the signed tree head.
+	err = logverifier.New(hasher.DefaultHasher).VerifyInclusionProof(resp.LeafIndex, int64(sth.TreeSize),
+		resp.AuditPath, sth.SHA256RootHash, leafHash)
 	if err != nil {
-		logger.Errorf("next entity: %v", err)
-
-		return false
+		return fmt.Errorf("%w: verify inclusion proof of credential %q in log %s: %s",
+			ErrInvalidProof, e.CredentialID, e.Domain, err)
 	}
 
-	return ok
+	return c.putInclusionProof(&InclusionProof{
+		CredentialID: e.CredentialID,
+		Domain:       e.Domain,
+		LeafIndex:    resp.LeafIndex,
+		AuditPath:    resp.AuditPath,
+		STH:          sth,
+	})
 }
 
-func (c *Client) handleEntities() error {
-	records, err := c.store.Query(tagNotConfirmed)
+// GetInclusionProofs returns the verified inclusion proofs of the given anchor credential.
+func (c *Client) GetInclusionProofs(anchorCredID string) ([]*InclusionProof, error) {
+	records, err := c.store.Query(fmt.Sprintf("%s:%s", tagCredential, credentialTag(anchorCredID)))
 	if err != nil {
-		return fmt.Errorf("query %q entities: %w", tagNotConfirmed, err)
+		return nil, fmt.Errorf("query inclusion proofs: %w", err)
 	}
 
 	defer storage.Close(records, logger)
 
-	for Next(records) {
-		var src []byte
+	var proofs []*InclusionProof
 
-		if src, err = records.Value(); err != nil {
-			return fmt.Errorf("get entity value: %w", err)
+	for Next(records) {
+		src, err := records.Value()
+		if err != nil {
+			return nil, fmt.Errorf("get inclusion proof value: %w", err)
 		}
 
-		var e *entity
-		if err = json.Unmarshal(src, &e); err != nil {
-			logger.Errorf("unmarshal entity: %v", err)
+		p := &InclusionProof{}
 
-			continue
+		if err := json.Unmarshal(src, p); err != nil {
+			return nil, fmt.Errorf("unmarshal inclusion proof: %w", err)
 		}
 
-		err = c.exist(e)
-		if err == nil {
-			logger.Infof("credential %q existence in the Merkle tree confirmed", e.CredentialID)
+		proofs = append(proofs, p)
+	}
 
-			// removes the entity from the store bc we confirmed that credential is in MT (log above).
-			if err = c.store.Delete(key(e.CredentialID)); err != nil {
-				logger.Errorf("delete credential %q from queue: %v", e.CredentialID, err)
-			}
+	return proofs, nil
+}
 
-			continue
-		}
+func (c *Client) putInclusionProof(p *InclusionProof) error {
+	src, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal inclusion proof: %w", err)
+	}
 
-		if !errors.Is(err, errExpired) {
-			logger.Warnf("credential %q existence: %v", e.CredentialID, err)
+	err = c.store.Put(proofKeyPrefix+p.CredentialID+"-"+p.Domain, src,
+		storage.Tag{Name: tagCredential, Value: credentialTag(p.CredentialID)})
+	if err != nil {
+		return fmt.Errorf("store inclusion proof: %w", err)
+	}
 
-			continue
-		}
+	return nil
+}
 
-		logger.Errorf("credential %q existence in the Merkle tree not confirmed", e.CredentialID)
+// Next is helper function that simplifies the usage of the iterator.
+func Next(records interface{ Next() (bool, error) }) bool {
+	ok, err := records.Next()
+	if err != nil {
+		logger.Errorf("next entity: %v", err)
 
-		// removes entity from the store bc we failed our promise (log above).
-		if err = c.store.Delete(key(e.CredentialID)); err != nil {
-			logger.Errorf("delete credential %q from queue: %v", e.CredentialID, err)
-		}
+		return false
 	}
 
-	return nil
+	return ok
 }
 
-// Watch starts monitoring.
-func (c *Client) Watch(anchorCredID string, endTime time.Time, proof []byte) error {
+// Watch starts monitoring. The witness is the actor that provided the proof and is used to
+// keep track of broken promises. It may be nil if the witness is not known.
+func (c *Client) Watch(witness *url.URL, anchorCredID string, endTime time.Time, proof []byte) error {
 	var p *Proof
 
 	if err := json.Unmarshal(proof, &p); err != nil {
 		return fmt.Errorf("unmarshal proof: %w", err)
 	}
 
+	if err := c.verifyDomain(witness, p.Data.Domain); err != nil {
+		return err
+	}
+
 	e := &entity{
 		CredentialID:   anchorCredID,
 		ExpirationDate: endTime,
-		// TODO: domain probably needs to be discovered by using a web finger.
-		Domain:  p.Data.Domain,
-		Created: p.Data.Created,
+		Domain:         p.Data.Domain,
+		Created:        p.Data.Created,
+	}
+
+	if witness != nil {
+		e.Witness = witness.String()
 	}
 
 	err := c.exist(e)
@@ -239,6 +321,8 @@ func (c *Client) Watch(anchorCredID string, endTime time.Time, proof []byte) err
 	if err == nil {
 		logger.Infof("credential %q existence in the Merkle tree confirmed", e.CredentialID)
 
+		c.promiseKept(e)
+
 		return nil
 	}
 
@@ -252,7 +336,7 @@ func (c *Client) Watch(anchorCredID string, endTime time.Time, proof []byte) err
 
 	// if error is errExpired no need to put data in the queue.
 	if errors.Is(err, errExpired) {
-		logger.Errorf("credential %q existence in the Merkle tree not confirmed", e.CredentialID)
+		c.promiseBroken(e)
 
 		return err
 	}
@@ -260,15 +344,56 @@ func (c *Client) Watch(anchorCredID string, endTime time.Time, proof []byte) err
 	logger.Warnf("credential %q existence: %v", e.CredentialID, err)
 	logger.Warnf("credential %q is not in the Merkle tree yet, entity will escape to the queue", e.CredentialID)
 
-	src, err := json.Marshal(e)
+	// puts data in the queue, the entity will be picked and checked by the worker later.
+	return c.schedule(e)
+}
+
+// verifyDomain ensures that the given domain (of a proof) is the log of the witness. If the witness doesn't
+// publish its log (e.g. an older version) then the domain of the proof is trusted.
+func (c *Client) verifyDomain(witness *url.URL, domain string) error {
+	if c.logResolver == nil || witness == nil {
+		return nil
+	}
+
+	endpoint, err := c.logResolver.GetLogEndpoint(witness)
 	if err != nil {
-		return fmt.Errorf("marshal entity: %w", err)
+		if errors.Is(err, discoveryclient.ErrNotFound) {
+			logger.Warnf("Unable to discover the log of witness %s. Trusting domain %s of the proof: %v",
+				witness, domain, err)
+
+			return nil
+		}
+
+		if errors.Is(err, discoveryclient.ErrPublicKeyMismatch) {
+			return fmt.Errorf("%w: resolve log of witness %s: %s", ErrInvalidProof, witness, err)
+		}
+
+		return fmt.Errorf("resolve log of witness %s: %w", witness, err)
 	}
 
-	// puts data in the queue, the entity will be picked and checked by the worker later.
-	return c.store.Put(key(e.CredentialID), src, storage.Tag{Name: tagNotConfirmed})
+	if normalizeURL(endpoint.URL) != normalizeURL(domain) {
+		return fmt.Errorf("%w: domain %s of the proof is not the log of witness %s (%s)",
+			ErrInvalidProof, domain, witness, endpoint.URL)
+	}
+
+	return nil
 }
 
-func key(id string) string {
-	return keyPrefix + id
+func normalizeURL(u string) string {
+	return strings.TrimSuffix(u, "/")
+}
+
+// key returns the queue key of the entity. The domain is part of the key since the same credential
+// may be watched in the logs of multiple witnesses. Entities queued by previous versions are keyed by
+// credential ID only and are migrated when they're read from the queue.
+func key(e *entity) string {
+	return keyPrefix + e.CredentialID + "-" + e.Domain
+}
+
+// credentialTag returns the tag value for the given credential ID. The ID is hashed since tag values
+// may not contain all of the characters of a URL.
+func credentialTag(id string) string {
+	hash := sha256.Sum256([]byte(id))
+
+	return hex.EncodeToString(hash[:])
 }
@@ -8,25 +8,41 @@ package monitoring_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	mockstore "github.com/hyperledger/aries-framework-go/component/storageutil/mock"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
 
 	. "github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
+	discoveryclient "github.com/trustbloc/orb/pkg/discovery/endpoint/client"
 	"github.com/trustbloc/orb/pkg/internal/testutil"
 	vcstore "github.com/trustbloc/orb/pkg/store/verifiable"
+	"github.com/trustbloc/orb/pkg/vcsigner"
+	"github.com/trustbloc/orb/pkg/vctlog"
 )
 
 const (
@@ -34,6 +50,8 @@ const (
 	tagNotConfirmed = "not_confirmed"
 )
 
+var witnessIRI = testutil.MustParseURL("https://witness.domain.com/services/orb")
+
 func TestNew(t *testing.T) {
 	client, err := New(mem.NewProvider(), nil)
 	require.NoError(t, err)
@@ -62,7 +80,7 @@ func TestClient_Watch(t *testing.T) {
 		client, err := New(mem.NewProvider(), nil)
 		require.NoError(t, err)
 
-		require.EqualError(t, client.Watch(vcID,
+		require.EqualError(t, client.Watch(witnessIRI, vcID,
 			time.Now().Add(-time.Minute),
 			[]byte(`{}`),
 		), "expired")
@@ -72,7 +90,7 @@ func TestClient_Watch(t *testing.T) {
 		client, err := New(mem.NewProvider(), nil)
 		require.NoError(t, err)
 
-		require.Contains(t, client.Watch(vcID,
+		require.Contains(t, client.Watch(witnessIRI, vcID,
 			time.Now().Add(-time.Minute),
 			[]byte(`[]`),
 		).Error(), "json: cannot unmarshal array into Go value ")
@@ -85,7 +103,7 @@ func TestClient_Watch(t *testing.T) {
 		client, err := New(mem.NewProvider(), vStore)
 		require.NoError(t, err)
 
-		require.EqualError(t, client.Watch(vcID,
+		require.EqualError(t, client.Watch(witnessIRI, vcID,
 			time.Now().Add(time.Minute),
 			[]byte(`{}`),
 		), "get credential \"id\": failed to get vc: data not found")
@@ -111,7 +129,7 @@ func TestClient_Watch(t *testing.T) {
 			Types:   []string{"VerifiableCredential"},
 		}))
 
-		require.NoError(t, client.Watch(ID1,
+		require.NoError(t, client.Watch(witnessIRI, ID1,
 			time.Now().Add(time.Minute),
 			[]byte(`{}`),
 		))
@@ -125,7 +143,7 @@ func TestClient_Watch(t *testing.T) {
 			Types:   []string{"VerifiableCredential"},
 		}))
 
-		require.NoError(t, client.Watch(ID2,
+		require.NoError(t, client.Watch(witnessIRI, ID2,
 			time.Now().Add(time.Minute),
 			[]byte(`{}`),
 		))
@@ -164,7 +182,7 @@ func TestClient_Watch(t *testing.T) {
 			Types:   []string{"VerifiableCredential"},
 		}))
 
-		require.NoError(t, client.Watch(ID,
+		require.NoError(t, client.Watch(witnessIRI, ID,
 			time.Now().Add(time.Minute),
 			[]byte(`{}`),
 		))
@@ -196,7 +214,7 @@ func TestClient_Watch(t *testing.T) {
 			Types:   []string{"VerifiableCredential"},
 		}))
 
-		require.NoError(t, client.Watch(ID,
+		require.NoError(t, client.Watch(witnessIRI, ID,
 			time.Now().Add(time.Minute),
 			[]byte(`{}`),
 		))
@@ -221,35 +239,30 @@ func TestClient_Watch(t *testing.T) {
 		vStore, err := vcstore.New(db, testutil.GetLoader(t))
 		require.NoError(t, err)
 
-		responses := make(chan string, 4)
-		responses <- `{}`
-		responses <- `{}`
-		responses <- `{}`
-		responses <- `{"audit_path":[[]]}`
+		var unavailable int32 = 1
 
-		client, err := New(db, vStore, WithHTTPClient(httpMock(func(req *http.Request) (*http.Response, error) {
-			return &http.Response{
-				Body:       ioutil.NopCloser(bytes.NewBufferString(<-responses)),
-				StatusCode: http.StatusOK,
-			}, nil
-		})))
+		vctLog := newTestLog(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			// The log is unavailable when the credential is first watched.
+			if atomic.LoadInt32(&unavailable) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient),
+			WithPollInterval(500*time.Millisecond, time.Second))
 		require.NoError(t, err)
 
 		ID := "https://orb.domain.com/" + uuid.New().String()
 
-		require.NoError(t, vStore.Put(&verifiable.Credential{
-			ID:      ID,
-			Context: []string{"https://www.w3.org/2018/credentials/v1"},
-			Subject: ID,
-			Issuer:  verifiable.Issuer{ID: ID},
-			Issued:  &util.TimeWithTrailingZeroMsec{},
-			Types:   []string{"VerifiableCredential"},
-		}))
+		proof := witness(t, vctLog, vStore, ID)
 
-		require.NoError(t, client.Watch(ID,
-			time.Now().Add(time.Minute),
-			[]byte(`{}`),
-		))
+		require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), proof))
+
+		atomic.StoreInt32(&unavailable, 0)
 
 		require.NoError(t, backoff.Retry(func() error {
 			select {
@@ -275,6 +288,10 @@ func TestClient_Watch(t *testing.T) {
 			return nil
 		}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), 3)))
 		checkQueue(t, db, 0)
+
+		proofs, err := client.GetInclusionProofs(ID)
+		require.NoError(t, err)
+		require.Len(t, proofs, 1)
 	})
 
 	t.Run("Worker handles queue (expired)", func(t *testing.T) {
@@ -318,7 +335,7 @@ func TestClient_Watch(t *testing.T) {
 			Types:   []string{"VerifiableCredential"},
 		}))
 
-		require.NoError(t, client.Watch(ID,
+		require.NoError(t, client.Watch(witnessIRI, ID,
 			time.Now().Add(time.Millisecond*100),
 			[]byte(`{}`),
 		))
@@ -347,29 +364,243 @@ func TestClient_Watch(t *testing.T) {
 		vStore, err := vcstore.New(db, testutil.GetLoader(t))
 		require.NoError(t, err)
 
-		client, err := New(db, vStore, WithHTTPClient(httpMock(func(req *http.Request) (*http.Response, error) {
-			return &http.Response{
-				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"audit_path":[[]]}`)),
-				StatusCode: http.StatusOK,
-			}, nil
-		})))
+		vctLog := newTestLog(t, nil)
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient))
 		require.NoError(t, err)
 
 		ID := "https://orb.domain.com/" + uuid.New().String()
 
-		require.NoError(t, vStore.Put(&verifiable.Credential{
-			ID:      ID,
-			Context: []string{"https://www.w3.org/2018/credentials/v1"},
-			Subject: ID,
-			Issuer:  verifiable.Issuer{ID: ID},
-			Issued:  &util.TimeWithTrailingZeroMsec{},
-			Types:   []string{"VerifiableCredential"},
-		}))
+		require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
 
-		require.NoError(t, client.Watch(ID,
-			time.Now().Add(time.Minute),
-			[]byte(`{}`),
-		))
+		checkQueue(t, db, 0)
+
+		proofs, err := client.GetInclusionProofs(ID)
+		require.NoError(t, err)
+		require.Len(t, proofs, 1)
+		require.Equal(t, ID, proofs[0].CredentialID)
+		require.Equal(t, vctLog.Endpoint, proofs[0].Domain)
+		require.Equal(t, uint64(1), proofs[0].STH.TreeSize)
+		require.Empty(t, proofs[0].AuditPath)
+
+		// Add more credentials so that the tree grows (and the STHs are checked for consistency).
+		for i := 0; i < 4; i++ {
+			ID := "https://orb.domain.com/" + uuid.New().String()
+
+			require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
+
+			proofs, err := client.GetInclusionProofs(ID)
+			require.NoError(t, err)
+			require.Len(t, proofs, 1)
+			require.Equal(t, int64(i+1), proofs[0].LeafIndex)
+			require.Equal(t, uint64(i+2), proofs[0].STH.TreeSize)
+		}
+
+		checkQueue(t, db, 0)
+	})
+}
+
+func TestClient_Verification(t *testing.T) {
+	t.Run("Invalid STH signature", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		var vctLog *testLog
+
+		vctLog = newTestLog(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			if r.URL.Path == rest.GetSTHPath {
+				sth, err := vctLog.GetSTH()
+				require.NoError(t, err)
+
+				sth.Timestamp++
+
+				require.NoError(t, json.NewEncoder(w).Encode(sth))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient))
+		require.NoError(t, err)
+
+		ID := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
+
+		// The proof couldn't be verified so the credential is queued.
+		checkQueue(t, db, 1)
+
+		proofs, err := client.GetInclusionProofs(ID)
+		require.NoError(t, err)
+		require.Empty(t, proofs)
+	})
+
+	t.Run("Invalid audit path", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		vctLog := newTestLog(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			if r.URL.Path == rest.GetProofByHashPath {
+				require.NoError(t, json.NewEncoder(w).Encode(&command.GetProofByHashResponse{
+					AuditPath: [][]byte{[]byte("invalid")},
+				}))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient))
+		require.NoError(t, err)
+
+		// Add two credentials so that the valid audit path isn't empty.
+		ID1 := "https://orb.domain.com/" + uuid.New().String()
+		witness(t, vctLog, vStore, ID1)
+
+		ID2 := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID2, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID2)))
+
+		checkQueue(t, db, 1)
+
+		proofs, err := client.GetInclusionProofs(ID2)
+		require.NoError(t, err)
+		require.Empty(t, proofs)
+	})
+
+	t.Run("Inconsistent log", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		var forked int32
+
+		var forkedLog *vctlog.Log
+
+		vctLog := newTestLog(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			if atomic.LoadInt32(&forked) == 1 && r.URL.Path != rest.GetPublicKeyPath {
+				// Serve the (forked) log that has a different history.
+				for _, h := range forkedLog.HTTPHandlers() {
+					if h.Path() == r.URL.Path {
+						h.Handler()(w, r)
+
+						return
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		// The forked log is signed with the same key but contains different credentials.
+		forkedLog, err = vctlog.New(&vctlog.Config{
+			Endpoint:        vctLog.Endpoint,
+			KeyID:           vctLog.KeyID,
+			DocumentLoader:  testutil.GetLoader(t),
+			ClaimSettleTime: time.Millisecond,
+		}, mem.NewProvider(), vctLog.km, vctLog.cr, &mockSigner{})
+		require.NoError(t, err)
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient))
+		require.NoError(t, err)
+
+		ID1 := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID1, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID1)))
+		checkQueue(t, db, 0)
+
+		for i := 0; i < 3; i++ {
+			witness(t, &testLog{Log: forkedLog}, vStore, "https://orb.domain.com/"+uuid.New().String())
+		}
+
+		atomic.StoreInt32(&forked, 1)
+
+		ID2 := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID2, time.Now().Add(time.Minute),
+			witness(t, &testLog{Log: forkedLog}, vStore, ID2)))
+
+		// The STH of the forked log isn't consistent with the STH previously returned by the log.
+		checkQueue(t, db, 1)
+
+		proofs, err := client.GetInclusionProofs(ID2)
+		require.NoError(t, err)
+		require.Empty(t, proofs)
+	})
+
+	t.Run("Slow log doesn't block other logs", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		var blocked int32
+
+		requested := make(chan struct{}, 1)
+		release := make(chan struct{})
+
+		slowLog := newTestLog(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			if atomic.LoadInt32(&blocked) == 1 && r.URL.Path == rest.GetSTHConsistencyPath {
+				requested <- struct{}{}
+
+				<-release
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		otherLog := newTestLog(t, nil)
+
+		// Unblock the slow log before the test servers are closed, including when the test fails.
+		var releaseOnce sync.Once
+
+		unblock := func() { releaseOnce.Do(func() { close(release) }) }
+		defer unblock()
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient))
+		require.NoError(t, err)
+
+		ID1 := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID1, time.Now().Add(time.Minute), witness(t, slowLog, vStore, ID1)))
+
+		atomic.StoreInt32(&blocked, 1)
+
+		ID2 := "https://orb.domain.com/" + uuid.New().String()
+		proof2 := witness(t, slowLog, vStore, ID2)
+
+		slowDone := make(chan error, 1)
+
+		go func() {
+			slowDone <- client.Watch(witnessIRI, ID2, time.Now().Add(time.Minute), proof2)
+		}()
+
+		// Wait until the consistency proof is requested from the slow log.
+		<-requested
+
+		ID3 := "https://orb.domain.com/" + uuid.New().String()
+		proof3 := witness(t, otherLog, vStore, ID3)
+
+		otherDone := make(chan error, 1)
+
+		go func() {
+			otherDone <- client.Watch(witnessIRI, ID3, time.Now().Add(time.Minute), proof3)
+		}()
+
+		select {
+		case err := <-otherDone:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("verification of the other log was blocked by the slow log")
+		}
+
+		unblock()
+
+		require.NoError(t, <-slowDone)
 
 		checkQueue(t, db, 0)
 	})
@@ -415,6 +646,7 @@ type dbMockStore struct {
 	mu        *sync.Mutex
 	errQuery  func() error
 	errDelete func() error
+	queries   int32
 }
 
 func (m *dbMockStore) Delete(key string) error {
@@ -429,6 +661,8 @@ func (m *dbMockStore) Delete(key string) error {
 }
 
 func (m *dbMockStore) Query(expression string, options ...storage.QueryOption) (storage.Iterator, error) {
+	atomic.AddInt32(&m.queries, 1)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -458,3 +692,231 @@ type mockNext struct{ err error }
 func (m *mockNext) Next() (bool, error) {
 	return true, m.err
 }
+
+type testLog struct {
+	*vctlog.Log
+
+	km kms.KeyManager
+	cr *tinkcrypto.Crypto
+}
+
+// newTestLog returns a transparency log that is served by a test HTTP server. The optional middleware
+// may be used to intercept the requests to the log.
+func newTestLog(t *testing.T, middleware func(w http.ResponseWriter, r *http.Request, next http.Handler)) *testLog {
+	t.Helper()
+
+	km, err := localkms.New("local-lock://custom/primary/key/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	keyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+
+	var handler http.Handler = router
+
+	if middleware != nil {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middleware(w, r, router)
+		})
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	l, err := vctlog.New(&vctlog.Config{
+		Endpoint:        server.URL,
+		KeyID:           keyID,
+		DocumentLoader:  testutil.GetLoader(t),
+		ClaimSettleTime: time.Millisecond,
+	}, mem.NewProvider(), km, cr, &mockSigner{})
+	require.NoError(t, err)
+
+	for _, h := range l.HTTPHandlers() {
+		router.HandleFunc(h.Path(), h.Handler()).Methods(h.Method())
+	}
+
+	return &testLog{Log: l, km: km, cr: cr}
+}
+
+// witness stores a new credential with the given ID and adds it to the log. The log's proof is returned.
+func witness(t *testing.T, l *testLog, vStore *vcstore.Store, id string) []byte {
+	t.Helper()
+
+	vc := &verifiable.Credential{
+		ID:      id,
+		Context: []string{"https://www.w3.org/2018/credentials/v1"},
+		Subject: id,
+		Issuer:  verifiable.Issuer{ID: id},
+		Issued:  &util.TimeWithTrailingZeroMsec{},
+		Types:   []string{"VerifiableCredential"},
+	}
+
+	require.NoError(t, vStore.Put(vc))
+
+	vcBytes, err := json.Marshal(vc)
+	require.NoError(t, err)
+
+	proof, err := l.Witness(vcBytes)
+	require.NoError(t, err)
+
+	return proof
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (p *kmsProvider) StorageProvider() storage.Provider {
+	return p.storageProvider
+}
+
+func (p *kmsProvider) SecretLock() secretlock.Service {
+	return p.secretLock
+}
+
+type mockSigner struct{}
+
+func (m *mockSigner) Sign(vc *verifiable.Credential, opts ...vcsigner.Opt) (*verifiable.Credential, error) {
+	ctx := &verifiable.LinkedDataProofContext{}
+
+	for _, opt := range opts {
+		opt(ctx)
+	}
+
+	vc.Proofs = append(vc.Proofs, map[string]interface{}{
+		"created": ctx.Created.Format(time.RFC3339Nano),
+		"domain":  ctx.Domain,
+	})
+
+	return vc, nil
+}
+
+func TestClient_LogResolver(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		vctLog := newTestLog(t, nil)
+
+		resolver := &mockLogResolver{endpoint: vctLog.Endpoint + "/", pubKey: vctLog.GetPublicKey()}
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient), WithLogResolver(resolver))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		ID := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
+
+		checkQueue(t, db, 0)
+		require.Equal(t, int32(1), atomic.LoadInt32(&resolver.keyRequests))
+	})
+
+	t.Run("Domain is not the log of the witness", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		vctLog := newTestLog(t, nil)
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient),
+			WithLogResolver(&mockLogResolver{endpoint: "https://vct.other.com"}))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		ID := "https://orb.domain.com/" + uuid.New().String()
+
+		err = client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrInvalidProof))
+		require.Contains(t, err.Error(), "is not the log of witness")
+
+		checkQueue(t, db, 0)
+
+		// The domain isn't checked if the witness is unknown.
+		require.NoError(t, client.Watch(nil, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
+	})
+
+	t.Run("Resolver error", func(t *testing.T) {
+		client, err := New(mem.NewProvider(), nil,
+			WithLogResolver(&mockLogResolver{err: errors.New("injected resolver error")}))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		err = client.Watch(witnessIRI, "https://orb.domain.com/vc1", time.Now().Add(time.Minute),
+			[]byte(`{"proof":{"domain":"https://vct.domain.com"}}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected resolver error")
+		require.False(t, errors.Is(err, ErrInvalidProof))
+	})
+
+	t.Run("Witness doesn't publish its log", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		vctLog := newTestLog(t, nil)
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient),
+			WithLogResolver(&mockLogResolver{
+				err:    fmt.Errorf("discover log: %w", discoveryclient.ErrNotFound),
+				pubKey: vctLog.GetPublicKey(),
+			}))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		ID := "https://orb.domain.com/" + uuid.New().String()
+
+		// The domain of the proof is trusted.
+		require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
+
+		checkQueue(t, db, 0)
+	})
+
+	t.Run("Public key mismatch", func(t *testing.T) {
+		client, err := New(mem.NewProvider(), nil,
+			WithLogResolver(&mockLogResolver{err: fmt.Errorf("resolve: %w", discoveryclient.ErrPublicKeyMismatch)}))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		err = client.Watch(witnessIRI, "https://orb.domain.com/vc1", time.Now().Add(time.Minute),
+			[]byte(`{"proof":{"domain":"https://vct.domain.com"}}`))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrInvalidProof))
+	})
+}
+
+type mockLogResolver struct {
+	endpoint    string
+	pubKey      []byte
+	err         error
+	keyRequests int32
+}
+
+func (m *mockLogResolver) GetLogEndpoint(*url.URL) (*discoveryclient.LogEndpoint, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &discoveryclient.LogEndpoint{URL: m.endpoint, PublicKey: m.pubKey}, nil
+}
+
+func (m *mockLogResolver) GetLogPublicKey(string) ([]byte, error) {
+	atomic.AddInt32(&m.keyRequests, 1)
+
+	return m.pubKey, nil
+}
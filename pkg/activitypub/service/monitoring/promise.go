@@ -0,0 +1,338 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package monitoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+const (
+	brokenKeyPrefix    = "broken-"
+	statsKeyPrefix     = "stats-"
+	logStatsPrefix     = statsKeyPrefix + "log-"
+	witnessStatsPrefix = statsKeyPrefix + "witness-"
+	tagLog             = "log"
+)
+
+// BrokenPromise is recorded when a witness promised (by way of a proof) to include an anchor credential in its log
+// but the inclusion of the credential could not be confirmed before the promise expired. It is also recorded
+// when the proof provided by a witness is invalid, in which case Reason contains the verification error.
+type BrokenPromise struct {
+	CredentialID   string    `json:"credential_id"`
+	Domain         string    `json:"domain"`
+	Witness        string    `json:"witness,omitempty"`
+	Created        time.Time `json:"created"`
+	ExpirationDate time.Time `json:"expiration_date"`
+	DetectedAt     time.Time `json:"detected_at"`
+	Reason         string    `json:"reason,omitempty"`
+}
+
+// BrokenPromiseHandler is invoked when a broken promise is detected.
+type BrokenPromiseHandler func(bp *BrokenPromise)
+
+// Reliability contains the number of promises that were kept and broken by a witness (or witness log).
+type Reliability struct {
+	Confirmed uint64 `json:"confirmed"`
+	Broken    uint64 `json:"broken"`
+}
+
+// Score returns the ratio of kept promises to all promises, in the range [0, 1]. A witness without any
+// history has a score of 1.
+func (r *Reliability) Score() float64 {
+	total := r.Confirmed + r.Broken
+	if total == 0 {
+		return 1
+	}
+
+	return float64(r.Confirmed) / float64(total)
+}
+
+// WithBrokenPromiseHandler registers a handler that is invoked when a broken promise is detected.
+// This option may be specified multiple times.
+func WithBrokenPromiseHandler(handler BrokenPromiseHandler) Opt {
+	return func(o *Client) {
+		o.brokenPromiseHandlers = append(o.brokenPromiseHandlers, handler)
+	}
+}
+
+// LogReliability returns the reliability of the witness log with the given domain.
+func (c *Client) LogReliability(domain string) (*Reliability, error) {
+	return c.getReliability(logStatsPrefix + domain)
+}
+
+// WitnessReliability returns the reliability of the given witness.
+func (c *Client) WitnessReliability(witness *url.URL) (*Reliability, error) {
+	return c.getReliability(witnessStatsPrefix + witness.String())
+}
+
+// GetBrokenPromises returns the promises that were broken by the witness log with the given domain.
+func (c *Client) GetBrokenPromises(domain string) ([]*BrokenPromise, error) {
+	records, err := c.store.Query(fmt.Sprintf("%s:%s", tagLog, credentialTag(domain)))
+	if err != nil {
+		return nil, fmt.Errorf("query broken promises: %w", err)
+	}
+
+	defer storage.Close(records, logger)
+
+	var promises []*BrokenPromise
+
+	for Next(records) {
+		src, err := records.Value()
+		if err != nil {
+			return nil, fmt.Errorf("get broken promise value: %w", err)
+		}
+
+		bp := &BrokenPromise{}
+
+		if err := json.Unmarshal(src, bp); err != nil {
+			return nil, fmt.Errorf("unmarshal broken promise: %w", err)
+		}
+
+		promises = append(promises, bp)
+	}
+
+	return promises, nil
+}
+
+// RecordInvalidProof records a proof of the given witness that failed verification as a broken promise and
+// notifies the registered handlers. Only the statistics of the witness are updated since the log referenced
+// by an invalid proof can't be trusted.
+func (c *Client) RecordInvalidProof(witness *url.URL, anchorCredID string, proof []byte, reason error) {
+	bp := &BrokenPromise{
+		CredentialID: anchorCredID,
+		DetectedAt:   time.Now(),
+		Reason:       reason.Error(),
+	}
+
+	var p *Proof
+
+	if err := json.Unmarshal(proof, &p); err == nil && p != nil {
+		bp.Domain = p.Data.Domain
+		bp.Created = p.Data.Created
+	}
+
+	var keys []string
+
+	if witness != nil {
+		bp.Witness = witness.String()
+
+		keys = append(keys, witnessStatsPrefix+bp.Witness)
+	}
+
+	logger.Errorf("invalid proof for credential %q from witness [%s]: %s", anchorCredID, bp.Witness, bp.Reason)
+
+	c.breakPromise(bp, keys)
+}
+
+// promiseKept updates the statistics of the witness (and its log) that kept its promise.
+func (c *Client) promiseKept(e *entity) {
+	c.updateStats(statsKeys(e), func(r *Reliability) { r.Confirmed++ })
+}
+
+// promiseBroken records the broken promise, updates the statistics of the witness (and its log) and
+// notifies the registered handlers.
+func (c *Client) promiseBroken(e *entity) {
+	logger.Errorf("credential %q existence in the Merkle tree not confirmed: witness [%s] broke its promise",
+		e.CredentialID, e.Witness)
+
+	c.breakPromise(&BrokenPromise{
+		CredentialID:   e.CredentialID,
+		Domain:         e.Domain,
+		Witness:        e.Witness,
+		Created:        e.Created,
+		ExpirationDate: e.ExpirationDate,
+		DetectedAt:     time.Now(),
+	}, statsKeys(e))
+}
+
+// breakPromise stores the broken promise, updates the given statistics and notifies the registered handlers.
+func (c *Client) breakPromise(bp *BrokenPromise, keys []string) {
+	if err := c.putBrokenPromise(bp); err != nil {
+		logger.Errorf("record broken promise for credential %q: %v", bp.CredentialID, err)
+	}
+
+	c.updateStats(keys, func(r *Reliability) { r.Broken++ })
+
+	for _, handle := range c.brokenPromiseHandlers {
+		handle(bp)
+	}
+}
+
+func (c *Client) putBrokenPromise(bp *BrokenPromise) error {
+	src, err := json.Marshal(bp)
+	if err != nil {
+		return fmt.Errorf("marshal broken promise: %w", err)
+	}
+
+	err = c.store.Put(brokenKeyPrefix+bp.CredentialID+"-"+bp.Domain, src,
+		storage.Tag{Name: tagLog, Value: credentialTag(bp.Domain)})
+	if err != nil {
+		return fmt.Errorf("store broken promise: %w", err)
+	}
+
+	return nil
+}
+
+// statsKeys returns the keys of the statistics of the log and the witness of the given entity.
+func statsKeys(e *entity) []string {
+	keys := []string{logStatsPrefix + e.Domain}
+
+	if e.Witness != "" {
+		keys = append(keys, witnessStatsPrefix+e.Witness)
+	}
+
+	return keys
+}
+
+func (c *Client) updateStats(keys []string, update func(r *Reliability)) {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	for _, k := range keys {
+		r, err := c.getReliability(k)
+		if err != nil {
+			logger.Errorf("update statistics %s: %v", k, err)
+
+			continue
+		}
+
+		update(r)
+
+		src, err := json.Marshal(r)
+		if err != nil {
+			logger.Errorf("marshal statistics %s: %v", k, err)
+
+			continue
+		}
+
+		if err := c.store.Put(k, src); err != nil {
+			logger.Errorf("store statistics %s: %v", k, err)
+		}
+	}
+}
+
+func (c *Client) getReliability(k string) (*Reliability, error) {
+	src, err := c.store.Get(k)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return &Reliability{}, nil
+		}
+
+		return nil, fmt.Errorf("get statistics: %w", err)
+	}
+
+	r := &Reliability{}
+
+	if err := json.Unmarshal(src, r); err != nil {
+		return nil, fmt.Errorf("unmarshal statistics: %w", err)
+	}
+
+	return r, nil
+}
+
+// NewWebhookHandler returns a broken promise handler that posts the broken promise (as JSON) to the given URL.
+func NewWebhookHandler(webhookURL string, client HTTPClient) BrokenPromiseHandler {
+	return func(bp *BrokenPromise) {
+		if err := postWebhook(webhookURL, client, bp); err != nil {
+			logger.Errorf("notify webhook %s of broken promise for credential %q: %v",
+				webhookURL, bp.CredentialID, err)
+		}
+	}
+}
+
+func postWebhook(webhookURL string, client HTTPClient, bp *BrokenPromise) error {
+	src, err := json.Marshal(bp)
+	if err != nil {
+		return fmt.Errorf("marshal broken promise: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(src))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Warnf("close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Outbox is used to post activities to the witness.
+type Outbox interface {
+	Post(activity *vocab.ActivityType) (*url.URL, error)
+}
+
+// NewWitnessNotifier returns a broken promise handler that notifies the witness that broke its promise by
+// posting a 'Reject' activity whose object is the witness's 'Like' of the anchor credential. Broken promises
+// of the local witness (serviceIRI) are not posted. The outbox is resolved lazily since the monitoring client
+// is created before the ActivityPub service.
+func NewWitnessNotifier(serviceIRI *url.URL, outbox func() Outbox) BrokenPromiseHandler {
+	return func(bp *BrokenPromise) {
+		if bp.Witness == "" || bp.Witness == serviceIRI.String() {
+			return
+		}
+
+		if err := notifyWitness(outbox(), bp); err != nil {
+			logger.Errorf("notify witness [%s] of broken promise for credential %q: %v",
+				bp.Witness, bp.CredentialID, err)
+		}
+	}
+}
+
+func notifyWitness(ob Outbox, bp *BrokenPromise) error {
+	witnessIRI, err := url.Parse(bp.Witness)
+	if err != nil {
+		return fmt.Errorf("parse witness IRI: %w", err)
+	}
+
+	credIRI, err := url.Parse(bp.CredentialID)
+	if err != nil {
+		return fmt.Errorf("parse credential ID: %w", err)
+	}
+
+	like := vocab.NewLikeActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(credIRI)),
+		vocab.WithActor(witnessIRI),
+		vocab.WithStartTime(&bp.Created),
+		vocab.WithEndTime(&bp.ExpirationDate),
+	)
+
+	reject := vocab.NewRejectActivity(
+		vocab.NewObjectProperty(vocab.WithActivity(like)),
+		vocab.WithTo(witnessIRI),
+	)
+
+	if _, err := ob.Post(reject); err != nil {
+		return fmt.Errorf("post 'Reject' activity: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package monitoring_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+	vcstore "github.com/trustbloc/orb/pkg/store/verifiable"
+)
+
+func TestClient_BrokenPromise(t *testing.T) {
+	t.Run("Expired before watch", func(t *testing.T) {
+		var brokenPromises []*BrokenPromise
+
+		client, err := New(mem.NewProvider(), nil, WithBrokenPromiseHandler(func(bp *BrokenPromise) {
+			brokenPromises = append(brokenPromises, bp)
+		}))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		const domain = "https://vct.domain.com"
+
+		require.EqualError(t, client.Watch(witnessIRI, "https://orb.domain.com/vc1",
+			time.Now().Add(-time.Minute),
+			[]byte(`{"proof":{"domain":"`+domain+`"}}`),
+		), "expired")
+
+		require.Len(t, brokenPromises, 1)
+		require.Equal(t, "https://orb.domain.com/vc1", brokenPromises[0].CredentialID)
+		require.Equal(t, domain, brokenPromises[0].Domain)
+		require.Equal(t, witnessIRI.String(), brokenPromises[0].Witness)
+
+		promises, err := client.GetBrokenPromises(domain)
+		require.NoError(t, err)
+		require.Len(t, promises, 1)
+		require.Equal(t, "https://orb.domain.com/vc1", promises[0].CredentialID)
+
+		promises, err = client.GetBrokenPromises("https://other.domain.com")
+		require.NoError(t, err)
+		require.Empty(t, promises)
+
+		r, err := client.WitnessReliability(witnessIRI)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), r.Broken)
+		require.Equal(t, uint64(0), r.Confirmed)
+		require.Equal(t, float64(0), r.Score())
+
+		r, err = client.LogReliability(domain)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), r.Broken)
+	})
+
+	t.Run("Expired in queue", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		// The log never includes the credential.
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		brokenCh := make(chan *BrokenPromise, 1)
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient),
+			WithBrokenPromiseHandler(func(bp *BrokenPromise) { brokenCh <- bp }),
+		)
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		ID := "https://orb.domain.com/" + uuid.New().String()
+
+		// The credential is added to a different log than the one the proof points to.
+		witness(t, newTestLog(t, nil), vStore, ID)
+
+		proof := []byte(fmt.Sprintf(`{"proof":{"domain":%q,"created":%q}}`,
+			server.URL, time.Now().Format(time.RFC3339Nano)))
+
+		require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(1500*time.Millisecond), proof))
+
+		select {
+		case bp := <-brokenCh:
+			require.Equal(t, ID, bp.CredentialID)
+			require.Equal(t, server.URL, bp.Domain)
+			require.Equal(t, witnessIRI.String(), bp.Witness)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for broken promise")
+		}
+
+		checkQueue(t, db, 0)
+
+		r, err := client.LogReliability(server.URL)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), r.Broken)
+	})
+
+	t.Run("Promise kept", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		vctLog := newTestLog(t, nil)
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		for i := 0; i < 3; i++ {
+			ID := "https://orb.domain.com/" + uuid.New().String()
+
+			require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
+		}
+
+		r, err := client.WitnessReliability(witnessIRI)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), r.Confirmed)
+		require.Equal(t, uint64(0), r.Broken)
+		require.Equal(t, float64(1), r.Score())
+
+		r, err = client.LogReliability(vctLog.Endpoint)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), r.Confirmed)
+
+		r, err = client.WitnessReliability(testutil.MustParseURL("https://unknown.domain.com/services/orb"))
+		require.NoError(t, err)
+		require.Equal(t, float64(1), r.Score())
+	})
+
+	t.Run("Invalid proof", func(t *testing.T) {
+		var brokenPromises []*BrokenPromise
+
+		client, err := New(mem.NewProvider(), nil, WithBrokenPromiseHandler(func(bp *BrokenPromise) {
+			brokenPromises = append(brokenPromises, bp)
+		}))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		const domain = "https://vct.domain.com"
+
+		client.RecordInvalidProof(witnessIRI, "https://orb.domain.com/vc1",
+			[]byte(`{"proof":{"domain":"`+domain+`"}}`), errors.New("invalid signature"))
+		client.RecordInvalidProof(nil, "https://orb.domain.com/vc2", []byte(`{`), errors.New("invalid proof"))
+
+		require.Len(t, brokenPromises, 2)
+		require.Equal(t, "https://orb.domain.com/vc1", brokenPromises[0].CredentialID)
+		require.Equal(t, domain, brokenPromises[0].Domain)
+		require.Equal(t, witnessIRI.String(), brokenPromises[0].Witness)
+		require.Equal(t, "invalid signature", brokenPromises[0].Reason)
+		require.Empty(t, brokenPromises[1].Domain)
+		require.Empty(t, brokenPromises[1].Witness)
+
+		promises, err := client.GetBrokenPromises(domain)
+		require.NoError(t, err)
+		require.Len(t, promises, 1)
+		require.Equal(t, "invalid signature", promises[0].Reason)
+
+		r, err := client.WitnessReliability(witnessIRI)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), r.Broken)
+
+		// The log referenced by an invalid proof is not penalized.
+		r, err = client.LogReliability(domain)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), r.Broken)
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		db := newDBMock(t)
+
+		client, err := New(db, nil)
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		db.mockStore.errQuery = func() error { return errors.New("query error") }
+
+		_, err = client.GetBrokenPromises("https://vct.domain.com")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "query error")
+	})
+}
+
+func TestReliability_Score(t *testing.T) {
+	require.Equal(t, float64(1), (&Reliability{}).Score())
+	require.Equal(t, 0.75, (&Reliability{Confirmed: 3, Broken: 1}).Score())
+	require.Equal(t, float64(0), (&Reliability{Broken: 2}).Score())
+}
+
+func TestNewWebhookHandler(t *testing.T) {
+	bp := &BrokenPromise{
+		CredentialID: "https://orb.domain.com/vc1",
+		Domain:       "https://vct.domain.com",
+		Witness:      witnessIRI.String(),
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		var received *BrokenPromise
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			received = &BrokenPromise{}
+			require.NoError(t, json.Unmarshal(body, received))
+		}))
+		defer server.Close()
+
+		NewWebhookHandler(server.URL, http.DefaultClient)(bp)
+
+		require.NotNil(t, received)
+		require.Equal(t, bp.CredentialID, received.CredentialID)
+		require.Equal(t, bp.Witness, received.Witness)
+	})
+
+	t.Run("Error status", func(t *testing.T) {
+		var calls int
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		NewWebhookHandler(server.URL, http.DefaultClient)(bp)
+
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("HTTP error", func(t *testing.T) {
+		var calls int
+
+		NewWebhookHandler("https://webhook.domain.com", httpMock(func(req *http.Request) (*http.Response, error) {
+			calls++
+
+			return nil, errors.New("injected HTTP error")
+		}))(bp)
+
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestNewWitnessNotifier(t *testing.T) {
+	serviceIRI := testutil.MustParseURL("https://orb.domain.com/services/orb")
+
+	created := time.Now().Add(-time.Minute)
+	expired := time.Now()
+
+	bp := &BrokenPromise{
+		CredentialID:   "https://orb.domain.com/vc1",
+		Domain:         "https://vct.domain.com",
+		Witness:        witnessIRI.String(),
+		Created:        created,
+		ExpirationDate: expired,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ob := &mockOutbox{}
+
+		NewWitnessNotifier(serviceIRI, func() Outbox { return ob })(bp)
+
+		require.Len(t, ob.activities, 1)
+
+		reject := ob.activities[0]
+		require.True(t, reject.Type().Is(vocab.TypeReject))
+		require.Equal(t, witnessIRI.String(), reject.To()[0].String())
+
+		like := reject.Object().Activity()
+		require.NotNil(t, like)
+		require.True(t, like.Type().Is(vocab.TypeLike))
+		require.Equal(t, witnessIRI.String(), like.Actor().String())
+		require.Equal(t, bp.CredentialID, like.Object().IRI().String())
+	})
+
+	t.Run("Local or unknown witness", func(t *testing.T) {
+		ob := &mockOutbox{}
+
+		notify := NewWitnessNotifier(serviceIRI, func() Outbox { return ob })
+
+		notify(&BrokenPromise{CredentialID: bp.CredentialID, Witness: serviceIRI.String()})
+		notify(&BrokenPromise{CredentialID: bp.CredentialID})
+
+		require.Empty(t, ob.activities)
+	})
+
+	t.Run("Post error", func(t *testing.T) {
+		ob := &mockOutbox{err: errors.New("injected post error")}
+
+		NewWitnessNotifier(serviceIRI, func() Outbox { return ob })(bp)
+
+		require.Empty(t, ob.activities)
+	})
+
+	t.Run("Invalid IRI", func(t *testing.T) {
+		ob := &mockOutbox{}
+
+		notify := NewWitnessNotifier(serviceIRI, func() Outbox { return ob })
+
+		notify(&BrokenPromise{CredentialID: bp.CredentialID, Witness: ":invalid"})
+		notify(&BrokenPromise{CredentialID: ":invalid", Witness: witnessIRI.String()})
+
+		require.Empty(t, ob.activities)
+	})
+}
+
+type mockOutbox struct {
+	mutex      sync.Mutex
+	activities []*vocab.ActivityType
+	err        error
+}
+
+func (m *mockOutbox) Post(activity *vocab.ActivityType) (*url.URL, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.activities = append(m.activities, activity)
+
+	return testutil.MustParseURL("https://orb.domain.com/activities/" + uuid.New().String()), nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package monitoring
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+)
+
+const (
+	// BasePath is the base path of the monitoring endpoints.
+	BasePath = "/monitoring"
+
+	// ProofsPath is the path of the endpoint that returns the verified inclusion proofs of an anchor credential.
+	ProofsPath = BasePath + "/proofs"
+
+	// ReliabilityPath is the path of the endpoint that returns the reliability of a witness or witness log.
+	ReliabilityPath = BasePath + "/reliability"
+
+	// BrokenPromisesPath is the path of the endpoint that returns the promises broken by a witness log.
+	BrokenPromisesPath = BasePath + "/broken-promises"
+
+	idParam      = "id"
+	witnessParam = "witness"
+	logParam     = "log"
+)
+
+// HTTPHandlers returns the HTTP handlers of the monitoring endpoints. The handlers must be registered
+// with an HTTP server.
+func (c *Client) HTTPHandlers() []common.HTTPHandler {
+	return []common.HTTPHandler{
+		&restHandler{path: ProofsPath, handle: c.handleGetInclusionProofs},
+		&restHandler{path: ReliabilityPath, handle: c.handleGetReliability},
+		&restHandler{path: BrokenPromisesPath, handle: c.handleGetBrokenPromises},
+	}
+}
+
+type restHandler struct {
+	path   string
+	handle common.HTTPRequestHandler
+}
+
+// Path returns the path of the endpoint.
+func (h *restHandler) Path() string {
+	return h.path
+}
+
+// Method returns the HTTP method, which is always GET.
+func (h *restHandler) Method() string {
+	return http.MethodGet
+}
+
+// Handler returns the request handler.
+func (h *restHandler) Handler() common.HTTPRequestHandler {
+	return h.handle
+}
+
+// handleGetInclusionProofs writes the verified inclusion proofs of the anchor credential given by the 'id'
+// query parameter.
+func (c *Client) handleGetInclusionProofs(w http.ResponseWriter, req *http.Request) {
+	id := req.URL.Query().Get(idParam)
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "the 'id' query parameter is required")
+
+		return
+	}
+
+	proofs, err := c.GetInclusionProofs(id)
+	if err != nil {
+		logger.Errorf("[%s] Error retrieving inclusion proofs of credential %q: %s", ProofsPath, id, err)
+
+		writeError(w, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+
+	if len(proofs) == 0 {
+		writeError(w, http.StatusNotFound, "no inclusion proofs found")
+
+		return
+	}
+
+	writeJSON(w, proofs)
+}
+
+// handleGetReliability writes the reliability of the witness given by the 'witness' query parameter or of the
+// witness log given by the 'log' query parameter.
+func (c *Client) handleGetReliability(w http.ResponseWriter, req *http.Request) {
+	witness := req.URL.Query().Get(witnessParam)
+	domain := req.URL.Query().Get(logParam)
+
+	if (witness == "") == (domain == "") {
+		writeError(w, http.StatusBadRequest, "exactly one of the 'witness' or 'log' query parameters is required")
+
+		return
+	}
+
+	var (
+		r   *Reliability
+		err error
+	)
+
+	if witness != "" {
+		witnessIRI, e := url.Parse(witness)
+		if e != nil {
+			writeError(w, http.StatusBadRequest, "invalid 'witness' query parameter")
+
+			return
+		}
+
+		r, err = c.WitnessReliability(witnessIRI)
+	} else {
+		r, err = c.LogReliability(domain)
+	}
+
+	if err != nil {
+		logger.Errorf("[%s] Error retrieving reliability: %s", ReliabilityPath, err)
+
+		writeError(w, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+
+	writeJSON(w, &reliabilityResponse{Reliability: r, Score: r.Score()})
+}
+
+type reliabilityResponse struct {
+	*Reliability
+
+	Score float64 `json:"score"`
+}
+
+// handleGetBrokenPromises writes the promises that were broken by the witness log given by the 'log'
+// query parameter.
+func (c *Client) handleGetBrokenPromises(w http.ResponseWriter, req *http.Request) {
+	domain := req.URL.Query().Get(logParam)
+	if domain == "" {
+		writeError(w, http.StatusBadRequest, "the 'log' query parameter is required")
+
+		return
+	}
+
+	promises, err := c.GetBrokenPromises(domain)
+	if err != nil {
+		logger.Errorf("[%s] Error retrieving broken promises of log %s: %s", BrokenPromisesPath, domain, err)
+
+		writeError(w, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+
+	if promises == nil {
+		promises = []*BrokenPromise{}
+	}
+
+	writeJSON(w, promises)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	src, err := json.Marshal(v)
+	if err != nil {
+		logger.Errorf("Error marshalling response: %s", err)
+
+		writeError(w, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := w.Write(src); err != nil {
+		logger.Warnf("Error writing response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+
+	if _, err := w.Write([]byte(msg)); err != nil {
+		logger.Warnf("Error writing response: %s", err)
+	}
+}
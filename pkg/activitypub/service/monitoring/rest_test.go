@@ -0,0 +1,188 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package monitoring_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+
+	. "github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+	vcstore "github.com/trustbloc/orb/pkg/store/verifiable"
+)
+
+func TestClient_HTTPHandlers(t *testing.T) {
+	db := mem.NewProvider()
+	vStore, err := vcstore.New(db, testutil.GetLoader(t))
+	require.NoError(t, err)
+
+	vctLog := newTestLog(t, nil)
+
+	client, err := New(db, vStore, WithHTTPClient(http.DefaultClient))
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	ID := "https://orb.domain.com/" + uuid.New().String()
+
+	require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
+
+	const domain = "https://vct.domain.com"
+
+	// Record a broken promise.
+	require.EqualError(t, client.Watch(witnessIRI, "https://orb.domain.com/vc1", time.Now().Add(-time.Minute),
+		[]byte(`{"proof":{"domain":"`+domain+`"}}`)), "expired")
+
+	handlers := client.HTTPHandlers()
+	require.Len(t, handlers, 3)
+
+	for _, h := range handlers {
+		require.Equal(t, http.MethodGet, h.Method())
+	}
+
+	h := handlers[0]
+	require.Equal(t, ProofsPath, h.Path())
+
+	t.Run("Success", func(t *testing.T) {
+		result := get(t, h, ProofsPath+"?id="+url.QueryEscape(ID))
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		var proofs []*InclusionProof
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&proofs))
+		require.NoError(t, result.Body.Close())
+
+		require.Len(t, proofs, 1)
+		require.Equal(t, ID, proofs[0].CredentialID)
+		require.NotNil(t, proofs[0].STH)
+	})
+
+	t.Run("Witness reliability", func(t *testing.T) {
+		result := get(t, handlers[1], ReliabilityPath+"?witness="+url.QueryEscape(witnessIRI.String()))
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		r := &reliability{}
+		require.NoError(t, json.NewDecoder(result.Body).Decode(r))
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, uint64(1), r.Confirmed)
+		require.Equal(t, uint64(1), r.Broken)
+		require.Equal(t, 0.5, r.Score)
+	})
+
+	t.Run("Log reliability", func(t *testing.T) {
+		result := get(t, handlers[1], ReliabilityPath+"?log="+url.QueryEscape(vctLog.Endpoint))
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		r := &reliability{}
+		require.NoError(t, json.NewDecoder(result.Body).Decode(r))
+		require.NoError(t, result.Body.Close())
+
+		require.Equal(t, uint64(1), r.Confirmed)
+		require.Equal(t, uint64(0), r.Broken)
+		require.Equal(t, float64(1), r.Score)
+	})
+
+	t.Run("Reliability - invalid parameters", func(t *testing.T) {
+		result := get(t, handlers[1], ReliabilityPath)
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		result = get(t, handlers[1], ReliabilityPath+"?log="+url.QueryEscape(domain)+
+			"&witness="+url.QueryEscape(witnessIRI.String()))
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		result = get(t, handlers[1], ReliabilityPath+"?witness=%3A%2F%2Finvalid")
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Broken promises", func(t *testing.T) {
+		require.Equal(t, BrokenPromisesPath, handlers[2].Path())
+
+		result := get(t, handlers[2], BrokenPromisesPath+"?log="+url.QueryEscape(domain))
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		var promises []*BrokenPromise
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&promises))
+		require.NoError(t, result.Body.Close())
+
+		require.Len(t, promises, 1)
+		require.Equal(t, "https://orb.domain.com/vc1", promises[0].CredentialID)
+
+		result = get(t, handlers[2], BrokenPromisesPath+"?log="+url.QueryEscape(vctLog.Endpoint))
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		promises = nil
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&promises))
+		require.NoError(t, result.Body.Close())
+		require.NotNil(t, promises)
+		require.Empty(t, promises)
+
+		result = get(t, handlers[2], BrokenPromisesPath)
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Missing ID", func(t *testing.T) {
+		result := get(t, h, ProofsPath)
+		require.Equal(t, http.StatusBadRequest, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		result := get(t, h, ProofsPath+"?id=https://orb.domain.com/unknown")
+		require.Equal(t, http.StatusNotFound, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		db := newDBMock(t)
+		db.mockStore.errQuery = func() error { return errors.New("injected query error") }
+
+		client, err := New(db, vStore)
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		handlers := client.HTTPHandlers()
+
+		result := get(t, handlers[0], ProofsPath+"?id="+url.QueryEscape(ID))
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+
+		result = get(t, handlers[2], BrokenPromisesPath+"?log="+url.QueryEscape(domain))
+		require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		require.NoError(t, result.Body.Close())
+	})
+}
+
+type reliability struct {
+	Confirmed uint64  `json:"confirmed"`
+	Broken    uint64  `json:"broken"`
+	Score     float64 `json:"score"`
+}
+
+func get(t *testing.T, h common.HTTPHandler, target string) *http.Response {
+	t.Helper()
+
+	rw := httptest.NewRecorder()
+
+	h.Handler()(rw, httptest.NewRequest(http.MethodGet, target, nil))
+
+	return rw.Result()
+}
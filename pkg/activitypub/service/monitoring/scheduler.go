@@ -0,0 +1,311 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	defaultMinPollInterval = time.Second
+	defaultMaxPollInterval = time.Minute
+
+	// pollsPerDeadline is the (minimum) number of times that an entity is checked in the time that remains
+	// until its promise expires. So the further away the deadline, the longer the interval between checks.
+	pollsPerDeadline = 4
+)
+
+// WithPollInterval sets the minimum and maximum interval between checks of an entity in the queue.
+// The queue is queried when the earliest check is due but at least once per maximum interval (since
+// other instances may add entities to the queue).
+func WithPollInterval(minInterval, maxInterval time.Duration) Opt {
+	return func(o *Client) {
+		o.minPollInterval = minInterval
+		o.maxPollInterval = maxInterval
+	}
+}
+
+func (c *Client) worker() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case now := <-c.ticker.C:
+			if !c.due(now) {
+				continue
+			}
+
+			if err := c.handleEntities(); err != nil {
+				logger.Errorf("handle entities: %v", err)
+			}
+		case <-c.done:
+			logger.Debugf("monitoring worker stopped")
+
+			return
+		}
+	}
+}
+
+// Close stops the worker and waits for it to exit.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		c.ticker.Stop()
+		close(c.done)
+		c.wg.Wait()
+	})
+}
+
+func (c *Client) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// handleEntities checks the entities in the queue that are due. The entities are grouped by log so that
+// only one signed tree head is retrieved per log.
+func (c *Client) handleEntities() error {
+	c.setNextRun(time.Now().Add(c.maxPollInterval))
+
+	entitiesByDomain, err := c.dueEntities()
+	if err != nil {
+		return err
+	}
+
+	for domain, entities := range entitiesByDomain {
+		if c.closed() {
+			return nil
+		}
+
+		c.checkLog(domain, entities)
+	}
+
+	return nil
+}
+
+// dueEntities returns the queued entities that are due to be checked, grouped by log domain. Entities
+// whose promise has expired are removed from the queue and recorded as broken promises. The worker is
+// woken up when the earliest of the remaining entities is due.
+func (c *Client) dueEntities() (map[string][]*entity, error) {
+	records, err := c.store.Query(tagNotConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("query %q entities: %w", tagNotConfirmed, err)
+	}
+
+	defer storage.Close(records, logger)
+
+	now := time.Now()
+
+	entitiesByDomain := make(map[string][]*entity)
+
+	for Next(records) {
+		var src []byte
+
+		if src, err = records.Value(); err != nil {
+			return nil, fmt.Errorf("get entity value: %w", err)
+		}
+
+		var k string
+
+		if k, err = records.Key(); err != nil {
+			return nil, fmt.Errorf("get entity key: %w", err)
+		}
+
+		var e *entity
+		if err = json.Unmarshal(src, &e); err != nil {
+			logger.Errorf("unmarshal entity: %v", err)
+
+			continue
+		}
+
+		e.key = k
+
+		if k != key(e) {
+			c.migrate(e)
+		}
+
+		if now.After(e.ExpirationDate) {
+			c.promiseBroken(e)
+
+			// removes entity from the store bc the witness failed its promise (recorded above).
+			c.dequeue(e)
+
+			continue
+		}
+
+		if e.NextCheck.After(now) {
+			c.wakeAt(e.NextCheck)
+
+			continue
+		}
+
+		entitiesByDomain[e.Domain] = append(entitiesByDomain[e.Domain], e)
+	}
+
+	return entitiesByDomain, nil
+}
+
+// checkLog retrieves the signed tree head of the log and verifies the inclusion of each of the given entities.
+// Entities that couldn't be confirmed are rescheduled.
+func (c *Client) checkLog(domain string, entities []*entity) {
+	vctClient := vct.New(domain, vct.WithHTTPClient(c.http))
+
+	sth, err := c.getVerifiedSTH(vctClient, domain)
+	if err != nil {
+		logger.Warnf("get STH of log %s for %d credential(s): %v", domain, len(entities), err)
+
+		for _, e := range entities {
+			c.reschedule(e)
+		}
+
+		return
+	}
+
+	for _, e := range entities {
+		if err := c.checkEntity(vctClient, e, sth); err != nil {
+			logger.Warnf("credential %q existence: %v", e.CredentialID, err)
+
+			c.reschedule(e)
+
+			continue
+		}
+
+		logger.Infof("credential %q existence in the Merkle tree confirmed", e.CredentialID)
+
+		c.promiseKept(e)
+
+		// removes the entity from the store bc we confirmed that credential is in MT (log above).
+		c.dequeue(e)
+	}
+}
+
+func (c *Client) checkEntity(client vctClient, e *entity, sth *command.GetSTHResponse) error {
+	hash, err := c.leafHash(e)
+	if err != nil {
+		return err
+	}
+
+	return c.verifyInclusion(client, e, hash, sth)
+}
+
+// schedule puts the entity in the queue with the time of its next check.
+func (c *Client) schedule(e *entity) error {
+	e.NextCheck = c.nextCheck(e, time.Now())
+
+	src, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal entity: %w", err)
+	}
+
+	if err := c.store.Put(key(e), src, storage.Tag{Name: tagNotConfirmed}); err != nil {
+		return err
+	}
+
+	e.key = key(e)
+
+	c.wakeAt(e.NextCheck)
+
+	return nil
+}
+
+func (c *Client) reschedule(e *entity) {
+	if err := c.schedule(e); err != nil {
+		logger.Errorf("reschedule credential %q: %v", e.CredentialID, err)
+	}
+}
+
+// dequeue deletes the entity from the queue by the key under which it was found. If the entity couldn't
+// be deleted then the queue is checked again at the next tick.
+func (c *Client) dequeue(e *entity) {
+	k := e.key
+	if k == "" {
+		k = key(e)
+	}
+
+	if err := c.store.Delete(k); err != nil {
+		logger.Errorf("delete credential %q from queue: %v", e.CredentialID, err)
+
+		c.wakeAt(time.Now())
+	}
+}
+
+// migrate moves an entity that was queued by a previous version (under a legacy key) to its current key.
+func (c *Client) migrate(e *entity) {
+	legacyKey := e.key
+
+	src, err := json.Marshal(e)
+	if err != nil {
+		logger.Errorf("marshal entity: %v", err)
+
+		return
+	}
+
+	if err := c.store.Put(key(e), src, storage.Tag{Name: tagNotConfirmed}); err != nil {
+		logger.Errorf("migrate credential %q in queue: %v", e.CredentialID, err)
+
+		return
+	}
+
+	e.key = key(e)
+
+	if err := c.store.Delete(legacyKey); err != nil {
+		logger.Errorf("delete legacy queue entry %q: %v", legacyKey, err)
+
+		return
+	}
+
+	logger.Infof("migrated credential %q in queue from legacy key %q", e.CredentialID, legacyKey)
+}
+
+// due returns true if the earliest check of the queued entities is due.
+func (c *Client) due(now time.Time) bool {
+	c.nextRunMutex.Lock()
+	defer c.nextRunMutex.Unlock()
+
+	return !now.Before(c.nextRun)
+}
+
+func (c *Client) setNextRun(t time.Time) {
+	c.nextRunMutex.Lock()
+	defer c.nextRunMutex.Unlock()
+
+	c.nextRun = t
+}
+
+// wakeAt ensures that the queue is checked no later than the given time.
+func (c *Client) wakeAt(t time.Time) {
+	c.nextRunMutex.Lock()
+	defer c.nextRunMutex.Unlock()
+
+	if t.Before(c.nextRun) {
+		c.nextRun = t
+	}
+}
+
+// nextCheck returns the time of the next check of the entity. The interval is a fraction of the time that
+// remains until the promise expires, bounded by the minimum and maximum poll interval.
+func (c *Client) nextCheck(e *entity, now time.Time) time.Time {
+	interval := e.ExpirationDate.Sub(now) / pollsPerDeadline
+
+	if interval < c.minPollInterval {
+		interval = c.minPollInterval
+	}
+
+	if interval > c.maxPollInterval {
+		interval = c.maxPollInterval
+	}
+
+	return now.Add(interval)
+}
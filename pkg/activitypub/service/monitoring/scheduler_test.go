@@ -0,0 +1,278 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package monitoring_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+
+	. "github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+	vcstore "github.com/trustbloc/orb/pkg/store/verifiable"
+)
+
+func putLegacyEntity(t *testing.T, store storage.Store, id, domain string, expiration time.Time) {
+	t.Helper()
+
+	src, err := json.Marshal(map[string]interface{}{
+		"credential_id":   id,
+		"expiration_date": expiration,
+		"domain":          domain,
+		"created":         time.Now(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put("queue"+id, src, storage.Tag{Name: tagNotConfirmed}))
+}
+
+func TestClient_Scheduler(t *testing.T) {
+	t.Run("One STH per log per cycle", func(t *testing.T) {
+		const numCredentials = 5
+
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		var (
+			unavailable int32 = 1
+			sthRequests int32
+		)
+
+		vctLog := newTestLog(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			if atomic.LoadInt32(&unavailable) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			if r.URL.Path == rest.GetSTHPath {
+				atomic.AddInt32(&sthRequests, 1)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient),
+			WithPollInterval(200*time.Millisecond, 200*time.Millisecond))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		var ids []string
+
+		for i := 0; i < numCredentials; i++ {
+			ID := "https://orb.domain.com/" + uuid.New().String()
+
+			require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Minute), witness(t, vctLog, vStore, ID)))
+
+			ids = append(ids, ID)
+		}
+
+		checkQueue(t, db, numCredentials)
+
+		atomic.StoreInt32(&unavailable, 0)
+
+		require.Eventually(t, func() bool {
+			for _, id := range ids {
+				proofs, err := client.GetInclusionProofs(id)
+				if err != nil || len(proofs) == 0 {
+					return false
+				}
+			}
+
+			return true
+		}, 5*time.Second, 50*time.Millisecond)
+
+		checkQueue(t, db, 0)
+
+		// The credentials were queued at (almost) the same time so they're checked in at most two cycles.
+		require.LessOrEqual(t, atomic.LoadInt32(&sthRequests), int32(2))
+
+		r, err := client.LogReliability(vctLog.Endpoint)
+		require.NoError(t, err)
+		require.Equal(t, uint64(numCredentials), r.Confirmed)
+	})
+
+	t.Run("Back off when deadline is far away", func(t *testing.T) {
+		db := mem.NewProvider()
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		var (
+			unavailable int32 = 1
+			requests    int32
+		)
+
+		vctLog := newTestLog(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			atomic.AddInt32(&requests, 1)
+
+			if atomic.LoadInt32(&unavailable) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient),
+			WithPollInterval(100*time.Millisecond, time.Hour))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		ID := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Hour), witness(t, vctLog, vStore, ID)))
+		require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+		atomic.StoreInt32(&unavailable, 0)
+
+		time.Sleep(500 * time.Millisecond)
+
+		// The next check is a quarter of an hour away.
+		require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+		checkQueue(t, db, 1)
+	})
+
+	t.Run("Sleep until the earliest check", func(t *testing.T) {
+		db := newDBMock(t)
+		vStore, err := vcstore.New(db, testutil.GetLoader(t))
+		require.NoError(t, err)
+
+		var unavailable int32 = 1
+
+		vctLog := newTestLog(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			if atomic.LoadInt32(&unavailable) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		client, err := New(db, vStore, WithHTTPClient(http.DefaultClient),
+			WithPollInterval(50*time.Millisecond, time.Hour))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		ID := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID, time.Now().Add(time.Hour), witness(t, vctLog, vStore, ID)))
+
+		time.Sleep(500 * time.Millisecond)
+
+		// The queue is queried once and then not until the next check (a quarter of an hour away) is due.
+		require.Equal(t, int32(1), atomic.LoadInt32(&db.mockStore.queries))
+
+		// Watching a credential with a closer deadline wakes up the worker.
+		ID2 := "https://orb.domain.com/" + uuid.New().String()
+
+		require.NoError(t, client.Watch(witnessIRI, ID2, time.Now().Add(200*time.Millisecond),
+			witness(t, vctLog, vStore, ID2)))
+
+		atomic.StoreInt32(&unavailable, 0)
+
+		require.Eventually(t, func() bool {
+			proofs, err := client.GetInclusionProofs(ID2)
+
+			return err == nil && len(proofs) == 1
+		}, time.Second, 20*time.Millisecond)
+
+		checkQueue(t, db, 1)
+	})
+
+	t.Run("Legacy queue entries", func(t *testing.T) {
+		db := mem.NewProvider()
+
+		store, err := db.OpenStore(storeName)
+		require.NoError(t, err)
+
+		const (
+			legacyID      = "https://orb.domain.com/legacy"
+			legacyExpired = "https://orb.domain.com/legacy-expired"
+			domain        = "https://vct.domain.com"
+		)
+
+		putLegacyEntity(t, store, legacyID, domain, time.Now().Add(time.Hour))
+		putLegacyEntity(t, store, legacyExpired, domain, time.Now().Add(-time.Minute))
+
+		var brokenPromises int32
+
+		client, err := New(db, nil, WithPollInterval(50*time.Millisecond, 100*time.Millisecond),
+			WithBrokenPromiseHandler(func(bp *BrokenPromise) {
+				require.Equal(t, legacyExpired, bp.CredentialID)
+
+				atomic.AddInt32(&brokenPromises, 1)
+			}))
+		require.NoError(t, err)
+
+		defer client.Close()
+
+		require.Eventually(t, func() bool {
+			_, err := store.Get("queue" + legacyID)
+
+			return errors.Is(err, storage.ErrDataNotFound)
+		}, time.Second, 20*time.Millisecond)
+
+		// The entity was migrated to the current key.
+		_, err = store.Get("queue" + legacyID + "-" + domain)
+		require.NoError(t, err)
+
+		// The expired entity is removed from the queue (by its legacy key) and its broken promise is
+		// recorded only once.
+		time.Sleep(300 * time.Millisecond)
+
+		_, err = store.Get("queue" + legacyExpired)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		require.Equal(t, int32(1), atomic.LoadInt32(&brokenPromises))
+
+		checkQueue(t, db, 1)
+	})
+
+	t.Run("Close stops the worker", func(t *testing.T) {
+		db := newDBMock(t)
+
+		var queries int32
+
+		db.mockStore.errQuery = func() error {
+			atomic.AddInt32(&queries, 1)
+
+			return errors.New("injected query error")
+		}
+
+		client, err := New(db, nil, WithPollInterval(50*time.Millisecond, time.Second))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return atomic.LoadInt32(&queries) > 0 },
+			time.Second, 10*time.Millisecond)
+
+		client.Close()
+
+		n := atomic.LoadInt32(&queries)
+
+		time.Sleep(200 * time.Millisecond)
+
+		require.Equal(t, n, atomic.LoadInt32(&queries))
+
+		// Closing again is a no-op.
+		client.Close()
+	})
+}
@@ -0,0 +1,213 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// ErrInconsistentLog indicates that the signed tree head returned by a log is not consistent with
+// a signed tree head that was previously returned by the log, i.e. the log has forked or rewritten its history.
+var ErrInconsistentLog = errors.New("inconsistent log")
+
+// ErrInvalidProof indicates that a signed tree head or inclusion proof returned by a log failed verification.
+var ErrInvalidProof = errors.New("invalid proof")
+
+type vctClient interface {
+	GetSTH(ctx context.Context) (*command.GetSTHResponse, error)
+	GetSTHConsistency(ctx context.Context, first, second uint64) (*command.GetSTHConsistencyResponse, error)
+	GetPublicKey(ctx context.Context) ([]byte, error)
+	GetProofByHash(ctx context.Context, hash string, treeSize uint64) (*command.GetProofByHashResponse, error)
+}
+
+// logState is the latest verified signed tree head of a log.
+type logState struct {
+	Domain string                  `json:"domain"`
+	STH    *command.GetSTHResponse `json:"sth"`
+}
+
+// getVerifiedSTH retrieves the latest signed tree head from the log of the given domain, verifies its signature
+// and checks that it's consistent with the signed tree head that was previously retrieved from the log.
+func (c *Client) getVerifiedSTH(client vctClient, domain string) (*command.GetSTHResponse, error) {
+	sth, err := client.GetSTH(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("get STH: %w", err)
+	}
+
+	pubKey, err := c.getPublicKey(client, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verifySTHSignature(sth, pubKey); err != nil {
+		return nil, fmt.Errorf("%w: verify STH signature of log %s: %s", ErrInvalidProof, domain, err)
+	}
+
+	// The state of the log is updated under a per-domain lock so that a slow log doesn't block the
+	// verification of other logs.
+	unlock := c.lockLog(domain)
+	defer unlock()
+
+	prev, err := c.getLogState(domain)
+	if err != nil {
+		if !errors.Is(err, storage.ErrDataNotFound) {
+			return nil, err
+		}
+
+		return sth, c.putLogState(domain, sth)
+	}
+
+	if err = checkConsistency(client, prev.STH, sth); err != nil {
+		logger.Errorf("Log %s is inconsistent: STH with tree size %d is not consistent with STH with tree size %d: %s",
+			domain, sth.TreeSize, prev.STH.TreeSize, err)
+
+		return nil, fmt.Errorf("%w: %s: %s", ErrInconsistentLog, domain, err)
+	}
+
+	if sth.TreeSize <= prev.STH.TreeSize {
+		// The log may return an older STH (e.g. from a replica that is behind), in which case the latest STH is kept.
+		return sth, nil
+	}
+
+	return sth, c.putLogState(domain, sth)
+}
+
+func (c *Client) getPublicKey(client vctClient, domain string) ([]byte, error) {
+	if c.logResolver != nil {
+		// The resolver caches the public key of the log.
+		return c.logResolver.GetLogPublicKey(domain)
+	}
+
+	c.keyMutex.RLock()
+	pubKey, ok := c.publicKeys[domain]
+	c.keyMutex.RUnlock()
+
+	if ok {
+		return pubKey, nil
+	}
+
+	pubKey, err := client.GetPublicKey(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("get public key: %w", err)
+	}
+
+	logger.Warnf("No log resolver is configured so the public key served by log %s is trusted on first use", domain)
+
+	c.keyMutex.Lock()
+	c.publicKeys[domain] = pubKey
+	c.keyMutex.Unlock()
+
+	return pubKey, nil
+}
+
+// lockLog locks the state of the log of the given domain and returns a function that unlocks it.
+func (c *Client) lockLog(domain string) func() {
+	c.sthMutex.Lock()
+
+	mutex, ok := c.logMutexes[domain]
+	if !ok {
+		mutex = &sync.Mutex{}
+		c.logMutexes[domain] = mutex
+	}
+
+	c.sthMutex.Unlock()
+
+	mutex.Lock()
+
+	return mutex.Unlock
+}
+
+func (c *Client) getLogState(domain string) (*logState, error) {
+	src, err := c.store.Get(sthKey(domain))
+	if err != nil {
+		return nil, fmt.Errorf("get STH of log %s: %w", domain, err)
+	}
+
+	state := &logState{}
+
+	if err := json.Unmarshal(src, state); err != nil {
+		return nil, fmt.Errorf("unmarshal STH of log %s: %w", domain, err)
+	}
+
+	return state, nil
+}
+
+func (c *Client) putLogState(domain string, sth *command.GetSTHResponse) error {
+	src, err := json.Marshal(&logState{Domain: domain, STH: sth})
+	if err != nil {
+		return fmt.Errorf("marshal STH: %w", err)
+	}
+
+	if err := c.store.Put(sthKey(domain), src); err != nil {
+		return fmt.Errorf("store STH of log %s: %w", domain, err)
+	}
+
+	return nil
+}
+
+// checkConsistency verifies that the smaller of the two trees is a prefix of the larger one.
+func checkConsistency(client vctClient, sth1, sth2 *command.GetSTHResponse) error {
+	if sth1.TreeSize > sth2.TreeSize {
+		sth1, sth2 = sth2, sth1
+	}
+
+	var proof [][]byte
+
+	if sth1.TreeSize > 0 && sth1.TreeSize < sth2.TreeSize {
+		resp, err := client.GetSTHConsistency(context.Background(), sth1.TreeSize, sth2.TreeSize)
+		if err != nil {
+			return fmt.Errorf("get STH consistency: %w", err)
+		}
+
+		proof = resp.Consistency
+	}
+
+	return logverifier.New(hasher.DefaultHasher).VerifyConsistencyProof(
+		int64(sth1.TreeSize), int64(sth2.TreeSize), sth1.SHA256RootHash, sth2.SHA256RootHash, proof,
+	)
+}
+
+func verifySTHSignature(sth *command.GetSTHResponse, pubKey []byte) error {
+	var sig *command.DigitallySigned
+
+	if err := json.Unmarshal(sth.TreeHeadSignature, &sig); err != nil {
+		return fmt.Errorf("unmarshal signature: %w", err)
+	}
+
+	data, err := json.Marshal(command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      sth.Timestamp,
+		TreeSize:       sth.TreeSize,
+		SHA256RootHash: sth.SHA256RootHash,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal tree head signature: %w", err)
+	}
+
+	kh, err := (&localkms.LocalKMS{}).PubKeyBytesToHandle(pubKey, sig.Algorithm.Type)
+	if err != nil {
+		return fmt.Errorf("pub key to handle: %w", err)
+	}
+
+	return (&tinkcrypto.Crypto{}).Verify(sig.Signature, data, kh)
+}
+
+func sthKey(domain string) string {
+	return sthKeyPrefix + domain
+}
@@ -0,0 +1,409 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
+	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/httppublisher"
+)
+
+var logger = log.New("activitypub_service")
+
+// ErrQueueFull is returned by Submit if the delivery queue for the destination host is full.
+var ErrQueueFull = errors.New("delivery queue is full")
+
+// ErrStopped is returned by Submit if the scheduler is stopping. It's also passed to the failure handler for
+// each message that was still queued when the scheduler stopped.
+var ErrStopped = errors.New("delivery scheduler stopped")
+
+const (
+	defaultMaxWorkers       = 20
+	defaultMaxPerHost       = 2
+	defaultMaxQueuedPerHost = 1000
+	defaultStatsLogInterval = 10 * time.Minute
+	defaultDrainTimeout     = 5 * time.Second
+	latencyAverageSmoothing = 0.2
+)
+
+// Config holds the configuration for the delivery scheduler.
+type Config struct {
+	// MaxWorkers is the maximum number of deliveries that are in progress at any one time (across all hosts).
+	MaxWorkers int
+	// MaxPerHost is the maximum number of deliveries to a single host that are in progress at any one time.
+	MaxPerHost int
+	// MaxQueuedPerHost is the maximum number of messages that may be queued for a single host. Messages that
+	// are submitted after this limit is reached are rejected with ErrQueueFull.
+	MaxQueuedPerHost int
+	// StatsLogInterval is the interval at which the per-host delivery statistics are logged.
+	StatsLogInterval time.Duration
+	// DrainTimeout is the maximum time that Stop waits for the queued messages to be delivered. Messages that
+	// are still queued after this time are passed to the failure handler with ErrStopped.
+	DrainTimeout time.Duration
+}
+
+// DeliverFunc delivers a message.
+type DeliverFunc func(msg *message.Message) error
+
+// FailureHandler is invoked when a message could not be delivered.
+type FailureHandler func(msg *message.Message, err error)
+
+// HostStats contains the delivery statistics for a host. (Latencies are marshalled to JSON in nanoseconds.)
+type HostStats struct {
+	Host      string `json:"host"`
+	Queued    int    `json:"queued"`
+	InFlight  int    `json:"inFlight"`
+	Delivered uint64 `json:"delivered"`
+	Failed    uint64 `json:"failed"`
+	// SuccessRate is the ratio of successful deliveries to attempted deliveries.
+	SuccessRate float64 `json:"successRate"`
+	// AverageLatency is the (exponentially weighted) average time taken to deliver a message to the host.
+	AverageLatency time.Duration `json:"averageLatency"`
+	LastLatency    time.Duration `json:"lastLatency"`
+}
+
+// Scheduler delivers messages in parallel using a pool of workers. Each destination host has its own queue
+// and the number of concurrent deliveries to a host is bounded, so that a slow host doesn't hold up deliveries
+// to other hosts. Workers take messages from the host queues in round-robin order so that a host with a large
+// backlog doesn't starve the other hosts.
+type Scheduler struct {
+	*Config
+	*lifecycle.Lifecycle
+
+	serviceName string
+	deliver     DeliverFunc
+	onFailure   FailureHandler
+	mutex       sync.Mutex
+	cond        *sync.Cond
+	hosts       map[string]*hostQueue
+	order       []string
+	next        int
+	draining    bool
+	stopped     bool
+	wg          sync.WaitGroup
+	done        chan struct{}
+}
+
+type hostQueue struct {
+	HostStats
+
+	messages []*message.Message
+}
+
+// New returns a new delivery scheduler.
+func New(serviceName string, cfg *Config, deliver DeliverFunc, onFailure FailureHandler) *Scheduler {
+	if cfg.MaxWorkers <= 0 {
+		cfg.MaxWorkers = defaultMaxWorkers
+	}
+
+	if cfg.MaxPerHost <= 0 {
+		cfg.MaxPerHost = defaultMaxPerHost
+	}
+
+	if cfg.MaxQueuedPerHost <= 0 {
+		cfg.MaxQueuedPerHost = defaultMaxQueuedPerHost
+	}
+
+	if cfg.StatsLogInterval <= 0 {
+		cfg.StatsLogInterval = defaultStatsLogInterval
+	}
+
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
+	}
+
+	s := &Scheduler{
+		Config:      cfg,
+		serviceName: serviceName,
+		deliver:     deliver,
+		onFailure:   onFailure,
+		hosts:       make(map[string]*hostQueue),
+		done:        make(chan struct{}),
+	}
+
+	s.cond = sync.NewCond(&s.mutex)
+
+	s.Lifecycle = lifecycle.New(serviceName+"-delivery",
+		lifecycle.WithStart(s.start),
+		lifecycle.WithStop(s.stop),
+	)
+
+	return s
+}
+
+// Submit queues the given message for delivery to the URL specified in the message's 'send-to' metadata.
+// ErrQueueFull is returned if the queue for the destination host is full and ErrStopped is returned if the
+// scheduler is stopping.
+func (s *Scheduler) Submit(msg *message.Message) error {
+	to := msg.Metadata[httppublisher.MetadataSendTo]
+
+	toURL, err := url.Parse(to)
+	if err != nil {
+		return fmt.Errorf("parse URL %s: %w", to, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.draining {
+		return ErrStopped
+	}
+
+	q, ok := s.hosts[toURL.Host]
+	if !ok {
+		q = &hostQueue{HostStats: HostStats{Host: toURL.Host}}
+
+		s.hosts[toURL.Host] = q
+		s.order = append(s.order, toURL.Host)
+	}
+
+	if len(q.messages) >= s.MaxQueuedPerHost {
+		return fmt.Errorf("host [%s]: %w", toURL.Host, ErrQueueFull)
+	}
+
+	q.messages = append(q.messages, msg)
+
+	logger.Debugf("[%s] Queued message [%s] for delivery to [%s]. Queue size: %d",
+		s.serviceName, msg.UUID, to, len(q.messages))
+
+	s.cond.Signal()
+
+	return nil
+}
+
+// Stats returns the delivery statistics for each host, sorted by host.
+func (s *Scheduler) Stats() []*HostStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := make([]*HostStats, 0, len(s.hosts))
+
+	for _, q := range s.hosts {
+		hs := q.HostStats
+		hs.Queued = len(q.messages)
+
+		stats = append(stats, &hs)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Host < stats[j].Host })
+
+	return stats
+}
+
+func (s *Scheduler) start() {
+	for i := 0; i < s.MaxWorkers; i++ {
+		s.wg.Add(1)
+
+		go s.work()
+	}
+
+	go s.logStats()
+
+	logger.Infof("[%s] Started delivery scheduler with %d workers and a maximum of %d concurrent deliveries per host",
+		s.serviceName, s.MaxWorkers, s.MaxPerHost)
+}
+
+// stop waits (up to the drain timeout) for the queued messages to be delivered. Messages that are still queued
+// after the drain timeout are passed to the failure handler so that they aren't silently dropped.
+func (s *Scheduler) stop() {
+	s.mutex.Lock()
+	s.draining = true
+	s.cond.Broadcast()
+	s.mutex.Unlock()
+
+	drained := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(s.DrainTimeout):
+		logger.Warnf("[%s] Timed out after %s waiting for queued messages to be delivered",
+			s.serviceName, s.DrainTimeout)
+
+		s.mutex.Lock()
+		s.stopped = true
+		s.cond.Broadcast()
+		s.mutex.Unlock()
+
+		// Wait for the in-flight deliveries to complete.
+		<-drained
+	}
+
+	close(s.done)
+
+	undelivered := s.takeAll()
+
+	if len(undelivered) == 0 {
+		return
+	}
+
+	logger.Warnf("[%s] Delivery scheduler stopped with %d undelivered messages", s.serviceName, len(undelivered))
+
+	for _, msg := range undelivered {
+		s.onFailure(msg, ErrStopped)
+	}
+}
+
+// takeAll removes and returns all queued messages.
+func (s *Scheduler) takeAll() []*message.Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var messages []*message.Message
+
+	for _, host := range s.order {
+		q := s.hosts[host]
+
+		messages = append(messages, q.messages...)
+		q.messages = nil
+	}
+
+	return messages
+}
+
+func (s *Scheduler) work() {
+	defer s.wg.Done()
+
+	for {
+		host, msg, ok := s.take()
+		if !ok {
+			return
+		}
+
+		start := time.Now()
+
+		err := s.deliver(msg)
+
+		s.complete(host, time.Since(start), err)
+
+		if err != nil {
+			logger.Debugf("[%s] Error delivering message [%s] to host [%s]: %s", s.serviceName, msg.UUID, host, err)
+
+			s.onFailure(msg, err)
+		}
+	}
+}
+
+// take blocks until a message may be delivered and returns the message along with its destination host.
+// False is returned if the scheduler was stopped or if the scheduler is stopping and no messages are queued.
+func (s *Scheduler) take() (string, *message.Message, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for {
+		if s.stopped || (s.draining && !s.hasQueued()) {
+			return "", nil, false
+		}
+
+		if q := s.nextQueue(); q != nil {
+			msg := q.messages[0]
+
+			q.messages[0] = nil
+			q.messages = q.messages[1:]
+			q.InFlight++
+
+			return q.Host, msg, true
+		}
+
+		s.cond.Wait()
+	}
+}
+
+// hasQueued returns true if any messages are queued. The caller must hold the lock.
+func (s *Scheduler) hasQueued() bool {
+	for _, q := range s.hosts {
+		if len(q.messages) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextQueue returns the next host queue (in round-robin order) that has a message to deliver and hasn't
+// reached its concurrency limit, or nil if there is no such queue. The caller must hold the lock.
+func (s *Scheduler) nextQueue() *hostQueue {
+	for i := 0; i < len(s.order); i++ {
+		idx := (s.next + i) % len(s.order)
+
+		q := s.hosts[s.order[idx]]
+
+		if len(q.messages) > 0 && q.InFlight < s.MaxPerHost {
+			s.next = idx + 1
+
+			return q
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) complete(host string, latency time.Duration, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	q := s.hosts[host]
+
+	q.InFlight--
+	q.LastLatency = latency
+
+	if q.AverageLatency == 0 {
+		q.AverageLatency = latency
+	} else {
+		q.AverageLatency += time.Duration(latencyAverageSmoothing * float64(latency-q.AverageLatency))
+	}
+
+	if err != nil {
+		q.Failed++
+	} else {
+		q.Delivered++
+	}
+
+	q.SuccessRate = float64(q.Delivered) / float64(q.Delivered+q.Failed)
+
+	if s.draining {
+		// Wake all workers so that they exit if there are no more queued messages.
+		s.cond.Broadcast()
+
+		return
+	}
+
+	// A slot is now available for this host.
+	s.cond.Signal()
+}
+
+func (s *Scheduler) logStats() {
+	ticker := time.NewTicker(s.StatsLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, hs := range s.Stats() {
+				logger.Infof("[%s] Delivery stats for host [%s] - Queued: %d, In-flight: %d, Delivered: %d, "+
+					"Failed: %d, Success rate: %.2f, Average latency: %s",
+					s.serviceName, hs.Host, hs.Queued, hs.InFlight, hs.Delivered, hs.Failed,
+					hs.SuccessRate, hs.AverageLatency)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,403 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/httppublisher"
+)
+
+func TestNew(t *testing.T) {
+	s := New("service1", &Config{}, nil, nil)
+	require.NotNil(t, s)
+	require.Equal(t, defaultMaxWorkers, s.MaxWorkers)
+	require.Equal(t, defaultMaxPerHost, s.MaxPerHost)
+	require.Equal(t, defaultMaxQueuedPerHost, s.MaxQueuedPerHost)
+	require.Equal(t, defaultStatsLogInterval, s.StatsLogInterval)
+	require.Equal(t, defaultDrainTimeout, s.DrainTimeout)
+
+	s.Start()
+	s.Stop()
+}
+
+func TestScheduler_PerHostConcurrency(t *testing.T) {
+	d := newMockDeliverer()
+
+	s := New("service1", &Config{MaxWorkers: 10, MaxPerHost: 2}, d.deliver, d.failed)
+
+	s.Start()
+	defer s.Stop()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Submit(newMessage("https://slow.com/inbox")))
+	}
+
+	require.NoError(t, s.Submit(newMessage("https://fast.com/inbox")))
+	require.NoError(t, s.Submit(newMessage("https://fast.com/inbox")))
+
+	// The deliveries to the fast host shouldn't be held up by the slow host.
+	d.release("fast.com", 2)
+
+	require.Eventually(t, func() bool { return d.delivered("fast.com") == 2 }, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, 2, d.maxInFlight("slow.com"))
+	require.Equal(t, 0, d.delivered("slow.com"))
+
+	stats := getStats(s, "slow.com")
+	require.Equal(t, 3, stats.Queued)
+	require.Equal(t, 2, stats.InFlight)
+
+	d.release("slow.com", 5)
+
+	require.Eventually(t, func() bool { return d.delivered("slow.com") == 5 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, 2, d.maxInFlight("slow.com"))
+
+	stats = getStats(s, "slow.com")
+	require.Equal(t, 0, stats.Queued)
+	require.Equal(t, 0, stats.InFlight)
+	require.Equal(t, uint64(5), stats.Delivered)
+	require.Equal(t, 1.0, stats.SuccessRate)
+}
+
+func TestScheduler_MaxWorkers(t *testing.T) {
+	d := newMockDeliverer()
+
+	s := New("service1", &Config{MaxWorkers: 3, MaxPerHost: 2}, d.deliver, d.failed)
+
+	s.Start()
+	defer s.Stop()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Submit(newMessage(fmt.Sprintf("https://domain%d.com/inbox", i))))
+	}
+
+	require.Eventually(t, func() bool { return d.totalInFlight() == 3 }, time.Second, 10*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+
+	require.Equal(t, 3, d.totalInFlight())
+
+	for i := 0; i < 5; i++ {
+		d.release(fmt.Sprintf("domain%d.com", i), 1)
+	}
+
+	require.Eventually(t, func() bool { return d.totalDelivered() == 5 }, time.Second, 10*time.Millisecond)
+	require.Len(t, s.Stats(), 5)
+}
+
+func TestScheduler_FairQueuing(t *testing.T) {
+	d := newMockDeliverer()
+
+	s := New("service1", &Config{MaxWorkers: 1}, d.deliver, d.failed)
+
+	// Queue the messages before starting so that the order of delivery is deterministic.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Submit(newMessage("https://domain1.com/inbox")))
+	}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Submit(newMessage("https://domain2.com/inbox")))
+	}
+
+	d.release("domain1.com", 3)
+	d.release("domain2.com", 3)
+
+	s.Start()
+	defer s.Stop()
+
+	require.Eventually(t, func() bool { return d.totalDelivered() == 6 }, time.Second, 10*time.Millisecond)
+
+	require.Equal(t,
+		[]string{"domain1.com", "domain2.com", "domain1.com", "domain2.com", "domain1.com", "domain2.com"},
+		d.deliveryOrder(),
+	)
+}
+
+func TestScheduler_Failure(t *testing.T) {
+	d := newMockDeliverer()
+	d.err = errors.New("injected delivery error")
+
+	s := New("service1", &Config{}, d.deliver, d.failed)
+
+	s.Start()
+	defer s.Stop()
+
+	msg := newMessage("https://domain1.com/inbox")
+
+	require.NoError(t, s.Submit(msg))
+
+	d.release("domain1.com", 1)
+
+	require.Eventually(t, func() bool { return len(d.failedMessages()) == 1 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, msg.UUID, d.failedMessages()[0].UUID)
+
+	stats := getStats(s, "domain1.com")
+	require.Equal(t, uint64(0), stats.Delivered)
+	require.Equal(t, uint64(1), stats.Failed)
+	require.Equal(t, 0.0, stats.SuccessRate)
+}
+
+func TestScheduler_Stop(t *testing.T) {
+	t.Run("Queued messages are delivered", func(t *testing.T) {
+		d := newMockDeliverer()
+
+		s := New("service1", &Config{MaxPerHost: 1, DrainTimeout: time.Second}, d.deliver, d.failed)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, s.Submit(newMessage("https://domain1.com/inbox")))
+		}
+
+		d.release("domain1.com", 3)
+
+		s.Start()
+		s.Stop()
+
+		require.Equal(t, 3, d.delivered("domain1.com"))
+		require.Empty(t, d.failedMessages())
+
+		require.True(t, errors.Is(s.Submit(newMessage("https://domain1.com/inbox")), ErrStopped))
+	})
+
+	t.Run("Drain timeout", func(t *testing.T) {
+		d := newMockDeliverer()
+
+		s := New("service1", &Config{MaxPerHost: 1, DrainTimeout: 50 * time.Millisecond}, d.deliver, d.failed)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, s.Submit(newMessage("https://domain1.com/inbox")))
+		}
+
+		d.release("domain1.com", 1)
+
+		s.Start()
+
+		stopped := make(chan struct{})
+
+		go func() {
+			s.Stop()
+
+			close(stopped)
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+
+		// Release the delivery that was in flight when the drain timeout expired.
+		d.release("domain1.com", 1)
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for scheduler to stop")
+		}
+
+		require.Equal(t, 2, d.delivered("domain1.com"))
+
+		// The message that was still queued should have been passed to the failure handler.
+		require.Len(t, d.failedMessages(), 1)
+		require.True(t, errors.Is(d.failureErrors()[0], ErrStopped))
+	})
+}
+
+func TestScheduler_Submit(t *testing.T) {
+	s := New("service1", &Config{MaxQueuedPerHost: 1}, nil, nil)
+
+	t.Run("Queue full", func(t *testing.T) {
+		require.NoError(t, s.Submit(newMessage("https://domain1.com/inbox")))
+
+		err := s.Submit(newMessage("https://domain1.com/inbox"))
+		require.True(t, errors.Is(err, ErrQueueFull))
+
+		require.NoError(t, s.Submit(newMessage("https://domain2.com/inbox")))
+	})
+
+	t.Run("Invalid URL", func(t *testing.T) {
+		err := s.Submit(newMessage(":invalid"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parse URL")
+	})
+}
+
+func TestScheduler_Latency(t *testing.T) {
+	s := New("service1", &Config{}, nil, nil)
+
+	s.hosts["domain1.com"] = &hostQueue{HostStats: HostStats{Host: "domain1.com", InFlight: 2}}
+
+	s.complete("domain1.com", 100*time.Millisecond, nil)
+
+	stats := getStats(s, "domain1.com")
+	require.Equal(t, 100*time.Millisecond, stats.AverageLatency)
+	require.Equal(t, 100*time.Millisecond, stats.LastLatency)
+
+	s.complete("domain1.com", 200*time.Millisecond, nil)
+
+	stats = getStats(s, "domain1.com")
+	require.Equal(t, 120*time.Millisecond, stats.AverageLatency)
+	require.Equal(t, 200*time.Millisecond, stats.LastLatency)
+}
+
+func getStats(s *Scheduler, host string) *HostStats {
+	for _, hs := range s.Stats() {
+		if hs.Host == host {
+			return hs
+		}
+	}
+
+	return nil
+}
+
+func newMessage(to string) *message.Message {
+	msg := message.NewMessage(watermill.NewUUID(), []byte("payload"))
+	msg.Metadata.Set(httppublisher.MetadataSendTo, to)
+
+	return msg
+}
+
+// mockDeliverer blocks each delivery until it is released for the destination host.
+type mockDeliverer struct {
+	mutex     sync.Mutex
+	err       error
+	permits   map[string]chan struct{}
+	inFlight  map[string]int
+	maxFlight map[string]int
+	done      map[string]int
+	order     []string
+	failures  []*message.Message
+	errs      []error
+}
+
+func newMockDeliverer() *mockDeliverer {
+	return &mockDeliverer{
+		permits:   make(map[string]chan struct{}),
+		inFlight:  make(map[string]int),
+		maxFlight: make(map[string]int),
+		done:      make(map[string]int),
+	}
+}
+
+func (m *mockDeliverer) permit(host string) chan struct{} {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	p, ok := m.permits[host]
+	if !ok {
+		p = make(chan struct{}, 100)
+		m.permits[host] = p
+	}
+
+	return p
+}
+
+func (m *mockDeliverer) release(host string, n int) {
+	p := m.permit(host)
+
+	for i := 0; i < n; i++ {
+		p <- struct{}{}
+	}
+}
+
+func (m *mockDeliverer) deliver(msg *message.Message) error {
+	u, err := url.Parse(msg.Metadata[httppublisher.MetadataSendTo])
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	m.inFlight[u.Host]++
+
+	if m.inFlight[u.Host] > m.maxFlight[u.Host] {
+		m.maxFlight[u.Host] = m.inFlight[u.Host]
+	}
+	m.mutex.Unlock()
+
+	<-m.permit(u.Host)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.inFlight[u.Host]--
+	m.done[u.Host]++
+	m.order = append(m.order, u.Host)
+
+	return m.err
+}
+
+func (m *mockDeliverer) failed(msg *message.Message, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.failures = append(m.failures, msg)
+	m.errs = append(m.errs, err)
+}
+
+func (m *mockDeliverer) failureErrors() []error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]error(nil), m.errs...)
+}
+
+func (m *mockDeliverer) delivered(host string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.done[host]
+}
+
+func (m *mockDeliverer) totalDelivered() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	total := 0
+	for _, n := range m.done {
+		total += n
+	}
+
+	return total
+}
+
+func (m *mockDeliverer) maxInFlight(host string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.maxFlight[host]
+}
+
+func (m *mockDeliverer) totalInFlight() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	total := 0
+	for _, n := range m.inFlight {
+		total += n
+	}
+
+	return total
+}
+
+func (m *mockDeliverer) deliveryOrder() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]string(nil), m.order...)
+}
+
+func (m *mockDeliverer) failedMessages() []*message.Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]*message.Message(nil), m.failures...)
+}
@@ -27,7 +27,9 @@ import (
 	"github.com/trustbloc/orb/pkg/activitypub/client"
 	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
 	"github.com/trustbloc/orb/pkg/activitypub/resthandler"
+	"github.com/trustbloc/orb/pkg/activitypub/service/actorresolver"
 	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
+	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/delivery"
 	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/httppublisher"
 	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/redelivery"
 	service "github.com/trustbloc/orb/pkg/activitypub/service/spi"
@@ -48,6 +50,7 @@ type redeliveryService interface {
 	service.ServiceLifecycle
 
 	Add(msg *message.Message) (time.Time, error)
+	Defer(msg *message.Message, at time.Time) error
 }
 
 type pubSub interface {
@@ -64,13 +67,24 @@ type Config struct {
 	RedeliveryConfig      *redelivery.Config
 	MaxRecipients         int
 	MaxConcurrentRequests int
+	ActorCacheTTL         time.Duration
+	Delivery              *delivery.Config
 }
 
 type activityPubClient interface {
-	GetActor(iri *url.URL) (*vocab.ActorType, error)
 	GetReferences(iri *url.URL) (client.ReferenceIterator, error)
 }
 
+type actorResolver interface {
+	GetActor(iri *url.URL) (*vocab.ActorType, error)
+}
+
+// peerHealthChecker is optionally implemented by the HTTP transport. If a peer is not healthy then
+// delivery to the peer is not attempted but is deferred until the peer may be healthy again.
+type peerHealthChecker interface {
+	HealthyAt(u *url.URL) time.Time
+}
+
 // Outbox implements the ActivityPub outbox.
 type Outbox struct {
 	*Config
@@ -78,14 +92,17 @@ type Outbox struct {
 
 	router               *message.Router
 	httpPublisher        message.Publisher
+	scheduler            *delivery.Scheduler
 	publisher            message.Publisher
 	activityHandler      service.ActivityHandler
 	undeliverableHandler service.UndeliverableActivityHandler
 	undeliverableChan    <-chan *message.Message
 	activityStore        store.Store
 	client               activityPubClient
+	actorResolver        actorResolver
 	redeliveryService    redeliveryService
 	redeliveryChan       chan *message.Message
+	peerHealth           peerHealthChecker
 	jsonMarshal          func(v interface{}) ([]byte, error)
 	jsonUnmarshal        func(data []byte, v interface{}) error
 }
@@ -115,6 +132,10 @@ func New(cfg *Config, s store.Store, pubSub pubSub, t httpTransport, activityHan
 		cfg.MaxConcurrentRequests = defaultConcurrentHTTPRequests
 	}
 
+	if cfg.Delivery == nil {
+		cfg.Delivery = &delivery.Config{}
+	}
+
 	h := &Outbox{
 		Config:               cfg,
 		activityHandler:      activityHandler,
@@ -127,6 +148,17 @@ func New(cfg *Config, s store.Store, pubSub pubSub, t httpTransport, activityHan
 		redeliveryService:    redelivery.NewService(cfg.ServiceName, cfg.RedeliveryConfig, redeliverChan),
 		jsonMarshal:          json.Marshal,
 		jsonUnmarshal:        json.Unmarshal,
+		actorResolver: actorresolver.New(
+			&actorresolver.Config{
+				ServiceName: cfg.ServiceName,
+				TTL:         cfg.ActorCacheTTL,
+			},
+			s, t,
+		),
+	}
+
+	if peerHealth, ok := t.(peerHealthChecker); ok {
+		h.peerHealth = peerHealth
 	}
 
 	h.Lifecycle = lifecycle.New(cfg.ServiceName,
@@ -141,18 +173,27 @@ func New(cfg *Config, s store.Store, pubSub pubSub, t httpTransport, activityHan
 
 	httpPublisher := httppublisher.New(cfg.ServiceName, t)
 
-	router.AddHandler(
-		"outbox-"+cfg.ServiceName, cfg.Topic,
-		pubSub, "outbox", httpPublisher,
-		func(msg *message.Message) ([]*message.Message, error) {
-			return message.Messages{msg}, nil
+	scheduler := delivery.New(cfg.ServiceName, cfg.Delivery,
+		func(msg *message.Message) error {
+			return httpPublisher.Publish(cfg.Topic, msg)
 		},
+		h.handleFailedDelivery,
+	)
+
+	// Messages are handed off to the delivery scheduler, which delivers them in parallel. If the scheduler's
+	// queue for the destination host is full then the message is nacked and is therefore redelivered later.
+	// When the outbox is stopped, the scheduler delivers the queued messages (up to its drain timeout) and
+	// passes any remaining messages to handleFailedDelivery so that they're not silently dropped.
+	router.AddNoPublisherHandler(
+		"outbox-"+cfg.ServiceName, cfg.Topic,
+		pubSub, scheduler.Submit,
 	)
 
 	router.AddPlugin(plugin.SignalsHandler)
 
 	h.router = router
 	h.httpPublisher = httpPublisher
+	h.scheduler = scheduler
 
 	return h, nil
 }
@@ -167,6 +208,7 @@ func (h *Outbox) start() {
 	// Start the router
 	go h.route()
 
+	h.scheduler.Start()
 	h.redeliveryService.Start()
 
 	// Wait for router to start
@@ -183,6 +225,14 @@ func (h *Outbox) stop() {
 	} else {
 		logger.Debugf("[%s] Closed router", h.ServiceName)
 	}
+
+	h.scheduler.Stop()
+}
+
+// DeliveryStats returns the delivery statistics (queue size, success rate, latency, etc.) for each
+// destination host.
+func (h *Outbox) DeliveryStats() []*delivery.HostStats {
+	return h.scheduler.Stats()
 }
 
 // Post posts an activity to the outbox and returns the ID of the activity that was posted.
@@ -231,18 +281,49 @@ func (h *Outbox) Post(activity *vocab.ActivityType) (*url.URL, error) {
 	return activity.ID().URL(), nil
 }
 
-func (h *Outbox) publish(id string, activityBytes []byte, to fmt.Stringer) error {
+func (h *Outbox) publish(id string, activityBytes []byte, to *url.URL) error {
 	msg := message.NewMessage(watermill.NewUUID(), activityBytes)
 	msg.Metadata.Set(metadataEventType, h.Topic)
 	msg.Metadata.Set(httppublisher.MetadataSendTo, to.String())
 
 	middleware.SetCorrelationID(id, msg)
 
+	if healthyAt, unhealthy := h.healthyAt(to); unhealthy {
+		logger.Infof("[%s] Peer [%s] is unhealthy. Deferring message [%s] until %s.",
+			h.ServiceName, to.Host, msg.UUID, healthyAt)
+
+		h.deferDelivery(msg, healthyAt)
+
+		return nil
+	}
+
 	logger.Debugf("[%s] Publishing %s", h.ServiceName, h.Topic)
 
 	return h.publisher.Publish(h.Topic, msg)
 }
 
+// healthyAt returns the time at which delivery to the given peer may be attempted and true if the peer
+// is currently unhealthy.
+func (h *Outbox) healthyAt(to *url.URL) (time.Time, bool) {
+	if h.peerHealth == nil {
+		return time.Time{}, false
+	}
+
+	healthyAt := h.peerHealth.HealthyAt(to)
+
+	return healthyAt, healthyAt.After(time.Now())
+}
+
+// deferDelivery schedules the given message for delivery at the given time. Since delivery was not attempted,
+// the redelivery attempts of the message are not incremented.
+func (h *Outbox) deferDelivery(msg *message.Message, at time.Time) {
+	if err := h.redeliveryService.Defer(msg, at); err != nil {
+		logger.Warnf("[%s] Unable to defer delivery of message [%s]: %s", h.ServiceName, msg.UUID, err)
+
+		h.handleUndeliverableActivity(msg)
+	}
+}
+
 func (h *Outbox) route() {
 	logger.Infof("Starting router")
 
@@ -264,6 +345,20 @@ func (h *Outbox) handleRedelivery() {
 	}
 }
 
+func (h *Outbox) handleFailedDelivery(msg *message.Message, err error) {
+	if errors.Is(err, transport.ErrCircuitOpen) {
+		// The request was rejected without being sent, so it doesn't count as a delivery attempt.
+		h.deferRejectedDelivery(msg)
+
+		return
+	}
+
+	logger.Warnf("[%s] Error delivering message [%s] to [%s]: %s", h.ServiceName, msg.UUID,
+		msg.Metadata[httppublisher.MetadataSendTo], err)
+
+	h.handleUndeliverableActivity(msg)
+}
+
 func (h *Outbox) handleUndeliverableActivity(msg *message.Message) {
 	toURL := msg.Metadata[httppublisher.MetadataSendTo]
 
@@ -288,8 +383,38 @@ func (h *Outbox) handleUndeliverableActivity(msg *message.Message) {
 	}
 }
 
+// deferRejectedDelivery defers a message that was rejected by the circuit breaker of the transport until the
+// circuit's open timeout expires or, if the circuit is half-open (i.e. a probe request is in progress), for
+// the initial redelivery backoff.
+func (h *Outbox) deferRejectedDelivery(msg *message.Message) {
+	at := time.Now().Add(h.RedeliveryConfig.InitialBackoff)
+
+	if toURL, err := url.Parse(msg.Metadata[httppublisher.MetadataSendTo]); err == nil {
+		if healthyAt, unhealthy := h.healthyAt(toURL); unhealthy {
+			at = healthyAt
+		}
+	}
+
+	logger.Infof("[%s] Delivery of message [%s] to [%s] was rejected since the peer is unhealthy. Deferring until %s.",
+		h.ServiceName, msg.UUID, msg.Metadata[httppublisher.MetadataSendTo], at)
+
+	h.deferDelivery(msg, at)
+}
+
 func (h *Outbox) redeliver() {
 	for msg := range h.redeliveryChan {
+		toURL, err := url.Parse(msg.Metadata[httppublisher.MetadataSendTo])
+		if err == nil {
+			if healthyAt, unhealthy := h.healthyAt(toURL); unhealthy {
+				logger.Infof("[%s] Peer [%s] is still unhealthy. Deferring message [%s] until %s.",
+					h.ServiceName, toURL.Host, msg.UUID, healthyAt)
+
+				h.deferDelivery(msg, healthyAt)
+
+				continue
+			}
+		}
+
 		logger.Infof("[%s] Attempting to redeliver message [%s]", h.ServiceName, msg.UUID)
 
 		if err := h.publisher.Publish(h.Topic, msg); err != nil {
@@ -300,8 +425,10 @@ func (h *Outbox) redeliver() {
 	}
 }
 
+// resolveInboxes returns the inboxes of the given recipients. If a recipient advertises a shared inbox
+// then the shared inbox is used, so that the activity is posted only once to recipients that share an inbox.
 func (h *Outbox) resolveInboxes(toIRIs []*url.URL) []*url.URL {
-	return h.resolveIRIs(
+	return deduplicate(h.resolveIRIs(
 		deduplicate(h.resolveIRIs(toIRIs, h.resolveActorIRIs)),
 		func(actorIRI *url.URL) ([]*url.URL, error) {
 			inboxIRI, err := h.resolveInbox(actorIRI)
@@ -311,34 +438,17 @@ func (h *Outbox) resolveInboxes(toIRIs []*url.URL) []*url.URL {
 
 			return []*url.URL{inboxIRI}, nil
 		},
-	)
+	))
 }
 
 func (h *Outbox) resolveInbox(iri *url.URL) (*url.URL, error) {
-	actor, err := h.activityStore.GetActor(iri)
+	actor, err := h.actorResolver.GetActor(iri)
 	if err != nil {
-		if !errors.Is(err, store.ErrNotFound) {
-			return nil, fmt.Errorf("unable to load actor %s from storage: %w", iri, err)
-		}
+		return nil, fmt.Errorf("unable to resolve actor %s: %w", iri, err)
 	}
 
-	if actor != nil && actor.Inbox() != nil {
-		logger.Debugf("[%s] Found actor  %s in local store", h.ServiceName, iri)
-
-		return actor.Inbox(), nil
-	}
-
-	logger.Debugf("[%s] Actor not found in local store. Retrieving actor from %s", h.ServiceName, iri)
-
-	actor, err = h.client.GetActor(iri)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add the actor to the local store so that we don't have to retrieve it next time.
-	err = h.activityStore.PutActor(actor)
-	if err != nil {
-		logger.Warnf("[%s] Unable to add actor %s to local storage: %s", h.ServiceName, iri, err)
+	if sharedInbox := actor.SharedInbox(); sharedInbox != nil {
+		return sharedInbox, nil
 	}
 
 	return actor.Inbox(), nil
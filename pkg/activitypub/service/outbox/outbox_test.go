@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package outbox
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -23,6 +24,7 @@ import (
 	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
 
+	clientmocks "github.com/trustbloc/orb/pkg/activitypub/client/mocks"
 	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
 	"github.com/trustbloc/orb/pkg/activitypub/resthandler"
 	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
@@ -434,6 +436,67 @@ func TestOutbox_PostError(t *testing.T) {
 		ob.Stop()
 	})
 
+	t.Run("Unhealthy peer", func(t *testing.T) {
+		undeliverableHandler := mocks.NewUndeliverableHandler()
+
+		httpClient := &clientmocks.HTTPClient{}
+		httpClient.DoReturns(&http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil)
+
+		breaker := transport.NewCircuitBreaker(&transport.CircuitBreakerConfig{
+			FailureThreshold: 1,
+			OpenTimeout:      500 * time.Millisecond,
+		})
+
+		breaker.Failure(service2URL)
+
+		cfg := *cfg
+		cfg.RedeliveryConfig = &redelivery.Config{
+			MaxRetries:     1,
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     10 * time.Millisecond,
+			BackoffFactor:  1,
+			MaxMessages:    10,
+		}
+
+		ob, err := New(&cfg, activityStore, mocks.NewPubSub(),
+			transport.New(httpClient, service1URL, transport.DefaultSigner(), transport.DefaultSigner(),
+				transport.WithCircuitBreaker(breaker)),
+			&mocks.ActivityHandler{}, spi.WithUndeliverableHandler(undeliverableHandler))
+		require.NoError(t, err)
+		require.NotNil(t, ob)
+
+		ob.Start()
+		defer ob.Stop()
+
+		activity := vocab.NewCreateActivity(
+			vocab.NewObjectProperty(
+				vocab.WithObject(
+					vocab.NewObject(
+						vocab.WithIRI(objIRI),
+					),
+				),
+			),
+			vocab.WithTo(service2URL),
+		)
+
+		activityID, err := ob.Post(activity)
+		require.NoError(t, err)
+		require.NotNil(t, activityID)
+
+		time.Sleep(200 * time.Millisecond)
+
+		// Delivery should not have been attempted since the peer is unhealthy. Since the skipped delivery doesn't
+		// count as an attempt, the activity is not undeliverable even though the maximum retries is 1.
+		require.Equal(t, 0, httpClient.DoCallCount())
+		require.Empty(t, undeliverableHandler.Activities())
+
+		time.Sleep(600 * time.Millisecond)
+
+		// Delivery should have been attempted once the open timeout expired.
+		require.Equal(t, 1, httpClient.DoCallCount())
+		require.Empty(t, undeliverableHandler.Activities())
+	})
+
 	t.Run("Handler error", func(t *testing.T) {
 		errExpected := fmt.Errorf("injected handler error")
 
@@ -504,6 +567,77 @@ func TestDeduplicate(t *testing.T) {
 	require.Len(t, deduplicate([]*url.URL{service1URL, service2URL, service1URL, service2URL}), 2)
 }
 
+func TestOutbox_ResolveInboxes(t *testing.T) {
+	service1URL := testutil.MustParseURL("http://localhost:8002/services/service1")
+	service2URL := testutil.MustParseURL("http://domain1.com/services/service2")
+	service3URL := testutil.MustParseURL("http://domain1.com/services/service3")
+	service4URL := testutil.MustParseURL("http://domain2.com/services/service4")
+
+	sharedInboxURL := testutil.MustParseURL("http://domain1.com/services/sharedinbox")
+
+	actors := map[string]*vocab.ActorType{
+		service2URL.String(): newMockActor(service2URL, sharedInboxURL),
+		service3URL.String(): newMockActor(service3URL, sharedInboxURL),
+		service4URL.String(): newMockActor(service4URL, nil),
+	}
+
+	activityStore := memstore.New("service1")
+
+	for _, actor := range actors {
+		require.NoError(t, activityStore.PutActor(actor))
+	}
+
+	ob, err := New(
+		&Config{
+			ServiceName: "service1",
+			ServiceIRI:  service1URL,
+			Topic:       "activities",
+		},
+		activityStore, mocks.NewPubSub(), transport.Default(),
+		&mocks.ActivityHandler{}, spi.WithUndeliverableHandler(mocks.NewUndeliverableHandler()),
+	)
+	require.NoError(t, err)
+
+	ob.actorResolver = &mockActorResolver{actors: actors}
+
+	inboxes := ob.resolveInboxes([]*url.URL{service2URL, service3URL, service4URL})
+	require.Len(t, inboxes, 2)
+
+	inboxMap := make(map[string]struct{})
+	for _, inbox := range inboxes {
+		inboxMap[inbox.String()] = struct{}{}
+	}
+
+	require.Contains(t, inboxMap, sharedInboxURL.String())
+	require.Contains(t, inboxMap, testutil.NewMockID(service4URL, resthandler.InboxPath).String())
+}
+
+type mockActorResolver struct {
+	actors map[string]*vocab.ActorType
+}
+
+func (m *mockActorResolver) GetActor(iri *url.URL) (*vocab.ActorType, error) {
+	actor, ok := m.actors[iri.String()]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return actor, nil
+}
+
+func newMockActor(serviceIRI, sharedInbox *url.URL) *vocab.ActorType {
+	opts := []vocab.Opt{
+		vocab.WithInbox(testutil.NewMockID(serviceIRI, resthandler.InboxPath)),
+		vocab.WithOutbox(testutil.NewMockID(serviceIRI, resthandler.OutboxPath)),
+	}
+
+	if sharedInbox != nil {
+		opts = append(opts, vocab.WithSharedInbox(sharedInbox))
+	}
+
+	return vocab.NewService(serviceIRI, opts...)
+}
+
 type testHandler struct {
 	path    string
 	method  string
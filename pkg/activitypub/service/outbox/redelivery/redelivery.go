@@ -76,6 +76,7 @@ type Service struct {
 	notifyChan  chan<- *message.Message
 	entryChan   chan *entry
 	done        chan struct{}
+	quit        chan struct{}
 	wg          sync.WaitGroup
 }
 
@@ -91,6 +92,7 @@ func NewService(serviceName string, cfg *Config, notifyChan chan<- *message.Mess
 		notifyChan:  notifyChan,
 		entryChan:   make(chan *entry, cfg.MaxMessages),
 		done:        make(chan struct{}),
+		quit:        make(chan struct{}),
 	}
 
 	m.Lifecycle = lifecycle.New(serviceName+"-redelivery",
@@ -144,6 +146,29 @@ func (m *Service) Add(msg *message.Message) (time.Time, error) {
 	return time.Now().Add(backoff), nil
 }
 
+// Defer adds a message for delivery at the given time. Unlike Add, the redelivery attempts of the message are
+// not incremented since delivery of the message was not attempted (for example, because the destination is
+// known to be unavailable). As with Add, this function may block if the MaxMessages limit has been reached.
+func (m *Service) Defer(msg *message.Message, at time.Time) error {
+	if m.State() != service.StateStarted {
+		return service.ErrNotStarted
+	}
+
+	delay := time.Until(at)
+	if delay < 0 {
+		delay = 0
+	}
+
+	m.entryChan <- &entry{
+		msg:   msg,
+		delay: delay,
+	}
+
+	logger.Debugf("[%s] Deferring delivery of message: ID [%s], Delay [%s]", m.serviceName, msg.UUID, delay)
+
+	return nil
+}
+
 func (m *Service) start() {
 	logger.Infof("[%s] Redelivery service started.", m.serviceName)
 
@@ -151,6 +176,9 @@ func (m *Service) start() {
 }
 
 func (m *Service) stop() {
+	// Abort the messages that are waiting to be redelivered.
+	close(m.quit)
+
 	m.done <- struct{}{}
 
 	logger.Debugf("[%s] Waiting for monitor to stop ...", m.serviceName)
@@ -183,9 +211,18 @@ func (m *Service) monitor() {
 }
 
 func (m *Service) redeliver(entry *entry) {
+	defer m.wg.Done()
+
 	logger.Debugf("[%s] Waiting %s to redeliver message %s", m.serviceName, entry.delay, entry.msg.UUID)
 
-	<-time.After(entry.delay)
+	select {
+	case <-time.After(entry.delay):
+	case <-m.quit:
+		logger.Warnf("[%s] Redelivery service stopped before message %s was redelivered",
+			m.serviceName, entry.msg.UUID)
+
+		return
+	}
 
 	logger.Debugf("[%s] Submitting message %s after waiting %s ...",
 		m.serviceName, entry.msg.UUID, entry.delay)
@@ -194,8 +231,6 @@ func (m *Service) redeliver(entry *entry) {
 
 	logger.Debugf("[%s] ... submitted message %s after waiting %s",
 		m.serviceName, entry.msg.UUID, entry.delay)
-
-	m.wg.Done()
 }
 
 func (m *Service) backoff(retries int) time.Duration {
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package redelivery
 
 import (
+	"errors"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -14,6 +15,8 @@ import (
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/spi"
 )
 
 func TestNewService(t *testing.T) {
@@ -78,6 +81,21 @@ func TestService(t *testing.T) {
 		require.Contains(t, err.Error(), "unable to redeliver message after 2 redelivery attempts")
 	})
 
+	t.Run("Defer", func(t *testing.T) {
+		msg := message.NewMessage(watermill.NewUUID(), payload)
+		msg.Metadata[metadataRedeliveryAttempts] = "1"
+
+		require.NoError(t, s.Defer(msg, time.Now().Add(50*time.Millisecond)))
+
+		select {
+		case m := <-notifyChan:
+			// The redelivery attempts should not have been incremented.
+			require.Equal(t, "1", m.Metadata[metadataRedeliveryAttempts])
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for deferred message")
+		}
+	})
+
 	// Add a message and immediately shut down to ensure we don't panic.
 	_, err := s.Add(message.NewMessage(watermill.NewUUID(), payload))
 	require.NoError(t, err)
@@ -147,3 +165,33 @@ func TestServiceStop(t *testing.T) {
 
 	t.Logf("Got %d undeliverable messages", atomic.LoadInt32(&count))
 }
+
+func TestServiceStop_Deferred(t *testing.T) {
+	notifyChan := make(chan *message.Message, 1)
+
+	s := NewService("service1", DefaultConfig(), notifyChan)
+	require.NotNil(t, s)
+
+	require.True(t, errors.Is(s.Defer(message.NewMessage(watermill.NewUUID(), nil), time.Now()), spi.ErrNotStarted))
+
+	s.Start()
+
+	require.NoError(t, s.Defer(message.NewMessage(watermill.NewUUID(), nil), time.Now().Add(time.Minute)))
+
+	// Stop should not wait for the deferred message.
+	stopped := make(chan struct{})
+
+	go func() {
+		s.Stop()
+
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for service to stop")
+	}
+
+	require.Empty(t, notifyChan)
+}
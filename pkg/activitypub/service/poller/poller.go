@@ -0,0 +1,543 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package poller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client"
+	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+var logger = log.New("activitypub_service")
+
+const (
+	storeName = "outbox-poller"
+
+	defaultInterval      = 10 * time.Minute
+	defaultMaxActivities = 100
+	defaultMaxAttempts   = 3
+
+	// maxSkipped is the maximum number of skipped activity IDs that are kept in the cursor.
+	maxSkipped = 100
+)
+
+// Config holds the configuration for the outbox poller.
+type Config struct {
+	// Interval is the interval at which the outboxes of followed services are polled.
+	Interval time.Duration
+
+	// MaxActivities is the maximum number of activities that are processed from an outbox in a single poll.
+	// If there are more new activities then the remaining activities are processed in subsequent polls.
+	MaxActivities int
+
+	// MaxAttempts is the maximum number of polls in which the processing of an activity is attempted. If the
+	// activity still fails then it's skipped so that it doesn't block the activities that follow it.
+	MaxAttempts int
+}
+
+// Cursor contains the position of the poller in the outbox of a followed service.
+type Cursor struct {
+	ActorIRI string `json:"actorIRI"`
+
+	// LastActivityID is the ID of the most recent activity in the outbox up to which all activities
+	// were seen by the poller.
+	LastActivityID string `json:"lastActivityID,omitempty"`
+
+	// GapHeadID is set while the poller is catching up on more than MaxActivities new activities. It is the ID
+	// of the most recent activity at the time that the gap was detected and it becomes the LastActivityID once
+	// all of the activities back to LastActivityID were seen.
+	GapHeadID string `json:"gapHeadID,omitempty"`
+
+	// ResumeActivityID is the ID of the oldest activity in the gap that was seen so far. The next poll continues
+	// with the activities that are older than this one.
+	ResumeActivityID string `json:"resumeActivityID,omitempty"`
+
+	// FailedActivityID is the ID of the activity whose processing failed in the previous poll and Attempts
+	// is the number of times that it failed.
+	FailedActivityID string `json:"failedActivityID,omitempty"`
+	Attempts         int    `json:"attempts,omitempty"`
+
+	// SkippedActivityIDs contains the IDs of the most recent activities that were skipped since they
+	// failed MaxAttempts times.
+	SkippedActivityIDs []string `json:"skippedActivityIDs,omitempty"`
+
+	Updated time.Time `json:"updated"`
+}
+
+type activityClient interface {
+	GetActor(actorIRI *url.URL) (*vocab.ActorType, error)
+	GetActivities(iri *url.URL, order client.Order) (client.ActivityIterator, error)
+}
+
+type activityProcessor interface {
+	HandleActivity(activity *vocab.ActivityType) error
+}
+
+// Poller periodically reads the outbox of each service that the local service is following and processes
+// any activity (addressed to the local service) that never reached the inbox, for example because delivery
+// failed. Activities that were already processed are ignored.
+type Poller struct {
+	*Config
+	*lifecycle.Lifecycle
+
+	serviceIRI    *url.URL
+	activityStore store.Store
+	client        activityClient
+	processor     activityProcessor
+	cursorStore   ariesstorage.Store
+	done          chan struct{}
+	now           func() time.Time
+}
+
+// New returns a new outbox poller.
+func New(cfg *Config, serviceIRI *url.URL, activityStore store.Store, c activityClient,
+	processor activityProcessor, provider ariesstorage.Provider) (*Poller, error) {
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultInterval
+	}
+
+	if cfg.MaxActivities == 0 {
+		cfg.MaxActivities = defaultMaxActivities
+	}
+
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+
+	cursorStore, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("open store [%s]: %w", storeName, err)
+	}
+
+	p := &Poller{
+		Config:        cfg,
+		serviceIRI:    serviceIRI,
+		activityStore: activityStore,
+		client:        c,
+		processor:     processor,
+		cursorStore:   cursorStore,
+		done:          make(chan struct{}),
+		now:           time.Now,
+	}
+
+	p.Lifecycle = lifecycle.New("outbox-poller",
+		lifecycle.WithStart(p.start),
+		lifecycle.WithStop(p.stop),
+	)
+
+	return p, nil
+}
+
+// Poll polls the outbox of each followed service and returns the total number of activities that were
+// recovered. An error polling one service doesn't prevent the other services from being polled.
+func (p *Poller) Poll() (int, error) {
+	actorIRIs, err := p.getFollowing()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+
+	for _, actorIRI := range actorIRIs {
+		recovered, err := p.PollActor(actorIRI)
+		if err != nil {
+			logger.Warnf("Error polling outbox of [%s]: %s", actorIRI, err)
+		}
+
+		total += recovered
+	}
+
+	return total, nil
+}
+
+// PollActor polls the outbox of the given actor and returns the number of activities that were recovered.
+// The first time that an actor is polled, only the position of the most recent activity is recorded.
+func (p *Poller) PollActor(actorIRI *url.URL) (int, error) {
+	actor, err := p.client.GetActor(actorIRI)
+	if err != nil {
+		return 0, fmt.Errorf("get actor [%s]: %w", actorIRI, err)
+	}
+
+	if actor.Outbox() == nil {
+		return 0, fmt.Errorf("actor [%s] has no outbox", actorIRI)
+	}
+
+	cursor, err := p.getCursor(actorIRI)
+	if err != nil {
+		return 0, err
+	}
+
+	activities, complete, err := p.readNewActivities(actor.Outbox(), cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	if cursor.GapHeadID != "" || !complete {
+		return p.processGap(actor, cursor, activities, complete)
+	}
+
+	if len(activities) == 0 {
+		return 0, nil
+	}
+
+	if cursor.LastActivityID == "" {
+		logger.Debugf("Starting to poll outbox [%s] from activity [%s]", actor.Outbox(), activities[0].ID())
+
+		cursor.LastActivityID = activities[0].ID().String()
+
+		return 0, p.putCursor(cursor)
+	}
+
+	recovered := 0
+
+	// The activities are sorted by most recent first so they're processed in reverse order.
+	for i := len(activities) - 1; i >= 0; i-- {
+		activity := activities[i]
+
+		ok, err := p.process(actor, cursor, activity)
+		if err != nil {
+			return recovered, p.checkpoint(cursor, err)
+		}
+
+		if ok {
+			recovered++
+		}
+
+		cursor.LastActivityID = activity.ID().String()
+	}
+
+	if recovered > 0 {
+		logger.Infof("Recovered %d activities from the outbox of [%s] that were not delivered to our inbox",
+			recovered, actorIRI)
+	}
+
+	return recovered, p.putCursor(cursor)
+}
+
+// processGap processes a batch of activities when there are more new activities in the outbox than can be
+// processed in a single poll. The activities are processed from most recent to oldest so that the position
+// of the poller within the gap is always the oldest activity that was processed. Once all of the activities
+// back to the last activity of the cursor were processed (complete is true), the gap is closed.
+func (p *Poller) processGap(actor *vocab.ActorType, cursor *Cursor, activities []*vocab.ActivityType,
+	complete bool) (int, error) {
+	if cursor.GapHeadID == "" {
+		logger.Infof("There are more than %d new activities in outbox [%s]. Older activities will be processed"+
+			" in subsequent polls.", p.MaxActivities, actor.Outbox())
+
+		cursor.GapHeadID = activities[0].ID().String()
+	}
+
+	recovered := 0
+
+	for _, activity := range activities {
+		ok, err := p.process(actor, cursor, activity)
+		if err != nil {
+			return recovered, p.checkpoint(cursor, err)
+		}
+
+		if ok {
+			recovered++
+		}
+
+		cursor.ResumeActivityID = activity.ID().String()
+	}
+
+	if recovered > 0 {
+		logger.Infof("Recovered %d activities from the outbox of [%s] that were not delivered to our inbox",
+			recovered, actor.ID())
+	}
+
+	if complete {
+		logger.Debugf("Caught up on the activities in outbox [%s] up to [%s]", actor.Outbox(), cursor.GapHeadID)
+
+		cursor.LastActivityID = cursor.GapHeadID
+		cursor.GapHeadID = ""
+		cursor.ResumeActivityID = ""
+	}
+
+	return recovered, p.putCursor(cursor)
+}
+
+// Cursor returns the position of the poller in the outbox of the given actor. ErrDataNotFound is returned
+// if the actor was never polled.
+func (p *Poller) Cursor(actorIRI *url.URL) (*Cursor, error) {
+	cursorBytes, err := p.cursorStore.Get(actorIRI.String())
+	if err != nil {
+		return nil, fmt.Errorf("get cursor for [%s]: %w", actorIRI, err)
+	}
+
+	cursor := &Cursor{}
+
+	if err := json.Unmarshal(cursorBytes, cursor); err != nil {
+		return nil, fmt.Errorf("unmarshal cursor for [%s]: %w", actorIRI, err)
+	}
+
+	return cursor, nil
+}
+
+func (p *Poller) start() {
+	go p.pollEvery(p.Interval)
+}
+
+func (p *Poller) stop() {
+	close(p.done)
+}
+
+func (p *Poller) pollEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := p.Poll(); err != nil {
+				logger.Warnf("Error polling outboxes: %s", err)
+			}
+		case <-p.done:
+			logger.Debugf("Stopped outbox poller")
+
+			return
+		}
+	}
+}
+
+// readNewActivities returns the activities in the outbox that are newer than the last activity of the cursor,
+// most recent first, up to the maximum number of activities. If the cursor has a resume point then the activities
+// up to and including the resume point are skipped. True is returned if all of the new activities were read,
+// i.e. if reading stopped at the last activity of the cursor or at the end of the outbox. If the cursor is empty
+// then only the most recent activity is returned.
+func (p *Poller) readNewActivities(outboxIRI *url.URL, cursor *Cursor) ([]*vocab.ActivityType, bool, error) {
+	it, err := p.client.GetActivities(outboxIRI, client.Forward)
+	if err != nil {
+		return nil, false, fmt.Errorf("get activities from outbox [%s]: %w", outboxIRI, err)
+	}
+
+	skipping := cursor.ResumeActivityID != ""
+
+	var activities []*vocab.ActivityType
+
+	for {
+		activity, err := it.Next()
+		if err != nil {
+			if errors.Is(err, client.ErrNotFound) {
+				return activities, true, nil
+			}
+
+			return nil, false, fmt.Errorf("get next activity from outbox [%s]: %w", outboxIRI, err)
+		}
+
+		activityID := activity.ID().String()
+
+		if activityID == cursor.LastActivityID {
+			return activities, true, nil
+		}
+
+		if skipping {
+			skipping = activityID != cursor.ResumeActivityID
+
+			continue
+		}
+
+		if len(activities) == p.MaxActivities {
+			return activities, false, nil
+		}
+
+		activities = append(activities, activity)
+
+		if cursor.LastActivityID == "" {
+			return activities, true, nil
+		}
+	}
+}
+
+// process processes the given activity and returns true if it was processed. An error is returned if the poller
+// must stop at the activity so that it's retried in the next poll. Errors accessing the local activity store are
+// always retried. If the processor fails then the activity is retried in up to MaxAttempts polls, after which
+// it's skipped (and recorded in the cursor) so that a single activity can't block the outbox of the actor.
+func (p *Poller) process(actor *vocab.ActorType, cursor *Cursor, activity *vocab.ActivityType) (bool, error) {
+	activityID := activity.ID().String()
+	retry := cursor.FailedActivityID == activityID
+
+	ok, err := p.processActivity(actor, activity, retry)
+	if err == nil {
+		if retry {
+			cursor.FailedActivityID = ""
+			cursor.Attempts = 0
+		}
+
+		return ok, nil
+	}
+
+	err = fmt.Errorf("process activity [%s]: %w", activityID, err)
+
+	if !isProcessorError(err) {
+		return false, err
+	}
+
+	if !retry {
+		cursor.FailedActivityID = activityID
+		cursor.Attempts = 0
+	}
+
+	cursor.Attempts++
+
+	if cursor.Attempts < p.MaxAttempts {
+		return false, err
+	}
+
+	logger.Errorf("Skipping activity [%s] from the outbox of [%s] after %d failed attempts: %s",
+		activityID, actor.ID(), cursor.Attempts, err)
+
+	cursor.FailedActivityID = ""
+	cursor.Attempts = 0
+	cursor.SkippedActivityIDs = append(cursor.SkippedActivityIDs, activityID)
+
+	if len(cursor.SkippedActivityIDs) > maxSkipped {
+		cursor.SkippedActivityIDs = cursor.SkippedActivityIDs[len(cursor.SkippedActivityIDs)-maxSkipped:]
+	}
+
+	return false, nil
+}
+
+// processActivity passes the given activity to the processor if it's addressed to the local service and
+// it hasn't already been received. True is returned if the activity was processed. If retry is true then
+// a previous attempt to process the activity failed, in which case the activity may already be in the store
+// and the processor determines whether it still needs to be processed.
+func (p *Poller) processActivity(actor *vocab.ActorType, activity *vocab.ActivityType, retry bool) (bool, error) {
+	if activity.Actor() == nil || activity.Actor().String() != actor.ID().String() {
+		logger.Debugf("Ignoring activity [%s] since it wasn't published by [%s]", activity.ID(), actor.ID())
+
+		return false, nil
+	}
+
+	if !p.isAddressedToUs(actor, activity) {
+		return false, nil
+	}
+
+	if !retry {
+		_, err := p.activityStore.GetActivity(activity.ID().URL())
+		if err == nil {
+			// The activity was delivered to our inbox.
+			return false, nil
+		}
+
+		if !errors.Is(err, store.ErrNotFound) {
+			return false, fmt.Errorf("get activity: %w", err)
+		}
+	}
+
+	logger.Debugf("Processing activity [%s] from the outbox of [%s]", activity.ID(), actor.ID())
+
+	if err := p.processor.HandleActivity(activity); err != nil {
+		return false, &processorError{err: err}
+	}
+
+	return true, nil
+}
+
+// processorError indicates that the processor failed to process an activity, as opposed to a failure
+// to access the local activity store.
+type processorError struct {
+	err error
+}
+
+func (e *processorError) Error() string {
+	return e.err.Error()
+}
+
+func (e *processorError) Unwrap() error {
+	return e.err
+}
+
+func isProcessorError(err error) bool {
+	var pe *processorError
+
+	return errors.As(err, &pe)
+}
+
+func (p *Poller) isAddressedToUs(actor *vocab.ActorType, activity *vocab.ActivityType) bool {
+	for _, to := range activity.To() {
+		switch to.String() {
+		case p.serviceIRI.String(), vocab.PublicIRI:
+			return true
+		}
+
+		if actor.Followers() != nil && to.String() == actor.Followers().String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkpoint saves the given cursor and returns the given error.
+func (p *Poller) checkpoint(cursor *Cursor, err error) error {
+	if e := p.putCursor(cursor); e != nil {
+		logger.Errorf("Error saving outbox cursor for [%s]: %s", cursor.ActorIRI, e)
+	}
+
+	return err
+}
+
+func (p *Poller) getCursor(actorIRI *url.URL) (*Cursor, error) {
+	cursor, err := p.Cursor(actorIRI)
+	if err != nil {
+		if errors.Is(err, ariesstorage.ErrDataNotFound) {
+			return &Cursor{ActorIRI: actorIRI.String()}, nil
+		}
+
+		return nil, err
+	}
+
+	return cursor, nil
+}
+
+func (p *Poller) putCursor(cursor *Cursor) error {
+	cursor.Updated = p.now()
+
+	cursorBytes, err := json.Marshal(cursor)
+	if err != nil {
+		return fmt.Errorf("marshal cursor: %w", err)
+	}
+
+	if err := p.cursorStore.Put(cursor.ActorIRI, cursorBytes); err != nil {
+		return fmt.Errorf("store cursor for [%s]: %w", cursor.ActorIRI, err)
+	}
+
+	return nil
+}
+
+func (p *Poller) getFollowing() ([]*url.URL, error) {
+	it, err := p.activityStore.QueryReferences(store.Following, store.NewCriteria(store.WithObjectIRI(p.serviceIRI)))
+	if err != nil {
+		return nil, fmt.Errorf("query following: %w", err)
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warnf("Error closing iterator: %s", e)
+		}
+	}()
+
+	actorIRIs, err := storeutil.ReadReferences(it, -1)
+	if err != nil {
+		return nil, fmt.Errorf("read following: %w", err)
+	}
+
+	return actorIRIs, nil
+}
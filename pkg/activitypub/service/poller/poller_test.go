@@ -0,0 +1,605 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package poller
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client"
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/aptestutil"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+var (
+	serviceIRI   = testutil.MustParseURL("https://example.com/services/orb")
+	service2     = testutil.MustParseURL("https://domain2.com/services/orb")
+	service3     = testutil.MustParseURL("https://domain3.com/services/orb")
+	outboxIRI    = testutil.NewMockID(service2, "/outbox")
+	followersIRI = testutil.NewMockID(service2, "/followers")
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		p, err := New(&Config{}, serviceIRI, memstore.New(""), newMockClient(), newMockProcessor(), mem.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, p)
+		require.Equal(t, defaultInterval, p.Interval)
+		require.Equal(t, defaultMaxActivities, p.MaxActivities)
+	})
+
+	t.Run("Open store error", func(t *testing.T) {
+		provider := mockstore.NewMockStoreProvider()
+		provider.ErrOpenStoreHandle = errors.New("injected open error")
+
+		_, err := New(&Config{}, serviceIRI, memstore.New(""), newMockClient(), newMockProcessor(), provider)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected open error")
+	})
+}
+
+func TestPoller_PollActor(t *testing.T) {
+	activityStore := memstore.New("")
+	processor := newMockProcessor()
+
+	a1 := newCreate(1, followersIRI)
+	c := newMockClient().withOutbox(a1)
+
+	p, err := New(&Config{}, serviceIRI, activityStore, c, processor, mem.NewProvider())
+	require.NoError(t, err)
+
+	t.Run("First poll -> cursor initialized", func(t *testing.T) {
+		recovered, err := p.PollActor(service2)
+		require.NoError(t, err)
+		require.Zero(t, recovered)
+		require.Zero(t, processor.count())
+
+		cursor, err := p.Cursor(service2)
+		require.NoError(t, err)
+		require.Equal(t, a1.ID().String(), cursor.LastActivityID)
+	})
+
+	t.Run("No new activities", func(t *testing.T) {
+		recovered, err := p.PollActor(service2)
+		require.NoError(t, err)
+		require.Zero(t, recovered)
+	})
+
+	t.Run("Missed activities -> recovered", func(t *testing.T) {
+		delivered := newCreate(3, followersIRI)
+		require.NoError(t, activityStore.AddActivity(delivered))
+
+		activities := []*vocab.ActivityType{
+			a1,
+			newCreate(2, serviceIRI),
+			delivered,
+			newCreate(4, testutil.MustParseURL(vocab.PublicIRI)),
+			newCreate(5, service3),
+			vocab.NewCreateActivity(
+				vocab.NewObjectProperty(vocab.WithIRI(testutil.NewMockID(service3, "/objects/6"))),
+				vocab.WithID(testutil.NewMockID(service3, "/activities/6")),
+				vocab.WithActor(service3),
+				vocab.WithTo(followersIRI),
+			),
+			newCreate(7, followersIRI),
+		}
+
+		c.withOutbox(activities...)
+
+		recovered, err := p.PollActor(service2)
+		require.NoError(t, err)
+		require.Equal(t, 3, recovered)
+		require.Equal(t, []string{
+			activities[1].ID().String(),
+			activities[3].ID().String(),
+			activities[6].ID().String(),
+		}, processor.processed())
+
+		cursor, err := p.Cursor(service2)
+		require.NoError(t, err)
+		require.Equal(t, activities[6].ID().String(), cursor.LastActivityID)
+
+		recovered, err = p.PollActor(service2)
+		require.NoError(t, err)
+		require.Zero(t, recovered)
+		require.Equal(t, 3, processor.count())
+	})
+
+	t.Run("Cursor not found -> max activities", func(t *testing.T) {
+		p, err := New(&Config{MaxActivities: 2}, serviceIRI, activityStore, c, newMockProcessor(), mem.NewProvider())
+		require.NoError(t, err)
+
+		activities, complete, err := p.readNewActivities(outboxIRI, &Cursor{LastActivityID: "https://unknown"})
+		require.NoError(t, err)
+		require.Len(t, activities, 2)
+		require.False(t, complete)
+	})
+}
+
+func TestPoller_PollActorGap(t *testing.T) {
+	processor := newMockProcessor()
+
+	activities := make([]*vocab.ActivityType, 8)
+
+	for i := range activities {
+		activities[i] = newCreate(i, followersIRI)
+	}
+
+	c := newMockClient().withOutbox(activities[0])
+
+	p, err := New(&Config{MaxActivities: 3}, serviceIRI, memstore.New(""), c, processor, mem.NewProvider())
+	require.NoError(t, err)
+
+	_, err = p.PollActor(service2)
+	require.NoError(t, err)
+
+	// Seven new activities were added, which is more than the maximum per poll.
+	c.withOutbox(activities...)
+
+	recovered, err := p.PollActor(service2)
+	require.NoError(t, err)
+	require.Equal(t, 3, recovered)
+
+	cursor, err := p.Cursor(service2)
+	require.NoError(t, err)
+	require.Equal(t, activities[0].ID().String(), cursor.LastActivityID)
+	require.Equal(t, activities[7].ID().String(), cursor.GapHeadID)
+	require.Equal(t, activities[5].ID().String(), cursor.ResumeActivityID)
+
+	// More activities are added while the gap is being processed.
+	extra := newCreate(8, followersIRI)
+	c.withOutbox(append(activities, extra)...)
+
+	recovered, err = p.PollActor(service2)
+	require.NoError(t, err)
+	require.Equal(t, 3, recovered)
+
+	cursor, err = p.Cursor(service2)
+	require.NoError(t, err)
+	require.Equal(t, activities[2].ID().String(), cursor.ResumeActivityID)
+
+	// The last activity in the gap is processed and the gap is closed.
+	recovered, err = p.PollActor(service2)
+	require.NoError(t, err)
+	require.Equal(t, 1, recovered)
+
+	cursor, err = p.Cursor(service2)
+	require.NoError(t, err)
+	require.Equal(t, activities[7].ID().String(), cursor.LastActivityID)
+	require.Empty(t, cursor.GapHeadID)
+	require.Empty(t, cursor.ResumeActivityID)
+
+	// The activity that was added while the gap was being processed is processed.
+	recovered, err = p.PollActor(service2)
+	require.NoError(t, err)
+	require.Equal(t, 1, recovered)
+
+	var expected []string
+
+	for i := 7; i > 0; i-- {
+		expected = append(expected, activities[i].ID().String())
+	}
+
+	require.Equal(t, append(expected, extra.ID().String()), processor.processed())
+
+	recovered, err = p.PollActor(service2)
+	require.NoError(t, err)
+	require.Zero(t, recovered)
+}
+
+func TestPoller_Poll(t *testing.T) {
+	activityStore := memstore.New("")
+	processor := newMockProcessor()
+
+	require.NoError(t, activityStore.AddReference(store.Following, serviceIRI, service2))
+
+	c := newMockClient().withOutbox(newCreate(1, followersIRI))
+
+	p, err := New(&Config{}, serviceIRI, activityStore, c, processor, mem.NewProvider())
+	require.NoError(t, err)
+
+	recovered, err := p.Poll()
+	require.NoError(t, err)
+	require.Zero(t, recovered)
+
+	c.withOutbox(newCreate(1, followersIRI), newCreate(2, followersIRI))
+
+	recovered, err = p.Poll()
+	require.NoError(t, err)
+	require.Equal(t, 1, recovered)
+
+	t.Run("Actor error -> ignored", func(t *testing.T) {
+		c.actorErr = errors.New("injected actor error")
+		defer func() { c.actorErr = nil }()
+
+		recovered, err := p.Poll()
+		require.NoError(t, err)
+		require.Zero(t, recovered)
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		s := &mocks.ActivityStore{}
+		s.QueryReferencesReturns(nil, errors.New("injected query error"))
+
+		p, err := New(&Config{}, serviceIRI, s, c, processor, mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = p.Poll()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected query error")
+	})
+}
+
+func TestPoller_Error(t *testing.T) {
+	t.Run("Get actor error", func(t *testing.T) {
+		c := newMockClient()
+		c.actorErr = errors.New("injected actor error")
+
+		p, err := New(&Config{}, serviceIRI, memstore.New(""), c, newMockProcessor(), mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = p.PollActor(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected actor error")
+	})
+
+	t.Run("Get activities error", func(t *testing.T) {
+		c := newMockClient()
+		c.activitiesErr = errors.New("injected activities error")
+
+		p, err := New(&Config{}, serviceIRI, memstore.New(""), c, newMockProcessor(), mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = p.PollActor(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected activities error")
+	})
+
+	t.Run("Iterator error", func(t *testing.T) {
+		c := newMockClient().withOutbox(newCreate(1, followersIRI))
+		c.failAt = 0
+
+		p, err := New(&Config{}, serviceIRI, memstore.New(""), c, newMockProcessor(), mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = p.PollActor(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected iterator error")
+	})
+
+	t.Run("Processor error -> cursor saved", func(t *testing.T) {
+		activities := []*vocab.ActivityType{
+			newCreate(1, followersIRI), newCreate(2, followersIRI), newCreate(3, followersIRI),
+		}
+
+		c := newMockClient().withOutbox(activities[0])
+		processor := newMockProcessor()
+
+		p, err := New(&Config{}, serviceIRI, memstore.New(""), c, processor, mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = p.PollActor(service2)
+		require.NoError(t, err)
+
+		c.withOutbox(activities...)
+		processor.failOn = activities[2].ID().String()
+
+		recovered, err := p.PollActor(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected processor error")
+		require.Equal(t, 1, recovered)
+
+		cursor, err := p.Cursor(service2)
+		require.NoError(t, err)
+		require.Equal(t, activities[1].ID().String(), cursor.LastActivityID)
+
+		processor.failOn = ""
+
+		recovered, err = p.PollActor(service2)
+		require.NoError(t, err)
+		require.Equal(t, 1, recovered)
+	})
+
+	t.Run("Processor always fails -> activity skipped", func(t *testing.T) {
+		activities := []*vocab.ActivityType{
+			newCreate(1, followersIRI), newCreate(2, followersIRI), newCreate(3, followersIRI),
+		}
+
+		c := newMockClient().withOutbox(activities[0])
+		processor := newMockProcessor()
+
+		p, err := New(&Config{MaxAttempts: 2}, serviceIRI, memstore.New(""), c, processor, mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = p.PollActor(service2)
+		require.NoError(t, err)
+
+		c.withOutbox(activities...)
+		processor.failOn = activities[1].ID().String()
+
+		// The first attempt fails and polling stops at the failed activity.
+		recovered, err := p.PollActor(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected processor error")
+		require.Zero(t, recovered)
+
+		cursor, err := p.Cursor(service2)
+		require.NoError(t, err)
+		require.Equal(t, activities[0].ID().String(), cursor.LastActivityID)
+		require.Equal(t, activities[1].ID().String(), cursor.FailedActivityID)
+		require.Equal(t, 1, cursor.Attempts)
+
+		// The second attempt fails so the activity is skipped and the subsequent activity is processed.
+		recovered, err = p.PollActor(service2)
+		require.NoError(t, err)
+		require.Equal(t, 1, recovered)
+		require.Equal(t, []string{activities[2].ID().String()}, processor.processed())
+
+		cursor, err = p.Cursor(service2)
+		require.NoError(t, err)
+		require.Equal(t, activities[2].ID().String(), cursor.LastActivityID)
+		require.Empty(t, cursor.FailedActivityID)
+		require.Zero(t, cursor.Attempts)
+		require.Equal(t, []string{activities[1].ID().String()}, cursor.SkippedActivityIDs)
+
+		recovered, err = p.PollActor(service2)
+		require.NoError(t, err)
+		require.Zero(t, recovered)
+	})
+
+	t.Run("Processor always fails in gap -> activity skipped", func(t *testing.T) {
+		activities := make([]*vocab.ActivityType, 5)
+
+		for i := range activities {
+			activities[i] = newCreate(i, followersIRI)
+		}
+
+		c := newMockClient().withOutbox(activities[0])
+		processor := newMockProcessor()
+
+		p, err := New(&Config{MaxActivities: 2, MaxAttempts: 1}, serviceIRI, memstore.New(""), c, processor,
+			mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = p.PollActor(service2)
+		require.NoError(t, err)
+
+		c.withOutbox(activities...)
+		processor.failOn = activities[3].ID().String()
+
+		for i := 0; i < 3; i++ {
+			_, err = p.PollActor(service2)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []string{
+			activities[4].ID().String(),
+			activities[2].ID().String(),
+			activities[1].ID().String(),
+		}, processor.processed())
+
+		cursor, err := p.Cursor(service2)
+		require.NoError(t, err)
+		require.Equal(t, activities[4].ID().String(), cursor.LastActivityID)
+		require.Empty(t, cursor.GapHeadID)
+		require.Equal(t, []string{activities[3].ID().String()}, cursor.SkippedActivityIDs)
+	})
+
+	t.Run("Activity store error -> not counted as an attempt", func(t *testing.T) {
+		activities := []*vocab.ActivityType{newCreate(1, followersIRI), newCreate(2, followersIRI)}
+
+		c := newMockClient().withOutbox(activities[0])
+
+		activityStore := &mocks.ActivityStore{}
+		activityStore.GetActivityReturns(nil, errors.New("injected store error"))
+
+		p, err := New(&Config{MaxAttempts: 1}, serviceIRI, activityStore, c, newMockProcessor(), mem.NewProvider())
+		require.NoError(t, err)
+
+		_, err = p.PollActor(service2)
+		require.NoError(t, err)
+
+		c.withOutbox(activities...)
+
+		for i := 0; i < 2; i++ {
+			_, err = p.PollActor(service2)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "injected store error")
+		}
+
+		cursor, err := p.Cursor(service2)
+		require.NoError(t, err)
+		require.Equal(t, activities[0].ID().String(), cursor.LastActivityID)
+		require.Empty(t, cursor.SkippedActivityIDs)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		s, err := provider.OpenStore(storeName)
+		require.NoError(t, err)
+		require.NoError(t, s.Put(service2.String(), []byte("{")))
+
+		p, err := New(&Config{}, serviceIRI, memstore.New(""), newMockClient(), newMockProcessor(), provider)
+		require.NoError(t, err)
+
+		_, err = p.PollActor(service2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal cursor")
+
+		_, err = p.Cursor(service3)
+		require.True(t, errors.Is(err, ariesstorage.ErrDataNotFound))
+	})
+}
+
+func TestPoller_StartStop(t *testing.T) {
+	activityStore := memstore.New("")
+	processor := newMockProcessor()
+
+	require.NoError(t, activityStore.AddReference(store.Following, serviceIRI, service2))
+
+	c := newMockClient().withOutbox(newCreate(1, followersIRI))
+
+	p, err := New(&Config{Interval: 10 * time.Millisecond}, serviceIRI, activityStore, c, processor,
+		mem.NewProvider())
+	require.NoError(t, err)
+
+	p.Start()
+	defer p.Stop()
+
+	require.Eventually(t, func() bool {
+		_, err := p.Cursor(service2)
+
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	c.withOutbox(newCreate(1, followersIRI), newCreate(2, followersIRI))
+
+	require.Eventually(t, func() bool {
+		return processor.count() == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func newCreate(i int, to *url.URL) *vocab.ActivityType {
+	return vocab.NewCreateActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(testutil.NewMockID(service2, fmt.Sprintf("/objects/%d", i)))),
+		vocab.WithID(testutil.NewMockID(service2, fmt.Sprintf("/activities/%d", i))),
+		vocab.WithActor(service2),
+		vocab.WithTo(to),
+	)
+}
+
+type mockProcessor struct {
+	mutex  sync.Mutex
+	ids    []string
+	failOn string
+}
+
+func newMockProcessor() *mockProcessor {
+	return &mockProcessor{}
+}
+
+func (m *mockProcessor) HandleActivity(activity *vocab.ActivityType) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if activity.ID().String() == m.failOn {
+		return errors.New("injected processor error")
+	}
+
+	m.ids = append(m.ids, activity.ID().String())
+
+	return nil
+}
+
+func (m *mockProcessor) processed() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]string(nil), m.ids...)
+}
+
+func (m *mockProcessor) count() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.ids)
+}
+
+type mockClient struct {
+	mutex         sync.Mutex
+	activities    []*vocab.ActivityType
+	failAt        int
+	actorErr      error
+	activitiesErr error
+}
+
+func newMockClient() *mockClient {
+	return &mockClient{failAt: -1}
+}
+
+// withOutbox sets the activities in the outbox, ordered from oldest to newest.
+func (m *mockClient) withOutbox(activities ...*vocab.ActivityType) *mockClient {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.activities = activities
+
+	return m
+}
+
+func (m *mockClient) GetActor(actorIRI *url.URL) (*vocab.ActorType, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.actorErr != nil {
+		return nil, m.actorErr
+	}
+
+	return aptestutil.NewMockService(actorIRI), nil
+}
+
+func (m *mockClient) GetActivities(iri *url.URL, order client.Order) (client.ActivityIterator, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.activitiesErr != nil {
+		return nil, m.activitiesErr
+	}
+
+	if iri.String() != outboxIRI.String() || order != client.Forward {
+		return nil, fmt.Errorf("unexpected request for %s in %s order", iri, order)
+	}
+
+	// The outbox is sorted by most recent first.
+	activities := make([]*vocab.ActivityType, len(m.activities))
+
+	for i, activity := range m.activities {
+		activities[len(m.activities)-1-i] = activity
+	}
+
+	return &mockIterator{activities: activities, failAt: m.failAt}, nil
+}
+
+type mockIterator struct {
+	activities []*vocab.ActivityType
+	current    int
+	failAt     int
+}
+
+func (it *mockIterator) Next() (*vocab.ActivityType, error) {
+	if it.current == it.failAt {
+		return nil, errors.New("injected iterator error")
+	}
+
+	if it.current >= len(it.activities) {
+		return nil, client.ErrNotFound
+	}
+
+	activity := it.activities[it.current]
+
+	it.current++
+
+	return activity, nil
+}
+
+func (it *mockIterator) TotalItems() int {
+	return len(it.activities)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retention
+
+import (
+	"errors"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+type vcStore interface {
+	Get(id string) (*verifiable.Credential, error)
+}
+
+// AnchorChecker retains the activities that carry an anchor credential ('Create' and 'Announce') for as long
+// as the anchor credential is in the verifiable credential store. These activities are used by followers to
+// backfill anchor credentials from the outbox and must therefore outlive the retention policy. Since anchor
+// credentials aren't pruned, the retention policies effectively don't apply to these activities.
+type AnchorChecker struct {
+	store vcStore
+}
+
+// NewAnchorChecker returns a new anchor credential checker.
+func NewAnchorChecker(s vcStore) *AnchorChecker {
+	return &AnchorChecker{store: s}
+}
+
+// IsReferenced returns true if the given activity carries an anchor credential that's in the verifiable
+// credential store.
+func (c *AnchorChecker) IsReferenced(activity *vocab.ActivityType) (bool, error) {
+	if !activity.Type().IsAny(vocab.TypeCreate, vocab.TypeAnnounce) {
+		return false, nil
+	}
+
+	for _, vcID := range getAnchorCredentialIRIs(activity) {
+		_, err := c.store.Get(vcID.String())
+		if err != nil {
+			if errors.Is(err, storage.ErrDataNotFound) {
+				continue
+			}
+
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+func TestAnchorChecker_IsReferenced(t *testing.T) {
+	vcID1 := testutil.MustParseURL("https://example.com/vc/vc1")
+	vcID2 := testutil.MustParseURL("https://example.com/vc/vc2")
+
+	c := NewAnchorChecker(&mockVCStore{vcs: map[string]*verifiable.Credential{
+		vcID1.String(): {ID: vcID1.String()},
+	}})
+
+	t.Run("Create", func(t *testing.T) {
+		referenced, err := c.IsReferenced(newCreate(vcID1))
+		require.NoError(t, err)
+		require.True(t, referenced)
+
+		referenced, err = c.IsReferenced(newCreate(vcID2))
+		require.NoError(t, err)
+		require.False(t, referenced)
+	})
+
+	t.Run("Announce", func(t *testing.T) {
+		ref, err := vocab.NewAnchorCredentialReferenceWithDocument(
+			testutil.MustParseURL("https://example.com/anchorcreds/ref1"),
+			testutil.MustParseURL("https://example.com/cas/cid1"), "cid1",
+			vocab.MustUnmarshalToDoc([]byte(fmt.Sprintf(`{"id":"%s","type":"VerifiableCredential"}`, vcID1))),
+		)
+		require.NoError(t, err)
+
+		announce := vocab.NewAnnounceActivity(
+			vocab.NewObjectProperty(
+				vocab.WithCollection(
+					vocab.NewCollection(
+						[]*vocab.ObjectProperty{vocab.NewObjectProperty(vocab.WithAnchorCredentialReference(ref))},
+					),
+				),
+			),
+		)
+
+		referenced, err := c.IsReferenced(announce)
+		require.NoError(t, err)
+		require.True(t, referenced)
+	})
+
+	t.Run("Offer and Like aren't retained", func(t *testing.T) {
+		referenced, err := c.IsReferenced(newOffer(vcID1))
+		require.NoError(t, err)
+		require.False(t, referenced)
+
+		referenced, err = c.IsReferenced(vocab.NewLikeActivity(vocab.NewObjectProperty(vocab.WithIRI(vcID1))))
+		require.NoError(t, err)
+		require.False(t, referenced)
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		c := NewAnchorChecker(&mockVCStore{err: errors.New("injected store error")})
+
+		_, err := c.IsReferenced(newCreate(vcID1))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected store error")
+	})
+}
+
+func newCreate(vcID fmt.Stringer) *vocab.ActivityType {
+	obj, err := vocab.NewObjectWithDocument(vocab.MustUnmarshalToDoc(
+		[]byte(fmt.Sprintf(`{"id":"%s","type":["VerifiableCredential","AnchorCredential"]}`, vcID)),
+	))
+	if err != nil {
+		panic(err)
+	}
+
+	return vocab.NewCreateActivity(vocab.NewObjectProperty(vocab.WithObject(obj)))
+}
+
+type mockVCStore struct {
+	vcs map[string]*verifiable.Credential
+	err error
+}
+
+func (m *mockVCStore) Get(id string) (*verifiable.Credential, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	vc, ok := m.vcs[id]
+	if !ok {
+		return nil, fmt.Errorf("failed to get vc: %w", storage.ErrDataNotFound)
+	}
+
+	return vc, nil
+}
@@ -0,0 +1,600 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+var logger = log.New("activitypub_service")
+
+const defaultInterval = time.Hour
+
+// activityReferenceTypes are the reference types that refer to activities in the activity store
+// (with the local service as the object). Only these reference types may be pruned.
+var activityReferenceTypes = []store.ReferenceType{store.Inbox, store.Outbox, store.Like, store.Liked}
+
+// policyReferenceTypes are the reference types for which a retention policy may be defined. Shares are
+// keyed by the anchor credential rather than by the local service, so the shares policy is applied to the
+// 'Announce' activities in the outbox (which are the activities that are added to shares).
+var policyReferenceTypes = append([]store.ReferenceType{store.Share}, activityReferenceTypes...)
+
+// Policy defines the retention policy for a reference type. An activity is pruned if it is older than
+// MaxAge or if it is beyond the MaxCount most recent activities. A zero value means that there is no limit.
+type Policy struct {
+	MaxAge   time.Duration
+	MaxCount int
+}
+
+// ReferenceChecker determines whether an activity is still referenced (for example by the witness store)
+// and must therefore be retained regardless of the retention policy.
+type ReferenceChecker interface {
+	IsReferenced(activity *vocab.ActivityType) (bool, error)
+}
+
+// Config holds the configuration for the pruner.
+type Config struct {
+	// Policies contains the retention policy for each reference type. Supported reference types are
+	// Inbox, Outbox, Like, Liked and Share.
+	Policies map[store.ReferenceType]Policy
+
+	// Interval is the interval at which activities are pruned.
+	Interval time.Duration
+
+	// ReferenceCheckers determine whether an activity that is eligible for pruning must be retained.
+	ReferenceCheckers []ReferenceChecker
+
+	// AnchorChecker (optional) determines whether an activity carries an anchor credential that's still stored.
+	// Anchor credentials aren't pruned, so retention doesn't apply to these activities. They're reported
+	// separately from the activities that are retained by the reference checkers.
+	AnchorChecker ReferenceChecker
+}
+
+// Report contains the results of a pruning run.
+type Report struct {
+	Time time.Time
+
+	// Removed contains the IDs of the activities that were removed from each reference collection.
+	Removed map[store.ReferenceType][]*url.URL
+
+	// Retained contains the number of activities in each reference collection that were eligible for pruning
+	// but were retained since they're still referenced.
+	Retained map[store.ReferenceType]int
+
+	// RetainedAnchors contains the number of activities in each reference collection that were eligible for
+	// pruning but were retained since they carry an anchor credential (see Config.AnchorChecker).
+	RetainedAnchors map[store.ReferenceType]int
+}
+
+// TotalRemoved returns the total number of activities that were removed.
+func (r *Report) TotalRemoved() int {
+	total := 0
+
+	for _, removed := range r.Removed {
+		total += len(removed)
+	}
+
+	return total
+}
+
+// Pruner periodically removes activities from the ActivityPub store according to the retention policies.
+// An activity is removed from the reference collection (inbox, outbox, etc.) and, once it's no longer in
+// any collection, it's deleted from the activity store. If a deleted activity is an 'Announce' then it's
+// also removed from the shares of the announced anchor credentials.
+type Pruner struct {
+	*Config
+	*lifecycle.Lifecycle
+
+	serviceIRI    *url.URL
+	activityStore store.Store
+	done          chan struct{}
+	mutex         sync.RWMutex
+	lastReport    *Report
+	now           func() time.Time
+}
+
+// New returns a new pruner.
+func New(cfg *Config, serviceIRI *url.URL, activityStore store.Store) (*Pruner, error) {
+	for refType := range cfg.Policies {
+		if !containsReferenceType(policyReferenceTypes, refType) {
+			return nil, fmt.Errorf("retention policy not supported for reference type [%s]", refType)
+		}
+	}
+
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultInterval
+	}
+
+	p := &Pruner{
+		Config:        cfg,
+		serviceIRI:    serviceIRI,
+		activityStore: activityStore,
+		done:          make(chan struct{}),
+		now:           time.Now,
+	}
+
+	p.Lifecycle = lifecycle.New("activity-pruner",
+		lifecycle.WithStart(p.start),
+		lifecycle.WithStop(p.stop),
+	)
+
+	return p, nil
+}
+
+// LastReport returns the report of the most recent pruning run or nil if pruning hasn't run yet.
+func (p *Pruner) LastReport() *Report {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.lastReport
+}
+
+// Prune removes the activities that are outside of the retention policies and returns a report
+// of what was removed.
+func (p *Pruner) Prune() (*Report, error) {
+	report := &Report{
+		Time:            p.now(),
+		Removed:         make(map[store.ReferenceType][]*url.URL),
+		Retained:        make(map[store.ReferenceType]int),
+		RetainedAnchors: make(map[store.ReferenceType]int),
+	}
+
+	for refType, policy := range p.Policies {
+		prune := p.prune
+		if refType == store.Share {
+			prune = p.pruneShares
+		}
+
+		if err := prune(refType, policy, report); err != nil {
+			return report, fmt.Errorf("prune %s: %w", refType, err)
+		}
+	}
+
+	p.mutex.Lock()
+	p.lastReport = report
+	p.mutex.Unlock()
+
+	return report, nil
+}
+
+func (p *Pruner) prune(refType store.ReferenceType, policy Policy, report *Report) error {
+	if policy.MaxAge <= 0 && policy.MaxCount <= 0 {
+		return nil
+	}
+
+	refs, err := p.readReferences(refType)
+	if err != nil {
+		return err
+	}
+
+	excess := 0
+	if policy.MaxCount > 0 && len(refs) > policy.MaxCount {
+		excess = len(refs) - policy.MaxCount
+	}
+
+	cutoff := p.now().Add(-policy.MaxAge)
+
+	for i, activityIRI := range refs {
+		activity, err := p.activityStore.GetActivity(activityIRI)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("get activity [%s]: %w", activityIRI, err)
+		}
+
+		// Every reference is evaluated since the references aren't necessarily in the order of their published
+		// time, which is set by the sender (and activities may be added late, e.g. by the poller or backfill).
+		if i >= excess && !isExpired(activity, policy.MaxAge, cutoff) {
+			continue
+		}
+
+		retain, err := p.retain(refType, activity, report)
+		if err != nil {
+			return err
+		}
+
+		if retain {
+			continue
+		}
+
+		if err := p.remove(refType, activityIRI, activity); err != nil {
+			return err
+		}
+
+		report.Removed[refType] = append(report.Removed[refType], activityIRI)
+	}
+
+	return nil
+}
+
+// pruneShares applies the shares policy to the 'Announce' activities in the outbox. An 'Announce' that's outside
+// of the policy is removed from the shares of the anchor credentials that it announced but it remains in the
+// outbox (which has its own policy).
+func (p *Pruner) pruneShares(refType store.ReferenceType, policy Policy, report *Report) error {
+	if policy.MaxAge <= 0 && policy.MaxCount <= 0 {
+		return nil
+	}
+
+	refs, err := p.readReferences(store.Outbox)
+	if err != nil {
+		return err
+	}
+
+	var announcements []*vocab.ActivityType
+
+	for _, activityIRI := range refs {
+		activity, err := p.activityStore.GetActivity(activityIRI)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+
+			return fmt.Errorf("get activity [%s]: %w", activityIRI, err)
+		}
+
+		if activity.Type().Is(vocab.TypeAnnounce) {
+			announcements = append(announcements, activity)
+		}
+	}
+
+	excess := 0
+	if policy.MaxCount > 0 && len(announcements) > policy.MaxCount {
+		excess = len(announcements) - policy.MaxCount
+	}
+
+	cutoff := p.now().Add(-policy.MaxAge)
+
+	for i, announce := range announcements {
+		if i >= excess && !isExpired(announce, policy.MaxAge, cutoff) {
+			continue
+		}
+
+		shared, err := p.isShared(announce)
+		if err != nil {
+			return err
+		}
+
+		if !shared {
+			continue
+		}
+
+		retain, err := p.retain(refType, announce, report)
+		if err != nil {
+			return err
+		}
+
+		if retain {
+			continue
+		}
+
+		if err := p.removeShares(announce); err != nil {
+			return err
+		}
+
+		report.Removed[refType] = append(report.Removed[refType], announce.ID().URL())
+	}
+
+	return nil
+}
+
+// readReferences returns the references of the given type for the local service in the order in which they
+// were added, i.e. oldest first.
+func (p *Pruner) readReferences(refType store.ReferenceType) ([]*url.URL, error) {
+	it, err := p.activityStore.QueryReferences(refType, store.NewCriteria(store.WithObjectIRI(p.serviceIRI)))
+	if err != nil {
+		return nil, fmt.Errorf("query references: %w", err)
+	}
+
+	refs, err := storeutil.ReadReferences(it, -1)
+
+	if e := it.Close(); e != nil {
+		logger.Warnf("Error closing iterator: %s", e)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read references: %w", err)
+	}
+
+	return refs, nil
+}
+
+// retain returns true if the activity must be retained, either because it's still referenced or because it
+// carries an anchor credential. The retained activity is added to the report.
+func (p *Pruner) retain(refType store.ReferenceType, activity *vocab.ActivityType, report *Report) (bool, error) {
+	referenced, err := p.isReferenced(activity)
+	if err != nil {
+		return false, err
+	}
+
+	if referenced {
+		report.Retained[refType]++
+
+		return true, nil
+	}
+
+	if activity == nil || p.AnchorChecker == nil {
+		return false, nil
+	}
+
+	anchored, err := p.AnchorChecker.IsReferenced(activity)
+	if err != nil {
+		return false, fmt.Errorf("check anchor credential of activity [%s]: %w", activity.ID(), err)
+	}
+
+	if anchored {
+		logger.Debugf("Retaining activity [%s] since it carries a stored anchor credential", activity.ID())
+
+		report.RetainedAnchors[refType]++
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (p *Pruner) isReferenced(activity *vocab.ActivityType) (bool, error) {
+	if activity == nil {
+		return false, nil
+	}
+
+	for _, checker := range p.ReferenceCheckers {
+		referenced, err := checker.IsReferenced(activity)
+		if err != nil {
+			return false, fmt.Errorf("check references of activity [%s]: %w", activity.ID(), err)
+		}
+
+		if referenced {
+			logger.Debugf("Retaining activity [%s] since it's still referenced", activity.ID())
+
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isShared returns true if the 'Announce' activity is in the shares of any of the anchor credentials
+// that it announced.
+func (p *Pruner) isShared(announce *vocab.ActivityType) (bool, error) {
+	for _, objectIRI := range getAnnouncedAnchorCredentialIRIs(announce) {
+		exists, err := p.hasReference(store.Share, objectIRI, announce.ID().URL())
+		if err != nil {
+			return false, err
+		}
+
+		if exists {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// remove removes the activity from the given reference collection and deletes the activity if it's no
+// longer in any other collection.
+func (p *Pruner) remove(refType store.ReferenceType, activityIRI *url.URL, activity *vocab.ActivityType) error {
+	if err := p.activityStore.DeleteReference(refType, p.serviceIRI, activityIRI); err != nil {
+		return fmt.Errorf("delete %s reference [%s]: %w", refType, activityIRI, err)
+	}
+
+	logger.Debugf("Removed activity [%s] from %s", activityIRI, refType)
+
+	if activity == nil {
+		return nil
+	}
+
+	for _, rt := range activityReferenceTypes {
+		exists, err := p.hasReference(rt, p.serviceIRI, activityIRI)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return nil
+		}
+	}
+
+	if activity.Type().Is(vocab.TypeAnnounce) {
+		if err := p.removeShares(activity); err != nil {
+			return err
+		}
+	}
+
+	if err := p.activityStore.DeleteActivity(activityIRI); err != nil {
+		return fmt.Errorf("delete activity [%s]: %w", activityIRI, err)
+	}
+
+	logger.Debugf("Deleted activity [%s]", activityIRI)
+
+	return nil
+}
+
+func (p *Pruner) hasReference(refType store.ReferenceType, objectIRI, activityIRI *url.URL) (bool, error) {
+	it, err := p.activityStore.QueryReferences(refType,
+		store.NewCriteria(store.WithObjectIRI(objectIRI), store.WithReferenceIRI(activityIRI)),
+	)
+	if err != nil {
+		return false, fmt.Errorf("query %s references: %w", refType, err)
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warnf("Error closing iterator: %s", e)
+		}
+	}()
+
+	_, err = it.Next()
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("get next %s reference: %w", refType, err)
+	}
+
+	return true, nil
+}
+
+// removeShares removes the given 'Announce' activity from the shares of each of the anchor credentials
+// that it announced.
+func (p *Pruner) removeShares(announce *vocab.ActivityType) error {
+	for _, objectIRI := range getAnnouncedAnchorCredentialIRIs(announce) {
+		if err := p.activityStore.DeleteReference(store.Share, objectIRI, announce.ID().URL()); err != nil {
+			return fmt.Errorf("delete share [%s] of [%s]: %w", announce.ID(), objectIRI, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Pruner) start() {
+	go p.pruneEvery(p.Interval)
+}
+
+func (p *Pruner) stop() {
+	close(p.done)
+}
+
+func (p *Pruner) pruneEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := p.Prune()
+			if err != nil {
+				logger.Warnf("Error pruning activities: %s", err)
+			}
+
+			logReport(report)
+		case <-p.done:
+			logger.Debugf("Stopped activity pruner")
+
+			return
+		}
+	}
+}
+
+func logReport(report *Report) {
+	if report.TotalRemoved() == 0 {
+		logger.Debugf("No activities were pruned. Retained: %v, retained anchor activities: %v",
+			report.Retained, report.RetainedAnchors)
+
+		return
+	}
+
+	for refType, removed := range report.Removed {
+		logger.Infof("Pruned %d activities from %s: %s", len(removed), refType, removed)
+	}
+
+	for refType, retained := range report.Retained {
+		logger.Infof("Retained %d activities in %s that are still referenced", retained, refType)
+	}
+
+	for refType, retained := range report.RetainedAnchors {
+		logger.Infof("Retained %d activities in %s that carry a stored anchor credential", retained, refType)
+	}
+}
+
+// isExpired returns true if the activity was published (or started) before the given cutoff time. An activity
+// without a published or start time doesn't expire. A reference to an activity that doesn't exist is always
+// considered to be expired.
+func isExpired(activity *vocab.ActivityType, maxAge time.Duration, cutoff time.Time) bool {
+	if maxAge <= 0 {
+		return false
+	}
+
+	if activity == nil {
+		return true
+	}
+
+	t := getTime(activity)
+
+	return t != nil && t.Before(cutoff)
+}
+
+func getTime(activity *vocab.ActivityType) *time.Time {
+	if activity == nil {
+		return nil
+	}
+
+	if t := activity.Published(); t != nil {
+		return t
+	}
+
+	return activity.StartTime()
+}
+
+func containsReferenceType(refTypes []store.ReferenceType, refType store.ReferenceType) bool {
+	for _, rt := range refTypes {
+		if rt == refType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getAnnouncedAnchorCredentialIRIs returns the IRIs under which an 'Announce' activity may have been added to
+// the shares of an anchor credential.
+func getAnnouncedAnchorCredentialIRIs(announce *vocab.ActivityType) []*url.URL {
+	var iris []*url.URL
+
+	for _, ref := range getAnchorCredentialRefs(announce) {
+		if ref.ID() != nil {
+			iris = append(iris, ref.ID().URL())
+		}
+
+		if target := ref.Target(); target != nil && target.Object() != nil && target.Object().ID() != nil {
+			iris = append(iris, target.Object().ID().URL())
+		}
+	}
+
+	return iris
+}
+
+func getAnchorCredentialRefs(activity *vocab.ActivityType) []*vocab.AnchorCredentialReferenceType {
+	obj := activity.Object()
+	if obj == nil {
+		return nil
+	}
+
+	if ref := obj.AnchorCredentialReference(); ref != nil {
+		return []*vocab.AnchorCredentialReferenceType{ref}
+	}
+
+	var items []*vocab.ObjectProperty
+
+	switch {
+	case obj.Collection() != nil:
+		items = obj.Collection().Items()
+	case obj.OrderedCollection() != nil:
+		items = obj.OrderedCollection().Items()
+	default:
+		return nil
+	}
+
+	var refs []*vocab.AnchorCredentialReferenceType
+
+	for _, item := range items {
+		if ref := item.AnchorCredentialReference(); ref != nil {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
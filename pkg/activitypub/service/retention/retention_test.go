@@ -0,0 +1,554 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+var serviceIRI = testutil.MustParseURL("https://example.com/services/orb")
+
+func TestNew(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		p, err := New(&Config{
+			Policies: map[store.ReferenceType]Policy{store.Inbox: {MaxCount: 10}},
+		}, serviceIRI, memstore.New(""))
+		require.NoError(t, err)
+		require.NotNil(t, p)
+		require.Equal(t, defaultInterval, p.Interval)
+		require.Nil(t, p.LastReport())
+	})
+
+	t.Run("Unsupported reference type", func(t *testing.T) {
+		_, err := New(&Config{
+			Policies: map[store.ReferenceType]Policy{store.Follower: {MaxCount: 10}},
+		}, serviceIRI, memstore.New(""))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "retention policy not supported for reference type [FOLLOWER]")
+	})
+}
+
+func TestPruner_MaxCount(t *testing.T) {
+	s := memstore.New("")
+
+	now := time.Now()
+
+	ids := addActivities(t, s, store.Inbox, now, 5)
+
+	p, err := New(&Config{
+		Policies: map[store.ReferenceType]Policy{store.Inbox: {MaxCount: 2}},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, 3, report.TotalRemoved())
+	require.Equal(t, ids[:3], report.Removed[store.Inbox])
+	require.Equal(t, report, p.LastReport())
+
+	require.Equal(t, ids[3:], getReferences(t, s, store.Inbox))
+
+	for _, id := range ids[:3] {
+		_, err := s.GetActivity(id)
+		require.True(t, errors.Is(err, store.ErrNotFound))
+	}
+
+	report, err = p.Prune()
+	require.NoError(t, err)
+	require.Zero(t, report.TotalRemoved())
+}
+
+func TestPruner_MaxAge(t *testing.T) {
+	s := memstore.New("")
+
+	now := time.Now()
+
+	ids := addActivities(t, s, store.Outbox, now.Add(-3*time.Hour), 3)
+
+	// An activity without a published time doesn't expire.
+	noTimeID := testutil.MustParseURL("https://example.com/activities/no-time")
+	require.NoError(t, s.AddActivity(vocab.NewCreateActivity(vocab.NewObjectProperty(), vocab.WithID(noTimeID))))
+	require.NoError(t, s.AddReference(store.Outbox, serviceIRI, noTimeID))
+
+	newIDs := addActivities(t, s, store.Outbox, now, 2)
+
+	p, err := New(&Config{
+		Policies: map[store.ReferenceType]Policy{store.Outbox: {MaxAge: time.Hour}},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	p.now = func() time.Time { return now }
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, ids, report.Removed[store.Outbox])
+
+	require.Equal(t, append([]*url.URL{noTimeID}, newIDs...), getReferences(t, s, store.Outbox))
+}
+
+func TestPruner_MaxAgeOutOfOrder(t *testing.T) {
+	s := memstore.New("")
+
+	now := time.Now()
+
+	// An activity with a published time in the future (e.g. a skewed clock of the sender) doesn't prevent
+	// the expired activities that were added after it from being pruned.
+	futureIDs := addActivities(t, s, store.Inbox, now.Add(24*time.Hour), 1)
+	ids := addActivities(t, s, store.Inbox, now.Add(-3*time.Hour), 3)
+
+	p, err := New(&Config{
+		Policies: map[store.ReferenceType]Policy{store.Inbox: {MaxAge: time.Hour}},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	p.now = func() time.Time { return now }
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, ids, report.Removed[store.Inbox])
+
+	require.Equal(t, futureIDs, getReferences(t, s, store.Inbox))
+}
+
+func TestPruner_MultipleCollections(t *testing.T) {
+	s := memstore.New("")
+
+	ids := addActivities(t, s, store.Outbox, time.Now(), 2)
+
+	// The first activity is also in 'liked'.
+	require.NoError(t, s.AddReference(store.Liked, serviceIRI, ids[0]))
+
+	p, err := New(&Config{
+		Policies: map[store.ReferenceType]Policy{store.Outbox: {MaxCount: 1}, store.Liked: {MaxCount: 1}},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, 1, report.TotalRemoved())
+
+	// The activity is still in 'liked' so it's not deleted.
+	_, err = s.GetActivity(ids[0])
+	require.NoError(t, err)
+
+	p.Policies[store.Liked] = Policy{MaxAge: time.Nanosecond}
+
+	report, err = p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, []*url.URL{ids[0]}, report.Removed[store.Liked])
+
+	_, err = s.GetActivity(ids[0])
+	require.True(t, errors.Is(err, store.ErrNotFound))
+}
+
+func TestPruner_Announce(t *testing.T) {
+	s := memstore.New("")
+
+	refID := testutil.MustParseURL("https://example.com/anchorcreds/ref1")
+	anchorCredID := testutil.MustParseURL("https://example.com/cas/cid1")
+	announceID := testutil.MustParseURL("https://example.com/activities/announce1")
+
+	published := time.Now().Add(-time.Hour)
+
+	announce := vocab.NewAnnounceActivity(
+		vocab.NewObjectProperty(
+			vocab.WithCollection(
+				vocab.NewCollection(
+					[]*vocab.ObjectProperty{
+						vocab.NewObjectProperty(
+							vocab.WithAnchorCredentialReference(
+								vocab.NewAnchorCredentialReference(refID, anchorCredID, "cid1"),
+							),
+						),
+					},
+				),
+			),
+		),
+		vocab.WithID(announceID),
+		vocab.WithPublishedTime(&published),
+	)
+
+	require.NoError(t, s.AddActivity(announce))
+	require.NoError(t, s.AddReference(store.Outbox, serviceIRI, announceID))
+	require.NoError(t, s.AddReference(store.Share, anchorCredID, announceID))
+
+	p, err := New(&Config{
+		Policies: map[store.ReferenceType]Policy{store.Outbox: {MaxAge: time.Minute}},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, 1, report.TotalRemoved())
+
+	it, err := s.QueryReferences(store.Share, store.NewCriteria(store.WithObjectIRI(anchorCredID)))
+	require.NoError(t, err)
+
+	refs, err := storeutil.ReadReferences(it, -1)
+	require.NoError(t, err)
+	require.Empty(t, refs)
+}
+
+func TestPruner_AnnounceOrderedCollection(t *testing.T) {
+	s := memstore.New("")
+
+	anchorCredIDs := []*url.URL{
+		testutil.MustParseURL("https://example.com/cas/cid1"),
+		testutil.MustParseURL("https://example.com/cas/cid2"),
+	}
+
+	announceID := testutil.MustParseURL("https://example.com/activities/announce1")
+
+	var items []*vocab.ObjectProperty
+
+	for i, anchorCredID := range anchorCredIDs {
+		items = append(items, vocab.NewObjectProperty(
+			vocab.WithAnchorCredentialReference(
+				vocab.NewAnchorCredentialReference(
+					testutil.MustParseURL(fmt.Sprintf("https://example.com/anchorcreds/ref%d", i)),
+					anchorCredID, fmt.Sprintf("cid%d", i),
+				),
+			),
+		))
+	}
+
+	published := time.Now().Add(-time.Hour)
+
+	announce := vocab.NewAnnounceActivity(
+		vocab.NewObjectProperty(vocab.WithOrderedCollection(vocab.NewOrderedCollection(items))),
+		vocab.WithID(announceID),
+		vocab.WithPublishedTime(&published),
+	)
+
+	require.NoError(t, s.AddActivity(announce))
+	require.NoError(t, s.AddReference(store.Outbox, serviceIRI, announceID))
+
+	for _, anchorCredID := range anchorCredIDs {
+		require.NoError(t, s.AddReference(store.Share, anchorCredID, announceID))
+	}
+
+	p, err := New(&Config{
+		Policies: map[store.ReferenceType]Policy{store.Outbox: {MaxAge: time.Minute}},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, 1, report.TotalRemoved())
+
+	for _, anchorCredID := range anchorCredIDs {
+		it, err := s.QueryReferences(store.Share, store.NewCriteria(store.WithObjectIRI(anchorCredID)))
+		require.NoError(t, err)
+
+		refs, err := storeutil.ReadReferences(it, -1)
+		require.NoError(t, err)
+		require.Empty(t, refs)
+	}
+}
+
+func TestPruner_Shares(t *testing.T) {
+	s := memstore.New("")
+
+	now := time.Now()
+
+	var announceIDs, anchorCredIDs []*url.URL
+
+	for i := 0; i < 3; i++ {
+		anchorCredID := testutil.MustParseURL(fmt.Sprintf("https://example.com/cas/cid%d", i))
+		announceID := testutil.MustParseURL(fmt.Sprintf("https://example.com/activities/announce%d", i))
+
+		published := now.Add(time.Duration(i-3) * time.Hour)
+
+		require.NoError(t, s.AddActivity(vocab.NewAnnounceActivity(
+			vocab.NewObjectProperty(
+				vocab.WithAnchorCredentialReference(
+					vocab.NewAnchorCredentialReference(
+						testutil.MustParseURL(fmt.Sprintf("https://example.com/anchorcreds/ref%d", i)),
+						anchorCredID, fmt.Sprintf("cid%d", i),
+					),
+				),
+			),
+			vocab.WithID(announceID),
+			vocab.WithPublishedTime(&published),
+		)))
+		require.NoError(t, s.AddReference(store.Outbox, serviceIRI, announceID))
+		require.NoError(t, s.AddReference(store.Share, anchorCredID, announceID))
+
+		announceIDs = append(announceIDs, announceID)
+		anchorCredIDs = append(anchorCredIDs, anchorCredID)
+	}
+
+	// Activities in the outbox that aren't 'Announce' activities are ignored.
+	addActivities(t, s, store.Outbox, now.Add(-5*time.Hour), 1)
+
+	checker := &mockReferenceChecker{referenced: map[string]bool{announceIDs[1].String(): true}}
+
+	p, err := New(&Config{
+		Policies:          map[store.ReferenceType]Policy{store.Share: {MaxCount: 1}},
+		ReferenceCheckers: []ReferenceChecker{checker},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, []*url.URL{announceIDs[0]}, report.Removed[store.Share])
+	require.Equal(t, 1, report.Retained[store.Share])
+
+	getShares := func(anchorCredID *url.URL) []*url.URL {
+		it, err := s.QueryReferences(store.Share, store.NewCriteria(store.WithObjectIRI(anchorCredID)))
+		require.NoError(t, err)
+
+		refs, err := storeutil.ReadReferences(it, -1)
+		require.NoError(t, err)
+
+		return refs
+	}
+
+	require.Empty(t, getShares(anchorCredIDs[0]))
+	require.Equal(t, []*url.URL{announceIDs[1]}, getShares(anchorCredIDs[1]))
+	require.Equal(t, []*url.URL{announceIDs[2]}, getShares(anchorCredIDs[2]))
+
+	// The 'Announce' activities remain in the outbox.
+	require.Len(t, getReferences(t, s, store.Outbox), 4)
+
+	// An 'Announce' that was already removed from shares isn't reported again.
+	report, err = p.Prune()
+	require.NoError(t, err)
+	require.Zero(t, report.TotalRemoved())
+
+	p.Policies[store.Share] = Policy{MaxAge: time.Minute}
+	checker.referenced = nil
+
+	report, err = p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, announceIDs[1:], report.Removed[store.Share])
+}
+
+func TestPruner_RetainedAnchors(t *testing.T) {
+	s := memstore.New("")
+
+	ids := addActivities(t, s, store.Outbox, time.Now().Add(-time.Hour), 3)
+
+	p, err := New(&Config{
+		Policies:          map[store.ReferenceType]Policy{store.Outbox: {MaxAge: time.Minute}},
+		ReferenceCheckers: []ReferenceChecker{&mockReferenceChecker{referenced: map[string]bool{ids[0].String(): true}}},
+		AnchorChecker:     &mockReferenceChecker{referenced: map[string]bool{ids[1].String(): true}},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, []*url.URL{ids[2]}, report.Removed[store.Outbox])
+	require.Equal(t, 1, report.Retained[store.Outbox])
+	require.Equal(t, 1, report.RetainedAnchors[store.Outbox])
+
+	t.Run("Anchor checker error", func(t *testing.T) {
+		p.AnchorChecker = &mockReferenceChecker{err: errors.New("injected anchor checker error")}
+
+		_, err := p.Prune()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected anchor checker error")
+	})
+}
+
+func TestPruner_Retained(t *testing.T) {
+	s := memstore.New("")
+
+	ids := addActivities(t, s, store.Inbox, time.Now().Add(-time.Hour), 3)
+
+	checker := &mockReferenceChecker{referenced: map[string]bool{ids[1].String(): true}}
+
+	p, err := New(&Config{
+		Policies:          map[store.ReferenceType]Policy{store.Inbox: {MaxAge: time.Minute}},
+		ReferenceCheckers: []ReferenceChecker{checker},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, []*url.URL{ids[0], ids[2]}, report.Removed[store.Inbox])
+	require.Equal(t, 1, report.Retained[store.Inbox])
+
+	t.Run("Checker error", func(t *testing.T) {
+		checker.err = errors.New("injected checker error")
+
+		_, err := p.Prune()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected checker error")
+	})
+}
+
+func TestPruner_DanglingReference(t *testing.T) {
+	s := memstore.New("")
+
+	activityID := testutil.MustParseURL("https://example.com/activities/missing")
+
+	require.NoError(t, s.AddReference(store.Like, serviceIRI, activityID))
+
+	p, err := New(&Config{
+		Policies: map[store.ReferenceType]Policy{store.Like: {MaxAge: time.Hour}},
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	report, err := p.Prune()
+	require.NoError(t, err)
+	require.Equal(t, []*url.URL{activityID}, report.Removed[store.Like])
+}
+
+func TestPruner_Error(t *testing.T) {
+	cfg := &Config{Policies: map[store.ReferenceType]Policy{store.Inbox: {MaxCount: 1}}}
+
+	t.Run("Query error", func(t *testing.T) {
+		s := &mocks.ActivityStore{}
+		s.QueryReferencesReturns(nil, errors.New("injected query error"))
+
+		p, err := New(cfg, serviceIRI, s)
+		require.NoError(t, err)
+
+		_, err = p.Prune()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected query error")
+	})
+
+	t.Run("Get activity error", func(t *testing.T) {
+		s := newMockStore(t, 2)
+		s.GetActivityReturns(nil, errors.New("injected get error"))
+
+		p, err := New(cfg, serviceIRI, s)
+		require.NoError(t, err)
+
+		_, err = p.Prune()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected get error")
+	})
+
+	t.Run("Delete reference error", func(t *testing.T) {
+		s := newMockStore(t, 2)
+		s.DeleteReferenceReturns(errors.New("injected delete error"))
+
+		p, err := New(cfg, serviceIRI, s)
+		require.NoError(t, err)
+
+		_, err = p.Prune()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected delete error")
+	})
+
+	t.Run("Delete activity error", func(t *testing.T) {
+		ms := memstore.New("")
+
+		addActivities(t, ms, store.Inbox, time.Now(), 2)
+
+		s := &mocks.ActivityStore{}
+		s.QueryReferencesStub = ms.QueryReferences
+		s.GetActivityStub = ms.GetActivity
+		s.DeleteReferenceStub = ms.DeleteReference
+		s.DeleteActivityReturns(errors.New("injected delete error"))
+
+		p, err := New(cfg, serviceIRI, s)
+		require.NoError(t, err)
+
+		_, err = p.Prune()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected delete error")
+	})
+}
+
+func TestPruner_StartStop(t *testing.T) {
+	s := memstore.New("")
+
+	addActivities(t, s, store.Inbox, time.Now(), 3)
+
+	p, err := New(&Config{
+		Policies: map[store.ReferenceType]Policy{store.Inbox: {MaxCount: 1}},
+		Interval: 10 * time.Millisecond,
+	}, serviceIRI, s)
+	require.NoError(t, err)
+
+	p.Start()
+	defer p.Stop()
+
+	require.Eventually(t, func() bool {
+		report := p.LastReport()
+
+		return report != nil && report.TotalRemoved() == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func addActivities(t *testing.T, s store.Store, refType store.ReferenceType, published time.Time,
+	n int) []*url.URL {
+	t.Helper()
+
+	var ids []*url.URL
+
+	for i := 0; i < n; i++ {
+		id := testutil.MustParseURL(fmt.Sprintf("https://example.com/activities/%s_%d", refType, published.UnixNano()+int64(i)))
+
+		p := published.Add(time.Duration(i) * time.Millisecond)
+
+		require.NoError(t, s.AddActivity(
+			vocab.NewCreateActivity(vocab.NewObjectProperty(), vocab.WithID(id), vocab.WithPublishedTime(&p)),
+		))
+		require.NoError(t, s.AddReference(refType, serviceIRI, id))
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func getReferences(t *testing.T, s store.Store, refType store.ReferenceType) []*url.URL {
+	t.Helper()
+
+	it, err := s.QueryReferences(refType, store.NewCriteria(store.WithObjectIRI(serviceIRI)))
+	require.NoError(t, err)
+
+	refs, err := storeutil.ReadReferences(it, -1)
+	require.NoError(t, err)
+
+	return refs
+}
+
+// newMockStore returns a mock store with the given number of inbox references.
+func newMockStore(t *testing.T, n int) *mocks.ActivityStore {
+	t.Helper()
+
+	ms := memstore.New("")
+
+	addActivities(t, ms, store.Inbox, time.Now(), n)
+
+	s := &mocks.ActivityStore{}
+	s.QueryReferencesStub = ms.QueryReferences
+
+	return s
+}
+
+type mockReferenceChecker struct {
+	referenced map[string]bool
+	err        error
+}
+
+func (m *mockReferenceChecker) IsReferenced(activity *vocab.ActivityType) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+
+	return m.referenced[activity.ID().String()], nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retention
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/anchor/proof"
+	"github.com/trustbloc/orb/pkg/store/witness"
+)
+
+type witnessStore interface {
+	Get(vcID string) ([]*proof.WitnessProof, error)
+}
+
+// WitnessChecker retains anchor-related activities ('Create', 'Announce', 'Offer' and 'Like') for which
+// witnessing is still in progress, i.e. the anchor credential is in the witness store but no witness
+// has provided a proof yet.
+type WitnessChecker struct {
+	store witnessStore
+}
+
+// NewWitnessChecker returns a new witness checker.
+func NewWitnessChecker(s witnessStore) *WitnessChecker {
+	return &WitnessChecker{store: s}
+}
+
+// IsReferenced returns true if the given activity refers to an anchor credential that's still being witnessed.
+func (c *WitnessChecker) IsReferenced(activity *vocab.ActivityType) (bool, error) {
+	for _, vcID := range getAnchorCredentialIRIs(activity) {
+		witnesses, err := c.store.Get(vcID.String())
+		if err != nil {
+			if errors.Is(err, witness.ErrNotFound) {
+				continue
+			}
+
+			return false, err
+		}
+
+		if !hasProof(witnesses) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func hasProof(witnesses []*proof.WitnessProof) bool {
+	for _, w := range witnesses {
+		if len(w.Proof) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getAnchorCredentialIRIs returns the IDs of the anchor credentials to which the given activity refers.
+func getAnchorCredentialIRIs(activity *vocab.ActivityType) []*url.URL {
+	obj := activity.Object()
+	if obj == nil {
+		return nil
+	}
+
+	switch {
+	case activity.Type().IsAny(vocab.TypeCreate, vocab.TypeOffer):
+		if obj.Object() != nil && obj.Object().ID() != nil &&
+			obj.Type().IsAny(vocab.TypeAnchorCredential, vocab.TypeVerifiableCredential) {
+			return []*url.URL{obj.Object().ID().URL()}
+		}
+
+		return getReferencedAnchorCredentialIRIs(activity)
+	case activity.Type().Is(vocab.TypeAnnounce):
+		return getReferencedAnchorCredentialIRIs(activity)
+	case activity.Type().Is(vocab.TypeLike):
+		if obj.IRI() != nil {
+			return []*url.URL{obj.IRI()}
+		}
+	}
+
+	return nil
+}
+
+func getReferencedAnchorCredentialIRIs(activity *vocab.ActivityType) []*url.URL {
+	var iris []*url.URL
+
+	for _, ref := range getAnchorCredentialRefs(activity) {
+		if ref.Object() != nil && ref.Object().Object() != nil && ref.Object().Object().ID() != nil {
+			iris = append(iris, ref.Object().Object().ID().URL())
+		}
+	}
+
+	return iris
+}
@@ -0,0 +1,125 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/anchor/proof"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+	"github.com/trustbloc/orb/pkg/store/witness"
+)
+
+func TestWitnessChecker_IsReferenced(t *testing.T) {
+	vcID1 := testutil.MustParseURL("https://example.com/vc/vc1")
+	vcID2 := testutil.MustParseURL("https://example.com/vc/vc2")
+	vcID3 := testutil.MustParseURL("https://example.com/vc/vc3")
+
+	ws := &mockWitnessStore{
+		witnesses: map[string][]*proof.WitnessProof{
+			// vc1 is still being witnessed.
+			vcID1.String(): {{Type: proof.TypeBatch, Witness: "https://witness1.com"}},
+			// vc2 has been witnessed.
+			vcID2.String(): {
+				{Type: proof.TypeBatch, Witness: "https://witness1.com", Proof: []byte("proof")},
+			},
+		},
+	}
+
+	c := NewWitnessChecker(ws)
+
+	t.Run("Offer", func(t *testing.T) {
+		referenced, err := c.IsReferenced(newOffer(vcID1))
+		require.NoError(t, err)
+		require.True(t, referenced)
+
+		referenced, err = c.IsReferenced(newOffer(vcID2))
+		require.NoError(t, err)
+		require.False(t, referenced)
+
+		referenced, err = c.IsReferenced(newOffer(vcID3))
+		require.NoError(t, err)
+		require.False(t, referenced)
+	})
+
+	t.Run("Like", func(t *testing.T) {
+		referenced, err := c.IsReferenced(vocab.NewLikeActivity(vocab.NewObjectProperty(vocab.WithIRI(vcID1))))
+		require.NoError(t, err)
+		require.True(t, referenced)
+	})
+
+	t.Run("Announce", func(t *testing.T) {
+		ref, err := vocab.NewAnchorCredentialReferenceWithDocument(
+			testutil.MustParseURL("https://example.com/anchorcreds/ref1"),
+			testutil.MustParseURL("https://example.com/cas/cid1"), "cid1",
+			vocab.MustUnmarshalToDoc([]byte(fmt.Sprintf(`{"id":"%s","type":"VerifiableCredential"}`, vcID1))),
+		)
+		require.NoError(t, err)
+
+		announce := vocab.NewAnnounceActivity(
+			vocab.NewObjectProperty(
+				vocab.WithCollection(
+					vocab.NewCollection(
+						[]*vocab.ObjectProperty{vocab.NewObjectProperty(vocab.WithAnchorCredentialReference(ref))},
+					),
+				),
+			),
+		)
+
+		referenced, err := c.IsReferenced(announce)
+		require.NoError(t, err)
+		require.True(t, referenced)
+	})
+
+	t.Run("Other activity", func(t *testing.T) {
+		referenced, err := c.IsReferenced(vocab.NewFollowActivity(vocab.NewObjectProperty(vocab.WithIRI(vcID1))))
+		require.NoError(t, err)
+		require.False(t, referenced)
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		c := NewWitnessChecker(&mockWitnessStore{err: errors.New("injected store error")})
+
+		_, err := c.IsReferenced(newOffer(vcID1))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected store error")
+	})
+}
+
+func newOffer(vcID fmt.Stringer) *vocab.ActivityType {
+	obj, err := vocab.NewObjectWithDocument(vocab.MustUnmarshalToDoc(
+		[]byte(fmt.Sprintf(`{"id":"%s","type":["VerifiableCredential","AnchorCredential"]}`, vcID)),
+	))
+	if err != nil {
+		panic(err)
+	}
+
+	return vocab.NewOfferActivity(vocab.NewObjectProperty(vocab.WithObject(obj)))
+}
+
+type mockWitnessStore struct {
+	witnesses map[string][]*proof.WitnessProof
+	err       error
+}
+
+func (m *mockWitnessStore) Get(vcID string) ([]*proof.WitnessProof, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	w, ok := m.witnesses[vcID]
+	if !ok {
+		return nil, fmt.Errorf("vcID[%s] %w", vcID, witness.ErrNotFound)
+	}
+
+	return w, nil
+}
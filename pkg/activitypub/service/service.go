@@ -14,17 +14,26 @@ import (
 	"time"
 
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
 
+	"github.com/trustbloc/orb/pkg/activitypub/client"
 	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
 	"github.com/trustbloc/orb/pkg/activitypub/resthandler"
 	"github.com/trustbloc/orb/pkg/activitypub/service/activityhandler"
+	"github.com/trustbloc/orb/pkg/activitypub/service/backfill"
 	"github.com/trustbloc/orb/pkg/activitypub/service/inbox"
+	"github.com/trustbloc/orb/pkg/activitypub/service/inbox/ratelimiter"
 	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
 	"github.com/trustbloc/orb/pkg/activitypub/service/mempubsub"
 	"github.com/trustbloc/orb/pkg/activitypub/service/outbox"
+	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/delivery"
 	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/redelivery"
+	"github.com/trustbloc/orb/pkg/activitypub/service/poller"
+	"github.com/trustbloc/orb/pkg/activitypub/service/retention"
 	"github.com/trustbloc/orb/pkg/activitypub/service/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/processedindex"
 	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 )
@@ -52,6 +61,54 @@ type Config struct {
 
 	// MaxWitnessDelay is the maximum delay that the witnessed transaction becomes included into the ledger.
 	MaxWitnessDelay time.Duration
+
+	// ActorCacheTTL is the amount of time that a cached actor is considered to be fresh before
+	// it's refreshed from the remote server.
+	ActorCacheTTL time.Duration
+
+	// AnnounceBatchSize is the maximum number of anchor credentials that are announced to our followers
+	// in a single 'Announce' activity. If neither AnnounceBatchSize nor AnnounceBatchWindow is set then
+	// each anchor credential is announced immediately in its own 'Announce'.
+	AnnounceBatchSize int
+
+	// AnnounceBatchWindow is the maximum amount of time that an anchor credential is buffered before
+	// it's announced to our followers.
+	AnnounceBatchWindow time.Duration
+
+	// RequireDigestAndDate indicates that requests posted to the inbox must contain signed Digest
+	// and Date headers.
+	RequireDigestAndDate bool
+
+	// InboxMaxClockSkew is the maximum difference between the Date header of a request posted
+	// to the inbox and the local time.
+	InboxMaxClockSkew time.Duration
+
+	// ProcessedActivityRetention is the amount of time that the ID of a processed activity is remembered
+	// in order to detect duplicates.
+	ProcessedActivityRetention time.Duration
+
+	// InboxRateLimits contains the per-actor and per-domain rate limits on requests posted to the inbox.
+	// If nil then requests are not rate limited.
+	InboxRateLimits *ratelimiter.Config
+
+	// Retention contains the activity retention policies. If nil then activities are never pruned.
+	Retention *retention.Config
+
+	// Backfill contains the configuration for backfilling the anchor credentials that were published by
+	// a service before it accepted our 'Follow'. If nil then anchor credentials are not backfilled.
+	Backfill *backfill.Config
+
+	// OutboxPolling contains the configuration for periodically polling the outboxes of followed services
+	// for activities that were never delivered to our inbox. If nil then outboxes are not polled.
+	OutboxPolling *poller.Config
+
+	// OutboxDelivery contains the worker limits for delivering activities from the outbox. If nil then
+	// default limits are used.
+	OutboxDelivery *delivery.Config
+
+	// StorageProvider is the storage provider for the service's indexes (e.g. the processed-activity index).
+	// If nil then an in-memory provider is used.
+	StorageProvider ariesstorage.Provider
 }
 
 // Service implements an ActivityPub service which has an inbox, outbox, and
@@ -59,9 +116,16 @@ type Config struct {
 type Service struct {
 	*lifecycle.Lifecycle
 
+	serviceEndpoint string
 	inbox           *inbox.Inbox
 	outbox          *outbox.Outbox
 	activityHandler spi.ActivityHandler
+	outboxHandler   spi.ActivityHandler
+	pruner          *retention.Pruner
+	backfiller      *backfill.Backfiller
+	poller          *poller.Poller
+	rateLimiter     *ratelimiter.Limiter
+	peerHealth      peerHealthProvider
 }
 
 type httpTransport interface {
@@ -78,9 +142,10 @@ func New(cfg *Config, activityStore store.Store, t httpTransport, sigVerifier si
 	handlerOpts ...spi.HandlerOpt) (*Service, error) {
 	outboxHandler := activityhandler.NewOutbox(
 		&activityhandler.Config{
-			ServiceName: cfg.ServiceEndpoint,
-			BufferSize:  cfg.ActivityHandlerBufferSize,
-			ServiceIRI:  cfg.ServiceIRI,
+			ServiceName:   cfg.ServiceEndpoint,
+			BufferSize:    cfg.ActivityHandlerBufferSize,
+			ServiceIRI:    cfg.ServiceIRI,
+			ActorCacheTTL: cfg.ActorCacheTTL,
 		},
 		activityStore, t)
 
@@ -90,6 +155,8 @@ func New(cfg *Config, activityStore store.Store, t httpTransport, sigVerifier si
 			ServiceIRI:       cfg.ServiceIRI,
 			Topic:            activitiesTopic,
 			RedeliveryConfig: cfg.RetryOpts,
+			ActorCacheTTL:    cfg.ActorCacheTTL,
+			Delivery:         cfg.OutboxDelivery,
 		},
 		activityStore, newPubSub(cfg, cfg.ServiceEndpoint+resthandler.OutboxPath),
 		t, outboxHandler, handlerOpts...,
@@ -100,32 +167,91 @@ func New(cfg *Config, activityStore store.Store, t httpTransport, sigVerifier si
 
 	inboxHandler := activityhandler.NewInbox(
 		&activityhandler.Config{
-			ServiceName:     cfg.ServiceEndpoint,
-			BufferSize:      cfg.ActivityHandlerBufferSize,
-			ServiceIRI:      cfg.ServiceIRI,
-			MaxWitnessDelay: cfg.MaxWitnessDelay,
+			ServiceName:         cfg.ServiceEndpoint,
+			BufferSize:          cfg.ActivityHandlerBufferSize,
+			ServiceIRI:          cfg.ServiceIRI,
+			MaxWitnessDelay:     cfg.MaxWitnessDelay,
+			ActorCacheTTL:       cfg.ActorCacheTTL,
+			AnnounceBatchSize:   cfg.AnnounceBatchSize,
+			AnnounceBatchWindow: cfg.AnnounceBatchWindow,
 		},
 		activityStore, ob, t, handlerOpts...)
 
+	storageProvider := cfg.StorageProvider
+	if storageProvider == nil {
+		storageProvider = mem.NewProvider()
+	}
+
+	index, err := processedindex.New(
+		&processedindex.Config{Retention: cfg.ProcessedActivityRetention},
+		storageProvider,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create processed-activity index: %w", err)
+	}
+
+	inboxOpts := []inbox.Opt{inbox.WithProcessedIndex(index)}
+
+	var rateLimiter *ratelimiter.Limiter
+
+	if cfg.InboxRateLimits != nil {
+		rateLimiter = ratelimiter.New(cfg.InboxRateLimits, cfg.ServiceIRI, activityStore)
+
+		inboxOpts = append(inboxOpts, inbox.WithRateLimiter(rateLimiter))
+	}
+
 	ib, err := inbox.New(
 		&inbox.Config{
 			ServiceEndpoint:        cfg.ServiceEndpoint + resthandler.InboxPath,
+			SharedInboxEndpoint:    cfg.ServiceEndpoint + resthandler.SharedInboxPath,
 			ServiceIRI:             cfg.ServiceIRI,
 			Topic:                  activitiesTopic,
 			VerifyActorInSignature: cfg.VerifyActorInSignature,
+			RequireDigestAndDate:   cfg.RequireDigestAndDate,
+			MaxClockSkew:           cfg.InboxMaxClockSkew,
 		},
 		activityStore,
 		newPubSub(cfg, cfg.ServiceEndpoint+resthandler.InboxPath),
-		inboxHandler, sigVerifier,
+		inboxHandler, sigVerifier, inboxOpts...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("create inbox failed: %w", err)
 	}
 
 	s := &Service{
+		serviceEndpoint: cfg.ServiceEndpoint,
 		inbox:           ib,
 		outbox:          ob,
 		activityHandler: inboxHandler,
+		outboxHandler:   outboxHandler,
+		rateLimiter:     rateLimiter,
+	}
+
+	if peerHealth, ok := t.(peerHealthProvider); ok {
+		s.peerHealth = peerHealth
+	}
+
+	if cfg.Retention != nil {
+		s.pruner, err = retention.New(cfg.Retention, cfg.ServiceIRI, activityStore)
+		if err != nil {
+			return nil, fmt.Errorf("create activity pruner: %w", err)
+		}
+	}
+
+	if cfg.Backfill != nil {
+		s.backfiller, err = newBackfiller(cfg, activityStore, t, storageProvider, handlerOpts)
+		if err != nil {
+			return nil, fmt.Errorf("create backfiller: %w", err)
+		}
+
+		s.backfiller.Listen(inboxHandler.Subscribe())
+	}
+
+	if cfg.OutboxPolling != nil {
+		s.poller, err = poller.New(cfg.OutboxPolling, cfg.ServiceIRI, activityStore, client.New(t), ib, storageProvider)
+		if err != nil {
+			return nil, fmt.Errorf("create outbox poller: %w", err)
+		}
 	}
 
 	s.Lifecycle = lifecycle.New(cfg.ServiceEndpoint,
@@ -138,14 +264,42 @@ func New(cfg *Config, activityStore store.Store, t httpTransport, sigVerifier si
 
 func (s *Service) start() {
 	s.activityHandler.Start()
+	s.outboxHandler.Start()
 	s.inbox.Start()
 	s.outbox.Start()
+
+	if s.pruner != nil {
+		s.pruner.Start()
+	}
+
+	if s.backfiller != nil {
+		s.backfiller.Start()
+	}
+
+	if s.poller != nil {
+		s.poller.Start()
+	}
 }
 
 func (s *Service) stop() {
-	s.outbox.Stop()
+	if s.poller != nil {
+		s.poller.Stop()
+	}
+
+	if s.backfiller != nil {
+		s.backfiller.Stop()
+	}
+
+	if s.pruner != nil {
+		s.pruner.Stop()
+	}
+
+	// The inbox is stopped first so that no more activities are received. The activity handler is stopped
+	// before the outbox since it may post buffered activities (e.g. batched announcements) when it's stopped.
 	s.inbox.Stop()
 	s.activityHandler.Stop()
+	s.outbox.Stop()
+	s.outboxHandler.Stop()
 }
 
 // Outbox returns the outbox, which allows clients to post activities.
@@ -153,17 +307,50 @@ func (s *Service) Outbox() spi.Outbox {
 	return s.outbox
 }
 
+// DeliveryStats returns the outbox delivery statistics (queue size, success rate, latency, etc.)
+// for each destination host.
+func (s *Service) DeliveryStats() []*delivery.HostStats {
+	return s.outbox.DeliveryStats()
+}
+
 // InboxHTTPHandler returns the HTTP handler for the inbox which is invoked by the HTTP server.
 // This handler must be registered with an HTTP server.
 func (s *Service) InboxHTTPHandler() common.HTTPHandler {
 	return s.inbox.HTTPHandler()
 }
 
+// SharedInboxHTTPHandler returns the HTTP handler for the shared inbox which is invoked by the HTTP server.
+// This handler must be registered with an HTTP server.
+func (s *Service) SharedInboxHTTPHandler() common.HTTPHandler {
+	return s.inbox.SharedInboxHTTPHandler()
+}
+
 // Subscribe allows a client to receive published activities.
 func (s *Service) Subscribe() <-chan *vocab.ActivityType {
 	return s.activityHandler.Subscribe()
 }
 
+// SubscribeOutbox allows a client to receive the activities that were posted to our outbox.
+func (s *Service) SubscribeOutbox() <-chan *vocab.ActivityType {
+	return s.outboxHandler.Subscribe()
+}
+
+func newBackfiller(cfg *Config, activityStore store.Store, t httpTransport, provider ariesstorage.Provider,
+	handlerOpts []spi.HandlerOpt) (*backfill.Backfiller, error) {
+	handlers := &spi.Handlers{}
+
+	for _, opt := range handlerOpts {
+		opt(handlers)
+	}
+
+	if handlers.AnchorCredentialHandler == nil {
+		return nil, fmt.Errorf("anchor credential handler is required")
+	}
+
+	return backfill.New(cfg.Backfill, cfg.ServiceIRI, activityStore, client.New(t),
+		handlers.AnchorCredentialHandler, provider)
+}
+
 func newPubSub(cfg *Config, serviceName string) PubSub {
 	if cfg.PubSubFactory != nil {
 		return cfg.PubSubFactory(serviceName)
@@ -23,6 +23,7 @@ import (
 	"github.com/google/uuid"
 	mockcrypto "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
 	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
 	"github.com/stretchr/testify/require"
 	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
@@ -30,8 +31,12 @@ import (
 	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
 	"github.com/trustbloc/orb/pkg/activitypub/httpsig"
 	"github.com/trustbloc/orb/pkg/activitypub/resthandler"
+	"github.com/trustbloc/orb/pkg/activitypub/service/backfill"
+	"github.com/trustbloc/orb/pkg/activitypub/service/inbox/ratelimiter"
 	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
 	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/redelivery"
+	"github.com/trustbloc/orb/pkg/activitypub/service/poller"
+	"github.com/trustbloc/orb/pkg/activitypub/service/retention"
 	service "github.com/trustbloc/orb/pkg/activitypub/service/spi"
 	"github.com/trustbloc/orb/pkg/activitypub/service/wmlogger"
 	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
@@ -57,13 +62,23 @@ func TestNewService(t *testing.T) {
 		PubSubFactory: func(serviceName string) PubSub {
 			return mocks.NewPubSub()
 		},
+		InboxRateLimits: &ratelimiter.Config{
+			DomainLimit: ratelimiter.Limit{Rate: 10, Burst: 10},
+		},
+		Retention: &retention.Config{
+			Policies: map[spi.ReferenceType]retention.Policy{spi.Inbox: {MaxCount: 100}},
+		},
+		Backfill:          &backfill.Config{},
+		OutboxPolling:     &poller.Config{},
+		AnnounceBatchSize: 10,
 	}
 
 	store1 := memstore.New(cfg1.ServiceEndpoint)
 	undeliverableHandler1 := mocks.NewUndeliverableHandler()
 
 	service1, err := New(cfg1, store1, transport.Default(), &mocks.SignatureVerifier{},
-		service.WithUndeliverableHandler(undeliverableHandler1))
+		service.WithUndeliverableHandler(undeliverableHandler1),
+		service.WithAnchorCredentialHandler(mocks.NewAnchorCredentialHandler()))
 	require.NoError(t, err)
 
 	stop := startHTTPServer(t, ":8311", service1.InboxHTTPHandler())
@@ -76,6 +91,62 @@ func TestNewService(t *testing.T) {
 	service1.Stop()
 
 	require.Equal(t, service.StateStopped, service1.State())
+
+	t.Run("Invalid retention policy", func(t *testing.T) {
+		cfg := &Config{
+			ServiceEndpoint: "/services/service1",
+			ServiceIRI:      testutil.MustParseURL("http://localhost:8301/services/service1"),
+			Retention: &retention.Config{
+				Policies: map[spi.ReferenceType]retention.Policy{spi.Follower: {MaxCount: 100}},
+			},
+		}
+
+		_, err := New(cfg, memstore.New(cfg.ServiceEndpoint), transport.Default(), &mocks.SignatureVerifier{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "create activity pruner")
+	})
+
+	t.Run("Backfill without anchor credential handler", func(t *testing.T) {
+		cfg := &Config{
+			ServiceEndpoint: "/services/service1",
+			ServiceIRI:      testutil.MustParseURL("http://localhost:8301/services/service1"),
+			Backfill:        &backfill.Config{},
+		}
+
+		_, err := New(cfg, memstore.New(cfg.ServiceEndpoint), transport.Default(), &mocks.SignatureVerifier{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "create backfiller")
+	})
+
+	t.Run("Outbox poller error", func(t *testing.T) {
+		provider := mockstore.NewMockStoreProvider()
+		provider.FailNamespace = "outbox-poller"
+
+		cfg := &Config{
+			ServiceEndpoint: "/services/service1",
+			ServiceIRI:      testutil.MustParseURL("http://localhost:8301/services/service1"),
+			OutboxPolling:   &poller.Config{},
+			StorageProvider: provider,
+		}
+
+		_, err := New(cfg, memstore.New(cfg.ServiceEndpoint), transport.Default(), &mocks.SignatureVerifier{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "create outbox poller")
+	})
+
+	t.Run("Backfill not enabled", func(t *testing.T) {
+		cfg := &Config{
+			ServiceEndpoint: "/services/service1",
+			ServiceIRI:      testutil.MustParseURL("http://localhost:8301/services/service1"),
+		}
+
+		s, err := New(cfg, memstore.New(cfg.ServiceEndpoint), transport.Default(), &mocks.SignatureVerifier{})
+		require.NoError(t, err)
+
+		err = s.Backfill(testutil.MustParseURL("http://localhost:8302/services/service2"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "backfill is not enabled")
+	})
 }
 
 func TestService_Create(t *testing.T) {
@@ -176,6 +247,16 @@ func TestService_Create(t *testing.T) {
 	ua := mockProviders1.undeliverableHandler.Activities()
 	require.Len(t, ua, 1)
 	require.Equal(t, testutil.NewMockID(unavailableServiceIRI, resthandler.InboxPath).String(), ua[0].ToURL)
+
+	stats := service1.DeliveryStats()
+	require.Len(t, stats, 2)
+	require.Equal(t, "localhost:8302", stats[0].Host)
+	require.Equal(t, uint64(1), stats[0].Delivered)
+	require.Equal(t, "localhost:8304", stats[1].Host)
+	require.Equal(t, 0.0, stats[1].SuccessRate)
+
+	// The delivery statistics should also be included in the service statistics.
+	require.Equal(t, stats, service1.Stats().Delivery)
 }
 
 func TestService_Follow(t *testing.T) {
@@ -0,0 +1,100 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
+	"github.com/trustbloc/orb/pkg/activitypub/service/inbox/ratelimiter"
+	"github.com/trustbloc/orb/pkg/activitypub/service/outbox/delivery"
+)
+
+var logger = log.New("activitypub_service")
+
+// StatsPath is the path (relative to the service endpoint) of the endpoint that returns the service statistics.
+const StatsPath = "/stats"
+
+// Stats contains the runtime statistics of the ActivityPub service.
+type Stats struct {
+	// InboxRejections contains the number of inbox requests that were rejected by the rate limiter
+	// for each actor and domain.
+	InboxRejections []*ratelimiter.Rejection `json:"inboxRejections,omitempty"`
+
+	// Delivery contains the outbox delivery statistics for each destination host.
+	Delivery []*delivery.HostStats `json:"delivery,omitempty"`
+
+	// PeerHealth contains the circuit breaker state of each remote host which has recently failed.
+	PeerHealth []*transport.PeerHealth `json:"peerHealth,omitempty"`
+}
+
+// peerHealthProvider is optionally implemented by the HTTP transport if it tracks the health of remote hosts.
+type peerHealthProvider interface {
+	PeerHealth() []*transport.PeerHealth
+}
+
+// Stats returns the runtime statistics of the service.
+func (s *Service) Stats() *Stats {
+	stats := &Stats{
+		Delivery: s.DeliveryStats(),
+	}
+
+	if s.rateLimiter != nil {
+		stats.InboxRejections = s.rateLimiter.Rejections()
+	}
+
+	if s.peerHealth != nil {
+		stats.PeerHealth = s.peerHealth.PeerHealth()
+	}
+
+	return stats
+}
+
+// StatsHTTPHandler returns the HTTP handler that returns the service statistics.
+// This handler must be registered with an HTTP server.
+func (s *Service) StatsHTTPHandler() common.HTTPHandler {
+	return &statsHandler{path: s.serviceEndpoint + StatsPath, stats: s.Stats}
+}
+
+type statsHandler struct {
+	path  string
+	stats func() *Stats
+}
+
+// Path returns the path of the stats endpoint.
+func (h *statsHandler) Path() string {
+	return h.path
+}
+
+// Method returns the HTTP method, which is always GET.
+func (h *statsHandler) Method() string {
+	return http.MethodGet
+}
+
+// Handler returns the handler that writes the service statistics.
+func (h *statsHandler) Handler() common.HTTPRequestHandler {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		statsBytes, err := json.Marshal(h.stats())
+		if err != nil {
+			logger.Errorf("[%s] Error marshalling stats: %s", h.path, err)
+
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if _, err := w.Write(statsBytes); err != nil {
+			logger.Warnf("[%s] Error writing response: %s", h.path, err)
+		}
+	}
+}
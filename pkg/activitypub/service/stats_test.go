@@ -0,0 +1,71 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/client/transport"
+	"github.com/trustbloc/orb/pkg/activitypub/service/inbox/ratelimiter"
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/store/memstore"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+func TestService_Stats(t *testing.T) {
+	cfg := &Config{
+		ServiceEndpoint: "/services/service1",
+		ServiceIRI:      testutil.MustParseURL("http://localhost:8301/services/service1"),
+		InboxRateLimits: &ratelimiter.Config{
+			DomainLimit: ratelimiter.Limit{Rate: 1, Burst: 1},
+		},
+	}
+
+	breaker := transport.NewCircuitBreaker(&transport.CircuitBreakerConfig{FailureThreshold: 1})
+
+	s, err := New(cfg, memstore.New(cfg.ServiceEndpoint),
+		transport.New(http.DefaultClient, cfg.ServiceIRI, transport.DefaultSigner(), transport.DefaultSigner(),
+			transport.WithCircuitBreaker(breaker)),
+		&mocks.SignatureVerifier{})
+	require.NoError(t, err)
+
+	breaker.Failure(testutil.MustParseURL("https://domain1.com/services/orb/inbox"))
+
+	_, ok := s.rateLimiter.Allow(nil, "10.0.0.1:1234", "Create")
+	require.True(t, ok)
+
+	_, ok = s.rateLimiter.Allow(nil, "10.0.0.1:1234", "Create")
+	require.False(t, ok)
+
+	h := s.StatsHTTPHandler()
+	require.Equal(t, "/services/service1/stats", h.Path())
+	require.Equal(t, http.MethodGet, h.Method())
+
+	rw := httptest.NewRecorder()
+
+	h.Handler()(rw, httptest.NewRequest(http.MethodGet, h.Path(), nil))
+
+	result := rw.Result()
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	stats := &Stats{}
+	require.NoError(t, json.NewDecoder(result.Body).Decode(stats))
+	require.NoError(t, result.Body.Close())
+
+	require.Len(t, stats.InboxRejections, 1)
+	require.Equal(t, "domain:10.0.0.1", stats.InboxRejections[0].Key)
+	require.Equal(t, uint64(1), stats.InboxRejections[0].Rejected)
+
+	require.Len(t, stats.PeerHealth, 1)
+	require.Equal(t, "domain1.com", stats.PeerHealth[0].Host)
+	require.Equal(t, transport.CircuitOpen, stats.PeerHealth[0].State)
+}
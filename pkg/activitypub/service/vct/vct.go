@@ -30,6 +30,10 @@ type signer interface {
 	Sign(vc *verifiable.Credential, opts ...vcsigner.Opt) (*verifiable.Credential, error)
 }
 
+type publicKeyResolver interface {
+	GetLogPublicKey(logURL string) ([]byte, error)
+}
+
 // HTTPClient represents HTTP client.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -41,6 +45,7 @@ type Client struct {
 	endpoint       string
 	documentLoader ld.DocumentLoader
 	vct            *vct.Client
+	keyResolver    publicKeyResolver
 }
 
 // ClientOpt represents client option func.
@@ -49,6 +54,7 @@ type ClientOpt func(*clientOptions)
 type clientOptions struct {
 	http           HTTPClient
 	documentLoader ld.DocumentLoader
+	keyResolver    publicKeyResolver
 }
 
 // WithHTTPClient allows providing HTTP client.
@@ -65,6 +71,14 @@ func WithDocumentLoader(loader ld.DocumentLoader) ClientOpt {
 	}
 }
 
+// WithPublicKeyResolver allows providing a resolver that resolves (and caches) the public key of the log.
+// If not provided then the public key is retrieved from the log on each request.
+func WithPublicKeyResolver(resolver publicKeyResolver) ClientOpt {
+	return func(o *clientOptions) {
+		o.keyResolver = resolver
+	}
+}
+
 // New returns the client.
 func New(endpoint string, signer signer, opts ...ClientOpt) *Client {
 	op := &clientOptions{http: &http.Client{
@@ -80,6 +94,7 @@ func New(endpoint string, signer signer, opts ...ClientOpt) *Client {
 		endpoint:       endpoint,
 		documentLoader: op.documentLoader,
 		vct:            vct.New(endpoint, vct.WithHTTPClient(op.http)),
+		keyResolver:    op.keyResolver,
 	}
 }
 
@@ -110,8 +125,7 @@ func (c *Client) Witness(anchorCred []byte) ([]byte, error) {
 		return nil, fmt.Errorf("add proof to credential: %w", err)
 	}
 
-	// TODO: public key probably needs to be discovered by using a web finger.
-	pubKey, err := c.vct.GetPublicKey(context.Background())
+	pubKey, err := c.getPublicKey()
 	if err != nil {
 		return nil, fmt.Errorf("get public key: %w", err)
 	}
@@ -137,13 +151,25 @@ func (c *Client) Witness(anchorCred []byte) ([]byte, error) {
 	}
 
 	return json.Marshal(Proof{
-		Context: []string{ctxSecurity, ctxJWS},
-		Proof:   proof,
+		Context:            []string{ctxSecurity, ctxJWS},
+		Proof:              proof,
+		TimestampSignature: resp.Signature,
 	})
 }
 
-// Proof represents response.
+func (c *Client) getPublicKey() ([]byte, error) {
+	if c.keyResolver != nil {
+		return c.keyResolver.GetLogPublicKey(c.endpoint)
+	}
+
+	return c.vct.GetPublicKey(context.Background())
+}
+
+// Proof represents response. TimestampSignature is the log's signature over the timestamp of the log entry
+// (which is the 'created' time of the proof) and the credential. It allows the receiver of the proof to verify
+// that the 'created' time was signed by the log.
 type Proof struct {
-	Context []string         `json:"@context"`
-	Proof   verifiable.Proof `json:"proof"`
+	Context            []string         `json:"@context"`
+	Proof              verifiable.Proof `json:"proof"`
+	TimestampSignature []byte           `json:"timestampSignature,omitempty"`
 }
@@ -8,7 +8,9 @@ package vct_test
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -174,6 +176,67 @@ func TestClient_Witness(t *testing.T) {
 		require.Error(t, err)
 		require.EqualError(t, err, "add VC: error")
 	})
+
+	t.Run("Public key resolver", func(t *testing.T) {
+		mockHTTP := httpMock(func(req *http.Request) (*http.Response, error) {
+			require.NotEqual(t, "/ct/v1/get-public-key", req.URL.Path)
+
+			return &http.Response{
+				Body:       ioutil.NopCloser(bytes.NewBufferString(mockResponse)),
+				StatusCode: http.StatusOK,
+			}, nil
+		})
+
+		pubKey, err := base64.StdEncoding.DecodeString(
+			"BLlG6D6mEemsE4/jqrW4yrHy98dJ0WzPVxrHohWMdQCPMBR2/93IueMq1XycbaDTHfUsgC5YdVjw3/EY0VfWc2U=",
+		)
+		require.NoError(t, err)
+
+		const endpoint = "https://example.com"
+
+		resolver := &mockKeyResolver{keys: map[string][]byte{endpoint: pubKey}}
+
+		client := New(endpoint, &mockSigner{},
+			WithHTTPClient(mockHTTP),
+			WithDocumentLoader(testutil.GetLoader(t)),
+			WithPublicKeyResolver(resolver),
+		)
+
+		_, err = client.Witness([]byte(mockVC))
+		require.NoError(t, err)
+	})
+
+	t.Run("Public key resolver (error)", func(t *testing.T) {
+		mockHTTP := httpMock(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				Body:       ioutil.NopCloser(bytes.NewBufferString(mockResponse)),
+				StatusCode: http.StatusOK,
+			}, nil
+		})
+
+		client := New("https://example.com", &mockSigner{},
+			WithHTTPClient(mockHTTP),
+			WithDocumentLoader(testutil.GetLoader(t)),
+			WithPublicKeyResolver(&mockKeyResolver{}),
+		)
+
+		_, err := client.Witness([]byte(mockVC))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get public key: key not found")
+	})
+}
+
+type mockKeyResolver struct {
+	keys map[string][]byte
+}
+
+func (m *mockKeyResolver) GetLogPublicKey(logURL string) ([]byte, error) {
+	pubKey, ok := m.keys[logURL]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+
+	return pubKey, nil
 }
 
 type mockSigner struct {
@@ -7,12 +7,15 @@ SPDX-License-Identifier: Apache-2.0
 package ariesstore
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
@@ -25,34 +28,78 @@ import (
 )
 
 const (
-	activityTag      = "Activity"
-	objectIRITagName = "ObjectIRI"
-	timeAddedTagName = "TimeAdded"
+	activityTag         = "Activity"
+	activityTypeTagName = "ActivityType"
+	objectIRITagName    = "ObjectIRI"
+	timeAddedTagName    = "TimeAdded"
+
+	countQueryPageSize = 100
+
+	hashedKeyPrefix = "sha256:"
+
+	// activityTypeMigrationKey is the key of the marker that indicates that the ActivityType tag was added
+	// to the activities that were stored before the tag was introduced.
+	activityTypeMigrationKey = "migration:activity-type-tag"
 )
 
 var logger = log.New("activitypub_store")
 
 // Provider implements an ActivityPub store backed by an Aries storage provider.
 type Provider struct {
-	serviceName     string
-	activityStore   ariesstorage.Store
-	referenceStores map[spi.ReferenceType]ariesstorage.Store
-	actorStore      ariesstorage.Store
+	serviceName         string
+	activityStore       ariesstorage.Store
+	referenceStores     map[spi.ReferenceType]ariesstorage.Store
+	actorStore          ariesstorage.Store
+	limitedQuerySupport bool
+	maxKeyLength        int
+}
+
+// Opt sets a Provider option.
+type Opt func(p *Provider)
+
+// WithLimitedQuerySupport indicates that the underlying storage provider supports tag queries but ignores
+// the sort and paging options and doesn't support bulk retrieval (as is the case with the MySQL provider).
+// In this case the query results are sorted and paged by this store, which requires all of the matching
+// entries to be loaded on each query.
+func WithLimitedQuerySupport() Opt {
+	return func(p *Provider) {
+		p.limitedQuerySupport = true
+	}
+}
+
+// WithMaxKeyLength sets the maximum length of a key supported by the underlying storage provider.
+// Keys that are longer than the maximum length are replaced with a hash of the key.
+func WithMaxKeyLength(maxLength int) Opt {
+	return func(p *Provider) {
+		p.maxKeyLength = maxLength
+	}
 }
 
 // New returns a new ActivityPub storage provider.
-func New(provider ariesstorage.Provider, serviceName string) (*Provider, error) {
+func New(provider ariesstorage.Provider, serviceName string, opts ...Opt) (*Provider, error) {
 	stores, err := openStores(provider)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open stores: %w", err)
 	}
 
-	return &Provider{
+	p := &Provider{
 		serviceName:     serviceName,
 		activityStore:   stores.activities,
 		referenceStores: stores.reference,
 		actorStore:      stores.actor,
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if err := p.migrateActivityTypeTags(); err != nil {
+		// The store is still usable, although activities that were stored before the upgrade won't be returned
+		// from queries by type. The migration is retried on the next startup.
+		logger.Errorf("[%s] Failed to add %s tags to existing activities: %s", serviceName, activityTypeTagName, err)
+	}
+
+	return p, nil
 }
 
 // PutActor stores the given actor.
@@ -64,7 +111,7 @@ func (s *Provider) PutActor(actor *vocab.ActorType) error {
 		return fmt.Errorf("failed to marshal actor: %w", err)
 	}
 
-	err = s.actorStore.Put(actor.ID().String(), actorBytes)
+	err = s.actorStore.Put(s.key(actor.ID().String()), actorBytes)
 	if err != nil {
 		return fmt.Errorf("failed to store actor: %w", err)
 	}
@@ -76,7 +123,7 @@ func (s *Provider) PutActor(actor *vocab.ActorType) error {
 func (s *Provider) GetActor(iri *url.URL) (*vocab.ActorType, error) { //nolint: dupl // false positive
 	logger.Debugf("[%s] Retrieving actor [%s]", s.serviceName, iri)
 
-	actorBytes, err := s.actorStore.Get(iri.String())
+	actorBytes, err := s.actorStore.Get(s.key(iri.String()))
 	if err != nil {
 		if errors.Is(err, ariesstorage.ErrDataNotFound) {
 			return nil, spi.ErrNotFound
@@ -95,6 +142,18 @@ func (s *Provider) GetActor(iri *url.URL) (*vocab.ActorType, error) { //nolint:
 	return &actor, nil
 }
 
+// DeleteActor deletes the actor with the given IRI.
+func (s *Provider) DeleteActor(iri *url.URL) error {
+	logger.Debugf("[%s] Deleting actor [%s]", s.serviceName, iri)
+
+	err := s.actorStore.Delete(s.key(iri.String()))
+	if err != nil {
+		return fmt.Errorf("failed to delete actor [%s]: %w", iri, err)
+	}
+
+	return nil
+}
+
 // AddActivity adds the given activity to the activity store.
 func (s *Provider) AddActivity(activity *vocab.ActivityType) error {
 	logger.Debugf("[%s] Storing activity - Type: %s, ID: %s",
@@ -105,13 +164,21 @@ func (s *Provider) AddActivity(activity *vocab.ActivityType) error {
 		return fmt.Errorf("failed to marshal activity: %w", err)
 	}
 
-	err = s.activityStore.Put(activity.ID().String(), activityBytes,
-		ariesstorage.Tag{
+	tags := []ariesstorage.Tag{
+		{
 			Name: activityTag,
-		}, ariesstorage.Tag{
+		},
+		{
 			Name:  timeAddedTagName,
 			Value: strconv.FormatInt(time.Now().UnixNano(), 10),
-		})
+		},
+	}
+
+	if tag, ok := activityTypeTag(activity); ok {
+		tags = append(tags, tag)
+	}
+
+	err = s.activityStore.Put(s.key(activity.ID().String()), activityBytes, tags...)
 	if err != nil {
 		return fmt.Errorf("failed to store activity: %w", err)
 	}
@@ -119,12 +186,136 @@ func (s *Provider) AddActivity(activity *vocab.ActivityType) error {
 	return nil
 }
 
+// activityTypeTag returns the ActivityType tag for the given activity. Only the first type is indexed since
+// an activity has one type in practice.
+func activityTypeTag(activity *vocab.ActivityType) (ariesstorage.Tag, bool) {
+	types := activity.Type().Types()
+	if len(types) == 0 {
+		return ariesstorage.Tag{}, false
+	}
+
+	return ariesstorage.Tag{Name: activityTypeTagName, Value: string(types[0])}, true
+}
+
+// migrateActivityTypeTags adds the ActivityType tag to the activities that were stored before the tag was
+// introduced, so that they're included in queries by activity type. The migration is performed once, after
+// which a marker is stored in the activity store.
+func (s *Provider) migrateActivityTypeTags() error {
+	_, err := s.activityStore.Get(activityTypeMigrationKey)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, ariesstorage.ErrDataNotFound) {
+		return fmt.Errorf("failed to get migration marker: %w", err)
+	}
+
+	keys, err := s.activitiesWithoutTypeTag()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := s.addActivityTypeTag(key); err != nil {
+			return err
+		}
+	}
+
+	if len(keys) > 0 {
+		logger.Infof("[%s] Added the %s tag to %d existing activities", s.serviceName, activityTypeTagName, len(keys))
+	}
+
+	err = s.activityStore.Put(activityTypeMigrationKey, []byte(time.Now().Format(time.RFC3339)))
+	if err != nil {
+		return fmt.Errorf("failed to store migration marker: %w", err)
+	}
+
+	return nil
+}
+
+// activitiesWithoutTypeTag returns the keys of all activities that don't have the ActivityType tag.
+func (s *Provider) activitiesWithoutTypeTag() ([]string, error) {
+	iterator, err := s.activityStore.Query(activityTag, ariesstorage.WithPageSize(countQueryPageSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query store: %w", err)
+	}
+
+	defer s.close(iterator)
+
+	var keys []string
+
+	for {
+		ok, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine if there are more results: %w", err)
+		}
+
+		if !ok {
+			return keys, nil
+		}
+
+		tags, err := iterator.Tags()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tags: %w", err)
+		}
+
+		if !hasTag(tags, activityTypeTagName) {
+			key, err := iterator.Key()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get key: %w", err)
+			}
+
+			keys = append(keys, key)
+		}
+	}
+}
+
+// addActivityTypeTag stores the activity with the given key along with its existing tags and the ActivityType tag.
+func (s *Provider) addActivityTypeTag(key string) error {
+	activityBytes, err := s.activityStore.Get(key)
+	if err != nil {
+		return fmt.Errorf("failed to get activity [%s]: %w", key, err)
+	}
+
+	tags, err := s.activityStore.GetTags(key)
+	if err != nil {
+		return fmt.Errorf("failed to get tags of activity [%s]: %w", key, err)
+	}
+
+	activity := &vocab.ActivityType{}
+
+	if err := json.Unmarshal(activityBytes, activity); err != nil {
+		return fmt.Errorf("failed to unmarshal activity [%s]: %w", key, err)
+	}
+
+	tag, ok := activityTypeTag(activity)
+	if !ok {
+		return nil
+	}
+
+	if err := s.activityStore.Put(key, activityBytes, append(tags, tag)...); err != nil {
+		return fmt.Errorf("failed to store activity [%s]: %w", key, err)
+	}
+
+	return nil
+}
+
+func hasTag(tags []ariesstorage.Tag, name string) bool {
+	for _, tag := range tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetActivity returns the activity for the given ID from the activity store
 // or ErrNotFound error if it wasn't found.
 func (s *Provider) GetActivity(activityID *url.URL) (*vocab.ActivityType, error) { //nolint: dupl // false positive
 	logger.Debugf("[%s] Retrieving activity - ID: %s", s.serviceName, activityID)
 
-	activityBytes, err := s.activityStore.Get(activityID.String())
+	activityBytes, err := s.activityStore.Get(s.key(activityID.String()))
 	if err != nil {
 		if errors.Is(err, ariesstorage.ErrDataNotFound) {
 			return nil, spi.ErrNotFound
@@ -143,6 +334,18 @@ func (s *Provider) GetActivity(activityID *url.URL) (*vocab.ActivityType, error)
 	return &activity, nil
 }
 
+// DeleteActivity deletes the activity with the given ID from the activity store.
+func (s *Provider) DeleteActivity(activityID *url.URL) error {
+	logger.Debugf("[%s] Deleting activity - ID: %s", s.serviceName, activityID)
+
+	err := s.activityStore.Delete(s.key(activityID.String()))
+	if err != nil {
+		return fmt.Errorf("failed to delete activity [%s]: %w", activityID, err)
+	}
+
+	return nil
+}
+
 // QueryActivities queries the given activity store using the provided criteria
 // and returns a results iterator.
 func (s *Provider) QueryActivities(query *spi.Criteria, opts ...spi.QueryOpt) (spi.ActivityIterator, error) {
@@ -154,213 +357,1116 @@ func (s *Provider) QueryActivities(query *spi.Criteria, opts ...spi.QueryOpt) (s
 		return s.queryActivitiesByRef(query.ReferenceType, query, opts...)
 	}
 
-	if len(query.ActivityIRIs) == 0 && len(query.Types) == 0 { // Get all activities
-		iterator, err := s.activityStore.Query(activityTag,
-			ariesstorage.WithSortOrder(&ariesstorage.SortOptions{
-				Order:   ariesstorage.SortOrder(options.SortOrder),
-				TagName: timeAddedTagName,
-			}),
-			ariesstorage.WithPageSize(options.PageSize),
-			ariesstorage.WithInitialPageNum(options.PageNumber))
-		if err != nil {
-			return nil, fmt.Errorf("failed to query store: %w", err)
-		}
+	if len(query.ActivityIRIs) > 0 {
+		return nil, errors.New("unsupported query criteria")
+	}
 
-		return &activityIterator{ariesIterator: iterator}, nil
+	if storeutil.HasActivityFilter(query) {
+		return s.queryAndFilterActivities(query, options)
 	}
 
-	return nil, errors.New("unsupported query criteria")
-}
+	if len(query.Types) > 1 {
+		return nil, errors.New("unsupported query criteria")
+	}
 
-// AddReference adds the reference of the given type to the given object.
-func (s *Provider) AddReference(referenceType spi.ReferenceType, objectIRI, referenceIRI *url.URL) error {
-	logger.Debugf("[%s] Adding reference of type %s to object %s: %s",
-		s.serviceName, referenceType, objectIRI, referenceIRI)
+	expression := activityTag // Get all activities
 
-	referenceStore, exists := s.referenceStores[referenceType]
-	if !exists {
-		return fmt.Errorf("no store found for %s", string(referenceType))
+	if len(query.Types) == 1 {
+		expression = fmt.Sprintf("%s:%s", activityTypeTagName, query.Types[0])
 	}
 
-	err := referenceStore.Put(objectIRI.String()+referenceIRI.String(),
-		[]byte(referenceIRI.String()), ariesstorage.Tag{
-			Name:  objectIRITagName,
-			Value: base64.RawStdEncoding.EncodeToString([]byte(objectIRI.String())),
-		}, ariesstorage.Tag{
-			Name:  timeAddedTagName,
-			Value: strconv.FormatInt(time.Now().UnixNano(), 10),
-		})
+	iterator, counter, err := s.query(s.activityStore, expression, options, s.key)
 	if err != nil {
-		return fmt.Errorf("failed to store reference: %w", err)
+		return nil, err
 	}
 
-	return nil
+	if iterator == nil {
+		return memstore.NewActivityIterator(nil, counter.TotalItems()), nil
+	}
+
+	return &activityIterator{ariesIterator: iterator, itemCounter: counter}, nil
 }
 
-// DeleteReference deletes the reference of the given type from the given object.
-func (s *Provider) DeleteReference(referenceType spi.ReferenceType, objectIRI, referenceIRI *url.URL) error {
-	logger.Debugf("[%s] Deleting reference of type %s from object %s: %s",
-		s.serviceName, referenceType, objectIRI, referenceIRI)
+// queryAndFilterActivities queries the activities using the most selective tag and then applies the actor, target
+// and time range filters to each activity. The storage provider sorts the results by the time that they were
+// added, so the time range bounds the scan and only the requested page of activities is held in memory.
+func (s *Provider) queryAndFilterActivities(query *spi.Criteria,
+	options *spi.QueryOptions) (spi.ActivityIterator, error) {
+	expression := activityTag
 
-	referenceStore, exists := s.referenceStores[referenceType]
-	if !exists {
-		return fmt.Errorf("no store found for %s", string(referenceType))
+	if len(query.Types) == 1 {
+		expression = fmt.Sprintf("%s:%s", activityTypeTagName, query.Types[0])
 	}
 
-	err := referenceStore.Delete(objectIRI.String() + referenceIRI.String())
-	if err != nil {
-		return fmt.Errorf("failed to delete reference: %w", err)
+	if s.limitedQuerySupport {
+		return s.queryFilterAndSortActivities(expression, query, options)
 	}
 
-	return nil
-}
+	counter := &itemCounter{
+		count: func() (int, error) {
+			return s.countFilteredActivities(expression, query, options)
+		},
+	}
 
-// QueryReferences returns the list of references of the given type according to the given query.
-func (s *Provider) QueryReferences(referenceType spi.ReferenceType, query *spi.Criteria,
-	opts ...spi.QueryOpt) (spi.ReferenceIterator, error) {
-	logger.Debugf("[%s] Querying references of type %s - Query: %+v", s.serviceName, referenceType, query)
+	var (
+		activities []*vocab.ActivityType
+		err        error
+	)
 
-	if query.ObjectIRI == nil {
-		return nil, fmt.Errorf("object IRI is required")
+	if options.Bookmark != "" {
+		activities, err = s.filteredPageFromBookmark(expression, query, options)
+	} else {
+		activities, err = s.filteredPageFromOffset(expression, query, options, counter)
 	}
 
-	options := storeutil.GetQueryOptions(opts...)
-
-	referenceStore, exists := s.referenceStores[referenceType]
-	if !exists {
-		return nil, fmt.Errorf("no store found for %s", string(referenceType))
+	if err != nil {
+		return nil, err
 	}
 
-	// If no reference IRI is set, then grab all references associated with the object IRI.
-	if query.ReferenceIRI == nil {
-		iterator, err := referenceStore.Query(
-			fmt.Sprintf("%s:%s", objectIRITagName,
-				base64.RawStdEncoding.EncodeToString([]byte(query.ObjectIRI.String()))),
-			ariesstorage.WithSortOrder(&ariesstorage.SortOptions{
-				Order:   ariesstorage.SortOrder(options.SortOrder),
-				TagName: timeAddedTagName,
-			}),
-			ariesstorage.WithPageSize(options.PageSize),
-			ariesstorage.WithInitialPageNum(options.PageNumber))
-		if err != nil {
-			return nil, fmt.Errorf("failed to query store: %w", err)
-		}
+	return &activityPageIterator{
+		ActivityIterator: memstore.NewActivityIterator(activities, 0),
+		totalItems:       counter.TotalItems,
+	}, nil
+}
 
-		return &referenceIterator{ariesIterator: iterator}, nil
-	}
+// filteredPageFromOffset returns the page of filtered activities at the page number in the query options.
+func (s *Provider) filteredPageFromOffset(expression string, query *spi.Criteria, options *spi.QueryOptions,
+	counter *itemCounter) ([]*vocab.ActivityType, error) {
+	var totalItems int
 
-	// Otherwise, if there is a reference IRI,
-	// then we should only grab the reference associated with the object IRI and reference IRI.
+	if storeutil.RequiresTotalItems(options) {
+		var err error
 
-	retrievedURLBytes, err := referenceStore.Get(query.ObjectIRI.String() + query.ReferenceIRI.String())
-	if err != nil {
-		if errors.Is(err, ariesstorage.ErrDataNotFound) {
-			return memstore.NewReferenceIterator(nil, 0), nil
+		totalItems, err = counter.get()
+		if err != nil {
+			return nil, err
 		}
-
-		return nil, fmt.Errorf("unexpected failure while getting reference: %w", err)
 	}
 
-	retrievedURL, err := url.Parse(string(retrievedURLBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse URL from storage: %w", err)
+	offset := storeutil.GetOffset(options, totalItems)
+	if offset < 0 {
+		return nil, nil
 	}
 
-	return memstore.NewReferenceIterator([]*url.URL{retrievedURL}, 1), nil
+	return s.filteredPage(expression, query, options, offset, options.PageSize)
 }
 
-func (s *Provider) queryActivitiesByRef(refType spi.ReferenceType, query *spi.Criteria,
-	opts ...spi.QueryOpt) (spi.ActivityIterator, error) {
-	iterator, err := s.QueryReferences(refType, query, opts...)
+// filteredPageFromBookmark returns the page of filtered activities at the cursor of the bookmark in the query
+// options. As with queryFromBookmark, the time at which the activity at the cursor was added is used to locate
+// the cursor and the offset hint is used if the activity no longer exists.
+func (s *Provider) filteredPageFromBookmark(expression string, query *spi.Criteria,
+	options *spi.QueryOptions) ([]*vocab.ActivityType, error) {
+	b, err := storeutil.ParseBookmark(options.Bookmark)
 	if err != nil {
 		return nil, err
 	}
 
-	options := storeutil.GetQueryOptions(opts...)
+	key := s.key(b.Key)
 
-	refs, err := storeutil.ReadReferences(iterator, options.PageSize)
+	tags, err := s.activityStore.GetTags(key)
 	if err != nil {
-		return nil, err
-	}
+		if !errors.Is(err, ariesstorage.ErrDataNotFound) {
+			return nil, fmt.Errorf("failed to get tags for bookmark: %w", err)
+		}
 
-	if len(refs) == 0 {
-		return memstore.NewActivityIterator(nil, 0), nil
-	}
+		logger.Debugf("[%s] Activity at bookmark cursor [%s] no longer exists. Using offset %d.",
+			s.serviceName, b.Key, b.Offset)
 
-	activityIDs := make([]string, len(refs))
+		if !b.Before || options.PageSize <= 0 {
+			return s.filteredPage(expression, query, options, b.Offset, options.PageSize)
+		}
 
-	for i, ref := range refs {
-		activityIDs[i] = ref.String()
+		start := b.Offset - options.PageSize
+		if start < 0 {
+			start = 0
+		}
+
+		return s.filteredPage(expression, query, options, start, b.Offset-start)
 	}
 
-	activitiesBytes, err := s.activityStore.GetBulk(activityIDs...)
+	timeAdded, err := getTimeAdded(tags)
 	if err != nil {
-		return nil, fmt.Errorf("unexpected failure while getting activities: %w", err)
+		return nil, err
 	}
 
-	var activities []*vocab.ActivityType
+	var (
+		page          []*vocab.ActivityType
+		reachedCursor bool
+	)
 
-	for _, activityBytes := range activitiesBytes {
-		if activityBytes != nil {
-			var activity vocab.ActivityType
+	err = s.scanFilteredActivities(expression, query, options, func(result *filteredActivity) bool {
+		if !reachedCursor {
+			reachedCursor = result.key == key || isAfter(options.SortOrder, result.timeAdded, timeAdded)
 
-			err = json.Unmarshal(activityBytes, &activity)
-			if err != nil {
-				return nil, fmt.Errorf("failed to unmarshal activity bytes: %w", err)
+			if !reachedCursor {
+				if b.Before {
+					page = append(page, result.activity)
+
+					if options.PageSize > 0 && len(page) > options.PageSize {
+						page = page[1:]
+					}
+				}
+
+				return true
 			}
 
-			activities = append(activities, &activity)
+			if b.Before {
+				return false
+			}
+
+			if result.key == key {
+				// The page starts after the cursor.
+				return true
+			}
 		}
+
+		page = append(page, result.activity)
+
+		return options.PageSize <= 0 || len(page) < options.PageSize
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return memstore.NewActivityIterator(activities, len(activities)), nil
+	return page, nil
 }
 
-type activityIterator struct {
-	ariesIterator ariesstorage.Iterator
+// filteredPage returns at most limit filtered activities starting at the given offset. All activities from the
+// offset are returned if limit is not positive.
+func (s *Provider) filteredPage(expression string, query *spi.Criteria, options *spi.QueryOptions,
+	offset, limit int) ([]*vocab.ActivityType, error) {
+	var (
+		page    []*vocab.ActivityType
+		skipped int
+	)
+
+	err := s.scanFilteredActivities(expression, query, options, func(result *filteredActivity) bool {
+		if skipped < offset {
+			skipped++
+
+			return true
+		}
+
+		page = append(page, result.activity)
+
+		return limit <= 0 || len(page) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
 }
 
-// TODO (#299) return a real result instead of 0.
-func (a *activityIterator) TotalItems() int {
-	return 0
+// countFilteredActivities returns the total number of activities that match the given criteria.
+func (s *Provider) countFilteredActivities(expression string, query *spi.Criteria,
+	options *spi.QueryOptions) (int, error) {
+	var count int
+
+	err := s.scanFilteredActivities(expression, query, options, func(*filteredActivity) bool {
+		count++
+
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
 }
 
-func (a *activityIterator) Next() (*vocab.ActivityType, error) {
-	areMoreResults, err := a.ariesIterator.Next()
+// scanFilteredActivities performs a sorted query for the given expression and invokes the visit function for
+// each activity that matches the given criteria until the function returns false. Activities before the start
+// of the time range (in sort order) are skipped without being unmarshalled and the scan ends at the first
+// activity after the end of the time range.
+func (s *Provider) scanFilteredActivities(expression string, query *spi.Criteria, options *spi.QueryOptions,
+	visit func(result *filteredActivity) bool) error {
+	iterator, err := s.querySorted(s.activityStore, expression, options, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to determine if there are more results: %w", err)
+		return err
 	}
 
-	if areMoreResults {
-		activityBytes, err := a.ariesIterator.Value()
+	defer s.close(iterator)
+
+	for {
+		ok, err := iterator.Next()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get value: %w", err)
+			return fmt.Errorf("failed to determine if there are more results: %w", err)
 		}
 
-		var activity vocab.ActivityType
+		if !ok {
+			return nil
+		}
 
-		err = json.Unmarshal(activityBytes, &activity)
+		entry, err := newSortedEntry(iterator)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal activity bytes: %w", err)
+			return err
 		}
 
-		return &activity, nil
-	}
+		if pastTimeRange(query, options.SortOrder, entry.timeAdded) {
+			return nil
+		}
 
-	return nil, spi.ErrNotFound
-}
+		result, err := s.filterActivity(iterator, entry, query)
+		if err != nil {
+			return err
+		}
 
-func (a *activityIterator) Close() error {
-	return a.ariesIterator.Close()
+		if result != nil && !visit(result) {
+			return nil
+		}
+	}
 }
 
-type referenceIterator struct {
-	ariesIterator ariesstorage.Iterator
+// pastTimeRange returns true if the given time comes after the end of the time range of the given
+// criteria in the given sort order.
+func pastTimeRange(query *spi.Criteria, sortOrder spi.SortOrder, timeAdded int64) bool {
+	if sortOrder == spi.SortDescending {
+		return query.AddedAfter != nil && time.Unix(0, timeAdded).Before(*query.AddedAfter)
+	}
+
+	return query.AddedBefore != nil && !time.Unix(0, timeAdded).Before(*query.AddedBefore)
 }
 
-// TODO (#299) return a real result instead of 0.
-func (r *referenceIterator) TotalItems() int {
-	return 0
+// queryFilterAndSortActivities applies the filters to all activities that match the given expression and
+// then sorts and pages the results in memory. This is used for storage providers that don't support the
+// sort and paging options.
+func (s *Provider) queryFilterAndSortActivities(expression string, query *spi.Criteria,
+	options *spi.QueryOptions) (spi.ActivityIterator, error) {
+	iterator, err := s.activityStore.Query(expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query store: %w", err)
+	}
+
+	defer s.close(iterator)
+
+	var results []*filteredActivity
+
+	for {
+		ok, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine if there are more results: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		entry, err := newSortedEntry(iterator)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := s.filterActivity(iterator, entry, query)
+		if err != nil {
+			return nil, err
+		}
+
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if options.SortOrder == spi.SortDescending {
+			return results[i].timeAdded > results[j].timeAdded
+		}
+
+		return results[i].timeAdded < results[j].timeAdded
+	})
+
+	start, end, err := storeutil.GetRange(options, len(results), func(activityID string) int {
+		key := s.key(activityID)
+
+		for i, result := range results {
+			if result.key == key {
+				return i
+			}
+		}
+
+		return -1
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	activities := make([]*vocab.ActivityType, 0, end-start)
+
+	for _, result := range results[start:end] {
+		activities = append(activities, result.activity)
+	}
+
+	return memstore.NewActivityIterator(activities, len(results)), nil
+}
+
+type filteredActivity struct {
+	activity  *vocab.ActivityType
+	key       string
+	timeAdded int64
+}
+
+// filterActivity returns the activity for the given entry at the current position of the iterator or nil
+// if the activity doesn't match the given criteria.
+func (s *Provider) filterActivity(iterator ariesstorage.Iterator, entry *sortedEntry,
+	query *spi.Criteria) (*filteredActivity, error) {
+	if !storeutil.InTimeRange(query, time.Unix(0, entry.timeAdded)) {
+		return nil, nil
+	}
+
+	activityBytes, err := iterator.Value()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	activity := &vocab.ActivityType{}
+
+	if err := json.Unmarshal(activityBytes, activity); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal activity bytes: %w", err)
+	}
+
+	if !storeutil.MatchesActivity(query, activity) {
+		return nil, nil
+	}
+
+	return &filteredActivity{activity: activity, key: entry.key, timeAdded: entry.timeAdded}, nil
+}
+
+// AddReference adds the reference of the given type to the given object.
+func (s *Provider) AddReference(referenceType spi.ReferenceType, objectIRI, referenceIRI *url.URL) error {
+	logger.Debugf("[%s] Adding reference of type %s to object %s: %s",
+		s.serviceName, referenceType, objectIRI, referenceIRI)
+
+	referenceStore, exists := s.referenceStores[referenceType]
+	if !exists {
+		return fmt.Errorf("no store found for %s", string(referenceType))
+	}
+
+	err := referenceStore.Put(s.key(objectIRI.String()+referenceIRI.String()),
+		[]byte(referenceIRI.String()), ariesstorage.Tag{
+			Name:  objectIRITagName,
+			Value: base64.RawStdEncoding.EncodeToString([]byte(objectIRI.String())),
+		}, ariesstorage.Tag{
+			Name:  timeAddedTagName,
+			Value: strconv.FormatInt(time.Now().UnixNano(), 10),
+		})
+	if err != nil {
+		return fmt.Errorf("failed to store reference: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteReference deletes the reference of the given type from the given object.
+func (s *Provider) DeleteReference(referenceType spi.ReferenceType, objectIRI, referenceIRI *url.URL) error {
+	logger.Debugf("[%s] Deleting reference of type %s from object %s: %s",
+		s.serviceName, referenceType, objectIRI, referenceIRI)
+
+	referenceStore, exists := s.referenceStores[referenceType]
+	if !exists {
+		return fmt.Errorf("no store found for %s", string(referenceType))
+	}
+
+	err := referenceStore.Delete(s.key(objectIRI.String() + referenceIRI.String()))
+	if err != nil {
+		return fmt.Errorf("failed to delete reference: %w", err)
+	}
+
+	return nil
+}
+
+// QueryReferences returns the list of references of the given type according to the given query.
+func (s *Provider) QueryReferences(referenceType spi.ReferenceType, query *spi.Criteria,
+	opts ...spi.QueryOpt) (spi.ReferenceIterator, error) {
+	logger.Debugf("[%s] Querying references of type %s - Query: %+v", s.serviceName, referenceType, query)
+
+	if query.ObjectIRI == nil {
+		return nil, fmt.Errorf("object IRI is required")
+	}
+
+	options := storeutil.GetQueryOptions(opts...)
+
+	referenceStore, exists := s.referenceStores[referenceType]
+	if !exists {
+		return nil, fmt.Errorf("no store found for %s", string(referenceType))
+	}
+
+	// If no reference IRI is set, then grab all references associated with the object IRI.
+	if query.ReferenceIRI == nil {
+		iterator, counter, err := s.query(referenceStore,
+			fmt.Sprintf("%s:%s", objectIRITagName,
+				base64.RawStdEncoding.EncodeToString([]byte(query.ObjectIRI.String()))),
+			options,
+			func(refIRI string) string {
+				return s.key(query.ObjectIRI.String() + refIRI)
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		if iterator == nil {
+			return memstore.NewReferenceIterator(nil, counter.TotalItems()), nil
+		}
+
+		return &referenceIterator{ariesIterator: iterator, itemCounter: counter}, nil
+	}
+
+	// Otherwise, if there is a reference IRI,
+	// then we should only grab the reference associated with the object IRI and reference IRI.
+
+	retrievedURLBytes, err := referenceStore.Get(s.key(query.ObjectIRI.String() + query.ReferenceIRI.String()))
+	if err != nil {
+		if errors.Is(err, ariesstorage.ErrDataNotFound) {
+			return memstore.NewReferenceIterator(nil, 0), nil
+		}
+
+		return nil, fmt.Errorf("unexpected failure while getting reference: %w", err)
+	}
+
+	retrievedURL, err := url.Parse(string(retrievedURLBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL from storage: %w", err)
+	}
+
+	return memstore.NewReferenceIterator([]*url.URL{retrievedURL}, 1), nil
+}
+
+func (s *Provider) queryActivitiesByRef(refType spi.ReferenceType, query *spi.Criteria,
+	opts ...spi.QueryOpt) (spi.ActivityIterator, error) {
+	iterator, err := s.QueryReferences(refType, query, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if e := iterator.Close(); e != nil {
+			logger.Warnf("[%s] Failed to close iterator: %s", s.serviceName, e)
+		}
+	}()
+
+	options := storeutil.GetQueryOptions(opts...)
+
+	refs, err := storeutil.ReadReferences(iterator, options.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(refs) == 0 {
+		return memstore.NewActivityIterator(nil, iterator.TotalItems()), nil
+	}
+
+	activityIDs := make([]string, len(refs))
+
+	for i, ref := range refs {
+		activityIDs[i] = s.key(ref.String())
+	}
+
+	activitiesBytes, err := s.getBulk(s.activityStore, activityIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected failure while getting activities: %w", err)
+	}
+
+	var activities []*vocab.ActivityType
+
+	for _, activityBytes := range activitiesBytes {
+		if activityBytes != nil {
+			var activity vocab.ActivityType
+
+			err = json.Unmarshal(activityBytes, &activity)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal activity bytes: %w", err)
+			}
+
+			activities = append(activities, &activity)
+		}
+	}
+
+	// The total number of items is the total number of references, which may be more than the number
+	// of activities on this page.
+	return &activityPageIterator{
+		ActivityIterator: memstore.NewActivityIterator(activities, 0),
+		totalItems:       iterator.TotalItems,
+	}, nil
+}
+
+// query performs a query for the given expression on the given store. The returned iterator is positioned
+// according to the paging options (page number or bookmark). A nil iterator is returned if the offset of a
+// page number is out of range. The returned counter determines the total number of items in the query results,
+// which requires a separate query (that is performed at most once). The cursorKey function returns the storage
+// key of the item with the given ID (i.e. the key of a bookmark).
+func (s *Provider) query(store ariesstorage.Store, expression string, options *spi.QueryOptions,
+	cursorKey func(id string) string) (ariesstorage.Iterator, *itemCounter, error) {
+	if s.limitedQuerySupport {
+		return s.queryAndSort(store, expression, options, cursorKey)
+	}
+
+	counter := &itemCounter{
+		count: func() (int, error) {
+			return countItems(store, expression)
+		},
+	}
+
+	if options.Bookmark != "" {
+		iterator, err := s.queryFromBookmark(store, expression, options, cursorKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return iterator, counter, nil
+	}
+
+	var totalItems int
+
+	if storeutil.RequiresTotalItems(options) {
+		var err error
+
+		totalItems, err = counter.get()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	offset := storeutil.GetOffset(options, totalItems)
+	if offset < 0 {
+		return nil, counter, nil
+	}
+
+	iterator, err := s.queryFromOffset(store, expression, options, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return iterator, counter, nil
+}
+
+// queryFromOffset performs a sorted query for the given expression and returns an iterator that is positioned
+// at the given offset.
+func (s *Provider) queryFromOffset(store ariesstorage.Store, expression string, options *spi.QueryOptions,
+	offset int) (ariesstorage.Iterator, error) {
+	initialPageNum := 0
+	skip := offset
+
+	if options.PageSize > 0 {
+		initialPageNum = offset / options.PageSize
+		skip = offset % options.PageSize
+	}
+
+	iterator, err := s.querySorted(store, expression, options, initialPageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip to the offset within the initial page.
+	for i := 0; i < skip; i++ {
+		ok, err := iterator.Next()
+		if err != nil {
+			s.close(iterator)
+
+			return nil, fmt.Errorf("failed to determine if there are more results: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+	}
+
+	return iterator, nil
+}
+
+// queryFromBookmark performs a sorted query for the given expression and returns an iterator that is positioned
+// at the cursor of the bookmark in the query options. The time at which the item at the cursor was added is
+// used to locate the cursor, so items that were added or deleted since the bookmark was created don't shift
+// the page. If the item at the cursor no longer exists then the page is positioned using the offset hint
+// in the bookmark.
+func (s *Provider) queryFromBookmark(store ariesstorage.Store, expression string, options *spi.QueryOptions,
+	cursorKey func(id string) string) (ariesstorage.Iterator, error) {
+	b, err := storeutil.ParseBookmark(options.Bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cursorKey(b.Key)
+
+	tags, err := store.GetTags(key)
+	if err != nil {
+		if !errors.Is(err, ariesstorage.ErrDataNotFound) {
+			return nil, fmt.Errorf("failed to get tags for bookmark: %w", err)
+		}
+
+		logger.Debugf("[%s] Item at bookmark cursor [%s] no longer exists. Using offset %d.",
+			s.serviceName, b.Key, b.Offset)
+
+		return s.queryFromOffsetHint(store, expression, options, b)
+	}
+
+	timeAdded, err := getTimeAdded(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the search on the page before the one that contained the cursor, in case items were deleted.
+	startPage := 0
+
+	if options.PageSize > 0 && b.Offset/options.PageSize > 0 {
+		startPage = b.Offset/options.PageSize - 1
+	}
+
+	iterator, err := s.seekCursor(store, expression, options, b.Before, key, timeAdded, startPage)
+	if errors.Is(err, errCursorNotInRange) {
+		// The cursor moved towards the start of the results, so search from the beginning.
+		return s.seekCursor(store, expression, options, b.Before, key, timeAdded, 0)
+	}
+
+	return iterator, err
+}
+
+// queryFromOffsetHint returns an iterator for the page at the offset hint of the given bookmark.
+func (s *Provider) queryFromOffsetHint(store ariesstorage.Store, expression string, options *spi.QueryOptions,
+	b *storeutil.Bookmark) (ariesstorage.Iterator, error) {
+	if !b.Before || options.PageSize <= 0 {
+		return s.queryFromOffset(store, expression, options, b.Offset)
+	}
+
+	start := b.Offset - options.PageSize
+	if start < 0 {
+		start = 0
+	}
+
+	iterator, err := s.queryFromOffset(store, expression, options, start)
+	if err != nil {
+		return nil, err
+	}
+
+	return &limitedIterator{Iterator: iterator, remaining: b.Offset - start}, nil
+}
+
+var errCursorNotInRange = errors.New("cursor is not within the scanned range")
+
+// seekCursor scans the sorted results of a query, starting at the given page, for the item with the given key
+// and returns an iterator that either starts immediately after the item or, if before is true, that contains
+// the page of items immediately preceding the item. If the item isn't found then the cursor is the position
+// of the first item that was added after the given time (in sort order). An errCursorNotInRange error is
+// returned if the start page is past the cursor.
+func (s *Provider) seekCursor(store ariesstorage.Store, expression string, options *spi.QueryOptions,
+	before bool, key string, timeAdded int64, startPage int) (ariesstorage.Iterator, error) {
+	iterator, err := s.querySorted(store, expression, options, startPage)
+	if err != nil {
+		return nil, err
+	}
+
+	var preceding []*sortedEntry
+
+	for scanned := 0; ; scanned++ {
+		ok, err := iterator.Next()
+		if err != nil {
+			s.close(iterator)
+
+			return nil, fmt.Errorf("failed to determine if there are more results: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		entry, err := newSortedEntry(iterator)
+		if err != nil {
+			s.close(iterator)
+
+			return nil, err
+		}
+
+		if entry.key == key {
+			break
+		}
+
+		if isAfter(options.SortOrder, entry.timeAdded, timeAdded) {
+			if startPage > 0 && scanned == 0 {
+				s.close(iterator)
+
+				return nil, errCursorNotInRange
+			}
+
+			if !before {
+				// The iterator is already positioned at the first item after the cursor.
+				return &pushbackIterator{Iterator: iterator, pushedBack: true}, nil
+			}
+
+			break
+		}
+
+		if before {
+			preceding = append(preceding, entry)
+
+			if options.PageSize > 0 && len(preceding) > options.PageSize {
+				preceding = preceding[1:]
+			}
+		}
+	}
+
+	if !before {
+		return iterator, nil
+	}
+
+	s.close(iterator)
+
+	if startPage > 0 && len(preceding) < options.PageSize {
+		return nil, errCursorNotInRange
+	}
+
+	return &sortedIterator{store: store, entries: preceding, current: -1}, nil
+}
+
+// querySorted performs a query for the given expression that is sorted by the time added, starting
+// at the given page.
+func (s *Provider) querySorted(store ariesstorage.Store, expression string, options *spi.QueryOptions,
+	initialPageNum int) (ariesstorage.Iterator, error) {
+	iterator, err := store.Query(expression,
+		ariesstorage.WithSortOrder(&ariesstorage.SortOptions{
+			Order:   ariesstorage.SortOrder(options.SortOrder),
+			TagName: timeAddedTagName,
+		}),
+		ariesstorage.WithPageSize(options.PageSize),
+		ariesstorage.WithInitialPageNum(initialPageNum))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query store: %w", err)
+	}
+
+	return iterator, nil
+}
+
+func (s *Provider) close(iterator ariesstorage.Iterator) {
+	if err := iterator.Close(); err != nil {
+		logger.Warnf("[%s] Failed to close iterator: %s", s.serviceName, err)
+	}
+}
+
+// isAfter returns true if the given time comes after the cursor time in the given sort order.
+func isAfter(sortOrder spi.SortOrder, t, cursor int64) bool {
+	if sortOrder == spi.SortDescending {
+		return t < cursor
+	}
+
+	return t > cursor
+}
+
+// queryAndSort performs a query for the given expression and sorts and pages the results in memory.
+// This is used for storage providers that don't support the sort and paging options.
+func (s *Provider) queryAndSort(store ariesstorage.Store, expression string, options *spi.QueryOptions,
+	cursorKey func(id string) string) (ariesstorage.Iterator, *itemCounter, error) {
+	iterator, err := store.Query(expression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query store: %w", err)
+	}
+
+	defer func() {
+		if e := iterator.Close(); e != nil {
+			logger.Warnf("[%s] Failed to close iterator: %s", s.serviceName, e)
+		}
+	}()
+
+	var entries []*sortedEntry
+
+	for {
+		ok, err := iterator.Next()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to determine if there are more results: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		entry, err := newSortedEntry(iterator)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if options.SortOrder == spi.SortDescending {
+			return entries[i].timeAdded > entries[j].timeAdded
+		}
+
+		return entries[i].timeAdded < entries[j].timeAdded
+	})
+
+	counter := &itemCounter{
+		count: func() (int, error) {
+			return len(entries), nil
+		},
+	}
+
+	start, end, err := storeutil.GetRange(options, len(entries), func(id string) int {
+		key := cursorKey(id)
+
+		for i, entry := range entries {
+			if entry.key == key {
+				return i
+			}
+		}
+
+		return -1
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &sortedIterator{store: store, entries: entries[start:end], current: -1}, counter, nil
+}
+
+// getBulk returns the values for the given keys. A nil value is returned for a key that isn't found.
+func (s *Provider) getBulk(store ariesstorage.Store, keys ...string) ([][]byte, error) {
+	if !s.limitedQuerySupport {
+		return store.GetBulk(keys...)
+	}
+
+	values := make([][]byte, len(keys))
+
+	for i, key := range keys {
+		value, err := store.Get(key)
+		if err != nil {
+			if errors.Is(err, ariesstorage.ErrDataNotFound) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// key returns the given key or, if the key exceeds the maximum key length, a hash of the key.
+func (s *Provider) key(key string) string {
+	if s.maxKeyLength <= 0 || len(key) <= s.maxKeyLength {
+		return key
+	}
+
+	hash := sha256.Sum256([]byte(key))
+
+	return hashedKeyPrefix + base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// countItems returns the number of items in the results of a query for the given expression.
+func countItems(store ariesstorage.Store, expression string) (int, error) {
+	iterator, err := store.Query(expression, ariesstorage.WithPageSize(countQueryPageSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query store: %w", err)
+	}
+
+	defer func() {
+		if e := iterator.Close(); e != nil {
+			logger.Warnf("Failed to close iterator: %s", e)
+		}
+	}()
+
+	count := 0
+
+	for {
+		ok, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to determine if there are more results: %w", err)
+		}
+
+		if !ok {
+			return count, nil
+		}
+
+		count++
+	}
+}
+
+// itemCounter determines the total number of items in the results of a query. The items are counted
+// at most once, and only if requested.
+type itemCounter struct {
+	count      func() (int, error)
+	once       sync.Once
+	totalItems int
+	err        error
+}
+
+func (c *itemCounter) get() (int, error) {
+	c.once.Do(func() {
+		c.totalItems, c.err = c.count()
+	})
+
+	return c.totalItems, c.err
+}
+
+// TotalItems returns the total number of items in the query results. If the items can't be
+// counted then the error is logged and 0 is returned.
+func (c *itemCounter) TotalItems() int {
+	totalItems, err := c.get()
+	if err != nil {
+		logger.Errorf("Failed to determine the total number of items: %s", err)
+	}
+
+	return totalItems
+}
+
+// sortedEntry is an entry in the results of a query that were sorted in memory.
+type sortedEntry struct {
+	key       string
+	timeAdded int64
+	tags      []ariesstorage.Tag
+}
+
+func newSortedEntry(iterator ariesstorage.Iterator) (*sortedEntry, error) {
+	key, err := iterator.Key()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key: %w", err)
+	}
+
+	tags, err := iterator.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	timeAdded, err := getTimeAdded(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sortedEntry{key: key, timeAdded: timeAdded, tags: tags}, nil
+}
+
+func getTimeAdded(tags []ariesstorage.Tag) (int64, error) {
+	for _, tag := range tags {
+		if tag.Name == timeAddedTagName {
+			timeAdded, err := strconv.ParseInt(tag.Value, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value for tag %s: %w", timeAddedTagName, err)
+			}
+
+			return timeAdded, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// sortedIterator iterates over query results that were sorted in memory. The values are
+// retrieved from the store on demand.
+type sortedIterator struct {
+	store   ariesstorage.Store
+	entries []*sortedEntry
+	current int
+}
+
+func (it *sortedIterator) Next() (bool, error) {
+	if it.current >= len(it.entries)-1 {
+		return false, nil
+	}
+
+	it.current++
+
+	return true, nil
+}
+
+func (it *sortedIterator) Key() (string, error) {
+	return it.entries[it.current].key, nil
+}
+
+func (it *sortedIterator) Value() ([]byte, error) {
+	return it.store.Get(it.entries[it.current].key)
+}
+
+func (it *sortedIterator) Tags() ([]ariesstorage.Tag, error) {
+	return it.entries[it.current].tags, nil
+}
+
+func (it *sortedIterator) Close() error {
+	return nil
+}
+
+// pushbackIterator wraps an iterator that is positioned at an entry which hasn't been returned yet, i.e.
+// the next call to Next returns the current entry.
+type pushbackIterator struct {
+	ariesstorage.Iterator
+
+	pushedBack bool
+}
+
+func (it *pushbackIterator) Next() (bool, error) {
+	if it.pushedBack {
+		it.pushedBack = false
+
+		return true, nil
+	}
+
+	return it.Iterator.Next()
+}
+
+// limitedIterator returns at most the given number of entries from an iterator.
+type limitedIterator struct {
+	ariesstorage.Iterator
+
+	remaining int
+}
+
+func (it *limitedIterator) Next() (bool, error) {
+	if it.remaining <= 0 {
+		return false, nil
+	}
+
+	it.remaining--
+
+	return it.Iterator.Next()
+}
+
+// activityPageIterator iterates over a page of activities which were resolved from references.
+type activityPageIterator struct {
+	*memstore.ActivityIterator
+
+	totalItems func() int
+}
+
+func (a *activityPageIterator) TotalItems() int {
+	return a.totalItems()
+}
+
+type activityIterator struct {
+	*itemCounter
+
+	ariesIterator ariesstorage.Iterator
+}
+
+func (a *activityIterator) Next() (*vocab.ActivityType, error) {
+	areMoreResults, err := a.ariesIterator.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine if there are more results: %w", err)
+	}
+
+	if areMoreResults {
+		activityBytes, err := a.ariesIterator.Value()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get value: %w", err)
+		}
+
+		var activity vocab.ActivityType
+
+		err = json.Unmarshal(activityBytes, &activity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal activity bytes: %w", err)
+		}
+
+		return &activity, nil
+	}
+
+	return nil, spi.ErrNotFound
+}
+
+func (a *activityIterator) Close() error {
+	return a.ariesIterator.Close()
+}
+
+type referenceIterator struct {
+	*itemCounter
+
+	ariesIterator ariesstorage.Iterator
 }
 
 func (r *referenceIterator) Next() (*url.URL, error) {
@@ -404,7 +1510,7 @@ func openStores(provider ariesstorage.Provider) (stores, error) {
 
 	err = provider.SetStoreConfig("activity",
 		ariesstorage.StoreConfiguration{
-			TagNames: []string{activityTag, timeAddedTagName},
+			TagNames: []string{activityTag, timeAddedTagName, activityTypeTagName},
 		})
 	if err != nil {
 		return stores{}, fmt.Errorf("failed to set store configuration on activity store: %w", err)
@@ -7,10 +7,15 @@ package ariesstore
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mock"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
 )
 
 func TestIterators_FailureCases(t *testing.T) {
@@ -45,3 +50,270 @@ func TestIterators_FailureCases(t *testing.T) {
 		require.Nil(t, activity)
 	})
 }
+
+func TestProvider_Query(t *testing.T) {
+	store := &pagingStore{values: []string{"v0", "v1", "v2", "v3", "v4", "v5", "v6"}}
+
+	p := &Provider{serviceName: "service1"}
+
+	t.Run("No paging", func(t *testing.T) {
+		it, counter, err := p.query(store, "tag", storeutil.GetQueryOptions(), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v0", "v1", "v2", "v3", "v4", "v5", "v6"}, readValues(t, it))
+		require.Equal(t, 7, counter.TotalItems())
+	})
+
+	t.Run("Page number - ascending", func(t *testing.T) {
+		it, _, err := p.query(store, "tag", storeutil.GetQueryOptions(spi.WithPageSize(3), spi.WithPageNum(1)), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v3", "v4", "v5"}, readValues(t, it)[:3])
+	})
+
+	t.Run("Page number - descending", func(t *testing.T) {
+		// Page 0 contains the oldest items.
+		it, _, err := p.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithPageNum(0), spi.WithSortOrder(spi.SortDescending)), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v0"}, readValues(t, it))
+
+		it, _, err = p.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithPageNum(2), spi.WithSortOrder(spi.SortDescending)), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v6", "v5", "v4"}, readValues(t, it)[:3])
+
+		it, counter, err := p.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithPageNum(3), spi.WithSortOrder(spi.SortDescending)), p.key)
+		require.NoError(t, err)
+		require.Nil(t, it)
+		require.Equal(t, 7, counter.TotalItems())
+	})
+
+	t.Run("Bookmark - after cursor", func(t *testing.T) {
+		it, _, err := p.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithBookmark(storeutil.NewBookmark("v3", 3, false))), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v4", "v5", "v6"}, readValues(t, it))
+
+		it, _, err = p.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithBookmark(storeutil.NewBookmark("v3", 3, false)),
+			spi.WithSortOrder(spi.SortDescending)), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v2", "v1", "v0"}, readValues(t, it))
+
+		_, _, err = p.query(store, "tag", storeutil.GetQueryOptions(spi.WithBookmark("{")), p.key)
+		require.True(t, errors.Is(err, spi.ErrInvalidBookmark))
+	})
+
+	t.Run("Bookmark - before cursor", func(t *testing.T) {
+		it, _, err := p.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithBookmark(storeutil.NewBookmark("v5", 5, true))), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v2", "v3", "v4"}, readValues(t, it))
+
+		it, _, err = p.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithBookmark(storeutil.NewBookmark("v2", 4, true)),
+			spi.WithSortOrder(spi.SortDescending)), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v5", "v4", "v3"}, readValues(t, it))
+	})
+
+	t.Run("Bookmark - items deleted before the cursor", func(t *testing.T) {
+		s := &pagingStore{values: []string{"v3", "v4", "v5", "v6"}}
+
+		// The search starts past the cursor, so it's restarted from the beginning.
+		it, _, err := p.query(s, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(2), spi.WithBookmark(storeutil.NewBookmark("v4", 5, false))), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v5", "v6"}, readValues(t, it))
+
+		it, _, err = p.query(s, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(2), spi.WithBookmark(storeutil.NewBookmark("v5", 5, true))), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v3", "v4"}, readValues(t, it))
+	})
+
+	t.Run("Bookmark - item at cursor deleted", func(t *testing.T) {
+		s := &pagingStore{values: []string{"v3", "v4", "v5", "v6"}}
+
+		it, _, err := p.query(s, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(2), spi.WithBookmark(storeutil.NewBookmark("v1", 1, false))), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v4", "v5", "v6"}, readValues(t, it))
+
+		it, _, err = p.query(s, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithBookmark(storeutil.NewBookmark("v9", 2, true))), p.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v3", "v4"}, readValues(t, it))
+	})
+
+	t.Run("Bookmark - limited query support", func(t *testing.T) {
+		lp := &Provider{serviceName: "service1", limitedQuerySupport: true}
+
+		it, _, err := lp.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithBookmark(storeutil.NewBookmark("v5", 5, true))), lp.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v2", "v3", "v4"}, readValues(t, it))
+
+		it, _, err = lp.query(store, "tag", storeutil.GetQueryOptions(
+			spi.WithPageSize(3), spi.WithBookmark(storeutil.NewBookmark("v3", 3, false)),
+			spi.WithSortOrder(spi.SortDescending)), lp.key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v2", "v1", "v0"}, readValues(t, it))
+	})
+
+	t.Run("Count error", func(t *testing.T) {
+		s := &mock.Store{ErrQuery: errors.New("query error")}
+
+		_, _, err := p.query(s, "tag", storeutil.GetQueryOptions(
+			spi.WithPageNum(1), spi.WithSortOrder(spi.SortDescending)), p.key)
+		require.EqualError(t, err, "failed to query store: query error")
+
+		counter := &itemCounter{count: func() (int, error) { return countItems(s, "tag") }}
+		require.Equal(t, 0, counter.TotalItems())
+	})
+}
+
+func readValues(t *testing.T, it storage.Iterator) []string {
+	t.Helper()
+
+	var values []string
+
+	for {
+		ok, err := it.Next()
+		require.NoError(t, err)
+
+		if !ok {
+			return values
+		}
+
+		value, err := it.Value()
+		require.NoError(t, err)
+
+		values = append(values, string(value))
+	}
+}
+
+// pagingStore is a store that implements the paging and sort options of a query. The values are also used as keys
+// and the TimeAdded tag of value "vN" is N.
+type pagingStore struct {
+	mock.Store
+
+	values []string
+}
+
+func (s *pagingStore) Query(_ string, opts ...storage.QueryOption) (storage.Iterator, error) {
+	options := &storage.QueryOptions{}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	values := make([]string, len(s.values))
+	copy(values, s.values)
+
+	if options.SortOptions != nil && options.SortOptions.Order == storage.SortDescending {
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+
+	skip := options.InitialPageNum * options.PageSize
+	if skip > len(values) {
+		skip = len(values)
+	}
+
+	return &sliceIterator{values: values[skip:], current: -1}, nil
+}
+
+func (s *pagingStore) Get(key string) ([]byte, error) {
+	if _, err := s.GetTags(key); err != nil {
+		return nil, err
+	}
+
+	return []byte(key), nil
+}
+
+func (s *pagingStore) GetTags(key string) ([]storage.Tag, error) {
+	for _, value := range s.values {
+		if value == key {
+			return timeAddedTags(value), nil
+		}
+	}
+
+	return nil, storage.ErrDataNotFound
+}
+
+func timeAddedTags(value string) []storage.Tag {
+	return []storage.Tag{{Name: timeAddedTagName, Value: strings.TrimPrefix(value, "v")}}
+}
+
+type sliceIterator struct {
+	mock.Iterator
+
+	values  []string
+	current int
+}
+
+func (it *sliceIterator) Next() (bool, error) {
+	it.current++
+
+	return it.current < len(it.values), nil
+}
+
+func (it *sliceIterator) Key() (string, error) {
+	return it.values[it.current], nil
+}
+
+func (it *sliceIterator) Value() ([]byte, error) {
+	return []byte(it.values[it.current]), nil
+}
+
+func (it *sliceIterator) Tags() ([]storage.Tag, error) {
+	return timeAddedTags(it.values[it.current]), nil
+}
+
+func TestProvider_Key(t *testing.T) {
+	p := &Provider{maxKeyLength: 16}
+
+	require.Equal(t, "short-key", p.key("short-key"))
+
+	key := p.key("https://example.com/activities/activity1")
+	require.True(t, strings.HasPrefix(key, hashedKeyPrefix))
+	require.Equal(t, key, p.key("https://example.com/activities/activity1"))
+	require.NotEqual(t, key, p.key("https://example.com/activities/activity2"))
+
+	p.maxKeyLength = 0
+
+	require.Equal(t, "https://example.com/activities/activity1", p.key("https://example.com/activities/activity1"))
+}
+
+func TestProvider_GetBulk(t *testing.T) {
+	p := &Provider{limitedQuerySupport: true}
+
+	t.Run("Not found", func(t *testing.T) {
+		s := &mock.Store{ErrGet: storage.ErrDataNotFound}
+
+		values, err := p.getBulk(s, "k1", "k2")
+		require.NoError(t, err)
+		require.Len(t, values, 2)
+		require.Nil(t, values[0])
+	})
+
+	t.Run("Get error", func(t *testing.T) {
+		s := &mock.Store{ErrGet: errors.New("get error")}
+
+		_, err := p.getBulk(s, "k1")
+		require.EqualError(t, err, "get error")
+	})
+
+	t.Run("Invalid time added tag", func(t *testing.T) {
+		s := &mock.Store{QueryReturn: &mock.Iterator{
+			NextReturn: true,
+			TagsReturn: []storage.Tag{{Name: timeAddedTagName, Value: "xxx"}},
+		}}
+
+		_, _, err := p.query(s, "tag", storeutil.GetQueryOptions(), p.key)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for tag TimeAdded")
+	})
+}
@@ -8,12 +8,14 @@ package ariesstore_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -30,6 +32,7 @@ import (
 
 	"github.com/trustbloc/orb/pkg/activitypub/store/ariesstore"
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 	"github.com/trustbloc/orb/pkg/internal/testutil"
 )
@@ -256,8 +259,7 @@ func TestStore_Activity(t *testing.T) {
 
 				checkActivityQueryResultsInOrder(t, it, activityID1, activityID2, activityID3)
 
-				// Currently Aries store doesn't TotalItems, so it always returns 0.
-				require.Equal(t, 0, it.TotalItems())
+				require.Equal(t, 3, it.TotalItems())
 
 				// With CouchDB, closing the iterator isn't necessary. Instead of calling it.Close() for every test,
 				// We'll just check it once here in order to increase code coverage.
@@ -270,11 +272,22 @@ func TestStore_Activity(t *testing.T) {
 
 				checkActivityQueryResultsInOrder(t, it, activityID3, activityID2, activityID1)
 
-				// Currently Aries store doesn't TotalItems, so it always returns 0.
-				require.Equal(t, 0, it.TotalItems())
+				require.Equal(t, 3, it.TotalItems())
 			})
 		})
 
+		t.Run("Query by type", func(t *testing.T) {
+			it, err := s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeCreate)),
+				spi.WithSortOrder(spi.SortDescending))
+			require.NoError(t, err)
+			require.Equal(t, 2, it.TotalItems())
+
+			checkActivityQueryResultsInOrder(t, it, activityID3, activityID1)
+
+			_, err = s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeCreate, vocab.TypeAnnounce)))
+			require.EqualError(t, err, "unsupported query criteria")
+		})
+
 		t.Run("Query by reference", func(t *testing.T) {
 			t.Run("Ascending (default) order", func(t *testing.T) {
 				it, err := s.QueryActivities(
@@ -293,6 +306,31 @@ func TestStore_Activity(t *testing.T) {
 
 				checkActivityQueryResultsInOrder(t, it, activityID3, activityID2, activityID1)
 			})
+			t.Run("Paging", func(t *testing.T) {
+				it, err := s.QueryActivities(
+					spi.NewCriteria(spi.WithReferenceType(spi.Inbox), spi.WithObjectIRI(serviceID1)),
+					spi.WithPageSize(2), spi.WithPageNum(1))
+				require.NoError(t, err)
+				require.Equal(t, 3, it.TotalItems())
+
+				checkActivityQueryResultsInOrder(t, it, activityID3)
+
+				it, err = s.QueryActivities(
+					spi.NewCriteria(spi.WithReferenceType(spi.Inbox), spi.WithObjectIRI(serviceID1)),
+					spi.WithPageSize(2), spi.WithPageNum(1), spi.WithSortOrder(spi.SortDescending))
+				require.NoError(t, err)
+				require.Equal(t, 3, it.TotalItems())
+
+				checkActivityQueryResultsInOrder(t, it, activityID3, activityID2)
+			})
+			t.Run("Bookmark", func(t *testing.T) {
+				it, err := s.QueryActivities(
+					spi.NewCriteria(spi.WithReferenceType(spi.Inbox), spi.WithObjectIRI(serviceID1)),
+					spi.WithPageSize(2), spi.WithBookmark(storeutil.NewBookmark(activityID1.String(), 0, false)))
+				require.NoError(t, err)
+
+				checkActivityQueryResultsInOrder(t, it, activityID2, activityID3)
+			})
 		})
 	})
 	t.Run("Fail to add activity", func(t *testing.T) {
@@ -312,6 +350,19 @@ func TestStore_Activity(t *testing.T) {
 			vocab.WithID(activityID1)))
 		require.EqualError(t, err, "failed to store activity: put error")
 	})
+	t.Run("Fail to delete activity", func(t *testing.T) {
+		provider, err := ariesstore.New(&mock.Provider{
+			OpenStoreReturn: &mock.Store{
+				ErrDelete: errors.New("delete error"),
+			},
+		},
+			"ServiceName")
+		require.NoError(t, err)
+
+		err = provider.DeleteActivity(testutil.MustParseURL("https://example.com/activities/activity1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "delete error")
+	})
 	t.Run("Fail to get activity", func(t *testing.T) {
 		provider, err := ariesstore.New(&mock.Provider{
 			OpenStoreReturn: &mock.Store{
@@ -350,6 +401,305 @@ func TestStore_Activity(t *testing.T) {
 	})
 }
 
+func TestStore_MigrateActivityTypeTags(t *testing.T) {
+	provider := mem.NewProvider()
+
+	activityID1 := testutil.MustParseURL("https://example.com/activities/activity1")
+	activityID2 := testutil.MustParseURL("https://example.com/activities/activity2")
+
+	activityStore, err := provider.OpenStore("activity")
+	require.NoError(t, err)
+
+	// Store activities without the ActivityType tag, as they were stored before the tag was introduced.
+	for _, a := range []*vocab.ActivityType{
+		vocab.NewCreateActivity(vocab.NewObjectProperty(vocab.WithIRI(activityID1)), vocab.WithID(activityID1)),
+		vocab.NewLikeActivity(vocab.NewObjectProperty(vocab.WithIRI(activityID2)), vocab.WithID(activityID2)),
+	} {
+		activityBytes, e := json.Marshal(a)
+		require.NoError(t, e)
+
+		require.NoError(t, activityStore.Put(a.ID().String(), activityBytes,
+			storage.Tag{Name: "Activity"},
+			storage.Tag{Name: "TimeAdded", Value: strconv.FormatInt(time.Now().UnixNano(), 10)},
+		))
+	}
+
+	s, err := ariesstore.New(provider, "ServiceName", ariesstore.WithLimitedQuerySupport())
+	require.NoError(t, err)
+
+	it, err := s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeLike)))
+	require.NoError(t, err)
+
+	checkActivityQueryResultsInOrder(t, it, activityID2)
+
+	tags, err := activityStore.GetTags(activityID1.String())
+	require.NoError(t, err)
+	require.Len(t, tags, 3)
+
+	// The migration is only performed once.
+	require.NoError(t, activityStore.Put(activityID1.String(), []byte("{"), storage.Tag{Name: "Activity"}))
+
+	_, err = ariesstore.New(provider, "ServiceName", ariesstore.WithLimitedQuerySupport())
+	require.NoError(t, err)
+
+	t.Run("Migration error", func(t *testing.T) {
+		// The store is still usable if the migration fails.
+		_, err := ariesstore.New(&mock.Provider{
+			OpenStoreReturn: &mock.Store{ErrGet: errors.New("get error")},
+		}, "ServiceName")
+		require.NoError(t, err)
+	})
+}
+
+func TestStore_LimitedQuerySupport(t *testing.T) {
+	// The mem provider ignores the sort and paging options, in the same way as the MySQL provider.
+	s, err := ariesstore.New(mem.NewProvider(), "ServiceName",
+		ariesstore.WithLimitedQuerySupport(), ariesstore.WithMaxKeyLength(64))
+	require.NoError(t, err)
+
+	serviceID1 := testutil.MustParseURL("https://example.com/services/service1")
+	activityID1 := testutil.MustParseURL("https://example.com/activities/activity1")
+	activityID2 := testutil.MustParseURL("https://example.com/activities/activity2")
+	activityID3 := testutil.MustParseURL("https://example.com/activities/activity3")
+	activityID4 := testutil.MustParseURL("https://example.com/activities/activity4")
+
+	for _, id := range []*url.URL{activityID1, activityID2, activityID3} {
+		require.NoError(t, s.AddActivity(vocab.NewCreateActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(serviceID1)), vocab.WithID(id))))
+		require.NoError(t, s.AddReference(spi.Inbox, serviceID1, id))
+
+		// Ensure that the TimeAdded tags are distinct.
+		time.Sleep(time.Millisecond)
+	}
+
+	require.NoError(t, s.AddActivity(vocab.NewAnnounceActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(serviceID1)), vocab.WithID(activityID4))))
+
+	t.Run("Long key", func(t *testing.T) {
+		a, err := s.GetActivity(activityID1)
+		require.NoError(t, err)
+		require.Equal(t, activityID1.String(), a.ID().String())
+
+		it, err := s.QueryReferences(spi.Inbox,
+			spi.NewCriteria(spi.WithObjectIRI(serviceID1), spi.WithReferenceIRI(activityID2)))
+		require.NoError(t, err)
+
+		checkReferenceQueryResultsInOrder(t, it, activityID2)
+	})
+
+	t.Run("Query all", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(), spi.WithSortOrder(spi.SortDescending))
+		require.NoError(t, err)
+		require.Equal(t, 4, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it, activityID4, activityID3, activityID2, activityID1)
+	})
+
+	t.Run("Query by type", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeAnnounce)))
+		require.NoError(t, err)
+		require.Equal(t, 1, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it, activityID4)
+	})
+
+	t.Run("Query references", func(t *testing.T) {
+		it, err := s.QueryReferences(spi.Inbox, spi.NewCriteria(spi.WithObjectIRI(serviceID1)),
+			spi.WithPageSize(2), spi.WithPageNum(0), spi.WithSortOrder(spi.SortDescending))
+		require.NoError(t, err)
+		require.Equal(t, 3, it.TotalItems())
+
+		checkReferenceQueryResultsInOrder(t, it, activityID1)
+	})
+
+	t.Run("Query by reference", func(t *testing.T) {
+		it, err := s.QueryActivities(
+			spi.NewCriteria(spi.WithReferenceType(spi.Inbox), spi.WithObjectIRI(serviceID1)),
+			spi.WithPageSize(2), spi.WithPageNum(1))
+		require.NoError(t, err)
+		require.Equal(t, 3, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it, activityID3)
+
+		it, err = s.QueryActivities(
+			spi.NewCriteria(spi.WithReferenceType(spi.Inbox), spi.WithObjectIRI(serviceID1)),
+			spi.WithPageSize(2), spi.WithBookmark(storeutil.NewBookmark(activityID3.String(), 0, false)),
+			spi.WithSortOrder(spi.SortDescending))
+		require.NoError(t, err)
+
+		checkActivityQueryResultsInOrder(t, it, activityID2, activityID1)
+	})
+
+	t.Run("Page out of range", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(), spi.WithPageSize(2), spi.WithPageNum(5))
+		require.NoError(t, err)
+		require.Equal(t, 4, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it)
+	})
+
+	t.Run("Delete activity", func(t *testing.T) {
+		require.NoError(t, s.DeleteActivity(activityID4))
+
+		_, err := s.GetActivity(activityID4)
+		require.True(t, errors.Is(err, spi.ErrNotFound))
+
+		require.NoError(t, s.DeleteActivity(activityID4))
+	})
+}
+
+func TestStore_QueryActivityFilters(t *testing.T) {
+	t.Run("CouchDB", func(t *testing.T) {
+		serviceName := generateRandomServiceName()
+		couchDBProvider, err := ariescouchdbstorage.NewProvider(couchDBURL, ariescouchdbstorage.WithDBPrefix(serviceName))
+		require.NoError(t, err)
+
+		s, err := ariesstore.New(couchDBProvider, serviceName)
+		require.NoError(t, err)
+
+		testQueryActivityFilters(t, s)
+	})
+
+	t.Run("Limited query support", func(t *testing.T) {
+		s, err := ariesstore.New(mem.NewProvider(), "ServiceName", ariesstore.WithLimitedQuerySupport())
+		require.NoError(t, err)
+
+		testQueryActivityFilters(t, s)
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		provider, err := ariesstore.New(&mock.Provider{
+			OpenStoreReturn: &mock.Store{
+				ErrQuery: errors.New("query error"),
+			},
+		}, "ServiceName")
+		require.NoError(t, err)
+
+		_, err = provider.QueryActivities(spi.NewCriteria(
+			spi.WithActorIRI(testutil.MustParseURL("https://example1.com/services/orb"))))
+		require.EqualError(t, err, "failed to query store: query error")
+	})
+}
+
+func testQueryActivityFilters(t *testing.T, s *ariesstore.Provider) {
+	t.Helper()
+
+	actor1 := testutil.MustParseURL("https://example1.com/services/orb")
+	actor2 := testutil.MustParseURL("https://example2.com/services/orb")
+	target1 := testutil.MustParseURL("https://example1.com/cas/bafkrei1")
+	target2 := testutil.MustParseURL("https://example1.com/cas/bafkrei2")
+	activityID1 := testutil.MustParseURL("https://example.com/activities/activity1")
+	activityID2 := testutil.MustParseURL("https://example.com/activities/activity2")
+	activityID3 := testutil.MustParseURL("https://example.com/activities/activity3")
+	activityID4 := testutil.MustParseURL("https://example.com/activities/activity4")
+
+	require.NoError(t, s.AddActivity(vocab.NewOfferActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(target1)), vocab.WithID(activityID1), vocab.WithActor(actor1))))
+
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+
+	for _, a := range []*vocab.ActivityType{
+		vocab.NewOfferActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(target2)), vocab.WithID(activityID2), vocab.WithActor(actor1)),
+		vocab.NewLikeActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(target1)), vocab.WithID(activityID3), vocab.WithActor(actor2)),
+		vocab.NewLikeActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(target2)), vocab.WithID(activityID4), vocab.WithActor(actor2)),
+	} {
+		require.NoError(t, s.AddActivity(a))
+
+		// Ensure that the TimeAdded tags are distinct.
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Run("Query by type and actor", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeOffer), spi.WithActorIRI(actor1)),
+			spi.WithSortOrder(spi.SortDescending))
+		require.NoError(t, err)
+		require.Equal(t, 2, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it, activityID2, activityID1)
+	})
+
+	t.Run("Query by target", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithTargetIRI(target1)))
+		require.NoError(t, err)
+		require.Equal(t, 2, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it, activityID1, activityID3)
+	})
+
+	t.Run("Query by multiple types and time range", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeOffer, vocab.TypeLike),
+			spi.WithTimeRange(&start, nil)))
+		require.NoError(t, err)
+		require.Equal(t, 3, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it, activityID2, activityID3, activityID4)
+
+		it, err = s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(nil, &start)))
+		require.NoError(t, err)
+
+		checkActivityQueryResultsInOrder(t, it, activityID1)
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(&start, nil)),
+			spi.WithPageSize(2), spi.WithPageNum(0), spi.WithSortOrder(spi.SortDescending))
+		require.NoError(t, err)
+		require.Equal(t, 3, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it, activityID2)
+
+		it, err = s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(&start, nil)),
+			spi.WithPageSize(2), spi.WithPageNum(5))
+		require.NoError(t, err)
+		require.Equal(t, 3, it.TotalItems())
+
+		checkActivityQueryResultsInOrder(t, it)
+
+		_, err = s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(&start, nil)),
+			spi.WithBookmark("invalid"))
+		require.True(t, errors.Is(err, spi.ErrInvalidBookmark))
+	})
+
+	t.Run("Bookmark", func(t *testing.T) {
+		// Only the first page of results is read since the iterator may contain the remaining results.
+		checkPage := func(t *testing.T, it spi.ActivityIterator, pageSize int, expected ...*url.URL) {
+			t.Helper()
+
+			activities, err := storeutil.ReadActivities(it, pageSize)
+			require.NoError(t, err)
+			require.Len(t, activities, len(expected))
+
+			for i, activity := range activities {
+				require.Equal(t, expected[i].String(), activity.ID().String())
+			}
+		}
+
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(&start, nil)),
+			spi.WithPageSize(1), spi.WithBookmark(storeutil.NewBookmark(activityID2.String(), 1, false)))
+		require.NoError(t, err)
+
+		checkPage(t, it, 1, activityID3)
+
+		it, err = s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(&start, nil)),
+			spi.WithPageSize(2), spi.WithBookmark(storeutil.NewBookmark(activityID4.String(), 2, true)))
+		require.NoError(t, err)
+
+		checkPage(t, it, 2, activityID2, activityID3)
+
+		it, err = s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(&start, nil)),
+			spi.WithPageSize(2), spi.WithSortOrder(spi.SortDescending),
+			spi.WithBookmark(storeutil.NewBookmark(activityID3.String(), 1, false)))
+		require.NoError(t, err)
+
+		checkPage(t, it, 2, activityID2)
+	})
+}
+
 func TestStore_Actors(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		serviceName := generateRandomServiceName()
@@ -442,7 +792,6 @@ func TestStore_Reference(t *testing.T) {
 		require.NotNil(t, it)
 
 		checkReferenceQueryResultsInOrder(t, it)
-		// Currently Aries store doesn't support TotalItems, so it always returns 0.
 		require.Equal(t, 0, it.TotalItems())
 
 		require.NoError(t, s.AddReference(spi.Follower, actor1, actor2))
@@ -452,6 +801,7 @@ func TestStore_Reference(t *testing.T) {
 		require.NoError(t, err)
 
 		checkReferenceQueryResultsInOrder(t, it, actor2, actor3)
+		require.Equal(t, 2, it.TotalItems())
 
 		// Try the same query as above, but in descending order this time
 		it, err = s.QueryReferences(spi.Follower, spi.NewCriteria(spi.WithObjectIRI(actor1)),
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/trustbloc/edge-core/pkg/log"
 
@@ -78,6 +79,18 @@ func (s *Store) GetActor(iri *url.URL) (*vocab.ActorType, error) {
 	return a, nil
 }
 
+// DeleteActor deletes the actor with the given IRI.
+func (s *Store) DeleteActor(iri *url.URL) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	logger.Debugf("[%s] Deleting actor [%s]", s.serviceName, iri)
+
+	delete(s.actorStore, iri.String())
+
+	return nil
+}
+
 // AddActivity adds the given activity to the activity store.
 func (s *Store) AddActivity(activity *vocab.ActivityType) error {
 	logger.Debugf("[%s] Storing activity - Type: %s, ID: %s",
@@ -94,6 +107,13 @@ func (s *Store) GetActivity(activityID *url.URL) (*vocab.ActivityType, error) {
 	return s.activityStore.get(activityID.String())
 }
 
+// DeleteActivity deletes the activity with the given ID from the activity store.
+func (s *Store) DeleteActivity(activityID *url.URL) error {
+	logger.Debugf("[%s] Deleting activity - ID: %s", s.serviceName, activityID)
+
+	return s.activityStore.delete(activityID.String())
+}
+
 // QueryActivities queries the given activity store using the provided criteria
 // and returns a results iterator.
 func (s *Store) QueryActivities(query *spi.Criteria, opts ...spi.QueryOpt) (spi.ActivityIterator, error) {
@@ -103,7 +123,7 @@ func (s *Store) QueryActivities(query *spi.Criteria, opts ...spi.QueryOpt) (spi.
 		return s.queryActivitiesByRef(query.ReferenceType, query, opts...)
 	}
 
-	return s.activityStore.query(query, opts...), nil
+	return s.activityStore.query(query, opts...)
 }
 
 // AddReference adds the reference of the given type to the given object.
@@ -164,9 +184,12 @@ func (s *Store) queryActivitiesByRef(refType spi.ReferenceType, query *spi.Crite
 		return NewActivityIterator(nil, it.TotalItems()), nil
 	}
 
-	ait := s.activityStore.query(
+	ait, err := s.activityStore.query(
 		spi.NewCriteria(spi.WithActivityIRIs(refs...)),
 		spi.WithSortOrder(options.SortOrder))
+	if err != nil {
+		return nil, err
+	}
 
 	// Set 'totalItems' to the 'totalItems' returned in the original reference query, which may be based on paging.
 	ait.totalItems = it.TotalItems()
@@ -178,11 +201,13 @@ type activityStore struct {
 	mutex        sync.RWMutex
 	activities   []*vocab.ActivityType
 	activityByID map[string]*vocab.ActivityType
+	timeAdded    map[string]time.Time
 }
 
 func newActivitiesStore() *activityStore {
 	return &activityStore{
 		activityByID: make(map[string]*vocab.ActivityType),
+		timeAdded:    make(map[string]time.Time),
 	}
 }
 
@@ -192,6 +217,7 @@ func (s *activityStore) add(activity *vocab.ActivityType) error {
 
 	s.activities = append(s.activities, activity)
 	s.activityByID[activity.ID().String()] = activity
+	s.timeAdded[activity.ID().String()] = time.Now()
 
 	return nil
 }
@@ -208,11 +234,58 @@ func (s *activityStore) get(activityID string) (*vocab.ActivityType, error) {
 	return a, nil
 }
 
-func (s *activityStore) query(query *spi.Criteria, opts ...spi.QueryOpt) *ActivityIterator {
+func (s *activityStore) delete(activityID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.activityByID[activityID]; !ok {
+		return nil
+	}
+
+	delete(s.activityByID, activityID)
+	delete(s.timeAdded, activityID)
+
+	for i, a := range s.activities {
+		if a.ID().String() == activityID {
+			s.activities = append(s.activities[0:i], s.activities[i+1:]...)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *activityStore) query(query *spi.Criteria, opts ...spi.QueryOpt) (*ActivityIterator, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	return NewActivityIterator(activityQueryResults(s.activities).filter(query, opts...))
+	activities := s.activities
+
+	if query.AddedAfter != nil || query.AddedBefore != nil {
+		activities = s.addedInTimeRange(query)
+	}
+
+	results, totalItems, err := activityQueryResults(activities).filter(query, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewActivityIterator(results, totalItems), nil
+}
+
+// addedInTimeRange returns the activities that were added within the time range of the given criteria.
+// The caller must hold the lock.
+func (s *activityStore) addedInTimeRange(query *spi.Criteria) []*vocab.ActivityType {
+	var activities []*vocab.ActivityType
+
+	for _, a := range s.activities {
+		if storeutil.InTimeRange(query, s.timeAdded[a.ID().String()]) {
+			activities = append(activities, a)
+		}
+	}
+
+	return activities
 }
 
 type referenceStore struct {
@@ -262,7 +335,12 @@ func (s *referenceStore) query(query *spi.Criteria, opts ...spi.QueryOpt) (spi.R
 		return nil, fmt.Errorf("object IRI is required")
 	}
 
-	return NewReferenceIterator(refQueryResults(s.irisByObject[query.ObjectIRI.String()]).filter(query, opts...)), nil
+	results, totalItems, err := refQueryResults(s.irisByObject[query.ObjectIRI.String()]).filter(query, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReferenceIterator(results, totalItems), nil
 }
 
 type activityQueryFilter struct {
@@ -289,7 +367,7 @@ func (q *activityQueryFilter) apply(activities []*vocab.ActivityType) []*vocab.A
 	}
 
 	for _, a := range activities {
-		if len(q.Types) == 0 || a.Type().IsAny(q.Types...) {
+		if storeutil.MatchesActivity(q.Criteria, a) {
 			results = append(results, a)
 		}
 	}
@@ -299,7 +377,7 @@ func (q *activityQueryFilter) apply(activities []*vocab.ActivityType) []*vocab.A
 
 type activityQueryResults []*vocab.ActivityType
 
-func (r activityQueryResults) filter(query *spi.Criteria, opts ...spi.QueryOpt) ([]*vocab.ActivityType, int) {
+func (r activityQueryResults) filter(query *spi.Criteria, opts ...spi.QueryOpt) ([]*vocab.ActivityType, int, error) {
 	results := newQueryFilter(query).apply(r)
 
 	options := storeutil.GetQueryOptions(opts...)
@@ -308,17 +386,25 @@ func (r activityQueryResults) filter(query *spi.Criteria, opts ...spi.QueryOpt)
 		reverseSort(results)
 	}
 
-	startIdx := getStartIndex(len(results), options)
-	if startIdx == -1 {
-		return nil, len(results)
+	start, end, err := storeutil.GetRange(options, len(results), func(key string) int {
+		for i, a := range results {
+			if a.ID().String() == key {
+				return i
+			}
+		}
+
+		return -1
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return results[startIdx:], len(results)
+	return results[start:end], len(results), nil
 }
 
 type refQueryResults []*url.URL
 
-func (r refQueryResults) filter(query *spi.Criteria, opts ...spi.QueryOpt) ([]*url.URL, int) {
+func (r refQueryResults) filter(query *spi.Criteria, opts ...spi.QueryOpt) ([]*url.URL, int, error) {
 	results := newRefQueryFilter(query).apply(r)
 
 	options := storeutil.GetQueryOptions(opts...)
@@ -327,12 +413,20 @@ func (r refQueryResults) filter(query *spi.Criteria, opts ...spi.QueryOpt) ([]*u
 		reverseSort(results)
 	}
 
-	startIdx := getStartIndex(len(results), options)
-	if startIdx == -1 {
-		return nil, len(results)
+	start, end, err := storeutil.GetRange(options, len(results), func(key string) int {
+		for i, ref := range results {
+			if ref.String() == key {
+				return i
+			}
+		}
+
+		return -1
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return results[startIdx:], len(results)
+	return results[start:end], len(results), nil
 }
 
 type refQueryFilter struct {
@@ -357,39 +451,6 @@ func (f *refQueryFilter) apply(refs []*url.URL) []*url.URL {
 	return results
 }
 
-func getFirstPageNum(totalItems, pageSize int) int {
-	if totalItems%pageSize > 0 {
-		return totalItems / pageSize
-	}
-
-	return totalItems/pageSize - 1
-}
-
-func getStartIndex(totalItems int, options *spi.QueryOptions) int {
-	if options.PageSize <= 0 {
-		return 0
-	}
-
-	startIdx := startIndex(totalItems, options)
-	if startIdx < 0 || startIdx >= totalItems {
-		return -1
-	}
-
-	return startIdx
-}
-
-func startIndex(totalItems int, options *spi.QueryOptions) int {
-	if options.PageNumber < 0 {
-		return 0
-	}
-
-	if options.SortOrder == spi.SortAscending {
-		return options.PageNumber * options.PageSize
-	}
-
-	return (getFirstPageNum(totalItems, options.PageSize) - options.PageNumber) * options.PageSize
-}
-
 func reverseSort(results interface{}) {
 	sort.SliceStable(results, func(i, j int) bool { return i > j }) //nolint:gocritic
 }
@@ -11,10 +11,12 @@ import (
 	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/store/storeutil"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 	"github.com/trustbloc/orb/pkg/internal/testutil"
 )
@@ -76,6 +78,85 @@ func TestStore_Activity(t *testing.T) {
 
 		checkQueryResults(t, it, activityID1, activityID2, activityID3)
 	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, s.DeleteActivity(activityID2))
+
+		_, err := s.GetActivity(activityID2)
+		require.True(t, errors.Is(err, spi.ErrNotFound))
+
+		it, err := s.QueryActivities(spi.NewCriteria())
+		require.NoError(t, err)
+
+		checkQueryResults(t, it, activityID1, activityID3)
+
+		// Deleting an activity that doesn't exist isn't an error.
+		require.NoError(t, s.DeleteActivity(activityID2))
+	})
+}
+
+func TestStore_QueryActivityFilters(t *testing.T) {
+	s := New("service1")
+	require.NotNil(t, s)
+
+	var (
+		actor1      = testutil.MustParseURL("https://example1.com/services/orb")
+		actor2      = testutil.MustParseURL("https://example2.com/services/orb")
+		target1     = testutil.MustParseURL("https://example1.com/cas/bafkrei1")
+		target2     = testutil.MustParseURL("https://example1.com/cas/bafkrei2")
+		activityID1 = testutil.MustParseURL("https://example.com/activities/activity1")
+		activityID2 = testutil.MustParseURL("https://example.com/activities/activity2")
+		activityID3 = testutil.MustParseURL("https://example.com/activities/activity3")
+	)
+
+	require.NoError(t, s.AddActivity(vocab.NewLikeActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(target1)), vocab.WithID(activityID1), vocab.WithActor(actor1),
+	)))
+
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+
+	require.NoError(t, s.AddActivity(vocab.NewLikeActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(target2)), vocab.WithID(activityID2), vocab.WithActor(actor2),
+	)))
+	require.NoError(t, s.AddActivity(vocab.NewOfferActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(target1)), vocab.WithID(activityID3), vocab.WithActor(actor1),
+	)))
+
+	t.Run("Query by actor", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithActorIRI(actor1)))
+		require.NoError(t, err)
+
+		checkQueryResults(t, it, activityID1, activityID3)
+	})
+
+	t.Run("Query by type and target", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithType(vocab.TypeLike), spi.WithTargetIRI(target1)))
+		require.NoError(t, err)
+
+		checkQueryResults(t, it, activityID1)
+	})
+
+	t.Run("Query by time range", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(&start, nil)))
+		require.NoError(t, err)
+
+		checkQueryResults(t, it, activityID2, activityID3)
+
+		it, err = s.QueryActivities(spi.NewCriteria(spi.WithTimeRange(nil, &start)))
+		require.NoError(t, err)
+
+		checkQueryResults(t, it, activityID1)
+	})
+
+	t.Run("Query by actor and time range", func(t *testing.T) {
+		it, err := s.QueryActivities(spi.NewCriteria(spi.WithActorIRI(actor1), spi.WithTimeRange(&start, nil)),
+			spi.WithSortOrder(spi.SortDescending))
+		require.NoError(t, err)
+
+		checkQueryResults(t, it, activityID3)
+	})
 }
 
 func TestStore_Reference(t *testing.T) {
@@ -229,56 +310,63 @@ func TestActivityQueryResults(t *testing.T) {
 	results := activityQueryResults(append(createActivities, announceActivities...))
 
 	// No paging
-	filtered, totalItems := results.filter(spi.NewCriteria())
+	filtered, totalItems, err := results.filter(spi.NewCriteria())
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 10)
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 10)
 	require.True(t, filtered[0] == results[0])
 	require.True(t, filtered[9] == results[9])
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 		spi.WithPageNum(1),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 6)
 	require.True(t, filtered[0] == results[4])
 	require.True(t, filtered[5] == results[9])
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 		spi.WithPageNum(2),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 2)
 	require.True(t, filtered[0] == results[8])
 	require.True(t, filtered[1] == results[9])
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 		spi.WithPageNum(3),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Empty(t, filtered)
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 		spi.WithPageNum(1),
 		spi.WithSortOrder(spi.SortDescending),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 6)
 	require.True(t, filtered[0] == results[5])
 	require.True(t, filtered[5] == results[0])
 
-	filtered, totalItems = results.filter(spi.NewCriteria(spi.WithType(vocab.TypeAnnounce)),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(spi.WithType(vocab.TypeAnnounce)),
 		spi.WithPageSize(3),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 3, totalItems)
 	require.Len(t, filtered, 3)
 	require.True(t, filtered[0] == results[7])
@@ -292,70 +380,113 @@ func TestReferenceQueryResults(t *testing.T) {
 	}))
 
 	// No paging
-	filtered, totalItems := results.filter(spi.NewCriteria())
+	filtered, totalItems, err := results.filter(spi.NewCriteria())
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 10)
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 10)
 	require.True(t, filtered[0] == results[0])
 	require.True(t, filtered[9] == results[9])
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(2),
 		spi.WithPageNum(4),
 		spi.WithSortOrder(spi.SortDescending),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 10)
 	require.Equal(t, results[9].String(), filtered[0].String())
 	require.Equal(t, results[0].String(), filtered[9].String())
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 		spi.WithPageNum(1),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 6)
 	require.True(t, filtered[0] == results[4])
 	require.True(t, filtered[5] == results[9])
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 		spi.WithPageNum(2),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 2)
 	require.True(t, filtered[0] == results[8])
 	require.True(t, filtered[1] == results[9])
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 		spi.WithPageNum(3),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Empty(t, filtered)
 
-	filtered, totalItems = results.filter(spi.NewCriteria(),
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
 		spi.WithPageSize(4),
 		spi.WithPageNum(1),
 		spi.WithSortOrder(spi.SortDescending),
 	)
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 6)
 	require.True(t, filtered[0] == results[5])
 	require.True(t, filtered[5] == results[0])
 
-	filtered, totalItems = results.filter(spi.NewCriteria(), spi.WithPageSize(20))
+	filtered, totalItems, err = results.filter(spi.NewCriteria(), spi.WithPageSize(20))
+	require.NoError(t, err)
 	require.Equal(t, 10, totalItems)
 	require.Len(t, filtered, 10)
 
-	filtered, totalItems = results.filter(spi.NewCriteria(spi.WithReferenceIRI(results[7])))
+	filtered, totalItems, err = results.filter(spi.NewCriteria(spi.WithReferenceIRI(results[7])))
+	require.NoError(t, err)
 	require.Equal(t, 1, totalItems)
 	require.True(t, filtered[0] == results[7])
+
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
+		spi.WithPageSize(4),
+		spi.WithBookmark(storeutil.NewBookmark(results[3].String(), 3, false)),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 10, totalItems)
+	require.Len(t, filtered, 6)
+	require.True(t, filtered[0] == results[4])
+
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
+		spi.WithPageSize(4),
+		spi.WithBookmark(storeutil.NewBookmark(results[6].String(), 6, true)),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 10, totalItems)
+	require.Len(t, filtered, 4)
+	require.True(t, filtered[0] == results[2])
+	require.True(t, filtered[3] == results[5])
+
+	// The offset in the bookmark is stale since two items were added after the bookmark was created.
+	filtered, totalItems, err = results.filter(spi.NewCriteria(),
+		spi.WithPageSize(4),
+		spi.WithSortOrder(spi.SortDescending),
+		spi.WithBookmark(storeutil.NewBookmark(results[4].String(), 3, false)),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 10, totalItems)
+	require.Len(t, filtered, 4)
+	require.True(t, filtered[0] == results[3])
+
+	_, _, err = results.filter(spi.NewCriteria(), spi.WithBookmark("{"))
+	require.Error(t, err)
+	require.True(t, errors.Is(err, spi.ErrInvalidBookmark))
 }
 
 func newMockActivities(t vocab.Type, num int) []*vocab.ActivityType {
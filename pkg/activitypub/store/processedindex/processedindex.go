@@ -0,0 +1,253 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package processedindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	ariesstorage "github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
+)
+
+var logger = log.New("activitypub_store")
+
+const (
+	storeName    = "processed-activity"
+	processedTag = "Processed"
+
+	defaultRetention     = 24 * time.Hour
+	defaultPurgeInterval = time.Hour
+)
+
+// Config holds the configuration for the processed-activity index.
+type Config struct {
+	// Retention is the amount of time that a processed activity is remembered. A duplicate of an activity
+	// that is received after this period is no longer detected, so the retention should be longer than
+	// the period over which peers redeliver activities.
+	Retention time.Duration
+
+	// PurgeInterval is the interval at which expired entries are removed from the index.
+	PurgeInterval time.Duration
+}
+
+type entry struct {
+	ProcessedTime time.Time `json:"processedTime,omitempty"`
+	Pending       bool      `json:"pending,omitempty"`
+}
+
+// Index is a persistent index of the IDs of activities that have been processed. An activity that is being
+// processed (or whose processing failed) is marked as pending. Pending entries don't expire.
+type Index struct {
+	*Config
+	*lifecycle.Lifecycle
+
+	store ariesstorage.Store
+	done  chan struct{}
+	now   func() time.Time
+}
+
+// New returns a new processed-activity index which is persisted in the given storage provider.
+func New(cfg *Config, provider ariesstorage.Provider) (*Index, error) {
+	if cfg.Retention == 0 {
+		cfg.Retention = defaultRetention
+	}
+
+	if cfg.PurgeInterval == 0 {
+		cfg.PurgeInterval = defaultPurgeInterval
+	}
+
+	store, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("open store [%s]: %w", storeName, err)
+	}
+
+	err = provider.SetStoreConfig(storeName, ariesstorage.StoreConfiguration{TagNames: []string{processedTag}})
+	if err != nil {
+		return nil, fmt.Errorf("set store configuration for [%s]: %w", storeName, err)
+	}
+
+	x := &Index{
+		Config: cfg,
+		store:  store,
+		done:   make(chan struct{}),
+		now:    time.Now,
+	}
+
+	x.Lifecycle = lifecycle.New("processed-activity-index",
+		lifecycle.WithStart(x.start),
+		lifecycle.WithStop(x.stop),
+	)
+
+	return x, nil
+}
+
+// IsProcessed returns true if the activity with the given ID was processed within the retention period.
+func (x *Index) IsProcessed(activityID *url.URL) (bool, error) {
+	e, err := x.get(activityID.String())
+	if err != nil {
+		if errors.Is(err, ariesstorage.ErrDataNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return !e.Pending && !x.isExpired(e), nil
+}
+
+// IsPending returns true if processing of the activity with the given ID was started but hasn't completed.
+func (x *Index) IsPending(activityID *url.URL) (bool, error) {
+	e, err := x.get(activityID.String())
+	if err != nil {
+		if errors.Is(err, ariesstorage.ErrDataNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return e.Pending, nil
+}
+
+// MarkPending adds the given activity ID to the index as pending. The entry isn't tagged and therefore
+// isn't purged until the activity is marked as processed.
+func (x *Index) MarkPending(activityID *url.URL) error {
+	entryBytes, err := json.Marshal(&entry{Pending: true})
+	if err != nil {
+		return fmt.Errorf("marshal entry: %w", err)
+	}
+
+	if err := x.store.Put(activityID.String(), entryBytes); err != nil {
+		return fmt.Errorf("store pending activity [%s]: %w", activityID, err)
+	}
+
+	return nil
+}
+
+// MarkProcessed adds the given activity ID to the index.
+func (x *Index) MarkProcessed(activityID *url.URL) error {
+	entryBytes, err := json.Marshal(&entry{ProcessedTime: x.now()})
+	if err != nil {
+		return fmt.Errorf("marshal entry: %w", err)
+	}
+
+	if err := x.store.Put(activityID.String(), entryBytes, ariesstorage.Tag{Name: processedTag}); err != nil {
+		return fmt.Errorf("store processed activity [%s]: %w", activityID, err)
+	}
+
+	return nil
+}
+
+// Purge removes all entries that are older than the retention period and returns the number
+// of entries that were removed.
+func (x *Index) Purge() (int, error) {
+	it, err := x.store.Query(processedTag)
+	if err != nil {
+		return 0, fmt.Errorf("query processed activities: %w", err)
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warnf("Error closing iterator: %s", e)
+		}
+	}()
+
+	var expired []string
+
+	for {
+		ok, err := it.Next()
+		if err != nil {
+			return 0, fmt.Errorf("next processed activity: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		key, err := it.Key()
+		if err != nil {
+			return 0, fmt.Errorf("get key: %w", err)
+		}
+
+		value, err := it.Value()
+		if err != nil {
+			return 0, fmt.Errorf("get value: %w", err)
+		}
+
+		e := &entry{}
+		if err := json.Unmarshal(value, e); err != nil {
+			logger.Warnf("Invalid processed-activity entry [%s]: %s", key, err)
+		}
+
+		if x.isExpired(e) {
+			expired = append(expired, key)
+		}
+	}
+
+	for _, key := range expired {
+		if err := x.store.Delete(key); err != nil {
+			return 0, fmt.Errorf("delete processed activity [%s]: %w", key, err)
+		}
+	}
+
+	return len(expired), nil
+}
+
+func (x *Index) get(key string) (*entry, error) {
+	entryBytes, err := x.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &entry{}
+	if err := json.Unmarshal(entryBytes, e); err != nil {
+		return nil, fmt.Errorf("unmarshal entry [%s]: %w", key, err)
+	}
+
+	return e, nil
+}
+
+func (x *Index) isExpired(e *entry) bool {
+	return x.now().Sub(e.ProcessedTime) > x.Retention
+}
+
+func (x *Index) start() {
+	go x.purgePeriodically()
+}
+
+func (x *Index) stop() {
+	close(x.done)
+}
+
+func (x *Index) purgePeriodically() {
+	ticker := time.NewTicker(x.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := x.Purge()
+			if err != nil {
+				logger.Warnf("Error purging processed activities: %s", err)
+
+				continue
+			}
+
+			logger.Debugf("Purged %d processed activities", n)
+		case <-x.done:
+			logger.Debugf("Stopped processed-activity purge")
+
+			return
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package processedindex
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+var (
+	activityID1 = testutil.MustParseURL("https://example.com/activities/activity1")
+	activityID2 = testutil.MustParseURL("https://example.com/activities/activity2")
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		x, err := New(&Config{}, mem.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, x)
+		require.Equal(t, defaultRetention, x.Retention)
+		require.Equal(t, defaultPurgeInterval, x.PurgeInterval)
+	})
+
+	t.Run("Open store error", func(t *testing.T) {
+		p := mockstore.NewMockStoreProvider()
+		p.ErrOpenStoreHandle = errors.New("injected open error")
+
+		_, err := New(&Config{}, p)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected open error")
+	})
+}
+
+func TestIndex(t *testing.T) {
+	x, err := New(&Config{Retention: time.Hour}, mem.NewProvider())
+	require.NoError(t, err)
+
+	now := time.Now()
+	x.now = func() time.Time { return now }
+
+	processed, err := x.IsProcessed(activityID1)
+	require.NoError(t, err)
+	require.False(t, processed)
+
+	require.NoError(t, x.MarkProcessed(activityID1))
+
+	processed, err = x.IsProcessed(activityID1)
+	require.NoError(t, err)
+	require.True(t, processed)
+
+	now = now.Add(30 * time.Minute)
+
+	require.NoError(t, x.MarkProcessed(activityID2))
+
+	now = now.Add(45 * time.Minute)
+
+	// activity1 is outside of the retention period.
+	processed, err = x.IsProcessed(activityID1)
+	require.NoError(t, err)
+	require.False(t, processed)
+
+	n, err := x.Purge()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	processed, err = x.IsProcessed(activityID2)
+	require.NoError(t, err)
+	require.True(t, processed)
+
+	t.Run("Pending", func(t *testing.T) {
+		activityID3 := testutil.MustParseURL("https://example.com/activities/activity3")
+
+		pending, err := x.IsPending(activityID3)
+		require.NoError(t, err)
+		require.False(t, pending)
+
+		require.NoError(t, x.MarkPending(activityID3))
+
+		pending, err = x.IsPending(activityID3)
+		require.NoError(t, err)
+		require.True(t, pending)
+
+		processed, err := x.IsProcessed(activityID3)
+		require.NoError(t, err)
+		require.False(t, processed)
+
+		// Pending entries are not purged.
+		now = now.Add(2 * time.Hour)
+
+		n, err := x.Purge()
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		pending, err = x.IsPending(activityID3)
+		require.NoError(t, err)
+		require.True(t, pending)
+
+		require.NoError(t, x.MarkProcessed(activityID3))
+
+		pending, err = x.IsPending(activityID3)
+		require.NoError(t, err)
+		require.False(t, pending)
+
+		processed, err = x.IsProcessed(activityID3)
+		require.NoError(t, err)
+		require.True(t, processed)
+	})
+}
+
+func TestIndex_Error(t *testing.T) {
+	p := mockstore.NewMockStoreProvider()
+
+	x, err := New(&Config{}, p)
+	require.NoError(t, err)
+
+	t.Run("Get error", func(t *testing.T) {
+		p.Store.ErrGet = errors.New("injected get error")
+		defer func() { p.Store.ErrGet = nil }()
+
+		_, err := x.IsProcessed(activityID1)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected get error")
+	})
+
+	t.Run("Invalid entry", func(t *testing.T) {
+		require.NoError(t, p.Store.Put(activityID1.String(), []byte("{")))
+
+		_, err := x.IsProcessed(activityID1)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal entry")
+	})
+
+	t.Run("Put error", func(t *testing.T) {
+		p.Store.ErrPut = errors.New("injected put error")
+		defer func() { p.Store.ErrPut = nil }()
+
+		err := x.MarkProcessed(activityID1)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected put error")
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		p.Store.ErrQuery = errors.New("injected query error")
+		defer func() { p.Store.ErrQuery = nil }()
+
+		_, err := x.Purge()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected query error")
+	})
+}
+
+func TestIndex_PurgePeriodically(t *testing.T) {
+	x, err := New(&Config{Retention: time.Millisecond, PurgeInterval: 10 * time.Millisecond}, mem.NewProvider())
+	require.NoError(t, err)
+
+	require.NoError(t, x.MarkProcessed(activityID1))
+
+	x.Start()
+	defer x.Stop()
+
+	require.Eventually(t, func() bool {
+		_, err := x.store.Get(activityID1.String())
+
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+}
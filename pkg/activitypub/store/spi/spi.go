@@ -9,6 +9,7 @@ package spi
 import (
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 )
@@ -17,6 +18,9 @@ import (
 // object is not found in the store.
 var ErrNotFound = fmt.Errorf("not found in ActivityPub store")
 
+// ErrInvalidBookmark is returned from a query if the bookmark in the query options is invalid.
+var ErrInvalidBookmark = fmt.Errorf("invalid bookmark")
+
 // ReferenceType defines the type of reference, e.g. follower, witness, etc.
 type ReferenceType string
 
@@ -52,11 +56,16 @@ type Store interface {
 	PutActor(actor *vocab.ActorType) error
 	// GetActor returns the actor for the given IRI. Returns an ErrNotFound error if the actor is not in the store.
 	GetActor(actorIRI *url.URL) (*vocab.ActorType, error)
+	// DeleteActor deletes the actor with the given IRI. No error is returned if the actor doesn't exist.
+	DeleteActor(actorIRI *url.URL) error
 	// AddActivity adds the given activity to the activity store.
 	AddActivity(activity *vocab.ActivityType) error
 	// GetActivity returns the activity for the given ID from the given activity store
 	// or an ErrNotFound error if it wasn't found.
 	GetActivity(activityID *url.URL) (*vocab.ActivityType, error)
+	// DeleteActivity deletes the activity with the given ID from the activity store. No error is returned
+	// if the activity doesn't exist.
+	DeleteActivity(activityID *url.URL) error
 	// QueryActivities queries the given activity store using the provided criteria
 	// and returns a results iterator.
 	QueryActivities(query *Criteria, opts ...QueryOpt) (ActivityIterator, error)
@@ -83,6 +92,10 @@ type QueryOptions struct {
 	PageNumber int
 	PageSize   int
 	SortOrder  SortOrder
+
+	// Bookmark is the position in the query results from which to continue (as returned by a previous
+	// query). If set then PageNumber is ignored.
+	Bookmark string
 }
 
 // QueryOpt sets a query option.
@@ -102,6 +115,13 @@ func WithPageNum(pageNum int) QueryOpt {
 	}
 }
 
+// WithBookmark sets the bookmark from which the query results are returned.
+func WithBookmark(bookmark string) QueryOpt {
+	return func(options *QueryOptions) {
+		options.Bookmark = bookmark
+	}
+}
+
 // WithSortOrder sets the sort order. (Default is ascending.)
 func WithSortOrder(sortOrder SortOrder) QueryOpt {
 	return func(options *QueryOptions) {
@@ -116,6 +136,16 @@ type Criteria struct {
 	ObjectIRI     *url.URL
 	ReferenceIRI  *url.URL
 	ActivityIRIs  []*url.URL
+
+	// ActorIRI matches activities that were published by the given actor.
+	ActorIRI *url.URL
+	// TargetIRI matches activities whose object (or target) has the given IRI, for example the 'Like'
+	// activities for an anchor credential.
+	TargetIRI *url.URL
+	// AddedAfter matches activities that were added to the store at or after the given time.
+	AddedAfter *time.Time
+	// AddedBefore matches activities that were added to the store before the given time.
+	AddedBefore *time.Time
 }
 
 // CriteriaOpt sets a Criteria option.
@@ -167,6 +197,29 @@ func WithActivityIRIs(iris ...*url.URL) CriteriaOpt {
 	}
 }
 
+// WithActorIRI sets the actor IRI on the criteria.
+func WithActorIRI(iri *url.URL) CriteriaOpt {
+	return func(query *Criteria) {
+		query.ActorIRI = iri
+	}
+}
+
+// WithTargetIRI sets the IRI of the activity's object (or target) on the criteria.
+func WithTargetIRI(iri *url.URL) CriteriaOpt {
+	return func(query *Criteria) {
+		query.TargetIRI = iri
+	}
+}
+
+// WithTimeRange sets the range of times in which the activities were added to the store. The range includes
+// the 'after' time and excludes the 'before' time. Either time may be nil.
+func WithTimeRange(after, before *time.Time) CriteriaOpt {
+	return func(query *Criteria) {
+		query.AddedAfter = after
+		query.AddedBefore = before
+	}
+}
+
 // ActivityIterator defines the query results iterator for activity queries.
 type ActivityIterator interface {
 	// TotalItems returns the total number of items as a result of the query.
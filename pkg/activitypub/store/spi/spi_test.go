@@ -7,7 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package spi
 
 import (
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -21,3 +23,24 @@ func TestCriteria(t *testing.T) {
 	require.Equal(t, vocab.TypeCreate, c.Types[0])
 	require.Equal(t, vocab.TypeAnnounce, c.Types[1])
 }
+
+func TestCriteria_ActivityFilters(t *testing.T) {
+	actorIRI, err := url.Parse("https://example.com/services/orb")
+	require.NoError(t, err)
+
+	targetIRI, err := url.Parse("https://example.com/cas/1234")
+	require.NoError(t, err)
+
+	after := time.Now().Add(-time.Hour)
+	before := time.Now()
+
+	c := NewCriteria(
+		WithActorIRI(actorIRI),
+		WithTargetIRI(targetIRI),
+		WithTimeRange(&after, &before),
+	)
+	require.Equal(t, actorIRI, c.ActorIRI)
+	require.Equal(t, targetIRI, c.TargetIRI)
+	require.Equal(t, &after, c.AddedAfter)
+	require.Equal(t, &before, c.AddedBefore)
+}
@@ -7,8 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package storeutil
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/url"
+	"time"
 
 	store "github.com/trustbloc/orb/pkg/activitypub/store/spi"
 	"github.com/trustbloc/orb/pkg/activitypub/vocab"
@@ -28,6 +32,138 @@ func GetQueryOptions(opts ...store.QueryOpt) *store.QueryOptions {
 	return options
 }
 
+// Bookmark is a cursor into the results of a query. A bookmark is passed to a query (encoded as a string)
+// in order to continue from where a previous query left off. Since the cursor refers to an item rather than
+// to an offset, items that are added or deleted in the meantime don't cause items to be skipped or repeated.
+type Bookmark struct {
+	// Key is the ID of the item at the cursor, i.e. the ID of an activity or the IRI of a reference.
+	Key string `json:"key"`
+	// Before is true if the page ends immediately before the item at the cursor. Otherwise the page starts
+	// immediately after the item at the cursor.
+	Before bool `json:"before,omitempty"`
+	// Offset is the offset of the item at the cursor when the bookmark was created. The offset is only a hint
+	// that is used to locate the cursor, or to position the page if the item no longer exists.
+	Offset int `json:"offset"`
+}
+
+// NewBookmark returns an encoded bookmark for the item with the given key (ID) at the given offset.
+func NewBookmark(key string, offset int, before bool) string {
+	bookmarkBytes, err := json.Marshal(&Bookmark{Key: key, Offset: offset, Before: before})
+	if err != nil {
+		// Should never happen.
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bookmarkBytes)
+}
+
+// ParseBookmark decodes the given bookmark. An ErrInvalidBookmark error is returned if the bookmark is invalid.
+func ParseBookmark(bookmark string) (*Bookmark, error) {
+	bookmarkBytes, err := base64.RawURLEncoding.DecodeString(bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", store.ErrInvalidBookmark, err)
+	}
+
+	b := &Bookmark{}
+
+	if err := json.Unmarshal(bookmarkBytes, b); err != nil {
+		return nil, fmt.Errorf("%w: %s", store.ErrInvalidBookmark, err)
+	}
+
+	if b.Key == "" {
+		return nil, fmt.Errorf("%w: missing key", store.ErrInvalidBookmark)
+	}
+
+	if b.Offset < 0 {
+		return nil, fmt.Errorf("%w: negative offset", store.ErrInvalidBookmark)
+	}
+
+	return b, nil
+}
+
+// RequiresTotalItems returns true if the total number of items in the query results is required in order
+// to determine the offset of the first item (see GetOffset).
+func RequiresTotalItems(options *store.QueryOptions) bool {
+	return options.SortOrder == store.SortDescending && options.Bookmark == "" && options.PageNumber >= 0
+}
+
+// GetOffset returns the offset of the first item to be returned from a query with the given page number,
+// where totalItems is the total number of items in the query results. Page numbers of results in descending
+// order start from the last page, i.e. page 0 contains the oldest items. Bookmarks are resolved using GetRange.
+func GetOffset(options *store.QueryOptions, totalItems int) int {
+	if options.PageNumber < 0 || options.PageSize <= 0 {
+		return 0
+	}
+
+	if options.SortOrder == store.SortAscending {
+		return options.PageNumber * options.PageSize
+	}
+
+	return (getLastPageNum(totalItems, options.PageSize) - options.PageNumber) * options.PageSize
+}
+
+// GetRange returns the range [start, end) of the items to be returned from sorted query results with the
+// given options, where numItems is the number of items in the results and indexOf returns the index of
+// the item with the given key (or -1 if the item isn't in the results).
+func GetRange(options *store.QueryOptions, numItems int, indexOf func(key string) int) (int, int, error) {
+	if options.Bookmark == "" {
+		offset := GetOffset(options, numItems)
+		if offset < 0 {
+			// The page number is out of range.
+			return numItems, numItems, nil
+		}
+
+		return clamp(offset, numItems), numItems, nil
+	}
+
+	b, err := ParseBookmark(options.Bookmark)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	pos := indexOf(b.Key)
+	if pos < 0 {
+		// The item at the cursor no longer exists, so fall back to the offset at which it was.
+		pos = b.Offset
+
+		if !b.Before {
+			pos--
+		}
+	}
+
+	if !b.Before {
+		return clamp(pos+1, numItems), numItems, nil
+	}
+
+	start := 0
+
+	if options.PageSize > 0 {
+		start = pos - options.PageSize
+	}
+
+	return clamp(start, numItems), clamp(pos, numItems), nil
+}
+
+func clamp(i, numItems int) int {
+	if i < 0 {
+		return 0
+	}
+
+	if i > numItems {
+		return numItems
+	}
+
+	return i
+}
+
+func getLastPageNum(totalItems, pageSize int) int {
+	if totalItems%pageSize > 0 {
+		return totalItems / pageSize
+	}
+
+	return totalItems/pageSize - 1
+}
+
 // ReadReferences returns all of the references resulting from iterating over the given iterator,
 // up to the given maximum number of references. If maxItems is <=0 then all items are read.
 func ReadReferences(it store.ReferenceIterator, maxItems int) ([]*url.URL, error) {
@@ -69,3 +205,112 @@ func ReadActivities(it store.ActivityIterator, maxItems int) ([]*vocab.ActivityT
 
 	return activities, nil
 }
+
+// HasActivityFilter returns true if the given criteria contains any of the activity filters (actor, target
+// or time range) which, in general, can't be satisfied by a storage index and so must be applied to each
+// activity.
+func HasActivityFilter(query *store.Criteria) bool {
+	return query.ActorIRI != nil || query.TargetIRI != nil || query.AddedAfter != nil || query.AddedBefore != nil
+}
+
+// InTimeRange returns true if the given time (at which an activity was added to the store) is within the
+// time range of the given criteria.
+func InTimeRange(query *store.Criteria, timeAdded time.Time) bool {
+	if query.AddedAfter != nil && timeAdded.Before(*query.AddedAfter) {
+		return false
+	}
+
+	if query.AddedBefore != nil && !timeAdded.Before(*query.AddedBefore) {
+		return false
+	}
+
+	return true
+}
+
+// MatchesActivity returns true if the given activity matches the types, actor and target of the given criteria.
+// (The time range is not checked since the time that an activity was added is held by the store.)
+func MatchesActivity(query *store.Criteria, activity *vocab.ActivityType) bool {
+	if len(query.Types) > 0 && !activity.Type().IsAny(query.Types...) {
+		return false
+	}
+
+	if query.ActorIRI != nil && (activity.Actor() == nil || activity.Actor().String() != query.ActorIRI.String()) {
+		return false
+	}
+
+	if query.TargetIRI != nil {
+		return containsIRI(getTargetIRIs(activity), query.TargetIRI)
+	}
+
+	return true
+}
+
+// getTargetIRIs returns the IRIs of the activity's object and target. If the object is a collection then
+// the IRIs of the items are returned. The IRI of the anchor credential (target) of an anchor credential
+// reference is also returned.
+func getTargetIRIs(activity *vocab.ActivityType) []*url.URL {
+	iris := getPropertyIRIs(activity.Target())
+
+	obj := activity.Object()
+	if obj == nil {
+		return iris
+	}
+
+	switch {
+	case obj.Collection() != nil:
+		for _, item := range obj.Collection().Items() {
+			iris = append(iris, getPropertyIRIs(item)...)
+		}
+	case obj.OrderedCollection() != nil:
+		for _, item := range obj.OrderedCollection().Items() {
+			iris = append(iris, getPropertyIRIs(item)...)
+		}
+	}
+
+	return append(iris, getPropertyIRIs(obj)...)
+}
+
+func getPropertyIRIs(prop *vocab.ObjectProperty) []*url.URL {
+	if prop == nil {
+		return nil
+	}
+
+	var iris []*url.URL
+
+	switch {
+	case prop.IRI() != nil:
+		iris = append(iris, prop.IRI())
+	case prop.AnchorCredentialReference() != nil:
+		ref := prop.AnchorCredentialReference()
+
+		iris = appendID(iris, ref.ObjectType)
+
+		if ref.Target() != nil {
+			iris = appendID(iris, ref.Target().Object())
+		}
+	case prop.Activity() != nil:
+		iris = appendID(iris, prop.Activity().ObjectType)
+	case prop.Object() != nil:
+		iris = appendID(iris, prop.Object())
+	}
+
+	return iris
+}
+
+func appendID(iris []*url.URL, obj *vocab.ObjectType) []*url.URL {
+	if obj == nil || obj.ID() == nil {
+		return iris
+	}
+
+	return append(iris, obj.ID().URL())
+}
+
+func containsIRI(iris []*url.URL, iri fmt.Stringer) bool {
+	for _, i := range iris {
+		if i.String() == iri.String() {
+			return true
+		}
+	}
+
+	return false
+}
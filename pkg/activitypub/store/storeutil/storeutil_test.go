@@ -7,14 +7,18 @@ SPDX-License-Identifier: Apache-2.0
 package storeutil
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/orb/pkg/activitypub/store/mocks"
 	"github.com/trustbloc/orb/pkg/activitypub/store/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
 )
 
 //go:generate counterfeiter -o ../mocks/referenceiterator.gen.go --fake-name ReferenceIterator ../spi ReferenceIterator
@@ -24,11 +28,115 @@ func TestGetQueryOptions(t *testing.T) {
 		spi.WithPageNum(1),
 		spi.WithSortOrder(spi.SortDescending),
 		spi.WithPageSize(10),
+		spi.WithBookmark("bookmark1"),
 	)
 	require.NotNil(t, options)
 	require.Equal(t, 1, options.PageNumber)
 	require.Equal(t, 10, options.PageSize)
 	require.Equal(t, spi.SortDescending, options.SortOrder)
+	require.Equal(t, "bookmark1", options.Bookmark)
+}
+
+func TestBookmark(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		b, err := ParseBookmark(NewBookmark("https://example.com/activities/1", 10, true))
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/activities/1", b.Key)
+		require.Equal(t, 10, b.Offset)
+		require.True(t, b.Before)
+	})
+
+	t.Run("Invalid encoding", func(t *testing.T) {
+		_, err := ParseBookmark("{")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, spi.ErrInvalidBookmark))
+	})
+
+	t.Run("Invalid content", func(t *testing.T) {
+		_, err := ParseBookmark(base64.RawURLEncoding.EncodeToString([]byte("{")))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, spi.ErrInvalidBookmark))
+	})
+
+	t.Run("Missing key", func(t *testing.T) {
+		_, err := ParseBookmark(NewBookmark("", 10, false))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, spi.ErrInvalidBookmark))
+	})
+
+	t.Run("Negative offset", func(t *testing.T) {
+		_, err := ParseBookmark(NewBookmark("key", -1, false))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, spi.ErrInvalidBookmark))
+	})
+}
+
+func TestGetOffset(t *testing.T) {
+	const totalItems = 25
+
+	getOffset := func(opts ...spi.QueryOpt) int {
+		return GetOffset(GetQueryOptions(opts...), totalItems)
+	}
+
+	require.Equal(t, 0, getOffset())
+	require.Equal(t, 0, getOffset(spi.WithPageNum(2)))
+	require.Equal(t, 20, getOffset(spi.WithPageSize(10), spi.WithPageNum(2)))
+	require.Equal(t, 0, getOffset(spi.WithPageSize(10), spi.WithPageNum(2), spi.WithSortOrder(spi.SortDescending)))
+	require.Equal(t, 20, getOffset(spi.WithPageSize(10), spi.WithPageNum(0), spi.WithSortOrder(spi.SortDescending)))
+
+	require.False(t, RequiresTotalItems(GetQueryOptions(spi.WithPageNum(1))))
+	require.False(t, RequiresTotalItems(GetQueryOptions(spi.WithSortOrder(spi.SortDescending))))
+	require.True(t, RequiresTotalItems(GetQueryOptions(spi.WithPageNum(1), spi.WithSortOrder(spi.SortDescending))))
+	require.False(t, RequiresTotalItems(GetQueryOptions(spi.WithBookmark("x"), spi.WithSortOrder(spi.SortDescending))))
+}
+
+func TestGetRange(t *testing.T) {
+	keys := []string{"k0", "k1", "k2", "k3", "k4", "k5", "k6"}
+
+	indexOf := func(key string) int {
+		for i, k := range keys {
+			if k == key {
+				return i
+			}
+		}
+
+		return -1
+	}
+
+	getRange := func(opts ...spi.QueryOpt) []int {
+		start, end, err := GetRange(GetQueryOptions(opts...), len(keys), indexOf)
+		require.NoError(t, err)
+
+		return []int{start, end}
+	}
+
+	require.Equal(t, []int{0, 7}, getRange())
+	require.Equal(t, []int{3, 7}, getRange(spi.WithPageSize(3), spi.WithPageNum(1)))
+	require.Equal(t, []int{7, 7}, getRange(spi.WithPageSize(3), spi.WithPageNum(5)))
+	require.Equal(t, []int{7, 7}, getRange(spi.WithPageSize(3), spi.WithPageNum(5), spi.WithSortOrder(spi.SortDescending)))
+
+	t.Run("After cursor", func(t *testing.T) {
+		require.Equal(t, []int{3, 7}, getRange(spi.WithPageSize(3), spi.WithBookmark(NewBookmark("k2", 2, false))))
+
+		// The offset hint is ignored if the item is found.
+		require.Equal(t, []int{3, 7}, getRange(spi.WithPageSize(3), spi.WithBookmark(NewBookmark("k2", 5, false))))
+
+		// The item no longer exists so the offset is used.
+		require.Equal(t, []int{4, 7}, getRange(spi.WithPageSize(3), spi.WithBookmark(NewBookmark("kx", 4, false))))
+		require.Equal(t, []int{7, 7}, getRange(spi.WithPageSize(3), spi.WithBookmark(NewBookmark("kx", 10, false))))
+	})
+
+	t.Run("Before cursor", func(t *testing.T) {
+		require.Equal(t, []int{2, 5}, getRange(spi.WithPageSize(3), spi.WithBookmark(NewBookmark("k5", 5, true))))
+		require.Equal(t, []int{0, 1}, getRange(spi.WithPageSize(3), spi.WithBookmark(NewBookmark("k1", 1, true))))
+		require.Equal(t, []int{0, 5}, getRange(spi.WithBookmark(NewBookmark("k5", 5, true))))
+
+		// The item no longer exists so the offset is used.
+		require.Equal(t, []int{1, 4}, getRange(spi.WithPageSize(3), spi.WithBookmark(NewBookmark("kx", 4, true))))
+	})
+
+	_, _, err := GetRange(GetQueryOptions(spi.WithBookmark("{")), len(keys), indexOf)
+	require.True(t, errors.Is(err, spi.ErrInvalidBookmark))
 }
 
 func TestReadReferences(t *testing.T) {
@@ -83,3 +191,91 @@ func TestReadReferences(t *testing.T) {
 		require.Empty(t, refs)
 	})
 }
+
+func TestHasActivityFilter(t *testing.T) {
+	iri, err := url.Parse("https://example.com/services/orb")
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	require.False(t, HasActivityFilter(spi.NewCriteria(spi.WithType(vocab.TypeCreate))))
+	require.True(t, HasActivityFilter(spi.NewCriteria(spi.WithActorIRI(iri))))
+	require.True(t, HasActivityFilter(spi.NewCriteria(spi.WithTargetIRI(iri))))
+	require.True(t, HasActivityFilter(spi.NewCriteria(spi.WithTimeRange(&now, nil))))
+	require.True(t, HasActivityFilter(spi.NewCriteria(spi.WithTimeRange(nil, &now))))
+}
+
+func TestInTimeRange(t *testing.T) {
+	now := time.Now()
+	after := now.Add(-time.Hour)
+	before := now.Add(time.Hour)
+
+	query := spi.NewCriteria(spi.WithTimeRange(&after, &before))
+
+	require.True(t, InTimeRange(query, now))
+	require.True(t, InTimeRange(query, after))
+	require.False(t, InTimeRange(query, before))
+	require.False(t, InTimeRange(query, after.Add(-time.Second)))
+	require.True(t, InTimeRange(spi.NewCriteria(), now))
+}
+
+func TestMatchesActivity(t *testing.T) {
+	actor1, err := url.Parse("https://example1.com/services/orb")
+	require.NoError(t, err)
+
+	actor2, err := url.Parse("https://example2.com/services/orb")
+	require.NoError(t, err)
+
+	anchorCredID, err := url.Parse("https://example1.com/cas/bafkrei1234")
+	require.NoError(t, err)
+
+	refID, err := url.Parse("https://example1.com/transactions/1234")
+	require.NoError(t, err)
+
+	activityID, err := url.Parse("https://example1.com/services/orb/activities/1234")
+	require.NoError(t, err)
+
+	like := vocab.NewLikeActivity(
+		vocab.NewObjectProperty(vocab.WithIRI(anchorCredID)),
+		vocab.WithActor(actor1),
+	)
+
+	announce := vocab.NewAnnounceActivity(
+		vocab.NewObjectProperty(vocab.WithCollection(vocab.NewCollection(
+			[]*vocab.ObjectProperty{
+				vocab.NewObjectProperty(vocab.WithAnchorCredentialReference(
+					vocab.NewAnchorCredentialReference(refID, anchorCredID, "bafkrei1234"),
+				)),
+			},
+		))),
+		vocab.WithActor(actor2),
+	)
+
+	undo := vocab.NewUndoActivity(
+		vocab.NewObjectProperty(vocab.WithActivity(vocab.NewFollowActivity(
+			vocab.NewObjectProperty(vocab.WithIRI(actor2)), vocab.WithID(activityID),
+		))),
+		vocab.WithActor(actor1),
+	)
+
+	t.Run("Type", func(t *testing.T) {
+		require.True(t, MatchesActivity(spi.NewCriteria(), like))
+		require.True(t, MatchesActivity(spi.NewCriteria(spi.WithType(vocab.TypeLike, vocab.TypeUndo)), like))
+		require.False(t, MatchesActivity(spi.NewCriteria(spi.WithType(vocab.TypeAnnounce)), like))
+	})
+
+	t.Run("Actor", func(t *testing.T) {
+		require.True(t, MatchesActivity(spi.NewCriteria(spi.WithActorIRI(actor1)), like))
+		require.False(t, MatchesActivity(spi.NewCriteria(spi.WithActorIRI(actor2)), like))
+		require.False(t, MatchesActivity(spi.NewCriteria(spi.WithActorIRI(actor2)), vocab.NewLikeActivity(nil)))
+	})
+
+	t.Run("Target", func(t *testing.T) {
+		require.True(t, MatchesActivity(spi.NewCriteria(spi.WithTargetIRI(anchorCredID)), like))
+		require.True(t, MatchesActivity(spi.NewCriteria(spi.WithTargetIRI(anchorCredID)), announce))
+		require.True(t, MatchesActivity(spi.NewCriteria(spi.WithTargetIRI(refID)), announce))
+		require.False(t, MatchesActivity(spi.NewCriteria(spi.WithTargetIRI(refID)), like))
+		require.True(t, MatchesActivity(spi.NewCriteria(spi.WithTargetIRI(activityID)), undo))
+		require.False(t, MatchesActivity(spi.NewCriteria(spi.WithTargetIRI(actor2)), undo))
+	})
+}
@@ -32,6 +32,8 @@ var (
 	offerActivityID   = newMockID(service1, "/activities/65b3d005-6bb6-673d-6879-18bc1ee84976")
 	undoActivityID    = newMockID(service1, "/activities/77bcd005-abb6-433d-a889-18bc1ce64981")
 	likeActivityID    = newMockID(witness1, "/likes/87bcd005-abb6-433d-a889-18bc1ce84988")
+	updateActivityID  = newMockID(service1, "/activities/57bcd005-abb6-433d-a889-18bc1ce64983")
+	deleteActivityID  = newMockID(service1, "/activities/47bcd005-abb6-433d-a889-18bc1ce64984")
 )
 
 func TestCreateTypeMarshal(t *testing.T) {
@@ -926,6 +928,115 @@ func TestUndoTypeMarshal(t *testing.T) {
 	})
 }
 
+func TestUpdateTypeMarshal(t *testing.T) {
+	followers := newMockID(service1, "/followers")
+	inbox := newMockID(service1, "/inbox")
+	keyID := newMockID(service1, "/keys/main-key")
+
+	published := getStaticTime()
+
+	t.Run("Marshal", func(t *testing.T) {
+		actor := NewService(service1,
+			WithPublicKey(NewPublicKey(
+				WithID(keyID),
+				WithOwner(service1),
+				WithPublicKeyPem("-----BEGIN PUBLIC KEY-----"),
+			)),
+			WithInbox(inbox),
+			WithOutbox(newMockID(service1, "/outbox")),
+			WithFollowers(followers),
+			WithFollowing(newMockID(service1, "/following")),
+			WithWitnesses(newMockID(service1, "/witnesses")),
+			WithWitnessing(newMockID(service1, "/witnessing")),
+			WithLiked(newMockID(service1, "/liked")),
+		)
+
+		update := NewUpdateActivity(
+			NewObjectProperty(WithActorObject(actor)),
+			WithID(updateActivityID),
+			WithActor(service1),
+			WithTo(followers),
+			WithPublishedTime(&published),
+		)
+
+		bytes, err := canonicalizer.MarshalCanonical(update)
+		require.NoError(t, err)
+		t.Log(string(bytes))
+
+		require.Equal(t, testutil.GetCanonical(t, jsonUpdate), string(bytes))
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		a := &ActivityType{}
+		require.NoError(t, json.Unmarshal([]byte(jsonUpdate), a))
+		require.NotNil(t, a.Type())
+		require.True(t, a.Type().Is(TypeUpdate))
+		require.Equal(t, updateActivityID.String(), a.ID().String())
+		require.Equal(t, service1.String(), a.Actor().String())
+
+		to := a.To()
+		require.Len(t, to, 1)
+		require.Equal(t, followers.String(), to[0].String())
+
+		objProp := a.Object()
+		require.NotNil(t, objProp)
+		require.True(t, objProp.Type().Is(TypeService))
+
+		actor := objProp.Actor()
+		require.NotNil(t, actor)
+		require.Equal(t, service1.String(), actor.ID().String())
+		require.Equal(t, inbox.String(), actor.Inbox().String())
+		require.NotNil(t, actor.PublicKey())
+		require.Equal(t, keyID.String(), actor.PublicKey().ID.String())
+	})
+}
+
+func TestDeleteTypeMarshal(t *testing.T) {
+	followers := newMockID(service1, "/followers")
+
+	published := getStaticTime()
+
+	t.Run("Marshal", func(t *testing.T) {
+		del := NewDeleteActivity(
+			NewObjectProperty(WithTombstone(NewTombstone(
+				WithID(createActivityID),
+				WithFormerType(TypeCreate),
+				WithDeletedTime(&published),
+			))),
+			WithID(deleteActivityID),
+			WithActor(service1),
+			WithTo(followers),
+			WithPublishedTime(&published),
+		)
+
+		bytes, err := canonicalizer.MarshalCanonical(del)
+		require.NoError(t, err)
+		t.Log(string(bytes))
+
+		require.Equal(t, testutil.GetCanonical(t, jsonDelete), string(bytes))
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		a := &ActivityType{}
+		require.NoError(t, json.Unmarshal([]byte(jsonDelete), a))
+		require.NotNil(t, a.Type())
+		require.True(t, a.Type().Is(TypeDelete))
+		require.Equal(t, deleteActivityID.String(), a.ID().String())
+		require.Equal(t, service1.String(), a.Actor().String())
+
+		objProp := a.Object()
+		require.NotNil(t, objProp)
+		require.True(t, objProp.Type().Is(TypeTombstone))
+
+		tombstone := objProp.Tombstone()
+		require.NotNil(t, tombstone)
+		require.Equal(t, createActivityID.String(), tombstone.ID().String())
+		require.True(t, tombstone.FormerType().Is(TypeCreate))
+		require.NotNil(t, tombstone.Deleted())
+		require.Equal(t, published, *tombstone.Deleted())
+	})
+}
+
 func newMockID(serviceIRI fmt.Stringer, path string) *url.URL {
 	return testutil.MustParseURL(fmt.Sprintf("%s%s", serviceIRI, path))
 }
@@ -1322,4 +1433,50 @@ const (
   "to": "https://org1.com/services/service2",
   "type": "InviteWitness"
 }`
+
+	jsonUpdate = `{
+  "@context": "https://www.w3.org/ns/activitystreams",
+  "actor": "https://sally.example.com/services/orb",
+  "id": "https://sally.example.com/services/orb/activities/57bcd005-abb6-433d-a889-18bc1ce64983",
+  "object": {
+    "@context": [
+      "https://www.w3.org/ns/activitystreams",
+      "https://w3id.org/security/v1",
+      "https://trustbloc.github.io/did-method-orb/contexts/anchor/v1"
+    ],
+    "followers": "https://sally.example.com/services/orb/followers",
+    "following": "https://sally.example.com/services/orb/following",
+    "id": "https://sally.example.com/services/orb",
+    "inbox": "https://sally.example.com/services/orb/inbox",
+    "liked": "https://sally.example.com/services/orb/liked",
+    "outbox": "https://sally.example.com/services/orb/outbox",
+    "publicKey": {
+      "id": "https://sally.example.com/services/orb/keys/main-key",
+      "owner": "https://sally.example.com/services/orb",
+      "publicKeyPem": "-----BEGIN PUBLIC KEY-----"
+    },
+    "type": "Service",
+    "witnesses": "https://sally.example.com/services/orb/witnesses",
+    "witnessing": "https://sally.example.com/services/orb/witnessing"
+  },
+  "published": "2021-01-27T09:30:10Z",
+  "to": "https://sally.example.com/services/orb/followers",
+  "type": "Update"
+}`
+
+	jsonDelete = `{
+  "@context": "https://www.w3.org/ns/activitystreams",
+  "actor": "https://sally.example.com/services/orb",
+  "id": "https://sally.example.com/services/orb/activities/47bcd005-abb6-433d-a889-18bc1ce64984",
+  "object": {
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "deleted": "2021-01-27T09:30:10Z",
+    "formerType": "Create",
+    "id": "https://sally.example.com/services/orb/activities/97bcd005-abb6-423d-a889-18bc1ce84988",
+    "type": "Tombstone"
+  },
+  "published": "2021-01-27T09:30:10Z",
+  "to": "https://sally.example.com/services/orb/followers",
+  "type": "Delete"
+}`
 )
@@ -44,6 +44,13 @@ type actorType struct {
 	Witnesses  *URLProperty   `json:"witnesses"`
 	Witnessing *URLProperty   `json:"witnessing"`
 	Liked      *URLProperty   `json:"liked"`
+	Endpoints  *EndpointsType `json:"endpoints,omitempty"`
+}
+
+// EndpointsType contains the endpoints of an actor which may be useful for the actor
+// or for anyone referencing the actor.
+type EndpointsType struct {
+	SharedInbox *URLProperty `json:"sharedInbox,omitempty"`
 }
 
 // PublicKey returns the actor's public key.
@@ -114,6 +121,15 @@ func (t *ActorType) Liked() *url.URL {
 	return t.actor.Liked.URL()
 }
 
+// SharedInbox returns the URL of the actor's shared inbox or nil if the actor doesn't have a shared inbox.
+func (t *ActorType) SharedInbox() *url.URL {
+	if t.actor.Endpoints == nil || t.actor.Endpoints.SharedInbox == nil {
+		return nil
+	}
+
+	return t.actor.Endpoints.SharedInbox.URL()
+}
+
 // MarshalJSON mmarshals the object to JSON.
 func (t *ActorType) MarshalJSON() ([]byte, error) {
 	return MarshalJSON(t.ObjectType, t.actor)
@@ -146,6 +162,17 @@ func NewService(id *url.URL, opts ...Opt) *ActorType {
 			Witnesses:  NewURLProperty(options.Witnesses),
 			Witnessing: NewURLProperty(options.Witnessing),
 			Liked:      NewURLProperty(options.Liked),
+			Endpoints:  newEndpoints(options),
 		},
 	}
 }
+
+func newEndpoints(options *Options) *EndpointsType {
+	if options.SharedInbox == nil {
+		return nil
+	}
+
+	return &EndpointsType{
+		SharedInbox: NewURLProperty(options.SharedInbox),
+	}
+}
@@ -28,6 +28,7 @@ func TestActor(t *testing.T) {
 	witnesses := testutil.MustParseURL("https://alice.example.com/services/orb/witnesses")
 	witnessing := testutil.MustParseURL("https://alice.example.com/services/orb/witnessing")
 	liked := testutil.MustParseURL("https://alice.example.com/services/orb/liked")
+	sharedInbox := testutil.MustParseURL("https://alice.example.com/services/orb/sharedinbox")
 
 	publicKey := NewPublicKey(
 		WithID(keyID),
@@ -45,6 +46,7 @@ func TestActor(t *testing.T) {
 			WithWitnesses(witnesses),
 			WithWitnessing(witnessing),
 			WithLiked(liked),
+			WithSharedInbox(sharedInbox),
 		)
 
 		bytes, err := canonicalizer.MarshalCanonical(service)
@@ -101,6 +103,10 @@ func TestActor(t *testing.T) {
 		lkd := a.Liked()
 		require.NotNil(t, lkd)
 		require.Equal(t, liked.String(), lkd.String())
+
+		si := a.SharedInbox()
+		require.NotNil(t, si)
+		require.Equal(t, sharedInbox.String(), si.String())
 	})
 
 	t.Run("Empty actor", func(t *testing.T) {
@@ -119,6 +125,7 @@ func TestActor(t *testing.T) {
 		require.Nil(t, a.Witnesses())
 		require.Nil(t, a.Witnessing())
 		require.Nil(t, a.Liked())
+		require.Nil(t, a.SharedInbox())
 	})
 }
 
@@ -141,6 +148,9 @@ const jsonService = `{
   "following": "https://sally.example.com/services/orb/following",
   "witnesses": "https://alice.example.com/services/orb/witnesses",
   "witnessing": "https://alice.example.com/services/orb/witnessing",
-  "liked": "https://alice.example.com/services/orb/liked"
+  "liked": "https://alice.example.com/services/orb/liked",
+  "endpoints": {
+    "sharedInbox": "https://alice.example.com/services/orb/sharedinbox"
+  }
 }
 `
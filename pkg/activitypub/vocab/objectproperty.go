@@ -21,6 +21,8 @@ type ObjectProperty struct {
 	orderedColl   *OrderedCollectionType
 	activity      *ActivityType
 	anchorCredRef *AnchorCredentialReferenceType
+	actor         *ActorType
+	tombstone     *TombstoneType
 }
 
 // NewObjectProperty returns a new 'object' property with the given options.
@@ -34,6 +36,8 @@ func NewObjectProperty(opts ...Opt) *ObjectProperty {
 		orderedColl:   options.OrderedCollection,
 		activity:      options.Activity,
 		anchorCredRef: options.AnchorCredRef,
+		actor:         options.ActorObject,
+		tombstone:     options.Tombstone,
 	}
 }
 
@@ -64,6 +68,14 @@ func (p *ObjectProperty) Type() *TypeProperty {
 		return p.anchorCredRef.Type()
 	}
 
+	if p.actor != nil {
+		return p.actor.Type()
+	}
+
+	if p.tombstone != nil {
+		return p.tombstone.Type()
+	}
+
 	return nil
 }
 
@@ -122,6 +134,24 @@ func (p *ObjectProperty) AnchorCredentialReference() *AnchorCredentialReferenceT
 	return p.anchorCredRef
 }
 
+// Actor returns the actor or nil if the actor is not set.
+func (p *ObjectProperty) Actor() *ActorType {
+	if p == nil {
+		return nil
+	}
+
+	return p.actor
+}
+
+// Tombstone returns the tombstone or nil if the tombstone is not set.
+func (p *ObjectProperty) Tombstone() *TombstoneType {
+	if p == nil {
+		return nil
+	}
+
+	return p.tombstone
+}
+
 // MarshalJSON marshals the 'object' property.
 func (p *ObjectProperty) MarshalJSON() ([]byte, error) {
 	if p.iri != nil {
@@ -148,6 +178,14 @@ func (p *ObjectProperty) MarshalJSON() ([]byte, error) {
 		return json.Marshal(p.anchorCredRef)
 	}
 
+	if p.actor != nil {
+		return json.Marshal(p.actor)
+	}
+
+	if p.tombstone != nil {
+		return json.Marshal(p.tombstone)
+	}
+
 	return nil, fmt.Errorf("nil object property")
 }
 
@@ -188,6 +226,12 @@ func (p *ObjectProperty) UnmarshalJSON(bytes []byte) error {
 	case obj.object.Type.Is(TypeAnchorCredentialRef):
 		err = p.unmarshalAnchorCredentialReference(bytes)
 
+	case obj.object.Type.IsAny(ActorTypes()...):
+		err = p.unmarshalActor(bytes)
+
+	case obj.object.Type.Is(TypeTombstone):
+		err = p.unmarshalTombstone(bytes)
+
 	default:
 		p.obj = obj
 	}
@@ -242,3 +286,27 @@ func (p *ObjectProperty) unmarshalAnchorCredentialReference(bytes []byte) error
 
 	return nil
 }
+
+func (p *ObjectProperty) unmarshalActor(bytes []byte) error {
+	a := &ActorType{}
+
+	if err := json.Unmarshal(bytes, &a); err != nil {
+		return err
+	}
+
+	p.actor = a
+
+	return nil
+}
+
+func (p *ObjectProperty) unmarshalTombstone(bytes []byte) error {
+	t := &TombstoneType{}
+
+	if err := json.Unmarshal(bytes, &t); err != nil {
+		return err
+	}
+
+	p.tombstone = t
+
+	return nil
+}
@@ -8,6 +8,7 @@ package vocab
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -35,6 +36,8 @@ func TestNewObjectProperty(t *testing.T) {
 		require.Nil(t, p.OrderedCollection())
 		require.Nil(t, p.Activity())
 		require.Nil(t, p.AnchorCredentialReference())
+		require.Nil(t, p.Actor())
+		require.Nil(t, p.Tombstone())
 	})
 
 	t.Run("Empty", func(t *testing.T) {
@@ -46,6 +49,8 @@ func TestNewObjectProperty(t *testing.T) {
 		require.Nil(t, p.OrderedCollection())
 		require.Nil(t, p.Activity())
 		require.Nil(t, p.AnchorCredentialReference())
+		require.Nil(t, p.Actor())
+		require.Nil(t, p.Tombstone())
 	})
 
 	t.Run("WithIRI", func(t *testing.T) {
@@ -121,6 +126,36 @@ func TestNewObjectProperty(t *testing.T) {
 		require.NotNil(t, collContext)
 		require.True(t, collContext.Contains(ContextActivityStreams))
 	})
+
+	t.Run("WithActorObject", func(t *testing.T) {
+		serviceIRI := testutil.MustParseURL("https://org1.com/services/service1")
+
+		p := NewObjectProperty(WithActorObject(NewService(serviceIRI)))
+		require.NotNil(t, p)
+
+		typeProp := p.Type()
+		require.Nil(t, p.IRI())
+		require.NotNil(t, typeProp)
+		require.True(t, typeProp.Is(TypeService))
+
+		actor := p.Actor()
+		require.NotNil(t, actor)
+		require.Equal(t, serviceIRI.String(), actor.ID().String())
+	})
+
+	t.Run("WithTombstone", func(t *testing.T) {
+		p := NewObjectProperty(WithTombstone(NewTombstone(WithID(txn1))))
+		require.NotNil(t, p)
+
+		typeProp := p.Type()
+		require.Nil(t, p.IRI())
+		require.NotNil(t, typeProp)
+		require.True(t, typeProp.Is(TypeTombstone))
+
+		tombstone := p.Tombstone()
+		require.NotNil(t, tombstone)
+		require.Equal(t, txn1.String(), tombstone.ID().String())
+	})
 }
 
 func TestObjectProperty_MarshalJSON(t *testing.T) {
@@ -338,6 +373,20 @@ func TestObjectProperty_UnmarshalJSON(t *testing.T) {
 		require.NotNil(t, iri)
 		require.Equal(t, txn2.String(), iri.String())
 	})
+
+	t.Run("WithActor", func(t *testing.T) {
+		for _, actorType := range ActorTypes() {
+			p := NewObjectProperty()
+			require.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(jsonActorObjectProperty, actorType)), p))
+
+			require.True(t, p.Type().Is(actorType))
+
+			actor := p.Actor()
+			require.NotNilf(t, actor, "expecting actor for type %s", actorType)
+			require.Equal(t, "https://org1.com/users/user1", actor.ID().String())
+			require.NotNil(t, actor.PublicKey())
+		}
+	})
 }
 
 var objectPropertyID = testutil.MustParseURL("https://example.com/some_obj_ID")
@@ -345,6 +394,18 @@ var objectPropertyID = testutil.MustParseURL("https://example.com/some_obj_ID")
 const (
 	jsonIRIObjectProperty = `"https://example.com/obj1"`
 
+	jsonActorObjectProperty = `{
+  "@context": "https://www.w3.org/ns/activitystreams",
+  "id": "https://org1.com/users/user1",
+  "type": "%s",
+  "publicKey": {
+    "id": "https://org1.com/users/user1/keys/main-key",
+    "owner": "https://org1.com/users/user1",
+    "publicKeyPem": "-----BEGIN PUBLIC KEY-----"
+  },
+  "inbox": "https://org1.com/users/user1/inbox"
+}`
+
 	jsonEmbeddedObjectProperty = `{
   "@context": "https://trustbloc.github.io/did-method-orb/contexts/anchor/v1",
   "id": "https://example.com/some_obj_ID",
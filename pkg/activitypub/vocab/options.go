@@ -27,6 +27,7 @@ type Options struct {
 	ActivityOptions
 	ActorOptions
 	PublicKeyOptions
+	TombstoneOptions
 }
 
 // Opt is an for an object, activity, etc.
@@ -167,6 +168,8 @@ type ObjectPropertyOptions struct {
 	OrderedCollection *OrderedCollectionType
 	Activity          *ActivityType
 	AnchorCredRef     *AnchorCredentialReferenceType
+	ActorObject       *ActorType
+	Tombstone         *TombstoneType
 }
 
 // WithIRI sets the 'object' property to an IRI.
@@ -211,6 +214,20 @@ func WithAnchorCredentialReference(ref *AnchorCredentialReferenceType) Opt {
 	}
 }
 
+// WithActorObject sets the 'object' property to an embedded actor.
+func WithActorObject(actor *ActorType) Opt {
+	return func(opts *Options) {
+		opts.ActorObject = actor
+	}
+}
+
+// WithTombstone sets the 'object' property to an embedded tombstone.
+func WithTombstone(tombstone *TombstoneType) Opt {
+	return func(opts *Options) {
+		opts.Tombstone = tombstone
+	}
+}
+
 // ActivityOptions holds the options for an Activity.
 type ActivityOptions struct {
 	Result *ObjectProperty
@@ -241,14 +258,15 @@ func WithResult(result *ObjectProperty) Opt {
 
 // ActorOptions holds the options for an Activity.
 type ActorOptions struct {
-	PublicKey  *PublicKeyType
-	Inbox      *url.URL
-	Outbox     *url.URL
-	Followers  *url.URL
-	Following  *url.URL
-	Witnesses  *url.URL
-	Witnessing *url.URL
-	Liked      *url.URL
+	PublicKey   *PublicKeyType
+	Inbox       *url.URL
+	Outbox      *url.URL
+	Followers   *url.URL
+	Following   *url.URL
+	Witnesses   *url.URL
+	Witnessing  *url.URL
+	Liked       *url.URL
+	SharedInbox *url.URL
 }
 
 // WithPublicKey sets the 'publicKey' property on the actor.
@@ -307,6 +325,13 @@ func WithLiked(liked *url.URL) Opt {
 	}
 }
 
+// WithSharedInbox sets the 'sharedInbox' endpoint on the actor.
+func WithSharedInbox(sharedInbox *url.URL) Opt {
+	return func(opts *Options) {
+		opts.SharedInbox = sharedInbox
+	}
+}
+
 // PublicKeyOptions holds the options for a Public Key.
 type PublicKeyOptions struct {
 	Owner        *url.URL
@@ -327,6 +352,26 @@ func WithPublicKeyPem(pem string) Opt {
 	}
 }
 
+// TombstoneOptions holds the options for a Tombstone.
+type TombstoneOptions struct {
+	FormerType []Type
+	Deleted    *time.Time
+}
+
+// WithFormerType sets the 'formerType' property on the tombstone.
+func WithFormerType(t ...Type) Opt {
+	return func(opts *Options) {
+		opts.FormerType = t
+	}
+}
+
+// WithDeletedTime sets the 'deleted' property on the tombstone.
+func WithDeletedTime(t *time.Time) Opt {
+	return func(opts *Options) {
+		opts.Deleted = t
+	}
+}
+
 func getContexts(options *Options, contexts ...Context) []Context {
 	return append(contexts, options.Context...)
 }
@@ -0,0 +1,63 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vocab
+
+import (
+	"time"
+)
+
+// TombstoneType defines a 'Tombstone' object, which is a placeholder for an object that has been deleted.
+type TombstoneType struct {
+	*ObjectType
+
+	tombstone *tombstoneType
+}
+
+type tombstoneType struct {
+	FormerType *TypeProperty `json:"formerType,omitempty"`
+	Deleted    *time.Time    `json:"deleted,omitempty"`
+}
+
+// NewTombstone returns a new 'Tombstone' object.
+func NewTombstone(opts ...Opt) *TombstoneType {
+	options := NewOptions(opts...)
+
+	return &TombstoneType{
+		ObjectType: NewObject(
+			WithContext(getContexts(options, ContextActivityStreams)...),
+			WithID(options.ID),
+			WithType(TypeTombstone),
+		),
+		tombstone: &tombstoneType{
+			FormerType: NewTypeProperty(options.FormerType...),
+			Deleted:    options.Deleted,
+		},
+	}
+}
+
+// FormerType returns the type of the object that was deleted.
+func (t *TombstoneType) FormerType() *TypeProperty {
+	return t.tombstone.FormerType
+}
+
+// Deleted returns the time when the object was deleted.
+func (t *TombstoneType) Deleted() *time.Time {
+	return t.tombstone.Deleted
+}
+
+// MarshalJSON marshals the tombstone.
+func (t *TombstoneType) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(t.ObjectType, t.tombstone)
+}
+
+// UnmarshalJSON unmarshals the tombstone.
+func (t *TombstoneType) UnmarshalJSON(bytes []byte) error {
+	t.ObjectType = NewObject()
+	t.tombstone = &tombstoneType{}
+
+	return UnmarshalJSON(bytes, t.ObjectType, t.tombstone)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vocab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/canonicalizer"
+
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+func TestTombstone(t *testing.T) {
+	deleted := getStaticTime()
+
+	t.Run("Marshal", func(t *testing.T) {
+		tombstone := NewTombstone(
+			WithID(txn1),
+			WithFormerType(TypeAnchorCredential),
+			WithDeletedTime(&deleted),
+		)
+
+		bytes, err := canonicalizer.MarshalCanonical(tombstone)
+		require.NoError(t, err)
+		t.Log(string(bytes))
+
+		require.Equal(t, testutil.GetCanonical(t, jsonTombstone), string(bytes))
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		tombstone := &TombstoneType{}
+		require.NoError(t, json.Unmarshal([]byte(jsonTombstone), tombstone))
+
+		require.True(t, tombstone.Type().Is(TypeTombstone))
+		require.Equal(t, txn1.String(), tombstone.ID().String())
+		require.True(t, tombstone.FormerType().Is(TypeAnchorCredential))
+		require.NotNil(t, tombstone.Deleted())
+		require.Equal(t, deleted, *tombstone.Deleted())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		tombstone := NewTombstone()
+		require.Nil(t, tombstone.FormerType())
+		require.Nil(t, tombstone.Deleted())
+	})
+}
+
+const jsonTombstone = `{
+  "@context": "https://www.w3.org/ns/activitystreams",
+  "deleted": "2021-01-27T09:30:10Z",
+  "formerType": "AnchorCredential",
+  "id": "https://org1.com/transactions/txn1",
+  "type": "Tombstone"
+}`
@@ -40,6 +40,14 @@ const (
 
 	// TypeService specifies the 'Service' actor type.
 	TypeService Type = "Service"
+	// TypePerson specifies the 'Person' actor type.
+	TypePerson Type = "Person"
+	// TypeApplication specifies the 'Application' actor type.
+	TypeApplication Type = "Application"
+	// TypeGroup specifies the 'Group' actor type.
+	TypeGroup Type = "Group"
+	// TypeOrganization specifies the 'Organization' actor type.
+	TypeOrganization Type = "Organization"
 	// TypeCreate specifies the 'Create' activity type.
 	TypeCreate Type = "Create"
 	// TypeAnnounce specifies the 'Announce' activity type.
@@ -68,8 +76,19 @@ const (
 	TypeOffer Type = "Offer"
 	// TypeUndo specifies the "Undo" activity type.
 	TypeUndo Type = "Undo"
+	// TypeUpdate specifies the "Update" activity type.
+	TypeUpdate Type = "Update"
+	// TypeDelete specifies the "Delete" activity type.
+	TypeDelete Type = "Delete"
+	// TypeTombstone specifies the "Tombstone" object type.
+	TypeTombstone Type = "Tombstone"
 )
 
+// ActorTypes returns the types of objects that are actors.
+func ActorTypes() []Type {
+	return []Type{TypeService, TypePerson, TypeApplication, TypeGroup, TypeOrganization}
+}
+
 const (
 	propertyContext    = "@context"
 	propertyID         = "id"
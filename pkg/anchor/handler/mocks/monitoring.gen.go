@@ -2,17 +2,27 @@
 package mocks
 
 import (
+	"net/url"
 	"sync"
 	"time"
 )
 
 type MonitoringService struct {
-	WatchStub        func(string, time.Time, []byte) error
+	RecordInvalidProofStub        func(*url.URL, string, []byte, error)
+	recordInvalidProofMutex       sync.RWMutex
+	recordInvalidProofArgsForCall []struct {
+		arg1 *url.URL
+		arg2 string
+		arg3 []byte
+		arg4 error
+	}
+	WatchStub        func(*url.URL, string, time.Time, []byte) error
 	watchMutex       sync.RWMutex
 	watchArgsForCall []struct {
-		arg1 string
-		arg2 time.Time
-		arg3 []byte
+		arg1 *url.URL
+		arg2 string
+		arg3 time.Time
+		arg4 []byte
 	}
 	watchReturns struct {
 		result1 error
@@ -24,23 +34,63 @@ type MonitoringService struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *MonitoringService) Watch(arg1 string, arg2 time.Time, arg3 []byte) error {
+func (fake *MonitoringService) RecordInvalidProof(arg1 *url.URL, arg2 string, arg3 []byte, arg4 error) {
 	var arg3Copy []byte
 	if arg3 != nil {
 		arg3Copy = make([]byte, len(arg3))
 		copy(arg3Copy, arg3)
 	}
+	fake.recordInvalidProofMutex.Lock()
+	fake.recordInvalidProofArgsForCall = append(fake.recordInvalidProofArgsForCall, struct {
+		arg1 *url.URL
+		arg2 string
+		arg3 []byte
+		arg4 error
+	}{arg1, arg2, arg3Copy, arg4})
+	fake.recordInvocation("RecordInvalidProof", []interface{}{arg1, arg2, arg3Copy, arg4})
+	fake.recordInvalidProofMutex.Unlock()
+	if fake.RecordInvalidProofStub != nil {
+		fake.RecordInvalidProofStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *MonitoringService) RecordInvalidProofCallCount() int {
+	fake.recordInvalidProofMutex.RLock()
+	defer fake.recordInvalidProofMutex.RUnlock()
+	return len(fake.recordInvalidProofArgsForCall)
+}
+
+func (fake *MonitoringService) RecordInvalidProofCalls(stub func(*url.URL, string, []byte, error)) {
+	fake.recordInvalidProofMutex.Lock()
+	defer fake.recordInvalidProofMutex.Unlock()
+	fake.RecordInvalidProofStub = stub
+}
+
+func (fake *MonitoringService) RecordInvalidProofArgsForCall(i int) (*url.URL, string, []byte, error) {
+	fake.recordInvalidProofMutex.RLock()
+	defer fake.recordInvalidProofMutex.RUnlock()
+	argsForCall := fake.recordInvalidProofArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *MonitoringService) Watch(arg1 *url.URL, arg2 string, arg3 time.Time, arg4 []byte) error {
+	var arg4Copy []byte
+	if arg4 != nil {
+		arg4Copy = make([]byte, len(arg4))
+		copy(arg4Copy, arg4)
+	}
 	fake.watchMutex.Lock()
 	ret, specificReturn := fake.watchReturnsOnCall[len(fake.watchArgsForCall)]
 	fake.watchArgsForCall = append(fake.watchArgsForCall, struct {
-		arg1 string
-		arg2 time.Time
-		arg3 []byte
-	}{arg1, arg2, arg3Copy})
-	fake.recordInvocation("Watch", []interface{}{arg1, arg2, arg3Copy})
+		arg1 *url.URL
+		arg2 string
+		arg3 time.Time
+		arg4 []byte
+	}{arg1, arg2, arg3, arg4Copy})
+	fake.recordInvocation("Watch", []interface{}{arg1, arg2, arg3, arg4Copy})
 	fake.watchMutex.Unlock()
 	if fake.WatchStub != nil {
-		return fake.WatchStub(arg1, arg2, arg3)
+		return fake.WatchStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -55,17 +105,17 @@ func (fake *MonitoringService) WatchCallCount() int {
 	return len(fake.watchArgsForCall)
 }
 
-func (fake *MonitoringService) WatchCalls(stub func(string, time.Time, []byte) error) {
+func (fake *MonitoringService) WatchCalls(stub func(*url.URL, string, time.Time, []byte) error) {
 	fake.watchMutex.Lock()
 	defer fake.watchMutex.Unlock()
 	fake.WatchStub = stub
 }
 
-func (fake *MonitoringService) WatchArgsForCall(i int) (string, time.Time, []byte) {
+func (fake *MonitoringService) WatchArgsForCall(i int) (*url.URL, string, time.Time, []byte) {
 	fake.watchMutex.RLock()
 	defer fake.watchMutex.RUnlock()
 	argsForCall := fake.watchArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *MonitoringService) WatchReturns(result1 error) {
@@ -94,6 +144,8 @@ func (fake *MonitoringService) WatchReturnsOnCall(i int, result1 error) {
 func (fake *MonitoringService) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.recordInvalidProofMutex.RLock()
+	defer fake.recordInvalidProofMutex.RUnlock()
 	fake.watchMutex.RLock()
 	defer fake.watchMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
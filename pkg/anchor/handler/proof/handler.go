@@ -8,19 +8,31 @@ package proof
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	"github.com/piprate/json-gold/ld"
 	"github.com/trustbloc/edge-core/pkg/log"
+	vctclient "github.com/trustbloc/vct/pkg/client/vct"
 
+	"github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
 	"github.com/trustbloc/orb/pkg/activitypub/service/vct"
+	discoveryclient "github.com/trustbloc/orb/pkg/discovery/endpoint/client"
 )
 
 var logger = log.New("proof-handler")
 
+// maxClockSkew is the tolerance allowed when comparing the created time of a witness proof with local time.
+const maxClockSkew = time.Minute
+
+// ErrInvalidProof is returned when a witness proof fails verification.
+var ErrInvalidProof = errors.New("invalid witness proof")
+
 // New creates new proof handler.
 func New(providers *Providers, vcChan chan *verifiable.Credential) *WitnessProofHandler {
 	return &WitnessProofHandler{Providers: providers, vcCh: vcChan}
@@ -32,6 +44,12 @@ type Providers struct {
 	MonitoringSvc monitoringSvc
 	DocLoader     ld.DocumentLoader
 	WitnessStore  witnessStore
+	Pkf           verifiable.PublicKeyFetcher
+	// WitnessMetrics (optional) records the proofs received from witnesses.
+	WitnessMetrics witnessMetrics
+	// LogResolver (optional) resolves the log of a witness in order to verify that the created time of a proof
+	// was signed by the log.
+	LogResolver logResolver
 }
 
 // WitnessProofHandler handles an anchor credential witness proof.
@@ -44,29 +62,38 @@ type witnessStore interface {
 	AddProof(vcID, witness string, p []byte) error
 }
 
+type witnessMetrics interface {
+	LikeReceived(witness, vcID string) error
+}
+
+type logResolver interface {
+	GetLogEndpoint(witness *url.URL) (*discoveryclient.LogEndpoint, error)
+}
+
 type vcStore interface {
 	Get(id string) (*verifiable.Credential, error)
 }
 
 type monitoringSvc interface {
-	Watch(anchorCredID string, endTime time.Time, proof []byte) error
+	Watch(witness *url.URL, anchorCredID string, endTime time.Time, proof []byte) error
+	RecordInvalidProof(witness *url.URL, anchorCredID string, proof []byte, reason error)
 }
 
-// HandleProof handles proof.
+// HandleProof handles proof. The proof is accepted only if it was signed by the witness over the anchor
+// credential that was offered to the witness, its created time is valid and its domain is the log of the
+// witness (which is verified by the monitoring service). An invalid proof is rejected and recorded. If the
+// proof couldn't be verified (e.g. the public key of the witness couldn't be resolved) then an error is
+// returned without recording the proof so that the 'Like' is processed again.
 func (h *WitnessProofHandler) HandleProof(witness *url.URL, anchorCredID string, startTime, endTime time.Time, proof []byte) error { //nolint:lll
 	logger.Debugf("received request anchorCredID[%s] from witness[%s], proof: %s",
 		anchorCredID, witness.String(), string(proof))
 
-	err := h.MonitoringSvc.Watch(anchorCredID, endTime, proof)
-	if err != nil {
-		return fmt.Errorf("failed to setup monitoring for anchor credential[%s]: %w", anchorCredID, err)
-	}
-
 	var witnessProof vct.Proof
 
-	err = json.Unmarshal(proof, &witnessProof)
+	err := json.Unmarshal(proof, &witnessProof)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal witness proof for anchor credential[%s]: %w", anchorCredID, err)
+		return h.rejectProof(witness, anchorCredID, proof,
+			fmt.Errorf("failed to unmarshal witness proof for anchor credential[%s]: %w", anchorCredID, err))
 	}
 
 	vc, err := h.Store.Get(anchorCredID)
@@ -74,6 +101,27 @@ func (h *WitnessProofHandler) HandleProof(witness *url.URL, anchorCredID string,
 		return fmt.Errorf("failed to retrieve anchor credential[%s]: %w", anchorCredID, err)
 	}
 
+	err = h.verifyProof(witness, vc, &witnessProof)
+	if err != nil {
+		if isTransient(err) {
+			return fmt.Errorf("failed to verify proof from witness[%s] for anchor credential[%s]: %w",
+				witness, anchorCredID, err)
+		}
+
+		return h.rejectProof(witness, anchorCredID, proof, err)
+	}
+
+	err = h.MonitoringSvc.Watch(witness, anchorCredID, endTime, proof)
+	if err != nil {
+		if errors.Is(err, monitoring.ErrInvalidProof) {
+			return h.rejectProof(witness, anchorCredID, proof, err)
+		}
+
+		return fmt.Errorf("failed to setup monitoring for anchor credential[%s]: %w", anchorCredID, err)
+	}
+
+	h.recordLikeReceived(witness, vc)
+
 	if len(vc.Proofs) > 1 {
 		// TODO: issue-322 (handle multiple proofs - our witness policy is currently 1)
 		logger.Debugf("Credential[%s] has already been witnessed, nothing to do", vc.ID)
@@ -92,3 +140,181 @@ func (h *WitnessProofHandler) HandleProof(witness *url.URL, anchorCredID string,
 
 	return nil
 }
+
+// verifyProof verifies that the proof was created by the given witness for the given (offered) anchor credential.
+// A transient error is returned if the proof couldn't be verified.
+func (h *WitnessProofHandler) verifyProof(witness *url.URL, vc *verifiable.Credential, wp *vct.Proof) error {
+	p := wp.Proof
+	if p == nil {
+		return errors.New("proof is missing")
+	}
+
+	if domain, ok := p["domain"].(string); !ok || domain == "" {
+		return errors.New("domain is missing in proof")
+	}
+
+	if err := verifyVerificationMethod(witness, p); err != nil {
+		return err
+	}
+
+	created, err := verifyCreated(vc, p)
+	if err != nil {
+		return err
+	}
+
+	// The signature is verified against the anchor credential that's in our store (i.e. the credential that was
+	// offered to the witness) so the proof is only valid if the witness signed the exact same content.
+	witnessedVC := *vc
+	witnessedVC.Proofs = []verifiable.Proof{p}
+
+	vcBytes, err := witnessedVC.MarshalJSON()
+	if err != nil {
+		return newTransientError(fmt.Errorf("marshal anchor credential[%s]: %w", vc.ID, err))
+	}
+
+	// Failures to resolve the public key of the witness don't invalidate the proof.
+	var resolveErr error
+
+	pkf := func(issuerID, keyID string) (*verifier.PublicKey, error) {
+		pubKey, e := h.Pkf(issuerID, keyID)
+		if e != nil {
+			resolveErr = e
+		}
+
+		return pubKey, e
+	}
+
+	_, err = verifiable.ParseCredential(vcBytes,
+		verifiable.WithPublicKeyFetcher(pkf),
+		verifiable.WithJSONLDDocumentLoader(h.DocLoader),
+	)
+	if err != nil {
+		if resolveErr != nil {
+			return newTransientError(fmt.Errorf("resolve public key of witness[%s]: %w", witness, resolveErr))
+		}
+
+		return fmt.Errorf("verify signature of witness[%s]: %w", witness, err)
+	}
+
+	return h.verifyTimestamp(witness, vc, wp, created)
+}
+
+// verifyTimestamp verifies that the created time of the proof is the timestamp that was signed by the log of
+// the witness. The timestamp is only verified if the witness publishes its log. If it does then the proof must
+// contain the signature of the log, otherwise a witness could avoid the check by leaving out the signature.
+func (h *WitnessProofHandler) verifyTimestamp(witness *url.URL, vc *verifiable.Credential, wp *vct.Proof,
+	created time.Time) error {
+	if h.LogResolver == nil {
+		return nil
+	}
+
+	endpoint, err := h.LogResolver.GetLogEndpoint(witness)
+	if err != nil {
+		switch {
+		case errors.Is(err, discoveryclient.ErrNotFound):
+			logger.Warnf("Unable to verify the created time of the proof from witness[%s]: %s", witness, err)
+
+			return nil
+		case errors.Is(err, discoveryclient.ErrPublicKeyMismatch):
+			return fmt.Errorf("resolve log of witness[%s]: %w", witness, err)
+		default:
+			return newTransientError(fmt.Errorf("resolve log of witness[%s]: %w", witness, err))
+		}
+	}
+
+	if len(wp.TimestampSignature) == 0 {
+		return fmt.Errorf("timestamp signature of log [%s] is missing in proof from witness[%s]",
+			endpoint.URL, witness)
+	}
+
+	err = vctclient.VerifyVCTimestampSignature(wp.TimestampSignature, endpoint.PublicKey,
+		uint64(created.UnixNano()/int64(time.Millisecond)), vc)
+	if err != nil {
+		return fmt.Errorf("proof created time [%s] was not signed by log [%s] of witness[%s]: %w",
+			created, endpoint.URL, witness, err)
+	}
+
+	return nil
+}
+
+// verifyVerificationMethod ensures that the proof was signed with a key that's published by the witness.
+func verifyVerificationMethod(witness *url.URL, p verifiable.Proof) error {
+	verificationMethod, ok := p["verificationMethod"].(string)
+	if !ok {
+		return errors.New("verification method is missing in proof")
+	}
+
+	did := "did:web:" + witness.Host
+
+	if !strings.HasPrefix(verificationMethod, did+"#") {
+		return fmt.Errorf("verification method [%s] does not belong to witness[%s]", verificationMethod, witness)
+	}
+
+	return nil
+}
+
+// verifyCreated ensures that the proof wasn't created before the anchor credential was issued or in the future.
+func verifyCreated(vc *verifiable.Credential, p verifiable.Proof) (time.Time, error) {
+	createdStr, ok := p["created"].(string)
+	if !ok {
+		return time.Time{}, errors.New("created time is missing in proof")
+	}
+
+	created, err := time.Parse(time.RFC3339, createdStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse created time: %w", err)
+	}
+
+	if vc.Issued != nil && created.Before(vc.Issued.Time.Add(-maxClockSkew)) {
+		return time.Time{}, fmt.Errorf("proof created time [%s] is before the anchor credential was issued [%s]",
+			created, vc.Issued.Time)
+	}
+
+	if created.After(time.Now().Add(maxClockSkew)) {
+		return time.Time{}, fmt.Errorf("proof created time [%s] is in the future", created)
+	}
+
+	return created, nil
+}
+
+// recordLikeReceived records the proof of the witness. The latency is measured (by the metrics store) from
+// the time that the anchor credential was offered to the witness.
+func (h *WitnessProofHandler) recordLikeReceived(witness *url.URL, vc *verifiable.Credential) {
+	if h.WitnessMetrics == nil {
+		return
+	}
+
+	err := h.WitnessMetrics.LikeReceived(witness.String(), vc.ID)
+	if err != nil {
+		logger.Warnf("failed to record proof received from witness[%s]: %s", witness, err)
+	}
+}
+
+// transientError indicates that a proof couldn't be verified, i.e. the proof isn't known to be invalid.
+type transientError struct {
+	err error
+}
+
+func newTransientError(err error) error {
+	return &transientError{err: err}
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+func isTransient(err error) bool {
+	var te *transientError
+
+	return errors.As(err, &te)
+}
+
+func (h *WitnessProofHandler) rejectProof(witness *url.URL, anchorCredID string, proof []byte, reason error) error {
+	h.MonitoringSvc.RecordInvalidProof(witness, anchorCredID, proof, reason)
+
+	return fmt.Errorf("%w from witness[%s] for anchor credential[%s]: %s", ErrInvalidProof, witness, anchorCredID, reason)
+}
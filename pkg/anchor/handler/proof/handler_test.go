@@ -7,19 +7,34 @@ SPDX-License-Identifier: Apache-2.0
 package proof
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/vct/pkg/controller/command"
 
+	"github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
+	"github.com/trustbloc/orb/pkg/activitypub/service/vct"
 	"github.com/trustbloc/orb/pkg/anchor/handler/mocks"
+	discoveryclient "github.com/trustbloc/orb/pkg/discovery/endpoint/client"
 	"github.com/trustbloc/orb/pkg/internal/testutil"
 	storemocks "github.com/trustbloc/orb/pkg/store/mocks"
 	vcstore "github.com/trustbloc/orb/pkg/store/verifiable"
+	"github.com/trustbloc/orb/pkg/vcsigner"
 )
 
 //go:generate counterfeiter -o ../mocks/monitoring.gen.go --fake-name MonitoringService . monitoringSvc
@@ -47,59 +62,118 @@ func TestWitnessProofHandler(t *testing.T) {
 	witnessIRI, err := url.Parse(witnessURL)
 	require.NoError(t, err)
 
+	w := newTestWitness(t, witnessIRI)
+
 	t.Run("success", func(t *testing.T) {
 		vcCh := make(chan *verifiable.Credential, 100)
 
-		store, err := vcstore.New(mem.NewProvider(), testutil.GetLoader(t))
-		require.NoError(t, err)
+		store := newVCStore(t, anchorCred)
 
-		anchorVC, err := verifiable.ParseCredential([]byte(anchorCred),
-			verifiable.WithDisabledProofCheck(),
-			verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
-		)
-		require.NoError(t, err)
+		metrics := &mockWitnessMetrics{}
+
+		providers := &Providers{
+			Store:          store,
+			MonitoringSvc:  &mocks.MonitoringService{},
+			WitnessStore:   &mockWitnessStore{},
+			DocLoader:      testutil.GetLoader(t),
+			Pkf:            w.pkf(),
+			WitnessMetrics: metrics,
+		}
+
+		proofHandler := New(providers, vcCh)
 
-		err = store.Put(anchorVC)
+		err = proofHandler.HandleProof(witnessIRI, vcID, time.Now(), time.Now(), w.sign(t, anchorCred, time.Now()))
 		require.NoError(t, err)
 
+		vc := <-vcCh
+		require.Len(t, vc.Proofs, 2)
+
+		require.Equal(t, []string{witnessURL}, metrics.witnesses)
+		require.Equal(t, []string{vcID}, metrics.vcIDs)
+	})
+
+	t.Run("success - created time signed by the log", func(t *testing.T) {
+		vcCh := make(chan *verifiable.Credential, 100)
+
+		resolver := &mockLogResolver{pubKey: w.logPubKey}
+
 		providers := &Providers{
-			Store:         store,
+			Store:         newVCStore(t, anchorCred),
 			MonitoringSvc: &mocks.MonitoringService{},
 			WitnessStore:  &mockWitnessStore{},
+			DocLoader:     testutil.GetLoader(t),
+			Pkf:           w.pkf(),
+			LogResolver:   resolver,
 		}
 
-		proofHandler := New(providers, vcCh)
+		created := time.Now()
 
-		err = proofHandler.HandleProof(witnessIRI, vcID, time.Now(), time.Now(), []byte(witnessProof))
+		err = New(providers, vcCh).HandleProof(witnessIRI, vcID, time.Now(), time.Now(),
+			w.signWithTimestamp(t, anchorCred, created, created))
 		require.NoError(t, err)
+		require.Len(t, vcCh, 1)
+		require.Equal(t, 1, resolver.requests)
 	})
 
-	t.Run("success - ignore if already witnessed", func(t *testing.T) {
+	t.Run("success - witness doesn't publish its log", func(t *testing.T) {
 		vcCh := make(chan *verifiable.Credential, 100)
 
-		store, err := vcstore.New(mem.NewProvider(), testutil.GetLoader(t))
+		providers := &Providers{
+			Store:         newVCStore(t, anchorCred),
+			MonitoringSvc: &mocks.MonitoringService{},
+			WitnessStore:  &mockWitnessStore{},
+			DocLoader:     testutil.GetLoader(t),
+			Pkf:           w.pkf(),
+			LogResolver:   &mockLogResolver{err: fmt.Errorf("discover: %w", discoveryclient.ErrNotFound)},
+		}
+
+		err = New(providers, vcCh).HandleProof(witnessIRI, vcID, time.Now(), time.Now(),
+			w.signWithTimestamp(t, anchorCred, time.Now(), time.Now()))
 		require.NoError(t, err)
 
-		anchorVC, err := verifiable.ParseCredential(
-			[]byte(anchorCredTwoProofs),
-			verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
-			verifiable.WithDisabledProofCheck())
+		// The timestamp signature isn't required if the log of the witness can't be discovered.
+		err = New(providers, vcCh).HandleProof(witnessIRI, vcID, time.Now(), time.Now(),
+			w.sign(t, anchorCred, time.Now()))
 		require.NoError(t, err)
+	})
+
+	t.Run("success - metrics error", func(t *testing.T) {
+		vcCh := make(chan *verifiable.Credential, 100)
+
+		providers := &Providers{
+			Store:          newVCStore(t, anchorCred),
+			MonitoringSvc:  &mocks.MonitoringService{},
+			WitnessStore:   &mockWitnessStore{},
+			DocLoader:      testutil.GetLoader(t),
+			Pkf:            w.pkf(),
+			WitnessMetrics: &mockWitnessMetrics{err: errors.New("injected metrics error")},
+		}
+
+		proofHandler := New(providers, vcCh)
 
-		err = store.Put(anchorVC)
+		err = proofHandler.HandleProof(witnessIRI, vcID, time.Now(), time.Now(), w.sign(t, anchorCred, time.Now()))
 		require.NoError(t, err)
+	})
+
+	t.Run("success - ignore if already witnessed", func(t *testing.T) {
+		vcCh := make(chan *verifiable.Credential, 100)
+
+		store := newVCStore(t, anchorCredTwoProofs)
 
 		providers := &Providers{
 			Store:         store,
 			MonitoringSvc: &mocks.MonitoringService{},
 			WitnessStore:  &mockWitnessStore{},
+			DocLoader:     testutil.GetLoader(t),
+			Pkf:           w.pkf(),
 		}
 
 		proofHandler := New(providers, vcCh)
 
 		err = proofHandler.HandleProof(witnessIRI, "http://orb.domain1.com/vc/9ac66b40-bcc6-4ca8-a9c7-d1fd3eaebafd",
-			time.Now(), time.Now(), []byte(witnessProof))
+			time.Now(), time.Now(), w.sign(t, anchorCredTwoProofs, time.Now()))
 		require.NoError(t, err)
+		require.Empty(t, vcCh)
 	})
 
 	t.Run("error - store error", func(t *testing.T) {
@@ -130,27 +204,19 @@ func TestWitnessProofHandler(t *testing.T) {
 	t.Run("error - witness store error", func(t *testing.T) {
 		vcCh := make(chan *verifiable.Credential, 100)
 
-		store, err := vcstore.New(mem.NewProvider(), testutil.GetLoader(t))
-		require.NoError(t, err)
-
-		anchorVC, err := verifiable.ParseCredential([]byte(anchorCred),
-			verifiable.WithDisabledProofCheck(),
-			verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
-		)
-		require.NoError(t, err)
-
-		err = store.Put(anchorVC)
-		require.NoError(t, err)
+		store := newVCStore(t, anchorCred)
 
 		providers := &Providers{
 			Store:         store,
 			MonitoringSvc: &mocks.MonitoringService{},
 			WitnessStore:  &mockWitnessStore{Err: fmt.Errorf("witness store error")},
+			DocLoader:     testutil.GetLoader(t),
+			Pkf:           w.pkf(),
 		}
 
 		proofHandler := New(providers, vcCh)
 
-		err = proofHandler.HandleProof(witnessIRI, vcID, time.Now(), time.Now(), []byte(witnessProof))
+		err = proofHandler.HandleProof(witnessIRI, vcID, time.Now(), time.Now(), w.sign(t, anchorCred, time.Now()))
 		require.Error(t, err)
 		require.Contains(t, err.Error(),
 			"failed to add witness[http://example.com/orb/services] proof for credential[http://peer1.com/vc/62c153d1-a6be-400e-a6a6-5b700b596d9d]: witness store error") //nolint:lll
@@ -162,32 +228,26 @@ func TestWitnessProofHandler(t *testing.T) {
 		vcStore, err := vcstore.New(mem.NewProvider(), testutil.GetLoader(t))
 		require.NoError(t, err)
 
+		monitoringSvc := &mocks.MonitoringService{}
+
 		providers := &Providers{
 			Store:         vcStore,
-			MonitoringSvc: &mocks.MonitoringService{},
+			MonitoringSvc: monitoringSvc,
 		}
 
 		proofHandler := New(providers, vcCh)
 
 		err = proofHandler.HandleProof(witnessIRI, vcID, time.Now(), time.Now(), []byte(""))
 		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrInvalidProof))
 		require.Contains(t, err.Error(), "failed to unmarshal witness proof for anchor credential")
+		require.Equal(t, 1, monitoringSvc.RecordInvalidProofCallCount())
 	})
 
 	t.Run("error - monitoring error", func(t *testing.T) {
 		vcCh := make(chan *verifiable.Credential, 100)
 
-		store, err := vcstore.New(mem.NewProvider(), testutil.GetLoader(t))
-		require.NoError(t, err)
-
-		anchorVC, err := verifiable.ParseCredential([]byte(anchorCred),
-			verifiable.WithDisabledProofCheck(),
-			verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
-		)
-		require.NoError(t, err)
-
-		err = store.Put(anchorVC)
-		require.NoError(t, err)
+		store := newVCStore(t, anchorCred)
 
 		monitoringSvc := &mocks.MonitoringService{}
 		monitoringSvc.WatchReturns(fmt.Errorf("monitoring error"))
@@ -195,15 +255,397 @@ func TestWitnessProofHandler(t *testing.T) {
 		providers := &Providers{
 			Store:         store,
 			MonitoringSvc: monitoringSvc,
+			DocLoader:     testutil.GetLoader(t),
+			Pkf:           w.pkf(),
 		}
 
 		proofHandler := New(providers, vcCh)
 
 		err = proofHandler.HandleProof(witnessIRI, vcID,
-			time.Now(), time.Now(), []byte(witnessProof))
+			time.Now(), time.Now(), w.sign(t, anchorCred, time.Now()))
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "monitoring error")
+		require.False(t, errors.Is(err, ErrInvalidProof))
+		require.Equal(t, 0, monitoringSvc.RecordInvalidProofCallCount())
+	})
+}
+
+func TestWitnessProofHandler_InvalidProof(t *testing.T) {
+	witnessIRI, err := url.Parse(witnessURL)
+	require.NoError(t, err)
+
+	w := newTestWitness(t, witnessIRI)
+
+	handleProof := func(t *testing.T, monitoringSvc *mocks.MonitoringService, witness *url.URL, proof []byte,
+		opts ...func(p *Providers)) error {
+		t.Helper()
+
+		vcCh := make(chan *verifiable.Credential, 100)
+
+		providers := &Providers{
+			Store:         newVCStore(t, anchorCred),
+			MonitoringSvc: monitoringSvc,
+			WitnessStore:  &mockWitnessStore{},
+			DocLoader:     testutil.GetLoader(t),
+			Pkf:           w.pkf(),
+		}
+
+		for _, opt := range opts {
+			opt(providers)
+		}
+
+		err := New(providers, vcCh).HandleProof(witness, vcID, time.Now(), time.Now(), proof)
+
+		require.Empty(t, vcCh)
+
+		return err
+	}
+
+	checkRejected := func(t *testing.T, monitoringSvc *mocks.MonitoringService, err error, msg string) {
+		t.Helper()
+
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrInvalidProof))
+		require.Contains(t, err.Error(), msg)
+		require.Equal(t, 1, monitoringSvc.RecordInvalidProofCallCount())
+
+		witness, credID, _, reason := monitoringSvc.RecordInvalidProofArgsForCall(0)
+		require.Equal(t, witnessIRI.String(), witness.String())
+		require.Equal(t, vcID, credID)
+		require.Contains(t, reason.Error(), msg)
+	}
+
+	t.Run("Signed content is not the offered credential", func(t *testing.T) {
+		monitoringSvc := &mocks.MonitoringService{}
+
+		otherCred := strings.Replace(anchorCred, `"issuer": "http://peer1.com"`, `"issuer": "http://peer2.com"`, 1)
+
+		err := handleProof(t, monitoringSvc, witnessIRI, w.sign(t, otherCred, time.Now()))
+		checkRejected(t, monitoringSvc, err, "verify signature of witness")
+		require.Equal(t, 0, monitoringSvc.WatchCallCount())
+	})
+
+	t.Run("Verification method of another witness", func(t *testing.T) {
+		monitoringSvc := &mocks.MonitoringService{}
+
+		other := newTestWitness(t, testutil.MustParseURL("https://other.com/services/orb"))
+
+		err := handleProof(t, monitoringSvc, witnessIRI, other.sign(t, anchorCred, time.Now()))
+		checkRejected(t, monitoringSvc, err, "does not belong to witness")
+	})
+
+	t.Run("Created before credential was issued", func(t *testing.T) {
+		monitoringSvc := &mocks.MonitoringService{}
+
+		err := handleProof(t, monitoringSvc, witnessIRI, w.sign(t, anchorCred, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+		checkRejected(t, monitoringSvc, err, "before the anchor credential was issued")
+	})
+
+	t.Run("Created in the future", func(t *testing.T) {
+		monitoringSvc := &mocks.MonitoringService{}
+
+		err := handleProof(t, monitoringSvc, witnessIRI, w.sign(t, anchorCred, time.Now().Add(time.Hour)))
+		checkRejected(t, monitoringSvc, err, "is in the future")
+	})
+
+	t.Run("Missing fields", func(t *testing.T) {
+		for _, tc := range []struct {
+			field string
+			msg   string
+		}{
+			{field: "domain", msg: "domain is missing"},
+			{field: "verificationMethod", msg: "verification method is missing"},
+			{field: "created", msg: "created time is missing"},
+		} {
+			monitoringSvc := &mocks.MonitoringService{}
+
+			p := &vct.Proof{}
+			require.NoError(t, json.Unmarshal(w.sign(t, anchorCred, time.Now()), p))
+
+			delete(p.Proof, tc.field)
+
+			proofBytes, err := json.Marshal(p)
+			require.NoError(t, err)
+
+			err = handleProof(t, monitoringSvc, witnessIRI, proofBytes)
+			checkRejected(t, monitoringSvc, err, tc.msg)
+		}
+
+		monitoringSvc := &mocks.MonitoringService{}
+
+		err = handleProof(t, monitoringSvc, witnessIRI, []byte(`{}`))
+		checkRejected(t, monitoringSvc, err, "proof is missing")
+	})
+
+	t.Run("Domain is not the log of the witness", func(t *testing.T) {
+		monitoringSvc := &mocks.MonitoringService{}
+		monitoringSvc.WatchReturns(fmt.Errorf("%w: domain mismatch", monitoring.ErrInvalidProof))
+
+		err := handleProof(t, monitoringSvc, witnessIRI, w.sign(t, anchorCred, time.Now()))
+		checkRejected(t, monitoringSvc, err, "domain mismatch")
+	})
+
+	t.Run("Created time not signed by the log", func(t *testing.T) {
+		monitoringSvc := &mocks.MonitoringService{}
+
+		created := time.Now()
+
+		err := handleProof(t, monitoringSvc, witnessIRI,
+			w.signWithTimestamp(t, anchorCred, created, created.Add(-time.Second)),
+			withLogResolver(&mockLogResolver{pubKey: w.logPubKey}))
+		checkRejected(t, monitoringSvc, err, "was not signed by log")
+		require.Equal(t, 0, monitoringSvc.WatchCallCount())
+	})
+
+	t.Run("Timestamp signature missing", func(t *testing.T) {
+		monitoringSvc := &mocks.MonitoringService{}
+
+		p := &vct.Proof{}
+		require.NoError(t, json.Unmarshal(w.signWithTimestamp(t, anchorCred, time.Now(), time.Now()), p))
+
+		p.TimestampSignature = nil
+
+		proofBytes, err := json.Marshal(p)
+		require.NoError(t, err)
+
+		err = handleProof(t, monitoringSvc, witnessIRI, proofBytes,
+			withLogResolver(&mockLogResolver{pubKey: w.logPubKey}))
+		checkRejected(t, monitoringSvc, err, "timestamp signature of log [https://vct.example.com] is missing")
+		require.Equal(t, 0, monitoringSvc.WatchCallCount())
+	})
+
+	t.Run("Log public key mismatch", func(t *testing.T) {
+		monitoringSvc := &mocks.MonitoringService{}
+
+		err := handleProof(t, monitoringSvc, witnessIRI, w.signWithTimestamp(t, anchorCred, time.Now(), time.Now()),
+			withLogResolver(&mockLogResolver{err: fmt.Errorf("resolve: %w", discoveryclient.ErrPublicKeyMismatch)}))
+		checkRejected(t, monitoringSvc, err, "public key mismatch")
+	})
+}
+
+func TestWitnessProofHandler_VerificationError(t *testing.T) {
+	witnessIRI, err := url.Parse(witnessURL)
+	require.NoError(t, err)
+
+	w := newTestWitness(t, witnessIRI)
+
+	// checkNotRejected ensures that the proof was neither accepted nor recorded as invalid.
+	checkNotRejected := func(t *testing.T, providers *Providers, proof []byte, msg string) {
+		t.Helper()
+
+		monitoringSvc := &mocks.MonitoringService{}
+
+		providers.Store = newVCStore(t, anchorCred)
+		providers.MonitoringSvc = monitoringSvc
+		providers.WitnessStore = &mockWitnessStore{}
+		providers.DocLoader = testutil.GetLoader(t)
+
+		vcCh := make(chan *verifiable.Credential, 100)
+
+		err := New(providers, vcCh).HandleProof(witnessIRI, vcID, time.Now(), time.Now(), proof)
+		require.Error(t, err)
+		require.False(t, errors.Is(err, ErrInvalidProof))
+		require.Contains(t, err.Error(), msg)
+		require.Equal(t, 0, monitoringSvc.RecordInvalidProofCallCount())
+		require.Equal(t, 0, monitoringSvc.WatchCallCount())
+		require.Empty(t, vcCh)
+	}
+
+	t.Run("Public key resolution error", func(t *testing.T) {
+		checkNotRejected(t,
+			&Providers{
+				Pkf: func(issuerID, keyID string) (*verifier.PublicKey, error) {
+					return nil, errors.New("injected resolution error")
+				},
+			},
+			w.sign(t, anchorCred, time.Now()), "injected resolution error")
+	})
+
+	t.Run("Log resolution error", func(t *testing.T) {
+		checkNotRejected(t,
+			&Providers{
+				Pkf:         w.pkf(),
+				LogResolver: &mockLogResolver{err: errors.New("injected log resolution error")},
+			},
+			w.signWithTimestamp(t, anchorCred, time.Now(), time.Now()), "injected log resolution error")
+	})
+}
+
+type testWitness struct {
+	signer    *vcsigner.Signer
+	pubKey    []byte
+	cr        *tinkcrypto.Crypto
+	logKH     interface{}
+	logPubKey []byte
+}
+
+func newTestWitness(t *testing.T, witnessIRI *url.URL) *testWitness {
+	t.Helper()
+
+	km, err := localkms.New("local-lock://custom/primary/key/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	keyID, _, err := km.Create(kms.ED25519Type)
+	require.NoError(t, err)
+
+	pubKey, err := km.ExportPubKeyBytes(keyID)
+	require.NoError(t, err)
+
+	s, err := vcsigner.New(
+		&vcsigner.Providers{DocLoader: testutil.GetLoader(t), KeyManager: km, Crypto: cr},
+		vcsigner.SigningParams{
+			VerificationMethod: "did:web:" + witnessIRI.Host + "#" + keyID,
+			SignatureSuite:     vcsigner.Ed25519Signature2018,
+			Domain:             "https://vct.example.com",
+		},
+	)
+	require.NoError(t, err)
+
+	logKeyID, logKH, err := km.Create(kms.ED25519Type)
+	require.NoError(t, err)
+
+	logPubKey, err := km.ExportPubKeyBytes(logKeyID)
+	require.NoError(t, err)
+
+	return &testWitness{signer: s, pubKey: pubKey, cr: cr, logKH: logKH, logPubKey: logPubKey}
+}
+
+// sign returns the witness proof (as returned in a 'Like' activity) for the given credential.
+func (w *testWitness) sign(t *testing.T, vcBytes string, created time.Time) []byte {
+	t.Helper()
+
+	vc, err := verifiable.ParseCredential([]byte(vcBytes),
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
+	)
+	require.NoError(t, err)
+
+	vc, err = w.signer.Sign(vc, vcsigner.WithCreated(created))
+	require.NoError(t, err)
+
+	proofBytes, err := json.Marshal(&vct.Proof{
+		Context: []string{"https://w3id.org/security/v1", "https://w3id.org/jws/v1"},
+		Proof:   vc.Proofs[len(vc.Proofs)-1],
+	})
+	require.NoError(t, err)
+
+	return proofBytes
+}
+
+// signWithTimestamp returns the witness proof for the given credential along with the log's signature over the
+// given timestamp.
+func (w *testWitness) signWithTimestamp(t *testing.T, vcBytes string, created, timestamp time.Time) []byte {
+	t.Helper()
+
+	p := &vct.Proof{}
+	require.NoError(t, json.Unmarshal(w.sign(t, vcBytes, created), p))
+
+	vc, err := verifiable.ParseCredential([]byte(vcBytes),
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
+	)
+	require.NoError(t, err)
+
+	leaf, err := command.CreateLeaf(uint64(timestamp.UnixNano()/int64(time.Millisecond)), vc)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(command.CreateVCTimestampSignature(leaf))
+	require.NoError(t, err)
+
+	signature, err := w.cr.Sign(data, w.logKH)
+	require.NoError(t, err)
+
+	p.TimestampSignature, err = json.Marshal(command.DigitallySigned{
+		Algorithm: command.SignatureAndHashAlgorithm{Type: kms.ED25519Type},
+		Signature: signature,
 	})
+	require.NoError(t, err)
+
+	proofBytes, err := json.Marshal(p)
+	require.NoError(t, err)
+
+	return proofBytes
+}
+
+func (w *testWitness) pkf() verifiable.PublicKeyFetcher {
+	return func(issuerID, keyID string) (*verifier.PublicKey, error) {
+		return &verifier.PublicKey{Type: kms.ED25519, Value: w.pubKey}, nil
+	}
+}
+
+func newVCStore(t *testing.T, vcBytes string) *vcstore.Store {
+	t.Helper()
+
+	store, err := vcstore.New(mem.NewProvider(), testutil.GetLoader(t))
+	require.NoError(t, err)
+
+	vc, err := verifiable.ParseCredential([]byte(vcBytes),
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(vc))
+
+	return store
+}
+
+type mockWitnessMetrics struct {
+	err       error
+	witnesses []string
+	vcIDs     []string
+}
+
+func (m *mockWitnessMetrics) LikeReceived(witness, vcID string) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.witnesses = append(m.witnesses, witness)
+	m.vcIDs = append(m.vcIDs, vcID)
+
+	return nil
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (p *kmsProvider) StorageProvider() storage.Provider {
+	return p.storageProvider
+}
+
+func (p *kmsProvider) SecretLock() secretlock.Service {
+	return p.secretLock
+}
+
+func withLogResolver(resolver logResolver) func(p *Providers) {
+	return func(p *Providers) {
+		p.LogResolver = resolver
+	}
+}
+
+type mockLogResolver struct {
+	pubKey   []byte
+	err      error
+	requests int
+}
+
+func (m *mockLogResolver) GetLogEndpoint(*url.URL) (*discoveryclient.LogEndpoint, error) {
+	m.requests++
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &discoveryclient.LogEndpoint{URL: "https://vct.example.com", PublicKey: m.pubKey}, nil
 }
 
 type mockWitnessStore struct {
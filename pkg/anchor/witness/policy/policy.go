@@ -0,0 +1,169 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
+	witnessstore "github.com/trustbloc/orb/pkg/store/witness"
+)
+
+var logger = log.New("witness-policy")
+
+const defaultExplorationInterval = 5
+
+type metricsStore interface {
+	Get(witness string) (*witnessstore.Metrics, error)
+}
+
+type reliabilityProvider interface {
+	WitnessReliability(witness *url.URL) (*monitoring.Reliability, error)
+}
+
+// Healthiest is a witness selection policy that selects the K healthiest witnesses. The health of a witness is
+// its reliability (the ratio of promises that it kept, as tracked by the monitoring service) multiplied by its
+// response rate (the ratio of offers that resulted in a proof). Witnesses with the same score are ordered by
+// their average latency.
+//
+// The metrics of a witness only change when it's offered an anchor credential, so a witness that drops out of
+// the K healthiest would never be able to recover. Therefore, on every Nth selection (the exploration interval),
+// the last of the K slots is given to one of the witnesses that would otherwise be excluded. The excluded
+// witnesses take turns so that each of them is eventually offered an anchor credential again.
+type Healthiest struct {
+	k                   int
+	metrics             metricsStore
+	reliability         reliabilityProvider
+	explorationInterval int
+
+	mutex        sync.Mutex
+	selections   int
+	explorations int
+}
+
+// Opt sets a Healthiest policy option.
+type Opt func(p *Healthiest)
+
+// WithExplorationInterval sets the interval (in number of selections) at which an excluded witness is selected
+// in place of the Kth healthiest witness. An interval of 1 means that an excluded witness is always selected.
+// Exploration is disabled if the interval is not positive. Defaults to 5.
+func WithExplorationInterval(interval int) Opt {
+	return func(p *Healthiest) {
+		p.explorationInterval = interval
+	}
+}
+
+// NewHealthiest returns a policy that selects (at most) k of the healthiest witnesses.
+func NewHealthiest(k int, metrics metricsStore, reliability reliabilityProvider, opts ...Opt) *Healthiest {
+	p := &Healthiest{
+		k:                   k,
+		metrics:             metrics,
+		reliability:         reliability,
+		explorationInterval: defaultExplorationInterval,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+type candidate struct {
+	witness *url.URL
+	score   float64
+	latency time.Duration
+}
+
+// Select returns the healthiest of the given witnesses. All of the witnesses are returned if there
+// are no more than K witnesses (or K is not positive).
+func (p *Healthiest) Select(witnesses []*url.URL) ([]*url.URL, error) {
+	if p.k <= 0 || len(witnesses) <= p.k {
+		return witnesses, nil
+	}
+
+	candidates := make([]*candidate, len(witnesses))
+
+	for i, w := range witnesses {
+		candidates[i] = p.newCandidate(w)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+
+		return candidates[i].latency < candidates[j].latency
+	})
+
+	selected := make([]*url.URL, p.k)
+
+	for i := range selected {
+		selected[i] = candidates[i].witness
+	}
+
+	if excluded, ok := p.explore(candidates[p.k:]); ok {
+		logger.Debugf("selecting excluded witness[%s] in place of witness[%s]", excluded, selected[p.k-1])
+
+		selected[p.k-1] = excluded
+	}
+
+	logger.Debugf("selected %d of %d witnesses: %s", p.k, len(witnesses), selected)
+
+	return selected, nil
+}
+
+// explore returns the excluded witness that should be selected in place of the Kth healthiest witness, or false
+// if this selection isn't an exploration.
+func (p *Healthiest) explore(excluded []*candidate) (*url.URL, bool) {
+	if p.explorationInterval <= 0 {
+		return nil, false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.selections++
+
+	if p.selections%p.explorationInterval != 0 {
+		return nil, false
+	}
+
+	witness := excluded[p.explorations%len(excluded)].witness
+
+	p.explorations++
+
+	return witness, true
+}
+
+func (p *Healthiest) newCandidate(witness *url.URL) *candidate {
+	m, err := p.metrics.Get(witness.String())
+	if err != nil {
+		// Treat the witness as one without any history rather than failing the offer.
+		logger.Warnf("failed to get metrics for witness[%s]: %s", witness, err)
+
+		m = &witnessstore.Metrics{Witness: witness.String()}
+	}
+
+	r, err := p.reliability.WitnessReliability(witness)
+	if err != nil {
+		logger.Warnf("failed to get reliability of witness[%s]: %s", witness, err)
+
+		r = &monitoring.Reliability{}
+	}
+
+	return &candidate{
+		witness: witness,
+		score:   r.Score() * m.ResponseRate(),
+		latency: m.AverageLatency(),
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/monitoring"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+	witnessstore "github.com/trustbloc/orb/pkg/store/witness"
+)
+
+func TestHealthiest_Select(t *testing.T) {
+	w1 := testutil.MustParseURL("https://orb.domain1.com/services/orb")
+	w2 := testutil.MustParseURL("https://orb.domain2.com/services/orb")
+	w3 := testutil.MustParseURL("https://orb.domain3.com/services/orb")
+	w4 := testutil.MustParseURL("https://orb.domain4.com/services/orb")
+
+	metrics := &mockMetricsStore{
+		metrics: map[string]*witnessstore.Metrics{
+			// response rate 0.5
+			w1.String(): {OffersSent: 3, LikesReceived: 1, LatencyCount: 1, TotalLatency: time.Second},
+			// response rate 1, slow
+			w2.String(): {OffersSent: 2, LikesReceived: 2, LatencyCount: 2, TotalLatency: 10 * time.Second},
+			// response rate 1, fast
+			w3.String(): {OffersSent: 2, LikesReceived: 2, LatencyCount: 2, TotalLatency: 2 * time.Second},
+			// response rate 1
+			w4.String(): {OffersSent: 3, LikesReceived: 3, LatencyCount: 3, TotalLatency: 30 * time.Second},
+		},
+	}
+
+	reliability := &mockReliabilityProvider{
+		reliability: map[string]*monitoring.Reliability{
+			w2.String(): {Confirmed: 2},
+			w3.String(): {Confirmed: 2},
+			// score 0.25 (reliability 0.25 * response rate 1)
+			w4.String(): {Confirmed: 1, Broken: 3},
+		},
+	}
+
+	witnesses := []*url.URL{w1, w2, w3, w4}
+
+	t.Run("Select K healthiest", func(t *testing.T) {
+		selected, err := NewHealthiest(2, metrics, reliability, WithExplorationInterval(0)).Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w3, w2}, selected)
+
+		selected, err = NewHealthiest(3, metrics, reliability, WithExplorationInterval(0)).Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w3, w2, w1}, selected)
+	})
+
+	t.Run("Fewer witnesses than K", func(t *testing.T) {
+		selected, err := NewHealthiest(5, metrics, reliability).Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, witnesses, selected)
+
+		selected, err = NewHealthiest(0, metrics, reliability).Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, witnesses, selected)
+	})
+
+	t.Run("Witness without history", func(t *testing.T) {
+		w5 := testutil.MustParseURL("https://orb.domain5.com/services/orb")
+
+		selected, err := NewHealthiest(1, metrics, reliability, WithExplorationInterval(0)).Select([]*url.URL{w1, w5})
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w5}, selected)
+	})
+
+	t.Run("Excluded witness is selected again", func(t *testing.T) {
+		p := NewHealthiest(2, metrics, reliability, WithExplorationInterval(2))
+
+		selected, err := p.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w3, w2}, selected)
+
+		// On every second selection the excluded witnesses (w1 and w4) take turns in place of the second healthiest.
+		selected, err = p.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w3, w1}, selected)
+
+		selected, err = p.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w3, w2}, selected)
+
+		selected, err = p.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w3, w4}, selected)
+	})
+
+	t.Run("Default exploration interval", func(t *testing.T) {
+		p := NewHealthiest(1, metrics, reliability)
+
+		for i := 1; i < defaultExplorationInterval; i++ {
+			selected, err := p.Select(witnesses)
+			require.NoError(t, err)
+			require.Equal(t, []*url.URL{w3}, selected)
+		}
+
+		selected, err := p.Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w2}, selected)
+	})
+
+	t.Run("Metrics error", func(t *testing.T) {
+		errMetrics := &mockMetricsStore{metrics: metrics.metrics, err: errors.New("injected metrics error")}
+
+		selected, err := NewHealthiest(2, errMetrics, reliability, WithExplorationInterval(0)).Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w1, w2}, selected)
+	})
+
+	t.Run("Reliability error", func(t *testing.T) {
+		errReliability := &mockReliabilityProvider{err: errors.New("injected reliability error")}
+
+		selected, err := NewHealthiest(2, metrics, errReliability, WithExplorationInterval(0)).Select(witnesses)
+		require.NoError(t, err)
+		require.Equal(t, []*url.URL{w3, w2}, selected)
+	})
+}
+
+type mockReliabilityProvider struct {
+	reliability map[string]*monitoring.Reliability
+	err         error
+}
+
+func (m *mockReliabilityProvider) WitnessReliability(witness *url.URL) (*monitoring.Reliability, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	r, ok := m.reliability[witness.String()]
+	if !ok {
+		return &monitoring.Reliability{}, nil
+	}
+
+	return r, nil
+}
+
+type mockMetricsStore struct {
+	metrics map[string]*witnessstore.Metrics
+	err     error
+}
+
+func (m *mockMetricsStore) Get(witness string) (*witnessstore.Metrics, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	metrics, ok := m.metrics[witness]
+	if !ok {
+		return &witnessstore.Metrics{Witness: witness}, nil
+	}
+
+	return metrics, nil
+}
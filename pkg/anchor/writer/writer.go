@@ -57,6 +57,19 @@ type Providers struct {
 	MonitoringSvc   monitoringSvc
 	WitnessStore    witnessStore
 	ActivityStore   activityStore
+	// WitnessPolicy (optional) selects the system witnesses that an anchor credential is offered to.
+	// If not set then the offer is sent to the entire 'witnesses' collection.
+	WitnessPolicy witnessPolicy
+	// WitnessMetrics (optional) records the offers sent to witnesses.
+	WitnessMetrics witnessMetrics
+}
+
+type witnessPolicy interface {
+	Select(witnesses []*url.URL) ([]*url.URL, error)
+}
+
+type witnessMetrics interface {
+	OffersSent(vcID string, witnesses []string, sentAt time.Time) error
 }
 
 type activityStore interface {
@@ -76,7 +89,7 @@ type signer interface {
 }
 
 type monitoringSvc interface {
-	Watch(anchorCredID string, endTime time.Time, proof []byte) error
+	Watch(witness *url.URL, anchorCredID string, endTime time.Time, proof []byte) error
 }
 
 type outbox interface {
@@ -284,7 +297,7 @@ func (c *Writer) signCredentialWithLocalWitnessLog(vc *verifiable.Credential) (*
 	startTime := time.Now()
 	endTime := startTime.Add(c.maxWitnessDelay)
 
-	err = c.MonitoringSvc.Watch(vc.ID, endTime, proofBytes)
+	err = c.MonitoringSvc.Watch(c.apServiceIRI, vc.ID, endTime, proofBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to setup monitoring for local witness for anchor credential[%s]: %w", vc.ID, err)
 	}
@@ -392,11 +405,14 @@ func (c *Writer) postCreateActivity(vc *verifiable.Credential, cid string) error
 		return fmt.Errorf("failed to create new object with document: %w", err)
 	}
 
+	published := time.Now()
+
 	create := vocab.NewCreateActivity(
 		vocab.NewObjectProperty(vocab.WithObject(obj)),
 		vocab.WithTarget(targetProperty),
 		vocab.WithContext(vocab.ContextOrb),
 		vocab.WithTo(systemFollowers),
+		vocab.WithPublishedTime(&published),
 	)
 
 	postID, err := c.Outbox.Post(create)
@@ -424,11 +440,29 @@ func (c *Writer) postOfferActivity(vc *verifiable.Credential, witnesses []string
 		return fmt.Errorf("failed to parse system witness path: %w", err)
 	}
 
+	systemWitnesses, err := c.getSystemWitnesses()
+	if err != nil {
+		return err
+	}
+
+	// batch witnesses are offered the anchor credential regardless of whether or not they're system witnesses
+	systemWitnesses = excludeURIs(systemWitnesses, batchWitnessesIRI)
+
 	var witnessesIRI []*url.URL
 
-	// add batch witnesses and system witnesses (activity pub collection)
+	// add batch witnesses and system witnesses (selected by policy or the activity pub collection)
 	witnessesIRI = append(witnessesIRI, batchWitnessesIRI...)
-	witnessesIRI = append(witnessesIRI, systemWitnessesIRI)
+
+	if c.WitnessPolicy != nil {
+		systemWitnesses, err = c.WitnessPolicy.Select(systemWitnesses)
+		if err != nil {
+			return fmt.Errorf("failed to select system witnesses: %w", err)
+		}
+
+		witnessesIRI = append(witnessesIRI, systemWitnesses...)
+	} else {
+		witnessesIRI = append(witnessesIRI, systemWitnessesIRI)
+	}
 
 	bytes, err := vc.MarshalJSON()
 	if err != nil {
@@ -455,11 +489,13 @@ func (c *Writer) postOfferActivity(vc *verifiable.Credential, witnesses []string
 		return fmt.Errorf("failed to post offer for vcID[%s]: %w", vc.ID, err)
 	}
 
-	err = c.storeWitnesses(vc.ID, batchWitnessesIRI)
+	err = c.storeWitnesses(vc.ID, batchWitnessesIRI, systemWitnesses)
 	if err != nil {
 		return err
 	}
 
+	c.recordOffersSent(vc.ID, startTime, batchWitnessesIRI, systemWitnesses)
+
 	logger.Debugf("created pre-announce activity for vc[%s], post id[%s]", vc.ID, postID)
 
 	return nil
@@ -545,7 +581,7 @@ func (c *Writer) Read(_ int) (bool, *txnapi.SidetreeTxn) {
 	return false, nil
 }
 
-func (c *Writer) storeWitnesses(vcID string, batchWitnesses []*url.URL) error {
+func (c *Writer) storeWitnesses(vcID string, batchWitnesses, systemWitnesses []*url.URL) error {
 	var witnesses []*proof.WitnessProof
 
 	for _, w := range batchWitnesses {
@@ -556,22 +592,15 @@ func (c *Writer) storeWitnesses(vcID string, batchWitnesses []*url.URL) error {
 			})
 	}
 
-	systemWitnesses, err := c.getSystemWitnesses()
-	if err != nil {
-		return err
-	}
-
 	for _, systemWitnessURI := range systemWitnesses {
-		if !containsURI(batchWitnesses, systemWitnessURI) {
-			witnesses = append(witnesses,
-				&proof.WitnessProof{
-					Type:    proof.TypeSystem,
-					Witness: systemWitnessURI.String(),
-				})
-		}
+		witnesses = append(witnesses,
+			&proof.WitnessProof{
+				Type:    proof.TypeSystem,
+				Witness: systemWitnessURI.String(),
+			})
 	}
 
-	err = c.WitnessStore.Put(vcID, witnesses)
+	err := c.WitnessStore.Put(vcID, witnesses)
 	if err != nil {
 		return fmt.Errorf("failed to store witnesses for vcID[%s]: %w", vcID, err)
 	}
@@ -579,6 +608,40 @@ func (c *Writer) storeWitnesses(vcID string, batchWitnesses []*url.URL) error {
 	return nil
 }
 
+func (c *Writer) recordOffersSent(vcID string, sentAt time.Time, batchWitnesses, systemWitnesses []*url.URL) {
+	if c.WitnessMetrics == nil {
+		return
+	}
+
+	var witnesses []string
+
+	for _, w := range batchWitnesses {
+		witnesses = append(witnesses, w.String())
+	}
+
+	for _, w := range systemWitnesses {
+		witnesses = append(witnesses, w.String())
+	}
+
+	// metrics are informational so the offer doesn't fail if they can't be recorded
+	err := c.WitnessMetrics.OffersSent(vcID, witnesses, sentAt)
+	if err != nil {
+		logger.Warnf("failed to record offers sent to witnesses %s: %s", witnesses, err)
+	}
+}
+
+func excludeURIs(values, exclude []*url.URL) []*url.URL {
+	var result []*url.URL
+
+	for _, v := range values {
+		if !containsURI(exclude, v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
 func containsURI(values []*url.URL, value *url.URL) bool {
 	for _, v := range values {
 		if v.String() == value.String() {
@@ -614,6 +614,104 @@ func TestWriter_postOfferActivity(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("success - witness policy", func(t *testing.T) {
+		anchorCh := make(chan []anchorinfo.AnchorInfo, 100)
+		vcCh := make(chan *verifiable.Credential, 100)
+
+		ob := &mockOutbox{}
+		ws := &mockWitnessStore{}
+		metrics := &mockWitnessMetrics{}
+
+		providers := &Providers{
+			Outbox:         ob,
+			WitnessStore:   ws,
+			ActivityStore:  &mockActivityStore{},
+			WitnessPolicy:  &mockWitnessPolicy{Count: 1},
+			WitnessMetrics: metrics,
+		}
+
+		anchorVC, err := verifiable.ParseCredential([]byte(anchorCred),
+			verifiable.WithDisabledProofCheck(),
+			verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
+		)
+		require.NoError(t, err)
+
+		c := New(namespace, apServiceIRI, casIRI, providers, anchorCh, vcCh, testMaxWitnessDelay, signWithLocalWitness)
+
+		err = c.postOfferActivity(anchorVC, []string{"https://abc.com/services/orb"})
+		require.NoError(t, err)
+
+		// The offer is addressed to the selected system witness rather than the 'witnesses' collection.
+		require.Len(t, ob.Activities, 1)
+		require.Len(t, ob.Activities[0].To(), 2)
+		require.Equal(t, "https://abc.com/services/orb", ob.Activities[0].To()[0].String())
+		require.Equal(t, "origin-2.com", ob.Activities[0].To()[1].String())
+
+		require.Len(t, ws.Witnesses, 2)
+		require.Equal(t, proof.TypeBatch, ws.Witnesses[0].Type)
+		require.Equal(t, proof.TypeSystem, ws.Witnesses[1].Type)
+
+		require.Equal(t, []string{anchorVC.ID}, metrics.VCIDs)
+		require.Equal(t, []string{"https://abc.com/services/orb", "origin-2.com"}, metrics.Witnesses)
+	})
+
+	t.Run("success - system witness is also a batch witness", func(t *testing.T) {
+		anchorCh := make(chan []anchorinfo.AnchorInfo, 100)
+		vcCh := make(chan *verifiable.Credential, 100)
+
+		ob := &mockOutbox{}
+		ws := &mockWitnessStore{}
+
+		providers := &Providers{
+			Outbox:         ob,
+			WitnessStore:   ws,
+			ActivityStore:  &mockActivityStore{},
+			WitnessPolicy:  &mockWitnessPolicy{Count: 1},
+			WitnessMetrics: &mockWitnessMetrics{Err: errors.New("injected metrics error")},
+		}
+
+		anchorVC, err := verifiable.ParseCredential([]byte(anchorCred),
+			verifiable.WithDisabledProofCheck(),
+			verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
+		)
+		require.NoError(t, err)
+
+		c := New(namespace, apServiceIRI, casIRI, providers, anchorCh, vcCh, testMaxWitnessDelay, signWithLocalWitness)
+
+		err = c.postOfferActivity(anchorVC, []string{"origin-2.com"})
+		require.NoError(t, err)
+
+		require.Len(t, ob.Activities, 1)
+		require.Len(t, ob.Activities[0].To(), 1)
+
+		require.Len(t, ws.Witnesses, 1)
+		require.Equal(t, proof.TypeBatch, ws.Witnesses[0].Type)
+	})
+
+	t.Run("error - witness policy error", func(t *testing.T) {
+		anchorCh := make(chan []anchorinfo.AnchorInfo, 100)
+		vcCh := make(chan *verifiable.Credential, 100)
+
+		providers := &Providers{
+			Outbox:        &mockOutbox{},
+			WitnessStore:  &mockWitnessStore{},
+			ActivityStore: &mockActivityStore{},
+			WitnessPolicy: &mockWitnessPolicy{Err: errors.New("injected policy error")},
+		}
+
+		anchorVC, err := verifiable.ParseCredential([]byte(anchorCred),
+			verifiable.WithDisabledProofCheck(),
+			verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
+		)
+		require.NoError(t, err)
+
+		c := New(namespace, apServiceIRI, casIRI, providers, anchorCh, vcCh, testMaxWitnessDelay, signWithLocalWitness)
+
+		err = c.postOfferActivity(anchorVC, []string{"https://abc.com/services/orb"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to select system witnesses: injected policy error")
+	})
+
 	t.Run("error - get witnesses URIs error", func(t *testing.T) {
 		anchorCh := make(chan []anchorinfo.AnchorInfo, 100)
 		vcCh := make(chan *verifiable.Credential, 100)
@@ -974,7 +1072,8 @@ func (m *mockOpProcessor) Resolve(uniqueSuffix string) (*protocol.ResolutionMode
 }
 
 type mockOutbox struct {
-	Err error
+	Err        error
+	Activities []*vocab.ActivityType
 }
 
 func (m *mockOutbox) Post(activity *vocab.ActivityType) (*url.URL, error) {
@@ -982,6 +1081,8 @@ func (m *mockOutbox) Post(activity *vocab.ActivityType) (*url.URL, error) {
 		return nil, m.Err
 	}
 
+	m.Activities = append(m.Activities, activity)
+
 	return activity.ID().URL(), nil
 }
 
@@ -1013,7 +1114,7 @@ type mockMonitoring struct {
 	Err error
 }
 
-func (m *mockMonitoring) Watch(_ string, _ time.Time, _ []byte) error {
+func (m *mockMonitoring) Watch(_ *url.URL, _ string, _ time.Time, _ []byte) error {
 	if m.Err != nil {
 		return m.Err
 	}
@@ -1044,7 +1145,8 @@ func (a *mockActivityStore) QueryReferences(refType spi.ReferenceType, query *sp
 }
 
 type mockWitnessStore struct {
-	Err error
+	Err       error
+	Witnesses []*proof.WitnessProof
 }
 
 func (w *mockWitnessStore) Put(vcID string, witnesses []*proof.WitnessProof) error {
@@ -1052,6 +1154,42 @@ func (w *mockWitnessStore) Put(vcID string, witnesses []*proof.WitnessProof) err
 		return w.Err
 	}
 
+	w.Witnesses = append(w.Witnesses, witnesses...)
+
+	return nil
+}
+
+type mockWitnessPolicy struct {
+	Err   error
+	Count int
+}
+
+func (p *mockWitnessPolicy) Select(witnesses []*url.URL) ([]*url.URL, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+
+	if len(witnesses) > p.Count {
+		return witnesses[:p.Count], nil
+	}
+
+	return witnesses, nil
+}
+
+type mockWitnessMetrics struct {
+	Err       error
+	VCIDs     []string
+	Witnesses []string
+}
+
+func (m *mockWitnessMetrics) OffersSent(vcID string, witnesses []string, _ time.Time) error {
+	if m.Err != nil {
+		return m.Err
+	}
+
+	m.VCIDs = append(m.VCIDs, vcID)
+	m.Witnesses = append(m.Witnesses, witnesses...)
+
 	return nil
 }
 
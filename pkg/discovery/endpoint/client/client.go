@@ -0,0 +1,270 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/vct/pkg/client/vct"
+
+	"github.com/trustbloc/orb/pkg/discovery/endpoint/restapi"
+)
+
+var logger = log.New("discovery-client")
+
+const (
+	webFingerPath   = "/.well-known/webfinger"
+	defaultCacheTTL = time.Hour
+)
+
+// ErrNotFound is returned when a service does not publish a VCT log.
+var ErrNotFound = errors.New("not found")
+
+// ErrPublicKeyMismatch is returned when the public key of a VCT log doesn't match the public key that the
+// witness publishes for its log.
+var ErrPublicKeyMismatch = errors.New("public key mismatch")
+
+// HTTPClient represents HTTP client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// LogEndpoint contains the VCT log endpoint of a witness and the public key of the log.
+type LogEndpoint struct {
+	URL       string
+	PublicKey []byte
+}
+
+type cacheEntry struct {
+	value  []byte
+	expiry time.Time
+}
+
+// Client discovers the VCT log of a witness using WebFinger. The results are cached.
+type Client struct {
+	http          HTTPClient
+	cacheTTL      time.Duration
+	mutex         sync.RWMutex
+	logURLs       map[string]*cacheEntry
+	publishedKeys map[string]*cacheEntry
+	keys          map[string]*cacheEntry
+}
+
+// Opt represents client option func.
+type Opt func(*Client)
+
+// WithHTTPClient allows providing HTTP client.
+func WithHTTPClient(client HTTPClient) Opt {
+	return func(o *Client) {
+		o.http = client
+	}
+}
+
+// WithCacheTTL sets the amount of time that a discovered log endpoint (and public key) is cached.
+func WithCacheTTL(ttl time.Duration) Opt {
+	return func(o *Client) {
+		o.cacheTTL = ttl
+	}
+}
+
+// New returns a new discovery client.
+func New(opts ...Opt) *Client {
+	c := &Client{
+		http:          &http.Client{Timeout: time.Minute},
+		cacheTTL:      defaultCacheTTL,
+		logURLs:       make(map[string]*cacheEntry),
+		publishedKeys: make(map[string]*cacheEntry),
+		keys:          make(map[string]*cacheEntry),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetLogEndpoint returns the VCT log of the given witness (service IRI). The endpoint (and public key) of the log
+// is discovered using WebFinger on the witness's domain and the public key that's retrieved from the discovered
+// log must match the published key. ErrNotFound is returned if the witness doesn't publish a log (or doesn't
+// support discovery) and ErrPublicKeyMismatch is returned if the log returns a different key.
+func (c *Client) GetLogEndpoint(witness *url.URL) (*LogEndpoint, error) {
+	logURL, publishedKey, err := c.getLog(witness)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := c.GetLogPublicKey(logURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if publishedKey == nil {
+		logger.Warnf("Witness [%s] doesn't publish the public key of its VCT log [%s]. Trusting the key of the log.",
+			witness, logURL)
+	} else if !bytes.Equal(publishedKey, pubKey) {
+		// The key may have been cached before it was changed so it's retrieved again on the next request.
+		c.evict(c.keys, logURL)
+
+		return nil, fmt.Errorf("%w: the public key of log %s doesn't match the key published by witness %s",
+			ErrPublicKeyMismatch, logURL, witness)
+	}
+
+	return &LogEndpoint{URL: logURL, PublicKey: pubKey}, nil
+}
+
+// GetLogPublicKey returns the public key of the VCT log at the given endpoint.
+func (c *Client) GetLogPublicKey(logURL string) ([]byte, error) {
+	if pubKey, ok := c.get(c.keys, logURL); ok {
+		return pubKey, nil
+	}
+
+	pubKey, err := vct.New(logURL, vct.WithHTTPClient(c.http)).GetPublicKey(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("get public key of log %s: %w", logURL, err)
+	}
+
+	c.put(c.keys, logURL, pubKey)
+
+	return pubKey, nil
+}
+
+// getLog returns the endpoint of the VCT log of the given witness and the public key that the witness publishes
+// for its log (which is nil if the witness doesn't publish the key).
+func (c *Client) getLog(witness *url.URL) (string, []byte, error) {
+	if logURL, ok := c.get(c.logURLs, witness.String()); ok {
+		publishedKey, _ := c.get(c.publishedKeys, witness.String())
+
+		return string(logURL), publishedKey, nil
+	}
+
+	logger.Debugf("Discovering the VCT log of witness [%s]", witness)
+
+	resp, err := c.webFinger(witness)
+	if err != nil {
+		return "", nil, fmt.Errorf("discover log of witness %s: %w", witness, err)
+	}
+
+	for _, link := range resp.Links {
+		if link.Rel != restapi.VCTRel {
+			continue
+		}
+
+		logger.Debugf("Discovered VCT log [%s] of witness [%s]", link.Href, witness)
+
+		publishedKey, err := getPublishedKey(resp)
+		if err != nil {
+			return "", nil, fmt.Errorf("discover log of witness %s: %w", witness, err)
+		}
+
+		c.put(c.logURLs, witness.String(), []byte(link.Href))
+		c.put(c.publishedKeys, witness.String(), publishedKey)
+
+		return link.Href, publishedKey, nil
+	}
+
+	return "", nil, fmt.Errorf("discover log of witness %s: %w", witness, ErrNotFound)
+}
+
+func getPublishedKey(resp *restapi.WebFingerResponse) ([]byte, error) {
+	value, ok := resp.Properties[restapi.VCTPublicKeyProperty]
+	if !ok {
+		return nil, nil
+	}
+
+	keyStr, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s property", restapi.VCTPublicKeyProperty)
+	}
+
+	publishedKey, err := base64.StdEncoding.DecodeString(keyStr)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s property: %w", restapi.VCTPublicKeyProperty, err)
+	}
+
+	return publishedKey, nil
+}
+
+func (c *Client) webFinger(resource *url.URL) (*restapi.WebFingerResponse, error) {
+	webFingerURL := fmt.Sprintf("%s://%s%s?resource=%s", resource.Scheme, resource.Host, webFingerPath,
+		url.QueryEscape(resource.String()))
+
+	req, err := http.NewRequest(http.MethodGet, webFingerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", webFingerURL, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Warnf("Error closing response body: %s", err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusBadRequest:
+		// Services that don't support the resource (e.g. older versions) respond with 400.
+		return nil, fmt.Errorf("%w: get %s: status code %d: %s", ErrNotFound, webFingerURL, resp.StatusCode, body)
+	default:
+		return nil, fmt.Errorf("get %s: status code %d: %s", webFingerURL, resp.StatusCode, body)
+	}
+
+	wf := &restapi.WebFingerResponse{}
+
+	if err := json.Unmarshal(body, wf); err != nil {
+		return nil, fmt.Errorf("unmarshal WebFinger response: %w", err)
+	}
+
+	return wf, nil
+}
+
+func (c *Client) get(cache map[string]*cacheEntry, key string) ([]byte, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	e, ok := cache[key]
+	if !ok || time.Now().After(e.expiry) {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+func (c *Client) put(cache map[string]*cacheEntry, key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cache[key] = &cacheEntry{value: value, expiry: time.Now().Add(c.cacheTTL)}
+}
+
+func (c *Client) evict(cache map[string]*cacheEntry, key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(cache, key)
+}
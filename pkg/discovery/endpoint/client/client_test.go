@@ -0,0 +1,268 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+
+	"github.com/trustbloc/orb/pkg/discovery/endpoint/restapi"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+)
+
+const servicePath = "/services/orb"
+
+var pubKey = []byte("public key")
+
+func TestClient_GetLogEndpoint(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		s := newTestServer(t, true)
+
+		c := New(WithHTTPClient(http.DefaultClient))
+
+		endpoint, err := c.GetLogEndpoint(s.serviceIRI)
+		require.NoError(t, err)
+		require.Equal(t, s.URL, endpoint.URL)
+		require.Equal(t, pubKey, endpoint.PublicKey)
+
+		// The endpoint and key are cached.
+		endpoint, err = c.GetLogEndpoint(s.serviceIRI)
+		require.NoError(t, err)
+		require.Equal(t, s.URL, endpoint.URL)
+		require.Equal(t, int32(1), atomic.LoadInt32(&s.webFingerRequests))
+		require.Equal(t, int32(1), atomic.LoadInt32(&s.keyRequests))
+
+		pk, err := c.GetLogPublicKey(s.URL)
+		require.NoError(t, err)
+		require.Equal(t, pubKey, pk)
+		require.Equal(t, int32(1), atomic.LoadInt32(&s.keyRequests))
+	})
+
+	t.Run("Cache expiry", func(t *testing.T) {
+		s := newTestServer(t, true)
+
+		c := New(WithHTTPClient(http.DefaultClient), WithCacheTTL(time.Millisecond))
+
+		_, err := c.GetLogEndpoint(s.serviceIRI)
+		require.NoError(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = c.GetLogEndpoint(s.serviceIRI)
+		require.NoError(t, err)
+		require.Equal(t, int32(2), atomic.LoadInt32(&s.webFingerRequests))
+		require.Equal(t, int32(2), atomic.LoadInt32(&s.keyRequests))
+	})
+
+	t.Run("No VCT log", func(t *testing.T) {
+		s := newTestServer(t, false)
+
+		_, err := New().GetLogEndpoint(s.serviceIRI)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("Unknown resource", func(t *testing.T) {
+		s := newTestServer(t, true)
+
+		_, err := New().GetLogEndpoint(testutil.MustParseURL(s.URL + "/services/unknown"))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrNotFound))
+		require.Contains(t, err.Error(), "status code 400")
+	})
+
+	t.Run("Server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		_, err := New().GetLogEndpoint(testutil.MustParseURL(server.URL + servicePath))
+		require.Error(t, err)
+		require.False(t, errors.Is(err, ErrNotFound))
+		require.Contains(t, err.Error(), "status code 500")
+	})
+
+	t.Run("Published public key", func(t *testing.T) {
+		s := newTestServer(t, true, withPublishedKey(pubKey))
+
+		c := New(WithHTTPClient(http.DefaultClient))
+
+		endpoint, err := c.GetLogEndpoint(s.serviceIRI)
+		require.NoError(t, err)
+		require.Equal(t, pubKey, endpoint.PublicKey)
+
+		// The published key is cached along with the endpoint.
+		endpoint, err = c.GetLogEndpoint(s.serviceIRI)
+		require.NoError(t, err)
+		require.Equal(t, pubKey, endpoint.PublicKey)
+		require.Equal(t, int32(1), atomic.LoadInt32(&s.webFingerRequests))
+	})
+
+	t.Run("Public key mismatch", func(t *testing.T) {
+		s := newTestServer(t, true, withPublishedKey([]byte("other key")))
+
+		c := New(WithHTTPClient(http.DefaultClient))
+
+		_, err := c.GetLogEndpoint(s.serviceIRI)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrPublicKeyMismatch))
+
+		// The key of the log is retrieved again.
+		_, err = c.GetLogEndpoint(s.serviceIRI)
+		require.True(t, errors.Is(err, ErrPublicKeyMismatch))
+		require.Equal(t, int32(2), atomic.LoadInt32(&s.keyRequests))
+	})
+
+	t.Run("Invalid published public key", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewEncoder(w).Encode(&restapi.WebFingerResponse{
+				Properties: map[string]interface{}{restapi.VCTPublicKeyProperty: "{"},
+				Links:      []restapi.WebFingerLink{{Rel: restapi.VCTRel, Href: "https://vct.domain.com"}},
+			}))
+		}))
+		defer server.Close()
+
+		_, err := New().GetLogEndpoint(testutil.MustParseURL(server.URL + servicePath))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decode "+restapi.VCTPublicKeyProperty+" property")
+
+		server2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewEncoder(w).Encode(&restapi.WebFingerResponse{
+				Properties: map[string]interface{}{restapi.VCTPublicKeyProperty: 1},
+				Links:      []restapi.WebFingerLink{{Rel: restapi.VCTRel, Href: "https://vct.domain.com"}},
+			}))
+		}))
+		defer server2.Close()
+
+		_, err = New().GetLogEndpoint(testutil.MustParseURL(server2.URL + servicePath))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid "+restapi.VCTPublicKeyProperty+" property")
+	})
+
+	t.Run("Invalid response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte("{"))
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		_, err := New().GetLogEndpoint(testutil.MustParseURL(server.URL + servicePath))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal WebFinger response")
+	})
+
+	t.Run("HTTP error", func(t *testing.T) {
+		c := New(WithHTTPClient(httpMock(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("injected HTTP error")
+		})))
+
+		_, err := c.GetLogEndpoint(testutil.MustParseURL("https://witness.domain.com/services/orb"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected HTTP error")
+	})
+
+	t.Run("Public key error", func(t *testing.T) {
+		s := newTestServer(t, true)
+
+		s.keyErr = true
+
+		_, err := New().GetLogEndpoint(s.serviceIRI)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get public key of log")
+	})
+}
+
+type testServer struct {
+	*httptest.Server
+
+	serviceIRI        *url.URL
+	webFingerRequests int32
+	keyRequests       int32
+	keyErr            bool
+}
+
+type logKeyProvider []byte
+
+func (p logKeyProvider) LogPublicKey() ([]byte, error) {
+	return p, nil
+}
+
+func withPublishedKey(key []byte) func(cfg *restapi.Config) {
+	return func(cfg *restapi.Config) {
+		cfg.LogKeyProvider = logKeyProvider(key)
+	}
+}
+
+func newTestServer(t *testing.T, withVCT bool, opts ...func(cfg *restapi.Config)) *testServer {
+	t.Helper()
+
+	s := &testServer{}
+
+	router := mux.NewRouter()
+
+	s.Server = httptest.NewServer(router)
+	t.Cleanup(s.Close)
+
+	s.serviceIRI = testutil.MustParseURL(s.URL + servicePath)
+
+	cfg := &restapi.Config{
+		BaseURL:    s.URL,
+		ServiceIRI: s.serviceIRI.String(),
+	}
+
+	if withVCT {
+		cfg.VctURL = s.URL
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	op, err := restapi.New(cfg)
+	require.NoError(t, err)
+
+	for _, h := range op.GetRESTHandlers() {
+		handle := h.Handler()
+
+		router.HandleFunc(h.Path(), func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&s.webFingerRequests, 1)
+
+			handle(w, r)
+		}).Methods(h.Method())
+	}
+
+	router.HandleFunc(rest.GetPublicKeyPath, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.keyRequests, 1)
+
+		if s.keyErr {
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(pubKey))
+	})
+
+	return s
+}
+
+type httpMock func(req *http.Request) (*http.Response, error)
+
+func (m httpMock) Do(req *http.Request) (*http.Response, error) {
+	return m(req)
+}
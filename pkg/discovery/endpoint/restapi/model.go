@@ -6,6 +6,13 @@ SPDX-License-Identifier: Apache-2.0
 
 package restapi
 
+// VCTRel is the WebFinger link relation of the VCT log of a service.
+const VCTRel = "vct"
+
+// VCTPublicKeyProperty is the WebFinger property that contains the (base64-encoded) public key of the VCT log
+// of a service.
+const VCTPublicKeyProperty = "https://trustbloc.dev/ns/vct-public-key"
+
 // ErrorResponse to send error message in the response.
 type ErrorResponse struct {
 	Message string `json:"errMessage,omitempty"`
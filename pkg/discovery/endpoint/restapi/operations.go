@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package restapi
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -47,6 +48,10 @@ func New(c *Config) (*Operation, error) {
 		baseURL:                   c.BaseURL,
 		discoveryMinimumResolvers: c.DiscoveryMinimumResolvers,
 		discoveryDomains:          c.DiscoveryDomains,
+		serviceIRI:                c.ServiceIRI,
+		vctURL:                    c.VctURL,
+		keyProvider:               c.KeyProvider,
+		logKeyProvider:            c.LogKeyProvider,
 	}, nil
 }
 
@@ -54,6 +59,8 @@ func New(c *Config) (*Operation, error) {
 type Operation struct {
 	pubKey                    []byte
 	kid                       string
+	keyProvider               KeyProvider
+	logKeyProvider            LogKeyProvider
 	host                      string
 	verificationMethodType    string
 	resolutionPath            string
@@ -61,6 +68,8 @@ type Operation struct {
 	baseURL                   string
 	discoveryDomains          []string
 	discoveryMinimumResolvers int
+	serviceIRI                string
+	vctURL                    string
 }
 
 // Config defines configuration for discovery operations.
@@ -73,6 +82,46 @@ type Config struct {
 	BaseURL                   string
 	DiscoveryDomains          []string
 	DiscoveryMinimumResolvers int
+	// ServiceIRI is the IRI of the ActivityPub service. WebFinger returns the VCT log of the service
+	// (used by the service when it acts as a witness) for this resource.
+	ServiceIRI string
+	// VctURL is the endpoint of the VCT log of the service.
+	VctURL string
+	// KeyProvider provides the public keys that are published in the did:web document. If not set then
+	// only PubKey is published (with ID KID).
+	KeyProvider KeyProvider
+	// LogKeyProvider provides the public key of the VCT log of the service, which is published in the
+	// WebFinger response of the service so that the log can't substitute its own key.
+	LogKeyProvider LogKeyProvider
+}
+
+// PublicKey is a public key that is published in the did:web document.
+type PublicKey struct {
+	// ID is the ID of the key, i.e. the fragment of the verification method.
+	ID string
+	// Value contains the raw bytes of the public key.
+	Value []byte
+	// AssertionOnly indicates that the key is no longer used and is only published so that credentials
+	// which were signed with the key can still be verified.
+	AssertionOnly bool
+}
+
+// KeyProvider provides the public keys of the service. The keys change when the signing key is rotated.
+type KeyProvider interface {
+	PublicKeys() []PublicKey
+}
+
+// LogKeyProvider provides the public key of the VCT log of the service.
+type LogKeyProvider interface {
+	LogPublicKey() ([]byte, error)
+}
+
+func (o *Operation) getPublicKeys() []PublicKey {
+	if o.keyProvider != nil {
+		return o.keyProvider.PublicKeys()
+	}
+
+	return []PublicKey{{ID: o.kid, Value: o.pubKey}}
 }
 
 // GetRESTHandlers get all controller API handler available for this service.
@@ -108,20 +157,32 @@ func (o *Operation) wellKnownHandler(rw http.ResponseWriter, r *http.Request) {
 func (o *Operation) webDIDHandler(rw http.ResponseWriter, r *http.Request) {
 	ID := "did:web:" + o.host
 
-	writeResponse(rw, &RawDoc{
+	doc := &RawDoc{
 		Context: context,
 		ID:      ID,
-		VerificationMethod: []verificationMethod{{
-			ID:              ID + "#" + o.kid,
+	}
+
+	// All keys may be used to verify assertions (credentials) but retired keys may not be used for anything else.
+	for _, key := range o.getPublicKeys() {
+		keyID := ID + "#" + key.ID
+
+		doc.VerificationMethod = append(doc.VerificationMethod, verificationMethod{
+			ID:              keyID,
 			Controller:      ID,
 			Type:            o.verificationMethodType,
-			PublicKeyBase58: base58.Encode(o.pubKey),
-		}},
-		Authentication:       []string{ID + "#" + o.kid},
-		AssertionMethod:      []string{ID + "#" + o.kid},
-		CapabilityDelegation: []string{ID + "#" + o.kid},
-		CapabilityInvocation: []string{ID + "#" + o.kid},
-	}, http.StatusOK)
+			PublicKeyBase58: base58.Encode(key.Value),
+		})
+
+		doc.AssertionMethod = append(doc.AssertionMethod, keyID)
+
+		if !key.AssertionOnly {
+			doc.Authentication = append(doc.Authentication, keyID)
+			doc.CapabilityDelegation = append(doc.CapabilityDelegation, keyID)
+			doc.CapabilityInvocation = append(doc.CapabilityInvocation, keyID)
+		}
+	}
+
+	writeResponse(rw, doc, http.StatusOK)
 }
 
 // webFingerHandler swagger:route Get /.well-known/webfinger discovery webFingerReq
@@ -174,12 +235,46 @@ func (o *Operation) webFingerHandler(rw http.ResponseWriter, r *http.Request) {
 			})
 		}
 
+		writeResponse(rw, resp, http.StatusOK)
+	case o.serviceIRI != "" && resource == o.serviceIRI:
+		resp := &WebFingerResponse{
+			Subject: resource,
+			Links: []WebFingerLink{
+				{Rel: "self", Href: resource},
+			},
+		}
+
+		if o.vctURL != "" {
+			resp.Links = append(resp.Links, WebFingerLink{Rel: VCTRel, Href: o.vctURL})
+
+			o.addLogPublicKey(resp)
+		}
+
 		writeResponse(rw, resp, http.StatusOK)
 	default:
 		writeErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("resource %s not found", resource))
 	}
 }
 
+// addLogPublicKey adds the public key of the VCT log to the properties of the response. The key is omitted
+// (and clients fall back to retrieving the key from the log) if it isn't available.
+func (o *Operation) addLogPublicKey(resp *WebFingerResponse) {
+	if o.logKeyProvider == nil {
+		return
+	}
+
+	pubKey, err := o.logKeyProvider.LogPublicKey()
+	if err != nil {
+		logger.Warnf("Unable to publish the public key of VCT log [%s]: %s", o.vctURL, err)
+
+		return
+	}
+
+	resp.Properties = map[string]interface{}{
+		VCTPublicKeyProperty: base64.StdEncoding.EncodeToString(pubKey),
+	}
+}
+
 // writeErrorResponse write error resp.
 func writeErrorResponse(rw http.ResponseWriter, status int, msg string) {
 	rw.Header().Add("Content-Type", "application/json")
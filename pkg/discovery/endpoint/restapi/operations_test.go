@@ -7,7 +7,9 @@ package restapi_test
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -138,6 +140,112 @@ func TestWebFinger(t *testing.T) {
 	})
 }
 
+func TestWebFinger_Service(t *testing.T) {
+	t.Run("test service resource", func(t *testing.T) {
+		c, err := restapi.New(&restapi.Config{
+			OperationPath:  "/op",
+			ResolutionPath: "/resolve",
+			BaseURL:        "http://base",
+			ServiceIRI:     "http://base/services/orb",
+			VctURL:         "http://vct",
+		})
+		require.NoError(t, err)
+
+		handler := getHandler(t, c, webFingerEndpoint)
+
+		rr := serveHTTP(t, handler.Handler(), http.MethodGet,
+			webFingerEndpoint+"?resource=http://base/services/orb", nil, nil)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var w restapi.WebFingerResponse
+
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &w))
+		require.Equal(t, "http://base/services/orb", w.Subject)
+		require.Len(t, w.Links, 2)
+		require.Equal(t, "http://base/services/orb", w.Links[0].Href)
+		require.Equal(t, restapi.VCTRel, w.Links[1].Rel)
+		require.Equal(t, "http://vct", w.Links[1].Href)
+		require.Empty(t, w.Properties)
+	})
+
+	t.Run("test service resource with VCT public key", func(t *testing.T) {
+		c, err := restapi.New(&restapi.Config{
+			BaseURL:        "http://base",
+			ServiceIRI:     "http://base/services/orb",
+			VctURL:         "http://vct",
+			LogKeyProvider: &mockLogKeyProvider{pubKey: []byte("public key")},
+		})
+		require.NoError(t, err)
+
+		handler := getHandler(t, c, webFingerEndpoint)
+
+		rr := serveHTTP(t, handler.Handler(), http.MethodGet,
+			webFingerEndpoint+"?resource=http://base/services/orb", nil, nil)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var w restapi.WebFingerResponse
+
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &w))
+		require.Len(t, w.Links, 2)
+		require.Equal(t, base64.StdEncoding.EncodeToString([]byte("public key")),
+			w.Properties[restapi.VCTPublicKeyProperty])
+	})
+
+	t.Run("test service resource with VCT public key error", func(t *testing.T) {
+		c, err := restapi.New(&restapi.Config{
+			BaseURL:        "http://base",
+			ServiceIRI:     "http://base/services/orb",
+			VctURL:         "http://vct",
+			LogKeyProvider: &mockLogKeyProvider{err: errors.New("injected error")},
+		})
+		require.NoError(t, err)
+
+		handler := getHandler(t, c, webFingerEndpoint)
+
+		rr := serveHTTP(t, handler.Handler(), http.MethodGet,
+			webFingerEndpoint+"?resource=http://base/services/orb", nil, nil)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var w restapi.WebFingerResponse
+
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &w))
+		require.Len(t, w.Links, 2)
+		require.Empty(t, w.Properties)
+	})
+
+	t.Run("test service resource without VCT", func(t *testing.T) {
+		c, err := restapi.New(&restapi.Config{
+			BaseURL:    "http://base",
+			ServiceIRI: "http://base/services/orb",
+		})
+		require.NoError(t, err)
+
+		handler := getHandler(t, c, webFingerEndpoint)
+
+		rr := serveHTTP(t, handler.Handler(), http.MethodGet,
+			webFingerEndpoint+"?resource=http://base/services/orb", nil, nil)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var w restapi.WebFingerResponse
+
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &w))
+		require.Len(t, w.Links, 1)
+	})
+}
+
+type mockLogKeyProvider struct {
+	pubKey []byte
+	err    error
+}
+
+func (m *mockLogKeyProvider) LogPublicKey() ([]byte, error) {
+	return m.pubKey, m.err
+}
+
 func TestWellKnownDID(t *testing.T) {
 	c, err := restapi.New(&restapi.Config{
 		BaseURL: "https://example.com",
@@ -157,6 +265,43 @@ func TestWellKnownDID(t *testing.T) {
 	require.Len(t, w.VerificationMethod, 1)
 }
 
+func TestWellKnownDID_KeyProvider(t *testing.T) {
+	c, err := restapi.New(&restapi.Config{
+		BaseURL: "https://example.com",
+		KID:     "key1",
+		PubKey:  []byte("pubkey1"),
+		KeyProvider: &mockKeyProvider{keys: []restapi.PublicKey{
+			{ID: "key2", Value: []byte("pubkey2")},
+			{ID: "key3", Value: []byte("pubkey3")},
+			{ID: "key1", Value: []byte("pubkey1"), AssertionOnly: true},
+		}},
+	})
+	require.NoError(t, err)
+
+	handler := getHandler(t, c, webDIDEndpoint)
+
+	rr := serveHTTP(t, handler.Handler(), http.MethodGet, webDIDEndpoint, nil, nil)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var w restapi.RawDoc
+
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &w))
+	require.Len(t, w.VerificationMethod, 3)
+	require.Equal(t, "did:web:example.com#key2", w.VerificationMethod[0].ID)
+	require.Equal(t, []string{"did:web:example.com#key2", "did:web:example.com#key3", "did:web:example.com#key1"},
+		w.AssertionMethod)
+	require.Equal(t, []string{"did:web:example.com#key2", "did:web:example.com#key3"}, w.Authentication)
+}
+
+type mockKeyProvider struct {
+	keys []restapi.PublicKey
+}
+
+func (m *mockKeyProvider) PublicKeys() []restapi.PublicKey {
+	return m.keys
+}
+
 func TestWellKnown(t *testing.T) {
 	c, err := restapi.New(&restapi.Config{
 		OperationPath:  "/op",
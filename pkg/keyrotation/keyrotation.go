@@ -0,0 +1,669 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyrotation
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/orb/pkg/activitypub/resthandler"
+	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
+	"github.com/trustbloc/orb/pkg/activitypub/service/spi"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+)
+
+var logger = log.New("key-rotation")
+
+const (
+	defaultOverlapPeriod = 24 * time.Hour
+	keysPath             = "/keys/"
+	stateKey             = "key-rotation-state"
+)
+
+// ErrRotationInProgress is returned from Rotate when a previous rotation has not yet completed.
+var ErrRotationInProgress = errors.New("key rotation is already in progress")
+
+// ErrUnsupportedKeyType is returned if the public key of the given type can't be published.
+var ErrUnsupportedKeyType = errors.New("unsupported key type")
+
+// Key contains the details of a signing key of the service.
+type Key struct {
+	// KMSKeyID is the ID of the key in the KMS. This ID is also used as the
+	// key ID of the verification method in the did:web document.
+	KMSKeyID string
+
+	// PublicKeyBytes contains the raw bytes of the public key.
+	PublicKeyBytes []byte
+
+	// PublicKey is the ActivityPub public key which is published at /services/orb/keys/{id}.
+	PublicKey *vocab.PublicKeyType
+}
+
+// KeyActivatedHandler is invoked after the service has switched to a new signing key
+// (and before the Update activity is sent to followers).
+type KeyActivatedHandler func(key *Key) error
+
+// Config contains the configuration for the key rotation manager.
+type Config struct {
+	// ServiceIRI is the IRI of the ActivityPub service (actor).
+	ServiceIRI *url.URL
+
+	// KeyType is the type of key to create in the KMS.
+	KeyType kms.KeyType
+
+	// RotationInterval is the interval at which keys are automatically rotated.
+	// If zero then keys are only rotated when Rotate is explicitly invoked.
+	RotationInterval time.Duration
+
+	// OverlapPeriod is the period during which both the old and the new key are published
+	// before the service starts signing with the new key.
+	OverlapPeriod time.Duration
+
+	// GracePeriod is the period after the new key is activated during which the old key is still published
+	// as an ActivityPub key, so that requests which were signed with the old key (for example, queued
+	// redeliveries) can still be verified. Defaults to the overlap period.
+	GracePeriod time.Duration
+}
+
+type keyManager interface {
+	Create(kt kms.KeyType) (string, interface{}, error)
+	ExportPubKeyBytes(keyID string) ([]byte, error)
+}
+
+// Outbox is used to post the 'Update' activity to followers after a key is activated.
+type Outbox interface {
+	Post(activity *vocab.ActivityType) (*url.URL, error)
+}
+
+// keyRef is the persisted reference to a key.
+type keyRef struct {
+	KMSKeyID    string `json:"kmsKeyId"`
+	PublicKeyID string `json:"publicKeyId"`
+
+	// Time is the time at which the next key is activated or at which the previous key is retired.
+	Time time.Time `json:"time,omitempty"`
+}
+
+// state is the persisted rotation state. It allows a rotation to resume after a restart.
+type state struct {
+	Current  *keyRef   `json:"current"`
+	Next     *keyRef   `json:"next,omitempty"`
+	Previous *keyRef   `json:"previous,omitempty"`
+	Retired  []*keyRef `json:"retired,omitempty"`
+}
+
+// Manager manages the signing key of the service. When the key is rotated, a new key is generated in
+// the KMS and both the old and the new key are published during an overlap period. After the overlap
+// period, the new key is used for signing and an 'Update' activity containing the updated service (actor)
+// is sent to followers. The old key is still published as an ActivityPub key for a grace period, after
+// which it's retired. Retired keys are still published in the did:web document so that anchor credentials
+// which were signed with them can be verified.
+//
+// The rotation state is persisted so that a rotation which is in progress is resumed after a restart.
+type Manager struct {
+	*Config
+	*lifecycle.Lifecycle
+
+	km         keyManager
+	store      storage.Store
+	outbox     func() Outbox
+	handlers   []KeyActivatedHandler
+	followers  *url.URL
+	mutex      sync.RWMutex
+	state      *state
+	current    *Key
+	next       *Key
+	previous   *Key
+	retired    []*Key
+	done       chan struct{}
+	afterFunc  func(d time.Duration, f func())
+	rotateLock sync.Mutex
+}
+
+// New returns a new key rotation manager. If rotation state was persisted by a previous instance then the
+// persisted keys are used, otherwise the current signing key is loaded from the KMS using the given KMS key ID
+// and is published with the given public key ID (the last segment of the public key IRI).
+// The outbox provider returns the outbox which is used to post 'Update' activities.
+func New(cfg *Config, km keyManager, s storage.Store, kmsKeyID, publicKeyID string, outbox func() Outbox,
+	handlers ...KeyActivatedHandler) (*Manager, error) {
+	if cfg.OverlapPeriod == 0 {
+		cfg.OverlapPeriod = defaultOverlapPeriod
+	}
+
+	if cfg.GracePeriod == 0 {
+		cfg.GracePeriod = cfg.OverlapPeriod
+	}
+
+	followers, err := url.Parse(cfg.ServiceIRI.String() + resthandler.FollowersPath)
+	if err != nil {
+		return nil, fmt.Errorf("parse followers IRI: %w", err)
+	}
+
+	m := &Manager{
+		Config:    cfg,
+		km:        km,
+		store:     s,
+		outbox:    outbox,
+		handlers:  handlers,
+		followers: followers,
+		done:      make(chan struct{}),
+		afterFunc: func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+	}
+
+	m.Lifecycle = lifecycle.New("key-rotation",
+		lifecycle.WithStart(m.start),
+		lifecycle.WithStop(m.stop),
+	)
+
+	st, err := m.loadState(kmsKeyID, publicKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.loadKeys(st); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// CurrentKey returns the key that is currently used for signing.
+func (m *Manager) CurrentKey() *Key {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.current
+}
+
+// PublicKey returns the ActivityPub public key that is currently used for signing.
+func (m *Manager) PublicKey() *vocab.PublicKeyType {
+	return m.CurrentKey().PublicKey
+}
+
+// PublicKeys returns all of the published ActivityPub public keys. During a rotation this includes
+// both the old and the new key.
+func (m *Manager) PublicKeys() []*vocab.PublicKeyType {
+	var publicKeys []*vocab.PublicKeyType
+
+	for _, key := range m.publishedKeys() {
+		publicKeys = append(publicKeys, key.PublicKey)
+	}
+
+	return publicKeys
+}
+
+// VerificationKeys returns the keys that are published in the did:web document. The active keys are the
+// current key, the next key (during the overlap period) and the previous key (during the grace period).
+// Retired keys are no longer used but are returned so that credentials which were signed with them
+// can still be verified.
+func (m *Manager) VerificationKeys() (active, retired []*Key) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.publishedKeysUnsafe(), append([]*Key(nil), m.retired...)
+}
+
+// PublicKeyID returns the ID of the ActivityPub public key that is currently used for signing.
+func (m *Manager) PublicKeyID() *url.URL {
+	return m.PublicKey().ID.URL()
+}
+
+// ResolveSigningKey returns the KMS key ID for the given ActivityPub public key ID.
+func (m *Manager) ResolveSigningKey(pubKeyID string) (string, error) {
+	for _, key := range m.publishedKeys() {
+		if key.PublicKey.ID.String() == pubKeyID {
+			return key.KMSKeyID, nil
+		}
+	}
+
+	return "", fmt.Errorf("public key [%s] is not published", pubKeyID)
+}
+
+// Rotate generates a new key in the KMS and publishes it alongside the current key. After the overlap
+// period the new key becomes the signing key.
+func (m *Manager) Rotate() (*Key, error) {
+	m.rotateLock.Lock()
+	defer m.rotateLock.Unlock()
+
+	m.mutex.RLock()
+	inProgress := m.next != nil || m.previous != nil
+	m.mutex.RUnlock()
+
+	if inProgress {
+		return nil, ErrRotationInProgress
+	}
+
+	kmsKeyID, _, err := m.km.Create(m.KeyType)
+	if err != nil {
+		return nil, fmt.Errorf("create key: %w", err)
+	}
+
+	key, err := m.loadKey(kmsKeyID, kmsKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	activationTime := time.Now().Add(m.OverlapPeriod)
+
+	err = m.updateState(func(st *state) {
+		st.Next = &keyRef{KMSKeyID: kmsKeyID, PublicKeyID: kmsKeyID, Time: activationTime}
+	}, func() {
+		m.next = key
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Published new key [%s]. The key will be activated in %s", key.PublicKey.ID, m.OverlapPeriod)
+
+	m.scheduleActivation(key, m.OverlapPeriod)
+
+	return key, nil
+}
+
+func (m *Manager) scheduleActivation(key *Key, d time.Duration) {
+	m.afterFunc(d, func() {
+		if m.State() == spi.StateStopped {
+			logger.Infof("Key [%s] not activated since the service is stopped", key.PublicKey.ID)
+
+			return
+		}
+
+		if err := m.activate(key); err != nil {
+			logger.Errorf("Error activating key [%s]: %s", key.PublicKey.ID, err)
+		}
+	})
+}
+
+func (m *Manager) scheduleRetirement(key *Key, d time.Duration) {
+	m.afterFunc(d, func() {
+		if m.State() == spi.StateStopped {
+			logger.Infof("Key [%s] not retired since the service is stopped", key.PublicKey.ID)
+
+			return
+		}
+
+		if err := m.retire(); err != nil {
+			logger.Errorf("Error retiring key [%s]: %s", key.PublicKey.ID, err)
+		}
+	})
+}
+
+func (m *Manager) activate(key *Key) error {
+	retirementTime := time.Now().Add(m.GracePeriod)
+
+	var previous *Key
+
+	err := m.updateState(func(st *state) {
+		previousRef := *st.Current
+		previousRef.Time = retirementTime
+
+		st.Previous = &previousRef
+		// The KMS key ID is also used as the ID of the ActivityPub public key for rotated keys.
+		st.Current = &keyRef{KMSKeyID: key.KMSKeyID, PublicKeyID: key.KMSKeyID}
+		st.Next = nil
+	}, func() {
+		previous = m.current
+
+		m.previous = m.current
+		m.current = key
+		m.next = nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Activated key [%s]. The previous key will be retired in %s", key.PublicKey.ID, m.GracePeriod)
+
+	// The old key remains published for the grace period so that requests which were signed with it can still
+	// be verified. It's retired after the grace period even if a subsequent step fails.
+	m.scheduleRetirement(previous, m.GracePeriod)
+
+	for _, handle := range m.handlers {
+		if err := handle(key); err != nil {
+			return fmt.Errorf("key activated handler: %w", err)
+		}
+	}
+
+	return m.postUpdate(key)
+}
+
+func (m *Manager) retire() error {
+	var retired *Key
+
+	err := m.updateState(func(st *state) {
+		if st.Previous != nil {
+			st.Retired = append(st.Retired, &keyRef{
+				KMSKeyID:    st.Previous.KMSKeyID,
+				PublicKeyID: st.Previous.PublicKeyID,
+			})
+		}
+
+		st.Previous = nil
+	}, func() {
+		retired = m.previous
+
+		if m.previous != nil {
+			m.retired = append(m.retired, m.previous)
+		}
+
+		m.previous = nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if retired != nil {
+		logger.Infof("Retired key [%s]", retired.PublicKey.ID)
+	}
+
+	return nil
+}
+
+func (m *Manager) postUpdate(key *Key) error {
+	service, err := resthandler.NewService(m.ServiceIRI, key.PublicKey)
+	if err != nil {
+		return fmt.Errorf("create service: %w", err)
+	}
+
+	published := time.Now()
+
+	update := vocab.NewUpdateActivity(
+		vocab.NewObjectProperty(vocab.WithActorObject(service)),
+		vocab.WithActor(m.ServiceIRI),
+		vocab.WithTo(m.followers),
+		vocab.WithPublishedTime(&published),
+	)
+
+	activityID, err := m.outbox().Post(update)
+	if err != nil {
+		return fmt.Errorf("post update: %w", err)
+	}
+
+	logger.Debugf("Posted update [%s] of service [%s] with new key [%s]", activityID, m.ServiceIRI, key.PublicKey.ID)
+
+	return nil
+}
+
+func (m *Manager) publishedKeys() []*Key {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.publishedKeysUnsafe()
+}
+
+func (m *Manager) publishedKeysUnsafe() []*Key {
+	keys := []*Key{m.current}
+
+	if m.next != nil {
+		keys = append(keys, m.next)
+	}
+
+	if m.previous != nil {
+		keys = append(keys, m.previous)
+	}
+
+	return keys
+}
+
+// loadState loads the persisted rotation state. If no state was persisted then the state is initialized
+// with the given key and persisted.
+func (m *Manager) loadState(kmsKeyID, publicKeyID string) (*state, error) {
+	src, err := m.store.Get(stateKey)
+	if err != nil {
+		if !errors.Is(err, storage.ErrDataNotFound) {
+			return nil, fmt.Errorf("load key rotation state: %w", err)
+		}
+
+		st := &state{Current: &keyRef{KMSKeyID: kmsKeyID, PublicKeyID: publicKeyID}}
+
+		if err := m.putState(st); err != nil {
+			return nil, err
+		}
+
+		return st, nil
+	}
+
+	st := &state{}
+
+	if err := json.Unmarshal(src, st); err != nil {
+		return nil, fmt.Errorf("unmarshal key rotation state: %w", err)
+	}
+
+	if st.Current == nil {
+		return nil, errors.New("invalid key rotation state: no current key")
+	}
+
+	if kmsKeyID != "" && st.Current.KMSKeyID != kmsKeyID {
+		logger.Warnf("Using the signing key [%s] from the key rotation state instead of the configured key [%s]",
+			st.Current.KMSKeyID, kmsKeyID)
+	}
+
+	return st, nil
+}
+
+func (m *Manager) loadKeys(st *state) error {
+	var err error
+
+	m.current, err = m.loadKey(st.Current.KMSKeyID, st.Current.PublicKeyID)
+	if err != nil {
+		return err
+	}
+
+	if st.Next != nil {
+		m.next, err = m.loadKey(st.Next.KMSKeyID, st.Next.PublicKeyID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if st.Previous != nil {
+		m.previous, err = m.loadKey(st.Previous.KMSKeyID, st.Previous.PublicKeyID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range st.Retired {
+		key, err := m.loadKey(ref.KMSKeyID, ref.PublicKeyID)
+		if err != nil {
+			// A retired key is only needed to verify old credentials so it's not fatal if it can't be loaded.
+			logger.Warnf("Unable to load retired key [%s]: %s", ref.KMSKeyID, err)
+
+			continue
+		}
+
+		m.retired = append(m.retired, key)
+	}
+
+	m.state = st
+
+	return nil
+}
+
+// updateState applies the given update to a copy of the rotation state and persists it. The in-memory keys
+// are updated (using the given function) only if the state was persisted.
+func (m *Manager) updateState(updateState func(st *state), updateKeys func()) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	st := &state{
+		Current:  m.state.Current,
+		Next:     m.state.Next,
+		Previous: m.state.Previous,
+		Retired:  append([]*keyRef(nil), m.state.Retired...),
+	}
+
+	updateState(st)
+
+	if err := m.putState(st); err != nil {
+		return err
+	}
+
+	m.state = st
+
+	updateKeys()
+
+	return nil
+}
+
+func (m *Manager) putState(st *state) error {
+	src, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("marshal key rotation state: %w", err)
+	}
+
+	if err := m.store.Put(stateKey, src); err != nil {
+		return fmt.Errorf("store key rotation state: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) loadKey(kmsKeyID, publicKeyID string) (*Key, error) {
+	pubKeyBytes, err := m.km.ExportPubKeyBytes(kmsKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("export public key [%s]: %w", kmsKeyID, err)
+	}
+
+	keyIRI, err := url.Parse(m.ServiceIRI.String() + keysPath + publicKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key IRI: %w", err)
+	}
+
+	publicKey, err := newPublicKey(m.KeyType, pubKeyBytes, m.ServiceIRI, keyIRI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		KMSKeyID:       kmsKeyID,
+		PublicKeyBytes: pubKeyBytes,
+		PublicKey:      publicKey,
+	}, nil
+}
+
+// start resumes a rotation that was in progress when the service was stopped and, if configured,
+// starts rotating keys periodically.
+func (m *Manager) start() {
+	m.mutex.RLock()
+	st, next, previous := m.state, m.next, m.previous
+	m.mutex.RUnlock()
+
+	if next != nil {
+		logger.Infof("Resuming rotation to key [%s]", next.PublicKey.ID)
+
+		m.scheduleActivation(next, timeUntil(st.Next.Time))
+	}
+
+	if previous != nil {
+		m.scheduleRetirement(previous, timeUntil(st.Previous.Time))
+	}
+
+	if m.RotationInterval == 0 {
+		logger.Debugf("Automatic key rotation is disabled")
+
+		return
+	}
+
+	go m.rotatePeriodically()
+}
+
+func (m *Manager) stop() {
+	close(m.done)
+}
+
+func (m *Manager) rotatePeriodically() {
+	ticker := time.NewTicker(m.RotationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := m.Rotate(); err != nil {
+				logger.Warnf("Error rotating key: %s", err)
+			}
+		case <-m.done:
+			logger.Debugf("Stopped key rotation")
+
+			return
+		}
+	}
+}
+
+func timeUntil(t time.Time) time.Duration {
+	d := time.Until(t)
+	if d < 0 {
+		return 0
+	}
+
+	return d
+}
+
+func newPublicKey(keyType kms.KeyType, pubKey []byte, serviceIRI, keyIRI *url.URL) (*vocab.PublicKeyType, error) {
+	pubDerKey, err := marshalPublicKey(keyType, pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("marshal pub key: %w", err)
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pubDerKey,
+	})
+
+	return vocab.NewPublicKey(
+		vocab.WithID(keyIRI),
+		vocab.WithOwner(serviceIRI),
+		vocab.WithPublicKeyPem(string(pemBytes)),
+	), nil
+}
+
+// marshalPublicKey returns the PKIX (DER) encoding of the public key that was exported from the KMS.
+func marshalPublicKey(keyType kms.KeyType, pubKey []byte) ([]byte, error) {
+	switch keyType {
+	case kms.ED25519Type, "":
+		return x509.MarshalPKIXPublicKey(ed25519.PublicKey(pubKey))
+	case kms.ECDSAP256TypeDER, kms.ECDSAP384TypeDER, kms.ECDSAP521TypeDER:
+		// The KMS exports ECDSA DER keys in PKIX format.
+		return pubKey, nil
+	case kms.ECDSAP256TypeIEEEP1363, kms.ECDSAP384TypeIEEEP1363, kms.ECDSAP521TypeIEEEP1363:
+		curve := curveForKeyType(keyType)
+
+		x, y := elliptic.Unmarshal(curve, pubKey)
+		if x == nil {
+			return nil, fmt.Errorf("invalid %s public key", keyType)
+		}
+
+		return x509.MarshalPKIXPublicKey(&ecdsa.PublicKey{Curve: curve, X: x, Y: y})
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyType, keyType)
+	}
+}
+
+func curveForKeyType(keyType kms.KeyType) elliptic.Curve {
+	switch keyType {
+	case kms.ECDSAP384TypeIEEEP1363:
+		return elliptic.P384()
+	case kms.ECDSAP521TypeIEEEP1363:
+		return elliptic.P521()
+	default:
+		return elliptic.P256()
+	}
+}
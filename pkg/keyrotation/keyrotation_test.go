@@ -0,0 +1,388 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyrotation
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/mocks"
+	"github.com/trustbloc/orb/pkg/activitypub/vocab"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+	storemocks "github.com/trustbloc/orb/pkg/store/mocks"
+)
+
+const (
+	kmsKeyID1 = "kms-key-1"
+	kmsKeyID2 = "kms-key-2"
+	mainKeyID = "main-key"
+)
+
+var serviceIRI = testutil.MustParseURL("https://example.com/services/orb")
+
+func TestNew(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		m, err := New(&Config{ServiceIRI: serviceIRI}, newMockKMS(t), newStore(t), kmsKeyID1, mainKeyID, newOutboxProvider())
+		require.NoError(t, err)
+		require.NotNil(t, m)
+		require.Equal(t, defaultOverlapPeriod, m.OverlapPeriod)
+
+		require.Equal(t, kmsKeyID1, m.CurrentKey().KMSKeyID)
+		require.Equal(t, "https://example.com/services/orb/keys/main-key", m.PublicKeyID().String())
+		require.Equal(t, serviceIRI.String(), m.PublicKey().Owner.String())
+		require.Contains(t, m.PublicKey().PublicKeyPem, "BEGIN PUBLIC KEY")
+		require.Len(t, m.PublicKeys(), 1)
+
+		keyID, err := m.ResolveSigningKey(m.PublicKeyID().String())
+		require.NoError(t, err)
+		require.Equal(t, kmsKeyID1, keyID)
+	})
+
+	t.Run("Export public key error", func(t *testing.T) {
+		km := &mockkms.KeyManager{ExportPubKeyBytesErr: errors.New("injected export error")}
+
+		_, err := New(&Config{ServiceIRI: serviceIRI}, km, newStore(t), kmsKeyID1, mainKeyID, newOutboxProvider())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected export error")
+	})
+}
+
+func TestManager_Rotate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ob := mocks.NewOutbox()
+
+		var activated []*Key
+
+		m, err := New(&Config{ServiceIRI: serviceIRI, KeyType: kms.ED25519Type}, newMockKMS(t), newStore(t),
+			kmsKeyID1, mainKeyID, func() Outbox { return ob },
+			func(key *Key) error {
+				activated = append(activated, key)
+
+				return nil
+			},
+		)
+		require.NoError(t, err)
+
+		var scheduled []func()
+
+		m.afterFunc = func(d time.Duration, f func()) {
+			require.Equal(t, defaultOverlapPeriod, d)
+
+			scheduled = append(scheduled, f)
+		}
+
+		m.Start()
+		defer m.Stop()
+
+		oldKeyID := m.PublicKeyID().String()
+
+		key, err := m.Rotate()
+		require.NoError(t, err)
+		require.Equal(t, kmsKeyID2, key.KMSKeyID)
+		require.Equal(t, "https://example.com/services/orb/keys/"+kmsKeyID2, key.PublicKey.ID.String())
+
+		// During the overlap period both keys are published but the old key is still used for signing.
+		require.Len(t, m.PublicKeys(), 2)
+		require.Equal(t, oldKeyID, m.PublicKeyID().String())
+
+		keyID, err := m.ResolveSigningKey(key.PublicKey.ID.String())
+		require.NoError(t, err)
+		require.Equal(t, kmsKeyID2, keyID)
+
+		_, err = m.Rotate()
+		require.True(t, errors.Is(err, ErrRotationInProgress))
+
+		require.Len(t, scheduled, 1)
+		scheduled[0]()
+
+		// The new key is used for signing and the old key is still published during the grace period.
+		require.Equal(t, key.PublicKey.ID.String(), m.PublicKeyID().String())
+		require.Len(t, m.PublicKeys(), 2)
+
+		keyID, err = m.ResolveSigningKey(oldKeyID)
+		require.NoError(t, err)
+		require.Equal(t, kmsKeyID1, keyID)
+
+		_, err = m.Rotate()
+		require.True(t, errors.Is(err, ErrRotationInProgress))
+
+		require.Len(t, scheduled, 2)
+		scheduled[1]()
+
+		// The old key is retired after the grace period but is still a verification key.
+		require.Len(t, m.PublicKeys(), 1)
+
+		_, err = m.ResolveSigningKey(oldKeyID)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not published")
+
+		active, retired := m.VerificationKeys()
+		require.Len(t, active, 1)
+		require.Equal(t, kmsKeyID2, active[0].KMSKeyID)
+		require.Len(t, retired, 1)
+		require.Equal(t, kmsKeyID1, retired[0].KMSKeyID)
+
+		require.Len(t, activated, 1)
+		require.Equal(t, kmsKeyID2, activated[0].KMSKeyID)
+
+		require.Len(t, ob.Activities(), 1)
+
+		update := ob.Activities()[0]
+		require.True(t, update.Type().Is(vocab.TypeUpdate))
+		require.Equal(t, serviceIRI.String(), update.Actor().String())
+		require.Len(t, update.To(), 1)
+		require.Equal(t, "https://example.com/services/orb/followers", update.To()[0].String())
+
+		actor := update.Object().Actor()
+		require.NotNil(t, actor)
+		require.Equal(t, key.PublicKey.ID.String(), actor.PublicKey().ID.String())
+	})
+
+	t.Run("Create key error", func(t *testing.T) {
+		km := newMockKMS(t)
+
+		m, err := New(&Config{ServiceIRI: serviceIRI}, km, newStore(t), kmsKeyID1, mainKeyID, newOutboxProvider())
+		require.NoError(t, err)
+
+		km.CreateKeyErr = errors.New("injected create error")
+
+		_, err = m.Rotate()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected create error")
+		require.Len(t, m.PublicKeys(), 1)
+	})
+
+	t.Run("Handler error -> old key still retired after grace period", func(t *testing.T) {
+		ob := mocks.NewOutbox()
+
+		m, err := New(&Config{ServiceIRI: serviceIRI}, newMockKMS(t), newStore(t), kmsKeyID1, mainKeyID,
+			func() Outbox { return ob },
+			func(key *Key) error { return errors.New("injected handler error") },
+		)
+		require.NoError(t, err)
+
+		var retire func()
+
+		m.afterFunc = func(d time.Duration, f func()) { retire = f }
+
+		key, err := m.Rotate()
+		require.NoError(t, err)
+
+		err = m.activate(key)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected handler error")
+
+		require.Equal(t, key.PublicKey.ID.String(), m.PublicKeyID().String())
+		require.Len(t, m.PublicKeys(), 2)
+		require.Empty(t, ob.Activities())
+
+		retire()
+
+		require.Len(t, m.PublicKeys(), 1)
+	})
+
+	t.Run("Outbox error", func(t *testing.T) {
+		ob := mocks.NewOutbox().WithError(errors.New("injected outbox error"))
+
+		m, err := New(&Config{ServiceIRI: serviceIRI}, newMockKMS(t), newStore(t), kmsKeyID1, mainKeyID,
+			func() Outbox { return ob })
+		require.NoError(t, err)
+
+		m.afterFunc = func(d time.Duration, f func()) {}
+
+		key, err := m.Rotate()
+		require.NoError(t, err)
+
+		err = m.activate(key)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected outbox error")
+	})
+
+	t.Run("Stopped -> key not activated", func(t *testing.T) {
+		m, err := New(&Config{ServiceIRI: serviceIRI}, newMockKMS(t), newStore(t), kmsKeyID1, mainKeyID, newOutboxProvider())
+		require.NoError(t, err)
+
+		var activate func()
+
+		m.afterFunc = func(d time.Duration, f func()) { activate = f }
+
+		m.Start()
+		m.Stop()
+
+		_, err = m.Rotate()
+		require.NoError(t, err)
+
+		activate()
+
+		require.Equal(t, kmsKeyID1, m.CurrentKey().KMSKeyID)
+	})
+}
+
+func TestManager_Restart(t *testing.T) {
+	km := newMockKMS(t)
+	s := newStore(t)
+
+	m, err := New(&Config{ServiceIRI: serviceIRI}, km, s, kmsKeyID1, mainKeyID, newOutboxProvider())
+	require.NoError(t, err)
+
+	m.afterFunc = func(d time.Duration, f func()) {}
+
+	key, err := m.Rotate()
+	require.NoError(t, err)
+
+	t.Run("Pending key is restored and activated", func(t *testing.T) {
+		// The configured key is ignored since the rotation state was persisted.
+		m2, err := New(&Config{ServiceIRI: serviceIRI}, km, s, "other-key", mainKeyID, newOutboxProvider())
+		require.NoError(t, err)
+		require.Equal(t, kmsKeyID1, m2.CurrentKey().KMSKeyID)
+		require.Len(t, m2.PublicKeys(), 2)
+
+		var scheduled []time.Duration
+
+		var activate func()
+
+		m2.afterFunc = func(d time.Duration, f func()) {
+			scheduled = append(scheduled, d)
+
+			if activate == nil {
+				activate = f
+			}
+		}
+
+		m2.Start()
+		defer m2.Stop()
+
+		require.Len(t, scheduled, 1)
+		require.True(t, scheduled[0] > 0 && scheduled[0] <= defaultOverlapPeriod)
+
+		activate()
+
+		require.Equal(t, key.PublicKey.ID.String(), m2.PublicKeyID().String())
+	})
+
+	t.Run("Activated key and previous key are restored", func(t *testing.T) {
+		m3, err := New(&Config{ServiceIRI: serviceIRI}, km, s, kmsKeyID1, mainKeyID, newOutboxProvider())
+		require.NoError(t, err)
+		require.Equal(t, kmsKeyID2, m3.CurrentKey().KMSKeyID)
+		require.Equal(t, key.PublicKey.ID.String(), m3.PublicKeyID().String())
+
+		// The previous key is still published during the grace period.
+		_, err = m3.ResolveSigningKey("https://example.com/services/orb/keys/main-key")
+		require.NoError(t, err)
+	})
+
+	t.Run("Invalid state", func(t *testing.T) {
+		s := newStore(t)
+		require.NoError(t, s.Put(stateKey, []byte("{")))
+
+		_, err := New(&Config{ServiceIRI: serviceIRI}, km, s, kmsKeyID1, mainKeyID, newOutboxProvider())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal key rotation state")
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		s := &storemocks.Store{}
+		s.GetReturns(nil, errors.New("injected get error"))
+
+		_, err := New(&Config{ServiceIRI: serviceIRI}, km, s, kmsKeyID1, mainKeyID, newOutboxProvider())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected get error")
+	})
+}
+
+func TestMarshalPublicKey(t *testing.T) {
+	t.Run("ECDSA IEEE P1363", func(t *testing.T) {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		pubKey := elliptic.Marshal(elliptic.P256(), privKey.X, privKey.Y)
+
+		der, err := marshalPublicKey(kms.ECDSAP256TypeIEEEP1363, pubKey)
+		require.NoError(t, err)
+
+		pk, err := x509.ParsePKIXPublicKey(der)
+		require.NoError(t, err)
+		require.True(t, privKey.PublicKey.Equal(pk))
+
+		_, err = marshalPublicKey(kms.ECDSAP256TypeIEEEP1363, []byte("invalid"))
+		require.Error(t, err)
+	})
+
+	t.Run("ECDSA DER", func(t *testing.T) {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		pubKey, err := x509.MarshalPKIXPublicKey(&privKey.PublicKey)
+		require.NoError(t, err)
+
+		der, err := marshalPublicKey(kms.ECDSAP256TypeDER, pubKey)
+		require.NoError(t, err)
+		require.Equal(t, pubKey, der)
+	})
+
+	t.Run("Unsupported key type", func(t *testing.T) {
+		_, err := marshalPublicKey(kms.BLS12381G2Type, []byte("key"))
+		require.True(t, errors.Is(err, ErrUnsupportedKeyType))
+	})
+}
+
+func TestManager_RotatePeriodically(t *testing.T) {
+	ob := mocks.NewOutbox()
+
+	m, err := New(&Config{
+		ServiceIRI:       serviceIRI,
+		RotationInterval: 10 * time.Millisecond,
+		OverlapPeriod:    10 * time.Millisecond,
+	}, newMockKMS(t), newStore(t), kmsKeyID1, mainKeyID, func() Outbox { return ob })
+	require.NoError(t, err)
+
+	m.Start()
+	defer m.Stop()
+
+	require.Eventually(t, func() bool {
+		return m.CurrentKey().KMSKeyID == kmsKeyID2 && len(ob.Activities()) > 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func newMockKMS(t *testing.T) *mockkms.KeyManager {
+	t.Helper()
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return &mockkms.KeyManager{
+		CreateKeyID:            kmsKeyID2,
+		ExportPubKeyBytesValue: pubKey,
+	}
+}
+
+func newStore(t *testing.T) storage.Store {
+	t.Helper()
+
+	s, err := mem.NewProvider().OpenStore("config")
+	require.NoError(t, err)
+
+	return s
+}
+
+func newOutboxProvider() func() Outbox {
+	ob := mocks.NewOutbox()
+
+	return func() Outbox { return ob }
+}
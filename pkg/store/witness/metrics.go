@@ -0,0 +1,183 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package witness
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	metricsNamespace = "witness-metrics"
+	offerKeyPrefix   = "offer-"
+)
+
+// Metrics contains the performance metrics of a witness. The promises that were kept or broken by the witness
+// are tracked by the monitoring service (see monitoring.Reliability).
+type Metrics struct {
+	Witness       string        `json:"witness"`
+	OffersSent    uint64        `json:"offers_sent"`
+	LikesReceived uint64        `json:"likes_received"`
+	LatencyCount  uint64        `json:"latency_count"`
+	TotalLatency  time.Duration `json:"total_latency"`
+}
+
+// AverageLatency returns the average amount of time between sending an offer to the witness and receiving
+// its (valid) proof. Zero is returned if the latency of the witness hasn't been measured.
+func (m *Metrics) AverageLatency() time.Duration {
+	if m.LatencyCount == 0 {
+		return 0
+	}
+
+	return m.TotalLatency / time.Duration(m.LatencyCount)
+}
+
+// ResponseRate returns the ratio of offers that resulted in a (valid) proof, in the range (0, 1]. The ratio is
+// smoothed so that a witness without any history has a rate of 1 and a single outstanding offer doesn't
+// exclude a witness.
+func (m *Metrics) ResponseRate() float64 {
+	rate := float64(m.LikesReceived+1) / float64(m.OffersSent+1)
+	if rate > 1 {
+		return 1
+	}
+
+	return rate
+}
+
+// NewMetricsStore creates a new witness metrics store.
+func NewMetricsStore(provider storage.Provider) (*MetricsStore, error) {
+	store, err := provider.OpenStore(metricsNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open witness metrics store: %w", err)
+	}
+
+	return &MetricsStore{
+		store: store,
+	}, nil
+}
+
+// MetricsStore records the number of offers sent to each witness, the number of proofs (likes) received and
+// the latency of the proofs, i.e. the time between sending the offer and receiving the proof.
+type MetricsStore struct {
+	store storage.Store
+	mutex sync.Mutex
+}
+
+// OffersSent records that the offer for the given anchor credential was sent to each of the given witnesses
+// at the given time.
+func (s *MetricsStore) OffersSent(vcID string, witnesses []string, sentAt time.Time) error {
+	value, err := json.Marshal(sentAt)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer time for vcID[%s]: %w", vcID, err)
+	}
+
+	err = s.store.Put(offerKeyPrefix+vcID, value)
+	if err != nil {
+		return fmt.Errorf("failed to store offer time for vcID[%s]: %w", vcID, err)
+	}
+
+	for _, witness := range witnesses {
+		err := s.update(witness, func(m *Metrics) { m.OffersSent++ })
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LikeReceived records that a (valid) proof for the given anchor credential was received from the witness.
+// The latency isn't measured if the time that the offer was sent wasn't recorded.
+func (s *MetricsStore) LikeReceived(witness, vcID string) error {
+	latency, err := s.offerLatency(vcID)
+	if err != nil {
+		return err
+	}
+
+	return s.update(witness, func(m *Metrics) {
+		m.LikesReceived++
+
+		if latency > 0 {
+			m.LatencyCount++
+			m.TotalLatency += latency
+		}
+	})
+}
+
+// Get returns the metrics of the given witness. Empty metrics are returned for a witness without any history.
+func (s *MetricsStore) Get(witness string) (*Metrics, error) {
+	value, err := s.store.Get(witness)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return &Metrics{Witness: witness}, nil
+		}
+
+		return nil, fmt.Errorf("failed to get metrics for witness[%s]: %w", witness, err)
+	}
+
+	m := &Metrics{}
+
+	err = json.Unmarshal(value, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metrics for witness[%s]: %w", witness, err)
+	}
+
+	return m, nil
+}
+
+func (s *MetricsStore) offerLatency(vcID string) (time.Duration, error) {
+	value, err := s.store.Get(offerKeyPrefix + vcID)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			logger.Debugf("offer time not found for vcID[%s]", vcID)
+
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to get offer time for vcID[%s]: %w", vcID, err)
+	}
+
+	var sentAt time.Time
+
+	err = json.Unmarshal(value, &sentAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal offer time for vcID[%s]: %w", vcID, err)
+	}
+
+	return time.Since(sentAt), nil
+}
+
+func (s *MetricsStore) update(witness string, update func(m *Metrics)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, err := s.Get(witness)
+	if err != nil {
+		return err
+	}
+
+	update(m)
+
+	value, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics for witness[%s]: %w", witness, err)
+	}
+
+	err = s.store.Put(witness, value)
+	if err != nil {
+		return fmt.Errorf("failed to store metrics for witness[%s]: %w", witness, err)
+	}
+
+	logger.Debugf("updated metrics for witness[%s]: %+v", witness, m)
+
+	return nil
+}
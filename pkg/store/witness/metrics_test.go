@@ -0,0 +1,140 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package witness
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/orb/pkg/store/mocks"
+)
+
+func TestNewMetricsStore(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		s, err := NewMetricsStore(mem.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, s)
+	})
+
+	t.Run("error - open store fails", func(t *testing.T) {
+		provider := &mocks.Provider{}
+		provider.OpenStoreReturns(nil, fmt.Errorf("open store error"))
+
+		s, err := NewMetricsStore(provider)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to open witness metrics store: open store error")
+		require.Nil(t, s)
+	})
+}
+
+func TestMetricsStore(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		s, err := NewMetricsStore(mem.NewProvider())
+		require.NoError(t, err)
+
+		m, err := s.Get(witness)
+		require.NoError(t, err)
+		require.Equal(t, witness, m.Witness)
+		require.Zero(t, m.OffersSent)
+		require.Zero(t, m.AverageLatency())
+		require.Equal(t, float64(1), m.ResponseRate())
+
+		now := time.Now()
+
+		require.NoError(t, s.OffersSent("vc1", []string{witness, "witness2"}, now.Add(-3*time.Second)))
+		require.NoError(t, s.OffersSent("vc2", []string{witness}, now.Add(-time.Second)))
+		require.NoError(t, s.LikeReceived(witness, "vc1"))
+		require.NoError(t, s.LikeReceived(witness, "vc2"))
+
+		// The offer time of vc3 wasn't recorded so the latency isn't measured.
+		require.NoError(t, s.LikeReceived(witness, "vc3"))
+
+		m, err = s.Get(witness)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), m.OffersSent)
+		require.Equal(t, uint64(3), m.LikesReceived)
+		require.Equal(t, uint64(2), m.LatencyCount)
+		require.GreaterOrEqual(t, int64(m.AverageLatency()), int64(2*time.Second))
+		require.Less(t, int64(m.AverageLatency()), int64(3*time.Second))
+		require.Equal(t, float64(1), m.ResponseRate())
+
+		m, err = s.Get("witness2")
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), m.OffersSent)
+		require.Equal(t, 0.5, m.ResponseRate())
+	})
+
+	t.Run("error - store get error", func(t *testing.T) {
+		store := &mocks.Store{}
+		store.GetReturns(nil, fmt.Errorf("get error"))
+
+		provider := &mocks.Provider{}
+		provider.OpenStoreReturns(store, nil)
+
+		s, err := NewMetricsStore(provider)
+		require.NoError(t, err)
+
+		_, err = s.Get(witness)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get metrics for witness[witness]: get error")
+
+		err = s.LikeReceived(witness, "vc1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get offer time for vcID[vc1]: get error")
+	})
+
+	t.Run("error - store put error", func(t *testing.T) {
+		store := &mocks.Store{}
+		store.GetReturns(nil, storage.ErrDataNotFound)
+		store.PutReturns(fmt.Errorf("put error"))
+
+		provider := &mocks.Provider{}
+		provider.OpenStoreReturns(store, nil)
+
+		s, err := NewMetricsStore(provider)
+		require.NoError(t, err)
+
+		err = s.OffersSent("vc1", []string{witness}, time.Now())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store offer time for vcID[vc1]: put error")
+
+		err = s.LikeReceived(witness, "vc1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store metrics for witness[witness]: put error")
+	})
+
+	t.Run("error - unmarshal error", func(t *testing.T) {
+		store := &mocks.Store{}
+		store.GetReturns([]byte("{"), nil)
+
+		provider := &mocks.Provider{}
+		provider.OpenStoreReturns(store, nil)
+
+		s, err := NewMetricsStore(provider)
+		require.NoError(t, err)
+
+		_, err = s.Get(witness)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal metrics for witness[witness]")
+
+		err = s.LikeReceived(witness, "vc1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal offer time for vcID[vc1]")
+	})
+}
+
+func TestMetrics_ResponseRate(t *testing.T) {
+	require.Equal(t, float64(1), (&Metrics{}).ResponseRate())
+	require.Equal(t, float64(1), (&Metrics{OffersSent: 1, LikesReceived: 2}).ResponseRate())
+	require.Equal(t, 0.5, (&Metrics{OffersSent: 3, LikesReceived: 1}).ResponseRate())
+	require.Equal(t, 0.1, (&Metrics{OffersSent: 9}).ResponseRate())
+}
@@ -9,6 +9,7 @@ package witness
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -25,6 +26,9 @@ const (
 
 var logger = log.New("witness-store")
 
+// ErrNotFound is returned if there are no witnesses for a given anchor credential.
+var ErrNotFound = errors.New("not found in the store")
+
 // New creates new anchor credential witness store.
 func New(provider storage.Provider) (*Store, error) {
 	store, err := provider.OpenStore(namespace)
@@ -139,7 +143,7 @@ func (s *Store) Get(vcID string) ([]*proof.WitnessProof, error) {
 	logger.Debugf("retrieved %d witnesses for vcID[%s]", len(witnesses), vcID)
 
 	if len(witnesses) == 0 {
-		return nil, fmt.Errorf("vcID[%s] not found in the store", vcID)
+		return nil, fmt.Errorf("vcID[%s] %w", vcID, ErrNotFound)
 	}
 
 	return witnesses, nil
@@ -193,6 +197,11 @@ func (s *Store) AddProof(vcID, witness string, p []byte) error {
 
 			w.Proof = p
 
+			value, err = json.Marshal(w)
+			if err != nil {
+				return fmt.Errorf("failed to marshal anchor credential witness for vcID[%s]: %w", vcID, err)
+			}
+
 			err = s.store.Put(key, value, storage.Tag{Name: vcIndex, Value: vcIDEncoded})
 			if err != nil {
 				return fmt.Errorf("failed to add proof for anchor credential vcID[%s] and witness[%s]: %w",
@@ -203,6 +212,11 @@ func (s *Store) AddProof(vcID, witness string, p []byte) error {
 
 			return nil
 		}
+
+		ok, err = iter.Next()
+		if err != nil {
+			return fmt.Errorf("iterator error for vcID[%s] : %w", vcID, err)
+		}
 	}
 
 	return fmt.Errorf("witness[%s] not found for vcID[%s]", witness, vcID)
@@ -9,6 +9,7 @@ package witness
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -106,6 +107,7 @@ func TestStore_Get(t *testing.T) {
 		require.Error(t, err)
 		require.Empty(t, ops)
 		require.Contains(t, err.Error(), "not found")
+		require.True(t, errors.Is(err, ErrNotFound))
 	})
 
 	t.Run("error - store error ", func(t *testing.T) {
@@ -213,6 +215,41 @@ func TestStore_AddProof(t *testing.T) {
 		bytes.Equal(wf, witnesses[0].Proof)
 	})
 
+	t.Run("success - multiple witnesses", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		s, err := New(provider)
+		require.NoError(t, err)
+
+		err = s.Put(vcID, []*proof.WitnessProof{
+			{Type: proof.TypeBatch, Witness: "witness1"},
+			{Type: proof.TypeSystem, Witness: "witness2"},
+			{Type: proof.TypeSystem, Witness: "witness3"},
+		})
+		require.NoError(t, err)
+
+		wf := []byte(witnessProof)
+
+		require.NoError(t, s.AddProof(vcID, "witness3", wf))
+		require.NoError(t, s.AddProof(vcID, "witness1", wf))
+
+		witnesses, err := s.Get(vcID)
+		require.NoError(t, err)
+		require.Len(t, witnesses, 3)
+
+		for _, w := range witnesses {
+			if w.Witness == "witness2" {
+				require.Empty(t, w.Proof)
+			} else {
+				require.Equal(t, wf, w.Proof)
+			}
+		}
+
+		err = s.AddProof(vcID, "witness4", wf)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "witness[witness4] not found")
+	})
+
 	t.Run("error - witness not found", func(t *testing.T) {
 		provider := mem.NewProvider()
 
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
@@ -79,6 +80,29 @@ func verifySigningParams(params SigningParams) error {
 type Signer struct {
 	*Providers
 	params SigningParams
+	mutex  sync.RWMutex
+}
+
+// SetVerificationMethod sets the verification method that is used for all subsequent signatures.
+// This function is invoked when the signing key is rotated.
+func (s *Signer) SetVerificationMethod(verificationMethod string) error {
+	if verificationMethod == "" {
+		return errors.New("missing verification method")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.params.VerificationMethod = verificationMethod
+
+	return nil
+}
+
+func (s *Signer) getParams() SigningParams {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.params
 }
 
 // Opt represents option for Sign fn.
@@ -121,29 +145,31 @@ func (s *Signer) Sign(vc *verifiable.Credential, opts ...Opt) (*verifiable.Crede
 }
 
 func (s *Signer) getLinkedDataProofContext(opts ...Opt) (*verifiable.LinkedDataProofContext, error) {
-	kmsSigner, err := s.getKMSSigner()
+	params := s.getParams()
+
+	kmsSigner, err := s.getKMSSigner(params.VerificationMethod)
 	if err != nil {
 		return nil, err
 	}
 
 	var signatureSuite ariessigner.SignatureSuite
 
-	switch s.params.SignatureSuite {
+	switch params.SignatureSuite {
 	case Ed25519Signature2018:
 		signatureSuite = ed25519signature2018.New(suite.WithSigner(kmsSigner))
 	case JSONWebSignature2020:
 		signatureSuite = jsonwebsignature2020.New(suite.WithSigner(kmsSigner))
 	default:
-		return nil, fmt.Errorf("signature type not supported: %s", s.params.SignatureSuite)
+		return nil, fmt.Errorf("signature type not supported: %s", params.SignatureSuite)
 	}
 
 	now := time.Now()
 
 	signingCtx := &verifiable.LinkedDataProofContext{
-		Domain:                  s.params.Domain,
-		VerificationMethod:      s.params.VerificationMethod,
+		Domain:                  params.Domain,
+		VerificationMethod:      params.VerificationMethod,
 		SignatureRepresentation: verifiable.SignatureJWS,
-		SignatureType:           s.params.SignatureSuite,
+		SignatureType:           params.SignatureSuite,
 		Suite:                   signatureSuite,
 		Purpose:                 AssertionMethod,
 		Created:                 &now,
@@ -157,8 +183,8 @@ func (s *Signer) getLinkedDataProofContext(opts ...Opt) (*verifiable.LinkedDataP
 }
 
 // getKMSSigner returns new KMS signer based on verification method.
-func (s *Signer) getKMSSigner() (signer, error) {
-	kmsSigner, err := newKMSSigner(s.Providers.KeyManager, s.Providers.Crypto, s.params.VerificationMethod)
+func (s *Signer) getKMSSigner(verificationMethod string) (signer, error) {
+	kmsSigner, err := newKMSSigner(s.Providers.KeyManager, s.Providers.Crypto, verificationMethod)
 	if err != nil {
 		return nil, err
 	}
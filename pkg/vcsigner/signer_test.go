@@ -173,6 +173,41 @@ func TestSigner_Sign(t *testing.T) {
 	})
 }
 
+func TestSigner_SetVerificationMethod(t *testing.T) {
+	signingParams := SigningParams{
+		VerificationMethod: "did:abc:123#key1",
+		SignatureSuite:     JSONWebSignature2020,
+		Domain:             "domain",
+	}
+
+	providers := &Providers{
+		KeyManager: &mockkms.KeyManager{},
+		Crypto:     &cryptomock.Crypto{},
+		DocLoader:  getLoader(t),
+	}
+
+	t.Run("success", func(t *testing.T) {
+		s, err := New(providers, signingParams)
+		require.NoError(t, err)
+
+		require.NoError(t, s.SetVerificationMethod("did:abc:123#key2"))
+
+		signedVC, err := s.Sign(&verifiable.Credential{ID: "http://example.edu/credentials/1872"})
+		require.NoError(t, err)
+		require.Len(t, signedVC.Proofs, 1)
+		require.Equal(t, "did:abc:123#key2", signedVC.Proofs[0]["verificationMethod"])
+	})
+
+	t.Run("error - missing verification method", func(t *testing.T) {
+		s, err := New(providers, signingParams)
+		require.NoError(t, err)
+
+		err = s.SetVerificationMethod("")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing verification method")
+	})
+}
+
 func TestSigner_verifySigningParams(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		signingParams := SigningParams{
@@ -0,0 +1,214 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vctlog
+
+import (
+	"fmt"
+
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+// nodeStore persists the hashes of the perfect (complete) subtrees of the Merkle tree.
+type nodeStore interface {
+	getNode(level uint, index uint64) ([]byte, error)
+}
+
+// merkleTree implements the RFC 6962 Merkle tree algorithms (tree hash, audit path and consistency proof)
+// on top of the stored hashes of the perfect subtrees. Since any range of leaves decomposes into at most
+// O(log n) perfect subtrees, none of the algorithms needs to visit the individual leaves.
+type merkleTree struct {
+	nodes nodeStore
+}
+
+// nodesForLeaf returns the operations that store the hash of the leaf at the given index along with the
+// hashes of the perfect subtrees that are completed by the leaf.
+func (t *merkleTree) nodesForLeaf(index uint64, leafHash []byte) ([]storage.Operation, error) {
+	ops := []storage.Operation{{Key: nodeKey(0, index), Value: leafHash}}
+
+	hash := leafHash
+
+	for level := uint(0); index%2 == 1; level++ {
+		left, err := t.nodes.getNode(level, index-1)
+		if err != nil {
+			return nil, fmt.Errorf("get node at level %d, index %d: %w", level, index-1, err)
+		}
+
+		hash = hasher.DefaultHasher.HashChildren(left, hash)
+		index /= 2
+
+		ops = append(ops, storage.Operation{Key: nodeKey(level+1, index), Value: hash})
+	}
+
+	return ops, nil
+}
+
+// rootHash returns the Merkle tree hash of the tree with the given size.
+func (t *merkleTree) rootHash(treeSize uint64) ([]byte, error) {
+	return t.subtreeHash(0, treeSize)
+}
+
+// inclusionProof returns the audit path (ordered from the leaf up) for the leaf at the given index
+// in the tree with the given size.
+func (t *merkleTree) inclusionProof(index, treeSize uint64) ([][]byte, error) {
+	if index >= treeSize {
+		return nil, fmt.Errorf("leaf index %d is not less than tree size %d", index, treeSize)
+	}
+
+	return t.path(index, 0, treeSize)
+}
+
+// consistencyProof returns the proof that the tree with size 'first' is a prefix of the tree with
+// size 'second'.
+func (t *merkleTree) consistencyProof(first, second uint64) ([][]byte, error) {
+	if first > second {
+		return nil, fmt.Errorf("first tree size %d is greater than second tree size %d", first, second)
+	}
+
+	if first == 0 || first == second {
+		return [][]byte{}, nil
+	}
+
+	return t.subProof(first, 0, second, true)
+}
+
+// path implements PATH(m, D[start:start+n]) from RFC 6962, section 2.1.1.
+func (t *merkleTree) path(m, start, n uint64) ([][]byte, error) {
+	if n <= 1 {
+		return [][]byte{}, nil
+	}
+
+	k := splitPoint(n)
+
+	var (
+		proof   [][]byte
+		sibling []byte
+		err     error
+	)
+
+	if m < k {
+		proof, err = t.path(m, start, k)
+		if err != nil {
+			return nil, err
+		}
+
+		sibling, err = t.subtreeHash(start+k, n-k)
+	} else {
+		proof, err = t.path(m-k, start+k, n-k)
+		if err != nil {
+			return nil, err
+		}
+
+		sibling, err = t.subtreeHash(start, k)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(proof, sibling), nil
+}
+
+// subProof implements SUBPROOF(m, D[start:start+n], b) from RFC 6962, section 2.1.2.
+func (t *merkleTree) subProof(m, start, n uint64, complete bool) ([][]byte, error) {
+	if m == n {
+		if complete {
+			return [][]byte{}, nil
+		}
+
+		hash, err := t.subtreeHash(start, n)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{hash}, nil
+	}
+
+	k := splitPoint(n)
+
+	var (
+		proof   [][]byte
+		sibling []byte
+		err     error
+	)
+
+	if m <= k {
+		proof, err = t.subProof(m, start, k, complete)
+		if err != nil {
+			return nil, err
+		}
+
+		sibling, err = t.subtreeHash(start+k, n-k)
+	} else {
+		proof, err = t.subProof(m-k, start+k, n-k, false)
+		if err != nil {
+			return nil, err
+		}
+
+		sibling, err = t.subtreeHash(start, k)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(proof, sibling), nil
+}
+
+// subtreeHash returns MTH(D[start:start+n]). The hash of a perfect subtree is read from the store.
+func (t *merkleTree) subtreeHash(start, n uint64) ([]byte, error) {
+	if n == 0 {
+		return hasher.DefaultHasher.EmptyRoot(), nil
+	}
+
+	if isPowerOfTwo(n) && start%n == 0 {
+		level := uint(0)
+		for size := n; size > 1; size >>= 1 {
+			level++
+		}
+
+		hash, err := t.nodes.getNode(level, start/n)
+		if err != nil {
+			return nil, fmt.Errorf("get node at level %d, index %d: %w", level, start/n, err)
+		}
+
+		return hash, nil
+	}
+
+	k := splitPoint(n)
+
+	left, err := t.subtreeHash(start, k)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := t.subtreeHash(start+k, n-k)
+	if err != nil {
+		return nil, err
+	}
+
+	return hasher.DefaultHasher.HashChildren(left, right), nil
+}
+
+// splitPoint returns the largest power of two that is less than n (n > 1).
+func splitPoint(n uint64) uint64 {
+	k := uint64(1)
+
+	for k<<1 < n {
+		k <<= 1
+	}
+
+	return k
+}
+
+func isPowerOfTwo(n uint64) bool {
+	return n&(n-1) == 0
+}
+
+func nodeKey(level uint, index uint64) string {
+	return fmt.Sprintf("%s%d-%d", nodeKeyPrefix, level, index)
+}
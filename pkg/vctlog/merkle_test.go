@@ -0,0 +1,150 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vctlog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/stretchr/testify/require"
+)
+
+const numLeaves = 33
+
+func TestMerkleTree_RootHash(t *testing.T) {
+	tree, leaves := newTestTree(t, numLeaves)
+
+	for size := uint64(0); size <= numLeaves; size++ {
+		root, err := tree.rootHash(size)
+		require.NoError(t, err)
+		require.Equalf(t, referenceRoot(leaves[:size]), root, "tree size %d", size)
+	}
+}
+
+func TestMerkleTree_InclusionProof(t *testing.T) {
+	tree, leaves := newTestTree(t, numLeaves)
+
+	verifier := logverifier.New(hasher.DefaultHasher)
+
+	for size := uint64(1); size <= numLeaves; size++ {
+		root := referenceRoot(leaves[:size])
+
+		for index := uint64(0); index < size; index++ {
+			proof, err := tree.inclusionProof(index, size)
+			require.NoError(t, err)
+
+			require.NoErrorf(t, verifier.VerifyInclusionProof(int64(index), int64(size), proof, root, leaves[index]),
+				"index %d, tree size %d", index, size)
+		}
+	}
+
+	_, err := tree.inclusionProof(3, 3)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "leaf index 3 is not less than tree size 3")
+}
+
+func TestMerkleTree_ConsistencyProof(t *testing.T) {
+	tree, leaves := newTestTree(t, numLeaves)
+
+	verifier := logverifier.New(hasher.DefaultHasher)
+
+	for second := uint64(1); second <= numLeaves; second++ {
+		root2 := referenceRoot(leaves[:second])
+
+		for first := uint64(1); first <= second; first++ {
+			proof, err := tree.consistencyProof(first, second)
+			require.NoError(t, err)
+
+			require.NoErrorf(t,
+				verifier.VerifyConsistencyProof(int64(first), int64(second), referenceRoot(leaves[:first]), root2, proof),
+				"first %d, second %d", first, second)
+		}
+	}
+
+	proof, err := tree.consistencyProof(0, 5)
+	require.NoError(t, err)
+	require.Empty(t, proof)
+
+	_, err = tree.consistencyProof(5, 3)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "first tree size 5 is greater than second tree size 3")
+}
+
+func TestMerkleTree_Error(t *testing.T) {
+	errExpected := errors.New("injected node store error")
+
+	tree := &merkleTree{nodes: &memNodeStore{err: errExpected}}
+
+	_, err := tree.nodesForLeaf(1, []byte("leaf"))
+	require.True(t, errors.Is(err, errExpected))
+
+	_, err = tree.rootHash(3)
+	require.True(t, errors.Is(err, errExpected))
+
+	_, err = tree.inclusionProof(1, 3)
+	require.True(t, errors.Is(err, errExpected))
+
+	_, err = tree.consistencyProof(1, 3)
+	require.True(t, errors.Is(err, errExpected))
+}
+
+func newTestTree(t *testing.T, n int) (*merkleTree, [][]byte) {
+	t.Helper()
+
+	nodes := &memNodeStore{nodes: make(map[string][]byte)}
+	tree := &merkleTree{nodes: nodes}
+
+	leaves := make([][]byte, n)
+
+	for i := 0; i < n; i++ {
+		leaves[i] = hasher.DefaultHasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", i)))
+
+		ops, err := tree.nodesForLeaf(uint64(i), leaves[i])
+		require.NoError(t, err)
+
+		for _, op := range ops {
+			nodes.nodes[op.Key] = op.Value
+		}
+	}
+
+	return tree, leaves
+}
+
+// referenceRoot computes the Merkle tree hash directly from the leaf hashes.
+func referenceRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		return hasher.DefaultHasher.EmptyRoot()
+	case 1:
+		return leaves[0]
+	}
+
+	k := splitPoint(uint64(len(leaves)))
+
+	return hasher.DefaultHasher.HashChildren(referenceRoot(leaves[:k]), referenceRoot(leaves[k:]))
+}
+
+type memNodeStore struct {
+	nodes map[string][]byte
+	err   error
+}
+
+func (s *memNodeStore) getNode(level uint, index uint64) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	node, ok := s.nodes[nodeKey(level, index)]
+	if !ok {
+		return nil, fmt.Errorf("node %d-%d not found", level, index)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vctlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	vcterrors "github.com/trustbloc/vct/pkg/controller/errors"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// HTTPHandlers returns the handlers for the (read-only) VCT REST API of the log, so that others may
+// retrieve the signed tree head and check the proofs that were issued by the log. Credentials may only
+// be added to the log by this server (via Witness) so the 'add-vc' endpoint isn't exposed.
+func (l *Log) HTTPHandlers() []common.HTTPHandler {
+	var handlers []common.HTTPHandler
+
+	for _, h := range rest.New(&restCmd{log: l}).GetRESTHandlers() {
+		if h.Path() == rest.AddVCPath || h.Path() == rest.HealthCheckPath {
+			continue
+		}
+
+		handlers = append(handlers, &httpHandler{handler: h})
+	}
+
+	return handlers
+}
+
+// httpHandler adapts a VCT REST handler to a Sidetree HTTP handler.
+type httpHandler struct {
+	handler rest.Handler
+}
+
+// Path returns the HTTP request path.
+func (h *httpHandler) Path() string {
+	return h.handler.Path()
+}
+
+// Method returns the HTTP request method.
+func (h *httpHandler) Method() string {
+	return h.handler.Method()
+}
+
+// Handler returns the HTTP request handler.
+func (h *httpHandler) Handler() common.HTTPRequestHandler {
+	return common.HTTPRequestHandler(h.handler.Handle())
+}
+
+// restCmd implements the commands of the VCT REST controller using the log.
+type restCmd struct {
+	log *Log
+}
+
+func (c *restCmd) AddVC(io.Writer, io.Reader) error {
+	return fmt.Errorf("%w: adding credentials to the log is not supported", vcterrors.ErrBadRequest)
+}
+
+func (c *restCmd) GetIssuers(w io.Writer, _ io.Reader) error {
+	return json.NewEncoder(w).Encode([]string{})
+}
+
+func (c *restCmd) GetPublicKey(w io.Writer, _ io.Reader) error {
+	return json.NewEncoder(w).Encode(c.log.GetPublicKey())
+}
+
+func (c *restCmd) GetSTH(w io.Writer, _ io.Reader) error {
+	resp, err := c.log.GetSTH()
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(resp)
+}
+
+func (c *restCmd) GetSTHConsistency(w io.Writer, r io.Reader) error {
+	request := &command.GetSTHConsistencyRequest{}
+
+	if err := decode(r, request, request.Validate); err != nil {
+		return err
+	}
+
+	resp, err := c.log.GetSTHConsistency(uint64(request.FirstTreeSize), uint64(request.SecondTreeSize))
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(resp)
+}
+
+func (c *restCmd) GetProofByHash(w io.Writer, r io.Reader) error {
+	request := &command.GetProofByHashRequest{}
+
+	if err := decode(r, request, request.Validate); err != nil {
+		return err
+	}
+
+	resp, err := c.log.GetProofByHash(request.Hash, uint64(request.TreeSize))
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(resp)
+}
+
+func (c *restCmd) GetEntries(w io.Writer, r io.Reader) error {
+	request := &command.GetEntriesRequest{}
+
+	if err := decode(r, request, request.Validate); err != nil {
+		return err
+	}
+
+	resp, err := c.log.GetEntries(uint64(request.Start), uint64(request.End))
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(resp)
+}
+
+func (c *restCmd) GetEntryAndProof(w io.Writer, r io.Reader) error {
+	request := &command.GetEntryAndProofRequest{}
+
+	if err := decode(r, request, request.Validate); err != nil {
+		return err
+	}
+
+	resp, err := c.log.GetEntryAndProof(uint64(request.LeafIndex), uint64(request.TreeSize))
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(resp)
+}
+
+func decode(r io.Reader, request interface{}, validate func() error) error {
+	if err := json.NewDecoder(r).Decode(request); err != nil {
+		return fmt.Errorf("%w: decode request: %s", vcterrors.ErrBadRequest, err)
+	}
+
+	return validate()
+}
@@ -0,0 +1,102 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vctlog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/require"
+	vctclient "github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+func TestLog_HTTPHandlers(t *testing.T) {
+	l, _ := newTestLog(t, mem.NewProvider())
+
+	handlers := l.HTTPHandlers()
+	require.Len(t, handlers, 7)
+
+	router := mux.NewRouter()
+
+	for _, h := range handlers {
+		require.NotEqual(t, rest.AddVCPath, h.Path())
+
+		router.HandleFunc(h.Path(), h.Handler()).Methods(h.Method())
+	}
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Use the VCT client to show that the log exposes the same REST API as VCT.
+	client := vctclient.New(server.URL, vctclient.WithHTTPClient(http.DefaultClient))
+
+	var leafHashes []string
+
+	for i := 0; i < 3; i++ {
+		vcBytes := []byte(fmt.Sprintf(vcTemplate, i, i))
+
+		resp, err := l.AddVC(parseCredential(t, vcBytes))
+		require.NoError(t, err)
+
+		leafHashes = append(leafHashes,
+			calculateLeafHash(t, time.Unix(0, int64(resp.Timestamp)*int64(time.Millisecond)), vcBytes))
+	}
+
+	ctx := context.Background()
+
+	sth, err := client.GetSTH(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), sth.TreeSize)
+
+	pubKey, err := client.GetPublicKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, l.GetPublicKey(), pubKey)
+
+	proof, err := client.GetProofByHash(ctx, leafHashes[1], sth.TreeSize)
+	require.NoError(t, err)
+	require.NoError(t, logverifier.New(hasher.DefaultHasher).VerifyInclusionProof(proof.LeafIndex,
+		int64(sth.TreeSize), proof.AuditPath, sth.SHA256RootHash, decodeHash(t, leafHashes[1])))
+
+	consistency, err := client.GetSTHConsistency(ctx, 1, 3)
+	require.NoError(t, err)
+	require.NotEmpty(t, consistency.Consistency)
+
+	entries, err := client.GetEntries(ctx, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, entries.Entries, 3)
+
+	entry, err := client.GetEntryAndProof(ctx, 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, entries.Entries[2].LeafInput, entry.LeafInput)
+
+	issuers, err := client.GetIssuers(ctx)
+	require.NoError(t, err)
+	require.Empty(t, issuers)
+
+	_, err = client.GetProofByHash(ctx, leafHashes[1], 4)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+
+	_, err = client.GetEntries(ctx, 2, 1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "validation failed")
+}
+
+func TestRestCmd_AddVC(t *testing.T) {
+	err := (&restCmd{}).AddVC(nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not supported")
+}
@@ -0,0 +1,760 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vctlog
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/piprate/json-gold/ld"
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	vcterrors "github.com/trustbloc/vct/pkg/controller/errors"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/lifecycle"
+	"github.com/trustbloc/orb/pkg/activitypub/service/vct"
+	"github.com/trustbloc/orb/pkg/vcsigner"
+)
+
+var logger = log.New("vct-log")
+
+// ErrNotWriter is returned when the log has been claimed for writing by another Log instance.
+var ErrNotWriter = errors.New("transparency log has been claimed by another writer")
+
+const (
+	storeName = "vct-log"
+
+	headKey        = "head"
+	writerKey      = "writer"
+	leafKeyPrefix  = "leaf-"
+	hashKeyPrefix  = "hash-"
+	entryKeyPrefix = "entry-"
+	nodeKeyPrefix  = "node-"
+
+	maxEntriesRange = 1000
+
+	defaultLeaseTTL        = 30 * time.Second
+	defaultClaimSettleTime = 2 * time.Second
+	leaseRenewalsPerTTL    = 3
+
+	defaultKeyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctxSecurity = "https://w3id.org/security/v1"
+	ctxJWS      = "https://w3id.org/jws/v1"
+)
+
+type signer interface {
+	Sign(vc *verifiable.Credential, opts ...vcsigner.Opt) (*verifiable.Credential, error)
+}
+
+type keyManager interface {
+	Get(keyID string) (interface{}, error)
+	ExportPubKeyBytes(keyID string) ([]byte, error)
+}
+
+type crypto interface {
+	Sign(msg []byte, kh interface{}) ([]byte, error)
+}
+
+// Config holds the configuration for the transparency log.
+type Config struct {
+	// Endpoint is the URL at which the log's REST API is exposed. It is used as the domain of the proofs
+	// that are created by the log so that the proofs may be checked by others.
+	Endpoint string
+	// KeyID is the ID of the KMS key that is used to sign the VC timestamps and the tree heads.
+	KeyID string
+	// KeyType is the type of the signing key. Only ECDSA P-256 keys are supported.
+	KeyType kms.KeyType
+	// DocumentLoader is the JSON-LD document loader used to parse the witnessed credentials.
+	DocumentLoader ld.DocumentLoader
+	// LeaseTTL is the duration of the writer's claim on the log. The claim is renewed while the log is running.
+	// Defaults to 30 seconds.
+	LeaseTTL time.Duration
+	// ClaimSettleTime is the time to wait after claiming the log before verifying that the claim wasn't
+	// overwritten by another instance that started at the same time. Defaults to 2 seconds.
+	ClaimSettleTime time.Duration
+	// WriterID is the stable identity of this instance, for example derived from the host name. An instance
+	// with the same writer ID (e.g. the same server after a restart) may re-claim the log immediately even if
+	// its previous lease hasn't expired. Defaults to a random ID.
+	WriterID string
+}
+
+// Log is an in-process verifiable credential transparency log which is backed by a Merkle tree (RFC 6962)
+// and is persisted using the storage provider. The log is a witness of anchor credentials and it supports
+// the same queries (signed tree head, proofs, entries) as the VCT server.
+//
+// The log supports a single writer. Appends are serialized within the process but the storage provider doesn't
+// support conditional updates, so concurrent appends from multiple processes would corrupt the tree. In order to
+// prevent this, a Log claims the log in the store with a lease that's renewed while the Log is running (see Start)
+// and released when it's stopped. If another instance holds an unexpired lease then New waits for the lease to
+// expire (for example, if the instance crashed) and fails if the lease is renewed in the meantime (for example,
+// by a running server that shares the database). Reads may be served by any instance.
+type Log struct {
+	*Config
+	*lifecycle.Lifecycle
+
+	store  storage.Store
+	crypto crypto
+	signer signer
+	kh     interface{}
+	pubKey []byte
+	logID  [32]byte
+	alg    command.SignatureAndHashAlgorithm
+	tree   *merkleTree
+	writer string
+	mutex  sync.Mutex
+	now    func() time.Time
+	done   chan struct{}
+}
+
+// writerClaim is the claim of a Log instance on the log.
+type writerClaim struct {
+	Writer  string    `json:"writer"`
+	Expires time.Time `json:"expires"`
+}
+
+type treeHead struct {
+	TreeSize  uint64 `json:"tree_size"`
+	Timestamp uint64 `json:"timestamp"`
+}
+
+// New returns a new transparency log.
+func New(cfg *Config, provider storage.Provider, km keyManager, cr crypto, signer signer) (*Log, error) {
+	if cfg.KeyType == "" {
+		cfg.KeyType = defaultKeyType
+	}
+
+	if cfg.LeaseTTL == 0 {
+		cfg.LeaseTTL = defaultLeaseTTL
+	}
+
+	if cfg.ClaimSettleTime == 0 {
+		cfg.ClaimSettleTime = defaultClaimSettleTime
+	}
+
+	if cfg.WriterID == "" {
+		cfg.WriterID = uuid.New().String()
+	}
+
+	if cfg.KeyType != kms.ECDSAP256TypeIEEEP1363 && cfg.KeyType != kms.ECDSAP256TypeDER {
+		return nil, fmt.Errorf("key type %s is not supported", cfg.KeyType)
+	}
+
+	store, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
+	kh, err := km.Get(cfg.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key handle: %w", err)
+	}
+
+	pubKey, err := km.ExportPubKeyBytes(cfg.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("export public key: %w", err)
+	}
+
+	l := &Log{
+		Config: cfg,
+		store:  store,
+		crypto: cr,
+		signer: signer,
+		kh:     kh,
+		pubKey: pubKey,
+		logID:  sha256.Sum256(pubKey),
+		alg: command.SignatureAndHashAlgorithm{
+			Hash:      command.SHA256Hash,
+			Signature: command.ECDSASignature,
+			Type:      cfg.KeyType,
+		},
+		now:    time.Now,
+		writer: cfg.WriterID,
+		done:   make(chan struct{}),
+	}
+
+	l.tree = &merkleTree{nodes: l}
+
+	l.Lifecycle = lifecycle.New("vct-log",
+		lifecycle.WithStart(l.start),
+		lifecycle.WithStop(l.stop),
+	)
+
+	if err := l.claim(); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Claimed transparency log for writer [%s]", l.writer)
+
+	return l, nil
+}
+
+// claim claims the log for this instance. If another instance holds an unexpired claim then claim waits for the
+// claim to expire. An error is returned if the other instance renews its claim in the meantime or if the claim was
+// overwritten by another instance that claimed the log at the same time. (The store doesn't support conditional
+// updates, so the claim is verified after a settle time.)
+func (l *Log) claim() error {
+	current, err := l.getClaim()
+	if err != nil {
+		return err
+	}
+
+	if l.isHeldByOther(current) {
+		if err := l.awaitExpiry(current); err != nil {
+			return err
+		}
+	}
+
+	if err := l.putClaim(); err != nil {
+		return fmt.Errorf("claim log: %w", err)
+	}
+
+	time.Sleep(l.ClaimSettleTime)
+
+	return l.checkWriter()
+}
+
+// awaitExpiry waits for the given claim of another instance to expire. The other instance may have stopped without
+// releasing its claim (e.g. it crashed), in which case the claim isn't renewed. ErrNotWriter is returned if the
+// claim is still held after it was due to expire, i.e. the other instance is running.
+func (l *Log) awaitExpiry(current *writerClaim) error {
+	wait := current.Expires.Sub(l.now())
+	if wait > l.LeaseTTL {
+		wait = l.LeaseTTL
+	}
+
+	logger.Warnf("Transparency log is claimed by writer [%s] until %s. Waiting %s for the claim to expire.",
+		current.Writer, current.Expires, wait)
+
+	time.Sleep(wait)
+
+	current, err := l.getClaim()
+	if err != nil {
+		return err
+	}
+
+	if l.isHeldByOther(current) {
+		return fmt.Errorf("%w: writer [%s] holds the claim until %s. The built-in transparency log may only be "+
+			"enabled on one instance", ErrNotWriter, current.Writer, current.Expires)
+	}
+
+	return nil
+}
+
+func (l *Log) isHeldByOther(claim *writerClaim) bool {
+	return claim != nil && claim.Writer != l.writer && claim.Expires.After(l.now())
+}
+
+func (l *Log) getClaim() (*writerClaim, error) {
+	claimBytes, err := l.store.Get(writerKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("get log writer: %w", err)
+	}
+
+	claim := &writerClaim{}
+
+	if err := json.Unmarshal(claimBytes, claim); err != nil {
+		// The claim was stored by a previous version which didn't have leases.
+		logger.Warnf("Ignoring invalid claim on transparency log: %s", err)
+
+		return nil, nil
+	}
+
+	return claim, nil
+}
+
+func (l *Log) putClaim() error {
+	claimBytes, err := json.Marshal(&writerClaim{Writer: l.writer, Expires: l.now().Add(l.LeaseTTL)})
+	if err != nil {
+		return fmt.Errorf("marshal claim: %w", err)
+	}
+
+	return l.store.Put(writerKey, claimBytes)
+}
+
+func (l *Log) start() {
+	go l.renewLease()
+}
+
+// stop stops renewing the lease and releases the claim so that another instance may claim the log.
+func (l *Log) stop() {
+	close(l.done)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.checkWriter(); err != nil {
+		return
+	}
+
+	if err := l.store.Delete(writerKey); err != nil {
+		logger.Warnf("Error releasing claim on transparency log: %s", err)
+
+		return
+	}
+
+	logger.Infof("Released transparency log for writer [%s]", l.writer)
+}
+
+func (l *Log) renewLease() {
+	ticker := time.NewTicker(l.LeaseTTL / leaseRenewalsPerTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.renew(); err != nil {
+				logger.Errorf("Error renewing claim on transparency log: %s", err)
+
+				if errors.Is(err, ErrNotWriter) {
+					return
+				}
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+func (l *Log) renew() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.checkWriter(); err != nil {
+		return err
+	}
+
+	return l.putClaim()
+}
+
+// Witness adds the given anchor credential to the log and returns the log's proof for the credential.
+// The 'created' time of the proof is the timestamp of the log entry.
+func (l *Log) Witness(anchorCred []byte) ([]byte, error) {
+	vc, err := verifiable.ParseCredential(anchorCred,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(l.DocumentLoader),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("parse credential: %w", err)
+	}
+
+	resp, err := l.AddVC(vc)
+	if err != nil {
+		return nil, fmt.Errorf("add credential to log: %w", err)
+	}
+
+	// adds linked data proof
+	vc, err = l.signer.Sign(vc,
+		// sets created time from the log entry.
+		vcsigner.WithCreated(time.Unix(0, int64(resp.Timestamp)*int64(time.Millisecond))),
+		vcsigner.WithSignatureRepresentation(verifiable.SignatureJWS),
+		vcsigner.WithDomain(l.Endpoint),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("add proof to credential: %w", err)
+	}
+
+	return json.Marshal(vct.Proof{
+		Context:            []string{ctxSecurity, ctxJWS},
+		Proof:              vc.Proofs[len(vc.Proofs)-1],
+		TimestampSignature: resp.Signature,
+	})
+}
+
+// AddVC adds the given credential to the log and returns the signed VC timestamp. If the credential
+// (without proofs) was already added then the timestamp of the existing entry is returned.
+func (l *Log) AddVC(vc *verifiable.Credential) (*command.AddVCResponse, error) {
+	leaf, err := command.CreateLeaf(uint64(l.now().UnixNano()/int64(time.Millisecond)), vc)
+	if err != nil {
+		return nil, fmt.Errorf("create leaf: %w", err)
+	}
+
+	extraData, err := json.Marshal(vc.Proofs)
+	if err != nil {
+		return nil, fmt.Errorf("marshal credential proofs: %w", err)
+	}
+
+	leaf, err = l.append(leaf, extraData)
+	if err != nil {
+		return nil, err
+	}
+
+	sct, err := l.sign(command.CreateVCTimestampSignature(leaf))
+	if err != nil {
+		return nil, fmt.Errorf("sign VC timestamp: %w", err)
+	}
+
+	return &command.AddVCResponse{
+		SVCTVersion: command.V1,
+		ID:          l.logID[:],
+		Timestamp:   leaf.TimestampedEntry.Timestamp,
+		Extensions:  base64.StdEncoding.EncodeToString(leaf.TimestampedEntry.Extensions),
+		Signature:   sct,
+	}, nil
+}
+
+// GetSTH returns the latest signed tree head.
+func (l *Log) GetSTH() (*command.GetSTHResponse, error) {
+	head, err := l.head()
+	if err != nil {
+		return nil, err
+	}
+
+	rootHash, err := l.tree.rootHash(head.TreeSize)
+	if err != nil {
+		return nil, fmt.Errorf("calculate root hash: %w", err)
+	}
+
+	ths, err := l.sign(command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      head.Timestamp,
+		TreeSize:       head.TreeSize,
+		SHA256RootHash: rootHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign tree head: %w", err)
+	}
+
+	return &command.GetSTHResponse{
+		TreeSize:          head.TreeSize,
+		Timestamp:         head.Timestamp,
+		SHA256RootHash:    rootHash,
+		TreeHeadSignature: ths,
+	}, nil
+}
+
+// GetProofByHash returns the audit path for the leaf with the given (base64-encoded) hash in the tree
+// with the given size.
+func (l *Log) GetProofByHash(hash string, treeSize uint64) (*command.GetProofByHashResponse, error) {
+	leafHash, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid base64 hash: %s", vcterrors.ErrBadRequest, err)
+	}
+
+	if err = l.checkTreeSize(treeSize); err != nil {
+		return nil, err
+	}
+
+	index, err := l.index(hashKeyPrefix + hex.EncodeToString(leafHash))
+	if err != nil {
+		return nil, err
+	}
+
+	if index >= treeSize {
+		return nil, fmt.Errorf("%w: leaf is not included in tree size %d", vcterrors.ErrNotFound, treeSize)
+	}
+
+	auditPath, err := l.tree.inclusionProof(index, treeSize)
+	if err != nil {
+		return nil, fmt.Errorf("get inclusion proof: %w", err)
+	}
+
+	return &command.GetProofByHashResponse{
+		LeafIndex: int64(index),
+		AuditPath: auditPath,
+	}, nil
+}
+
+// GetSTHConsistency returns the consistency proof between the trees with the given sizes.
+func (l *Log) GetSTHConsistency(first, second uint64) (*command.GetSTHConsistencyResponse, error) {
+	if first > second {
+		return nil, fmt.Errorf("%w: first tree size %d is greater than second tree size %d",
+			vcterrors.ErrValidation, first, second)
+	}
+
+	if err := l.checkTreeSize(second); err != nil {
+		return nil, err
+	}
+
+	proof, err := l.tree.consistencyProof(first, second)
+	if err != nil {
+		return nil, fmt.Errorf("get consistency proof: %w", err)
+	}
+
+	return &command.GetSTHConsistencyResponse{Consistency: proof}, nil
+}
+
+// GetEntries returns the log entries in the given (inclusive) range. At most 1000 entries are returned.
+func (l *Log) GetEntries(start, end uint64) (*command.GetEntriesResponse, error) {
+	if start > end {
+		return nil, fmt.Errorf("%w: start %d is greater than end %d", vcterrors.ErrValidation, start, end)
+	}
+
+	head, err := l.head()
+	if err != nil {
+		return nil, err
+	}
+
+	if start >= head.TreeSize {
+		return nil, fmt.Errorf("%w: need tree size %d to get leaves but only got %d",
+			vcterrors.ErrNotFound, start+1, head.TreeSize)
+	}
+
+	if end >= head.TreeSize {
+		end = head.TreeSize - 1
+	}
+
+	if end-start+1 > maxEntriesRange {
+		end = start + maxEntriesRange - 1
+	}
+
+	entries := make([]command.LeafEntry, 0, end-start+1)
+
+	for i := start; i <= end; i++ {
+		entry, err := l.entry(i)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	return &command.GetEntriesResponse{Entries: entries}, nil
+}
+
+// GetEntryAndProof returns the entry at the given index along with its audit path in the tree with the given size.
+func (l *Log) GetEntryAndProof(leafIndex, treeSize uint64) (*command.GetEntryAndProofResponse, error) {
+	if leafIndex >= treeSize {
+		return nil, fmt.Errorf("%w: leaf index %d is not less than tree size %d",
+			vcterrors.ErrValidation, leafIndex, treeSize)
+	}
+
+	if err := l.checkTreeSize(treeSize); err != nil {
+		return nil, err
+	}
+
+	entry, err := l.entry(leafIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	auditPath, err := l.tree.inclusionProof(leafIndex, treeSize)
+	if err != nil {
+		return nil, fmt.Errorf("get inclusion proof: %w", err)
+	}
+
+	return &command.GetEntryAndProofResponse{
+		LeafInput: entry.LeafInput,
+		ExtraData: entry.ExtraData,
+		AuditPath: auditPath,
+	}, nil
+}
+
+// GetPublicKey returns the public key of the log.
+func (l *Log) GetPublicKey() []byte {
+	return l.pubKey
+}
+
+// append adds the leaf to the log. If an entry with the same credential already exists then the existing leaf
+// is returned.
+func (l *Log) append(leaf *command.MerkleTreeLeaf, extraData []byte) (*command.MerkleTreeLeaf, error) {
+	entryHash := sha256.Sum256(leaf.TimestampedEntry.VCEntry)
+	entryKey := entryKeyPrefix + hex.EncodeToString(entryHash[:])
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.checkWriter(); err != nil {
+		return nil, err
+	}
+
+	index, err := l.index(entryKey)
+	if err == nil {
+		logger.Debugf("Credential is already in the log at index %d", index)
+
+		return l.leaf(index)
+	}
+
+	if !errors.Is(err, vcterrors.ErrNotFound) {
+		return nil, err
+	}
+
+	leafInput, err := json.Marshal(leaf)
+	if err != nil {
+		return nil, fmt.Errorf("marshal leaf: %w", err)
+	}
+
+	head, err := l.head()
+	if err != nil {
+		return nil, err
+	}
+
+	index = head.TreeSize
+	leafHash := hasher.DefaultHasher.HashLeaf(leafInput)
+
+	ops, err := l.tree.nodesForLeaf(index, leafHash)
+	if err != nil {
+		return nil, fmt.Errorf("add leaf to tree: %w", err)
+	}
+
+	entryBytes, err := json.Marshal(command.LeafEntry{LeafInput: leafInput, ExtraData: extraData})
+	if err != nil {
+		return nil, fmt.Errorf("marshal leaf entry: %w", err)
+	}
+
+	headBytes, err := json.Marshal(treeHead{TreeSize: index + 1, Timestamp: leaf.TimestampedEntry.Timestamp})
+	if err != nil {
+		return nil, fmt.Errorf("marshal tree head: %w", err)
+	}
+
+	indexBytes := []byte(strconv.FormatUint(index, 10))
+
+	ops = append(ops,
+		storage.Operation{Key: leafKey(index), Value: entryBytes},
+		storage.Operation{Key: hashKeyPrefix + hex.EncodeToString(leafHash), Value: indexBytes},
+		storage.Operation{Key: entryKey, Value: indexBytes},
+		storage.Operation{Key: headKey, Value: headBytes},
+	)
+
+	if err := l.store.Batch(ops); err != nil {
+		return nil, fmt.Errorf("store leaf: %w", err)
+	}
+
+	logger.Debugf("Added credential to the log at index %d", index)
+
+	return leaf, nil
+}
+
+// checkWriter returns ErrNotWriter if this Log instance doesn't hold the claim on the log.
+func (l *Log) checkWriter() error {
+	claim, err := l.getClaim()
+	if err != nil {
+		return err
+	}
+
+	if claim == nil || claim.Writer != l.writer {
+		logger.Errorf("Transparency log isn't claimed by this instance [%s]. Claim: %+v", l.writer, claim)
+
+		return ErrNotWriter
+	}
+
+	return nil
+}
+
+func (l *Log) head() (*treeHead, error) {
+	headBytes, err := l.store.Get(headKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return &treeHead{Timestamp: uint64(l.now().UnixNano() / int64(time.Millisecond))}, nil
+		}
+
+		return nil, fmt.Errorf("get tree head: %w", err)
+	}
+
+	head := &treeHead{}
+
+	if err := json.Unmarshal(headBytes, head); err != nil {
+		return nil, fmt.Errorf("unmarshal tree head: %w", err)
+	}
+
+	return head, nil
+}
+
+func (l *Log) checkTreeSize(treeSize uint64) error {
+	head, err := l.head()
+	if err != nil {
+		return err
+	}
+
+	if treeSize > head.TreeSize {
+		return fmt.Errorf("%w: requested tree size %d but the tree size is %d",
+			vcterrors.ErrNotFound, treeSize, head.TreeSize)
+	}
+
+	return nil
+}
+
+func (l *Log) index(key string) (uint64, error) {
+	indexBytes, err := l.store.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return 0, fmt.Errorf("%w: leaf", vcterrors.ErrNotFound)
+		}
+
+		return 0, fmt.Errorf("get leaf index: %w", err)
+	}
+
+	index, err := strconv.ParseUint(string(indexBytes), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse leaf index: %w", err)
+	}
+
+	return index, nil
+}
+
+func (l *Log) entry(index uint64) (*command.LeafEntry, error) {
+	entryBytes, err := l.store.Get(leafKey(index))
+	if err != nil {
+		return nil, fmt.Errorf("get leaf %d: %w", index, err)
+	}
+
+	entry := &command.LeafEntry{}
+
+	if err := json.Unmarshal(entryBytes, entry); err != nil {
+		return nil, fmt.Errorf("unmarshal leaf %d: %w", index, err)
+	}
+
+	return entry, nil
+}
+
+func (l *Log) leaf(index uint64) (*command.MerkleTreeLeaf, error) {
+	entry, err := l.entry(index)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := &command.MerkleTreeLeaf{}
+
+	if err := json.Unmarshal(entry.LeafInput, leaf); err != nil {
+		return nil, fmt.Errorf("unmarshal leaf input %d: %w", index, err)
+	}
+
+	return leaf, nil
+}
+
+func (l *Log) getNode(level uint, index uint64) ([]byte, error) {
+	return l.store.Get(nodeKey(level, index))
+}
+
+func (l *Log) sign(data interface{}) ([]byte, error) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal data: %w", err)
+	}
+
+	signature, err := l.crypto.Sign(dataBytes, l.kh)
+	if err != nil {
+		return nil, fmt.Errorf("sign data: %w", err)
+	}
+
+	return json.Marshal(command.DigitallySigned{
+		Algorithm: l.alg,
+		Signature: signature,
+	})
+}
+
+func leafKey(index uint64) string {
+	return leafKeyPrefix + strconv.FormatUint(index, 10)
+}
@@ -0,0 +1,676 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vctlog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+	vctclient "github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	vcterrors "github.com/trustbloc/vct/pkg/controller/errors"
+
+	"github.com/trustbloc/orb/pkg/activitypub/service/vct"
+	"github.com/trustbloc/orb/pkg/internal/testutil"
+	"github.com/trustbloc/orb/pkg/vcsigner"
+)
+
+const (
+	endpoint = "https://orb.domain1.com/vct"
+
+	vcTemplate = `{
+  "@context": ["https://www.w3.org/2018/credentials/v1"],
+  "id": "https://orb.domain1.com/vc/%d",
+  "type": "VerifiableCredential",
+  "issuer": "https://orb.domain1.com",
+  "issuanceDate": "2021-05-01T10:00:00Z",
+  "credentialSubject": {"id": "https://orb.domain1.com/subject/%d"}
+}`
+)
+
+func TestNew(t *testing.T) {
+	km, cr := newKMSAndCrypto(t)
+
+	keyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		l, err := New(&Config{KeyID: keyID, ClaimSettleTime: time.Millisecond}, mem.NewProvider(), km, cr, &mockSigner{})
+		require.NoError(t, err)
+		require.NotNil(t, l)
+		require.Equal(t, kms.ECDSAP256TypeIEEEP1363, l.KeyType)
+		require.Equal(t, defaultLeaseTTL, l.LeaseTTL)
+		require.NotEmpty(t, l.WriterID)
+	})
+
+	t.Run("Unsupported key type", func(t *testing.T) {
+		_, err := New(&Config{KeyID: keyID, KeyType: kms.ED25519Type}, mem.NewProvider(), km, cr, &mockSigner{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "key type ED25519 is not supported")
+	})
+
+	t.Run("Invalid key ID", func(t *testing.T) {
+		_, err := New(&Config{KeyID: "invalid"}, mem.NewProvider(), km, cr, &mockSigner{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get key handle")
+	})
+
+	t.Run("Open store error", func(t *testing.T) {
+		p := &mockProvider{Provider: mem.NewProvider(), openErr: errors.New("injected open error")}
+
+		_, err := New(&Config{KeyID: keyID, ClaimSettleTime: time.Millisecond}, p, km, cr, &mockSigner{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected open error")
+	})
+}
+
+func TestLog_Witness(t *testing.T) {
+	l, km := newTestLog(t, mem.NewProvider())
+
+	vcBytes := []byte(fmt.Sprintf(vcTemplate, 1, 1))
+
+	proofBytes, err := l.Witness(vcBytes)
+	require.NoError(t, err)
+
+	proof := &vct.Proof{}
+	require.NoError(t, json.Unmarshal(proofBytes, proof))
+	require.Len(t, proof.Context, 2)
+	require.Equal(t, endpoint, proof.Proof["domain"])
+
+	created, err := time.Parse(time.RFC3339Nano, proof.Proof["created"].(string))
+	require.NoError(t, err)
+
+	// The leaf hash is calculated from the 'created' time of the proof and the witnessed credential, which is
+	// what the monitoring service does in order to look up the proof.
+	leafHash := calculateLeafHash(t, created, vcBytes)
+
+	sth, err := l.GetSTH()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), sth.TreeSize)
+	require.Equal(t, uint64(created.UnixNano()/int64(time.Millisecond)), sth.Timestamp)
+	require.NoError(t, verifySTH(km, l, sth))
+
+	resp, err := l.GetProofByHash(leafHash, sth.TreeSize)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), resp.LeafIndex)
+
+	// The log's signature over the 'created' time of the proof is included.
+	require.NoError(t, vctclient.VerifyVCTimestampSignature(proof.TimestampSignature, l.GetPublicKey(),
+		uint64(created.UnixNano()/int64(time.Millisecond)), parseCredential(t, vcBytes)))
+
+	t.Run("Duplicate", func(t *testing.T) {
+		proofBytes2, err := l.Witness(vcBytes)
+		require.NoError(t, err)
+
+		proof2 := &vct.Proof{}
+		require.NoError(t, json.Unmarshal(proofBytes2, proof2))
+		require.Equal(t, proof.Proof["created"], proof2.Proof["created"])
+
+		sth, err := l.GetSTH()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), sth.TreeSize)
+	})
+
+	t.Run("Invalid credential", func(t *testing.T) {
+		_, err := l.Witness([]byte("{}"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parse credential")
+	})
+
+	t.Run("Signer error", func(t *testing.T) {
+		l.signer = &mockSigner{err: errors.New("injected signer error")}
+		defer func() { l.signer = &mockSigner{} }()
+
+		_, err := l.Witness(vcBytes)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected signer error")
+	})
+}
+
+func TestLog_AddVC(t *testing.T) {
+	l, km := newTestLog(t, mem.NewProvider())
+
+	vc := parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 1, 1)))
+
+	resp, err := l.AddVC(vc)
+	require.NoError(t, err)
+	require.Equal(t, command.V1, resp.SVCTVersion)
+
+	pubKey := l.GetPublicKey()
+
+	require.NoError(t, vctclient.VerifyVCTimestampSignature(resp.Signature, pubKey, resp.Timestamp, vc))
+
+	pubKey2, err := km.ExportPubKeyBytes(l.KeyID)
+	require.NoError(t, err)
+	require.Equal(t, pubKey2, pubKey)
+
+	t.Run("Crypto error", func(t *testing.T) {
+		cr := l.crypto
+		l.crypto = &mockCrypto{err: errors.New("injected sign error")}
+		defer func() { l.crypto = cr }()
+
+		_, err := l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 2, 2))))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected sign error")
+
+		_, err = l.GetSTH()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "injected sign error")
+	})
+}
+
+func TestLog_Proofs(t *testing.T) {
+	const n = 11
+
+	l, km := newTestLog(t, mem.NewProvider())
+
+	verifier := logverifier.New(hasher.DefaultHasher)
+
+	var (
+		leafHashes []string
+		sths       []*command.GetSTHResponse
+	)
+
+	for i := 0; i < n; i++ {
+		vcBytes := []byte(fmt.Sprintf(vcTemplate, i, i))
+
+		resp, err := l.AddVC(parseCredential(t, vcBytes))
+		require.NoError(t, err)
+
+		leafHashes = append(leafHashes,
+			calculateLeafHash(t, time.Unix(0, int64(resp.Timestamp)*int64(time.Millisecond)), vcBytes))
+
+		sth, err := l.GetSTH()
+		require.NoError(t, err)
+		require.Equal(t, uint64(i+1), sth.TreeSize)
+		require.NoError(t, verifySTH(km, l, sth))
+
+		sths = append(sths, sth)
+	}
+
+	t.Run("Inclusion", func(t *testing.T) {
+		for _, sth := range sths {
+			for i := 0; i < int(sth.TreeSize); i++ {
+				resp, err := l.GetProofByHash(leafHashes[i], sth.TreeSize)
+				require.NoError(t, err)
+				require.Equal(t, int64(i), resp.LeafIndex)
+
+				require.NoError(t, verifier.VerifyInclusionProof(resp.LeafIndex, int64(sth.TreeSize),
+					resp.AuditPath, sth.SHA256RootHash, decodeHash(t, leafHashes[i])))
+			}
+		}
+
+		_, err := l.GetProofByHash(leafHashes[5], 5)
+		require.True(t, errors.Is(err, vcterrors.ErrNotFound))
+
+		_, err = l.GetProofByHash(leafHashes[0], n+1)
+		require.True(t, errors.Is(err, vcterrors.ErrNotFound))
+
+		_, err = l.GetProofByHash("AAAA", n)
+		require.True(t, errors.Is(err, vcterrors.ErrNotFound))
+
+		_, err = l.GetProofByHash("invalid hash", n)
+		require.True(t, errors.Is(err, vcterrors.ErrBadRequest))
+	})
+
+	t.Run("Consistency", func(t *testing.T) {
+		for _, sth1 := range sths {
+			for _, sth2 := range sths[sth1.TreeSize-1:] {
+				resp, err := l.GetSTHConsistency(sth1.TreeSize, sth2.TreeSize)
+				require.NoError(t, err)
+
+				require.NoError(t, verifier.VerifyConsistencyProof(int64(sth1.TreeSize), int64(sth2.TreeSize),
+					sth1.SHA256RootHash, sth2.SHA256RootHash, resp.Consistency))
+			}
+		}
+
+		_, err := l.GetSTHConsistency(2, 1)
+		require.True(t, errors.Is(err, vcterrors.ErrValidation))
+
+		_, err = l.GetSTHConsistency(1, n+1)
+		require.True(t, errors.Is(err, vcterrors.ErrNotFound))
+	})
+
+	t.Run("Entries", func(t *testing.T) {
+		resp, err := l.GetEntries(3, 100)
+		require.NoError(t, err)
+		require.Len(t, resp.Entries, n-3)
+
+		leaf := &command.MerkleTreeLeaf{}
+		require.NoError(t, json.Unmarshal(resp.Entries[0].LeafInput, leaf))
+
+		vc := parseCredential(t, leaf.TimestampedEntry.VCEntry)
+		require.Equal(t, "https://orb.domain1.com/vc/3", vc.ID)
+
+		_, err = l.GetEntries(n, n+1)
+		require.True(t, errors.Is(err, vcterrors.ErrNotFound))
+
+		_, err = l.GetEntries(2, 1)
+		require.True(t, errors.Is(err, vcterrors.ErrValidation))
+	})
+
+	t.Run("Entry and proof", func(t *testing.T) {
+		sth := sths[6]
+
+		resp, err := l.GetEntryAndProof(4, sth.TreeSize)
+		require.NoError(t, err)
+
+		require.NoError(t, verifier.VerifyInclusionProof(4, int64(sth.TreeSize), resp.AuditPath,
+			sth.SHA256RootHash, hasher.DefaultHasher.HashLeaf(resp.LeafInput)))
+
+		_, err = l.GetEntryAndProof(7, 7)
+		require.True(t, errors.Is(err, vcterrors.ErrValidation))
+
+		_, err = l.GetEntryAndProof(1, n+1)
+		require.True(t, errors.Is(err, vcterrors.ErrNotFound))
+	})
+
+	t.Run("Persistence", func(t *testing.T) {
+		// Release the claim so that another instance may open the log.
+		l.Start()
+		l.Stop()
+
+		l2, err := New(&Config{KeyID: l.KeyID, Endpoint: endpoint, ClaimSettleTime: time.Millisecond},
+			&mockProvider{store: l.store}, km, l.crypto, &mockSigner{})
+		require.NoError(t, err)
+
+		sth, err := l2.GetSTH()
+		require.NoError(t, err)
+		require.Equal(t, sths[n-1].TreeSize, sth.TreeSize)
+		require.Equal(t, sths[n-1].SHA256RootHash, sth.SHA256RootHash)
+	})
+}
+
+func TestLog_SingleWriter(t *testing.T) {
+	newLog := func(l *Log, km kms.KeyManager, opts ...func(cfg *Config)) (*Log, error) {
+		cfg := &Config{KeyID: l.KeyID, Endpoint: endpoint, ClaimSettleTime: time.Millisecond}
+
+		for _, opt := range opts {
+			opt(cfg)
+		}
+
+		return New(cfg, &mockProvider{store: l.store}, km, l.crypto, &mockSigner{})
+	}
+
+	t.Run("Claimed by another instance", func(t *testing.T) {
+		l, km := newTestLog(t, mem.NewProvider())
+
+		l.LeaseTTL = 50 * time.Millisecond
+
+		require.NoError(t, l.renew())
+
+		l.Start()
+
+		_, err := l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 1, 1))))
+		require.NoError(t, err)
+
+		// Another instance can't claim the log while the lease is renewed.
+		_, err = newLog(l, km)
+		require.True(t, errors.Is(err, ErrNotWriter))
+		require.Contains(t, err.Error(), "may only be enabled on one instance")
+
+		_, err = l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 2, 2))))
+		require.NoError(t, err)
+
+		// Once the claim is released, another instance may claim the log.
+		l.Stop()
+
+		_, err = l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 3, 3))))
+		require.True(t, errors.Is(err, ErrNotWriter))
+
+		l2, err := newLog(l, km)
+		require.NoError(t, err)
+
+		_, err = l2.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 3, 3))))
+		require.NoError(t, err)
+
+		// Both instances may read the log.
+		sth, err := l.GetSTH()
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), sth.TreeSize)
+	})
+
+	t.Run("Lease of stopped instance", func(t *testing.T) {
+		l, km := newTestLog(t, mem.NewProvider())
+
+		// The instance stops without releasing its claim (e.g. it crashed).
+		l.LeaseTTL = 50 * time.Millisecond
+
+		require.NoError(t, l.renew())
+
+		// Another instance waits for the lease to expire.
+		l2, err := newLog(l, km)
+		require.NoError(t, err)
+
+		_, err = l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 1, 1))))
+		require.True(t, errors.Is(err, ErrNotWriter))
+
+		_, err = l2.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 1, 1))))
+		require.NoError(t, err)
+	})
+
+	t.Run("Same writer ID", func(t *testing.T) {
+		l, km := newTestLog(t, mem.NewProvider())
+
+		// The instance restarts with the same writer ID and re-claims its unexpired lease without waiting.
+		start := time.Now()
+
+		l2, err := newLog(l, km, func(cfg *Config) { cfg.WriterID = l.WriterID })
+		require.NoError(t, err)
+		require.Less(t, time.Since(start), time.Second)
+
+		_, err = l2.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 1, 1))))
+		require.NoError(t, err)
+	})
+
+	t.Run("Expired lease", func(t *testing.T) {
+		l, km := newTestLog(t, mem.NewProvider())
+
+		l.now = func() time.Time { return time.Now().Add(-time.Hour) }
+
+		require.NoError(t, l.renew())
+
+		l2, err := newLog(l, km)
+		require.NoError(t, err)
+
+		_, err = l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 1, 1))))
+		require.True(t, errors.Is(err, ErrNotWriter))
+
+		_, err = l2.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 1, 1))))
+		require.NoError(t, err)
+
+		// The lease of the previous writer isn't renewed.
+		require.True(t, errors.Is(l.renew(), ErrNotWriter))
+	})
+
+	t.Run("Lease renewal", func(t *testing.T) {
+		l, km := newTestLog(t, mem.NewProvider())
+
+		now := time.Now()
+
+		l.LeaseTTL = 30 * time.Millisecond
+		l.now = func() time.Time { return now }
+
+		require.NoError(t, l.renew())
+
+		claim, err := l.getClaim()
+		require.NoError(t, err)
+		require.Equal(t, l.writer, claim.Writer)
+
+		l.now = time.Now
+
+		l.Start()
+		defer l.Stop()
+
+		require.Eventually(t, func() bool {
+			c, e := l.getClaim()
+
+			return e == nil && c.Expires.After(claim.Expires)
+		}, time.Second, 5*time.Millisecond)
+
+		_, err = newLog(l, km)
+		require.True(t, errors.Is(err, ErrNotWriter))
+	})
+
+	t.Run("Concurrent claim", func(t *testing.T) {
+		l, km := newTestLog(t, mem.NewProvider())
+
+		l.Start()
+		l.Stop()
+
+		// Another instance overwrites the claim while this instance waits for its claim to settle.
+		s := &mockStore{Store: l.store}
+		s.afterPut = func(key string) {
+			if key == writerKey {
+				s.afterPut = nil
+
+				require.NoError(t, s.Store.Put(writerKey, []byte(`{"writer":"other"}`)))
+			}
+		}
+
+		_, err := New(&Config{KeyID: l.KeyID, Endpoint: endpoint, ClaimSettleTime: time.Millisecond},
+			&mockProvider{store: s}, km, l.crypto, &mockSigner{})
+		require.True(t, errors.Is(err, ErrNotWriter))
+	})
+
+	t.Run("Legacy claim", func(t *testing.T) {
+		l, km := newTestLog(t, mem.NewProvider())
+
+		require.NoError(t, l.store.Put(writerKey, []byte("legacy-writer")))
+
+		_, err := newLog(l, km)
+		require.NoError(t, err)
+	})
+}
+
+func TestLog_StoreError(t *testing.T) {
+	errExpected := errors.New("injected store error")
+
+	l, _ := newTestLog(t, mem.NewProvider())
+
+	_, err := l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 1, 1))))
+	require.NoError(t, err)
+
+	l.store = &mockStore{Store: l.store, getErr: errExpected}
+
+	_, err = l.GetSTH()
+	require.True(t, errors.Is(err, errExpected))
+
+	_, err = l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 2, 2))))
+	require.True(t, errors.Is(err, errExpected))
+
+	l.store = &mockStore{Store: l.store.(*mockStore).Store, batchErr: errExpected}
+
+	_, err = l.AddVC(parseCredential(t, []byte(fmt.Sprintf(vcTemplate, 2, 2))))
+	require.True(t, errors.Is(err, errExpected))
+}
+
+func newTestLog(t *testing.T, provider storage.Provider) (*Log, kms.KeyManager) {
+	t.Helper()
+
+	km, cr := newKMSAndCrypto(t)
+
+	keyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	l, err := New(&Config{
+		Endpoint:        endpoint,
+		KeyID:           keyID,
+		DocumentLoader:  testutil.GetLoader(t),
+		ClaimSettleTime: time.Millisecond,
+	}, provider, km, cr, &mockSigner{})
+	require.NoError(t, err)
+
+	return l, km
+}
+
+func newKMSAndCrypto(t *testing.T) (kms.KeyManager, *tinkcrypto.Crypto) {
+	t.Helper()
+
+	km, err := localkms.New("local-lock://custom/primary/key/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	return km, cr
+}
+
+func verifySTH(km kms.KeyManager, l *Log, sth *command.GetSTHResponse) error {
+	sig := &command.DigitallySigned{}
+
+	if err := json.Unmarshal(sth.TreeHeadSignature, sig); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      sth.Timestamp,
+		TreeSize:       sth.TreeSize,
+		SHA256RootHash: sth.SHA256RootHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	kh, err := km.(*localkms.LocalKMS).PubKeyBytesToHandle(l.GetPublicKey(), sig.Algorithm.Type)
+	if err != nil {
+		return err
+	}
+
+	return l.crypto.(*tinkcrypto.Crypto).Verify(sig.Signature, data, kh)
+}
+
+func calculateLeafHash(t *testing.T, created time.Time, vcBytes []byte) string {
+	t.Helper()
+
+	hash, err := vctclient.CalculateLeafHash(uint64(created.UnixNano()/int64(time.Millisecond)),
+		parseCredential(t, vcBytes))
+	require.NoError(t, err)
+
+	return hash
+}
+
+func decodeHash(t *testing.T, hash string) []byte {
+	t.Helper()
+
+	b, err := base64.StdEncoding.DecodeString(hash)
+	require.NoError(t, err)
+
+	return b
+}
+
+func parseCredential(t *testing.T, vcBytes []byte) *verifiable.Credential {
+	t.Helper()
+
+	vc, err := verifiable.ParseCredential(vcBytes,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(testutil.GetLoader(t)),
+	)
+	require.NoError(t, err)
+
+	return vc
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (p *kmsProvider) StorageProvider() storage.Provider {
+	return p.storageProvider
+}
+
+func (p *kmsProvider) SecretLock() secretlock.Service {
+	return p.secretLock
+}
+
+type mockSigner struct {
+	err error
+}
+
+func (m *mockSigner) Sign(vc *verifiable.Credential, opts ...vcsigner.Opt) (*verifiable.Credential, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	ctx := &verifiable.LinkedDataProofContext{}
+
+	for _, opt := range opts {
+		opt(ctx)
+	}
+
+	vc.Proofs = append(vc.Proofs, map[string]interface{}{
+		"created": ctx.Created.Format(time.RFC3339Nano),
+		"domain":  ctx.Domain,
+	})
+
+	return vc, nil
+}
+
+type mockCrypto struct {
+	err error
+}
+
+func (m *mockCrypto) Sign([]byte, interface{}) ([]byte, error) {
+	return nil, m.err
+}
+
+type mockProvider struct {
+	storage.Provider
+
+	store   storage.Store
+	openErr error
+}
+
+func (m *mockProvider) OpenStore(name string) (storage.Store, error) {
+	if m.openErr != nil {
+		return nil, m.openErr
+	}
+
+	if m.store != nil {
+		return m.store, nil
+	}
+
+	return m.Provider.OpenStore(name)
+}
+
+type mockStore struct {
+	storage.Store
+
+	getErr   error
+	batchErr error
+	afterPut func(key string)
+}
+
+func (m *mockStore) Put(key string, value []byte, tags ...storage.Tag) error {
+	if err := m.Store.Put(key, value, tags...); err != nil {
+		return err
+	}
+
+	if m.afterPut != nil {
+		m.afterPut(key)
+	}
+
+	return nil
+}
+
+func (m *mockStore) Get(key string) ([]byte, error) {
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+
+	return m.Store.Get(key)
+}
+
+func (m *mockStore) Batch(ops []storage.Operation) error {
+	if m.batchErr != nil {
+		return m.batchErr
+	}
+
+	return m.Store.Batch(ops)
+}